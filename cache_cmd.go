@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go-story/internal/config"
+)
+
+const cacheCommandUsage = `usage:
+  go-story cache dump [-prefix <prefix>] [-out <file>]
+  go-story cache restore [-in <file>]`
+
+// runCacheCommand 執行 cache 子命令：dump 將 prefix 下的 entry 匯出成 JSON lines，
+// restore 將匯出的檔案寫回 (例如以 production 的內容預熱 staging)
+func runCacheCommand(cfg config.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(cacheCommandUsage)
+	}
+
+	cache, err := newCache(cfg, logger, nil)
+	if err != nil {
+		return fmt.Errorf("failed to initialize cache: %w", err)
+	}
+	defer cache.Close()
+	if !cache.Enabled() {
+		return errors.New("cache is disabled (REDIS_ENABLED=false)")
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "dump":
+		fs := flag.NewFlagSet("cache dump", flag.ContinueOnError)
+		prefix := fs.String("prefix", "", "only dump keys starting with this prefix (without namespace)")
+		out := fs.String("out", "", "output file (default stdout)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := cache.Dump(ctx, *prefix, w)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "dumped %d entries\n", n)
+		return nil
+
+	case "restore":
+		fs := flag.NewFlagSet("cache restore", flag.ContinueOnError)
+		in := fs.String("in", "", "dump file (default stdin)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+		if *in != "" {
+			f, err := os.Open(*in)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		n, err := cache.Restore(ctx, r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "restored %d entries\n", n)
+		return nil
+	}
+	return errors.New(cacheCommandUsage)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"go-story/internal/data"
+	"go-story/internal/grpcapi"
+
+	"google.golang.org/grpc"
+)
+
+// startGRPCServer 在 addr 啟動 gRPC story 服務
+func startGRPCServer(addr string, stories *data.StoryService) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	s := grpc.NewServer()
+	grpcapi.Register(s, stories)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+	log.Printf("gRPC server listening on %s", addr)
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"net/url"
 	"os"
 	"strconv"
@@ -10,6 +11,14 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// RateLimitPolicy is the rate limit of a route group (see
+// RATE_LIMIT_POLICIES).
+type RateLimitPolicy struct {
+	PerIP     int
+	PerAPIKey int
+	Window    int // 秒
+}
+
 // Config holds runtime configuration from environment.
 type Config struct {
 	// DATABASE_URL: Postgres 連線字串 (必填)
@@ -26,6 +35,302 @@ type Config struct {
 	RedisURL string
 	// REDIS_TTL: Cache TTL (秒)，預設為 3600 (選填)
 	RedisTTL int
+	// REDIS_MODE: Redis 連線模式 (standalone/cluster/sentinel)，未設定時依 REDIS_URL 自動判斷 (選填)
+	RedisMode string
+	// REDIS_SENTINEL_MASTER: Sentinel 監控的 master 名稱 (選填，使用 sentinel 時必填)
+	RedisSentinelMaster string
+	// REDIS_SENTINEL_ADDRS: Sentinel 位址，以逗號分隔，例如 sentinel-1:26379,sentinel-2:26379 (選填)
+	RedisSentinelAddrs []string
+	// REDIS_SENTINEL_PASSWORD: Sentinel 本身的密碼 (選填)
+	RedisSentinelPassword string
+	// REDIS_DIAL_TIMEOUT_MS: 建立 Redis 連線的逾時 (毫秒)，0 表示使用預設值 5000 (選填)
+	RedisDialTimeoutMS int
+	// REDIS_READ_TIMEOUT_MS: Redis 讀取逾時 (毫秒)，0 表示使用預設值 3000 (選填)
+	RedisReadTimeoutMS int
+	// REDIS_WRITE_TIMEOUT_MS: Redis 寫入逾時 (毫秒)，0 表示與讀取逾時相同 (選填)
+	RedisWriteTimeoutMS int
+	// CACHE_OP_TIMEOUT_MS: 每次 cache 操作的時限 (毫秒)，逾時視為 miss，預設為 0 (不限制) (選填)
+	CacheOpTimeoutMS int
+	// REDIS_USERNAME: Redis ACL 帳號，設定時覆寫 REDIS_URL 中的帳號 (選填)
+	RedisUsername string
+	// REDIS_PASSWORD: Redis 密碼，設定時覆寫 REDIS_URL 中的密碼 (選填)
+	RedisPassword string
+	// REDIS_TLS_ENABLED: 是否以自訂 TLS 設定連線 Redis，預設為 false (選填)
+	RedisTLSEnabled bool
+	// REDIS_TLS_CA_FILE: 驗證 Redis 伺服器憑證用的 CA bundle (PEM) 路徑 (選填)
+	RedisTLSCAFile string
+	// REDIS_TLS_CERT_FILE: client 憑證 (PEM) 路徑，需與 REDIS_TLS_KEY_FILE 一起設定 (選填)
+	RedisTLSCertFile string
+	// REDIS_TLS_KEY_FILE: client 私鑰 (PEM) 路徑 (選填)
+	RedisTLSKeyFile string
+	// REDIS_TLS_INSECURE_SKIP_VERIFY: 是否略過伺服器憑證驗證，僅供測試使用，預設為 false (選填)
+	RedisTLSInsecureSkipVerify bool
+	// LOG_LEVEL: 日誌等級 (debug/info/warn/error)，prod 預設為 info，其他環境預設為 debug (選填)
+	LogLevel string
+	// LOG_FORMAT: 日誌格式 (text/json)，預設為 text (選填)
+	LogFormat string
+	// METRICS_ENABLED: 是否於 /metrics 提供 Prometheus 指標，預設為 false (選填)
+	MetricsEnabled bool
+	// CACHE_ADMIN_TOKEN: /internal/cache/ 管理 API 的 Bearer token，未設定時不提供管理 API (選填)
+	CacheAdminToken string
+	// CACHE_STATS_ENABLED: 是否於 /internal/cache/stats 提供 cache 狀態，預設為 false (選填)
+	CacheStatsEnabled bool
+	// RATE_LIMIT_PER_IP: 每個 IP 在時間窗內可查詢 /api/graphql 的次數，預設為 0 (不限制) (選填)
+	RateLimitPerIP int
+	// RATE_LIMIT_PER_API_KEY: 帶有驗證通過的 X-API-Key (需 API_KEYS_ENABLED) 的請求在時間窗內可查詢的次數，預設為 0 (改用 IP 限制) (選填)
+	RateLimitPerAPIKey int
+	// RATE_LIMIT_WINDOW: 計算請求次數的時間窗 (秒)，預設為 60 (選填)
+	RateLimitWindow int
+	// RATE_LIMIT_POLICIES: 各 route group 自己的上限，逗號分隔的 group=perIP/perAPIKey/window，
+	// 例如 graphql=60/600/60,images=300；省略的欄位沿用上面三個設定，未列出的 group 共用同一組計數 (選填)
+	RateLimitPolicies map[string]RateLimitPolicy
+	// TRUSTED_PROXIES: 前方 load balancer / proxy 的 IP 或 CIDR，逗號分隔，例如 10.0.0.0/8；
+	// 只有來自這些位址的請求才採信 X-Forwarded-For，未設定時以連線的位址為 client IP (選填)
+	TrustedProxies []netip.Prefix
+	// COMPRESSION_ENABLED: 是否依 Accept-Encoding 壓縮文字格式的回應 (br/zstd/gzip)，預設為 true (選填)
+	CompressionEnabled bool
+	// COMPRESSION_MIN_SIZE: 達到此大小 (bytes) 的回應才壓縮，預設為 1024 (選填)
+	CompressionMinSize int
+	// CACHE_NAMESPACE: 所有 cache key 的前綴，例如 story (選填)
+	CacheNamespace string
+	// CACHE_KEY_VERSION: 加在 namespace 後的版本，例如 v3；變更後舊資料即全部失效 (選填)
+	CacheKeyVersion string
+	// CACHE_DEBUG_KEYS: cache key 是否帶有可讀的參數摘要，預設為 false (選填)
+	CacheDebugKeys bool
+	// CACHE_FALLBACK_SIZE: Redis 無法連線時 in-memory LRU 的最大筆數，預設為 1000，設為 0 則停用 (選填)
+	CacheFallbackSize int
+	// CACHE_BREAKER_THRESHOLD: Redis 連續失敗幾次後暫停使用 (circuit breaker 開啟)，預設為 5 (選填)
+	CacheBreakerThreshold int
+	// CACHE_BREAKER_COOLDOWN: circuit breaker 開啟後多久 (秒) 再試探 Redis，預設為 30 (選填)
+	CacheBreakerCooldown int
+	// CACHE_LOCAL_SIZE: 兩層快取中本地 LRU 的最大筆數，預設為 0 (不啟用) (選填)
+	CacheLocalSize int
+	// CACHE_LOCAL_TTL: 兩層快取中本地 LRU 的 TTL (秒)，預設為 30 (選填)
+	CacheLocalTTL int
+	// CACHE_STALE_TTL: TTL 過後仍可回傳舊資料並於背景更新的時間 (秒)，預設為 0 (不啟用) (選填)
+	CacheStaleTTL int
+	// CACHE_HOT_KEY_THRESHOLD: 每分鐘讀取達此次數的 key 會在到期前於背景提前更新，預設為 0 (不啟用) (選填)
+	CacheHotKeyThreshold int
+	// CACHE_HOT_KEY_REFRESH_AHEAD: 熱門 key 在到期前多久 (秒) 開始背景更新，預設為 10 (選填)
+	CacheHotKeyRefreshAhead int
+	// CACHE_NEGATIVE_TTL: 查無資料 (例如不存在的 slug) 的快取時間 (秒)，預設為 0 (不啟用) (選填)
+	CacheNegativeTTL int
+	// CACHE_TTL_JITTER: TTL 隨機浮動比例，例如 0.1 表示 ±10%，預設為 0 (不浮動) (選填)
+	CacheTTLJitter float64
+	// CACHE_WARM_SLUGS: 啟動時預熱的文章 slug，以逗號分隔 (選填)
+	CacheWarmSlugs []string
+	// CACHE_WARM_TOP_N: 啟動時預熱最近兩天最熱門的前 N 篇文章，預設為 0 (不啟用) (選填)
+	CacheWarmTopN int
+	// CACHE_WARM_INTERVAL: 定期重新預熱的間隔 (秒)，預設為 0 (只在啟動時預熱) (選填)
+	CacheWarmInterval int
+	// CACHE_CODEC: cache 值的序列化格式 (json/msgpack/cbor)，預設為 json (選填)
+	CacheCodec string
+	// CACHE_COMPRESSION: 大型 cache 值的壓縮方式 (none/gzip/zstd)，預設為 none (選填)
+	CacheCompression string
+	// CACHE_COMPRESSION_THRESHOLD: 超過此大小 (bytes) 才壓縮，預設為 4096 (選填)
+	CacheCompressionThreshold int
+	// CACHE_MAX_VALUE_SIZE: 序列化 (與壓縮) 後超過此大小 (bytes) 的 value 不寫入 cache，預設為 0 (不限制) (選填)
+	CacheMaxValueSize int
+	// CACHE_ENCRYPTION_KEY: 加密敏感 cache 值的 AES 金鑰 (base64 編碼的 16/24/32 bytes) (選填)
+	CacheEncryptionKey string
+	// CACHE_ENCRYPTED_PREFIXES: 一律加密的 cache key 前綴，以逗號分隔 (選填)
+	CacheEncryptedPrefixes []string
+	// MIGRATE_ON_START: 啟動時是否自動套用未執行的 migration，預設為 false (選填)
+	MigrateOnStart bool
+	// STORY_STORE: story 的儲存層 (postgres/mongo)，預設為 postgres (選填)
+	StoryStore string
+	// MONGO_URL: STORY_STORE=mongo 時的 MongoDB 連線字串 (選填)
+	MongoURL string
+	// MONGO_DATABASE: STORY_STORE=mongo 時使用的 database，預設為 go-story (選填)
+	MongoDatabase string
+	// GRPC_PORT: gRPC story 服務的監聽埠，未設定時不啟動 (選填)
+	GRPCPort string
+	// SEARCH_BACKEND: 全文搜尋的 backend (postgres/elasticsearch)，STORY_STORE=postgres 時預設為 postgres，否則預設不提供搜尋 (選填)
+	SearchBackend string
+	// ELASTICSEARCH_URL: SEARCH_BACKEND=elasticsearch 時的 Elasticsearch / OpenSearch 位址，可含帳號密碼 (選填)
+	ElasticsearchURL string
+	// ELASTICSEARCH_INDEX: 存放 story 的 index alias 名稱，預設為 stories (選填)
+	ElasticsearchIndex string
+	// ELASTICSEARCH_SYNC_INTERVAL: 將 story 的變更增量同步到 index 的間隔 (秒)，預設為 60，設為 0 則停用 (選填)
+	ElasticsearchSyncInterval int
+	// VIEW_DEDUPE_WINDOW: 同一訪客重複瀏覽同一篇 story 只計一次的時間窗 (秒)，預設為 1800，設為 0 則不去重 (選填)
+	ViewDedupeWindow int
+	// VIEW_FLUSH_INTERVAL: 將累計的瀏覽數寫入資料庫的間隔 (秒)，預設為 60，設為 0 則停用瀏覽數累計 (選填)
+	ViewFlushInterval int
+	// SITE_URL: 前台網站的網址，例如 https://www.example.com，feed 中的文章連結為 SITE_URL/story/{slug}；未設定時不提供 feed (選填)
+	SiteURL string
+	// SITE_NAME: 網站名稱，用於 feed 標題，預設為 go-story (選填)
+	SiteName string
+	// SITE_DESCRIPTION: 網站說明，用於 feed (選填)
+	SiteDescription string
+	// SITE_LANGUAGE: 網站內容的語言 (BCP 47)，預設為 zh-TW (選填)
+	SiteLanguage string
+	// SITE_IMAGE: 網站的預設分享圖片網址，story 沒有可用的圖片時作為 og:image (選填)
+	SiteImage string
+	// SITE_TWITTER: 網站的 X (Twitter) 帳號，例如 @example，作為 twitter:site (選填)
+	SiteTwitter string
+	// SITE_LOGO: 網站的 logo 網址，作為 JSON-LD 中 publisher 的 logo (選填)
+	SiteLogo string
+	// SITE_ARTICLE_TYPE: story 的 JSON-LD 類型 (NewsArticle/Article/BlogPosting)，預設為 NewsArticle (選填)
+	SiteArticleType string
+	// AMP_ENABLED: 是否於 /amp/{slug} 提供 story 的 AMP 頁面，預設為 false；需設定 SITE_URL (選填)
+	AMPEnabled bool
+	// SITEMAP_INTERVAL: 重新產生 sitemap 的間隔 (秒)，預設為 3600，設為 0 則不提供 sitemap；需設定 SITE_URL (選填)
+	SitemapInterval int
+	// WEBHOOK_DELIVERY_INTERVAL: 投遞 webhook 的檢查間隔 (秒)，預設為 10，設為 0 則停用 webhook (選填)
+	WebhookDeliveryInterval int
+	// WEBHOOK_ADMIN_TOKEN: /internal/webhooks/ 管理 API 的 Bearer token，未設定時不提供管理 API (選填)
+	WebhookAdminToken string
+	// PUBLISH_SCHEDULE_INTERVAL: 檢查並發布到期排程 story 的間隔 (秒)，預設為 30，設為 0 則不自動發布 (選填)
+	PublishScheduleInterval int
+	// WORKFLOW_WRITER_TOKEN: /internal/stories/ 工作流程 API 的撰稿者 (author) Bearer token (選填)
+	WorkflowWriterToken string
+	// WORKFLOW_EDITOR_TOKEN: /internal/stories/ 工作流程 API 的編輯 Bearer token (選填)
+	WorkflowEditorToken string
+	// WORKFLOW_ADMIN_TOKEN: /internal/stories/ 工作流程 API 的管理者 Bearer token；三者皆未設定且未設定 AUTH_JWKS_URL 與 OIDC_ISSUER_URL 時不提供工作流程 API (選填)
+	WorkflowAdminToken string
+	// AUDIT_ADMIN_TOKEN: /internal/audit 稽核紀錄查詢 API 的 Bearer token，未設定時不提供查詢 API (選填)
+	AuditAdminToken string
+	// PREVIEW_SECRET: 簽署未發布 story 預覽 token 的密鑰，未設定時不提供預覽 (選填)
+	PreviewSecret string
+	// PREVIEW_TOKEN_TTL: 預覽 token 的有效期間 (秒)，預設為 86400 (選填)
+	PreviewTokenTTL int
+	// PREVIEW_URL: 分享給預覽者的網址範本，以 {token} 代入 token，例如 https://www.example.com/preview?token={token}；未設定時為 REST API 的預覽網址 (選填)
+	PreviewURL string
+	// STORY_BODY_FORMAT: story body 的格式 (html/markdown)，預設為 html；markdown 時在回應中轉換為過濾後的 HTML (選填)
+	StoryBodyFormat string
+	// MARKDOWN_EXTENSIONS: STORY_BODY_FORMAT=markdown 時啟用的擴充語法 (table/footnote/strikethrough/linkify/tasklist，逗號分隔)，預設為 table,footnote (選填)
+	MarkdownExtensions []string
+	// HTML_ALLOWLIST: story HTML 允許的元素與屬性，逗號分隔，例如 p,br,a[href|title]；預設為 data.DefaultHTMLAllowlist (選填)
+	HTMLAllowlist string
+	// EXTERNAL_LINK_REL: 連到其他網站的連結加上的 rel (逗號分隔)，預設為 noopener,nofollow (選填)
+	ExternalLinkRel []string
+	// OEMBED_CACHE_TTL: embed block 的 oEmbed 解析結果 (YouTube、X、Instagram) 在 cache 中保留的時間 (秒)，預設為 604800，0 表示不解析 (選填)
+	OEmbedCacheTTL int
+	// INSTAGRAM_OEMBED_TOKEN: 解析 Instagram embed 所需的 Meta access token，未設定時不解析 Instagram (選填)
+	InstagramOEmbedToken string
+	// SUMMARIZER_URL: 產生摘要的 LLM chat completions API 網址 (OpenAI 相容)，例如 https://api.openai.com/v1/chat/completions；未設定時摘要取 body 的前幾句 (選填)
+	SummarizerURL string
+	// SUMMARIZER_API_KEY: 呼叫 SUMMARIZER_URL 的 API key (選填)
+	SummarizerAPIKey string
+	// SUMMARIZER_MODEL: 產生摘要的模型，預設為 data.DefaultSummarizerModel (選填)
+	SummarizerModel string
+	// MEDIA_STORAGE: 上傳圖片的儲存空間 (local/s3/gcs)，未設定時不提供上傳 (選填)
+	MediaStorage string
+	// MEDIA_LOCAL_DIR: MEDIA_STORAGE=local 時存放圖片的目錄，預設為 ./media (選填)
+	MediaLocalDir string
+	// MEDIA_PUBLIC_URL: 圖片網址的前綴，例如 https://api.example.com/media；MEDIA_STORAGE=local 時必填，並由本服務提供該路徑下的檔案 (選填)
+	MediaPublicURL string
+	// MEDIA_MAX_SIZE: 上傳圖片的大小上限 (bytes)，預設為 10485760 (選填)
+	MediaMaxSize int64
+	// MEDIA_S3_ENDPOINT: MEDIA_STORAGE=s3 時的 API 位址，用於 MinIO、R2 等相容服務；未設定時為 AWS S3 (選填)
+	MediaS3Endpoint string
+	// MEDIA_S3_REGION: MEDIA_STORAGE=s3 時的區域，預設為 us-east-1 (選填)
+	MediaS3Region string
+	// MEDIA_BUCKET: MEDIA_STORAGE=s3/gcs 時的 bucket (選填)
+	MediaBucket string
+	// MEDIA_ACCESS_KEY_ID: MEDIA_STORAGE=s3/gcs 時的 access key；GCS 為 HMAC key (選填)
+	MediaAccessKeyID string
+	// MEDIA_SECRET_ACCESS_KEY: MEDIA_ACCESS_KEY_ID 的 secret (選填)
+	MediaSecretAccessKey string
+	// IMAGE_PROXY_SOURCES: 圖片轉換可使用的來源網址前綴 (逗號分隔)，上傳圖片的網址一律可用；皆未設定時不提供 /images (選填)
+	ImageProxySources []string
+	// IMAGE_CACHE_TTL: 轉換後的圖片在 cache 中保留的時間 (秒)，預設為 86400 (選填)
+	ImageCacheTTL int
+	// COMMENTS_ENABLED: 是否提供 story 的留言，預設為 false (選填)
+	CommentsEnabled bool
+	// COMMENT_AUTO_APPROVE: 新留言是否直接公開，預設為 false，由編輯審核後公開 (選填)
+	CommentAutoApprove bool
+	// COMMENT_RATE_LIMIT: 每位使用者在 COMMENT_RATE_WINDOW 內可發表的留言數，預設為 5，設為 0 則不限制 (選填)
+	CommentRateLimit int
+	// COMMENT_RATE_WINDOW: 留言頻率限制的時間窗 (秒)，預設為 60 (選填)
+	CommentRateWindow int
+	// REACTIONS_ENABLED: 是否提供 story 的回應 (例如 like、clap)，預設為 false (選填)
+	ReactionsEnabled bool
+	// REACTIONS: 可使用的回應名稱 (逗號分隔，小寫英數字、- 與 _)，預設為 like,clap (選填)
+	Reactions []string
+	// REACTION_FLUSH_INTERVAL: 將累計的回應數寫入資料庫的間隔 (秒)，預設為 60 (選填)
+	ReactionFlushInterval int
+	// BOOKMARKS_ENABLED: 是否提供讀者收藏 story，預設為 false (選填)
+	BookmarksEnabled bool
+	// READING_PROGRESS_ENABLED: 是否提供跨裝置同步的閱讀位置，預設為 false (選填)
+	ReadingProgressEnabled bool
+	// NEWSLETTER_ADMIN_TOKEN: 電子報摘要管理 API (/internal/newsletter/) 的 Bearer token，未設定時不提供電子報 (選填)
+	NewsletterAdminToken string
+	// NEWSLETTER_SECTIONS: 電子報摘要依序列出的 section (逗號分隔)，未設定時列出所有 section (選填)
+	NewsletterSections []string
+	// NEWSLETTER_STORIES_PER_SECTION: 電子報摘要每個 section 的 story 數，預設為 3 (選填)
+	NewsletterStoriesPerSection int
+	// NEWSLETTER_WEBHOOK_URL: 接收電子報摘要的電子報服務 (或轉接服務) 網址，未設定時只能預覽 (選填)
+	NewsletterWebhookURL string
+	// NEWSLETTER_WEBHOOK_SECRET: 簽署送往 NEWSLETTER_WEBHOOK_URL 的請求的密鑰 (選填)
+	NewsletterWebhookSecret string
+	// PUSH_TAGS: 發布時推播通知的 story 所帶的 tag (逗號分隔)，預設為 breaking (選填)
+	PushTags []string
+	// PUSH_FCM_CREDENTIALS_FILE: 以 FCM 推播時使用的 Google service account 金鑰檔 (JSON)，未設定時不使用 FCM (選填)
+	PushFCMCredentialsFile string
+	// PUSH_FCM_TOPIC: App 訂閱的 FCM topic，預設為 stories (選填)
+	PushFCMTopic string
+	// PUSH_APNS_KEY_FILE: 以 APNs 推播時使用的 .p8 金鑰檔，未設定時不使用 APNs (選填)
+	PushAPNsKeyFile string
+	// PUSH_APNS_KEY_ID: .p8 金鑰的 Key ID，使用 APNs 時必填
+	PushAPNsKeyID string
+	// PUSH_APNS_TEAM_ID: Apple Developer 的 Team ID，使用 APNs 時必填
+	PushAPNsTeamID string
+	// PUSH_APNS_TOPIC: iOS App 的 bundle ID，使用 APNs 時必填
+	PushAPNsTopic string
+	// PUSH_APNS_SANDBOX: 是否送到 APNs 的開發環境，預設為 false (選填)
+	PushAPNsSandbox bool
+	// PUSH_WEBHOOK_URL: 接收推播通知的自建推播服務網址，未設定時不使用 (選填)
+	PushWebhookURL string
+	// PUSH_WEBHOOK_SECRET: 簽署送往 PUSH_WEBHOOK_URL 的請求的密鑰 (選填)
+	PushWebhookSecret string
+
+	// PAYWALL_ENABLED: 是否啟用付費牆；設定 PAYWALL_TOKEN_SECRET 時一律啟用，未啟用時所有文章皆提供全文，預設為 false (選填)
+	PaywallEnabled bool
+	// PAYWALL_TOKEN_SECRET: 會員系統簽署 membership token (HS256 JWT) 的密鑰；使用 AUTH_JWKS_URL 時可不設定，改由驗證過的 JWT 讀取會員資格 (選填)
+	PaywallTokenSecret string
+	// PAYWALL_MEMBERSHIP_CLAIM: token 中表示會員資格的 claim，預設為 membership (選填)
+	PaywallMembershipClaim string
+	// PAYWALL_METER_LIMIT: 非會員在每個計次期間可免費閱讀的計次文章數，預設為 3 (選填)
+	PaywallMeterLimit int
+	// PAYWALL_METER_WINDOW: 計次期間 (秒)，自訪客第一次閱讀計次文章起算，預設為 2592000 (30 天) (選填)
+	PaywallMeterWindow int
+
+	// AUTH_JWKS_URL: 身分提供者公布簽章公鑰的 JWKS 網址；設定後驗證 Authorization: Bearer 帶入的 JWT (選填)
+	AuthJWKSURL string
+	// AUTH_ISSUER: JWT 的 iss 必須等於此值，未設定時不檢查 (選填)
+	AuthIssuer string
+	// AUTH_AUDIENCE: 接受的 JWT aud (逗號分隔)，未設定時不檢查 (選填)
+	AuthAudience []string
+	// AUTH_CLOCK_SKEW: 檢查 exp、nbf 與 iat 時允許的時鐘誤差 (秒)，預設為 60 (選填)
+	AuthClockSkew int
+	// AUTH_JWKS_CACHE_TTL: JWKS 的快取時間 (秒)，預設為 3600 (選填)
+	AuthJWKSCacheTTL int
+	// AUTH_ROLES_CLAIM: JWT 中表示使用者角色的 claim，預設為 roles (選填)
+	AuthRolesClaim string
+
+	// API_KEYS_ENABLED: 是否驗證 X-API-Key 帶入的 API key，未知或已撤銷的 key 回傳 401，預設為 false (選填)
+	APIKeysEnabled bool
+	// API_KEY_ADMIN_TOKEN: /internal/api-keys 管理 API 的 Bearer token，需同時啟用 API_KEYS_ENABLED，未設定時不提供管理 API (選填)
+	APIKeyAdminToken string
+
+	// OIDC_ISSUER_URL: 管理介面登入使用的 OIDC 身分提供者 issuer；設定後提供 /auth/ 登入流程 (選填)
+	OIDCIssuerURL string
+	// OIDC_CLIENT_ID: 在身分提供者註冊的 client ID (選填，設定 OIDC_ISSUER_URL 時必填)
+	OIDCClientID string
+	// OIDC_CLIENT_SECRET: client secret，public client 可不設定，只以 PKCE 保護 (選填)
+	OIDCClientSecret string
+	// OIDC_REDIRECT_URL: 在身分提供者註冊的 callback 網址，例如 https://example.com/auth/callback (選填，設定 OIDC_ISSUER_URL 時必填)
+	OIDCRedirectURL string
+	// OIDC_SCOPES: 登入時要求的 scope (逗號分隔)，預設為 openid,profile,email；需要 refresh token 時加上 offline_access (選填)
+	OIDCScopes []string
+	// OIDC_GROUPS_CLAIM: ID token 中表示使用者群組的 claim，預設為 groups (選填)
+	OIDCGroupsClaim string
+	// OIDC_GROUP_ROLES: 群組對應的角色，例如 cms-admins=admin,cms-editors=editor,writers=author；未設定時以群組名稱作為角色 (選填)
+	OIDCGroupRoles map[string]string
+	// ADMIN_SESSION_TTL: 管理介面 session 的有效期間 (秒)，預設為 43200 (12 小時) (選填)
+	AdminSessionTTL int
 }
 
 // Load reads required environment variables.
@@ -35,15 +340,178 @@ type Config struct {
 // REDIS_ENABLED is optional; defaults to false.
 // REDIS_URL is optional; required if REDIS_ENABLED=true.
 // REDIS_TTL is optional; defaults to 3600 seconds.
+// REDIS_MODE is optional; detected from REDIS_URL when empty.
+// REDIS_SENTINEL_MASTER, REDIS_SENTINEL_ADDRS and REDIS_SENTINEL_PASSWORD are optional.
+// REDIS_DIAL_TIMEOUT_MS, REDIS_READ_TIMEOUT_MS and REDIS_WRITE_TIMEOUT_MS are optional; 0 keeps the client defaults.
+// CACHE_OP_TIMEOUT_MS is optional; defaults to 0 (no per-operation deadline).
+// REDIS_USERNAME and REDIS_PASSWORD are optional; they override REDIS_URL credentials.
+// REDIS_TLS_ENABLED is optional; defaults to false. Setting REDIS_TLS_CA_FILE,
+// REDIS_TLS_CERT_FILE or REDIS_TLS_KEY_FILE also enables TLS.
+// REDIS_TLS_INSECURE_SKIP_VERIFY is optional; defaults to false.
+// LOG_LEVEL is optional; defaults to "info" in prod and "debug" elsewhere.
+// LOG_FORMAT is optional; defaults to "text".
+// METRICS_ENABLED is optional; defaults to false.
+// CACHE_STATS_ENABLED is optional; defaults to false.
+// CACHE_ADMIN_TOKEN is optional; the cache admin API is disabled when unset.
+// RATE_LIMIT_PER_IP is optional; defaults to 0 (no per-IP limit).
+// RATE_LIMIT_PER_API_KEY is optional; defaults to 0 (API keys are limited per IP).
+// RATE_LIMIT_WINDOW is optional; defaults to 60 seconds.
+// RATE_LIMIT_POLICIES is optional; comma-separated group=perIP/perAPIKey/window
+// entries whose omitted fields default to the three settings above.
+// COMPRESSION_ENABLED is optional; defaults to true.
+// COMPRESSION_MIN_SIZE is optional; defaults to 1024 bytes.
+// CACHE_NAMESPACE and CACHE_KEY_VERSION are optional; keys are not prefixed when empty.
+// CACHE_DEBUG_KEYS is optional; defaults to false.
+// CACHE_FALLBACK_SIZE is optional; defaults to 1000 entries.
+// CACHE_BREAKER_THRESHOLD is optional; defaults to 5 consecutive failures.
+// CACHE_BREAKER_COOLDOWN is optional; defaults to 30 seconds.
+// CACHE_LOCAL_SIZE is optional; defaults to 0 (local tier disabled).
+// CACHE_LOCAL_TTL is optional; defaults to 30 seconds.
+// CACHE_STALE_TTL is optional; defaults to 0 (stale-while-revalidate disabled).
+// CACHE_HOT_KEY_THRESHOLD is optional; defaults to 0 (hot key refresh disabled).
+// CACHE_HOT_KEY_REFRESH_AHEAD is optional; defaults to 10 seconds.
+// CACHE_NEGATIVE_TTL is optional; defaults to 0 (negative caching disabled).
+// CACHE_TTL_JITTER is optional; defaults to 0 (no jitter).
+// CACHE_WARM_SLUGS is optional; comma-separated story slugs to warm on startup.
+// CACHE_WARM_TOP_N is optional; defaults to 0 (popularity-based warming disabled).
+// CACHE_WARM_INTERVAL is optional; defaults to 0 (warm only on startup).
+// CACHE_CODEC is optional; defaults to "json".
+// CACHE_COMPRESSION is optional; defaults to "none".
+// CACHE_COMPRESSION_THRESHOLD is optional; defaults to 4096 bytes.
+// CACHE_MAX_VALUE_SIZE is optional; defaults to 0 (no limit).
+// CACHE_ENCRYPTION_KEY is optional; sensitive values are stored in plain text when unset.
+// CACHE_ENCRYPTED_PREFIXES is optional; comma-separated key prefixes that are always encrypted.
+// MIGRATE_ON_START is optional; defaults to false.
+// STORY_STORE is optional; defaults to "postgres".
+// MONGO_URL is optional; required if STORY_STORE=mongo.
+// MONGO_DATABASE is optional; defaults to "go-story".
+// GRPC_PORT is optional; the gRPC server is not started when unset.
+// SEARCH_BACKEND is optional; defaults to "postgres" when STORY_STORE=postgres, search is disabled otherwise.
+// ELASTICSEARCH_URL is optional; required if SEARCH_BACKEND=elasticsearch.
+// ELASTICSEARCH_INDEX is optional; defaults to "stories".
+// ELASTICSEARCH_SYNC_INTERVAL is optional; defaults to 60 seconds, 0 disables the incremental sync.
+// VIEW_DEDUPE_WINDOW is optional; defaults to 1800 seconds, 0 counts every reported view.
+// VIEW_FLUSH_INTERVAL is optional; defaults to 60 seconds, 0 disables view counting.
+// SITE_URL is optional; feeds are not served when unset.
+// SITE_NAME is optional; defaults to "go-story".
+// SITE_DESCRIPTION is optional.
+// SITE_LANGUAGE is optional; defaults to "zh-TW".
+// SITE_IMAGE, SITE_TWITTER and SITE_LOGO are optional.
+// SITE_ARTICLE_TYPE is optional; defaults to "NewsArticle".
+// AMP_ENABLED is optional; defaults to false.
+// SITEMAP_INTERVAL is optional; defaults to 3600 seconds, 0 disables sitemaps.
+// WEBHOOK_DELIVERY_INTERVAL is optional; defaults to 10 seconds, 0 disables webhooks.
+// WEBHOOK_ADMIN_TOKEN is optional; the webhook admin API is disabled when unset.
+// PUBLISH_SCHEDULE_INTERVAL is optional; defaults to 30 seconds, 0 disables scheduled publishing.
+// WORKFLOW_WRITER_TOKEN, WORKFLOW_EDITOR_TOKEN and WORKFLOW_ADMIN_TOKEN are optional; the workflow API is disabled when all are unset and neither AUTH_JWKS_URL nor OIDC_ISSUER_URL is set.
+// AUDIT_ADMIN_TOKEN is optional; the audit log API is disabled when unset.
+// PREVIEW_SECRET is optional; story previews are disabled when unset.
+// PREVIEW_TOKEN_TTL is optional; defaults to 86400 seconds.
+// PREVIEW_URL is optional; defaults to the REST preview endpoint.
+// STORY_BODY_FORMAT is optional; defaults to "html".
+// MARKDOWN_EXTENSIONS is optional; comma-separated, defaults to "table,footnote".
+// HTML_ALLOWLIST is optional; defaults to data.DefaultHTMLAllowlist.
+// EXTERNAL_LINK_REL is optional; comma-separated, defaults to "noopener,nofollow".
+// OEMBED_CACHE_TTL is optional; defaults to 604800 seconds, 0 disables oEmbed resolution.
+// INSTAGRAM_OEMBED_TOKEN is optional; Instagram embeds are not resolved when unset.
+// SUMMARIZER_URL is optional; generated excerpts are extracted from the body when unset.
+// SUMMARIZER_API_KEY and SUMMARIZER_MODEL are optional.
+// MEDIA_STORAGE is optional; media uploads are disabled when unset.
+// MEDIA_LOCAL_DIR is optional; defaults to "./media".
+// MEDIA_PUBLIC_URL is optional; required when MEDIA_STORAGE=local.
+// MEDIA_MAX_SIZE is optional; defaults to 10485760 bytes.
+// MEDIA_S3_ENDPOINT, MEDIA_S3_REGION, MEDIA_BUCKET, MEDIA_ACCESS_KEY_ID and
+// MEDIA_SECRET_ACCESS_KEY are optional; the bucket and keys are required for s3 and gcs.
+// IMAGE_PROXY_SOURCES is optional; comma-separated URL prefixes.
+// IMAGE_CACHE_TTL is optional; defaults to 86400 seconds.
+// COMMENTS_ENABLED and COMMENT_AUTO_APPROVE are optional; default to false.
+// COMMENT_RATE_LIMIT is optional; defaults to 5 comments per COMMENT_RATE_WINDOW (default 60 seconds).
+// REACTIONS_ENABLED is optional; defaults to false.
+// REACTIONS is optional; comma-separated, defaults to like,clap.
+// REACTION_FLUSH_INTERVAL is optional; defaults to 60 seconds.
+// BOOKMARKS_ENABLED and READING_PROGRESS_ENABLED are optional; default to false.
+// NEWSLETTER_ADMIN_TOKEN, NEWSLETTER_SECTIONS, NEWSLETTER_WEBHOOK_URL and
+// NEWSLETTER_WEBHOOK_SECRET are optional; newsletters also need SITE_URL.
+// NEWSLETTER_STORIES_PER_SECTION is optional; defaults to 3.
+// PUSH_TAGS is optional; comma-separated, defaults to breaking.
+// PUSH_FCM_CREDENTIALS_FILE, PUSH_APNS_KEY_FILE and PUSH_WEBHOOK_URL are
+// optional and each enable a push provider; PUSH_APNS_KEY_ID,
+// PUSH_APNS_TEAM_ID and PUSH_APNS_TOPIC are required with PUSH_APNS_KEY_FILE.
+// PAYWALL_ENABLED and PAYWALL_TOKEN_SECRET are optional and each enable the
+// paywall; PAYWALL_MEMBERSHIP_CLAIM defaults to membership,
+// PAYWALL_METER_LIMIT to 3 and PAYWALL_METER_WINDOW to 2592000 seconds.
+// AUTH_JWKS_URL is optional and enables JWT authentication; AUTH_ISSUER and
+// AUTH_AUDIENCE (comma-separated) are optional, AUTH_CLOCK_SKEW defaults to
+// 60 seconds, AUTH_JWKS_CACHE_TTL to 3600 seconds and AUTH_ROLES_CLAIM to
+// roles.
+// API_KEYS_ENABLED is optional; defaults to false. API_KEY_ADMIN_TOKEN is
+// optional; the API key admin API is disabled when unset.
+// OIDC_ISSUER_URL is optional and enables admin login; OIDC_CLIENT_ID and
+// OIDC_REDIRECT_URL are required with it. OIDC_CLIENT_SECRET, OIDC_SCOPES
+// (comma-separated) and OIDC_GROUP_ROLES (comma-separated group=role pairs)
+// are optional, OIDC_GROUPS_CLAIM defaults to groups and ADMIN_SESSION_TTL
+// to 43200 seconds.
 func Load() (Config, error) {
 	_ = godotenv.Load()
 
 	cfg := Config{
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		StaticsHost: os.Getenv("STATICS_HOST"),
-		Port:        os.Getenv("PORT"),
-		GoEnv:       os.Getenv("GO_ENV"),
-		RedisURL:    os.Getenv("REDIS_URL"),
+		DatabaseURL:           os.Getenv("DATABASE_URL"),
+		StaticsHost:           os.Getenv("STATICS_HOST"),
+		Port:                  os.Getenv("PORT"),
+		GoEnv:                 os.Getenv("GO_ENV"),
+		RedisURL:              os.Getenv("REDIS_URL"),
+		RedisMode:             os.Getenv("REDIS_MODE"),
+		RedisSentinelMaster:   os.Getenv("REDIS_SENTINEL_MASTER"),
+		RedisSentinelPassword: os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		RedisUsername:         os.Getenv("REDIS_USERNAME"),
+		RedisPassword:         os.Getenv("REDIS_PASSWORD"),
+		RedisTLSCAFile:        os.Getenv("REDIS_TLS_CA_FILE"),
+		RedisTLSCertFile:      os.Getenv("REDIS_TLS_CERT_FILE"),
+		RedisTLSKeyFile:       os.Getenv("REDIS_TLS_KEY_FILE"),
+		LogLevel:              os.Getenv("LOG_LEVEL"),
+		LogFormat:             os.Getenv("LOG_FORMAT"),
+		CacheNamespace:        os.Getenv("CACHE_NAMESPACE"),
+		CacheKeyVersion:       os.Getenv("CACHE_KEY_VERSION"),
+		CacheCodec:            os.Getenv("CACHE_CODEC"),
+		CacheCompression:      os.Getenv("CACHE_COMPRESSION"),
+		CacheAdminToken:       os.Getenv("CACHE_ADMIN_TOKEN"),
+		CacheEncryptionKey:    os.Getenv("CACHE_ENCRYPTION_KEY"),
+		StoryStore:            os.Getenv("STORY_STORE"),
+		MongoURL:              os.Getenv("MONGO_URL"),
+		MongoDatabase:         os.Getenv("MONGO_DATABASE"),
+		GRPCPort:              os.Getenv("GRPC_PORT"),
+		SearchBackend:         os.Getenv("SEARCH_BACKEND"),
+		ElasticsearchURL:      os.Getenv("ELASTICSEARCH_URL"),
+		ElasticsearchIndex:    os.Getenv("ELASTICSEARCH_INDEX"),
+		SiteURL:               os.Getenv("SITE_URL"),
+		SiteName:              os.Getenv("SITE_NAME"),
+		SiteDescription:       os.Getenv("SITE_DESCRIPTION"),
+		SiteLanguage:          os.Getenv("SITE_LANGUAGE"),
+		SiteImage:             os.Getenv("SITE_IMAGE"),
+		SiteTwitter:           os.Getenv("SITE_TWITTER"),
+		SiteLogo:              os.Getenv("SITE_LOGO"),
+		SiteArticleType:       os.Getenv("SITE_ARTICLE_TYPE"),
+		WebhookAdminToken:     os.Getenv("WEBHOOK_ADMIN_TOKEN"),
+		WorkflowWriterToken:   os.Getenv("WORKFLOW_WRITER_TOKEN"),
+		WorkflowEditorToken:   os.Getenv("WORKFLOW_EDITOR_TOKEN"),
+		WorkflowAdminToken:    os.Getenv("WORKFLOW_ADMIN_TOKEN"),
+		AuditAdminToken:       os.Getenv("AUDIT_ADMIN_TOKEN"),
+		PreviewSecret:         os.Getenv("PREVIEW_SECRET"),
+		PreviewURL:            os.Getenv("PREVIEW_URL"),
+		StoryBodyFormat:       os.Getenv("STORY_BODY_FORMAT"),
+		HTMLAllowlist:         os.Getenv("HTML_ALLOWLIST"),
+		InstagramOEmbedToken:  os.Getenv("INSTAGRAM_OEMBED_TOKEN"),
+		SummarizerURL:         os.Getenv("SUMMARIZER_URL"),
+		SummarizerAPIKey:      os.Getenv("SUMMARIZER_API_KEY"),
+		SummarizerModel:       os.Getenv("SUMMARIZER_MODEL"),
+		MediaStorage:          os.Getenv("MEDIA_STORAGE"),
+		MediaLocalDir:         os.Getenv("MEDIA_LOCAL_DIR"),
+		MediaPublicURL:        os.Getenv("MEDIA_PUBLIC_URL"),
+		MediaS3Endpoint:       os.Getenv("MEDIA_S3_ENDPOINT"),
+		MediaS3Region:         os.Getenv("MEDIA_S3_REGION"),
+		MediaBucket:           os.Getenv("MEDIA_BUCKET"),
+		MediaAccessKeyID:      os.Getenv("MEDIA_ACCESS_KEY_ID"),
+		MediaSecretAccessKey:  os.Getenv("MEDIA_SECRET_ACCESS_KEY"),
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -66,6 +534,46 @@ func Load() (Config, error) {
 	if cfg.GoEnv == "" {
 		cfg.GoEnv = "dev"
 	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "debug"
+		if cfg.GoEnv == "prod" {
+			cfg.LogLevel = "info"
+		}
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "text"
+	}
+	if cfg.CacheCodec == "" {
+		cfg.CacheCodec = "json"
+	}
+	if cfg.CacheCompression == "" {
+		cfg.CacheCompression = "none"
+	}
+	if cfg.StoryStore == "" {
+		cfg.StoryStore = "postgres"
+	}
+	if cfg.MongoDatabase == "" {
+		cfg.MongoDatabase = "go-story"
+	}
+	if cfg.SearchBackend == "" && cfg.StoryStore == "postgres" {
+		cfg.SearchBackend = "postgres"
+	}
+	if cfg.ElasticsearchIndex == "" {
+		cfg.ElasticsearchIndex = "stories"
+	}
+	if cfg.SiteName == "" {
+		cfg.SiteName = "go-story"
+	}
+	switch cfg.SiteArticleType {
+	case "":
+		cfg.SiteArticleType = "NewsArticle"
+	case "NewsArticle", "Article", "BlogPosting":
+	default:
+		return Config{}, fmt.Errorf("invalid SITE_ARTICLE_TYPE value: %q", cfg.SiteArticleType)
+	}
+	if cfg.SiteLanguage == "" {
+		cfg.SiteLanguage = "zh-TW"
+	}
 
 	// 解析 REDIS_ENABLED，預設為 false
 	redisEnabledStr := os.Getenv("REDIS_ENABLED")
@@ -77,6 +585,603 @@ func Load() (Config, error) {
 		cfg.RedisEnabled = enabled
 	}
 
+	// 解析 REDIS_TLS_ENABLED，預設為 false；有設定憑證檔時自動啟用
+	redisTLSEnabledStr := os.Getenv("REDIS_TLS_ENABLED")
+	if redisTLSEnabledStr != "" {
+		enabled, err := strconv.ParseBool(redisTLSEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_TLS_ENABLED value: %v", err)
+		}
+		cfg.RedisTLSEnabled = enabled
+	}
+	if cfg.RedisTLSCAFile != "" || cfg.RedisTLSCertFile != "" || cfg.RedisTLSKeyFile != "" {
+		cfg.RedisTLSEnabled = true
+	}
+
+	// 解析 REDIS_TLS_INSECURE_SKIP_VERIFY，預設為 false
+	insecureStr := os.Getenv("REDIS_TLS_INSECURE_SKIP_VERIFY")
+	if insecureStr != "" {
+		insecure, err := strconv.ParseBool(insecureStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_TLS_INSECURE_SKIP_VERIFY value: %v", err)
+		}
+		cfg.RedisTLSInsecureSkipVerify = insecure
+	}
+
+	// 解析 AMP_ENABLED，預設為 false
+	ampEnabledStr := os.Getenv("AMP_ENABLED")
+	if ampEnabledStr != "" {
+		enabled, err := strconv.ParseBool(ampEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid AMP_ENABLED value: %v", err)
+		}
+		cfg.AMPEnabled = enabled
+	}
+
+	// 解析 METRICS_ENABLED，預設為 false
+	metricsEnabledStr := os.Getenv("METRICS_ENABLED")
+	if metricsEnabledStr != "" {
+		enabled, err := strconv.ParseBool(metricsEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid METRICS_ENABLED value: %v", err)
+		}
+		cfg.MetricsEnabled = enabled
+	}
+
+	// 解析 CACHE_STATS_ENABLED，預設為 false
+	cacheStatsEnabledStr := os.Getenv("CACHE_STATS_ENABLED")
+	if cacheStatsEnabledStr != "" {
+		enabled, err := strconv.ParseBool(cacheStatsEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_STATS_ENABLED value: %v", err)
+		}
+		cfg.CacheStatsEnabled = enabled
+	}
+
+	// 解析 MIGRATE_ON_START，預設為 false
+	migrateOnStartStr := os.Getenv("MIGRATE_ON_START")
+	if migrateOnStartStr != "" {
+		enabled, err := strconv.ParseBool(migrateOnStartStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid MIGRATE_ON_START value: %v", err)
+		}
+		cfg.MigrateOnStart = enabled
+	}
+
+	// 解析 CACHE_DEBUG_KEYS，預設為 false
+	debugKeysStr := os.Getenv("CACHE_DEBUG_KEYS")
+	if debugKeysStr != "" {
+		enabled, err := strconv.ParseBool(debugKeysStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_DEBUG_KEYS value: %v", err)
+		}
+		cfg.CacheDebugKeys = enabled
+	}
+
+	// 解析 RATE_LIMIT_PER_IP，預設為 0 (不限制)
+	perIPStr := os.Getenv("RATE_LIMIT_PER_IP")
+	if perIPStr != "" {
+		limit, err := strconv.Atoi(perIPStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_PER_IP value: %v", err)
+		}
+		cfg.RateLimitPerIP = limit
+	}
+
+	// 解析 RATE_LIMIT_PER_API_KEY，預設為 0 (改用 IP 限制)
+	perAPIKeyStr := os.Getenv("RATE_LIMIT_PER_API_KEY")
+	if perAPIKeyStr != "" {
+		limit, err := strconv.Atoi(perAPIKeyStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_PER_API_KEY value: %v", err)
+		}
+		cfg.RateLimitPerAPIKey = limit
+	}
+
+	// 解析 RATE_LIMIT_WINDOW，預設為 60 秒
+	rateLimitWindowStr := os.Getenv("RATE_LIMIT_WINDOW")
+	if rateLimitWindowStr != "" {
+		window, err := strconv.Atoi(rateLimitWindowStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_WINDOW value: %v", err)
+		}
+		cfg.RateLimitWindow = window
+	} else {
+		cfg.RateLimitWindow = 60
+	}
+
+	// 解析 RATE_LIMIT_POLICIES (逗號分隔的 group=perIP/perAPIKey/window)，group 名稱由 main 檢查
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMIT_POLICIES"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		group, limits, ok := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_POLICIES entry: %q (want group=perIP/perAPIKey/window)", entry)
+		}
+		policy := RateLimitPolicy{PerIP: cfg.RateLimitPerIP, PerAPIKey: cfg.RateLimitPerAPIKey, Window: cfg.RateLimitWindow}
+		fields := strings.Split(limits, "/")
+		if len(fields) > 3 {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_POLICIES entry: %q (want group=perIP/perAPIKey/window)", entry)
+		}
+		for i, dest := range []*int{&policy.PerIP, &policy.PerAPIKey, &policy.Window}[:len(fields)] {
+			field := strings.TrimSpace(fields[i])
+			if field == "" {
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 || (i == 2 && n == 0) {
+				return Config{}, fmt.Errorf("invalid RATE_LIMIT_POLICIES entry: %q", entry)
+			}
+			*dest = n
+		}
+		if cfg.RateLimitPolicies == nil {
+			cfg.RateLimitPolicies = map[string]RateLimitPolicy{}
+		}
+		cfg.RateLimitPolicies[group] = policy
+	}
+
+	// 解析 TRUSTED_PROXIES (逗號分隔的 IP 或 CIDR)
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return Config{}, fmt.Errorf("invalid TRUSTED_PROXIES entry: %q (want an IP or CIDR)", entry)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix.Masked())
+	}
+
+	// 解析 COMPRESSION_ENABLED，預設為 true
+	cfg.CompressionEnabled = true
+	compressionEnabledStr := os.Getenv("COMPRESSION_ENABLED")
+	if compressionEnabledStr != "" {
+		enabled, err := strconv.ParseBool(compressionEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid COMPRESSION_ENABLED value: %v", err)
+		}
+		cfg.CompressionEnabled = enabled
+	}
+
+	// 解析 COMPRESSION_MIN_SIZE，預設為 1024 bytes
+	compressionMinSizeStr := os.Getenv("COMPRESSION_MIN_SIZE")
+	if compressionMinSizeStr != "" {
+		minSize, err := strconv.Atoi(compressionMinSizeStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid COMPRESSION_MIN_SIZE value: %v", err)
+		}
+		if minSize <= 0 {
+			return Config{}, fmt.Errorf("invalid COMPRESSION_MIN_SIZE value: must be positive")
+		}
+		cfg.CompressionMinSize = minSize
+	} else {
+		cfg.CompressionMinSize = 1024
+	}
+
+	// 解析 ELASTICSEARCH_SYNC_INTERVAL，預設為 60 秒
+	syncIntervalStr := os.Getenv("ELASTICSEARCH_SYNC_INTERVAL")
+	if syncIntervalStr != "" {
+		interval, err := strconv.Atoi(syncIntervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ELASTICSEARCH_SYNC_INTERVAL value: %v", err)
+		}
+		cfg.ElasticsearchSyncInterval = interval
+	} else {
+		cfg.ElasticsearchSyncInterval = 60
+	}
+
+	// 解析 VIEW_DEDUPE_WINDOW，預設為 1800 秒
+	dedupeWindowStr := os.Getenv("VIEW_DEDUPE_WINDOW")
+	if dedupeWindowStr != "" {
+		window, err := strconv.Atoi(dedupeWindowStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid VIEW_DEDUPE_WINDOW value: %v", err)
+		}
+		cfg.ViewDedupeWindow = window
+	} else {
+		cfg.ViewDedupeWindow = 1800
+	}
+
+	// 解析 VIEW_FLUSH_INTERVAL，預設為 60 秒
+	flushIntervalStr := os.Getenv("VIEW_FLUSH_INTERVAL")
+	if flushIntervalStr != "" {
+		interval, err := strconv.Atoi(flushIntervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid VIEW_FLUSH_INTERVAL value: %v", err)
+		}
+		cfg.ViewFlushInterval = interval
+	} else {
+		cfg.ViewFlushInterval = 60
+	}
+
+	// 解析 SITEMAP_INTERVAL，預設為 3600 秒
+	sitemapIntervalStr := os.Getenv("SITEMAP_INTERVAL")
+	if sitemapIntervalStr != "" {
+		interval, err := strconv.Atoi(sitemapIntervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid SITEMAP_INTERVAL value: %v", err)
+		}
+		cfg.SitemapInterval = interval
+	} else {
+		cfg.SitemapInterval = 3600
+	}
+
+	// 解析 WEBHOOK_DELIVERY_INTERVAL，預設為 10 秒
+	webhookIntervalStr := os.Getenv("WEBHOOK_DELIVERY_INTERVAL")
+	if webhookIntervalStr != "" {
+		interval, err := strconv.Atoi(webhookIntervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_DELIVERY_INTERVAL value: %v", err)
+		}
+		cfg.WebhookDeliveryInterval = interval
+	} else {
+		cfg.WebhookDeliveryInterval = 10
+	}
+
+	// 解析 PUBLISH_SCHEDULE_INTERVAL，預設為 30 秒
+	scheduleIntervalStr := os.Getenv("PUBLISH_SCHEDULE_INTERVAL")
+	if scheduleIntervalStr != "" {
+		interval, err := strconv.Atoi(scheduleIntervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PUBLISH_SCHEDULE_INTERVAL value: %v", err)
+		}
+		cfg.PublishScheduleInterval = interval
+	} else {
+		cfg.PublishScheduleInterval = 30
+	}
+
+	// 解析 PREVIEW_TOKEN_TTL，預設為 86400 秒
+	previewTTLStr := os.Getenv("PREVIEW_TOKEN_TTL")
+	if previewTTLStr != "" {
+		ttl, err := strconv.Atoi(previewTTLStr)
+		if err != nil || ttl <= 0 {
+			return Config{}, fmt.Errorf("invalid PREVIEW_TOKEN_TTL value: %q", previewTTLStr)
+		}
+		cfg.PreviewTokenTTL = ttl
+	} else {
+		cfg.PreviewTokenTTL = 86400
+	}
+
+	// 解析 OEMBED_CACHE_TTL，預設為 604800 秒 (7 天)
+	oembedTTLStr := os.Getenv("OEMBED_CACHE_TTL")
+	if oembedTTLStr != "" {
+		ttl, err := strconv.Atoi(oembedTTLStr)
+		if err != nil || ttl < 0 {
+			return Config{}, fmt.Errorf("invalid OEMBED_CACHE_TTL value: %q", oembedTTLStr)
+		}
+		cfg.OEmbedCacheTTL = ttl
+	} else {
+		cfg.OEmbedCacheTTL = 604800
+	}
+
+	if cfg.MediaLocalDir == "" {
+		cfg.MediaLocalDir = "./media"
+	}
+	// 解析 MEDIA_MAX_SIZE，預設為 10485760 bytes (10 MB)
+	mediaMaxSizeStr := os.Getenv("MEDIA_MAX_SIZE")
+	if mediaMaxSizeStr != "" {
+		size, err := strconv.ParseInt(mediaMaxSizeStr, 10, 64)
+		if err != nil || size <= 0 {
+			return Config{}, fmt.Errorf("invalid MEDIA_MAX_SIZE value: %q", mediaMaxSizeStr)
+		}
+		cfg.MediaMaxSize = size
+	} else {
+		cfg.MediaMaxSize = 10485760
+	}
+
+	// 解析 IMAGE_PROXY_SOURCES (逗號分隔)
+	for _, source := range strings.Split(os.Getenv("IMAGE_PROXY_SOURCES"), ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			cfg.ImageProxySources = append(cfg.ImageProxySources, source)
+		}
+	}
+	// 解析 IMAGE_CACHE_TTL，預設為 86400 秒 (1 天)
+	imageTTLStr := os.Getenv("IMAGE_CACHE_TTL")
+	if imageTTLStr != "" {
+		ttl, err := strconv.Atoi(imageTTLStr)
+		if err != nil || ttl < 1 {
+			return Config{}, fmt.Errorf("invalid IMAGE_CACHE_TTL value: %q", imageTTLStr)
+		}
+		cfg.ImageCacheTTL = ttl
+	} else {
+		cfg.ImageCacheTTL = 86400
+	}
+
+	// 解析 COMMENTS_ENABLED，預設為 false
+	commentsEnabledStr := os.Getenv("COMMENTS_ENABLED")
+	if commentsEnabledStr != "" {
+		enabled, err := strconv.ParseBool(commentsEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid COMMENTS_ENABLED value: %v", err)
+		}
+		cfg.CommentsEnabled = enabled
+	}
+	// 解析 COMMENT_AUTO_APPROVE，預設為 false
+	commentAutoApproveStr := os.Getenv("COMMENT_AUTO_APPROVE")
+	if commentAutoApproveStr != "" {
+		enabled, err := strconv.ParseBool(commentAutoApproveStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid COMMENT_AUTO_APPROVE value: %v", err)
+		}
+		cfg.CommentAutoApprove = enabled
+	}
+	// 解析 COMMENT_RATE_LIMIT，預設為 5 則
+	commentRateLimitStr := os.Getenv("COMMENT_RATE_LIMIT")
+	if commentRateLimitStr != "" {
+		limit, err := strconv.Atoi(commentRateLimitStr)
+		if err != nil || limit < 0 {
+			return Config{}, fmt.Errorf("invalid COMMENT_RATE_LIMIT value: %q", commentRateLimitStr)
+		}
+		cfg.CommentRateLimit = limit
+	} else {
+		cfg.CommentRateLimit = 5
+	}
+	// 解析 COMMENT_RATE_WINDOW，預設為 60 秒
+	commentRateWindowStr := os.Getenv("COMMENT_RATE_WINDOW")
+	if commentRateWindowStr != "" {
+		window, err := strconv.Atoi(commentRateWindowStr)
+		if err != nil || window < 1 {
+			return Config{}, fmt.Errorf("invalid COMMENT_RATE_WINDOW value: %q", commentRateWindowStr)
+		}
+		cfg.CommentRateWindow = window
+	} else {
+		cfg.CommentRateWindow = 60
+	}
+
+	// 解析 REACTIONS_ENABLED，預設為 false
+	reactionsEnabledStr := os.Getenv("REACTIONS_ENABLED")
+	if reactionsEnabledStr != "" {
+		enabled, err := strconv.ParseBool(reactionsEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REACTIONS_ENABLED value: %v", err)
+		}
+		cfg.ReactionsEnabled = enabled
+	}
+	// 解析 REACTIONS (逗號分隔)，未設定時由 data.NewReactionService 套用預設值
+	for _, reaction := range strings.Split(os.Getenv("REACTIONS"), ",") {
+		if reaction = strings.TrimSpace(reaction); reaction != "" {
+			cfg.Reactions = append(cfg.Reactions, reaction)
+		}
+	}
+	// 解析 REACTION_FLUSH_INTERVAL，預設為 60 秒
+	reactionFlushIntervalStr := os.Getenv("REACTION_FLUSH_INTERVAL")
+	if reactionFlushIntervalStr != "" {
+		interval, err := strconv.Atoi(reactionFlushIntervalStr)
+		if err != nil || interval < 1 {
+			return Config{}, fmt.Errorf("invalid REACTION_FLUSH_INTERVAL value: %q", reactionFlushIntervalStr)
+		}
+		cfg.ReactionFlushInterval = interval
+	} else {
+		cfg.ReactionFlushInterval = 60
+	}
+
+	// 解析 BOOKMARKS_ENABLED，預設為 false
+	bookmarksEnabledStr := os.Getenv("BOOKMARKS_ENABLED")
+	if bookmarksEnabledStr != "" {
+		enabled, err := strconv.ParseBool(bookmarksEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid BOOKMARKS_ENABLED value: %v", err)
+		}
+		cfg.BookmarksEnabled = enabled
+	}
+	// 解析 READING_PROGRESS_ENABLED，預設為 false
+	readingProgressEnabledStr := os.Getenv("READING_PROGRESS_ENABLED")
+	if readingProgressEnabledStr != "" {
+		enabled, err := strconv.ParseBool(readingProgressEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid READING_PROGRESS_ENABLED value: %v", err)
+		}
+		cfg.ReadingProgressEnabled = enabled
+	}
+
+	cfg.NewsletterAdminToken = os.Getenv("NEWSLETTER_ADMIN_TOKEN")
+	cfg.NewsletterWebhookURL = os.Getenv("NEWSLETTER_WEBHOOK_URL")
+	cfg.NewsletterWebhookSecret = os.Getenv("NEWSLETTER_WEBHOOK_SECRET")
+	// 解析 NEWSLETTER_SECTIONS (逗號分隔)
+	for _, section := range strings.Split(os.Getenv("NEWSLETTER_SECTIONS"), ",") {
+		if section = strings.TrimSpace(section); section != "" {
+			cfg.NewsletterSections = append(cfg.NewsletterSections, section)
+		}
+	}
+	// 解析 NEWSLETTER_STORIES_PER_SECTION，預設為 3 篇
+	newsletterStoriesStr := os.Getenv("NEWSLETTER_STORIES_PER_SECTION")
+	if newsletterStoriesStr != "" {
+		n, err := strconv.Atoi(newsletterStoriesStr)
+		if err != nil || n < 1 || n > 20 {
+			return Config{}, fmt.Errorf("invalid NEWSLETTER_STORIES_PER_SECTION value: %q", newsletterStoriesStr)
+		}
+		cfg.NewsletterStoriesPerSection = n
+	} else {
+		cfg.NewsletterStoriesPerSection = 3
+	}
+
+	cfg.PushFCMCredentialsFile = os.Getenv("PUSH_FCM_CREDENTIALS_FILE")
+	cfg.PushFCMTopic = os.Getenv("PUSH_FCM_TOPIC")
+	cfg.PushAPNsKeyFile = os.Getenv("PUSH_APNS_KEY_FILE")
+	cfg.PushAPNsKeyID = os.Getenv("PUSH_APNS_KEY_ID")
+	cfg.PushAPNsTeamID = os.Getenv("PUSH_APNS_TEAM_ID")
+	cfg.PushAPNsTopic = os.Getenv("PUSH_APNS_TOPIC")
+	cfg.PushWebhookURL = os.Getenv("PUSH_WEBHOOK_URL")
+	cfg.PushWebhookSecret = os.Getenv("PUSH_WEBHOOK_SECRET")
+	// 解析 PUSH_TAGS (逗號分隔)，未設定時由 data.NewPushDispatcher 套用預設值
+	for _, tag := range strings.Split(os.Getenv("PUSH_TAGS"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cfg.PushTags = append(cfg.PushTags, tag)
+		}
+	}
+	if cfg.PushAPNsKeyFile != "" && (cfg.PushAPNsKeyID == "" || cfg.PushAPNsTeamID == "" || cfg.PushAPNsTopic == "") {
+		return Config{}, fmt.Errorf("PUSH_APNS_KEY_ID, PUSH_APNS_TEAM_ID and PUSH_APNS_TOPIC are required with PUSH_APNS_KEY_FILE")
+	}
+	// 解析 PUSH_APNS_SANDBOX，預設為 false
+	pushAPNsSandboxStr := os.Getenv("PUSH_APNS_SANDBOX")
+	if pushAPNsSandboxStr != "" {
+		sandbox, err := strconv.ParseBool(pushAPNsSandboxStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PUSH_APNS_SANDBOX value: %v", err)
+		}
+		cfg.PushAPNsSandbox = sandbox
+	}
+
+	// 解析 PAYWALL_ENABLED
+	paywallEnabledStr := os.Getenv("PAYWALL_ENABLED")
+	if paywallEnabledStr != "" {
+		enabled, err := strconv.ParseBool(paywallEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PAYWALL_ENABLED value: %v", err)
+		}
+		cfg.PaywallEnabled = enabled
+	}
+	cfg.PaywallTokenSecret = os.Getenv("PAYWALL_TOKEN_SECRET")
+	cfg.PaywallMembershipClaim = os.Getenv("PAYWALL_MEMBERSHIP_CLAIM")
+	// 解析 PAYWALL_METER_LIMIT，未設定時由 data.NewPaywall 套用預設值
+	meterLimitStr := os.Getenv("PAYWALL_METER_LIMIT")
+	if meterLimitStr != "" {
+		limit, err := strconv.Atoi(meterLimitStr)
+		if err != nil || limit <= 0 {
+			return Config{}, fmt.Errorf("invalid PAYWALL_METER_LIMIT value: %q", meterLimitStr)
+		}
+		cfg.PaywallMeterLimit = limit
+	}
+	// 解析 PAYWALL_METER_WINDOW (秒)，未設定時由 data.NewPaywall 套用預設值
+	meterWindowStr := os.Getenv("PAYWALL_METER_WINDOW")
+	if meterWindowStr != "" {
+		window, err := strconv.Atoi(meterWindowStr)
+		if err != nil || window <= 0 {
+			return Config{}, fmt.Errorf("invalid PAYWALL_METER_WINDOW value: %q", meterWindowStr)
+		}
+		cfg.PaywallMeterWindow = window
+	}
+
+	cfg.AuthJWKSURL = os.Getenv("AUTH_JWKS_URL")
+	cfg.AuthIssuer = os.Getenv("AUTH_ISSUER")
+	cfg.AuthRolesClaim = os.Getenv("AUTH_ROLES_CLAIM")
+	// 解析 AUTH_AUDIENCE (逗號分隔)
+	for _, aud := range strings.Split(os.Getenv("AUTH_AUDIENCE"), ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			cfg.AuthAudience = append(cfg.AuthAudience, aud)
+		}
+	}
+	// 解析 AUTH_CLOCK_SKEW (秒)，未設定時由 data.NewJWTVerifier 套用預設值
+	clockSkewStr := os.Getenv("AUTH_CLOCK_SKEW")
+	if clockSkewStr != "" {
+		skew, err := strconv.Atoi(clockSkewStr)
+		if err != nil || skew < 0 {
+			return Config{}, fmt.Errorf("invalid AUTH_CLOCK_SKEW value: %q", clockSkewStr)
+		}
+		cfg.AuthClockSkew = skew
+	}
+	// 解析 AUTH_JWKS_CACHE_TTL (秒)，未設定時由 data.NewJWTVerifier 套用預設值
+	jwksTTLStr := os.Getenv("AUTH_JWKS_CACHE_TTL")
+	if jwksTTLStr != "" {
+		ttl, err := strconv.Atoi(jwksTTLStr)
+		if err != nil || ttl <= 0 {
+			return Config{}, fmt.Errorf("invalid AUTH_JWKS_CACHE_TTL value: %q", jwksTTLStr)
+		}
+		cfg.AuthJWKSCacheTTL = ttl
+	}
+
+	// 解析 API_KEYS_ENABLED
+	apiKeysEnabledStr := os.Getenv("API_KEYS_ENABLED")
+	if apiKeysEnabledStr != "" {
+		enabled, err := strconv.ParseBool(apiKeysEnabledStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid API_KEYS_ENABLED value: %v", err)
+		}
+		cfg.APIKeysEnabled = enabled
+	}
+	cfg.APIKeyAdminToken = os.Getenv("API_KEY_ADMIN_TOKEN")
+
+	cfg.OIDCIssuerURL = os.Getenv("OIDC_ISSUER_URL")
+	cfg.OIDCClientID = os.Getenv("OIDC_CLIENT_ID")
+	cfg.OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	cfg.OIDCRedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+	cfg.OIDCGroupsClaim = os.Getenv("OIDC_GROUPS_CLAIM")
+	if cfg.OIDCIssuerURL != "" && (cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "") {
+		return Config{}, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER_URL")
+	}
+	// 解析 OIDC_SCOPES (逗號分隔)
+	for _, scope := range strings.Split(os.Getenv("OIDC_SCOPES"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			cfg.OIDCScopes = append(cfg.OIDCScopes, scope)
+		}
+	}
+	// 解析 OIDC_GROUP_ROLES (逗號分隔的 group=role)，角色名稱由 data.NewOIDCClient 檢查
+	for _, pair := range strings.Split(os.Getenv("OIDC_GROUP_ROLES"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return Config{}, fmt.Errorf("invalid OIDC_GROUP_ROLES entry: %q (want group=role)", pair)
+		}
+		if cfg.OIDCGroupRoles == nil {
+			cfg.OIDCGroupRoles = map[string]string{}
+		}
+		cfg.OIDCGroupRoles[group] = role
+	}
+	// 解析 ADMIN_SESSION_TTL (秒)，未設定時由 data.NewAdminSessionService 套用預設值
+	sessionTTLStr := os.Getenv("ADMIN_SESSION_TTL")
+	if sessionTTLStr != "" {
+		ttl, err := strconv.Atoi(sessionTTLStr)
+		if err != nil || ttl <= 0 {
+			return Config{}, fmt.Errorf("invalid ADMIN_SESSION_TTL value: %q", sessionTTLStr)
+		}
+		cfg.AdminSessionTTL = ttl
+	}
+
+	// 解析 REDIS_SENTINEL_ADDRS (逗號分隔)
+	for _, addr := range strings.Split(os.Getenv("REDIS_SENTINEL_ADDRS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.RedisSentinelAddrs = append(cfg.RedisSentinelAddrs, addr)
+		}
+	}
+
+	// 解析 MARKDOWN_EXTENSIONS (逗號分隔)，未設定時由 data.NewBodyRenderer 套用預設值
+	for _, ext := range strings.Split(os.Getenv("MARKDOWN_EXTENSIONS"), ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			cfg.MarkdownExtensions = append(cfg.MarkdownExtensions, ext)
+		}
+	}
+	for _, rel := range strings.Split(os.Getenv("EXTERNAL_LINK_REL"), ",") {
+		if rel = strings.TrimSpace(rel); rel != "" {
+			cfg.ExternalLinkRel = append(cfg.ExternalLinkRel, rel)
+		}
+	}
+
+	// 解析 CACHE_WARM_SLUGS (逗號分隔)
+	for _, slug := range strings.Split(os.Getenv("CACHE_WARM_SLUGS"), ",") {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			cfg.CacheWarmSlugs = append(cfg.CacheWarmSlugs, slug)
+		}
+	}
+
+	// 解析 CACHE_WARM_TOP_N，預設為 0 (不依熱門度預熱)
+	warmTopNStr := os.Getenv("CACHE_WARM_TOP_N")
+	if warmTopNStr != "" {
+		n, err := strconv.Atoi(warmTopNStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_WARM_TOP_N value: %v", err)
+		}
+		cfg.CacheWarmTopN = n
+	}
+
+	// 解析 CACHE_WARM_INTERVAL，預設為 0 (只在啟動時預熱)
+	warmIntervalStr := os.Getenv("CACHE_WARM_INTERVAL")
+	if warmIntervalStr != "" {
+		interval, err := strconv.Atoi(warmIntervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_WARM_INTERVAL value: %v", err)
+		}
+		cfg.CacheWarmInterval = interval
+	}
+
 	// 解析 REDIS_TTL，預設為 3600 秒
 	redisTTLStr := os.Getenv("REDIS_TTL")
 	if redisTTLStr != "" {
@@ -89,6 +1194,188 @@ func Load() (Config, error) {
 		cfg.RedisTTL = 3600 // 預設 1 小時
 	}
 
+	// 解析 REDIS_DIAL_TIMEOUT_MS，0 表示使用預設值
+	dialTimeoutStr := os.Getenv("REDIS_DIAL_TIMEOUT_MS")
+	if dialTimeoutStr != "" {
+		ms, err := strconv.Atoi(dialTimeoutStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_DIAL_TIMEOUT_MS value: %v", err)
+		}
+		cfg.RedisDialTimeoutMS = ms
+	}
+
+	// 解析 REDIS_READ_TIMEOUT_MS，0 表示使用預設值
+	readTimeoutStr := os.Getenv("REDIS_READ_TIMEOUT_MS")
+	if readTimeoutStr != "" {
+		ms, err := strconv.Atoi(readTimeoutStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_READ_TIMEOUT_MS value: %v", err)
+		}
+		cfg.RedisReadTimeoutMS = ms
+	}
+
+	// 解析 REDIS_WRITE_TIMEOUT_MS，0 表示與讀取逾時相同
+	writeTimeoutStr := os.Getenv("REDIS_WRITE_TIMEOUT_MS")
+	if writeTimeoutStr != "" {
+		ms, err := strconv.Atoi(writeTimeoutStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_WRITE_TIMEOUT_MS value: %v", err)
+		}
+		cfg.RedisWriteTimeoutMS = ms
+	}
+
+	// 解析 CACHE_OP_TIMEOUT_MS，預設為 0 (不限制)
+	opTimeoutStr := os.Getenv("CACHE_OP_TIMEOUT_MS")
+	if opTimeoutStr != "" {
+		ms, err := strconv.Atoi(opTimeoutStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_OP_TIMEOUT_MS value: %v", err)
+		}
+		cfg.CacheOpTimeoutMS = ms
+	}
+
+	// 解析 CACHE_FALLBACK_SIZE，預設為 1000 筆
+	fallbackSizeStr := os.Getenv("CACHE_FALLBACK_SIZE")
+	if fallbackSizeStr != "" {
+		size, err := strconv.Atoi(fallbackSizeStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_FALLBACK_SIZE value: %v", err)
+		}
+		cfg.CacheFallbackSize = size
+	} else {
+		cfg.CacheFallbackSize = 1000
+	}
+
+	// 解析 CACHE_BREAKER_THRESHOLD，預設為 5 次
+	breakerThresholdStr := os.Getenv("CACHE_BREAKER_THRESHOLD")
+	if breakerThresholdStr != "" {
+		threshold, err := strconv.Atoi(breakerThresholdStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_BREAKER_THRESHOLD value: %v", err)
+		}
+		cfg.CacheBreakerThreshold = threshold
+	} else {
+		cfg.CacheBreakerThreshold = 5
+	}
+
+	// 解析 CACHE_BREAKER_COOLDOWN，預設為 30 秒
+	breakerCooldownStr := os.Getenv("CACHE_BREAKER_COOLDOWN")
+	if breakerCooldownStr != "" {
+		cooldown, err := strconv.Atoi(breakerCooldownStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_BREAKER_COOLDOWN value: %v", err)
+		}
+		cfg.CacheBreakerCooldown = cooldown
+	} else {
+		cfg.CacheBreakerCooldown = 30
+	}
+
+	// 解析 CACHE_LOCAL_SIZE，預設為 0 (不啟用本地層)
+	localSizeStr := os.Getenv("CACHE_LOCAL_SIZE")
+	if localSizeStr != "" {
+		size, err := strconv.Atoi(localSizeStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_LOCAL_SIZE value: %v", err)
+		}
+		cfg.CacheLocalSize = size
+	}
+
+	// 解析 CACHE_LOCAL_TTL，預設為 30 秒
+	localTTLStr := os.Getenv("CACHE_LOCAL_TTL")
+	if localTTLStr != "" {
+		ttl, err := strconv.Atoi(localTTLStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_LOCAL_TTL value: %v", err)
+		}
+		cfg.CacheLocalTTL = ttl
+	} else {
+		cfg.CacheLocalTTL = 30
+	}
+
+	// 解析 CACHE_STALE_TTL，預設為 0 (不啟用 stale-while-revalidate)
+	staleTTLStr := os.Getenv("CACHE_STALE_TTL")
+	if staleTTLStr != "" {
+		ttl, err := strconv.Atoi(staleTTLStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_STALE_TTL value: %v", err)
+		}
+		cfg.CacheStaleTTL = ttl
+	}
+
+	// 解析 CACHE_HOT_KEY_THRESHOLD，預設為 0 (不提前更新熱門 key)
+	hotKeyThresholdStr := os.Getenv("CACHE_HOT_KEY_THRESHOLD")
+	if hotKeyThresholdStr != "" {
+		threshold, err := strconv.Atoi(hotKeyThresholdStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_HOT_KEY_THRESHOLD value: %v", err)
+		}
+		cfg.CacheHotKeyThreshold = threshold
+	}
+
+	// 解析 CACHE_HOT_KEY_REFRESH_AHEAD，預設為 10 秒
+	refreshAheadStr := os.Getenv("CACHE_HOT_KEY_REFRESH_AHEAD")
+	if refreshAheadStr != "" {
+		ahead, err := strconv.Atoi(refreshAheadStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_HOT_KEY_REFRESH_AHEAD value: %v", err)
+		}
+		cfg.CacheHotKeyRefreshAhead = ahead
+	} else {
+		cfg.CacheHotKeyRefreshAhead = 10
+	}
+
+	// 解析 CACHE_NEGATIVE_TTL，預設為 0 (不啟用 negative caching)
+	negativeTTLStr := os.Getenv("CACHE_NEGATIVE_TTL")
+	if negativeTTLStr != "" {
+		ttl, err := strconv.Atoi(negativeTTLStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_NEGATIVE_TTL value: %v", err)
+		}
+		cfg.CacheNegativeTTL = ttl
+	}
+
+	// 解析 CACHE_TTL_JITTER，預設為 0 (不浮動)
+	ttlJitterStr := os.Getenv("CACHE_TTL_JITTER")
+	if ttlJitterStr != "" {
+		jitter, err := strconv.ParseFloat(ttlJitterStr, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_TTL_JITTER value: %v", err)
+		}
+		if jitter < 0 || jitter >= 1 {
+			return Config{}, fmt.Errorf("invalid CACHE_TTL_JITTER value: must be in [0, 1)")
+		}
+		cfg.CacheTTLJitter = jitter
+	}
+
+	// 解析 CACHE_COMPRESSION_THRESHOLD，預設為 4096 bytes
+	compressionThresholdStr := os.Getenv("CACHE_COMPRESSION_THRESHOLD")
+	if compressionThresholdStr != "" {
+		threshold, err := strconv.Atoi(compressionThresholdStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_COMPRESSION_THRESHOLD value: %v", err)
+		}
+		cfg.CacheCompressionThreshold = threshold
+	} else {
+		cfg.CacheCompressionThreshold = 4096
+	}
+
+	// 解析 CACHE_MAX_VALUE_SIZE，預設為 0 (不限制)
+	maxValueSizeStr := os.Getenv("CACHE_MAX_VALUE_SIZE")
+	if maxValueSizeStr != "" {
+		size, err := strconv.Atoi(maxValueSizeStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_MAX_VALUE_SIZE value: %v", err)
+		}
+		cfg.CacheMaxValueSize = size
+	}
+
+	// 解析 CACHE_ENCRYPTED_PREFIXES (逗號分隔)
+	for _, prefix := range strings.Split(os.Getenv("CACHE_ENCRYPTED_PREFIXES"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			cfg.CacheEncryptedPrefixes = append(cfg.CacheEncryptedPrefixes, prefix)
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -0,0 +1,378 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrSessionNotFound is returned by AdminSessionService.Session for a
+	// session that was never issued, has expired or was ended.
+	ErrSessionNotFound = errors.New("session not found or expired")
+	// ErrInvalidLogin is returned (wrapped) by CompleteLogin for an unknown,
+	// expired or already used login state.
+	ErrInvalidLogin = errors.New("invalid or expired login")
+	// ErrLoginForbidden is returned (wrapped) by CompleteLogin when the
+	// user's groups grant no role above reader.
+	ErrLoginForbidden = errors.New("no editorial role")
+)
+
+// 管理介面登入的設定：session 與登入流程的 token 格式、登入流程與預設 session 的有效期間，
+// 以及身分提供者無法連線時延後重新檢查身分的時間
+const (
+	adminSessionPrefix      = "gss_"
+	adminSessionRandomBytes = 32
+	defaultAdminSessionTTL  = 12 * time.Hour
+	oidcLoginTTL            = 10 * time.Minute
+	oidcRetryRefreshAfter   = time.Minute
+)
+
+// adminSessionColumns 為查詢 admin_sessions 時的欄位順序，需與 scanAdminSession 一致
+const adminSessionColumns = `id, subject, email, name, groups, role, created_at, refresh_at, expires_at`
+
+// AdminSession is a signed-in editorial user of the admin surface.
+type AdminSession struct {
+	ID      string    `json:"id"`
+	Subject string    `json:"sub"`
+	Email   string    `json:"email,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Groups  []string  `json:"groups"`
+	Role    StoryRole `json:"role"`
+	// RefreshAt is when the user's identity is next checked with the
+	// identity provider; the session ends when the check fails or the
+	// user lost their role.
+	RefreshAt time.Time `json:"refreshAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Token 只在 CompleteLogin 時設定，不會儲存
+	Token string `json:"-"`
+}
+
+// Principal returns the principal of requests made with the session, whose
+// only role is the session's story role.
+func (s *AdminSession) Principal() *Principal {
+	return &Principal{Subject: s.Subject, Email: s.Email, Name: s.Name, Roles: []string{string(s.Role)}, ExpiresAt: s.ExpiresAt}
+}
+
+// AdminSessionService signs editorial users in to the admin surface with an
+// OIDC provider and issues sessions for them. Sessions are opaque random
+// tokens stored hashed in Postgres with the user's groups and role, and
+// last for the session TTL. When the provider issued a refresh token, the
+// user's identity is refreshed whenever the provider's tokens expire, so
+// removing a user from their groups or disabling them at the provider ends
+// their session; without one the role is kept until the session expires.
+type AdminSessionService struct {
+	db    *sql.DB
+	oidc  *OIDCClient
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+// NewAdminSessionService returns a service signing users in with oidc and
+// storing sessions in db (see internal/data/migrations). Sessions last for
+// ttl; zero uses 12 hours.
+func NewAdminSessionService(db *sql.DB, oidc *OIDCClient, ttl time.Duration) *AdminSessionService {
+	if ttl <= 0 {
+		ttl = defaultAdminSessionTTL
+	}
+	return &AdminSessionService{db: db, oidc: oidc, ttl: ttl}
+}
+
+// TTL returns how long sessions last.
+func (s *AdminSessionService) TTL() time.Duration {
+	return s.ttl
+}
+
+// SecureCookies reports whether the admin surface is served over HTTPS, as
+// the redirect URL registered with the provider is, so session cookies
+// must be Secure.
+func (s *AdminSessionService) SecureCookies() bool {
+	return strings.HasPrefix(s.oidc.RedirectURL(), "https://")
+}
+
+// LogoutURL returns the provider's logout URL, or "" when it has none.
+func (s *AdminSessionService) LogoutURL(ctx context.Context) string {
+	return s.oidc.EndSessionURL(ctx)
+}
+
+// hashSessionToken 回傳 session 或登入流程的 token 儲存於資料庫中的 hash
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken 回傳 n bytes 的隨機值，以 base64url 編碼
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// BeginLogin starts a login that returns to returnTo, and returns its
+// state, to bind to the browser, and the provider's authorization URL to
+// redirect the user to. The state expires after 10 minutes.
+func (s *AdminSessionService) BeginLogin(ctx context.Context, returnTo string) (state, authURL string, err error) {
+	var nonce, codeVerifier string
+	for _, token := range []*string{&state, &nonce, &codeVerifier} {
+		if *token, err = randomToken(32); err != nil {
+			return "", "", fmt.Errorf("generate login state: %w", err)
+		}
+	}
+	authURL, err = s.oidc.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	// 順便清除過期的登入流程與 session
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM oidc_logins WHERE expires_at < now()`); err != nil {
+		slog.Warn("failed to delete expired logins", "error", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM admin_sessions WHERE expires_at < now()`); err != nil {
+		slog.Warn("failed to delete expired sessions", "error", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO oidc_logins (state_hash, nonce, code_verifier, return_to, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		hashSessionToken(state), nonce, codeVerifier, returnTo, time.Now().Add(oidcLoginTTL))
+	if err != nil {
+		return "", "", fmt.Errorf("create login: %w", err)
+	}
+	return state, authURL, nil
+}
+
+// CompleteLogin finishes the login with state by redeeming the provider's
+// code, and issues a session for the user, with Token set to the session
+// token. It also returns where the login was started from. A state can only
+// be used once.
+func (s *AdminSessionService) CompleteLogin(ctx context.Context, state, code string) (*AdminSession, string, error) {
+	var nonce, codeVerifier, returnTo string
+	err := func() error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		return s.db.QueryRowContext(ctx, `DELETE FROM oidc_logins WHERE state_hash = $1 AND expires_at > now() RETURNING nonce, code_verifier, return_to`,
+			hashSessionToken(state)).Scan(&nonce, &codeVerifier, &returnTo)
+	}()
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrInvalidLogin
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("get login: %w", err)
+	}
+
+	identity, err := s.oidc.Exchange(ctx, code, nonce, codeVerifier)
+	if err != nil {
+		return nil, "", err
+	}
+	if !identity.Role.Includes(StoryRoleAuthor) {
+		return nil, "", fmt.Errorf("%w: %s is not in a group granting access", ErrLoginForbidden, identity.Principal.Subject)
+	}
+	session, err := s.issue(ctx, identity)
+	if err != nil {
+		return nil, "", err
+	}
+	slog.Info("admin session issued", "session", session.ID, "subject", session.Subject, "role", session.Role)
+	return session, returnTo, nil
+}
+
+// issue 建立 identity 的 session
+func (s *AdminSessionService) issue(ctx context.Context, identity *OIDCIdentity) (*AdminSession, error) {
+	buf := make([]byte, adminSessionRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate session: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	p := identity.Principal
+	session := &AdminSession{
+		ID:        newUUID(),
+		Subject:   p.Subject,
+		Email:     p.Email,
+		Name:      p.Name,
+		Groups:    p.Roles,
+		Role:      identity.Role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+		Token:     adminSessionPrefix + hex.EncodeToString(buf),
+	}
+	if session.Groups == nil {
+		session.Groups = []string{}
+	}
+	session.RefreshAt = session.nextRefresh(identity)
+	groups, err := json.Marshal(session.Groups)
+	if err != nil {
+		return nil, fmt.Errorf("marshal groups: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO admin_sessions (id, token_hash, subject, email, name, groups, role, refresh_token, created_at, refresh_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		session.ID, hashSessionToken(session.Token), session.Subject, session.Email, session.Name, string(groups), string(session.Role), identity.RefreshToken, session.CreatedAt, session.RefreshAt, session.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	return session, nil
+}
+
+// nextRefresh 回傳下次向身分提供者檢查身分的時間；沒有 refresh token 時不檢查 (即 session 到期時)
+func (s *AdminSession) nextRefresh(identity *OIDCIdentity) time.Time {
+	if identity.RefreshToken == "" || identity.ExpiresAt.IsZero() || identity.ExpiresAt.After(s.ExpiresAt) {
+		return s.ExpiresAt
+	}
+	return identity.ExpiresAt
+}
+
+// Session returns the session token was issued as, refreshing the user's
+// identity with the provider when due, or ErrSessionNotFound when it was
+// never issued, has expired or was ended. When the provider cannot be
+// reached the session is kept and the refresh retried a minute later.
+func (s *AdminSessionService) Session(ctx context.Context, token string) (*AdminSession, error) {
+	if !strings.HasPrefix(token, adminSessionPrefix) || len(token) != len(adminSessionPrefix)+2*adminSessionRandomBytes {
+		return nil, ErrSessionNotFound
+	}
+	session, refreshToken, err := s.lookup(ctx, hashSessionToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(session.RefreshAt) {
+		return session, nil
+	}
+	// 同時到期的請求共用一次 refresh，避免 refresh token 輪替時互相作廢
+	v, err, _ := s.group.Do(session.ID, func() (interface{}, error) {
+		return s.refresh(context.WithoutCancel(ctx), session, refreshToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*AdminSession), nil
+}
+
+// refresh 以 refresh token 重新向身分提供者確認身分並更新角色；被拒絕或已失去角色時結束 session
+func (s *AdminSessionService) refresh(ctx context.Context, session *AdminSession, refreshToken string) (*AdminSession, error) {
+	identity, err := s.oidc.Refresh(ctx, refreshToken)
+	switch {
+	case errors.Is(err, ErrOIDCUnavailable):
+		slog.Warn("failed to refresh admin session, retrying later", "session", session.ID, "error", err)
+		session.RefreshAt = minTime(time.Now().Add(oidcRetryRefreshAfter), session.ExpiresAt)
+		if err := s.update(ctx, session, refreshToken); err != nil {
+			return nil, err
+		}
+		return session, nil
+	case err != nil:
+		slog.Info("admin session ended by identity provider", "session", session.ID, "subject", session.Subject, "error", err)
+		return nil, s.end(ctx, session.ID)
+	}
+	if p := identity.Principal; p != nil {
+		if p.Subject != session.Subject {
+			slog.Warn("admin session refreshed as another user", "session", session.ID, "subject", session.Subject, "refreshed", p.Subject)
+			return nil, s.end(ctx, session.ID)
+		}
+		session.Email, session.Name, session.Groups, session.Role = p.Email, p.Name, p.Roles, identity.Role
+		if session.Groups == nil {
+			session.Groups = []string{}
+		}
+	}
+	if !session.Role.Includes(StoryRoleAuthor) {
+		slog.Info("admin session ended: user lost their role", "session", session.ID, "subject", session.Subject)
+		return nil, s.end(ctx, session.ID)
+	}
+	session.RefreshAt = session.nextRefresh(identity)
+	if err := s.update(ctx, session, identity.RefreshToken); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// minTime 回傳較早的時間
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// End ends the session token was issued as. Ending an unknown session is
+// not an error.
+func (s *AdminSessionService) End(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM admin_sessions WHERE token_hash = $1`, hashSessionToken(token)); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// end 以 ID 結束 session，並回傳 ErrSessionNotFound 供呼叫者直接回傳
+func (s *AdminSessionService) end(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM admin_sessions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return ErrSessionNotFound
+}
+
+// lookup 以 token hash 讀取未過期的 session 與其 refresh token
+func (s *AdminSessionService) lookup(ctx context.Context, hash string) (*AdminSession, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var refreshToken string
+	session, err := scanAdminSession(s.db.QueryRowContext(ctx, `SELECT `+adminSessionColumns+`, refresh_token FROM admin_sessions WHERE token_hash = $1 AND expires_at > now()`, hash), &refreshToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("get session: %w", err)
+	}
+	return session, refreshToken, nil
+}
+
+// update 寫入 refresh 後的身分、角色與 refresh token
+func (s *AdminSessionService) update(ctx context.Context, session *AdminSession, refreshToken string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	groups, err := json.Marshal(session.Groups)
+	if err != nil {
+		return fmt.Errorf("marshal groups: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE admin_sessions SET email = $2, name = $3, groups = $4, role = $5, refresh_token = $6, refresh_at = $7 WHERE id = $1`,
+		session.ID, session.Email, session.Name, string(groups), string(session.Role), refreshToken, session.RefreshAt)
+	if err != nil {
+		return fmt.Errorf("update session: %w", err)
+	}
+	return nil
+}
+
+// scanAdminSession 依 adminSessionColumns 的順序讀取一筆 session；extra 為 adminSessionColumns 之後額外選取的欄位
+func scanAdminSession(row rowScanner, extra ...interface{}) (*AdminSession, error) {
+	var (
+		session AdminSession
+		groups  []byte
+		role    string
+	)
+	dest := append([]interface{}{&session.ID, &session.Subject, &session.Email, &session.Name, &groups, &role, &session.CreatedAt, &session.RefreshAt, &session.ExpiresAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(groups, &session.Groups); err != nil {
+		return nil, fmt.Errorf("decode groups: %w", err)
+	}
+	session.Role = StoryRole(role)
+	return &session, nil
+}
@@ -0,0 +1,529 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAMP is returned (wrapped) when the AMP rendering of a story
+// still contains markup AMP does not allow. It should not happen; the
+// story is then served only at its canonical URL.
+var ErrInvalidAMP = errors.New("invalid amp document")
+
+// ampCachePrefix 為 AMP 頁面的 cache key 前綴，在 story: 前綴下，story 寫入時一併清除；
+// ampImageSizeCachePrefix 為圖片尺寸的 cache key 前綴，依網址計算，story 寫入時不需清除
+const (
+	ampCachePrefix          = storyCachePrefix + "amp"
+	ampImageSizeCachePrefix = "amp:imagesize"
+	ampImageSizeTimeout     = 5 * time.Second
+	ampImageSizeMaxRead     = 256 << 10
+)
+
+// ampDefaultWidth、ampDefaultHeight 為無法取得尺寸的圖片與 iframe 使用的 16:9 尺寸；layout=responsive 只使用比例
+const (
+	ampDefaultWidth  = 1600
+	ampDefaultHeight = 900
+)
+
+// ampDisallowedElements 為 AMP 不允許的元素，移除標籤但保留文字；script、style 等 raw text 元素連同內容移除
+var ampDisallowedElements = map[string]bool{
+	"video": true, "audio": true, "source": true, "track": true, "picture": true, "canvas": true, "frame": true,
+	"frameset": true, "object": true, "embed": true, "applet": true, "param": true, "base": true, "link": true,
+	"meta": true, "form": true, "input": true, "button": true, "select": true, "option": true, "textarea": true,
+	"svg": true, "math": true, "template": true, "dialog": true,
+}
+
+// ampComponents 為 AMP 擴充元件與其 script；amp-img 不需要額外的 script
+var ampComponents = map[string]string{
+	"amp-iframe":    "https://cdn.ampproject.org/v0/amp-iframe-0.1.js",
+	"amp-youtube":   "https://cdn.ampproject.org/v0/amp-youtube-0.1.js",
+	"amp-twitter":   "https://cdn.ampproject.org/v0/amp-twitter-0.1.js",
+	"amp-instagram": "https://cdn.ampproject.org/v0/amp-instagram-0.1.js",
+}
+
+// ampSizedElements 為必須有 width、height 的 AMP 元素
+var ampSizedElements = map[string]bool{
+	"amp-img": true, "amp-iframe": true, "amp-youtube": true, "amp-twitter": true, "amp-instagram": true,
+}
+
+var (
+	// youTubeVideoID 符合 YouTube 影片 ID
+	youTubeVideoID = regexp.MustCompile(`^[A-Za-z0-9_-]{6,}$`)
+	// tweetStatusPath 取出 X (Twitter) 貼文網址中的 ID
+	tweetStatusPath = regexp.MustCompile(`^/[^/]+/status(?:es)?/(\d+)`)
+	// instagramPostPath 取出 Instagram 貼文網址中的 shortcode
+	instagramPostPath = regexp.MustCompile(`^/(?:p|reel|tv)/([A-Za-z0-9_-]+)`)
+)
+
+// AMPDocument is the AMP page of a story. Slug is the current slug of the
+// story, which differs from the requested one after a rename.
+type AMPDocument struct {
+	Slug string `json:"slug"`
+	HTML string `json:"html"`
+}
+
+// AMPService renders stories as AMP pages for publishers still serving AMP
+// to Google Discover. The body HTML is rewritten for AMP: images become
+// amp-img with their width and height inlined, YouTube, X (Twitter) and
+// Instagram embeds become their AMP components, other iframes become
+// amp-iframe, and scripts, styles, forms, media elements and inline style
+// and on* attributes are removed. Every page is validated before it is
+// served and cached under the story cache prefix, apart from the story
+// payloads, so story writes purge it.
+type AMPService struct {
+	stories      *StoryService
+	cache        *Cache
+	site         Site
+	client       *http.Client
+	imageSources []string
+}
+
+// NewAMPService returns a service rendering AMP pages of stories that link
+// to their canonical page on site. Images without width and height are
+// measured by fetching the start of the file when their URL starts with one
+// of imageSources; other images are laid out as 16:9.
+func NewAMPService(stories *StoryService, cache *Cache, site Site, imageSources []string) *AMPService {
+	return &AMPService{stories: stories, cache: cache, site: site, client: &http.Client{Timeout: ampImageSizeTimeout},
+		imageSources: imageSources}
+}
+
+// Render returns the AMP page of the published story with slug, or
+// ErrStoryNotFound. It returns ErrInvalidAMP when the rendered page fails
+// validation. Paid (metered and member-only) stories show their excerpt
+// only.
+func (s *AMPService) Render(ctx context.Context, slug string) (*AMPDocument, error) {
+	key := NewCacheKey(ampCachePrefix).Field("slug", slug).ShortHash().String()
+	doc, err := NewTypedCache[AMPDocument](s.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) (AMPDocument, error) {
+		story, err := s.stories.Story(ctx, "", slug)
+		if err != nil {
+			return AMPDocument{}, err
+		}
+		page, err := s.render(ctx, story)
+		if err != nil {
+			return AMPDocument{}, err
+		}
+		return AMPDocument{Slug: story.Slug, HTML: page}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ampPage 為 AMP 頁面的 template 參數
+type ampPage struct {
+	Lang         string
+	Title        string
+	Description  string
+	CanonicalURL string
+	Scripts      []ampScript
+	JSONLD       *StoryJSONLD
+	SiteTitle    string
+	SiteURL      string
+	Story        *Story
+	Authors      []string
+	Body         htmltemplate.HTML
+}
+
+// ampScript 為頁面使用的擴充元件與其 script
+type ampScript struct {
+	Element string
+	Src     string
+}
+
+// ampTemplate 為 AMP 頁面；amp-boilerplate 的樣式必須與 AMP 規範的內容完全相同
+var ampTemplate = htmltemplate.Must(htmltemplate.New("amp").Parse(`<!doctype html>
+<html amp lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<script async src="https://cdn.ampproject.org/v0.js"></script>
+{{range .Scripts}}<script async custom-element="{{.Element}}" src="{{.Src}}"></script>
+{{end}}<title>{{.Title}}</title>
+<link rel="canonical" href="{{.CanonicalURL}}">
+<meta name="viewport" content="width=device-width">
+{{with .Description}}<meta name="description" content="{{.}}">
+{{end}}{{with .JSONLD}}<script type="application/ld+json">{{.}}</script>
+{{end}}<style amp-boilerplate>body{-webkit-animation:-amp-start 8s steps(1,end) 0s 1 normal both;-moz-animation:-amp-start 8s steps(1,end) 0s 1 normal both;-ms-animation:-amp-start 8s steps(1,end) 0s 1 normal both;animation:-amp-start 8s steps(1,end) 0s 1 normal both}@-webkit-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@-moz-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@-ms-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@-o-keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}@keyframes -amp-start{from{visibility:hidden}to{visibility:visible}}</style><noscript><style amp-boilerplate>body{-webkit-animation:none;-moz-animation:none;-ms-animation:none;animation:none}</style></noscript>
+<style amp-custom>body{margin:0 auto;max-width:720px;padding:0 16px;font-family:sans-serif;line-height:1.7}figure{margin:1em 0}figcaption{font-size:.875em;color:#666}</style>
+</head>
+<body>
+<header><a href="{{.SiteURL}}">{{.SiteTitle}}</a></header>
+<article>
+<h1>{{.Story.Title}}</h1>
+{{with .Story.Subtitle}}<p>{{.}}</p>
+{{end}}{{with .Authors}}<p>{{range $i, $name := .}}{{if $i}}、{{end}}{{$name}}{{end}}</p>
+{{end}}{{.Body}}
+</article>
+</body>
+</html>
+`))
+
+// render 組成 story 的 AMP 頁面並驗證
+func (s *AMPService) render(ctx context.Context, story *Story) (string, error) {
+	var body string
+	if story.AccessTier() != StoryAccessFree {
+		if story.Excerpt != "" {
+			body = "<p>" + html.EscapeString(story.Excerpt) + "</p>"
+		}
+	} else {
+		rendered, err := s.stories.BodyHTML(ctx, story)
+		if err != nil {
+			return "", err
+		}
+		body = rendered
+	}
+	if story.CoverImage != "" {
+		body = `<figure><img src="` + html.EscapeString(story.CoverImage) + `" alt=""></figure>` + body
+	}
+	body, components := s.transform(ctx, body)
+	if err := validateAMP(body, components); err != nil {
+		return "", fmt.Errorf("story %s: %w", story.ID, err)
+	}
+
+	page := ampPage{
+		Lang:         s.stories.Locale(story),
+		Title:        story.Title,
+		Description:  socialDescription(story, s.site.Description),
+		CanonicalURL: s.site.StoryURL(story.Slug),
+		SiteTitle:    s.site.Title,
+		SiteURL:      s.site.URL,
+		Story:        story,
+		Body:         htmltemplate.HTML(body),
+	}
+	if story.Title == "" {
+		page.Title = s.site.Title
+	}
+	for _, component := range components {
+		page.Scripts = append(page.Scripts, ampScript{Element: component, Src: ampComponents[component]})
+	}
+	ld, err := s.stories.StructuredData(ctx, story)
+	if err != nil {
+		return "", err
+	}
+	page.JSONLD = ld
+	for _, author := range ld.Author {
+		page.Authors = append(page.Authors, author.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := ampTemplate.Execute(&buf, page); err != nil {
+		return "", fmt.Errorf("render amp page: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// transform 將 body HTML 轉為 AMP，回傳結果與使用到的擴充元件 (已排序)
+func (s *AMPService) transform(ctx context.Context, body string) (string, []string) {
+	var (
+		sb    strings.Builder
+		open  []string
+		used  = map[string]bool{}
+		skip  = -1 // 已轉為 AMP 元件的 embed figure 在 open 中的位置；其內容略過到 figcaption
+		input = body
+	)
+	sb.Grow(len(input))
+	for len(input) > 0 {
+		i := strings.IndexByte(input, '<')
+		text := input
+		if i >= 0 {
+			text = input[:i]
+		}
+		if skip < 0 {
+			writeHTMLText(&sb, text)
+		}
+		if i < 0 {
+			break
+		}
+		tag, n := readHTMLTag(input[i:])
+		input = input[i+n:]
+
+		switch tag.kind {
+		case htmlTagText:
+			if skip < 0 {
+				sb.WriteString("&lt;")
+			}
+		case htmlTagStart:
+			if skip >= 0 && tag.name != "figcaption" {
+				if rawTextElements[tag.name] {
+					input = skipRawText(input, tag.name)
+				} else if !voidElements[tag.name] {
+					open = append(open, tag.name)
+				}
+				continue
+			}
+			if skip >= 0 {
+				// 略過的內容中未關閉的元素不輸出結束標籤
+				open, skip = open[:skip+1], -1
+			}
+			switch {
+			case tag.name == "iframe":
+				input = skipRawText(input, tag.name)
+				s.writeIframe(&sb, tag, used)
+				continue
+			case rawTextElements[tag.name]:
+				input = skipRawText(input, tag.name)
+				continue
+			case ampDisallowedElements[tag.name] || !validHTMLName(tag.name) || strings.HasPrefix(tag.name, "amp-"):
+				continue
+			case tag.name == "img":
+				s.writeImage(ctx, &sb, tag)
+				continue
+			}
+			sb.WriteString("<" + tag.name)
+			writeAMPAttributes(&sb, tag)
+			sb.WriteByte('>')
+			if tag.name == "figure" && hasClass(tag, "block-embed") {
+				if element, markup := ampEmbed(attrValue(tag, "data-url")); element != "" {
+					sb.WriteString(markup)
+					used[element] = true
+					skip = len(open)
+				}
+			}
+			if !voidElements[tag.name] {
+				open = append(open, tag.name)
+			}
+		case htmlTagEnd:
+			i := slices.Index(open, tag.name)
+			if i < 0 {
+				continue
+			}
+			for j := len(open) - 1; j >= i; j-- {
+				if skip < 0 || j <= skip {
+					sb.WriteString("</" + open[j] + ">")
+				}
+			}
+			open = open[:i]
+			if i <= skip {
+				skip = -1
+			}
+		}
+	}
+	for j := len(open) - 1; j >= 0; j-- {
+		sb.WriteString("</" + open[j] + ">")
+	}
+
+	components := make([]string, 0, len(used))
+	for name := range used {
+		components = append(components, name)
+	}
+	sort.Strings(components)
+	return sb.String(), components
+}
+
+// writeAMPAttributes 寫入屬性；AMP 不允許的 style、on* 與保留給 AMP 的屬性略過，網址不安全的屬性略過
+func writeAMPAttributes(sb *strings.Builder, tag htmlTag) {
+	seen := map[string]bool{}
+	for _, attr := range tag.attrs {
+		if seen[attr.name] || !validHTMLName(attr.name) || attr.name == "style" || strings.HasPrefix(attr.name, "on") ||
+			strings.HasPrefix(attr.name, "i-amp-") || strings.HasPrefix(attr.name, "i-amphtml-") {
+			continue
+		}
+		seen[attr.name] = true
+		value := attr.value
+		if urlAttributes[attr.name] {
+			var ok bool
+			if value, ok = cleanHTMLURL(value); !ok {
+				continue
+			}
+		}
+		sb.WriteString(" " + attr.name + `="` + html.EscapeString(value) + `"`)
+	}
+}
+
+// writeImage 將 img 轉為 amp-img；沒有尺寸時量測圖片，無法量測時以 16:9 顯示
+func (s *AMPService) writeImage(ctx context.Context, sb *strings.Builder, tag htmlTag) {
+	src, ok := cleanHTMLURL(attrValue(tag, "src"))
+	if !ok || src == "" {
+		return
+	}
+	src = s.stories.absoluteURL(src)
+	width, height := attrSize(tag, "width"), attrSize(tag, "height")
+	if width == 0 || height == 0 {
+		width, height = s.imageSize(ctx, src)
+	}
+	sb.WriteString(`<amp-img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(attrValue(tag, "alt")) + `"`)
+	if title := attrValue(tag, "title"); title != "" {
+		sb.WriteString(` title="` + html.EscapeString(title) + `"`)
+	}
+	sb.WriteString(` width="` + strconv.Itoa(width) + `" height="` + strconv.Itoa(height) + `" layout="responsive"></amp-img>`)
+}
+
+// writeIframe 將 YouTube iframe 轉為 amp-youtube，其他 https iframe 轉為 amp-iframe；其餘移除
+func (s *AMPService) writeIframe(sb *strings.Builder, tag htmlTag, used map[string]bool) {
+	src, ok := cleanHTMLURL(attrValue(tag, "src"))
+	if !ok {
+		return
+	}
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return
+	}
+	width, height := attrSize(tag, "width"), attrSize(tag, "height")
+	if width == 0 || height == 0 {
+		width, height = ampDefaultWidth, ampDefaultHeight
+	}
+	size := ` width="` + strconv.Itoa(width) + `" height="` + strconv.Itoa(height) + `" layout="responsive"`
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if id, found := strings.CutPrefix(u.Path, "/embed/"); found && (host == "youtube.com" || host == "youtube-nocookie.com") &&
+		youTubeVideoID.MatchString(id) {
+		sb.WriteString(`<amp-youtube data-videoid="` + id + `"` + size + `></amp-youtube>`)
+		used["amp-youtube"] = true
+		return
+	}
+	sb.WriteString(`<amp-iframe src="` + html.EscapeString(src) + `" sandbox="allow-scripts allow-same-origin allow-popups"` +
+		` frameborder="0"` + size + `></amp-iframe>`)
+	used["amp-iframe"] = true
+}
+
+// ampEmbed 依 embed block 的網址回傳對應的 AMP 元件名稱與標籤；不支援的網址回傳空字串
+func ampEmbed(raw string) (element, markup string) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch host {
+	case "youtube.com", "m.youtube.com", "youtu.be":
+		id := u.Query().Get("v")
+		if host == "youtu.be" {
+			id = strings.Trim(u.Path, "/")
+		} else if shorts, ok := strings.CutPrefix(u.Path, "/shorts/"); ok {
+			id = strings.Trim(shorts, "/")
+		}
+		if youTubeVideoID.MatchString(id) {
+			return "amp-youtube", `<amp-youtube data-videoid="` + id + `" width="480" height="270" layout="responsive"></amp-youtube>`
+		}
+	case "x.com", "twitter.com", "mobile.twitter.com":
+		if m := tweetStatusPath.FindStringSubmatch(u.Path); m != nil {
+			return "amp-twitter", `<amp-twitter data-tweetid="` + m[1] + `" width="375" height="472" layout="responsive"></amp-twitter>`
+		}
+	case "instagram.com":
+		if m := instagramPostPath.FindStringSubmatch(u.Path); m != nil {
+			return "amp-instagram", `<amp-instagram data-shortcode="` + m[1] + `" data-captioned width="400" height="400" layout="responsive"></amp-instagram>`
+		}
+	}
+	return "", ""
+}
+
+// imageSize 回傳圖片的尺寸；只量測 imageSources 下的圖片，結果依網址快取，無法量測時回傳 16:9 的預設尺寸
+func (s *AMPService) imageSize(ctx context.Context, src string) (int, int) {
+	allowed := false
+	for _, prefix := range s.imageSources {
+		if prefix != "" && strings.HasPrefix(src, prefix) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ampDefaultWidth, ampDefaultHeight
+	}
+	key := NewCacheKey(ampImageSizeCachePrefix).Field("url", src).ShortHash().String()
+	size, err := NewTypedCache[[2]int](s.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([2]int, error) {
+		config, err := s.fetchImageConfig(ctx, src)
+		if err != nil {
+			// 無法量測的圖片也記錄預設尺寸，之後不再請求
+			return [2]int{ampDefaultWidth, ampDefaultHeight}, nil
+		}
+		return [2]int{config.Width, config.Height}, nil
+	})
+	if err != nil || size[0] <= 0 || size[1] <= 0 {
+		return ampDefaultWidth, ampDefaultHeight
+	}
+	return size[0], size[1]
+}
+
+// fetchImageConfig 只讀取圖片的開頭並解析尺寸
+func (s *AMPService) fetchImageConfig(ctx context.Context, src string) (image.Config, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return image.Config{}, err
+	}
+	req.Header.Set("Range", "bytes=0-"+strconv.Itoa(ampImageSizeMaxRead-1))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return image.Config{}, fmt.Errorf("fetch image: status %d", resp.StatusCode)
+	}
+	config, _, err := image.DecodeConfig(io.LimitReader(resp.Body, ampImageSizeMaxRead))
+	return config, err
+}
+
+// validateAMP 確認 body 中沒有 AMP 不允許的元素與屬性，AMP 元件都有尺寸且已載入 script
+func validateAMP(body string, components []string) error {
+	for len(body) > 0 {
+		i := strings.IndexByte(body, '<')
+		if i < 0 {
+			break
+		}
+		tag, n := readHTMLTag(body[i:])
+		body = body[i+n:]
+		if tag.kind != htmlTagStart {
+			continue
+		}
+		if tag.name == "img" || tag.name == "iframe" || rawTextElements[tag.name] || ampDisallowedElements[tag.name] {
+			return fmt.Errorf("%w: <%s> is not allowed", ErrInvalidAMP, tag.name)
+		}
+		for _, attr := range tag.attrs {
+			if attr.name == "style" || strings.HasPrefix(attr.name, "on") {
+				return fmt.Errorf("%w: attribute %s on <%s> is not allowed", ErrInvalidAMP, attr.name, tag.name)
+			}
+		}
+		if !strings.HasPrefix(tag.name, "amp-") {
+			continue
+		}
+		if _, ok := ampComponents[tag.name]; ok && !slices.Contains(components, tag.name) {
+			return fmt.Errorf("%w: <%s> without its script", ErrInvalidAMP, tag.name)
+		}
+		if !ampSizedElements[tag.name] {
+			return fmt.Errorf("%w: <%s> is not supported", ErrInvalidAMP, tag.name)
+		}
+		if attrSize(tag, "width") == 0 || attrSize(tag, "height") == 0 {
+			return fmt.Errorf("%w: <%s> without width and height", ErrInvalidAMP, tag.name)
+		}
+		if tag.name == "amp-iframe" && !strings.HasPrefix(attrValue(tag, "src"), "https://") {
+			return fmt.Errorf("%w: amp-iframe without an https src", ErrInvalidAMP)
+		}
+	}
+	return nil
+}
+
+// attrValue 回傳標籤中第一個名稱為 name 的屬性值
+func attrValue(tag htmlTag, name string) string {
+	for _, attr := range tag.attrs {
+		if attr.name == name {
+			return attr.value
+		}
+	}
+	return ""
+}
+
+// attrSize 回傳尺寸屬性的像素值；不是正整數 (例如百分比) 時回傳 0
+func attrSize(tag htmlTag, name string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(attrValue(tag, name)), "px"))
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
+
+// hasClass 回傳標籤的 class 是否包含 class
+func hasClass(tag htmlTag, class string) bool {
+	return slices.Contains(strings.Fields(attrValue(tag, "class")), class)
+}
@@ -0,0 +1,313 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when no API key matches the lookup.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey is returned (wrapped) for an API key with an empty
+	// name, a malformed scope or a negative rate limit.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrUnknownAPIKey is returned by APIKeyService.Authenticate for a key
+	// that was never issued or has been revoked.
+	ErrUnknownAPIKey = errors.New("unknown or revoked api key")
+)
+
+// API key 的格式：固定前綴加上 32 bytes 的隨機值 (hex)；列出時只顯示前 apiKeyDisplayLength 個字元
+const (
+	apiKeyPrefix        = "gsk_"
+	apiKeyRandomBytes   = 32
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	apiKeyCacheTTL      = 10 * time.Minute
+	apiKeyNameMaxLength = 200
+)
+
+// apiKeyColumns 為查詢 api_keys 時的欄位順序，需與 scanAPIKey 一致
+const apiKeyColumns = `id, name, prefix, scopes, rate_limit, created_at, revoked_at`
+
+// apiKeyScopePattern 限制 scope 的格式，例如 stories:read
+var apiKeyScopePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9:._-]*$`)
+
+// APIKey is a key issued to a machine client. The key itself is only
+// returned once, by Issue; the database keeps its SHA-256 hash and Prefix,
+// its first characters, so keys can be told apart when listed.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// RateLimit is the number of requests the key may make per rate limit
+	// window; zero uses the default per-key limit.
+	RateLimit int        `json:"rateLimit"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	// Key 只在 Issue 時設定，不會儲存
+	Key string `json:"key,omitempty"`
+}
+
+// Principal returns the principal of requests authenticated with the key,
+// whose subject is "apikey:" followed by the key ID.
+func (k *APIKey) Principal() *Principal {
+	return &Principal{Subject: "apikey:" + k.ID, Name: k.Name, Scopes: k.Scopes}
+}
+
+// apiKeyContextKey 為 context 中 APIKey 的 key
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a copy of ctx carrying the API key the request was
+// authenticated with.
+func WithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the API key set on ctx with WithAPIKey, or nil.
+func APIKeyFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key
+}
+
+// APIKeyService issues, revokes and authenticates API keys. Keys are
+// stored hashed: they are long random values, so a plain SHA-256 is enough
+// to keep a database leak from exposing usable keys. Authenticate caches
+// keys by hash in Redis for 10 minutes, unknown keys too when negative
+// caching is enabled, so requests rarely reach the database; revoking a key
+// deletes its cache entry. Issues and revocations are recorded in the audit
+// log.
+type APIKeyService struct {
+	db    *sql.DB
+	cache *Cache
+	audit *AuditLog
+}
+
+// NewAPIKeyService returns a service storing API keys in db (see
+// internal/data/migrations) and caching lookups in cache. audit may be nil.
+func NewAPIKeyService(db *sql.DB, cache *Cache, audit *AuditLog) *APIKeyService {
+	return &APIKeyService{db: db, cache: cache, audit: audit}
+}
+
+// hashAPIKey 回傳 key 儲存於資料庫與 cache key 中的 hash
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyCacheKey 回傳 key hash 對應的 cache key
+func apiKeyCacheKey(hash string) string {
+	return "apikey:" + hash
+}
+
+// Keys returns every API key, revoked ones included, oldest first.
+func (s *APIKeyService) Keys(ctx context.Context) ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Key returns the API key with id, or ErrAPIKeyNotFound.
+func (s *APIKeyService) Key(ctx context.Context, id string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	return key, nil
+}
+
+// Issue generates and stores a new key with key.Name, key.Scopes and
+// key.RateLimit, filling in ID, Prefix, CreatedAt and Key, the key to hand
+// to the client.
+func (s *APIKeyService) Issue(ctx context.Context, key *APIKey) error {
+	if err := prepareAPIKey(key); err != nil {
+		return err
+	}
+	buf := make([]byte, apiKeyRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("generate api key: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	key.ID = newUUID()
+	key.Key = apiKeyPrefix + hex.EncodeToString(buf)
+	key.Prefix = key.Key[:apiKeyDisplayLength]
+	key.CreatedAt = time.Now().UTC()
+	key.RevokedAt = nil
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("marshal scopes: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO api_keys (id, name, prefix, key_hash, scopes, rate_limit, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		key.ID, key.Name, key.Prefix, hashAPIKey(key.Key), string(scopes), key.RateLimit, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create api key: %w", err)
+	}
+	s.record(ctx, AuditActionCreate, key.ID, nil, key)
+	return nil
+}
+
+// Revoke revokes the API key with id, or returns ErrAPIKeyNotFound.
+// Revoking a revoked key keeps its original revocation time.
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (*APIKey, error) {
+	current, err := s.Key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.RevokedAt != nil {
+		return current, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var hash string
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1 RETURNING `+apiKeyColumns+`, key_hash`, id), &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("revoke api key: %w", err)
+	}
+	if err := s.cache.Delete(ctx, apiKeyCacheKey(hash)); err != nil {
+		slog.Warn("failed to invalidate cached api key", "id", id, "error", err)
+	}
+	s.record(ctx, AuditActionDelete, id, current, key)
+	return key, nil
+}
+
+// Authenticate returns the API key raw was issued as, or ErrUnknownAPIKey
+// when it was never issued or has been revoked.
+func (s *APIKeyService) Authenticate(ctx context.Context, raw string) (*APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) || len(raw) != len(apiKeyPrefix)+2*apiKeyRandomBytes {
+		return nil, ErrUnknownAPIKey
+	}
+	hash := hashAPIKey(raw)
+	cacheKey := apiKeyCacheKey(hash)
+
+	var key APIKey
+	found, err := s.cache.Get(ctx, cacheKey, &key)
+	switch {
+	case errors.Is(err, ErrCachedNotFound):
+		return nil, ErrUnknownAPIKey
+	case found && err == nil:
+		if key.RevokedAt != nil {
+			return nil, ErrUnknownAPIKey
+		}
+		return &key, nil
+	}
+
+	loaded, err := s.lookup(ctx, hash)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		_ = s.cache.SetNotFound(ctx, cacheKey)
+		return nil, ErrUnknownAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.SetWithTTL(ctx, cacheKey, loaded, apiKeyCacheTTL)
+	if loaded.RevokedAt != nil {
+		return nil, ErrUnknownAPIKey
+	}
+	return loaded, nil
+}
+
+// lookup 以 hash 自資料庫讀取 API key
+func (s *APIKeyService) lookup(ctx context.Context, hash string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	return key, nil
+}
+
+// record 將 API key 的發行與撤銷記錄到稽核紀錄，不含 key；失敗時只記錄日誌
+func (s *APIKeyService) record(ctx context.Context, action, id string, before, after *APIKey) {
+	redact := func(key *APIKey) interface{} {
+		if key == nil {
+			return nil
+		}
+		copied := *key
+		copied.Key = ""
+		return &copied
+	}
+	if err := s.audit.Record(context.WithoutCancel(ctx), action, AuditEntityAPIKey, id, redact(before), redact(after)); err != nil {
+		slog.Warn("failed to record api key audit entry", "id", id, "action", action, "error", err)
+	}
+}
+
+// prepareAPIKey 檢查名稱、scope 與 rate limit
+func prepareAPIKey(key *APIKey) error {
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" || len(key.Name) > apiKeyNameMaxLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidAPIKey, apiKeyNameMaxLength)
+	}
+	if key.RateLimit < 0 {
+		return fmt.Errorf("%w: rate limit must not be negative", ErrInvalidAPIKey)
+	}
+	for _, scope := range key.Scopes {
+		if !apiKeyScopePattern.MatchString(scope) {
+			return fmt.Errorf("%w: malformed scope %q", ErrInvalidAPIKey, scope)
+		}
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	return nil
+}
+
+// scanAPIKey 依 apiKeyColumns 的順序讀取一筆 API key；extra 為 apiKeyColumns 之後額外選取的欄位
+func scanAPIKey(row rowScanner, extra ...interface{}) (*APIKey, error) {
+	var (
+		key       APIKey
+		scopes    []byte
+		revokedAt sql.NullTime
+	)
+	dest := append([]interface{}{&key.ID, &key.Name, &key.Prefix, &scopes, &key.RateLimit, &key.CreatedAt, &revokedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+		return nil, fmt.Errorf("decode scopes: %w", err)
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAuditQuery is returned (wrapped) for an AuditQuery with an
+// invalid time range.
+var ErrInvalidAuditQuery = errors.New("invalid audit query")
+
+// Audit actions.
+const (
+	AuditActionCreate     = "create"
+	AuditActionUpdate     = "update"
+	AuditActionTransition = "transition" // 狀態改變的修改
+	AuditActionDelete     = "delete"     // 移至垃圾桶，或不支援垃圾桶時的永久刪除
+	AuditActionRestore    = "restore"    // 自垃圾桶還原
+	AuditActionPurge      = "purge"      // 自垃圾桶永久刪除
+	AuditActionMerge      = "merge"      // tag / 分類合併至另一個
+)
+
+// Audited entities.
+const (
+	AuditEntityStory      = "story"
+	AuditEntityWebhook    = "webhook"
+	AuditEntityAuthor     = "author"
+	AuditEntityTag        = TermKindTag
+	AuditEntityCategory   = TermKindCategory
+	AuditEntityCollection = "collection"
+	AuditEntityMedia      = "media"
+	AuditEntityComment    = "comment"
+	AuditEntityAPIKey     = "api_key"
+)
+
+// AuditActorSystem is the actor of writes whose context has no actor (see
+// WithAuditActor), such as background jobs.
+const AuditActorSystem = "system"
+
+// auditColumns 為查詢 audit_log 時的欄位順序，需與 scanAuditEntry 一致
+const auditColumns = `id, actor, action, entity, entity_id, before, after, created_at`
+
+// 稽核紀錄每次回傳筆數的預設值與上限
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// AuditEntry records one write: who (Actor) did what (Action) to which
+// entity, with its content before and after the write as JSON. Before is
+// null for creates and After is null for deletes.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entityId"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// AuditQuery filters AuditLog.Entries. Zero values mean no filter; Since
+// is inclusive and Until exclusive. BeforeID pages through results: only
+// entries with a smaller ID (older) are returned.
+type AuditQuery struct {
+	Actor    string
+	Action   string
+	Entity   string
+	EntityID string
+	Since    *time.Time
+	Until    *time.Time
+	BeforeID int64
+	Limit    int // 0 表示使用預設值 (defaultAuditLimit)
+}
+
+// auditActorKey 為 context 中 actor 的 key
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx whose writes are recorded in the
+// audit log as made by actor.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActor returns the actor set on ctx with WithAuditActor, or
+// AuditActorSystem.
+func AuditActor(ctx context.Context) string {
+	if actor, ok := ctx.Value(auditActorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AuditActorSystem
+}
+
+// AuditLog stores the audit trail in the audit_log table (see
+// internal/data/migrations). Entries are only ever appended. A nil
+// *AuditLog records nothing, so services can take it as optional.
+type AuditLog struct {
+	db *sql.DB
+}
+
+// NewAuditLog returns an audit log stored in db.
+func NewAuditLog(db *sql.DB) *AuditLog {
+	return &AuditLog{db: db}
+}
+
+// Record appends an entry by the actor of ctx. before and after are
+// marshalled to JSON; nil is stored as null.
+func (l *AuditLog) Record(ctx context.Context, action, entity, entityID string, before, after interface{}) error {
+	if l == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.ExecContext(ctx, `INSERT INTO audit_log (actor, action, entity, entity_id, before, after, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		AuditActor(ctx), action, entity, entityID, beforeJSON, afterJSON, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("record audit entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the entries matching q, newest first.
+func (l *AuditLog) Entries(ctx context.Context, q AuditQuery) ([]AuditEntry, error) {
+	if q.Since != nil && q.Until != nil && !q.Since.Before(*q.Until) {
+		return nil, fmt.Errorf("%w: since must be before until", ErrInvalidAuditQuery)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	conds := []string{}
+	args := []interface{}{}
+	addCond := func(format string, value interface{}) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf(format, len(args)))
+	}
+	if q.Actor != "" {
+		addCond(`actor = $%d`, q.Actor)
+	}
+	if q.Action != "" {
+		addCond(`action = $%d`, q.Action)
+	}
+	if q.Entity != "" {
+		addCond(`entity = $%d`, q.Entity)
+	}
+	if q.EntityID != "" {
+		addCond(`entity_id = $%d`, q.EntityID)
+	}
+	if q.Since != nil {
+		addCond(`created_at >= $%d`, *q.Since)
+	}
+	if q.Until != nil {
+		addCond(`created_at < $%d`, *q.Until)
+	}
+	if q.BeforeID > 0 {
+		addCond(`id < $%d`, q.BeforeID)
+	}
+	limit := q.Limit
+	switch {
+	case limit <= 0:
+		limit = defaultAuditLimit
+	case limit > maxAuditLimit:
+		limit = maxAuditLimit
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + auditColumns + ` FROM audit_log`)
+	if len(conds) > 0 {
+		sb.WriteString(` WHERE ` + strings.Join(conds, ` AND `))
+	}
+	args = append(args, limit)
+	sb.WriteString(fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args)))
+
+	rows, err := l.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// auditJSON 將內容轉為 JSONB 參數；nil 寫入 NULL
+func auditJSON(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal audit snapshot: %w", err)
+	}
+	if string(b) == "null" {
+		return nil, nil // 內容為 nil 指標
+	}
+	return string(b), nil
+}
+
+// scanAuditEntry 依 auditColumns 的順序讀取一筆稽核紀錄
+func scanAuditEntry(row rowScanner) (*AuditEntry, error) {
+	var (
+		entry         AuditEntry
+		before, after []byte
+	)
+	if err := row.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Entity, &entry.EntityID, &before, &after, &entry.CreatedAt); err != nil {
+		return nil, err
+	}
+	entry.Before, entry.After = before, after // NULL 讀回為 nil，輸出為 null
+	return &entry, nil
+}
@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Principal is the authenticated user of a request, taken from a verified
+// JWT (see JWTVerifier).
+type Principal struct {
+	Subject   string    `json:"sub"`
+	Issuer    string    `json:"iss"`
+	Email     string    `json:"email,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Roles     []string  `json:"roles,omitempty"`  // 由 JWTConfig.RolesClaim 讀取
+	Scopes    []string  `json:"scopes,omitempty"` // 由 scope (空白分隔) 或 scp claim 讀取
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Claims holds every claim of the token, for checks beyond the typed
+	// fields such as the paywall's membership claim.
+	Claims map[string]interface{} `json:"-"`
+}
+
+// HasRole reports whether the principal has role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasScope reports whether the token was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// principalKey 為 context 中 Principal 的 key
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal set on ctx with WithPrincipal,
+// or nil for anonymous requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
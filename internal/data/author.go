@@ -0,0 +1,124 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAuthorNotFound is returned when no author matches the lookup, or
+	// when a story references an author that does not exist.
+	ErrAuthorNotFound = errors.New("author not found")
+	// ErrAuthorsUnsupported is returned by author lookups when the story
+	// store does not store authors.
+	ErrAuthorsUnsupported = errors.New("story store does not support authors")
+	// ErrAuthorSlugTaken is returned by CreateAuthor and UpdateAuthor when
+	// another author already uses the slug.
+	ErrAuthorSlugTaken = errors.New("author slug already taken")
+	// ErrAuthorHasStories is returned by DeleteAuthor while stories,
+	// including trashed ones, still credit the author.
+	ErrAuthorHasStories = errors.New("author still has stories")
+	// ErrInvalidAuthor is returned by CreateAuthor and UpdateAuthor for an
+	// author without a slug or name.
+	ErrInvalidAuthor = errors.New("author slug and name are required")
+)
+
+// Author is a byline attached to stories through Story.AuthorIDs.
+type Author struct {
+	ID          string       `json:"id"`
+	Slug        string       `json:"slug"`
+	Name        string       `json:"name"`
+	Bio         string       `json:"bio"`
+	Avatar      string       `json:"avatar"` // 頭像圖片網址
+	SocialLinks []AuthorLink `json:"socialLinks"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// AuthorLink is a link to one of an author's profiles elsewhere, e.g.
+// {"network": "x", "url": "https://x.com/..."}.
+type AuthorLink struct {
+	Network string `json:"network"`
+	URL     string `json:"url"`
+}
+
+// AuthorReader is implemented by story repositories that also store
+// authors. Callers type-assert a StoryRepository to it.
+type AuthorReader interface {
+	// GetAuthorByID returns the author with id, or ErrAuthorNotFound.
+	GetAuthorByID(ctx context.Context, id string) (*Author, error)
+	// GetAuthorBySlug returns the author with slug, or ErrAuthorNotFound.
+	GetAuthorBySlug(ctx context.Context, slug string) (*Author, error)
+	// GetAuthorsByIDs returns the authors with ids in the order of ids,
+	// skipping ids that do not exist.
+	GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error)
+	// ListAuthors returns authors ordered by name, then ID.
+	ListAuthors(ctx context.Context, limit, offset int) ([]Author, error)
+}
+
+// AuthorWriter is implemented by story repositories that can also edit
+// authors. Callers type-assert a StoryRepository to it. Stories are linked
+// to authors through Story.AuthorIDs, so author writes change what the
+// stories' bylines show.
+type AuthorWriter interface {
+	// CreateAuthor stores a new author, filling in ID (when empty) and
+	// timestamps, or returns ErrAuthorSlugTaken.
+	CreateAuthor(ctx context.Context, author *Author) error
+	// UpdateAuthor replaces the author with author.ID and refreshes
+	// UpdatedAt, or returns ErrAuthorNotFound or ErrAuthorSlugTaken.
+	UpdateAuthor(ctx context.Context, author *Author) error
+	// DeleteAuthor removes the author with id, or returns ErrAuthorNotFound
+	// or ErrAuthorHasStories.
+	DeleteAuthor(ctx context.Context, id string) error
+}
+
+// defaultAuthorLimit 與 maxAuthorLimit 為 ListAuthors 每頁筆數的預設值與上限
+const (
+	defaultAuthorLimit = 20
+	maxAuthorLimit     = 100
+)
+
+// authorLimit 回傳套用預設值與上限後的筆數；儲存層允許多取一筆，供 StoryService 判斷是否有下一頁
+func authorLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultAuthorLimit
+	case limit > maxAuthorLimit+1:
+		return maxAuthorLimit + 1
+	}
+	return limit
+}
+
+// prepareAuthor 在寫入前檢查必填欄位，並補上 ID 與時間欄位
+func prepareAuthor(author *Author, now time.Time) error {
+	if author.Slug == "" || author.Name == "" {
+		return ErrInvalidAuthor
+	}
+	if author.ID == "" {
+		author.ID = newUUID()
+	}
+	if author.SocialLinks == nil {
+		author.SocialLinks = []AuthorLink{}
+	}
+	if author.CreatedAt.IsZero() {
+		author.CreatedAt = now
+	}
+	author.UpdatedAt = now
+	return nil
+}
+
+// orderAuthors 依 ids 的順序排列 authors，略過不存在的 id
+func orderAuthors(ids []string, authors []Author) []Author {
+	byID := make(map[string]Author, len(authors))
+	for _, author := range authors {
+		byID[author.ID] = author
+	}
+	ordered := make([]Author, 0, len(ids))
+	for _, id := range ids {
+		if author, ok := byID[id]; ok {
+			ordered = append(ordered, author)
+		}
+	}
+	return ordered
+}
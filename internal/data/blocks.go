@@ -0,0 +1,241 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Content block types of Story.Blocks.
+const (
+	BlockParagraph = "paragraph"
+	BlockHeading   = "heading"
+	BlockImage     = "image"
+	BlockEmbed     = "embed"
+	BlockQuote     = "quote"
+	BlockGallery   = "gallery"
+)
+
+// MaxStoryBlocks and MaxGalleryImages bound the size of a block body.
+const (
+	MaxStoryBlocks   = 1000
+	MaxGalleryImages = 50
+)
+
+// ErrInvalidBlocks is returned (wrapped) by Create and Update for a story
+// whose blocks do not match the block schema (see ValidateBlocks).
+var ErrInvalidBlocks = errors.New("invalid content blocks")
+
+// ContentBlock is one block of a structured story body. Which fields apply
+// depends on Type:
+//
+//   - paragraph: Text
+//   - heading: Text and Level (2–6; the title is the page's h1)
+//   - image: URL, Alt and Caption, and MediaID when the image was uploaded
+//     (see MediaService)
+//   - embed: URL of the embedded page (e.g. a YouTube video) and Caption
+//   - quote: Text and Cite, the attribution
+//   - gallery: Images and Caption
+//
+// MediaID only records which Media an image shows; URL is still required
+// and is what gets rendered.
+//
+// Text, Caption, Alt and Cite are plain text; line breaks in Text are kept.
+// Embed is the oEmbed payload of an embed block, filled in on reads by
+// OEmbedResolver.Blocks; it is never stored.
+type ContentBlock struct {
+	Type    string         `json:"type"`
+	Text    string         `json:"text,omitempty"`
+	Level   int            `json:"level,omitempty"`
+	URL     string         `json:"url,omitempty"`
+	Alt     string         `json:"alt,omitempty"`
+	Caption string         `json:"caption,omitempty"`
+	Cite    string         `json:"cite,omitempty"`
+	Images  []GalleryImage `json:"images,omitempty"`
+	Embed   *OEmbed        `json:"embed,omitempty"`
+	MediaID string         `json:"mediaId,omitempty"`
+}
+
+// GalleryImage is an image of a gallery block.
+type GalleryImage struct {
+	URL     string `json:"url"`
+	Alt     string `json:"alt,omitempty"`
+	Caption string `json:"caption,omitempty"`
+	MediaID string `json:"mediaId,omitempty"`
+}
+
+// ValidateBlocks checks blocks against the block schema: known types, the
+// fields each type requires, heading levels, absolute http(s) URLs and the
+// MaxStoryBlocks and MaxGalleryImages limits. Fields that do not apply to a
+// block's type must be empty.
+func ValidateBlocks(blocks []ContentBlock) error {
+	if len(blocks) > MaxStoryBlocks {
+		return fmt.Errorf("%w: at most %d blocks", ErrInvalidBlocks, MaxStoryBlocks)
+	}
+	for i, block := range blocks {
+		if err := validateBlock(block); err != nil {
+			return fmt.Errorf("%w: block %d (%s): %s", ErrInvalidBlocks, i, block.Type, err)
+		}
+	}
+	return nil
+}
+
+// blockFields 為各類型 block 可使用的欄位 (type 以外)
+var blockFields = map[string][]string{
+	BlockParagraph: {"text"},
+	BlockHeading:   {"text", "level"},
+	BlockImage:     {"url", "alt", "caption", "mediaId"},
+	BlockEmbed:     {"url", "caption"},
+	BlockQuote:     {"text", "cite"},
+	BlockGallery:   {"images", "caption"},
+}
+
+// validateBlock 檢查單一 block 的類型、必填欄位與不適用的欄位
+func validateBlock(block ContentBlock) error {
+	allowed, ok := blockFields[block.Type]
+	if !ok {
+		return errors.New("unknown block type")
+	}
+	set := []struct {
+		name  string
+		isSet bool
+	}{
+		{"text", block.Text != ""}, {"level", block.Level != 0}, {"url", block.URL != ""}, {"alt", block.Alt != ""},
+		{"caption", block.Caption != ""}, {"cite", block.Cite != ""}, {"images", len(block.Images) > 0},
+		{"mediaId", block.MediaID != ""},
+	}
+	for _, field := range set {
+		if field.isSet && !slices.Contains(allowed, field.name) {
+			return fmt.Errorf("%s does not apply", field.name)
+		}
+	}
+
+	switch block.Type {
+	case BlockParagraph, BlockQuote:
+		if strings.TrimSpace(block.Text) == "" {
+			return errors.New("text is required")
+		}
+	case BlockHeading:
+		if strings.TrimSpace(block.Text) == "" {
+			return errors.New("text is required")
+		}
+		if block.Level < 2 || block.Level > 6 {
+			return errors.New("level must be between 2 and 6")
+		}
+	case BlockImage, BlockEmbed:
+		return checkBlockURL(block.URL)
+	case BlockGallery:
+		if len(block.Images) == 0 || len(block.Images) > MaxGalleryImages {
+			return fmt.Errorf("a gallery has 1 to %d images", MaxGalleryImages)
+		}
+		for i, image := range block.Images {
+			if err := checkBlockURL(image.URL); err != nil {
+				return fmt.Errorf("image %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkBlockURL 確認網址為 http 或 https 的絕對網址
+func checkBlockURL(raw string) error {
+	if raw == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q is not an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
+// blockRenderers 將各類型的 block 轉為 HTML；文字欄位一律跳脫
+var blockRenderers = map[string]func(sb *strings.Builder, block ContentBlock){
+	BlockParagraph: func(sb *strings.Builder, block ContentBlock) {
+		sb.WriteString("<p>" + blockText(block.Text) + "</p>")
+	},
+	BlockHeading: func(sb *strings.Builder, block ContentBlock) {
+		level := strconv.Itoa(block.Level)
+		sb.WriteString("<h" + level + ">" + blockText(block.Text) + "</h" + level + ">")
+	},
+	BlockImage: func(sb *strings.Builder, block ContentBlock) {
+		sb.WriteString(`<figure class="block-image">`)
+		writeBlockImage(sb, block.URL, block.Alt)
+		writeBlockCaption(sb, block.Caption)
+		sb.WriteString("</figure>")
+	},
+	BlockEmbed: func(sb *strings.Builder, block ContentBlock) {
+		u := html.EscapeString(block.URL)
+		sb.WriteString(`<figure class="block-embed" data-url="` + u + `">`)
+		// 已解析的 embed 輸出 provider 的 HTML，否則以連結代替
+		if block.Embed != nil && block.Embed.HTML != "" {
+			sb.WriteString(block.Embed.HTML)
+		} else {
+			sb.WriteString(`<a href="` + u + `">` + u + "</a>")
+		}
+		writeBlockCaption(sb, block.Caption)
+		sb.WriteString("</figure>")
+	},
+	BlockQuote: func(sb *strings.Builder, block ContentBlock) {
+		sb.WriteString(`<figure class="block-quote"><blockquote><p>` + blockText(block.Text) + "</p></blockquote>")
+		writeBlockCaption(sb, block.Cite)
+		sb.WriteString("</figure>")
+	},
+	BlockGallery: func(sb *strings.Builder, block ContentBlock) {
+		sb.WriteString(`<figure class="block-gallery">`)
+		for _, image := range block.Images {
+			sb.WriteString("<figure>")
+			writeBlockImage(sb, image.URL, image.Alt)
+			writeBlockCaption(sb, image.Caption)
+			sb.WriteString("</figure>")
+		}
+		writeBlockCaption(sb, block.Caption)
+		sb.WriteString("</figure>")
+	},
+}
+
+// RenderBlocks renders blocks to HTML, one line per block so revision
+// diffs compare blocks. Blocks of unknown types are skipped. Resolved embed
+// blocks are rendered with the provider's embed HTML.
+func RenderBlocks(blocks []ContentBlock) string {
+	return renderBlocks(blocks, func(_ ContentBlock, html string) string { return html })
+}
+
+// renderBlocks 逐一轉換 block，並以 clean 處理各 block 的 HTML
+func renderBlocks(blocks []ContentBlock, clean func(block ContentBlock, html string) string) string {
+	var sb strings.Builder
+	for _, block := range blocks {
+		render, ok := blockRenderers[block.Type]
+		if !ok {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		var out strings.Builder
+		render(&out, block)
+		sb.WriteString(clean(block, out.String()))
+	}
+	return sb.String()
+}
+
+// blockText 跳脫文字並將換行轉為 <br>
+func blockText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(strings.TrimSpace(text)), "\n", "<br>")
+}
+
+// writeBlockImage 寫入延遲載入的圖片
+func writeBlockImage(sb *strings.Builder, src, alt string) {
+	sb.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `" loading="lazy">`)
+}
+
+// writeBlockCaption 寫入圖說；沒有圖說時不輸出
+func writeBlockCaption(sb *strings.Builder, caption string) {
+	if caption != "" {
+		sb.WriteString("<figcaption>" + blockText(caption) + "</figcaption>")
+	}
+}
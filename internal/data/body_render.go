@@ -0,0 +1,115 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Story body formats (STORY_BODY_FORMAT).
+const (
+	BodyFormatHTML     = "html"
+	BodyFormatMarkdown = "markdown"
+)
+
+// Markdown extensions (MARKDOWN_EXTENSIONS), on top of CommonMark.
+const (
+	MarkdownExtensionTable         = "table"
+	MarkdownExtensionFootnote      = "footnote"
+	MarkdownExtensionStrikethrough = "strikethrough"
+	MarkdownExtensionLinkify       = "linkify"
+	MarkdownExtensionTaskList      = "tasklist"
+)
+
+// DefaultMarkdownExtensions are the extensions used when none are
+// configured.
+var DefaultMarkdownExtensions = []string{MarkdownExtensionTable, MarkdownExtensionFootnote}
+
+// markdownExtensions 為支援的 Markdown 擴充語法
+var markdownExtensions = []string{MarkdownExtensionTable, MarkdownExtensionFootnote, MarkdownExtensionStrikethrough,
+	MarkdownExtensionLinkify, MarkdownExtensionTaskList}
+
+// bodyRenderCachePrefix 為轉換結果的 cache key 前綴；key 依設定與原文的內容計算，不在 story: 前綴下，story 寫入時不需清除
+const bodyRenderCachePrefix = "render:body"
+
+// BodyRenderer renders story bodies to HTML. HTML bodies are cleaned with
+// the HTMLSanitizer on every read, which also covers content stored before
+// sanitizing on write; Markdown bodies are converted and sanitized, and
+// the result is cached by the hash of the source and the configured
+// extensions, apart from the cached stories, so editing a story only
+// re-renders its new body.
+type BodyRenderer struct {
+	format     string
+	extensions []string
+	markdown   func(source []byte) ([]byte, error)
+	sanitizer  *HTMLSanitizer
+	cache      *Cache
+}
+
+// NewBodyRenderer returns a renderer for bodies in format (BodyFormatHTML
+// when empty) caching in cache. extensions only apply to Markdown; nil
+// means DefaultMarkdownExtensions. sanitizer cleans HTML bodies and
+// rendered blocks; nil serves them as stored.
+func NewBodyRenderer(format string, extensions []string, sanitizer *HTMLSanitizer, cache *Cache) (*BodyRenderer, error) {
+	switch format {
+	case "", BodyFormatHTML:
+		return &BodyRenderer{format: BodyFormatHTML, sanitizer: sanitizer}, nil
+	case BodyFormatMarkdown:
+	default:
+		return nil, fmt.Errorf("unknown story body format %q", format)
+	}
+	if extensions == nil {
+		extensions = DefaultMarkdownExtensions
+	}
+	for _, ext := range extensions {
+		if !slices.Contains(markdownExtensions, ext) {
+			return nil, fmt.Errorf("unknown markdown extension %q", ext)
+		}
+	}
+	markdown, err := newMarkdownConverter(extensions)
+	if err != nil {
+		return nil, err
+	}
+	return &BodyRenderer{format: format, extensions: extensions, markdown: markdown, sanitizer: sanitizer, cache: cache}, nil
+}
+
+// Format returns the body format the renderer reads.
+func (r *BodyRenderer) Format() string {
+	if r == nil {
+		return BodyFormatHTML
+	}
+	return r.format
+}
+
+// Render returns body as HTML. A nil renderer returns body unchanged.
+func (r *BodyRenderer) Render(ctx context.Context, body string) (string, error) {
+	if r == nil || body == "" {
+		return body, nil
+	}
+	if r.markdown == nil {
+		return r.sanitizer.Sanitize(body), nil
+	}
+	key := NewCacheKey(bodyRenderCachePrefix).Field("format", r.format).Field("extensions", r.extensions).Field("source", body).String()
+	return NewTypedCache[string](r.cache).GetOrSet(ctx, key, 0, func(context.Context) (string, error) {
+		html, err := r.markdown([]byte(body))
+		if err != nil {
+			return "", fmt.Errorf("render markdown: %w", err)
+		}
+		return string(html), nil
+	})
+}
+
+// RenderBlocks renders blocks like RenderBlocks, cleaning each block with
+// the renderer's sanitizer except resolved embeds, whose HTML comes from a
+// trusted oEmbed provider and usually needs its script to display.
+func (r *BodyRenderer) RenderBlocks(blocks []ContentBlock) string {
+	if r == nil {
+		return RenderBlocks(blocks)
+	}
+	return renderBlocks(blocks, func(block ContentBlock, html string) string {
+		if block.Type == BlockEmbed && block.Embed != nil && block.Embed.HTML != "" {
+			return html
+		}
+		return r.sanitizer.Sanitize(html)
+	})
+}
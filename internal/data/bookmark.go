@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidBookmark is returned (wrapped) for a bookmark without a
+	// user.
+	ErrInvalidBookmark = errors.New("invalid bookmark")
+	// ErrBookmarksUnsupported is returned by a nil BookmarkService, i.e.
+	// when bookmarks are disabled.
+	ErrBookmarksUnsupported = errors.New("bookmarks are not enabled")
+)
+
+// Bookmark is a story saved by a reader.
+type Bookmark struct {
+	StoryID   string    `json:"storyId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BookmarkPage is a page of a reader's bookmarks. Pass NextCursor as after
+// to fetch the next page.
+type BookmarkPage struct {
+	Bookmarks   []Bookmark
+	NextCursor  string
+	HasNextPage bool
+}
+
+// BookmarkService stores the stories readers save for later in the
+// bookmarks table. Bookmarks belong to one reader, so unlike story data
+// they are never cached.
+type BookmarkService struct {
+	db *sql.DB
+}
+
+// NewBookmarkService returns a service storing bookmarks in db (see
+// internal/data/migrations).
+func NewBookmarkService(db *sql.DB) *BookmarkService {
+	return &BookmarkService{db: db}
+}
+
+// Add saves the story with storyID for userID. Saving a story twice keeps
+// the first bookmark.
+func (s *BookmarkService) Add(ctx context.Context, userID, storyID string) error {
+	if s == nil {
+		return ErrBookmarksUnsupported
+	}
+	if err := validateBookmarkUser(userID); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO bookmarks (user_id, story_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		userID, storyID); err != nil {
+		return fmt.Errorf("add bookmark: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the bookmark of userID on the story with storyID, if
+// any.
+func (s *BookmarkService) Remove(ctx context.Context, userID, storyID string) error {
+	if s == nil {
+		return ErrBookmarksUnsupported
+	}
+	if err := validateBookmarkUser(userID); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM bookmarks WHERE user_id = $1 AND story_id = $2`, userID, storyID); err != nil {
+		return fmt.Errorf("remove bookmark: %w", err)
+	}
+	return nil
+}
+
+// List returns a page of the bookmarks of userID, newest first. after is
+// the NextCursor of the previous page.
+func (s *BookmarkService) List(ctx context.Context, userID string, limit int, after string) (*BookmarkPage, error) {
+	if s == nil {
+		return nil, ErrBookmarksUnsupported
+	}
+	if err := validateBookmarkUser(userID); err != nil {
+		return nil, err
+	}
+	query := `SELECT story_id, created_at FROM bookmarks WHERE user_id = $1`
+	args := []interface{}{userID}
+	if after != "" {
+		// 收藏與留言的 cursor 格式相同：時間與 ID
+		createdAt, storyID, err := decodeCommentCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, story_id) < ($2, $3)`
+		args = append(args, createdAt, storyID)
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC, story_id DESC LIMIT %d`, limit+1)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list bookmarks: %w", err)
+	}
+	defer rows.Close()
+	bookmarks := []Bookmark{}
+	for rows.Next() {
+		var bookmark Bookmark
+		if err := rows.Scan(&bookmark.StoryID, &bookmark.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan bookmark: %w", err)
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list bookmarks: %w", err)
+	}
+
+	page := &BookmarkPage{Bookmarks: bookmarks}
+	if len(bookmarks) > limit {
+		page.Bookmarks, page.HasNextPage = bookmarks[:limit], true
+		last := page.Bookmarks[limit-1]
+		page.NextCursor = encodeCommentCursor(last.CreatedAt, last.StoryID)
+	}
+	return page, nil
+}
+
+// Bookmarked reports whether userID saved the story with storyID. It is
+// false on a nil BookmarkService.
+func (s *BookmarkService) Bookmarked(ctx context.Context, userID, storyID string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM bookmarks WHERE user_id = $1 AND story_id = $2)`,
+		userID, storyID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("get bookmark: %w", err)
+	}
+	return exists, nil
+}
+
+// validateBookmarkUser 檢查使用者 ID
+func validateBookmarkUser(userID string) error {
+	switch {
+	case strings.TrimSpace(userID) == "":
+		return fmt.Errorf("%w: user is required", ErrInvalidBookmark)
+	case len(userID) > maxCommentUserIDLength:
+		return fmt.Errorf("%w: user is longer than %d bytes", ErrInvalidBookmark, maxCommentUserIDLength)
+	}
+	return nil
+}
@@ -2,172 +2,697 @@ package data
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"crypto/cipher"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
-// Cache wraps Redis client with enabled flag.
-// If Redis connection fails, Enabled will be set to false.
+// ErrCacheMiss is returned by a CacheBackend when the key does not exist.
+var ErrCacheMiss = errors.New("cache miss")
+
+// CacheKeySchemaVersion is embedded in every key built by GenerateCacheKey.
+// Bump it whenever the shape of a cached struct changes so that entries
+// written by older deployments are ignored instead of failing to unmarshal.
+const CacheKeySchemaVersion = "v1"
+
+// CacheBackend is the storage behind Cache.
+// Implementations only deal with raw bytes; serialization, logging and the
+// enabled flag are handled by Cache.
+type CacheBackend interface {
+	// Get returns the stored value, or ErrCacheMiss if the key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key with the given TTL (0 means no expiry).
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Close releases resources held by the backend.
+	Close() error
+}
+
+// Cache wraps a CacheBackend with enabled flag.
+// A circuit breaker guards the backend: after repeated failures Cache serves
+// from the in-memory fallback when one is configured, otherwise it skips
+// caching, until a probe shows the backend has recovered.
 type Cache struct {
-	client  *redis.Client
-	enabled bool
-	ttl     time.Duration
-	env     string // 執行環境 (dev/staging/prod)
+	mu       sync.RWMutex
+	primary  CacheBackend // 主要 backend (Redis)
+	fallback CacheBackend // Redis 無法使用時改用的 in-memory LRU，可為 nil
+	enabled  bool
+	ttl      time.Duration
+	env      string // 執行環境 (dev/staging/prod)
+
+	localSize   int           // 本地 LRU 層的最大筆數，0 表示不啟用兩層快取
+	localTTL    time.Duration // 本地 LRU 層的 TTL
+	staleTTL    time.Duration // 過了 TTL 後仍可提供舊資料的時間，0 表示不啟用 stale-while-revalidate
+	negativeTTL time.Duration // 查無資料標記的 TTL，0 表示不啟用 negative caching
+	opTimeout   time.Duration // 每次 backend 操作的時限，0 表示只依呼叫端的 context
+
+	trackPopularity bool       // 是否記錄各項目的請求次數，供預熱使用
+	ttlJitter       float64    // TTL 隨機浮動比例，例如 0.1 表示 ±10%
+	codec           CacheCodec // 寫入時使用的序列化格式，預設 JSON
+
+	cipher            cipher.AEAD // 加密敏感資料用的 AES-GCM，nil 表示不加密
+	sensitivePrefixes []string    // 以這些前綴開頭的 key 一律加密
+
+	compression          CacheCompression // 大型 value 的壓縮方式
+	compressionThreshold int              // 超過此大小 (bytes) 才壓縮
+	maxValueSize         int              // 序列化 (與壓縮) 後超過此大小 (bytes) 的 value 不寫入，0 表示不限制
+
+	redisConn redisConnOptions // Redis 連線設定 (模式、sentinel 等)
+
+	keyPrefix string // 加在所有 key 前的 namespace 與版本，例如 "story:v3:"
+
+	metrics CacheMetrics  // 命中率、錯誤與延遲等指標
+	stats   *statsMetrics // 累計次數與最近一次錯誤，供 Stats 使用
+	logger  *slog.Logger  // 結構化日誌；每個 key 的 hit/miss 記錄在 Debug 等級
+
+	refreshing   sync.Map       // 正在背景更新的 key
+	hotKeys      *hotKeyTracker // 熱門 key 的存取計數，nil 表示不提前更新
+	refreshAhead time.Duration  // 熱門 key 在到期前多久開始背景更新
+
+	group    singleflight.Group // 合併同一個 key 的並行載入
+	inflight sync.Map           // 正在透過 group 載入的 key，供依 prefix 放棄載入使用
+
+	bus *invalidationBus // 跨 instance 的失效通知，未連上 Redis 時為 nil
+
+	breaker *circuitBreaker // 保護 primary 的 circuit breaker
+
+	done      chan struct{} // Close 時關閉，用來停止背景 goroutine
+	closeOnce sync.Once
+}
+
+// CacheOption customizes a Cache created by NewCache.
+type CacheOption func(*Cache)
+
+// WithFallbackLRU keeps up to size entries in an in-process LRU whenever
+// Redis is unreachable, instead of disabling caching entirely.
+// A size <= 0 disables the fallback.
+func WithFallbackLRU(size int) CacheOption {
+	return func(c *Cache) {
+		if size > 0 {
+			c.fallback = NewMemoryBackend(size)
+		}
+	}
+}
+
+// WithLocalTier puts an in-process LRU of size entries with a short ttl in
+// front of Redis. Writes and deletes are broadcast over Redis pub/sub so other
+// instances drop their local copies. A size <= 0 disables the local tier.
+func WithLocalTier(size int, ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.localSize = size
+		c.localTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate keeps entries for an extra window after their TTL.
+// During that window GetOrRefresh returns the stale value immediately and
+// refreshes it in the background. A window <= 0 disables the behaviour.
+func WithStaleWhileRevalidate(window time.Duration) CacheOption {
+	return func(c *Cache) {
+		if window > 0 {
+			c.staleTTL = window
+		}
+	}
+}
+
+// WithTTLJitter randomizes every TTL by ±fraction (e.g. 0.1 for ±10%) so keys
+// written in a burst do not all expire at the same instant.
+// Values outside (0, 1) disable jitter.
+func WithTTLJitter(fraction float64) CacheOption {
+	return func(c *Cache) {
+		if fraction > 0 && fraction < 1 {
+			c.ttlJitter = fraction
+		}
+	}
+}
+
+// WithCodec selects the serialization used for new entries. Entries written
+// with another codec are still decoded correctly. A nil codec keeps JSON.
+func WithCodec(codec CacheCodec) CacheOption {
+	return func(c *Cache) {
+		if codec != nil {
+			c.codec = codec
+		}
+	}
+}
+
+// WithCompression compresses values of at least threshold bytes using method
+// (gzip or zstd). Compressed entries are flagged in the entry header, so
+// uncompressed entries and other methods keep decoding correctly.
+func WithCompression(method CacheCompression, threshold int) CacheOption {
+	return func(c *Cache) {
+		c.compression = method
+		c.compressionThreshold = threshold
+	}
+}
+
+// WithMaxValueSize skips caching values whose serialized (and compressed)
+// size exceeds maxBytes, so a few huge payloads cannot evict thousands of
+// small entries. Skipped values are reported through CacheMetrics.Oversized.
+// A maxBytes <= 0 disables the limit.
+func WithMaxValueSize(maxBytes int) CacheOption {
+	return func(c *Cache) {
+		c.maxValueSize = maxBytes
+	}
 }
 
-// NewCache creates a new cache instance.
-// If Redis connection fails, enabled will be set to false.
-func NewCache(redisURL string, enabled bool, ttlSeconds int, env string) (*Cache, error) {
+// WithRedisMode selects how NewCache connects to Redis: RedisModeStandalone,
+// RedisModeCluster or RedisModeSentinel. An empty mode uses sentinel when
+// WithRedisSentinel is configured, and cluster for URLs that list extra nodes
+// with the addr query parameter.
+func WithRedisMode(mode string) CacheOption {
+	return func(c *Cache) {
+		c.redisConn.mode = mode
+	}
+}
+
+// WithRedisAuth sets the Redis ACL username and password, overriding any
+// credentials embedded in the Redis URL. Empty values keep the URL's.
+func WithRedisAuth(username, password string) CacheOption {
+	return func(c *Cache) {
+		c.redisConn.username = username
+		c.redisConn.password = password
+	}
+}
+
+// WithRedisTLS enables TLS with the given config for Redis connections,
+// taking precedence over the defaults implied by a rediss:// URL. A nil
+// config leaves the URL's TLS settings untouched.
+func WithRedisTLS(cfg *tls.Config) CacheOption {
+	return func(c *Cache) {
+		c.redisConn.tlsConfig = cfg
+	}
+}
+
+// WithRedisTimeouts sets the Redis dial, read and write timeouts. Zero values
+// keep the go-redis defaults (5s dial, 3s read, write equal to read).
+func WithRedisTimeouts(dial, read, write time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.redisConn.dialTimeout = dial
+		c.redisConn.readTimeout = read
+		c.redisConn.writeTimeout = write
+	}
+}
+
+// WithOperationTimeout bounds every cache operation (Get, Set, Delete, ...)
+// to d, so a slow Redis node degrades into a miss quickly instead of stalling
+// the request for its full deadline. A d <= 0 disables the bound.
+func WithOperationTimeout(d time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.opTimeout = d
+		c.redisConn.contextTimeout = d > 0
+	}
+}
+
+// WithRedisSentinel connects through Redis Sentinel using a failover client,
+// so a master failover does not take the cache down. The Redis URL becomes
+// optional and only supplies credentials and the DB number.
+func WithRedisSentinel(masterName string, sentinelAddrs []string, sentinelPassword string) CacheOption {
+	return func(c *Cache) {
+		c.redisConn.sentinelMaster = masterName
+		c.redisConn.sentinelAddrs = sentinelAddrs
+		c.redisConn.sentinelPassword = sentinelPassword
+	}
+}
+
+// WithKeyNamespace prefixes every key with namespace and version, e.g.
+// "story:v3:<key>", so several services can share one Redis and a version
+// bump invalidates all existing entries at once. Empty segments are omitted.
+func WithKeyNamespace(namespace, version string) CacheOption {
+	return func(c *Cache) {
+		c.keyPrefix = ""
+		for _, segment := range []string{namespace, version} {
+			if segment != "" {
+				c.keyPrefix += segment + ":"
+			}
+		}
+	}
+}
+
+// WithLogger sends cache logs to logger, tagged with component=cache.
+// Per-key hits, misses and writes are logged at Debug level, so production
+// handlers set to Info stay quiet. A nil logger keeps the default.
+func WithLogger(logger *slog.Logger) CacheOption {
+	return func(c *Cache) {
+		if logger != nil {
+			c.logger = logger.With("component", "cache")
+		}
+	}
+}
+
+// NewCache creates a new cache instance backed by Redis.
+// If Redis connection fails, the cache uses the fallback LRU when configured,
+// otherwise enabled will be set to false.
+func NewCache(redisURL string, enabled bool, ttlSeconds int, env string, opts ...CacheOption) (*Cache, error) {
 	cache := &Cache{
 		enabled: false,
 		ttl:     time.Duration(ttlSeconds) * time.Second,
 		env:     env,
+		codec:   jsonCodec{},
+		metrics: noopCacheMetrics{},
+		logger:  newDefaultLogger(env),
+		breaker: newCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+		done:    make(chan struct{}),
 	}
 
+	for _, opt := range opts {
+		opt(cache)
+	}
+	cache.initStats()
+	cache.redisConn.url = redisURL
+
 	if !enabled {
-		cache.logInfo("[Redis] Cache disabled (REDIS_ENABLED=false)")
+		cache.logger.Info("cache disabled", "reason", "REDIS_ENABLED=false")
 		return cache, nil
 	}
 
-	if redisURL == "" {
-		cache.logInfo("[Redis] Cache disabled (REDIS_URL not set)")
+	if redisURL == "" && cache.redisConn.resolvedMode() != RedisModeSentinel {
+		cache.logger.Info("cache disabled", "reason", "REDIS_URL not set")
 		return cache, nil
 	}
 
-	cache.logInfo("[Redis] Initializing cache with URL: %s, TTL: %d seconds", redisURL, ttlSeconds)
+	cache.logger.Info("initializing redis cache", "mode", cache.redisConn.resolvedMode(), "url", redisURL, "ttl", cache.ttl)
 
-	opt, err := redis.ParseURL(redisURL)
+	client, err := newRedisClient(cache.redisConn)
 	if err != nil {
-		cache.logError("[Redis] Failed to parse Redis URL: %v", err)
+		cache.logger.Error("failed to create redis client", "error", err)
+		// 無法建立 client 時只能使用 fallback
+		cache.enabled = cache.fallback != nil
 		return cache, nil
 	}
 
-	client := redis.NewClient(opt)
+	cache.bus = newInvalidationBus(client, cache.logger)
+	cache.bus.subscribe(cache.handleInvalidation)
+	cache.primary = NewRedisBackend(client)
+	if cache.localSize > 0 && cache.localTTL > 0 {
+		cache.primary = newTieredBackend(cache.primary, cache.bus, cache.localSize, cache.localTTL)
+		cache.logger.Info("local cache tier enabled", "size", cache.localSize, "ttl", cache.localTTL)
+	}
 
-	// 測試連線，如果失敗則將 enabled 設為 false
+	// 測試連線，如果失敗則直接開啟 breaker，冷卻後再試探 Redis
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	cache.enabled = true
 	if err := client.Ping(ctx).Err(); err != nil {
-		cache.logError("[Redis] Connection failed: %v", err)
-		_ = client.Close()
+		cache.logger.Error("redis connection failed", "error", err)
+		cache.breaker.trip(time.Now())
 		return cache, nil
 	}
 
-	cache.client = client
-	cache.enabled = true
-	cache.logInfo("[Redis] Cache enabled and connected successfully")
+	cache.logger.Info("redis cache connected")
 	return cache, nil
 }
 
+// NewCacheWithBackend creates an enabled cache on top of the given backend.
+// A nil backend yields a disabled cache.
+func NewCacheWithBackend(backend CacheBackend, ttlSeconds int, env string, opts ...CacheOption) *Cache {
+	cache := &Cache{
+		primary: backend,
+		enabled: backend != nil,
+		ttl:     time.Duration(ttlSeconds) * time.Second,
+		env:     env,
+		codec:   jsonCodec{},
+		metrics: noopCacheMetrics{},
+		logger:  newDefaultLogger(env),
+		breaker: newCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	cache.initStats()
+	return cache
+}
+
 // Enabled returns whether cache is enabled.
 func (c *Cache) Enabled() bool {
-	return c.enabled && c.client != nil
+	c.mu.RLock()
+	enabled := c.enabled
+	c.mu.RUnlock()
+	if !enabled {
+		return false
+	}
+	return c.fallback != nil || (c.primary != nil && c.breaker.available(time.Now()))
 }
 
-// logInfo 輸出資訊類日誌，prod 環境不輸出
-func (c *Cache) logInfo(format string, v ...interface{}) {
-	if c.env != "prod" {
-		log.Printf(format, v...)
+// active returns the backend that should serve this request: the primary
+// while the circuit breaker allows it, otherwise the fallback (nil when none
+// is configured or the cache is disabled).
+func (c *Cache) active() CacheBackend {
+	c.mu.RLock()
+	enabled := c.enabled
+	c.mu.RUnlock()
+	if !enabled {
+		return nil
 	}
+	if c.primary != nil && c.breaker.allow(time.Now()) {
+		return c.primary
+	}
+	return c.fallback
 }
 
-// logError 輸出錯誤日誌，所有環境都輸出
-func (c *Cache) logError(format string, v ...interface{}) {
-	log.Printf(format, v...)
+// newDefaultLogger 建立未注入 logger 時使用的預設 logger：prod 環境只輸出 Info 以上，
+// 其他環境連同 Debug 的 hit/miss 紀錄一併輸出
+func newDefaultLogger(env string) *slog.Logger {
+	level := slog.LevelDebug
+	if env == "prod" {
+		level = slog.LevelInfo
+	}
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	return slog.New(handler).With("component", "cache")
 }
 
-// Close closes the Redis client.
+// Close stops background goroutines, unsubscribes from the invalidation bus
+// and closes the primary backend and the fallback.
 func (c *Cache) Close() error {
-	if c.client != nil {
-		return c.client.Close()
+	c.closeOnce.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.bus != nil {
+		_ = c.bus.Close()
 	}
-	return nil
+	if c.primary != nil {
+		err = c.primary.Close()
+	}
+	if c.fallback != nil {
+		if fbErr := c.fallback.Close(); err == nil {
+			err = fbErr
+		}
+	}
+	return err
 }
 
 // Get retrieves a value from cache.
+// Entries past their soft expiry are treated as misses; use GetOrRefresh to
+// serve them while they are refreshed in the background.
+// For a not-found marker written by SetNotFound, dest is set to its zero
+// value and Get returns true with ErrCachedNotFound.
 func (c *Cache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	_, found, err := c.get(ctx, key, dest)
+	return found, err
+}
+
+// get 與 Get 相同，另外回傳命中的 entry 供呼叫端判斷到期時間
+func (c *Cache) get(ctx context.Context, key string, dest interface{}) (cacheEntry, bool, error) {
 	if !c.Enabled() {
-		return false, nil
+		return cacheEntry{}, false, nil
+	}
+	entry, found := c.lookup(ctx, key)
+	if !found {
+		c.metrics.Miss(CacheOpGet, 1)
+		c.traceMiss(ctx, 1)
+		return cacheEntry{}, false, nil
+	}
+	if entry.notFound() {
+		c.metrics.Hit(CacheOpGet, 1)
+		c.traceHit(ctx, entry)
+		c.logger.Debug("cache hit (not found)", "key", key)
+		if err := zeroDest(dest); err != nil {
+			return cacheEntry{}, false, err
+		}
+		return entry, true, ErrCachedNotFound
+	}
+	if entry.stale(time.Now()) {
+		c.metrics.Miss(CacheOpGet, 1)
+		c.traceMiss(ctx, 1)
+		c.logger.Debug("cache stale", "key", key)
+		return cacheEntry{}, false, nil
 	}
 
-	val, err := c.client.Get(ctx, key).Result()
-	if errors.Is(err, redis.Nil) {
-		c.logInfo("[Redis] Cache miss: %s", key)
-		return false, nil
+	if err := c.decodeValue(key, entry, dest); err != nil {
+		c.metrics.Error(CacheOpDecode)
+		c.traceMiss(ctx, 1)
+		c.logger.Error("cache unmarshal failed", "key", key, "error", err)
+		return cacheEntry{}, false, fmt.Errorf("unmarshal cache value: %w", err)
 	}
-	if err != nil {
-		c.logError("[Redis] Get error for key %s: %v (disabling cache)", key, err)
-		// 如果讀取失敗，可能是連線問題，將 enabled 設為 false
-		c.enabled = false
-		return false, nil
+
+	c.metrics.Hit(CacheOpGet, 1)
+	c.traceHit(ctx, entry)
+	c.logger.Debug("cache hit", "key", key)
+	return entry, true, nil
+}
+
+// lookup 從 backend 讀取並解析 entry；miss、錯誤或舊格式皆回傳 false
+func (c *Cache) lookup(ctx context.Context, key string) (cacheEntry, bool) {
+	backend := c.active()
+	if backend == nil {
+		return cacheEntry{}, false
 	}
 
-	if err := json.Unmarshal([]byte(val), dest); err != nil {
-		c.logError("[Redis] Unmarshal error for key %s: %v", key, err)
-		return false, fmt.Errorf("unmarshal cache value: %w", err)
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	val, err := backend.Get(ctx, c.fullKey(key))
+	c.observe(CacheOpGet, start)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		c.metrics.Error(CacheOpGet)
+		c.logger.Error("cache get failed", "key", key, "error", err)
+		// 如果讀取失敗，可能是連線問題，計入 circuit breaker
+		c.handleBackendError(backend, err)
+		return cacheEntry{}, false
+	}
+	c.handleBackendSuccess(backend)
+	if err != nil {
+		c.logger.Debug("cache miss", "key", key)
+		return cacheEntry{}, false
 	}
 
-	c.logInfo("[Redis] Cache hit: %s", key)
-	return true, nil
+	entry, err := decodeEntry(val)
+	if err != nil {
+		c.logger.Debug("cache miss", "key", key, "reason", err)
+		return cacheEntry{}, false
+	}
+	return entry, true
 }
 
-// Set stores a value in cache.
+// Set stores a value in cache using the configured TTL.
 func (c *Cache) Set(ctx context.Context, key string, value interface{}) error {
-	if !c.Enabled() {
+	return c.store(ctx, key, value, c.ttl)
+}
+
+// SetWithTTL stores a value in cache with its own TTL, e.g. a long TTL for
+// published stories and a short one for listings.
+// A ttl <= 0 falls back to the configured TTL.
+func (c *Cache) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	return c.store(ctx, key, value, ttl)
+}
+
+// TTL returns the default TTL applied by Set.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// store 序列化 value 並寫入 backend。ttl 為 soft TTL，若啟用 stale-while-revalidate，
+// backend 實際保存時間會再加上 staleTTL
+func (c *Cache) store(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	backend := c.active()
+	if backend == nil {
 		return nil
 	}
 
-	data, err := json.Marshal(value)
+	data, flags, err := c.encodeValue(key, value)
 	if err != nil {
-		c.logError("[Redis] Marshal error for key %s: %v", key, err)
+		c.metrics.Error(CacheOpMarshal)
+		c.logger.Error("cache marshal failed", "key", key, "error", err)
 		return fmt.Errorf("marshal cache value: %w", err)
 	}
 
-	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
-		c.logError("[Redis] Set error for key %s: %v (disabling cache)", key, err)
-		// 如果寫入失敗，可能是連線問題，將 enabled 設為 false
-		c.enabled = false
+	if c.oversized(key, len(data)) {
+		return nil
+	}
+
+	ttl = c.jitterTTL(ttl)
+	entry := c.newEntry(time.Now(), data, flags, ttl)
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	err = backend.Set(ctx, c.fullKey(key), encodeEntry(entry), c.hardTTL(ttl))
+	c.observe(CacheOpSet, start)
+	if err != nil {
+		c.metrics.Error(CacheOpSet)
+		c.logger.Error("cache set failed", "key", key, "error", err)
+		// 如果寫入失敗，可能是連線問題，計入 circuit breaker
+		c.handleBackendError(backend, err)
 		return nil // 不返回錯誤，讓查詢繼續進行
 	}
+	c.handleBackendSuccess(backend)
 
-	c.logInfo("[Redis] Cache set: %s (TTL: %v)", key, c.ttl)
+	c.metrics.Set(1)
+	c.logger.Debug("cache set", "key", key, "ttl", ttl)
 	return nil
 }
 
+// oversized 判斷序列化後的 value 是否超過 maxValueSize；超過時記錄 metrics 與 log
+func (c *Cache) oversized(key string, size int) bool {
+	if c.maxValueSize <= 0 || size <= c.maxValueSize {
+		return false
+	}
+	c.metrics.Oversized(size)
+	c.logger.Warn("cache value too large, skipped", "key", key, "size", size, "max", c.maxValueSize)
+	return true
+}
+
+// opContext 為單次 backend 操作加上 opTimeout 時限，讓緩慢的 Redis 盡快視為 miss
+func (c *Cache) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.opTimeout)
+}
+
+// fullKey 回傳加上 namespace 與版本的實際 key
+func (c *Cache) fullKey(key string) string {
+	return c.keyPrefix + key
+}
+
+// newEntry 建立 entry 並記錄 soft expiry (stale-while-revalidate、熱門 key 提前更新與
+// HTTP 快取標頭都依此判斷剩餘時間)；未啟用 stale 視窗時與 backend 的 TTL 相同
+func (c *Cache) newEntry(now time.Time, payload []byte, flags byte, ttl time.Duration) cacheEntry {
+	entry := cacheEntry{flags: flags, storedAt: now, payload: payload}
+	if ttl > 0 {
+		entry.softExpiresAt = now.Add(ttl)
+	}
+	return entry
+}
+
+// encodeValue 以設定的 codec 序列化 value (必要時壓縮，敏感資料再加密)，並回傳記錄
+// codec、壓縮方式與是否加密的 flags
+func (c *Cache) encodeValue(key string, value interface{}) ([]byte, byte, error) {
+	codec := c.codec
+	if codec == nil {
+		codec = jsonCodec{}
+	}
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, compressFlags, err := compressPayload(data, c.compression, c.compressionThreshold)
+	if err != nil {
+		return nil, 0, fmt.Errorf("compress: %w", err)
+	}
+	flags := codecID(codec) | compressFlags
+	if c.cipher != nil && c.sensitive(key, value) {
+		if data, err = c.encryptPayload(c.fullKey(key), data); err != nil {
+			return nil, 0, fmt.Errorf("encrypt: %w", err)
+		}
+		flags |= entryFlagEncrypted
+	}
+	return data, flags, nil
+}
+
+// decodeValue 依 entry flags 記錄的加密、壓縮方式與 codec 解碼，與目前設定無關
+func (c *Cache) decodeValue(key string, entry cacheEntry, dest interface{}) error {
+	codec, ok := cacheCodecs[entry.flags&codecFlagMask]
+	if !ok {
+		return fmt.Errorf("unknown codec id %d", entry.flags&codecFlagMask)
+	}
+	payload := entry.payload
+	if entry.flags&entryFlagEncrypted != 0 {
+		var err error
+		if payload, err = c.decryptPayload(c.fullKey(key), payload); err != nil {
+			return fmt.Errorf("decrypt: %w", err)
+		}
+	}
+	payload, err := decompressPayload(payload, entry.flags)
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+	return codec.Unmarshal(payload, dest)
+}
+
+// hardTTL 回傳 backend 實際保存的時間 (soft TTL 加上 stale 視窗)
+func (c *Cache) hardTTL(ttl time.Duration) time.Duration {
+	if c.staleTTL > 0 && ttl > 0 {
+		return ttl + c.staleTTL
+	}
+	return ttl
+}
+
+// jitterTTL 依 ttlJitter 將 ttl 隨機調整 ±fraction
+func (c *Cache) jitterTTL(ttl time.Duration) time.Duration {
+	if c.ttlJitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := (rand.Float64()*2 - 1) * c.ttlJitter * float64(ttl)
+	return ttl + time.Duration(delta)
+}
+
 // Delete removes a key from cache.
 func (c *Cache) Delete(ctx context.Context, key string) error {
-	if !c.Enabled() {
+	backend := c.active()
+	if backend == nil {
 		return nil
 	}
 
-	if err := c.client.Del(ctx, key).Err(); err != nil {
-		c.logError("[Redis] Delete error for key %s: %v (disabling cache)", key, err)
-		// 如果刪除失敗，可能是連線問題，將 enabled 設為 false
-		c.enabled = false
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	err := backend.Delete(ctx, c.fullKey(key))
+	c.observe(CacheOpDelete, start)
+	if err != nil {
+		c.metrics.Error(CacheOpDelete)
+		c.logger.Error("cache delete failed", "key", key, "error", err)
+		// 如果刪除失敗，可能是連線問題，計入 circuit breaker
+		c.handleBackendError(backend, err)
 		return nil
 	}
+	c.handleBackendSuccess(backend)
+	c.publishInvalidation(ctx, backend, c.fullKey(key))
 
-	c.logInfo("[Redis] Cache deleted: %s", key)
+	c.metrics.Delete(1)
+	c.logger.Debug("cache deleted", "key", key)
 	return nil
 }
 
-// GenerateCacheKey generates a cache key from query parameters.
-func GenerateCacheKey(prefix string, params interface{}) string {
-	data, err := json.Marshal(params)
-	if err != nil {
-		// 如果序列化失敗，使用簡單的 key
-		return fmt.Sprintf("%s:fallback", prefix)
+// Do runs loader for key, making sure only one loader per key is in flight;
+// concurrent callers wait for and share its result instead of hitting the
+// database simultaneously. The loader runs with a context detached from the
+// leading caller's cancellation so one aborted request does not fail the rest.
+// Invalidating key on any instance detaches callers arriving afterwards from
+// a load already in flight. Do is safe to call on a nil Cache, in which case
+// loader simply runs.
+func (c *Cache) Do(ctx context.Context, key string, loader func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return loader(ctx)
 	}
 
-	hash := sha256.Sum256(data)
-	hashStr := hex.EncodeToString(hash[:])
-	return fmt.Sprintf("%s:%s", prefix, hashStr)
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		c.inflight.Store(key, struct{}{})
+		defer c.inflight.Delete(key)
+		return loader(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			c.logger.Debug("shared in-flight load", "key", key)
+		}
+		return res.Val, res.Err
+	}
+}
+
+// GenerateCacheKey generates a cache key from query parameters, in the form
+// <prefix>:<CacheKeySchemaVersion>:<sha256 of params>. params are
+// canonicalized as described on CacheKeyBuilder.
+func GenerateCacheKey(prefix string, params interface{}) string {
+	return NewCacheKey(prefix).Fields(params).String()
 }
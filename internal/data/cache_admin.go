@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// CacheKeyInfo describes a single cache entry as returned by Inspect.
+type CacheKeyInfo struct {
+	Key           string      `json:"key"`
+	Found         bool        `json:"found"`
+	TTL           string      `json:"ttl,omitempty"` // backend 剩餘保存時間，空字串表示不過期或無法取得
+	StoredAt      *time.Time  `json:"storedAt,omitempty"`
+	SoftExpiresAt *time.Time  `json:"softExpiresAt,omitempty"`
+	NotFound      bool        `json:"notFound,omitempty"` // 是否為 SetNotFound 寫入的查無資料標記
+	Codec         string      `json:"codec,omitempty"`
+	Compressed    bool        `json:"compressed,omitempty"`
+	Encrypted     bool        `json:"encrypted,omitempty"`
+	Size          int         `json:"size"` // backend 中的大小 (bytes)，含 entry header
+	Value         interface{} `json:"value,omitempty"`
+	DecodeError   string      `json:"decodeError,omitempty"`
+}
+
+// ttlBackend is implemented by backends that can report a key's remaining TTL.
+type ttlBackend interface {
+	// TTL returns the remaining time to live of key, or 0 when it has none.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// Inspect reads key directly from the backend and describes the stored entry,
+// including its decoded value and remaining TTL. It is meant for operators;
+// hits and misses are not counted.
+func (c *Cache) Inspect(ctx context.Context, key string) (CacheKeyInfo, error) {
+	info := CacheKeyInfo{Key: key}
+	backend := c.active()
+	if backend == nil {
+		return info, nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	raw, err := backend.Get(ctx, c.fullKey(key))
+	if errors.Is(err, ErrCacheMiss) {
+		return info, nil
+	}
+	if err != nil {
+		return info, err
+	}
+	info.Found = true
+	info.Size = len(raw)
+
+	if tb, ok := backend.(ttlBackend); ok {
+		if ttl, err := tb.TTL(ctx, c.fullKey(key)); err == nil && ttl > 0 {
+			info.TTL = ttl.Round(time.Second).String()
+		}
+	}
+
+	entry, err := decodeEntry(raw)
+	if err != nil {
+		info.DecodeError = err.Error()
+		return info, nil
+	}
+	info.StoredAt = &entry.storedAt
+	if !entry.softExpiresAt.IsZero() {
+		info.SoftExpiresAt = &entry.softExpiresAt
+	}
+	info.NotFound = entry.notFound()
+	info.Compressed = entry.flags&compressFlagMask != compressNone
+	info.Encrypted = entry.flags&entryFlagEncrypted != 0
+	if codec, ok := cacheCodecs[entry.flags&codecFlagMask]; ok {
+		info.Codec = codec.Name()
+	}
+	if info.NotFound {
+		return info, nil
+	}
+	if err := c.decodeValue(key, entry, &info.Value); err != nil {
+		info.DecodeError = err.Error()
+	}
+	return info, nil
+}
+
+// DecodeRaw decodes bytes stored in the backend under backendKey (the key
+// including namespace) into dest, e.g. values captured by a recording backend
+// in tests. It returns ErrCachedNotFound for entries written by SetNotFound.
+func (c *Cache) DecodeRaw(backendKey string, raw []byte, dest interface{}) error {
+	entry, err := decodeEntry(raw)
+	if err != nil {
+		return err
+	}
+	if entry.notFound() {
+		return ErrCachedNotFound
+	}
+	return c.decodeValue(strings.TrimPrefix(backendKey, c.keyPrefix), entry, dest)
+}
+
+// SetEnabled turns caching on or off at runtime, e.g. to bypass a misbehaving
+// cache during an incident. Turning it back on only takes effect when a
+// backend was configured at startup.
+func (c *Cache) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	c.enabled = enabled && (c.primary != nil || c.fallback != nil)
+	c.mu.Unlock()
+
+	state := c.state()
+	c.metrics.StateChange(state)
+	c.logger.Warn("cache toggled at runtime", "enabled", enabled, "state", state)
+}
+
+func (b *redisBackend) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := b.client.PTTL(ctx, key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+func (b *tieredBackend) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if tb, ok := b.remote.(ttlBackend); ok {
+		return tb.TTL(ctx, key)
+	}
+	return 0, nil
+}
+
+func (b *memoryBackend) TTL(_ context.Context, key string) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.items[key]
+	if !ok {
+		return 0, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if entry.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(entry.expiresAt), nil
+}
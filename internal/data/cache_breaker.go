@@ -0,0 +1,158 @@
+package data
+
+import (
+	"sync"
+	"time"
+)
+
+// Default circuit breaker settings used when WithCircuitBreaker is not given.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常使用 primary
+	breakerOpen                         // 暫停使用 primary，改用 fallback 或停用
+	breakerHalfOpen                     // 冷卻時間已過，放行一個請求試探 primary
+)
+
+// circuitBreaker guards the primary backend: it opens after threshold
+// consecutive failures, lets a single probe through once cooldown has
+// passed, and closes again when that probe succeeds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     breakerState
+	failures  int       // 連續失敗次數
+	openedAt  time.Time // 最近一次開啟的時間
+	probeAt   time.Time // 半開狀態下最近一次放行試探的時間
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// WithCircuitBreaker stops using Redis after threshold consecutive failures
+// and serves from the fallback (or skips caching) for cooldown, after which a
+// single request probes Redis again; a successful probe restores it.
+// Defaults to 5 failures and a 30s cooldown.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+// allow 回傳這次請求是否可以使用 primary；半開狀態下同時只放行一個試探，
+// 若試探結果遲遲未回報 (例如請求提早結束)，超過 cooldown 後再放行下一個
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeAt = now
+		return true
+	case breakerHalfOpen:
+		if now.Sub(b.probeAt) < b.cooldown {
+			return false
+		}
+		b.probeAt = now
+		return true
+	}
+	return true
+}
+
+// available 回傳 primary 目前是否可能被使用，不會佔用試探名額
+func (b *circuitBreaker) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		return now.Sub(b.openedAt) >= b.cooldown
+	case breakerHalfOpen:
+		return now.Sub(b.probeAt) >= b.cooldown
+	}
+	return true
+}
+
+// success 記錄一次成功；回傳 breaker 是否因此由開啟轉為關閉
+func (b *circuitBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == breakerClosed {
+		return false
+	}
+	b.state = breakerClosed
+	return true
+}
+
+// failure 記錄一次失敗；回傳 breaker 是否因此開啟
+func (b *circuitBreaker) failure(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	switch b.state {
+	case breakerClosed:
+		if b.failures < b.threshold {
+			return false
+		}
+	case breakerOpen:
+		return false
+	}
+	b.state = breakerOpen
+	b.openedAt = now
+	return true
+}
+
+// trip 直接開啟 breaker，例如啟動時就無法連線
+func (b *circuitBreaker) trip(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerOpen
+	b.openedAt = now
+}
+
+// handleBackendError 在 backend 發生錯誤時呼叫；記錄最近一次錯誤供 Stats 使用，
+// primary 連續失敗達門檻時開啟 breaker，冷卻期間改用 fallback (未設定時暫停 cache)
+func (c *Cache) handleBackendError(failed CacheBackend, err error) {
+	c.stats.recordError(err)
+	if failed != c.primary {
+		return
+	}
+	if !c.breaker.failure(time.Now()) {
+		return
+	}
+	state := CacheStateDisabled
+	if c.fallback != nil {
+		state = CacheStateFallback
+	}
+	c.metrics.StateChange(state)
+	c.logger.Warn("circuit breaker opened", "serving", state, "cooldown", c.breaker.cooldown)
+}
+
+// handleBackendSuccess 在 backend 操作成功時呼叫；半開試探成功時關閉 breaker
+func (c *Cache) handleBackendSuccess(succeeded CacheBackend) {
+	if succeeded != c.primary {
+		return
+	}
+	if c.breaker.success() {
+		c.metrics.StateChange(CacheStatePrimary)
+		c.logger.Warn("circuit breaker closed, primary backend restored")
+	}
+}
@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cacheInvalidationChannel is the Redis pub/sub channel used to tell other
+// instances that a key changed or was deleted.
+const cacheInvalidationChannel = "go-story:cache:invalidate"
+
+// cachePrefixInvalidationChannel tells other instances to drop every entry
+// starting with a prefix.
+const cachePrefixInvalidationChannel = "go-story:cache:invalidate-prefix"
+
+// invalidation is a message received from another instance on the bus.
+// key holds the full backend key (or key prefix when prefix is set).
+type invalidation struct {
+	key    string
+	prefix bool
+}
+
+// invalidationBus broadcasts invalidations between instances over Redis
+// pub/sub. Messages are "<instance id>|<key>", and each instance ignores the
+// ones it sent itself.
+type invalidationBus struct {
+	client     redis.UniversalClient
+	pubsub     *redis.PubSub
+	instanceID string
+
+	mu       sync.RWMutex
+	handlers []func(invalidation)
+}
+
+// newInvalidationBus subscribes to the invalidation channels on client and
+// dispatches incoming messages to the registered handlers.
+func newInvalidationBus(client redis.UniversalClient, logger *slog.Logger) *invalidationBus {
+	b := &invalidationBus{
+		client:     client,
+		instanceID: newInstanceID(),
+	}
+	b.pubsub = client.Subscribe(context.Background(), cacheInvalidationChannel, cachePrefixInvalidationChannel)
+	go b.listen(logger)
+	return b
+}
+
+// subscribe 註冊收到其他 instance 的失效通知時要執行的 handler
+func (b *invalidationBus) subscribe(handler func(invalidation)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// listen 接收其他 instance 發出的失效通知並交給各 handler
+func (b *invalidationBus) listen(logger *slog.Logger) {
+	for msg := range b.pubsub.Channel() {
+		sender, key, ok := strings.Cut(msg.Payload, "|")
+		if !ok || sender == b.instanceID {
+			continue
+		}
+		inv := invalidation{key: key, prefix: msg.Channel == cachePrefixInvalidationChannel}
+
+		b.mu.RLock()
+		handlers := b.handlers
+		b.mu.RUnlock()
+		for _, handler := range handlers {
+			handler(inv)
+		}
+		logger.Debug("invalidation received from peer", "key", key, "prefix", inv.prefix)
+	}
+}
+
+// publish 通知其他 instance 這些 key 已變更或刪除；多個 key 以 pipeline 一次送出
+func (b *invalidationBus) publish(ctx context.Context, keys ...string) error {
+	switch len(keys) {
+	case 0:
+		return nil
+	case 1:
+		return b.client.Publish(ctx, cacheInvalidationChannel, b.instanceID+"|"+keys[0]).Err()
+	}
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Publish(ctx, cacheInvalidationChannel, b.instanceID+"|"+key)
+		}
+		return nil
+	})
+	return err
+}
+
+// publishPrefix 通知其他 instance 刪除所有以 prefix 開頭的 entry
+func (b *invalidationBus) publishPrefix(ctx context.Context, prefix string) error {
+	return b.client.Publish(ctx, cachePrefixInvalidationChannel, b.instanceID+"|"+prefix).Err()
+}
+
+// Close unsubscribes from the invalidation channels.
+func (b *invalidationBus) Close() error {
+	return b.pubsub.Close()
+}
+
+// publishInvalidation 在 primary 刪除成功後透過 bus 通知其他 instance，並放棄本機
+// 進行中的載入，避免載入結果把舊資料寫回 cache
+func (c *Cache) publishInvalidation(ctx context.Context, backend CacheBackend, keys ...string) {
+	for _, key := range keys {
+		c.forget(key)
+	}
+	if c.bus == nil || backend != c.primary || len(keys) == 0 {
+		return
+	}
+	if err := c.bus.publish(ctx, keys...); err != nil {
+		c.logger.Warn("publish cache invalidation failed", "keys", len(keys), "error", err)
+	}
+}
+
+// publishPrefixInvalidation 與 publishInvalidation 相同，但對象為 prefix
+func (c *Cache) publishPrefixInvalidation(ctx context.Context, backend CacheBackend, prefix string) {
+	c.forgetPrefix(prefix)
+	if c.bus == nil || backend != c.primary {
+		return
+	}
+	if err := c.bus.publishPrefix(ctx, prefix); err != nil {
+		c.logger.Warn("publish cache prefix invalidation failed", "prefix", prefix, "error", err)
+	}
+}
+
+// handleInvalidation 處理其他 instance 發出的失效通知
+func (c *Cache) handleInvalidation(inv invalidation) {
+	if inv.prefix {
+		c.forgetPrefix(inv.key)
+		return
+	}
+	c.forget(inv.key)
+}
+
+// forget 讓進行中的 singleflight 載入不再被之後的呼叫共用 (fullKey 為含 namespace 的 key)
+func (c *Cache) forget(fullKey string) {
+	if key, ok := strings.CutPrefix(fullKey, c.keyPrefix); ok {
+		c.group.Forget(key)
+	}
+}
+
+// forgetPrefix 對所有以 fullPrefix 開頭的進行中載入執行 forget
+func (c *Cache) forgetPrefix(fullPrefix string) {
+	prefix, ok := strings.CutPrefix(fullPrefix, c.keyPrefix)
+	if !ok {
+		return
+	}
+	c.inflight.Range(func(k, _ interface{}) bool {
+		if key := k.(string); strings.HasPrefix(key, prefix) {
+			c.group.Forget(key)
+		}
+		return true
+	})
+}
+
+// newInstanceID 產生隨機的 instance 識別碼，用來忽略自己發出的失效通知
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
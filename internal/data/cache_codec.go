@@ -0,0 +1,104 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// CacheCodec serializes cache values. Every codec honours the `json` struct
+// tags so the cached shape matches the GraphQL output.
+type CacheCodec interface {
+	// Name is the identifier used in configuration (CACHE_CODEC).
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// codec IDs 記錄在 entry flags 的低 2 bits，讓切換 codec 後舊資料仍能正確解碼
+const (
+	codecJSON    byte = 0
+	codecMsgpack byte = 1
+	codecCBOR    byte = 2
+
+	codecFlagMask byte = 0x03
+)
+
+var cacheCodecs = map[byte]CacheCodec{
+	codecJSON:    jsonCodec{},
+	codecMsgpack: msgpackCodec{},
+	codecCBOR:    newCBORCodec(),
+}
+
+// CacheCodecByName returns the codec registered under name ("json", "msgpack"
+// or "cbor"). An empty name selects JSON.
+func CacheCodecByName(name string) (CacheCodec, error) {
+	if name == "" {
+		return jsonCodec{}, nil
+	}
+	for _, codec := range cacheCodecs {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cache codec %q", name)
+}
+
+// codecID 回傳 codec 對應的 flags 值
+func codecID(codec CacheCodec) byte {
+	for id, registered := range cacheCodecs {
+		if registered.Name() == codec.Name() {
+			return id
+		}
+	}
+	return codecJSON
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                               { return "json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetOmitEmpty(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+type cborCodec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+func newCBORCodec() cborCodec {
+	enc, _ := cbor.CanonicalEncOptions().EncMode()
+	// 巢狀的 map 需解成 map[string]interface{}，否則 GraphQL 輸出 JSON 時會失敗
+	dec, _ := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+	}.DecMode()
+	return cborCodec{enc: enc, dec: dec}
+}
+
+func (cborCodec) Name() string                                 { return "cbor" }
+func (c cborCodec) Marshal(v interface{}) ([]byte, error)      { return c.enc.Marshal(v) }
+func (c cborCodec) Unmarshal(data []byte, v interface{}) error { return c.dec.Unmarshal(data, v) }
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 壓縮方式記錄在 entry flags 的 bit 2-3
+const (
+	compressNone byte = 0 << 2
+	compressGzip byte = 1 << 2
+	compressZstd byte = 2 << 2
+
+	compressFlagMask byte = 0x0c
+)
+
+// CacheCompression selects how large cache values are compressed.
+type CacheCompression string
+
+const (
+	CompressionNone CacheCompression = "none"
+	CompressionGzip CacheCompression = "gzip"
+	CompressionZstd CacheCompression = "zstd"
+)
+
+// ParseCacheCompression validates a CACHE_COMPRESSION value. An empty string
+// means no compression.
+func ParseCacheCompression(name string) (CacheCompression, error) {
+	switch CacheCompression(name) {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionZstd:
+		return CacheCompression(name), nil
+	}
+	return "", fmt.Errorf("unknown cache compression %q", name)
+}
+
+// zstd encoder/decoder 可重複使用且為 concurrency-safe
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// compressPayload 在 payload 超過門檻時壓縮，回傳壓縮後資料與對應 flags
+func compressPayload(payload []byte, method CacheCompression, threshold int) ([]byte, byte, error) {
+	if method == "" || method == CompressionNone || len(payload) < threshold {
+		return payload, compressNone, nil
+	}
+	switch method {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, 0, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, 0, err
+		}
+		return buf.Bytes(), compressGzip, nil
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(payload, nil), compressZstd, nil
+	}
+	return payload, compressNone, nil
+}
+
+// decompressPayload 依 entry flags 解壓縮
+func decompressPayload(payload []byte, flags byte) ([]byte, error) {
+	switch flags & compressFlagMask {
+	case compressNone:
+		return payload, nil
+	case compressGzip:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case compressZstd:
+		return zstdDecoder.DecodeAll(payload, nil)
+	}
+	return nil, fmt.Errorf("unknown compression flag %d", flags&compressFlagMask)
+}
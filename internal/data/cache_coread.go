@@ -0,0 +1,106 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// coReadHistorySize 為每位訪客保留的最近閱讀數；新讀的 story 只與這些 story 建立共讀關係
+const coReadHistorySize = 20
+
+// coReadHistoryTTL 為訪客閱讀紀錄的保留時間，超過後的閱讀視為不同的 session
+const coReadHistoryTTL = 24 * time.Hour
+
+// coReadTTL 為共讀 sorted set 的保留時間，每次新增共讀時延長
+const coReadTTL = 30 * 24 * time.Hour
+
+// coReadBackend is implemented by backends that can keep a short, capped
+// history list per visitor.
+type coReadBackend interface {
+	// PushHistory prepends member to the list at key, keeping the newest size
+	// entries for ttl, and returns the entries that were there before.
+	PushHistory(ctx context.Context, key, member string, size int, ttl time.Duration) ([]string, error)
+}
+
+// RecordCoRead notes that visitor read member (e.g. a story ID): member is
+// counted as co-read with each story the same visitor read in the last day.
+// visitor is hashed before it is stored. Failures are only logged.
+func (c *Cache) RecordCoRead(ctx context.Context, visitor, member string) error {
+	backend := c.active()
+	hb, ok := backend.(coReadBackend)
+	pb, ok2 := backend.(popularityBackend)
+	if !ok || !ok2 || visitor == "" {
+		return nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	history, err := hb.PushHistory(ctx, c.coReadHistoryKey(visitor), member, coReadHistorySize, coReadHistoryTTL)
+	if err != nil {
+		c.logger.Debug("record co-read failed", "member", member, "error", err)
+		return nil
+	}
+	seen := map[string]bool{member: true}
+	for _, other := range history {
+		if seen[other] {
+			continue
+		}
+		seen[other] = true
+		// 雙向記錄，讓兩篇 story 都能查到對方
+		for _, pair := range [][2]string{{member, other}, {other, member}} {
+			if err := pb.IncrScore(ctx, c.coReadKey(pair[0]), pair[1], coReadTTL); err != nil {
+				c.logger.Debug("record co-read failed", "member", pair[0], "error", err)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// TopCoReads returns up to n members most often read together with member,
+// most co-read first.
+func (c *Cache) TopCoReads(ctx context.Context, member string, n int) ([]string, error) {
+	pb, ok := c.active().(popularityBackend)
+	if !ok || n <= 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return pb.TopMembers(ctx, []string{c.coReadKey(member)}, n)
+}
+
+// coReadKey 回傳與 member 共讀次數的 sorted set key
+func (c *Cache) coReadKey(member string) string {
+	return c.fullKey("coread:" + member)
+}
+
+// coReadHistoryKey 回傳訪客最近閱讀紀錄的 key；訪客識別碼只以 hash 保存
+func (c *Cache) coReadHistoryKey(visitor string) string {
+	return c.fullKey("coread-history:" + visitorHash(visitor))
+}
+
+func (b *redisBackend) PushHistory(ctx context.Context, key, member string, size int, ttl time.Duration) ([]string, error) {
+	var history *redis.StringSliceCmd
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		history = pipe.LRange(ctx, key, 0, int64(size-1))
+		pipe.LRem(ctx, key, 0, member)
+		pipe.LPush(ctx, key, member)
+		pipe.LTrim(ctx, key, 0, int64(size-1))
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history.Val(), nil
+}
+
+func (b *tieredBackend) PushHistory(ctx context.Context, key, member string, size int, ttl time.Duration) ([]string, error) {
+	if hb, ok := b.remote.(coReadBackend); ok {
+		return hb.PushHistory(ctx, key, member, size, ttl)
+	}
+	return nil, nil
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// counterBackend is implemented by backends that can keep counters in
+// hashes and hand them over in batches.
+type counterBackend interface {
+	// IncrCounter adds n to field of the hash at key.
+	IncrCounter(ctx context.Context, key, field string, n int64) error
+	// SumCounters returns the sum of field across the hashes at keys.
+	SumCounters(ctx context.Context, keys []string, field string) (int64, error)
+	// DrainCounters moves the hash at key to drainKey and returns its
+	// fields. When drainKey still holds an earlier batch, that batch is
+	// returned instead and key is left alone.
+	DrainCounters(ctx context.Context, key, drainKey string) (map[string]int64, error)
+	// DropCounters deletes the hash at key.
+	DropCounters(ctx context.Context, key string) error
+}
+
+// AddCount adds n to field of the named counter batch and reports whether
+// Redis took it; callers keep the count themselves when it did not.
+func (c *Cache) AddCount(ctx context.Context, name, field string, n int64) bool {
+	if c == nil {
+		return false
+	}
+	cb, ok := c.active().(counterBackend)
+	if !ok {
+		return false
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	if err := cb.IncrCounter(ctx, c.counterKey(name), field, n); err != nil {
+		c.logger.Debug("add count failed", "name", name, "field", field, "error", err)
+		return false
+	}
+	return true
+}
+
+// PendingCount returns the count of field in the named counter batch that
+// has not been drained and committed yet.
+func (c *Cache) PendingCount(ctx context.Context, name, field string) (int64, error) {
+	if c == nil {
+		return 0, nil
+	}
+	cb, ok := c.active().(counterBackend)
+	if !ok {
+		return 0, nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return cb.SumCounters(ctx, []string{c.counterKey(name), c.counterDrainKey(name)}, field)
+}
+
+// DrainCounts takes the current batch of the named counters. Call commit
+// once the counts are stored elsewhere; until then the batch is kept and
+// returned again by the next DrainCounts, so counts are delivered at least
+// once. Hold a lock around drain and commit when several instances drain.
+func (c *Cache) DrainCounts(ctx context.Context, name string) (counts map[string]int64, commit func(ctx context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+	if c == nil {
+		return nil, noop, nil
+	}
+	cb, ok := c.active().(counterBackend)
+	if !ok {
+		return nil, noop, nil
+	}
+
+	drainKey := c.counterDrainKey(name)
+	opCtx, cancel := c.opContext(ctx)
+	defer cancel()
+	counts, err = cb.DrainCounters(opCtx, c.counterKey(name), drainKey)
+	if err != nil {
+		return nil, noop, err
+	}
+	return counts, func(ctx context.Context) error {
+		ctx, cancel := c.opContext(ctx)
+		defer cancel()
+		return cb.DropCounters(ctx, drainKey)
+	}, nil
+}
+
+// counterKey 與 counterDrainKey 為計數中與取出待寫入的 hash；以 hash tag 讓兩者落在同一個
+// cluster slot，才能 RENAME
+func (c *Cache) counterKey(name string) string {
+	return c.fullKey("counter:{" + name + "}:pending")
+}
+
+func (c *Cache) counterDrainKey(name string) string {
+	return c.fullKey("counter:{" + name + "}:draining")
+}
+
+func (b *redisBackend) IncrCounter(ctx context.Context, key, field string, n int64) error {
+	return b.client.HIncrBy(ctx, key, field, n).Err()
+}
+
+func (b *redisBackend) SumCounters(ctx context.Context, keys []string, field string) (int64, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HGet(ctx, key, field)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	var sum int64
+	for _, cmd := range cmds {
+		n, err := cmd.Int64()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+func (b *redisBackend) DrainCounters(ctx context.Context, key, drainKey string) (map[string]int64, error) {
+	// 上一批尚未 commit 時先處理上一批
+	exists, err := b.client.Exists(ctx, drainKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		if err := b.client.Rename(ctx, key, drainKey).Err(); err != nil {
+			if err.Error() == "ERR no such key" {
+				return map[string]int64{}, nil
+			}
+			return nil, err
+		}
+	}
+	fields, err := b.client.HGetAll(ctx, drainKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(fields))
+	for field, value := range fields {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		counts[field] = n
+	}
+	return counts, nil
+}
+
+func (b *redisBackend) DropCounters(ctx context.Context, key string) error {
+	return b.client.Del(ctx, key).Err()
+}
+
+func (b *tieredBackend) IncrCounter(ctx context.Context, key, field string, n int64) error {
+	if cb, ok := b.remote.(counterBackend); ok {
+		return cb.IncrCounter(ctx, key, field, n)
+	}
+	return errors.New("counters are not supported by the remote backend")
+}
+
+func (b *tieredBackend) SumCounters(ctx context.Context, keys []string, field string) (int64, error) {
+	if cb, ok := b.remote.(counterBackend); ok {
+		return cb.SumCounters(ctx, keys, field)
+	}
+	return 0, nil
+}
+
+func (b *tieredBackend) DrainCounters(ctx context.Context, key, drainKey string) (map[string]int64, error) {
+	if cb, ok := b.remote.(counterBackend); ok {
+		return cb.DrainCounters(ctx, key, drainKey)
+	}
+	return map[string]int64{}, nil
+}
+
+func (b *tieredBackend) DropCounters(ctx context.Context, key string) error {
+	if cb, ok := b.remote.(counterBackend); ok {
+		return cb.DropCounters(ctx, key)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrDumpUnsupported is returned by Dump and Restore when the cache is
+// disabled or its backend cannot list keys.
+var ErrDumpUnsupported = errors.New("cache backend does not support dump")
+
+// CacheDumpEntry is one line of a dump written by Dump: the stored bytes of a
+// single entry (header included, so codec, compression, encryption and
+// timestamps survive a round trip) and its remaining TTL.
+type CacheDumpEntry struct {
+	Key   string `json:"key"`             // 不含 namespace 的 key
+	TTLMS int64  `json:"ttlMs,omitempty"` // 剩餘 TTL (毫秒)，0 表示不過期
+	Value []byte `json:"value"`
+}
+
+// scanBackend is implemented by backends that can iterate over their keys.
+type scanBackend interface {
+	// ScanKeys calls fn with successive batches of keys starting with prefix.
+	// fn is never called concurrently; a key may be passed more than once.
+	ScanKeys(ctx context.Context, prefix string, fn func(keys []string) error) error
+}
+
+// Dump writes every cache entry whose key starts with prefix to w as JSON
+// lines (see CacheDumpEntry) and returns how many were written. Keys are
+// found with SCAN; keys that are not cache entries (tag sets, locks, rate
+// limit counters) are skipped. Entries encrypted with WithEncryption stay
+// encrypted and can only be read back under the same namespace and key.
+func (c *Cache) Dump(ctx context.Context, prefix string, w io.Writer) (int, error) {
+	backend := c.active()
+	sb, ok := backend.(scanBackend)
+	if !ok {
+		return 0, ErrDumpUnsupported
+	}
+
+	enc := json.NewEncoder(w)
+	seen := map[string]struct{}{}
+	n := 0
+	err := sb.ScanKeys(ctx, c.fullKey(prefix), func(keys []string) error {
+		for _, key := range keys {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			entry, ok, err := c.dumpEntry(ctx, backend, key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := enc.Encode(entry); err != nil {
+				return fmt.Errorf("write dump: %w", err)
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("cache dump failed", "prefix", prefix, "dumped", n, "error", err)
+		return n, err
+	}
+	c.logger.Info("cache dumped", "prefix", prefix, "entries", n)
+	return n, nil
+}
+
+// dumpEntry 讀取單一 key；不存在或不是 cache entry 時回傳 false
+func (c *Cache) dumpEntry(ctx context.Context, backend CacheBackend, fullKey string) (CacheDumpEntry, bool, error) {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+
+	raw, err := backend.Get(ctx, fullKey)
+	if errors.Is(err, ErrCacheMiss) {
+		return CacheDumpEntry{}, false, nil
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		// 例如 tag 的 set (WRONGTYPE)
+		c.logger.Debug("cache dump skipped key", "key", fullKey, "error", err)
+		return CacheDumpEntry{}, false, nil
+	}
+	if err != nil {
+		return CacheDumpEntry{}, false, fmt.Errorf("read %s: %w", fullKey, err)
+	}
+	if _, err := decodeEntry(raw); err != nil {
+		return CacheDumpEntry{}, false, nil
+	}
+
+	entry := CacheDumpEntry{Key: strings.TrimPrefix(fullKey, c.keyPrefix), Value: raw}
+	if tb, ok := backend.(ttlBackend); ok {
+		ttl, err := tb.TTL(ctx, fullKey)
+		if err != nil {
+			return CacheDumpEntry{}, false, fmt.Errorf("ttl %s: %w", fullKey, err)
+		}
+		entry.TTLMS = ttl.Milliseconds()
+	}
+	return entry, true, nil
+}
+
+// Restore reads a dump written by Dump from r and stores every entry under
+// the current namespace with its recorded TTL, overwriting existing keys.
+// It returns how many entries were restored.
+func (c *Cache) Restore(ctx context.Context, r io.Reader) (int, error) {
+	backend := c.active()
+	if backend == nil {
+		return 0, ErrDumpUnsupported
+	}
+
+	scanner := bufio.NewScanner(r)
+	// 單一 entry 可能很大 (例如壓縮前的文章列表)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	n := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry CacheDumpEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, err := decodeEntry(entry.Value); err != nil {
+			return n, fmt.Errorf("line %d: key %s: %w", line, entry.Key, err)
+		}
+
+		opCtx, cancel := c.opContext(ctx)
+		start := time.Now()
+		err := backend.Set(opCtx, c.fullKey(entry.Key), entry.Value, time.Duration(entry.TTLMS)*time.Millisecond)
+		c.observe(CacheOpSet, start)
+		cancel()
+		if err != nil {
+			c.metrics.Error(CacheOpSet)
+			c.logger.Error("cache restore failed", "key", entry.Key, "restored", n, "error", err)
+			return n, fmt.Errorf("line %d: key %s: %w", line, entry.Key, err)
+		}
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, fmt.Errorf("read dump: %w", err)
+	}
+	c.metrics.Set(n)
+	c.logger.Info("cache restored", "entries", n)
+	return n, nil
+}
+
+func (b *redisBackend) ScanKeys(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	pattern := escapeGlob(prefix) + "*"
+	cluster, ok := b.client.(*redis.ClusterClient)
+	if !ok {
+		return scanKeys(ctx, b.client, pattern, fn)
+	}
+
+	// cluster 需在每個 master 上各自 SCAN；ForEachMaster 會並行執行，fn 需序列化
+	var mu sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanKeys(ctx, node, pattern, func(keys []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(keys)
+		})
+	})
+}
+
+// scanKeys 以 SCAN 逐批取得符合 pattern 的 key
+func scanKeys(ctx context.Context, client redis.UniversalClient, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, prefixScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (b *tieredBackend) ScanKeys(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	sb, ok := b.remote.(scanBackend)
+	if !ok {
+		return ErrDumpUnsupported
+	}
+	return sb.ScanKeys(ctx, prefix, fn)
+}
+
+func (b *memoryBackend) ScanKeys(_ context.Context, prefix string, fn func(keys []string) error) error {
+	b.mu.Lock()
+	keys := []string{}
+	for key := range b.items {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	b.mu.Unlock()
+
+	return fn(keys)
+}
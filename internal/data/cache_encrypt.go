@@ -0,0 +1,112 @@
+package data
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// entryFlagEncrypted marks an entry whose payload is AES-GCM encrypted
+// (nonce followed by ciphertext), applied after compression.
+const entryFlagEncrypted byte = 1 << 5
+
+// errNoEncryptionKey 表示讀到加密的 entry，但目前沒有設定金鑰
+var errNoEncryptionKey = errors.New("encrypted cache entry but no encryption key configured")
+
+// SensitiveValue is implemented by values that must be encrypted when cached,
+// e.g. member-only stories. Slices are sensitive when any element is.
+type SensitiveValue interface {
+	CacheSensitive() bool
+}
+
+// NewCacheCipher builds an AES-GCM cipher from a base64-encoded 16, 24 or
+// 32 byte key (AES-128/192/256), e.g. injected from a secret manager.
+func NewCacheCipher(encodedKey string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// WithEncryption encrypts sensitive entries with aead: values implementing
+// SensitiveValue that report true, and every key starting with one of
+// sensitivePrefixes. The cache key is bound as additional data, so an
+// encrypted value cannot be replayed under another key. Encrypted entries
+// read without a key are treated as misses. A nil aead disables encryption.
+func WithEncryption(aead cipher.AEAD, sensitivePrefixes ...string) CacheOption {
+	return func(c *Cache) {
+		c.cipher = aead
+		c.sensitivePrefixes = sensitivePrefixes
+	}
+}
+
+// sensitive 判斷 key 與 value 是否需要加密
+func (c *Cache) sensitive(key string, value interface{}) bool {
+	for _, prefix := range c.sensitivePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return sensitiveValue(reflect.ValueOf(value))
+}
+
+// sensitiveValueType 為 SensitiveValue 的 reflect.Type
+var sensitiveValueType = reflect.TypeOf((*SensitiveValue)(nil)).Elem()
+
+// sensitiveValue 檢查 value (或 slice 中的任一元素) 是否實作 SensitiveValue 並回傳 true
+func sensitiveValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return false
+	}
+	if sv, ok := v.Interface().(SensitiveValue); ok {
+		return sv.CacheSensitive()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return sensitiveValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if !v.Type().Elem().Implements(sensitiveValueType) {
+			return false
+		}
+		for i := 0; i < v.Len(); i++ {
+			if sensitiveValue(v.Index(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encryptPayload 以 AES-GCM 加密，輸出為 nonce 加上密文；fullKey 作為 additional data
+func (c *Cache) encryptPayload(fullKey string, payload []byte) ([]byte, error) {
+	nonce := make([]byte, c.cipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.cipher.Seal(nonce, nonce, payload, []byte(fullKey)), nil
+}
+
+// decryptPayload 解開 encryptPayload 的輸出
+func (c *Cache) decryptPayload(fullKey string, payload []byte) ([]byte, error) {
+	if c.cipher == nil {
+		return nil, errNoEncryptionKey
+	}
+	size := c.cipher.NonceSize()
+	if len(payload) < size {
+		return nil, errors.New("encrypted payload too short")
+	}
+	return c.cipher.Open(nil, payload[:size], payload[size:], []byte(fullKey))
+}
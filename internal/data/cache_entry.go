@@ -0,0 +1,73 @@
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Cache entries are stored with a small fixed header in front of the payload:
+//
+//	[0]     format version
+//	[1]     flags
+//	[2:10]  stored-at, unix milliseconds
+//	[10:18] soft expiry, unix milliseconds (0 means never stale)
+//	[18:]   payload
+const (
+	entryVersion    byte = 1
+	entryHeaderSize      = 18
+)
+
+// entryFlagNotFound marks a negative-cache entry written by SetNotFound.
+// Bits 0-1 hold the codec, bits 2-3 the compression method and bit 5 marks
+// encryption (entryFlagEncrypted).
+const entryFlagNotFound byte = 1 << 4
+
+// errLegacyEntry 表示 entry 不是目前的格式 (例如升級前寫入的純 JSON)，視為 cache miss
+var errLegacyEntry = errors.New("legacy cache entry")
+
+type cacheEntry struct {
+	flags         byte
+	storedAt      time.Time
+	softExpiresAt time.Time
+	payload       []byte
+}
+
+// stale reports whether the entry is past its soft expiry.
+func (e cacheEntry) stale(now time.Time) bool {
+	return !e.softExpiresAt.IsZero() && now.After(e.softExpiresAt)
+}
+
+// notFound reports whether the entry is a negative-cache marker.
+func (e cacheEntry) notFound() bool {
+	return e.flags&entryFlagNotFound != 0
+}
+
+func encodeEntry(e cacheEntry) []byte {
+	buf := make([]byte, entryHeaderSize+len(e.payload))
+	buf[0] = entryVersion
+	buf[1] = e.flags
+	binary.BigEndian.PutUint64(buf[2:10], uint64(e.storedAt.UnixMilli()))
+	var soft int64
+	if !e.softExpiresAt.IsZero() {
+		soft = e.softExpiresAt.UnixMilli()
+	}
+	binary.BigEndian.PutUint64(buf[10:18], uint64(soft))
+	copy(buf[entryHeaderSize:], e.payload)
+	return buf
+}
+
+func decodeEntry(raw []byte) (cacheEntry, error) {
+	if len(raw) < entryHeaderSize || raw[0] != entryVersion {
+		return cacheEntry{}, errLegacyEntry
+	}
+	e := cacheEntry{
+		flags:    raw[1],
+		storedAt: time.UnixMilli(int64(binary.BigEndian.Uint64(raw[2:10]))),
+		payload:  raw[entryHeaderSize:],
+	}
+	if soft := int64(binary.BigEndian.Uint64(raw[10:18])); soft > 0 {
+		e.softExpiresAt = time.UnixMilli(soft)
+	}
+	return e, nil
+}
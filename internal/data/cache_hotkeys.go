@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// hotKeyWindow 為計算存取次數的時間窗；每過一個時間窗，所有計數減半
+	hotKeyWindow = time.Minute
+	// hotKeyMaxTracked 限制追蹤的 key 數量，避免大量冷門 key 佔用記憶體
+	hotKeyMaxTracked = 10000
+)
+
+// hotKeyTracker keeps an approximate, in-memory count of reads per key.
+// Counts decay by half every hotKeyWindow, so a key stays hot only while it
+// keeps being requested.
+type hotKeyTracker struct {
+	mu        sync.Mutex
+	threshold int
+	counts    map[string]int
+	decayAt   time.Time // 下一次衰減的時間
+}
+
+func newHotKeyTracker(threshold int) *hotKeyTracker {
+	return &hotKeyTracker{
+		threshold: threshold,
+		counts:    map[string]int{},
+		decayAt:   time.Now().Add(hotKeyWindow),
+	}
+}
+
+// WithHotKeyRefresh re-fetches entries read through GetOrSet or GetOrRefresh
+// at least threshold times per minute once they are within ahead of expiry,
+// so the hottest keys are replaced before they ever miss. Counts are kept
+// per instance and are approximate. A threshold or ahead <= 0 disables it.
+func WithHotKeyRefresh(threshold int, ahead time.Duration) CacheOption {
+	return func(c *Cache) {
+		if threshold > 0 && ahead > 0 {
+			c.hotKeys = newHotKeyTracker(threshold)
+			c.refreshAhead = ahead
+		} else {
+			c.hotKeys = nil
+			c.refreshAhead = 0
+		}
+	}
+}
+
+// touch 記錄一次存取，回傳 key 是否已達熱門門檻
+func (t *hotKeyTracker) touch(key string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !now.Before(t.decayAt) {
+		for k, n := range t.counts {
+			if n /= 2; n == 0 {
+				delete(t.counts, k)
+			} else {
+				t.counts[k] = n
+			}
+		}
+		t.decayAt = now.Add(hotKeyWindow)
+	}
+
+	n, ok := t.counts[key]
+	if !ok && len(t.counts) >= hotKeyMaxTracked {
+		return false
+	}
+	n++
+	t.counts[key] = n
+	return n >= t.threshold
+}
+
+// refreshIfHot 在 key 為熱門且 entry 即將到期時，於背景提前重新載入
+func (c *Cache) refreshIfHot(key string, entry cacheEntry, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) {
+	if c.hotKeys == nil {
+		return
+	}
+	now := time.Now()
+	if !c.hotKeys.touch(key, now) || entry.softExpiresAt.IsZero() {
+		return
+	}
+	if entry.softExpiresAt.Sub(now) > c.refreshAhead {
+		return
+	}
+	c.logger.Debug("refreshing hot key before expiry", "key", key, "expires_in", entry.softExpiresAt.Sub(now))
+	c.refreshAsync(key, ttl, loader)
+}
@@ -0,0 +1,285 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// CacheKeyBuilder builds cache keys from query parameters in a canonical form,
+// so that logically equal queries always map to the same key:
+//
+//   - map keys and struct fields are sorted by name;
+//   - struct fields are named by their mapstructure (or json) tag, so renaming
+//     a Go field does not change the key;
+//   - zero values (nil pointers, empty strings, slices, maps and structs,
+//     false, 0) are dropped, so an unset filter and an explicitly empty one
+//     are equal and adding an optional field keeps existing keys stable.
+//     A non-nil pointer to a zero scalar (e.g. equals: false) is kept.
+//
+// Keys have the form <prefix>:<CacheKeySchemaVersion>:<hash of the fields>,
+// or <prefix>:<CacheKeySchemaVersion>:<summary>:<hash> with debug keys on.
+type CacheKeyBuilder struct {
+	prefix string
+	fields map[string]interface{}
+	short  bool
+}
+
+// debugKeySummaryLimit 為 debug key 中參數摘要的長度上限 (字元)
+const debugKeySummaryLimit = 80
+
+// debugCacheKeys 為 true 時 key 會帶有參數摘要
+var debugCacheKeys atomic.Bool
+
+// SetDebugCacheKeys makes every key built afterwards include a truncated,
+// human-readable summary of its parameters before the hash, e.g.
+// posts:v1:take=2:where.slug.equals=foo:<hash>, so entries can be found in
+// Redis with SCAN during incidents. Keys change when it is toggled, so the
+// cache starts cold.
+func SetDebugCacheKeys(enabled bool) {
+	debugCacheKeys.Store(enabled)
+}
+
+// NewCacheKey starts a key with the given prefix.
+func NewCacheKey(prefix string) *CacheKeyBuilder {
+	return &CacheKeyBuilder{prefix: prefix, fields: map[string]interface{}{}}
+}
+
+// Field adds a single named parameter. Zero values are ignored.
+func (b *CacheKeyBuilder) Field(name string, value interface{}) *CacheKeyBuilder {
+	if v, ok := canonicalValue(reflect.ValueOf(value)); ok {
+		b.fields[name] = v
+	} else {
+		delete(b.fields, name)
+	}
+	return b
+}
+
+// Fields adds the named fields of a struct or map (by tag name for structs).
+// With no names every field is added; listing them explicitly keeps fields
+// that do not affect the result, or that are added later, out of the key.
+func (b *CacheKeyBuilder) Fields(params interface{}, names ...string) *CacheKeyBuilder {
+	v, ok := canonicalValue(reflect.ValueOf(params))
+	if !ok {
+		return b
+	}
+	m, isMap := v.(map[string]interface{})
+	if !isMap {
+		// 非 struct / map 時整個值視為單一欄位
+		b.fields["value"] = v
+		return b
+	}
+	if len(names) == 0 {
+		for name, value := range m {
+			b.fields[name] = value
+		}
+		return b
+	}
+	for _, name := range names {
+		if value, ok := m[name]; ok {
+			b.fields[name] = value
+		}
+	}
+	return b
+}
+
+// ShortHash uses a 64-bit xxhash instead of SHA-256. Keys are 16 instead of
+// 64 hex characters and cheaper to compute; collisions are not a security
+// concern for cache keys built from query parameters.
+func (b *CacheKeyBuilder) ShortHash() *CacheKeyBuilder {
+	b.short = true
+	return b
+}
+
+// Canonical returns the canonical JSON encoding of the fields that is hashed
+// into the key.
+func (b *CacheKeyBuilder) Canonical() string {
+	data, _ := b.canonical()
+	return string(data)
+}
+
+// canonical 序列化欄位；encoding/json 輸出 map 時依 key 排序，因此結果是確定的
+func (b *CacheKeyBuilder) canonical() ([]byte, error) {
+	return json.Marshal(b.fields)
+}
+
+// String returns the key.
+func (b *CacheKeyBuilder) String() string {
+	data, err := b.canonical()
+	if err != nil {
+		// 如果序列化失敗，使用簡單的 key
+		return fmt.Sprintf("%s:%s:fallback", b.prefix, CacheKeySchemaVersion)
+	}
+	var hash string
+	if b.short {
+		hash = fmt.Sprintf("%016x", xxhash.Sum64(data))
+	} else {
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+	}
+	if debugCacheKeys.Load() {
+		if summary := b.Summary(); summary != "" {
+			return fmt.Sprintf("%s:%s:%s:%s", b.prefix, CacheKeySchemaVersion, summary, hash)
+		}
+	}
+	return fmt.Sprintf("%s:%s:%s", b.prefix, CacheKeySchemaVersion, hash)
+}
+
+// Summary returns the human-readable parameter summary used by debug keys:
+// name=value pairs of the canonical fields sorted by path and joined with
+// ":", truncated to 80 characters. Characters with special meaning in keys
+// or SCAN patterns are replaced by "_".
+func (b *CacheKeyBuilder) Summary() string {
+	pairs := []string{}
+	flattenSummary("", b.fields, &pairs)
+	sort.Strings(pairs)
+	summary := strings.Join(pairs, ":")
+	if utf8.RuneCountInString(summary) > debugKeySummaryLimit {
+		summary = string([]rune(summary)[:debugKeySummaryLimit])
+	}
+	return summary
+}
+
+// summaryReplacer 取代 key 分隔符號、SCAN glob 字元與空白
+var summaryReplacer = strings.NewReplacer(":", "_", "*", "_", "?", "_", "[", "_", "]", "_", "\\", "_", " ", "_", "\n", "_")
+
+// flattenSummary 將 canonicalValue 的結果展開為 path=value
+func flattenSummary(path string, value interface{}, pairs *[]string) {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, item := range v {
+			flattenSummary(join(summaryReplacer.Replace(name)), item, pairs)
+		}
+	case []interface{}:
+		scalars := make([]string, 0, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				flattenSummary(join(strconv.Itoa(i)), item, pairs)
+			default:
+				scalars = append(scalars, summaryReplacer.Replace(fmt.Sprint(item)))
+			}
+		}
+		if len(scalars) > 0 {
+			*pairs = append(*pairs, path+"="+strings.Join(scalars, ","))
+		}
+	default:
+		*pairs = append(*pairs, path+"="+summaryReplacer.Replace(fmt.Sprint(v)))
+	}
+}
+
+// timeType 為 time.Time 的 reflect.Type，以 UTC RFC3339 表示
+var timeType = reflect.TypeOf(time.Time{})
+
+// canonicalValue 將 v 轉成只含 map[string]interface{}、[]interface{} 與基本型別的值；
+// 零值回傳 false
+func canonicalValue(v reflect.Value) (interface{}, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return nil, false
+		}
+		return t.UTC().Format(time.RFC3339Nano), true
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, false
+		}
+		// 指向基本型別零值的指標是明確指定的值 (例如 equals: false)，不可省略
+		value, ok := canonicalValue(v.Elem())
+		switch v.Elem().Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Ptr, reflect.Interface:
+			return value, ok
+		}
+		return value, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return canonicalValue(v.Elem())
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := canonicalFieldName(field)
+			if name == "" {
+				continue
+			}
+			if value, ok := canonicalValue(v.Field(i)); ok {
+				m[name] = value
+			}
+		}
+		return m, len(m) > 0
+	case reflect.Map:
+		m := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			if value, ok := canonicalValue(iter.Value()); ok {
+				m[fmt.Sprint(iter.Key().Interface())] = value
+			}
+		}
+		return m, len(m) > 0
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return nil, false
+		}
+		// slice 的順序有意義 (例如排序規則)，只正規化元素本身
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i], _ = canonicalValue(v.Index(i))
+		}
+		return list, true
+	case reflect.String:
+		return v.String(), v.Len() > 0
+	case reflect.Bool:
+		return v.Bool(), v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), v.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), v.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		// 以字串表示避免 float 格式差異
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), v.Float() != 0
+	}
+	return fmt.Sprint(v.Interface()), !v.IsZero()
+}
+
+// canonicalFieldName 回傳欄位在 key 中的名稱：mapstructure tag、json tag 或欄位名稱；
+// 未匯出或標記為 "-" 的欄位回傳空字串
+func canonicalFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	for _, tag := range []string{"mapstructure", "json"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
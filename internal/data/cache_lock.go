@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLockNotAcquired is returned by Cache.Lock when another holder owns the lock.
+var ErrLockNotAcquired = errors.New("lock already held")
+
+// ErrLockNotHeld is returned by CacheLock.Unlock when the lock expired and
+// may have been taken over by another holder.
+var ErrLockNotHeld = errors.New("lock not held")
+
+// lockBackend is implemented by backends that can hold named locks.
+type lockBackend interface {
+	// AcquireLock sets key to token for ttl unless key already exists.
+	AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// ReleaseLock deletes key only if it still holds token.
+	ReleaseLock(ctx context.Context, key, token string) (bool, error)
+}
+
+// CacheLock is a lock acquired with Cache.Lock.
+type CacheLock struct {
+	cache   *Cache
+	backend lockBackend
+	name    string
+	key     string
+	token   string
+}
+
+// Lock acquires the named lock for ttl, e.g. so that only one instance
+// regenerates a feed or builds the sitemap at a time. It returns
+// ErrLockNotAcquired when the lock is held elsewhere. The lock expires after
+// ttl even if Unlock is never called.
+// While Redis is unavailable the lock only excludes holders in this process,
+// and a nil or disabled Cache grants every lock, so jobs keep running.
+func (c *Cache) Lock(ctx context.Context, name string, ttl time.Duration) (*CacheLock, error) {
+	if c == nil {
+		return &CacheLock{name: name}, nil
+	}
+	backend := c.active()
+	lb, ok := backend.(lockBackend)
+	if !ok {
+		return &CacheLock{cache: c, name: name}, nil
+	}
+
+	lock := &CacheLock{cache: c, backend: lb, name: name, key: c.fullKey("lock:" + name), token: newInstanceID()}
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	acquired, err := lb.AcquireLock(ctx, lock.key, lock.token, ttl)
+	c.observe(CacheOpLock, start)
+	if err != nil {
+		c.metrics.Error(CacheOpLock)
+		c.logger.Error("cache lock failed", "name", name, "error", err)
+		c.handleBackendError(backend, err)
+		return nil, fmt.Errorf("acquire lock %s: %w", name, err)
+	}
+	c.handleBackendSuccess(backend)
+
+	if !acquired {
+		c.logger.Debug("cache lock busy", "name", name)
+		return nil, ErrLockNotAcquired
+	}
+	c.logger.Debug("cache lock acquired", "name", name, "ttl", ttl)
+	return lock, nil
+}
+
+// Unlock releases the lock if it is still held by this holder. It returns
+// ErrLockNotHeld when the lock already expired.
+func (l *CacheLock) Unlock(ctx context.Context) error {
+	if l == nil || l.backend == nil {
+		return nil
+	}
+	c := l.cache
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	released, err := l.backend.ReleaseLock(ctx, l.key, l.token)
+	c.observe(CacheOpLock, start)
+	if err != nil {
+		c.metrics.Error(CacheOpLock)
+		c.logger.Error("cache unlock failed", "name", l.name, "error", err)
+		return fmt.Errorf("release lock %s: %w", l.name, err)
+	}
+	if !released {
+		c.logger.Warn("cache lock expired before unlock", "name", l.name)
+		return ErrLockNotHeld
+	}
+	c.logger.Debug("cache lock released", "name", l.name)
+	return nil
+}
+
+// releaseLockScript 只在 key 仍為自己的 token 時才刪除，避免誤刪他人取得的鎖
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (b *redisBackend) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return b.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+func (b *redisBackend) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	n, err := releaseLockScript.Run(ctx, b.client, []string{key}, token).Int()
+	return n == 1, err
+}
+
+func (b *tieredBackend) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	if lb, ok := b.remote.(lockBackend); ok {
+		return lb.AcquireLock(ctx, key, token, ttl)
+	}
+	return true, nil
+}
+
+func (b *tieredBackend) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	if lb, ok := b.remote.(lockBackend); ok {
+		return lb.ReleaseLock(ctx, key, token)
+	}
+	return true, nil
+}
+
+// memoryLock 為 memoryBackend 中的鎖；與 LRU 分開保存，避免被淘汰
+type memoryLock struct {
+	token     string
+	expiresAt time.Time
+}
+
+func (b *memoryBackend) AcquireLock(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if held, ok := b.locks[key]; ok && now.Before(held.expiresAt) {
+		return false, nil
+	}
+	b.locks[key] = memoryLock{token: token, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (b *memoryBackend) ReleaseLock(_ context.Context, key, token string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	held, ok := b.locks[key]
+	if !ok || held.token != token || !time.Now().Before(held.expiresAt) {
+		return false, nil
+	}
+	delete(b.locks, key)
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryBackend is a bounded in-process LRU implementing CacheBackend.
+// It is used as a fallback when Redis is unavailable.
+type memoryBackend struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	tags       map[string]map[string]struct{} // tag -> keys
+	locks      map[string]memoryLock          // Lock 取得的鎖
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // zero 表示不過期
+	tags      []string  // 此 entry 所屬的 tag，移除時一併清理索引
+}
+
+// NewMemoryBackend creates an in-process LRU backend holding at most maxEntries keys.
+func NewMemoryBackend(maxEntries int) CacheBackend {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	return &memoryBackend{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		tags:       map[string]map[string]struct{}{},
+		locks:      map[string]memoryLock{},
+	}
+}
+
+func (b *memoryBackend) Get(_ context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		b.removeElement(el)
+		return nil, ErrCacheMiss
+	}
+	b.ll.MoveToFront(el)
+	return entry.value, nil
+}
+
+func (b *memoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 複製一份，避免呼叫端之後修改 slice 影響快取內容
+	stored := make([]byte, len(value))
+	copy(stored, value)
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if el, ok := b.items[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = stored
+		entry.expiresAt = expiresAt
+		b.ll.MoveToFront(el)
+		return nil
+	}
+
+	el := b.ll.PushFront(&memoryEntry{key: key, value: stored, expiresAt: expiresAt})
+	b.items[key] = el
+	for b.ll.Len() > b.maxEntries {
+		b.removeElement(b.ll.Back())
+	}
+	return nil
+}
+
+func (b *memoryBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if el, ok := b.items[key]; ok {
+		b.removeElement(el)
+	}
+	return nil
+}
+
+func (b *memoryBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ll.Init()
+	b.items = map[string]*list.Element{}
+	b.tags = map[string]map[string]struct{}{}
+	b.locks = map[string]memoryLock{}
+	return nil
+}
+
+func (b *memoryBackend) DeleteByPrefix(_ context.Context, prefix string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for key, el := range b.items {
+		if strings.HasPrefix(key, prefix) {
+			b.removeElement(el)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// AddTags 只記錄目前存在的 key；tag 索引隨 entry 移除而清理，因此忽略 ttl
+func (b *memoryBackend) AddTags(_ context.Context, key string, tagKeys []string, _ time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.items[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*memoryEntry)
+	for _, tagKey := range tagKeys {
+		keys, ok := b.tags[tagKey]
+		if !ok {
+			keys = map[string]struct{}{}
+			b.tags[tagKey] = keys
+		}
+		if _, dup := keys[key]; !dup {
+			keys[key] = struct{}{}
+			entry.tags = append(entry.tags, tagKey)
+		}
+	}
+	return nil
+}
+
+func (b *memoryBackend) InvalidateTag(_ context.Context, tagKey string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0, len(b.tags[tagKey]))
+	for key := range b.tags[tagKey] {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if el, ok := b.items[key]; ok {
+			b.removeElement(el)
+		}
+	}
+	delete(b.tags, tagKey)
+	return keys, nil
+}
+
+func (b *memoryBackend) removeElement(el *list.Element) {
+	b.ll.Remove(el)
+	entry := el.Value.(*memoryEntry)
+	delete(b.items, entry.key)
+	for _, tagKey := range entry.tags {
+		if keys, ok := b.tags[tagKey]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(b.tags, tagKey)
+			}
+		}
+	}
+}
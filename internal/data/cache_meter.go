@@ -0,0 +1,78 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// meterBackend is implemented by backends that can count distinct reads per
+// visitor atomically.
+type meterBackend interface {
+	// CountRead adds member to the set at key unless the set already holds
+	// limit members, starting the window when the set is created. It
+	// returns whether member is in the set afterwards and the set size.
+	CountRead(ctx context.Context, key, member string, limit int, window time.Duration) (bool, int, error)
+}
+
+// MeterRead counts visitor reading member (e.g. a story ID) against limit
+// distinct reads per window and reports whether the read is allowed and how
+// many reads the visitor has used. The window starts with the visitor's
+// first counted read; reading the same member again is allowed without
+// counting. visitor is hashed before it is stored. Without Redis every read
+// is allowed and none is counted.
+func (c *Cache) MeterRead(ctx context.Context, visitor, member string, limit int, window time.Duration) (bool, int, error) {
+	if c == nil {
+		return true, 0, nil
+	}
+	backend := c.active()
+	mb, ok := backend.(meterBackend)
+	if !ok {
+		return true, 0, nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	allowed, used, err := mb.CountRead(ctx, c.fullKey("meter:"+visitorHash(visitor)), member, limit, window)
+	c.observe(CacheOpMeter, start)
+	if err != nil {
+		c.metrics.Error(CacheOpMeter)
+		c.handleBackendError(backend, err)
+		return false, 0, err
+	}
+	c.handleBackendSuccess(backend)
+	return allowed, used, nil
+}
+
+// meterScript 已讀過的 member 直接允許；未達上限時加入並在建立時設定時間窗
+var meterScript = redis.NewScript(`
+if redis.call("SISMEMBER", KEYS[1], ARGV[1]) == 1 then
+	return {1, redis.call("SCARD", KEYS[1])}
+end
+local count = redis.call("SCARD", KEYS[1])
+if count >= tonumber(ARGV[2]) then
+	return {0, count}
+end
+redis.call("SADD", KEYS[1], ARGV[1])
+if count == 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+return {1, count + 1}
+`)
+
+func (b *redisBackend) CountRead(ctx context.Context, key, member string, limit int, window time.Duration) (bool, int, error) {
+	res, err := meterScript.Run(ctx, b.client, []string{key}, member, limit, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, int(res[1]), nil
+}
+
+func (b *tieredBackend) CountRead(ctx context.Context, key, member string, limit int, window time.Duration) (bool, int, error) {
+	if mb, ok := b.remote.(meterBackend); ok {
+		return mb.CountRead(ctx, key, member, limit, window)
+	}
+	return true, 0, nil
+}
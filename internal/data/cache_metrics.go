@@ -0,0 +1,78 @@
+package data
+
+import "time"
+
+// Cache operation names reported to CacheMetrics.
+const (
+	CacheOpGet           = "get"
+	CacheOpSet           = "set"
+	CacheOpDelete        = "delete"
+	CacheOpGetMulti      = "get_multi"
+	CacheOpSetMulti      = "set_multi"
+	CacheOpPipeline      = "pipeline"
+	CacheOpDeletePrefix  = "delete_prefix"
+	CacheOpTag           = "tag"
+	CacheOpInvalidateTag = "invalidate_tag"
+	CacheOpLock          = "lock"
+	CacheOpRateLimit     = "rate_limit"
+	CacheOpMeter         = "meter"
+	CacheOpMarshal       = "marshal"
+	CacheOpDecode        = "decode"
+)
+
+// Cache states reported to CacheMetrics.StateChange.
+const (
+	CacheStatePrimary  = "primary"
+	CacheStateFallback = "fallback"
+	CacheStateDisabled = "disabled"
+)
+
+// CacheMetrics receives instrumentation events from Cache.
+// Implementations must be safe for concurrent use.
+type CacheMetrics interface {
+	// Hit counts n keys found (including stale entries served by GetOrRefresh).
+	Hit(op string, n int)
+	// Miss counts n keys not found or no longer fresh.
+	Miss(op string, n int)
+	// Set counts n values written.
+	Set(n int)
+	// Delete counts n keys removed.
+	Delete(n int)
+	// Error counts a failed operation.
+	Error(op string)
+	// Oversized counts a value of size bytes that was not cached because it
+	// exceeded the configured maximum value size.
+	Oversized(size int)
+	// StateChange records the cache switching to the primary backend, the
+	// in-memory fallback, or being disabled.
+	StateChange(state string)
+	// ObserveLatency records how long a backend round trip took.
+	ObserveLatency(op string, d time.Duration)
+}
+
+// noopCacheMetrics 為未設定 metrics 時的預設實作
+type noopCacheMetrics struct{}
+
+func (noopCacheMetrics) Hit(string, int)                      {}
+func (noopCacheMetrics) Miss(string, int)                     {}
+func (noopCacheMetrics) Set(int)                              {}
+func (noopCacheMetrics) Delete(int)                           {}
+func (noopCacheMetrics) Error(string)                         {}
+func (noopCacheMetrics) Oversized(int)                        {}
+func (noopCacheMetrics) StateChange(string)                   {}
+func (noopCacheMetrics) ObserveLatency(string, time.Duration) {}
+
+// WithMetrics reports cache hits, misses, writes, errors, state changes and
+// backend latency to m. A nil m disables instrumentation.
+func WithMetrics(m CacheMetrics) CacheOption {
+	return func(c *Cache) {
+		if m != nil {
+			c.metrics = m
+		}
+	}
+}
+
+// observe 記錄從 start 到現在的 backend 延遲
+func (c *Cache) observe(op string, start time.Time) {
+	c.metrics.ObserveLatency(op, time.Since(start))
+}
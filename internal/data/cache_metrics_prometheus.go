@@ -0,0 +1,114 @@
+package data
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheusCacheMetrics implements CacheMetrics with Prometheus collectors.
+type prometheusCacheMetrics struct {
+	hits         *prometheus.CounterVec
+	misses       *prometheus.CounterVec
+	sets         prometheus.Counter
+	deletes      prometheus.Counter
+	errors       *prometheus.CounterVec
+	oversized    prometheus.Counter
+	stateChanges *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+}
+
+// NewPrometheusCacheMetrics registers the cache collectors on reg and returns
+// a CacheMetrics that updates them.
+func NewPrometheusCacheMetrics(reg prometheus.Registerer) (CacheMetrics, error) {
+	m := &prometheusCacheMetrics{
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Number of cache lookups that found a value.",
+		}, []string{"op"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Number of cache lookups that found no fresh value.",
+		}, []string{"op"}),
+		sets: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "sets_total",
+			Help:      "Number of values written to the cache.",
+		}),
+		deletes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "deletes_total",
+			Help:      "Number of keys removed from the cache.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "errors_total",
+			Help:      "Number of failed cache operations.",
+		}, []string{"op"}),
+		oversized: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "oversized_total",
+			Help:      "Number of values not cached because they exceeded the maximum value size.",
+		}),
+		stateChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "state_changes_total",
+			Help:      "Number of times the cache switched to the primary backend, the fallback, or was disabled.",
+		}, []string{"state"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "go_story",
+			Subsystem: "cache",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of cache backend round trips.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"op"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.hits, m.misses, m.sets, m.deletes, m.errors, m.oversized, m.stateChanges, m.latency} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *prometheusCacheMetrics) Hit(op string, n int) {
+	m.hits.WithLabelValues(op).Add(float64(n))
+}
+
+func (m *prometheusCacheMetrics) Miss(op string, n int) {
+	m.misses.WithLabelValues(op).Add(float64(n))
+}
+
+func (m *prometheusCacheMetrics) Set(n int) {
+	m.sets.Add(float64(n))
+}
+
+func (m *prometheusCacheMetrics) Delete(n int) {
+	m.deletes.Add(float64(n))
+}
+
+func (m *prometheusCacheMetrics) Error(op string) {
+	m.errors.WithLabelValues(op).Inc()
+}
+
+func (m *prometheusCacheMetrics) Oversized(int) {
+	m.oversized.Inc()
+}
+
+func (m *prometheusCacheMetrics) StateChange(state string) {
+	m.stateChanges.WithLabelValues(state).Inc()
+}
+
+func (m *prometheusCacheMetrics) ObserveLatency(op string, d time.Duration) {
+	m.latency.WithLabelValues(op).Observe(d.Seconds())
+}
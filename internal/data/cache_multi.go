@@ -0,0 +1,152 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// multiBackend is implemented by backends that can read or write many keys in
+// a single round trip. Backends without it are handled key by key.
+type multiBackend interface {
+	// GetMulti returns one value per key, with nil for missing keys.
+	GetMulti(ctx context.Context, keys []string) ([][]byte, error)
+	// SetMulti stores all items with the same TTL.
+	SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error
+}
+
+// GetMulti fetches keys in one round trip (Redis MGET) and decodes each hit
+// into the destination at the same index. The returned slice reports which
+// keys were found; stale entries count as misses. Keys holding a not-found
+// marker are reported as found with a zero-valued destination.
+func (c *Cache) GetMulti(ctx context.Context, keys []string, dests []interface{}) ([]bool, error) {
+	found := make([]bool, len(keys))
+	if len(keys) != len(dests) {
+		return found, errors.New("cache GetMulti: keys and dests length mismatch")
+	}
+	backend := c.active()
+	if backend == nil || len(keys) == 0 {
+		return found, nil
+	}
+
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = c.fullKey(key)
+	}
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	raws, err := c.rawGetMulti(ctx, backend, fullKeys)
+	c.observe(CacheOpGetMulti, start)
+	if err != nil {
+		c.metrics.Error(CacheOpGetMulti)
+		c.logger.Error("cache get multi failed", "keys", len(keys), "error", err)
+		c.handleBackendError(backend, err)
+		return found, nil
+	}
+	c.handleBackendSuccess(backend)
+
+	now := time.Now()
+	hits := 0
+	for i, raw := range raws {
+		if raw == nil {
+			continue
+		}
+		entry, err := decodeEntry(raw)
+		if err != nil || entry.stale(now) {
+			continue
+		}
+		if entry.notFound() {
+			found[i] = zeroDest(dests[i]) == nil
+			if found[i] {
+				hits++
+				c.traceHit(ctx, entry)
+			}
+			continue
+		}
+		if err := c.decodeValue(keys[i], entry, dests[i]); err != nil {
+			c.metrics.Error(CacheOpDecode)
+			c.logger.Error("cache unmarshal failed", "key", keys[i], "error", err)
+			continue
+		}
+		found[i] = true
+		hits++
+		c.traceHit(ctx, entry)
+	}
+	c.metrics.Hit(CacheOpGetMulti, hits)
+	c.metrics.Miss(CacheOpGetMulti, len(keys)-hits)
+	c.traceMiss(ctx, len(keys)-hits)
+	c.logger.Debug("cache get multi", "hits", hits, "keys", len(keys))
+	return found, nil
+}
+
+// rawGetMulti 使用 backend 的批次讀取，不支援時逐一讀取
+func (c *Cache) rawGetMulti(ctx context.Context, backend CacheBackend, keys []string) ([][]byte, error) {
+	if mb, ok := backend.(multiBackend); ok {
+		return mb.GetMulti(ctx, keys)
+	}
+	result := make([][]byte, len(keys))
+	for i, key := range keys {
+		val, err := backend.Get(ctx, key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[i] = val
+	}
+	return result, nil
+}
+
+// SetMulti stores many values in one round trip (pipelined SET) using the
+// configured TTL.
+func (c *Cache) SetMulti(ctx context.Context, items map[string]interface{}) error {
+	backend := c.active()
+	if backend == nil || len(items) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	ttl := c.jitterTTL(c.ttl)
+	hardTTL := c.hardTTL(ttl)
+	encoded := make(map[string][]byte, len(items))
+	for key, value := range items {
+		data, flags, err := c.encodeValue(key, value)
+		if err != nil {
+			c.metrics.Error(CacheOpMarshal)
+			c.logger.Error("cache marshal failed", "key", key, "error", err)
+			continue
+		}
+		if c.oversized(key, len(data)) {
+			continue
+		}
+		encoded[c.fullKey(key)] = encodeEntry(c.newEntry(now, data, flags, ttl))
+	}
+
+	var err error
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	if mb, ok := backend.(multiBackend); ok {
+		err = mb.SetMulti(ctx, encoded, hardTTL)
+	} else {
+		for key, raw := range encoded {
+			if err = backend.Set(ctx, key, raw, hardTTL); err != nil {
+				break
+			}
+		}
+	}
+	c.observe(CacheOpSetMulti, start)
+	if err != nil {
+		c.metrics.Error(CacheOpSetMulti)
+		c.logger.Error("cache set multi failed", "keys", len(items), "error", err)
+		c.handleBackendError(backend, err)
+		return nil
+	}
+	c.handleBackendSuccess(backend)
+
+	c.metrics.Set(len(encoded))
+	c.logger.Debug("cache set multi", "keys", len(encoded), "ttl", ttl)
+	return nil
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrCachedNotFound is returned by Get (together with found == true) when the
+// key holds a marker written by SetNotFound: the value is known not to exist.
+var ErrCachedNotFound = errors.New("cached not found")
+
+// WithNegativeTTL enables negative caching: SetNotFound remembers missing
+// values for ttl so repeated lookups of deleted or nonexistent slugs do not
+// reach the database. A ttl <= 0 disables it.
+func WithNegativeTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// SetNotFound records that key has no value, using the negative TTL.
+// It does nothing when negative caching is disabled.
+func (c *Cache) SetNotFound(ctx context.Context, key string) error {
+	backend := c.active()
+	if backend == nil || c.negativeTTL <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	entry := cacheEntry{flags: entryFlagNotFound, storedAt: now, softExpiresAt: now.Add(c.negativeTTL)}
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	err := backend.Set(ctx, c.fullKey(key), encodeEntry(entry), c.negativeTTL)
+	c.observe(CacheOpSet, start)
+	if err != nil {
+		c.metrics.Error(CacheOpSet)
+		c.logger.Error("cache set not found failed", "key", key, "error", err)
+		// 如果寫入失敗，可能是連線問題，計入 circuit breaker
+		c.handleBackendError(backend, err)
+		return nil
+	}
+	c.handleBackendSuccess(backend)
+
+	c.metrics.Set(1)
+	c.logger.Debug("cache set not found", "key", key, "ttl", c.negativeTTL)
+	return nil
+}
+
+// zeroDest 將 dest 指向的值設為零值，用於 not found 標記
+func zeroDest(dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("cache destination must be a non-nil pointer, got %T", dest)
+	}
+	dv.Elem().Set(reflect.Zero(dv.Elem().Type()))
+	return nil
+}
@@ -0,0 +1,135 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// cacheOp is a single write queued in a CachePipeline.
+type cacheOp struct {
+	key    string
+	value  []byte
+	ttl    time.Duration
+	delete bool
+}
+
+// batchBackend is implemented by backends that can apply many writes in one
+// round trip. Backends without it apply the operations one by one.
+type batchBackend interface {
+	Batch(ctx context.Context, ops []cacheOp) error
+}
+
+// CachePipeline buffers Set/Delete operations and sends them to the backend
+// in a single round trip when Exec is called. It is not safe for concurrent use.
+type CachePipeline struct {
+	cache *Cache
+	ops   []cacheOp
+	err   error
+}
+
+// Pipeline starts a new batch of writes, e.g. for re-caching a batch of
+// stories after a sync job.
+func (c *Cache) Pipeline() *CachePipeline {
+	return &CachePipeline{cache: c}
+}
+
+// Set queues a write using the cache's default TTL.
+func (p *CachePipeline) Set(key string, value interface{}) *CachePipeline {
+	return p.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL queues a write with its own TTL; ttl <= 0 uses the default TTL.
+func (p *CachePipeline) SetWithTTL(key string, value interface{}, ttl time.Duration) *CachePipeline {
+	if p.err != nil {
+		return p
+	}
+	data, flags, err := p.cache.encodeValue(key, value)
+	if err != nil {
+		p.err = fmt.Errorf("marshal cache value for key %s: %w", key, err)
+		return p
+	}
+	if p.cache.oversized(key, len(data)) {
+		return p
+	}
+	if ttl <= 0 {
+		ttl = p.cache.ttl
+	}
+	ttl = p.cache.jitterTTL(ttl)
+	entry := p.cache.newEntry(time.Now(), data, flags, ttl)
+	p.ops = append(p.ops, cacheOp{key: p.cache.fullKey(key), value: encodeEntry(entry), ttl: p.cache.hardTTL(ttl)})
+	return p
+}
+
+// Delete queues removal of key.
+func (p *CachePipeline) Delete(key string) *CachePipeline {
+	p.ops = append(p.ops, cacheOp{key: p.cache.fullKey(key), delete: true})
+	return p
+}
+
+// Len returns the number of queued operations.
+func (p *CachePipeline) Len() int {
+	return len(p.ops)
+}
+
+// Exec sends all queued operations and resets the pipeline.
+// Marshal errors are returned; backend errors are handled like Set/Delete
+// (fallback or disable) and not returned.
+func (p *CachePipeline) Exec(ctx context.Context) error {
+	ops, err := p.ops, p.err
+	p.ops, p.err = nil, nil
+	if err != nil {
+		return err
+	}
+
+	c := p.cache
+	backend := c.active()
+	if backend == nil || len(ops) == 0 {
+		return nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	if bb, ok := backend.(batchBackend); ok {
+		err = bb.Batch(ctx, ops)
+	} else {
+		err = applyOps(ctx, backend, ops)
+	}
+	c.observe(CacheOpPipeline, start)
+	if err != nil {
+		c.metrics.Error(CacheOpPipeline)
+		c.logger.Error("cache pipeline failed", "ops", len(ops), "error", err)
+		c.handleBackendError(backend, err)
+		return nil
+	}
+	c.handleBackendSuccess(backend)
+
+	deleted := []string{}
+	for _, op := range ops {
+		if op.delete {
+			deleted = append(deleted, op.key)
+		}
+	}
+	c.publishInvalidation(ctx, backend, deleted...)
+	c.metrics.Set(len(ops) - len(deleted))
+	c.metrics.Delete(len(deleted))
+	c.logger.Debug("cache pipeline executed", "ops", len(ops))
+	return nil
+}
+
+// applyOps 逐一套用操作，給不支援批次的 backend 使用
+func applyOps(ctx context.Context, backend CacheBackend, ops []cacheOp) error {
+	for _, op := range ops {
+		var err error
+		if op.delete {
+			err = backend.Delete(ctx, op.key)
+		} else {
+			err = backend.Set(ctx, op.key, op.value, op.ttl)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
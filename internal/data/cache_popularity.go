@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// popularityWindow 為熱門度統計涵蓋的天數 (今天與昨天)，每天一個 sorted set
+const popularityWindow = 2
+
+// popularityBackend is implemented by backends that can keep request counters
+// in sorted sets.
+type popularityBackend interface {
+	// IncrScore adds one to member in the sorted set at key and keeps the set for ttl.
+	IncrScore(ctx context.Context, key, member string, ttl time.Duration) error
+	// TopMembers returns up to n members with the highest combined score across keys.
+	TopMembers(ctx context.Context, keys []string, n int) ([]string, error)
+}
+
+// WithPopularityTracking enables RecordPopularity, which counts requests per
+// item in Redis so that the most requested items can be warmed on startup.
+func WithPopularityTracking(enabled bool) CacheOption {
+	return func(c *Cache) {
+		c.trackPopularity = enabled
+	}
+}
+
+// RecordPopularity counts one request for member (e.g. a story slug) in the
+// named popularity list. It does nothing unless popularity tracking is enabled.
+func (c *Cache) RecordPopularity(ctx context.Context, name, member string) error {
+	if !c.trackPopularity {
+		return nil
+	}
+	pb, ok := c.active().(popularityBackend)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	now := time.Now()
+	if err := pb.IncrScore(ctx, c.popularityKey(name, now), member, popularityWindow*24*time.Hour); err != nil {
+		c.logger.Debug("record popularity failed", "name", name, "member", member, "error", err)
+	}
+	return nil
+}
+
+// TopPopular returns up to n of the most requested members of the named
+// popularity list over the last two days, most popular first.
+func (c *Cache) TopPopular(ctx context.Context, name string, n int) ([]string, error) {
+	pb, ok := c.active().(popularityBackend)
+	if !ok || n <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	keys := make([]string, popularityWindow)
+	for i := range keys {
+		keys[i] = c.popularityKey(name, now.AddDate(0, 0, -i))
+	}
+	return pb.TopMembers(ctx, keys, n)
+}
+
+// popularityKey 回傳某一天的熱門度 sorted set key；以 hash tag 讓同名的每日 key
+// 落在同一個 cluster slot，才能一起 ZUNION
+func (c *Cache) popularityKey(name string, day time.Time) string {
+	return c.fullKey("popular:{" + name + "}:" + day.UTC().Format("20060102"))
+}
+
+func (b *redisBackend) IncrScore(ctx context.Context, key, member string, ttl time.Duration) error {
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZIncrBy(ctx, key, 1, member)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (b *redisBackend) TopMembers(ctx context.Context, keys []string, n int) ([]string, error) {
+	scores, err := b.client.ZUnionWithScores(ctx, redis.ZStore{Keys: keys}).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	if len(scores) > n {
+		scores = scores[:n]
+	}
+	members := make([]string, 0, len(scores))
+	for _, z := range scores {
+		if member, ok := z.Member.(string); ok {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (b *tieredBackend) IncrScore(ctx context.Context, key, member string, ttl time.Duration) error {
+	if pb, ok := b.remote.(popularityBackend); ok {
+		return pb.IncrScore(ctx, key, member, ttl)
+	}
+	return nil
+}
+
+func (b *tieredBackend) TopMembers(ctx context.Context, keys []string, n int) ([]string, error) {
+	if pb, ok := b.remote.(popularityBackend); ok {
+		return pb.TopMembers(ctx, keys, n)
+	}
+	return nil, nil
+}
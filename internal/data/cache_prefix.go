@@ -0,0 +1,45 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// prefixBackend is implemented by backends that can delete every key
+// starting with a prefix.
+type prefixBackend interface {
+	// DeleteByPrefix removes all keys starting with prefix and returns how
+	// many were deleted.
+	DeleteByPrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// DeleteByPrefix removes every entry whose key starts with prefix, e.g.
+// "posts:" after a data backfill, without flushing the whole database.
+// Redis keys are found with SCAN (never KEYS) and deleted in batches.
+// It returns the number of deleted entries.
+func (c *Cache) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	backend := c.active()
+	if backend == nil {
+		return 0, nil
+	}
+	pb, ok := backend.(prefixBackend)
+	if !ok {
+		return 0, nil
+	}
+
+	start := time.Now()
+	n, err := pb.DeleteByPrefix(ctx, c.fullKey(prefix))
+	c.observe(CacheOpDeletePrefix, start)
+	if err != nil {
+		c.metrics.Error(CacheOpDeletePrefix)
+		c.logger.Error("cache prefix delete failed", "prefix", prefix, "deleted", n, "error", err)
+		c.handleBackendError(backend, err)
+		return n, nil
+	}
+	c.handleBackendSuccess(backend)
+	c.publishPrefixInvalidation(ctx, backend, c.fullKey(prefix))
+
+	c.metrics.Delete(n)
+	c.logger.Info("cache prefix deleted", "prefix", prefix, "deleted", n)
+	return n, nil
+}
@@ -0,0 +1,364 @@
+package data
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis connection modes accepted by WithRedisMode (REDIS_MODE).
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeCluster    = "cluster"
+	RedisModeSentinel   = "sentinel"
+)
+
+// redisConnOptions 收集建立 Redis client 所需的連線設定
+type redisConnOptions struct {
+	url              string
+	mode             string
+	sentinelMaster   string
+	sentinelAddrs    []string
+	sentinelPassword string
+	username         string
+	password         string
+	tlsConfig        *tls.Config
+	dialTimeout      time.Duration
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	contextTimeout   bool // 讓 context 的 deadline 套用到連線讀寫
+}
+
+// resolvedAuth 回傳覆寫後的帳號密碼；未明確設定時沿用 URL 中的值
+func (o redisConnOptions) resolvedAuth(username, password string) (string, string) {
+	if o.username != "" {
+		username = o.username
+	}
+	if o.password != "" {
+		password = o.password
+	}
+	return username, password
+}
+
+// resolvedTLS 回傳實際使用的 TLS 設定；明確設定時優先，並沿用 rediss:// URL 推得的 ServerName
+func (o redisConnOptions) resolvedTLS(fromURL *tls.Config) *tls.Config {
+	if o.tlsConfig == nil {
+		return fromURL
+	}
+	cfg := o.tlsConfig.Clone()
+	if cfg.ServerName == "" && fromURL != nil {
+		cfg.ServerName = fromURL.ServerName
+	}
+	return cfg
+}
+
+// resolvedMode 回傳實際使用的連線模式；未指定時依設定自動判斷
+func (o redisConnOptions) resolvedMode() string {
+	if o.mode != "" {
+		return o.mode
+	}
+	if o.sentinelMaster != "" && len(o.sentinelAddrs) > 0 {
+		return RedisModeSentinel
+	}
+	if u, err := url.Parse(o.url); err == nil && u.Query().Has("addr") {
+		return RedisModeCluster
+	}
+	return RedisModeStandalone
+}
+
+// applyTimeouts 將有設定的 timeout 寫入各模式的 client 選項
+func (o redisConnOptions) applyTimeouts(dial, read, write *time.Duration, contextTimeout *bool) {
+	if o.dialTimeout > 0 {
+		*dial = o.dialTimeout
+	}
+	if o.readTimeout > 0 {
+		*read = o.readTimeout
+	}
+	if o.writeTimeout > 0 {
+		*write = o.writeTimeout
+	}
+	if o.contextTimeout {
+		*contextTimeout = true
+	}
+}
+
+// newRedisClient 依連線模式建立 Redis client。
+// cluster 模式的 URL 格式為 redis://node1:6379?addr=node2:6379&addr=node3:6379；
+// sentinel 模式的 URL 可省略，若提供則沿用其中的帳號、密碼與 DB 設定
+func newRedisClient(o redisConnOptions) (redis.UniversalClient, error) {
+	switch mode := o.resolvedMode(); mode {
+	case RedisModeStandalone:
+		opt, err := redis.ParseURL(o.url)
+		if err != nil {
+			return nil, err
+		}
+		opt.Username, opt.Password = o.resolvedAuth(opt.Username, opt.Password)
+		opt.TLSConfig = o.resolvedTLS(opt.TLSConfig)
+		o.applyTimeouts(&opt.DialTimeout, &opt.ReadTimeout, &opt.WriteTimeout, &opt.ContextTimeoutEnabled)
+		return redis.NewClient(opt), nil
+	case RedisModeCluster:
+		opt, err := redis.ParseClusterURL(o.url)
+		if err != nil {
+			return nil, err
+		}
+		opt.Username, opt.Password = o.resolvedAuth(opt.Username, opt.Password)
+		opt.TLSConfig = o.resolvedTLS(opt.TLSConfig)
+		o.applyTimeouts(&opt.DialTimeout, &opt.ReadTimeout, &opt.WriteTimeout, &opt.ContextTimeoutEnabled)
+		return redis.NewClusterClient(opt), nil
+	case RedisModeSentinel:
+		if o.sentinelMaster == "" || len(o.sentinelAddrs) == 0 {
+			return nil, errors.New("sentinel mode requires a master name and sentinel addresses")
+		}
+		failoverOpt := &redis.FailoverOptions{
+			MasterName:       o.sentinelMaster,
+			SentinelAddrs:    o.sentinelAddrs,
+			SentinelPassword: o.sentinelPassword,
+		}
+		if o.url != "" {
+			opt, err := redis.ParseURL(o.url)
+			if err != nil {
+				return nil, err
+			}
+			failoverOpt.Username = opt.Username
+			failoverOpt.Password = opt.Password
+			failoverOpt.DB = opt.DB
+			failoverOpt.TLSConfig = opt.TLSConfig
+		}
+		failoverOpt.Username, failoverOpt.Password = o.resolvedAuth(failoverOpt.Username, failoverOpt.Password)
+		failoverOpt.TLSConfig = o.resolvedTLS(failoverOpt.TLSConfig)
+		o.applyTimeouts(&failoverOpt.DialTimeout, &failoverOpt.ReadTimeout, &failoverOpt.WriteTimeout, &failoverOpt.ContextTimeoutEnabled)
+		return redis.NewFailoverClient(failoverOpt), nil
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", mode)
+	}
+}
+
+// redisBackend implements CacheBackend on top of a Redis client.
+// Both single-node and cluster clients are supported.
+type redisBackend struct {
+	client redis.UniversalClient
+}
+
+// NewRedisBackend wraps an existing Redis client as a CacheBackend.
+func NewRedisBackend(client redis.UniversalClient) CacheBackend {
+	return &redisBackend{client: client}
+}
+
+// isCluster 判斷是否為 cluster client；cluster 下跨 slot 的 MGET 會失敗，需改用 pipeline
+func (b *redisBackend) isCluster() bool {
+	_, ok := b.client.(*redis.ClusterClient)
+	return ok
+}
+
+func (b *redisBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := b.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return val, err
+}
+
+func (b *redisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (b *redisBackend) Delete(ctx context.Context, key string) error {
+	return b.client.Del(ctx, key).Err()
+}
+
+func (b *redisBackend) Close() error {
+	return b.client.Close()
+}
+
+// cachePinger is implemented by backends that can report connectivity.
+type cachePinger interface {
+	Ping(ctx context.Context) error
+}
+
+func (b *redisBackend) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
+
+func (b *redisBackend) GetMulti(ctx context.Context, keys []string) ([][]byte, error) {
+	if b.isCluster() {
+		return b.getMultiPipelined(ctx, keys)
+	}
+	vals, err := b.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]byte, len(keys))
+	for i, v := range vals {
+		if s, ok := v.(string); ok {
+			result[i] = []byte(s)
+		}
+	}
+	return result, nil
+}
+
+// getMultiPipelined 以 pipeline 送出多個 GET，cluster client 會自動依 slot 分派
+func (b *redisBackend) getMultiPipelined(ctx context.Context, keys []string) ([][]byte, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	result := make([][]byte, len(keys))
+	for i, cmd := range cmds {
+		if val, err := cmd.Bytes(); err == nil {
+			result[i] = val
+		}
+	}
+	return result, nil
+}
+
+func (b *redisBackend) SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range items {
+			pipe.Set(ctx, key, value, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// addTagScript 將 key 加入 tag 集合，並只延長 (不縮短) 集合的 TTL；
+// ARGV[2] 為毫秒，0 表示不過期
+var addTagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	redis.call('PERSIST', KEYS[1])
+	return 1
+end
+local cur = redis.call('PTTL', KEYS[1])
+if existed == 0 or (cur >= 0 and cur < ttl) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+func (b *redisBackend) AddTags(ctx context.Context, key string, tagKeys []string, ttl time.Duration) error {
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tagKey := range tagKeys {
+			addTagScript.Eval(ctx, pipe, []string{tagKey}, key, ttl.Milliseconds())
+		}
+		return nil
+	})
+	return err
+}
+
+func (b *redisBackend) InvalidateTag(ctx context.Context, tagKey string) ([]string, error) {
+	keys, err := b.client.SMembers(ctx, tagKey).Result()
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	// 只移除讀到的成員，不直接刪除集合，避免漏掉期間新加入的 key
+	members := make([]interface{}, len(keys))
+	for i, key := range keys {
+		members[i] = key
+	}
+	_, err = b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		pipe.SRem(ctx, tagKey, members...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// prefixScanCount 為每次 SCAN 的 COUNT，同時也是每批刪除的數量
+const prefixScanCount = 500
+
+func (b *redisBackend) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	pattern := escapeGlob(prefix) + "*"
+	cluster, ok := b.client.(*redis.ClusterClient)
+	if !ok {
+		return scanDelete(ctx, b.client, pattern)
+	}
+
+	// cluster 需在每個 master 上各自 SCAN
+	var mu sync.Mutex
+	total := 0
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		n, err := scanDelete(ctx, node, pattern)
+		mu.Lock()
+		total += n
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+// scanDelete 以 SCAN 逐批找出符合 pattern 的 key 並以 pipeline 刪除
+func scanDelete(ctx context.Context, client redis.UniversalClient, pattern string) (int, error) {
+	total := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, prefixScanCount).Result()
+		if err != nil {
+			return total, err
+		}
+		if len(keys) > 0 {
+			// 逐一 DEL 而非單一多 key DEL，避免 cluster 的 CROSSSLOT 錯誤
+			_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Del(ctx, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return total, err
+			}
+			total += len(keys)
+		}
+		if next == 0 {
+			return total, nil
+		}
+		cursor = next
+	}
+}
+
+// escapeGlob 跳脫 SCAN MATCH pattern 中的特殊字元
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (b *redisBackend) Batch(ctx context.Context, ops []cacheOp) error {
+	_, err := b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, op := range ops {
+			if op.delete {
+				pipe.Del(ctx, op.key)
+			} else {
+				pipe.Set(ctx, op.key, op.value, op.ttl)
+			}
+		}
+		return nil
+	})
+	return err
+}
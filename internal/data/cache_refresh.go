@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// GetOrRefresh loads key into dest, calling loader on a miss.
+// When stale-while-revalidate is enabled, an entry past its TTL is still
+// decoded into dest and returned immediately, while loader refreshes it in the
+// background. Concurrent misses for the same key share one loader call.
+// Nil results are returned but not cached, while empty slices are; a not-found
+// marker from SetNotFound yields the zero value without calling loader.
+// GetOrRefresh is safe to call on a nil or disabled Cache.
+func (c *Cache) GetOrRefresh(ctx context.Context, key string, dest interface{}, loader func(ctx context.Context) (interface{}, error)) error {
+	if c != nil && c.Enabled() {
+		if entry, found := c.lookup(ctx, key); found {
+			if entry.notFound() {
+				c.metrics.Hit(CacheOpGet, 1)
+				c.traceHit(ctx, entry)
+				c.logger.Debug("cache hit (not found)", "key", key)
+				return zeroDest(dest)
+			}
+			err := c.decodeValue(key, entry, dest)
+			if err == nil {
+				c.metrics.Hit(CacheOpGet, 1)
+				c.traceHit(ctx, entry)
+				if entry.stale(time.Now()) {
+					c.logger.Debug("serving stale entry while refreshing", "key", key)
+					c.refreshAsync(key, 0, loader)
+				} else {
+					c.logger.Debug("cache hit", "key", key)
+					c.refreshIfHot(key, entry, 0, loader)
+				}
+				return nil
+			}
+			c.metrics.Error(CacheOpDecode)
+			c.logger.Error("cache unmarshal failed", "key", key, "error", err)
+		}
+		c.metrics.Miss(CacheOpGet, 1)
+		c.traceMiss(ctx, 1)
+	}
+
+	v, err := c.Do(ctx, key, c.loadAndStore(key, 0, loader))
+	if err != nil {
+		return err
+	}
+	return assignResult(dest, v)
+}
+
+// GetOrSet loads key into dest, calling loader on a miss and caching its
+// result for ttl (ttl <= 0 uses the configured TTL). Concurrent misses for the
+// same key share one loader call. A nil result is recorded with SetNotFound
+// when negative caching is enabled; an empty slice is cached like any value.
+// With WithHotKeyRefresh, hot entries are reloaded in the background shortly
+// before they expire. GetOrSet is safe to call on a nil or disabled Cache.
+func (c *Cache) GetOrSet(ctx context.Context, key string, dest interface{}, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) error {
+	if c != nil && c.Enabled() {
+		entry, found, err := c.get(ctx, key, dest)
+		if found && (err == nil || errors.Is(err, ErrCachedNotFound)) {
+			c.refreshIfHot(key, entry, ttl, loader)
+			return nil
+		}
+	}
+
+	v, err := c.Do(ctx, key, c.loadAndStore(key, ttl, loader))
+	if err != nil {
+		return err
+	}
+	return assignResult(dest, v)
+}
+
+// refreshAsync 在背景重新載入 key 並以 ttl 寫回；同一個 key 同時只會有一個更新在進行
+func (c *Cache) refreshAsync(key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) {
+	if _, busy := c.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	go func() {
+		defer c.refreshing.Delete(key)
+		if _, err := c.Do(context.Background(), key, c.loadAndStore(key, ttl, loader)); err != nil {
+			c.logger.Error("background refresh failed", "key", key, "error", err)
+		}
+	}()
+}
+
+// loadAndStore 包裝 loader，成功後將結果以 ttl 寫入 cache (ttl <= 0 使用預設 TTL)；
+// 結果為 nil 時寫入 not found 標記
+func (c *Cache) loadAndStore(key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) func(ctx context.Context) (interface{}, error) {
+	return func(ctx context.Context) (interface{}, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return v, nil
+		}
+		if cacheable(v) {
+			_ = c.SetWithTTL(ctx, key, v, ttl)
+		} else if isNil(v) {
+			_ = c.SetNotFound(ctx, key)
+		}
+		return v, nil
+	}
+}
+
+// isNil 判斷 loader 的結果是否為 nil 或 nil 指標 (查無資料)
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// cacheable 判斷 loader 的結果是否值得快取：nil 不快取，空 slice 代表查無結果，照常快取
+func cacheable(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface, reflect.Slice:
+		return !rv.IsNil()
+	}
+	return true
+}
+
+// assignResult 將 loader 回傳的值寫入 dest (需為非 nil 指標)。
+// 型別相符時直接指派，否則透過 JSON 轉換
+func assignResult(dest interface{}, v interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("cache destination must be a non-nil pointer, got %T", dest)
+	}
+	target := dv.Elem()
+	if v == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	vv := reflect.ValueOf(v)
+	if vv.Type().AssignableTo(target.Type()) {
+		target.Set(vv)
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal loader result: %w", err)
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return fmt.Errorf("unmarshal loader result: %w", err)
+	}
+	return nil
+}
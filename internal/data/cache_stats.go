@@ -0,0 +1,294 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// statsSampleSize 為 Stats 以 SCAN 取樣的 key 數上限
+const statsSampleSize = 1000
+
+// CacheStats is a point-in-time snapshot of cache behaviour for operators.
+type CacheStats struct {
+	State        string         `json:"state"` // primary / fallback / disabled
+	Hits         int64          `json:"hits"`
+	Misses       int64          `json:"misses"`
+	Sets         int64          `json:"sets"`
+	Deletes      int64          `json:"deletes"`
+	Errors       int64          `json:"errors"`
+	Oversized    int64          `json:"oversized"`
+	HitRate      float64        `json:"hitRate"`
+	SampledKeys  int            `json:"sampledKeys"`
+	KeysByPrefix map[string]int `json:"keysByPrefix,omitempty"`
+	MemoryBytes  int64          `json:"memoryBytes"`
+	LastError    string         `json:"lastError,omitempty"`
+	LastErrorAt  *time.Time     `json:"lastErrorAt,omitempty"`
+}
+
+// statsBackend is implemented by backends that can be introspected by Stats.
+type statsBackend interface {
+	// SampleKeys returns up to limit keys starting with prefix.
+	SampleKeys(ctx context.Context, prefix string, limit int) ([]string, error)
+	// MemoryUsage returns the memory used by the backend in bytes.
+	MemoryUsage(ctx context.Context) (int64, error)
+}
+
+// statsMetrics 累計各項次數並轉送給設定的 CacheMetrics
+type statsMetrics struct {
+	next CacheMetrics
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	sets      atomic.Int64
+	deletes   atomic.Int64
+	errors    atomic.Int64
+	oversized atomic.Int64
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// initStats 在套用 options 後包裝 metrics，讓 Stats 可以取得累計次數
+func (c *Cache) initStats() {
+	c.stats = &statsMetrics{next: c.metrics}
+	c.metrics = c.stats
+}
+
+func (m *statsMetrics) Hit(op string, n int) {
+	m.hits.Add(int64(n))
+	m.next.Hit(op, n)
+}
+
+func (m *statsMetrics) Miss(op string, n int) {
+	m.misses.Add(int64(n))
+	m.next.Miss(op, n)
+}
+
+func (m *statsMetrics) Set(n int) {
+	m.sets.Add(int64(n))
+	m.next.Set(n)
+}
+
+func (m *statsMetrics) Delete(n int) {
+	m.deletes.Add(int64(n))
+	m.next.Delete(n)
+}
+
+func (m *statsMetrics) Error(op string) {
+	m.errors.Add(1)
+	m.next.Error(op)
+}
+
+func (m *statsMetrics) Oversized(size int) {
+	m.oversized.Add(1)
+	m.next.Oversized(size)
+}
+
+func (m *statsMetrics) StateChange(state string) {
+	m.next.StateChange(state)
+}
+
+func (m *statsMetrics) ObserveLatency(op string, d time.Duration) {
+	m.next.ObserveLatency(op, d)
+}
+
+// recordError 記錄最近一次 backend 錯誤
+func (m *statsMetrics) recordError(err error) {
+	if m == nil || err == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastError = err.Error()
+	m.lastErrorAt = time.Now()
+}
+
+// Stats returns counters accumulated since startup, the backend currently in
+// use, the last backend error, and, when the backend supports it, key counts
+// grouped by their first key segment (from a SCAN sample of up to 1000 keys)
+// and the backend's memory usage.
+func (c *Cache) Stats(ctx context.Context) CacheStats {
+	stats := CacheStats{State: c.state()}
+	if c.stats == nil {
+		return stats
+	}
+	stats.Hits = c.stats.hits.Load()
+	stats.Misses = c.stats.misses.Load()
+	stats.Sets = c.stats.sets.Load()
+	stats.Deletes = c.stats.deletes.Load()
+	stats.Errors = c.stats.errors.Load()
+	stats.Oversized = c.stats.oversized.Load()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	c.stats.mu.Lock()
+	if c.stats.lastError != "" {
+		at := c.stats.lastErrorAt
+		stats.LastError = c.stats.lastError
+		stats.LastErrorAt = &at
+	}
+	c.stats.mu.Unlock()
+
+	sb, ok := c.active().(statsBackend)
+	if !ok {
+		return stats
+	}
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	keys, err := sb.SampleKeys(ctx, c.keyPrefix, statsSampleSize)
+	if err != nil {
+		c.logger.Warn("cache stats key sampling failed", "error", err)
+	}
+	stats.SampledKeys = len(keys)
+	if len(keys) > 0 {
+		stats.KeysByPrefix = map[string]int{}
+		for _, key := range keys {
+			segment, _, _ := strings.Cut(strings.TrimPrefix(key, c.keyPrefix), ":")
+			stats.KeysByPrefix[segment]++
+		}
+	}
+	if stats.MemoryBytes, err = sb.MemoryUsage(ctx); err != nil {
+		c.logger.Warn("cache stats memory lookup failed", "error", err)
+	}
+	return stats
+}
+
+// state 回傳目前提供服務的 backend
+func (c *Cache) state() string {
+	c.mu.RLock()
+	enabled := c.enabled
+	c.mu.RUnlock()
+	switch {
+	case !enabled:
+		return CacheStateDisabled
+	case c.primary != nil && c.breaker.available(time.Now()):
+		return CacheStatePrimary
+	case c.fallback != nil:
+		return CacheStateFallback
+	}
+	return CacheStateDisabled
+}
+
+func (b *redisBackend) SampleKeys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	pattern := escapeGlob(prefix) + "*"
+	cluster, ok := b.client.(*redis.ClusterClient)
+	if !ok {
+		return scanSample(ctx, b.client, pattern, limit)
+	}
+
+	// cluster 需在每個 master 上各自 SCAN
+	var mu sync.Mutex
+	result := []string{}
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		keys, err := scanSample(ctx, node, pattern, limit)
+		mu.Lock()
+		result = append(result, keys...)
+		mu.Unlock()
+		return err
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, err
+}
+
+// scanSample 以 SCAN 取得最多 limit 個符合 pattern 的 key
+func scanSample(ctx context.Context, client redis.UniversalClient, pattern string, limit int) ([]string, error) {
+	result := []string{}
+	var cursor uint64
+	for len(result) < limit {
+		keys, next, err := client.Scan(ctx, cursor, pattern, prefixScanCount).Result()
+		if err != nil {
+			return result, err
+		}
+		result = append(result, keys...)
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (b *redisBackend) MemoryUsage(ctx context.Context) (int64, error) {
+	cluster, ok := b.client.(*redis.ClusterClient)
+	if !ok {
+		return usedMemory(ctx, b.client)
+	}
+
+	var total atomic.Int64
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		n, err := usedMemory(ctx, node)
+		total.Add(n)
+		return err
+	})
+	return total.Load(), err
+}
+
+// usedMemory 從 INFO memory 解析 used_memory
+func usedMemory(ctx context.Context, client redis.UniversalClient) (int64, error) {
+	info, err := client.Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "used_memory:"); ok {
+			return strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return 0, nil
+}
+
+func (b *tieredBackend) SampleKeys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	if sb, ok := b.remote.(statsBackend); ok {
+		return sb.SampleKeys(ctx, prefix, limit)
+	}
+	return nil, nil
+}
+
+func (b *tieredBackend) MemoryUsage(ctx context.Context) (int64, error) {
+	if sb, ok := b.remote.(statsBackend); ok {
+		return sb.MemoryUsage(ctx)
+	}
+	return 0, nil
+}
+
+func (b *memoryBackend) SampleKeys(_ context.Context, prefix string, limit int) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := []string{}
+	for key := range b.items {
+		if len(keys) >= limit {
+			break
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// MemoryUsage 回傳所有 key 與 value 的大小總和 (不含資料結構本身的開銷)
+func (b *memoryBackend) MemoryUsage(_ context.Context) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var total int64
+	for key, el := range b.items {
+		total += int64(len(key) + len(el.Value.(*memoryEntry).value))
+	}
+	return total, nil
+}
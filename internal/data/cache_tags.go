@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// tagBackend is implemented by backends that can index keys by tag.
+// Backends without it ignore tags.
+type tagBackend interface {
+	// AddTags records key as a member of each tag set. The tag sets are kept
+	// at least as long as ttl (0 means no expiry).
+	AddTags(ctx context.Context, key string, tagKeys []string, ttl time.Duration) error
+	// InvalidateTag deletes every key recorded under tagKey and returns them.
+	InvalidateTag(ctx context.Context, tagKey string) ([]string, error)
+}
+
+// SetWithTags stores a value using the configured TTL and attaches tags to it,
+// e.g. the story ID, author ID and section a listing contains, so that
+// InvalidateTag can purge it later.
+func (c *Cache) SetWithTags(ctx context.Context, key string, value interface{}, tags ...string) error {
+	if err := c.store(ctx, key, value, c.ttl); err != nil {
+		return err
+	}
+	return c.addTags(ctx, key, tags)
+}
+
+// InvalidateTag deletes all entries carrying tag, e.g. a story's detail view,
+// the list pages and the feeds it appears in once it is republished.
+func (c *Cache) InvalidateTag(ctx context.Context, tag string) error {
+	backend := c.active()
+	if backend == nil {
+		return nil
+	}
+	tb, ok := backend.(tagBackend)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	keys, err := tb.InvalidateTag(ctx, c.tagKey(tag))
+	c.observe(CacheOpInvalidateTag, start)
+	if err != nil {
+		c.metrics.Error(CacheOpInvalidateTag)
+		c.logger.Error("cache tag invalidation failed", "tag", tag, "error", err)
+		c.handleBackendError(backend, err)
+		return nil
+	}
+	c.handleBackendSuccess(backend)
+	c.publishInvalidation(ctx, backend, keys...)
+
+	c.metrics.Delete(len(keys))
+	c.logger.Info("cache tag invalidated", "tag", tag, "keys", len(keys))
+	return nil
+}
+
+// addTags 將 key 加入各個 tag 的集合
+func (c *Cache) addTags(ctx context.Context, key string, tags []string) error {
+	backend := c.active()
+	if backend == nil || len(tags) == 0 {
+		return nil
+	}
+	tb, ok := backend.(tagBackend)
+	if !ok {
+		return nil
+	}
+
+	tagKeys := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKeys[i] = c.tagKey(tag)
+	}
+	// tag 集合至少要保存到 entry 最晚可能過期的時間 (含 jitter 與 stale 視窗)
+	ttl := c.hardTTL(c.ttl + time.Duration(c.ttlJitter*float64(c.ttl)))
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	err := tb.AddTags(ctx, c.fullKey(key), tagKeys, ttl)
+	c.observe(CacheOpTag, start)
+	if err != nil {
+		c.metrics.Error(CacheOpTag)
+		c.logger.Error("cache tagging failed", "key", key, "error", err)
+		c.handleBackendError(backend, err)
+		return nil
+	}
+	c.handleBackendSuccess(backend)
+	return nil
+}
+
+// tagKey 回傳 tag 集合在 backend 中的 key
+func (c *Cache) tagKey(tag string) string {
+	return c.fullKey("tag:" + tag)
+}
@@ -0,0 +1,199 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-story/internal/data"
+	"go-story/internal/data/cachetest"
+)
+
+// testItem 為測試寫入 cache 的值
+type testItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestCacheSetAndGet(t *testing.T) {
+	h, _ := cachetest.New(t)
+	ctx := context.Background()
+
+	if err := h.Cache.Set(ctx, "item:1", testItem{Name: "one", Count: 1}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	h.ResetCounts()
+
+	var got testItem
+	found, err := h.Cache.Get(ctx, "item:1", &got)
+	if err != nil || !found {
+		t.Fatalf("Get = %v, %v; want found", found, err)
+	}
+	if got != (testItem{Name: "one", Count: 1}) {
+		t.Errorf("Get value = %+v", got)
+	}
+	if found, _ := h.Cache.Get(ctx, "item:2", &got); found {
+		t.Errorf("Get of a missing key reported found")
+	}
+	h.AssertHits(1)
+	h.AssertMisses(1)
+}
+
+func TestCacheEntriesExpire(t *testing.T) {
+	h, server := cachetest.New(t)
+	ctx := context.Background()
+
+	if err := h.Cache.SetWithTTL(ctx, "item:ttl", testItem{Name: "ttl"}, 10*time.Second); err != nil {
+		t.Fatalf("SetWithTTL: %v", err)
+	}
+	h.AssertStored("item:ttl")
+	server.FastForward(time.Minute)
+	h.AssertNotStored("item:ttl")
+}
+
+func TestCacheDeleteByPrefix(t *testing.T) {
+	h, _ := cachetest.New(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"story:a", "story:b", "author:a"} {
+		if err := h.Cache.Set(ctx, key, testItem{Name: key}); err != nil {
+			t.Fatalf("Set %s: %v", key, err)
+		}
+	}
+	n, err := h.Cache.DeleteByPrefix(ctx, "story:")
+	if err != nil {
+		t.Fatalf("DeleteByPrefix: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteByPrefix deleted %d keys, want 2", n)
+	}
+	h.AssertNotStored("story:a")
+	h.AssertNotStored("story:b")
+	h.AssertStored("author:a")
+}
+
+func TestCacheGetMultiAndSetMulti(t *testing.T) {
+	h, _ := cachetest.New(t, data.WithNegativeTTL(time.Minute))
+	ctx := context.Background()
+
+	if err := h.Cache.SetMulti(ctx, map[string]interface{}{
+		"item:1": testItem{Name: "one"},
+		"item:3": testItem{Name: "three"},
+	}); err != nil {
+		t.Fatalf("SetMulti: %v", err)
+	}
+	if err := h.Cache.SetNotFound(ctx, "item:4"); err != nil {
+		t.Fatalf("SetNotFound: %v", err)
+	}
+
+	items := make([]testItem, 4)
+	dests := []interface{}{&items[0], &items[1], &items[2], &items[3]}
+	found, err := h.Cache.GetMulti(ctx, []string{"item:1", "item:2", "item:3", "item:4"}, dests)
+	if err != nil {
+		t.Fatalf("GetMulti: %v", err)
+	}
+	want := []bool{true, false, true, true}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("GetMulti found[%d] = %v, want %v", i, found[i], want[i])
+		}
+	}
+	if items[0].Name != "one" || items[2].Name != "three" || items[3] != (testItem{}) {
+		t.Errorf("GetMulti values = %+v", items)
+	}
+}
+
+func TestCacheGetOrSetLoadsOnce(t *testing.T) {
+	h, recorder := cachetest.NewRecording(t)
+	ctx := context.Background()
+	typed := data.NewTypedCache[testItem](h.Cache)
+
+	loads := 0
+	load := func(context.Context) (testItem, error) {
+		loads++
+		return testItem{Name: "loaded", Count: loads}, nil
+	}
+	for i := 0; i < 3; i++ {
+		got, err := typed.GetOrSet(ctx, "item:load", 0, load)
+		if err != nil {
+			t.Fatalf("GetOrSet: %v", err)
+		}
+		if got.Name != "loaded" || got.Count != 1 {
+			t.Errorf("GetOrSet #%d = %+v, want the first load", i, got)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loader called %d times, want 1", loads)
+	}
+
+	var stored testItem
+	h.AssertSet(recorder, "item:load", &stored)
+	if stored.Name != "loaded" {
+		t.Errorf("stored value = %+v", stored)
+	}
+}
+
+func TestCacheGetOrSetPropagatesErrors(t *testing.T) {
+	h, recorder := cachetest.NewRecording(t)
+	errLoad := errors.New("load failed")
+
+	_, err := data.NewTypedCache[testItem](h.Cache).GetOrSet(context.Background(), "item:error", 0, func(context.Context) (testItem, error) {
+		return testItem{}, errLoad
+	})
+	if !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrSet err = %v, want the loader error", err)
+	}
+	if keys := recorder.Keys(cachetest.OpSet); len(keys) != 0 {
+		t.Errorf("failed load was cached under %v", keys)
+	}
+}
+
+func TestCacheNoopBackendAlwaysLoads(t *testing.T) {
+	cache := data.NewCacheWithBackend(cachetest.NoopCache{}, 60, "test")
+	t.Cleanup(func() { _ = cache.Close() })
+	typed := data.NewTypedCache[testItem](cache)
+
+	loads := 0
+	for i := 0; i < 2; i++ {
+		if _, err := typed.GetOrSet(context.Background(), "item:noop", 0, func(context.Context) (testItem, error) {
+			loads++
+			return testItem{Name: "noop"}, nil
+		}); err != nil {
+			t.Fatalf("GetOrSet: %v", err)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("loader called %d times, want 2", loads)
+	}
+}
+
+func TestCacheGetOrSetStoresEmptySlice(t *testing.T) {
+	h, recorder := cachetest.NewRecording(t)
+	ctx := context.Background()
+	typed := data.NewTypedCache[[]testItem](h.Cache)
+
+	loads := 0
+	load := func(context.Context) ([]testItem, error) {
+		loads++
+		return []testItem{}, nil
+	}
+	for i := 0; i < 3; i++ {
+		got, err := typed.GetOrSet(ctx, "item:empty", 0, load)
+		if err != nil {
+			t.Fatalf("GetOrSet: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("GetOrSet #%d = %+v, want an empty slice", i, got)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loader called %d times, want 1", loads)
+	}
+
+	var stored []testItem
+	h.AssertSet(recorder, "item:empty", &stored)
+	if stored == nil || len(stored) != 0 {
+		t.Errorf("stored value = %#v, want an empty slice", stored)
+	}
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// tieredBackend keeps a small short-lived in-process LRU in front of Redis.
+// Writes are broadcast on the invalidation bus so every instance drops its
+// local copy of the key; deletes are broadcast by Cache itself.
+type tieredBackend struct {
+	local    CacheBackend
+	remote   CacheBackend
+	localTTL time.Duration
+	bus      *invalidationBus
+}
+
+// newTieredBackend wraps remote with a local LRU of localSize entries that
+// drops entries invalidated by other instances on bus.
+func newTieredBackend(remote CacheBackend, bus *invalidationBus, localSize int, localTTL time.Duration) *tieredBackend {
+	b := &tieredBackend{
+		local:    NewMemoryBackend(localSize),
+		remote:   remote,
+		localTTL: localTTL,
+		bus:      bus,
+	}
+	bus.subscribe(b.dropLocal)
+	return b
+}
+
+// dropLocal 刪除其他 instance 通知失效的本地 entry
+func (b *tieredBackend) dropLocal(inv invalidation) {
+	if inv.prefix {
+		if pb, ok := b.local.(prefixBackend); ok {
+			_, _ = pb.DeleteByPrefix(context.Background(), inv.key)
+		}
+		return
+	}
+	_ = b.local.Delete(context.Background(), inv.key)
+}
+
+func (b *tieredBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if val, err := b.local.Get(ctx, key); err == nil {
+		return val, nil
+	}
+	val, err := b.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	_ = b.local.Set(ctx, key, val, b.localTTL)
+	return val, nil
+}
+
+func (b *tieredBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := b.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	localTTL := b.localTTL
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	_ = b.local.Set(ctx, key, value, localTTL)
+	return b.bus.publish(ctx, key)
+}
+
+func (b *tieredBackend) Delete(ctx context.Context, key string) error {
+	_ = b.local.Delete(ctx, key)
+	return b.remote.Delete(ctx, key)
+}
+
+func (b *tieredBackend) GetMulti(ctx context.Context, keys []string) ([][]byte, error) {
+	result := make([][]byte, len(keys))
+	missing := []string{}
+	missingIdx := []int{}
+	for i, key := range keys {
+		if val, err := b.local.Get(ctx, key); err == nil {
+			result[i] = val
+			continue
+		}
+		missing = append(missing, key)
+		missingIdx = append(missingIdx, i)
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	mb, ok := b.remote.(multiBackend)
+	if !ok {
+		for j, key := range missing {
+			val, err := b.Get(ctx, key)
+			if err != nil && !errors.Is(err, ErrCacheMiss) {
+				return nil, err
+			}
+			result[missingIdx[j]] = val
+		}
+		return result, nil
+	}
+	vals, err := mb.GetMulti(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, val := range vals {
+		if val == nil {
+			continue
+		}
+		result[missingIdx[j]] = val
+		_ = b.local.Set(ctx, missing[j], val, b.localTTL)
+	}
+	return result, nil
+}
+
+func (b *tieredBackend) SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	mb, ok := b.remote.(multiBackend)
+	if !ok {
+		for key, value := range items {
+			if err := b.Set(ctx, key, value, ttl); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := mb.SetMulti(ctx, items, ttl); err != nil {
+		return err
+	}
+	localTTL := b.localTTL
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	keys := make([]string, 0, len(items))
+	for key, value := range items {
+		_ = b.local.Set(ctx, key, value, localTTL)
+		keys = append(keys, key)
+	}
+	return b.bus.publish(ctx, keys...)
+}
+
+func (b *tieredBackend) Batch(ctx context.Context, ops []cacheOp) error {
+	bb, ok := b.remote.(batchBackend)
+	if !ok {
+		return applyOps(ctx, b, ops)
+	}
+	if err := bb.Batch(ctx, ops); err != nil {
+		return err
+	}
+	written := []string{}
+	for _, op := range ops {
+		if op.delete {
+			_ = b.local.Delete(ctx, op.key)
+			continue
+		}
+		localTTL := b.localTTL
+		if op.ttl > 0 && op.ttl < localTTL {
+			localTTL = op.ttl
+		}
+		_ = b.local.Set(ctx, op.key, op.value, localTTL)
+		written = append(written, op.key)
+	}
+	return b.bus.publish(ctx, written...)
+}
+
+func (b *tieredBackend) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	if pb, ok := b.local.(prefixBackend); ok {
+		_, _ = pb.DeleteByPrefix(ctx, prefix)
+	}
+	pb, ok := b.remote.(prefixBackend)
+	if !ok {
+		return 0, nil
+	}
+	return pb.DeleteByPrefix(ctx, prefix)
+}
+
+func (b *tieredBackend) AddTags(ctx context.Context, key string, tagKeys []string, ttl time.Duration) error {
+	if tb, ok := b.remote.(tagBackend); ok {
+		return tb.AddTags(ctx, key, tagKeys, ttl)
+	}
+	return nil
+}
+
+func (b *tieredBackend) InvalidateTag(ctx context.Context, tagKey string) ([]string, error) {
+	tb, ok := b.remote.(tagBackend)
+	if !ok {
+		return nil, nil
+	}
+	keys, err := tb.InvalidateTag(ctx, tagKey)
+	for _, key := range keys {
+		_ = b.local.Delete(ctx, key)
+	}
+	return keys, err
+}
+
+func (b *tieredBackend) Close() error {
+	_ = b.local.Close()
+	return b.remote.Close()
+}
+
+func (b *tieredBackend) Ping(ctx context.Context) error {
+	if p, ok := b.remote.(cachePinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheTraceKey 為 context 中存放 CacheTrace 的 key
+type cacheTraceKey struct{}
+
+// CacheTrace collects the outcome of every cache lookup made while serving a
+// single request, so the HTTP layer can derive Cache-Control, Age and X-Cache
+// headers from the entries actually used. It is safe for concurrent use by
+// resolvers running in parallel.
+type CacheTrace struct {
+	mu          sync.Mutex
+	lookups     int
+	misses      int
+	stale       bool
+	oldest      time.Time     // 命中的 entry 中最早的寫入時間
+	expiresAt   time.Time     // 命中的 entry 中最早的 soft expiry
+	missTTL     time.Duration // 未命中時新寫入 entry 的 TTL
+	staleWindow time.Duration
+}
+
+// WithCacheTrace returns a context that records cache lookups into the
+// returned trace.
+func WithCacheTrace(ctx context.Context) (context.Context, *CacheTrace) {
+	trace := &CacheTrace{}
+	return context.WithValue(ctx, cacheTraceKey{}, trace), trace
+}
+
+// Lookups returns the number of keys looked up.
+func (t *CacheTrace) Lookups() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lookups
+}
+
+// Hit reports whether every lookup was answered from cache.
+func (t *CacheTrace) Hit() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lookups > 0 && t.misses == 0
+}
+
+// Stale reports whether any entry was served past its TTL (stale-while-revalidate).
+func (t *CacheTrace) Stale() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stale
+}
+
+// Age returns how long ago the oldest entry used was stored, or 0 when no
+// lookup hit.
+func (t *CacheTrace) Age(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.oldest.IsZero() || now.Before(t.oldest) {
+		return 0
+	}
+	return now.Sub(t.oldest)
+}
+
+// MaxAge returns how long the response stays fresh: the shortest remaining
+// TTL among the entries used, or the TTL of newly cached entries on a miss.
+func (t *CacheTrace) MaxAge(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	maxAge := t.missTTL
+	if !t.expiresAt.IsZero() {
+		remaining := t.expiresAt.Sub(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		if t.misses == 0 || remaining < maxAge {
+			maxAge = remaining
+		}
+	}
+	return maxAge
+}
+
+// StaleWindow returns the stale-while-revalidate window of the cache, or 0
+// when it is disabled.
+func (t *CacheTrace) StaleWindow() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.staleWindow
+}
+
+// traceHit 將命中的 entry 記錄到 ctx 中的 CacheTrace (若有)
+func (c *Cache) traceHit(ctx context.Context, entry cacheEntry) {
+	t, ok := ctx.Value(cacheTraceKey{}).(*CacheTrace)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lookups++
+	t.staleWindow = c.staleTTL
+	if entry.stale(time.Now()) {
+		t.stale = true
+	}
+	if t.oldest.IsZero() || entry.storedAt.Before(t.oldest) {
+		t.oldest = entry.storedAt
+	}
+	if !entry.softExpiresAt.IsZero() && (t.expiresAt.IsZero() || entry.softExpiresAt.Before(t.expiresAt)) {
+		t.expiresAt = entry.softExpiresAt
+	}
+}
+
+// traceMiss 將 n 個未命中的 key 記錄到 ctx 中的 CacheTrace (若有)
+func (c *Cache) traceMiss(ctx context.Context, n int) {
+	t, ok := ctx.Value(cacheTraceKey{}).(*CacheTrace)
+	if !ok || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lookups += n
+	t.misses += n
+	t.staleWindow = c.staleTTL
+	if t.missTTL == 0 || c.ttl < t.missTTL {
+		t.missTTL = c.ttl
+	}
+}
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TrendingWindow is a time window of the trending and most-read rankings.
+type TrendingWindow string
+
+// Trending windows.
+const (
+	TrendingWindowHour TrendingWindow = "1h"
+	TrendingWindowDay  TrendingWindow = "24h"
+	TrendingWindowWeek TrendingWindow = "7d"
+)
+
+// trendingBucket 描述一種計數 bucket：1h 以 5 分鐘為單位，24h 與 7d 以 1 小時為單位
+type trendingBucket struct {
+	name string // key 中的 bucket 種類
+	size time.Duration
+	ttl  time.Duration
+}
+
+var (
+	trendingMinuteBuckets = trendingBucket{name: "m", size: 5 * time.Minute, ttl: 2 * time.Hour}
+	trendingHourBuckets   = trendingBucket{name: "h", size: time.Hour, ttl: 8 * 24 * time.Hour}
+)
+
+// trendingWindowSpec 為 window 的長度與使用的 bucket
+var trendingWindowSpec = map[TrendingWindow]struct {
+	length  time.Duration
+	buckets trendingBucket
+}{
+	TrendingWindowHour: {time.Hour, trendingMinuteBuckets},
+	TrendingWindowDay:  {24 * time.Hour, trendingHourBuckets},
+	TrendingWindowWeek: {7 * 24 * time.Hour, trendingHourBuckets},
+}
+
+// trendingHalfLifeRatio 為熱門度分數的半衰期占 window 的比例：24h 的 window 中，
+// 6 小時前的瀏覽只算一半
+const trendingHalfLifeRatio = 0.25
+
+// Valid reports whether w is one of the trending windows.
+func (w TrendingWindow) Valid() bool {
+	_, ok := trendingWindowSpec[w]
+	return ok
+}
+
+// ScoredMember is a member of a ranking with its score.
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// trendingBackend is implemented by backends that can rank members across
+// several sorted sets with a weight per set.
+type trendingBackend interface {
+	// TopWeighted returns up to n members with the highest sum of their
+	// scores across keys, each multiplied by the weight at the same index.
+	TopWeighted(ctx context.Context, keys []string, weights []float64, n int) ([]ScoredMember, error)
+}
+
+// RecordTrending counts one view of member (e.g. a story ID) in the named
+// trending ranking. It does nothing when Redis is unavailable.
+func (c *Cache) RecordTrending(ctx context.Context, name, member string) error {
+	pb, ok := c.active().(popularityBackend)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	now := time.Now()
+	for _, buckets := range []trendingBucket{trendingMinuteBuckets, trendingHourBuckets} {
+		if err := pb.IncrScore(ctx, c.trendingKey(name, buckets, now), member, buckets.ttl); err != nil {
+			c.logger.Debug("record trending failed", "name", name, "member", member, "error", err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// TopTrending returns up to n members of the named trending ranking with
+// the most views in window, highest score first. With decay, views lose half
+// their weight every quarter of the window, so recent views rank higher;
+// without it scores are plain view counts.
+func (c *Cache) TopTrending(ctx context.Context, name string, window TrendingWindow, n int, decay bool) ([]ScoredMember, error) {
+	spec, ok := trendingWindowSpec[window]
+	if !ok {
+		return nil, fmt.Errorf("unknown trending window %q", window)
+	}
+	tb, ok := c.active().(trendingBackend)
+	if !ok || n <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	count := int(spec.length / spec.buckets.size)
+	keys := make([]string, count)
+	weights := make([]float64, count)
+	halfLife := float64(spec.length) * trendingHalfLifeRatio
+	for i := range keys {
+		start := now.Add(-time.Duration(i) * spec.buckets.size)
+		keys[i] = c.trendingKey(name, spec.buckets, start)
+		weights[i] = 1
+		if decay {
+			// 以 bucket 中點距今的時間計算衰減
+			age := float64(time.Duration(i)*spec.buckets.size + spec.buckets.size/2)
+			weights[i] = math.Pow(0.5, age/halfLife)
+		}
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return tb.TopWeighted(ctx, keys, weights, n)
+}
+
+// MarkSeen records key for ttl and reports whether it was not recorded yet,
+// e.g. to count a view once per visitor. When Redis is unavailable keys are
+// only remembered by this process; a nil or disabled Cache reports every key
+// as new.
+func (c *Cache) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	lb, ok := c.active().(lockBackend)
+	if !ok {
+		return true, nil
+	}
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return lb.AcquireLock(ctx, c.fullKey("seen:"+key), "1", ttl)
+}
+
+// trendingKey 回傳 t 所在 bucket 的 sorted set key；以 hash tag 讓同名的 bucket 落在同一個 cluster slot
+func (c *Cache) trendingKey(name string, buckets trendingBucket, t time.Time) string {
+	index := t.Unix() / int64(buckets.size/time.Second)
+	return c.fullKey(fmt.Sprintf("trending:{%s}:%s:%d", name, buckets.name, index))
+}
+
+// visitorHash 回傳訪客識別碼的 hash；訪客識別碼不以原文保存
+func visitorHash(visitor string) string {
+	return fmt.Sprintf("%016x", xxhash.Sum64String(visitor))
+}
+
+func (b *redisBackend) TopWeighted(ctx context.Context, keys []string, weights []float64, n int) ([]ScoredMember, error) {
+	scores, err := b.client.ZUnionWithScores(ctx, redis.ZStore{Keys: keys, Weights: weights}).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	if len(scores) > n {
+		scores = scores[:n]
+	}
+	members := make([]ScoredMember, 0, len(scores))
+	for _, z := range scores {
+		if member, ok := z.Member.(string); ok {
+			members = append(members, ScoredMember{Member: member, Score: z.Score})
+		}
+	}
+	return members, nil
+}
+
+func (b *tieredBackend) TopWeighted(ctx context.Context, keys []string, weights []float64, n int) ([]ScoredMember, error) {
+	if tb, ok := b.remote.(trendingBackend); ok {
+		return tb.TopWeighted(ctx, keys, weights, n)
+	}
+	return nil, nil
+}
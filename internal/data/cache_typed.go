@@ -0,0 +1,63 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// TypedCache is a type-safe view over Cache for values of type T, so callers
+// get compile-time checks instead of passing interface{} destinations.
+// A TypedCache over a nil Cache behaves like a disabled cache.
+type TypedCache[T any] struct {
+	cache *Cache
+}
+
+// NewTypedCache returns a TypedCache storing T values in c.
+func NewTypedCache[T any](c *Cache) *TypedCache[T] {
+	return &TypedCache[T]{cache: c}
+}
+
+// Get returns the cached value for key and whether it was found.
+// For a not-found marker it returns the zero value, true and ErrCachedNotFound.
+func (t *TypedCache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var v T
+	if t.cache == nil {
+		return v, false, nil
+	}
+	found, err := t.cache.Get(ctx, key, &v)
+	return v, found, err
+}
+
+// Set stores v under key using the configured TTL.
+func (t *TypedCache[T]) Set(ctx context.Context, key string, v T) error {
+	if t.cache == nil {
+		return nil
+	}
+	return t.cache.Set(ctx, key, v)
+}
+
+// SetWithTTL stores v under key with its own TTL; ttl <= 0 uses the default.
+func (t *TypedCache[T]) SetWithTTL(ctx context.Context, key string, v T, ttl time.Duration) error {
+	if t.cache == nil {
+		return nil
+	}
+	return t.cache.SetWithTTL(ctx, key, v, ttl)
+}
+
+// Delete removes key.
+func (t *TypedCache[T]) Delete(ctx context.Context, key string) error {
+	if t.cache == nil {
+		return nil
+	}
+	return t.cache.Delete(ctx, key)
+}
+
+// GetOrSet returns the cached value for key, calling loader on a miss and
+// caching its result for ttl. See Cache.GetOrSet.
+func (t *TypedCache[T]) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	err := t.cache.GetOrSet(ctx, key, &v, ttl, func(ctx context.Context) (interface{}, error) {
+		return loader(ctx)
+	})
+	return v, err
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// popularPostsList 為記錄文章 slug 熱門度的名稱
+const popularPostsList = "posts"
+
+// warmConcurrency 為預熱時同時查詢 DB 的上限
+const warmConcurrency = 4
+
+// warmLockName 為預熱時使用的鎖，讓多個 instance 同時啟動時只有一個去查 DB
+const warmLockName = "cache-warm"
+
+// warmLockTTL 為預熱鎖的最長持有時間
+const warmLockTTL = 5 * time.Minute
+
+// CacheWarmer pre-populates the cache with story detail entries so that a
+// fresh deploy does not hit the database for every popular story at once.
+// Stories come from a fixed slug list plus the top-N most requested slugs
+// recorded with popularity tracking.
+type CacheWarmer struct {
+	repo  *Repo
+	slugs []string
+	topN  int
+}
+
+// NewCacheWarmer creates a warmer for the given slugs and the topN most
+// requested stories.
+func NewCacheWarmer(repo *Repo, slugs []string, topN int) *CacheWarmer {
+	return &CacheWarmer{repo: repo, slugs: slugs, topN: topN}
+}
+
+// Warm loads every target story through the repository, filling cache misses.
+// Only one instance warms at a time; the others skip the round.
+// It returns the number of stories loaded; individual failures are logged.
+func (w *CacheWarmer) Warm(ctx context.Context) int {
+	cache := w.repo.cache
+	if cache == nil || !cache.Enabled() {
+		return 0
+	}
+	lock, err := cache.Lock(ctx, warmLockName, warmLockTTL)
+	if err != nil {
+		cache.logger.Info("cache warm skipped", "reason", err)
+		return 0
+	}
+	defer func() { _ = lock.Unlock(context.WithoutCancel(ctx)) }()
+
+	slugs := w.targets(ctx)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		loaded int
+	)
+	sem := make(chan struct{}, warmConcurrency)
+	for _, slug := range slugs {
+		slug := slug
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			post, err := w.repo.QueryPostByUnique(ctx, &PostWhereUniqueInput{Slug: &slug})
+			if err != nil {
+				cache.logger.Warn("cache warm failed", "slug", slug, "error", err)
+				return
+			}
+			if post != nil {
+				mu.Lock()
+				loaded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	cache.logger.Info("cache warmed", "stories", loaded, "targets", len(slugs))
+	return loaded
+}
+
+// Run warms the cache immediately and then every interval until ctx is done.
+// An interval <= 0 warms only once.
+func (w *CacheWarmer) Run(ctx context.Context, interval time.Duration) {
+	w.Warm(ctx)
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Warm(ctx)
+		}
+	}
+}
+
+// targets 合併固定清單與熱門清單並去除重複
+func (w *CacheWarmer) targets(ctx context.Context) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	add := func(slugs []string) {
+		for _, slug := range slugs {
+			if slug != "" && !seen[slug] {
+				seen[slug] = true
+				result = append(result, slug)
+			}
+		}
+	}
+
+	add(w.slugs)
+	if w.topN > 0 {
+		popular, err := w.repo.cache.TopPopular(ctx, popularPostsList, w.topN)
+		if err != nil {
+			w.repo.cache.logger.Warn("load popular stories failed", "error", err)
+		}
+		add(popular)
+	}
+	return result
+}
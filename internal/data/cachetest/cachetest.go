@@ -0,0 +1,122 @@
+// Package cachetest provides a data.Cache for tests together with helpers to
+// assert hits and misses and to inspect stored values.
+package cachetest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go-story/internal/data"
+)
+
+// defaultTTLSeconds 為測試用 cache 的預設 TTL
+const defaultTTLSeconds = 60
+
+// memoryEntries 為 NewMemory 的 LRU 筆數上限，測試中不應發生淘汰
+const memoryEntries = 100000
+
+// Harness wraps a Cache under test. Hit and miss counts are relative to the
+// last call to ResetCounts (or to creation).
+type Harness struct {
+	Cache *data.Cache
+
+	tb       testing.TB
+	baseline data.CacheStats
+}
+
+// NewMemory returns a harness whose cache stores entries in process memory.
+// It needs no Redis, but features that only Redis provides (rate limiting,
+// popularity tracking, pub/sub invalidation) are not available; use New
+// for those.
+func NewMemory(tb testing.TB, opts ...data.CacheOption) *Harness {
+	tb.Helper()
+	cache := data.NewCacheWithBackend(data.NewMemoryBackend(memoryEntries), defaultTTLSeconds, "test", opts...)
+	return newHarness(tb, cache)
+}
+
+// newHarness 建立 Harness 並在測試結束時關閉 cache
+func newHarness(tb testing.TB, cache *data.Cache) *Harness {
+	tb.Cleanup(func() { _ = cache.Close() })
+	return &Harness{Cache: cache, tb: tb}
+}
+
+// ResetCounts starts counting hits and misses from zero.
+func (h *Harness) ResetCounts() {
+	h.baseline = h.Cache.Stats(context.Background())
+}
+
+// Hits returns the number of cache hits since the last ResetCounts.
+func (h *Harness) Hits() int64 {
+	return h.Cache.Stats(context.Background()).Hits - h.baseline.Hits
+}
+
+// Misses returns the number of cache misses since the last ResetCounts.
+func (h *Harness) Misses() int64 {
+	return h.Cache.Stats(context.Background()).Misses - h.baseline.Misses
+}
+
+// AssertHits fails the test unless exactly want hits happened since the last
+// ResetCounts.
+func (h *Harness) AssertHits(want int64) {
+	h.tb.Helper()
+	if got := h.Hits(); got != want {
+		h.tb.Errorf("cache hits = %d, want %d", got, want)
+	}
+}
+
+// AssertMisses fails the test unless exactly want misses happened since the
+// last ResetCounts.
+func (h *Harness) AssertMisses(want int64) {
+	h.tb.Helper()
+	if got := h.Misses(); got != want {
+		h.tb.Errorf("cache misses = %d, want %d", got, want)
+	}
+}
+
+// Inspect returns the stored entry for key (see data.Cache.Inspect).
+func (h *Harness) Inspect(key string) data.CacheKeyInfo {
+	h.tb.Helper()
+	info, err := h.Cache.Inspect(context.Background(), key)
+	if err != nil {
+		h.tb.Fatalf("inspect %q: %v", key, err)
+	}
+	return info
+}
+
+// AssertStored fails the test unless key is in the cache.
+func (h *Harness) AssertStored(key string) {
+	h.tb.Helper()
+	if !h.Inspect(key).Found {
+		h.tb.Errorf("cache key %q not stored", key)
+	}
+}
+
+// AssertNotStored fails the test if key is in the cache.
+func (h *Harness) AssertNotStored(key string) {
+	h.tb.Helper()
+	if h.Inspect(key).Found {
+		h.tb.Errorf("cache key %q unexpectedly stored", key)
+	}
+}
+
+// Value decodes the value stored under key into dest without counting a hit
+// or miss, failing the test when the key is missing.
+func (h *Harness) Value(key string, dest interface{}) {
+	h.tb.Helper()
+	info := h.Inspect(key)
+	if !info.Found {
+		h.tb.Fatalf("cache key %q not stored", key)
+	}
+	if info.DecodeError != "" {
+		h.tb.Fatalf("decode %q: %s", key, info.DecodeError)
+	}
+	// Inspect 解碼成泛型的值，再經 JSON 轉成 dest 的型別
+	raw, err := json.Marshal(info.Value)
+	if err != nil {
+		h.tb.Fatalf("decode %q: %v", key, err)
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		h.tb.Fatalf("decode %q: %v", key, err)
+	}
+}
@@ -0,0 +1,23 @@
+package cachetest
+
+import (
+	"testing"
+
+	"go-story/internal/data"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// New returns a harness whose cache talks to an in-process miniredis server,
+// so Redis-only features (tags, locks, rate limiting, pub/sub invalidation)
+// behave as in production. The server is returned to fast-forward time
+// (FastForward) or inspect keys directly; it is stopped when the test ends.
+func New(tb testing.TB, opts ...data.CacheOption) (*Harness, *miniredis.Miniredis) {
+	tb.Helper()
+	server := miniredis.RunT(tb)
+	cache, err := data.NewCache("redis://"+server.Addr(), true, defaultTTLSeconds, "test", opts...)
+	if err != nil {
+		tb.Fatalf("cachetest: connect to miniredis: %v", err)
+	}
+	return newHarness(tb, cache), server
+}
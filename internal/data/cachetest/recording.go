@@ -0,0 +1,171 @@
+package cachetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// Operations recorded by RecordingCache.
+const (
+	OpGet    = "get"
+	OpSet    = "set"
+	OpDelete = "delete"
+)
+
+// Call is a single backend call captured by RecordingCache.
+type Call struct {
+	Op    string
+	Key   string        // backend key，含 namespace
+	Value []byte        // Set 寫入或 Get 讀到的原始內容 (含 entry header)
+	TTL   time.Duration // Set 的 TTL
+	Hit   bool          // Get 是否讀到資料
+}
+
+// NoopCache is a data.CacheBackend that stores nothing: every Get misses and
+// writes are discarded. Use it to exercise the uncached code path while the
+// cache stays enabled.
+type NoopCache struct{}
+
+func (NoopCache) Get(context.Context, string) ([]byte, error) {
+	return nil, data.ErrCacheMiss
+}
+
+func (NoopCache) Set(context.Context, string, []byte, time.Duration) error {
+	return nil
+}
+
+func (NoopCache) Delete(context.Context, string) error {
+	return nil
+}
+
+func (NoopCache) Close() error {
+	return nil
+}
+
+// RecordingCache is a data.CacheBackend that records every Get, Set and
+// Delete before passing it to the wrapped backend, so tests can assert
+// exactly what was cached and under which key. It is safe for concurrent use.
+type RecordingCache struct {
+	next data.CacheBackend
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewRecordingCache wraps next; a nil next records calls on top of an
+// in-memory backend.
+func NewRecordingCache(next data.CacheBackend) *RecordingCache {
+	if next == nil {
+		next = data.NewMemoryBackend(memoryEntries)
+	}
+	return &RecordingCache{next: next}
+}
+
+// NewRecording returns a harness whose cache stores entries in memory through
+// a RecordingCache.
+func NewRecording(tb testing.TB, opts ...data.CacheOption) (*Harness, *RecordingCache) {
+	tb.Helper()
+	recorder := NewRecordingCache(nil)
+	cache := data.NewCacheWithBackend(recorder, defaultTTLSeconds, "test", opts...)
+	return newHarness(tb, cache), recorder
+}
+
+// record 記錄一次呼叫
+func (r *RecordingCache) record(call Call) {
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+}
+
+func (r *RecordingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := r.next.Get(ctx, key)
+	r.record(Call{Op: OpGet, Key: key, Value: val, Hit: err == nil})
+	return val, err
+}
+
+func (r *RecordingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.record(Call{Op: OpSet, Key: key, Value: value, TTL: ttl})
+	return r.next.Set(ctx, key, value, ttl)
+}
+
+func (r *RecordingCache) Delete(ctx context.Context, key string) error {
+	r.record(Call{Op: OpDelete, Key: key})
+	return r.next.Delete(ctx, key)
+}
+
+func (r *RecordingCache) Close() error {
+	return r.next.Close()
+}
+
+// Calls returns the recorded calls in order. With ops it returns only calls
+// of those operations.
+func (r *RecordingCache) Calls(ops ...string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := []Call{}
+	for _, call := range r.calls {
+		if len(ops) == 0 || containsOp(ops, call.Op) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Keys returns the keys of the recorded calls of operation op, in order.
+func (r *RecordingCache) Keys(op string) []string {
+	keys := []string{}
+	for _, call := range r.Calls(op) {
+		keys = append(keys, call.Key)
+	}
+	return keys
+}
+
+// LastSet returns the most recent Set of key.
+func (r *RecordingCache) LastSet(key string) (Call, bool) {
+	calls := r.Calls(OpSet)
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Key == key {
+			return calls[i], true
+		}
+	}
+	return Call{}, false
+}
+
+// Reset discards the recorded calls; stored values are kept.
+func (r *RecordingCache) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
+
+// containsOp 判斷 ops 是否包含 op
+func containsOp(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertSet fails the test unless key (the backend key, including any
+// namespace) was written, and decodes the last written value into dest
+// (when non-nil).
+func (h *Harness) AssertSet(recorder *RecordingCache, key string, dest interface{}) {
+	h.tb.Helper()
+	call, ok := recorder.LastSet(key)
+	if !ok {
+		h.tb.Fatalf("cache key %q was not set; set keys: %v", key, recorder.Keys(OpSet))
+	}
+	if dest == nil {
+		return
+	}
+	if err := h.Cache.DecodeRaw(call.Key, call.Value, dest); err != nil {
+		h.tb.Fatalf("decode %q: %v", key, err)
+	}
+}
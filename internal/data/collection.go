@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrCollectionNotFound is returned when no collection matches the
+	// lookup.
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionSlugTaken is returned by CreateCollection and
+	// UpdateCollection when another collection already uses the slug.
+	ErrCollectionSlugTaken = errors.New("collection slug already taken")
+	// ErrInvalidCollection is returned (wrapped) by CreateCollection and
+	// UpdateCollection for a collection without a slug or title, or with
+	// too many or repeated stories.
+	ErrInvalidCollection = errors.New("invalid collection")
+	// ErrCollectionsUnsupported is returned when the story store does not
+	// store collections.
+	ErrCollectionsUnsupported = errors.New("story store does not support collections")
+)
+
+// MaxCollectionStories is the largest number of stories a collection can
+// hold.
+const MaxCollectionStories = 200
+
+// Collection is an ordered list of stories, e.g. the parts of a multi-part
+// investigative series. StoryIDs holds the stories in reading order; a
+// story can belong to several collections.
+type Collection struct {
+	ID         string    `json:"id"`
+	Slug       string    `json:"slug"`
+	Title      string    `json:"title"`
+	Intro      string    `json:"intro"`
+	CoverImage string    `json:"coverImage"`
+	StoryIDs   []string  `json:"storyIds"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// CollectionStore is implemented by story repositories that also store
+// collections. Callers type-assert a StoryRepository to it. Collections
+// list stories in any status; StoryService only exposes the published ones.
+type CollectionStore interface {
+	// GetCollectionByID returns the collection with id, or
+	// ErrCollectionNotFound.
+	GetCollectionByID(ctx context.Context, id string) (*Collection, error)
+	// GetCollectionBySlug returns the collection with slug, or
+	// ErrCollectionNotFound.
+	GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error)
+	// ListCollections returns collections, most recently updated first.
+	ListCollections(ctx context.Context, limit, offset int) ([]Collection, error)
+	// CollectionsByStory returns the collections containing the story with
+	// storyID, ordered by title.
+	CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error)
+	// CreateCollection stores a new collection, filling in ID (when empty)
+	// and timestamps, or returns ErrCollectionSlugTaken, or
+	// ErrStoryNotFound for a story ID that does not exist.
+	CreateCollection(ctx context.Context, collection *Collection) error
+	// UpdateCollection replaces the collection with collection.ID,
+	// including its stories, and refreshes UpdatedAt.
+	UpdateCollection(ctx context.Context, collection *Collection) error
+	// DeleteCollection removes the collection with id; its stories are
+	// kept.
+	DeleteCollection(ctx context.Context, id string) error
+}
+
+// defaultCollectionLimit 與 maxCollectionLimit 為 ListCollections 每頁筆數的預設值與上限
+const (
+	defaultCollectionLimit = 20
+	maxCollectionLimit     = 100
+)
+
+// collectionLimit 回傳套用預設值與上限後的筆數；儲存層允許多取一筆，供 StoryService 判斷是否有下一頁
+func collectionLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultCollectionLimit
+	case limit > maxCollectionLimit+1:
+		return maxCollectionLimit + 1
+	}
+	return limit
+}
+
+// prepareCollection 在寫入前檢查欄位，並補上 ID 與時間欄位；story 是否存在由儲存層檢查
+func prepareCollection(collection *Collection, now time.Time) error {
+	switch {
+	case collection.Slug == "" || collection.Title == "":
+		return fmt.Errorf("%w: slug and title are required", ErrInvalidCollection)
+	case len(collection.StoryIDs) > MaxCollectionStories:
+		return fmt.Errorf("%w: at most %d stories", ErrInvalidCollection, MaxCollectionStories)
+	}
+	seen := make(map[string]bool, len(collection.StoryIDs))
+	for _, id := range collection.StoryIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: story %s is listed twice", ErrInvalidCollection, id)
+		}
+		seen[id] = true
+	}
+	if collection.ID == "" {
+		collection.ID = newUUID()
+	}
+	if collection.StoryIDs == nil {
+		collection.StoryIDs = []string{}
+	}
+	if collection.CreatedAt.IsZero() {
+		collection.CreatedAt = now
+	}
+	collection.UpdatedAt = now
+	return nil
+}
+
+// SeriesStory is one part of a series in StorySeries.
+type SeriesStory struct {
+	Part  int    `json:"part"`
+	ID    string `json:"id"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+// StorySeries places a story within one of its collections: it is part
+// Part of Total, between Previous and Next (nil at either end). Parts
+// lists every part in order. Only published stories are counted, and
+// Collection.StoryIDs lists only those.
+type StorySeries struct {
+	Collection Collection    `json:"collection"`
+	Part       int           `json:"part"`
+	Total      int           `json:"total"`
+	Previous   *SeriesStory  `json:"previous"`
+	Next       *SeriesStory  `json:"next"`
+	Parts      []SeriesStory `json:"parts"`
+}
+
+// newStorySeries 依 stories (已依閱讀順序排列、只含已發布的 story) 產生 storyID 在 collection 中的位置；不在其中時回傳 nil
+func newStorySeries(collection Collection, stories []Story, storyID string) *StorySeries {
+	series := &StorySeries{Collection: collection, Total: len(stories), Parts: make([]SeriesStory, len(stories))}
+	series.Collection.StoryIDs = make([]string, len(stories))
+	for i, story := range stories {
+		series.Parts[i] = SeriesStory{Part: i + 1, ID: story.ID, Slug: story.Slug, Title: story.Title}
+		series.Collection.StoryIDs[i] = story.ID
+		if story.ID == storyID {
+			series.Part = i + 1
+		}
+	}
+	if series.Part == 0 {
+		return nil
+	}
+	if series.Part > 1 {
+		series.Previous = &series.Parts[series.Part-2]
+	}
+	if series.Part < series.Total {
+		series.Next = &series.Parts[series.Part]
+	}
+	return series
+}
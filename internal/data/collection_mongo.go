@@ -0,0 +1,187 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionDocument 為合集在 MongoDB 中的格式，storyIds 依閱讀順序排列
+type collectionDocument struct {
+	ID         string    `bson:"_id"`
+	Slug       string    `bson:"slug"`
+	Title      string    `bson:"title"`
+	Intro      string    `bson:"intro"`
+	CoverImage string    `bson:"coverImage"`
+	StoryIDs   []string  `bson:"storyIds"`
+	CreatedAt  time.Time `bson:"createdAt"`
+	UpdatedAt  time.Time `bson:"updatedAt"`
+}
+
+// collection 將 document 轉回 Collection
+func (d collectionDocument) collection() Collection {
+	storyIDs := d.StoryIDs
+	if storyIDs == nil {
+		storyIDs = []string{}
+	}
+	return Collection{
+		ID: d.ID, Slug: d.Slug, Title: d.Title, Intro: d.Intro, CoverImage: d.CoverImage, StoryIDs: storyIDs,
+		CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt,
+	}
+}
+
+func (r *MongoStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	return r.getCollection(ctx, bson.M{"_id": id})
+}
+
+func (r *MongoStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	return r.getCollection(ctx, bson.M{"slug": slug})
+}
+
+// getCollection 依條件查詢一個合集
+func (r *MongoStoryRepository) getCollection(ctx context.Context, filter bson.M) (*Collection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc collectionDocument
+	err := r.collections.FindOne(r.ctx(ctx), filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get collection: %w", err)
+	}
+	collection := doc.collection()
+	return &collection, nil
+}
+
+func (r *MongoStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	findOpts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(collectionLimit(limit))).SetSkip(int64(max(offset, 0)))
+	return r.findCollections(ctx, "list collections", bson.M{}, findOpts)
+}
+
+func (r *MongoStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	findOpts := options.Find().SetSort(bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}})
+	return r.findCollections(ctx, "get story collections", bson.M{"storyIds": storyID}, findOpts)
+}
+
+// findCollections 執行回傳多個合集的查詢
+func (r *MongoStoryRepository) findCollections(ctx context.Context, op string, filter bson.M, findOpts *options.FindOptions) ([]Collection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collections.Find(r.ctx(ctx), filter, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	var docs []collectionDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	collections := make([]Collection, 0, len(docs))
+	for _, doc := range docs {
+		collections = append(collections, doc.collection())
+	}
+	return collections, nil
+}
+
+func (r *MongoStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := prepareCollection(collection, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := r.checkCollectionStories(ctx, collection.StoryIDs); err != nil {
+		return err
+	}
+	_, err := r.collections.InsertOne(r.ctx(ctx), collectionDocument{
+		ID: collection.ID, Slug: collection.Slug, Title: collection.Title, Intro: collection.Intro, CoverImage: collection.CoverImage,
+		StoryIDs: collection.StoryIDs, CreatedAt: collection.CreatedAt, UpdatedAt: collection.UpdatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrCollectionSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("create collection: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if collection.ID == "" {
+		return ErrCollectionNotFound
+	}
+	if err := prepareCollection(collection, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := r.checkCollectionStories(ctx, collection.StoryIDs); err != nil {
+		return err
+	}
+	// createdAt 不更新，回傳資料庫中的值
+	update := bson.M{"$set": bson.M{
+		"slug": collection.Slug, "title": collection.Title, "intro": collection.Intro, "coverImage": collection.CoverImage,
+		"storyIds": collection.StoryIDs, "updatedAt": collection.UpdatedAt,
+	}}
+	var stored collectionDocument
+	err := r.collections.FindOneAndUpdate(r.ctx(ctx), bson.M{"_id": collection.ID}, update).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrCollectionNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrCollectionSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("update collection: %w", err)
+	}
+	collection.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+func (r *MongoStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.collections.DeleteOne(r.ctx(ctx), bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// checkCollectionStories 確認 storyIDs 中的 story 都存在 (含垃圾桶)；檢查與寫入不是原子操作，story 永久刪除時由 PurgeStory 自合集移除
+func (r *MongoStoryRepository) checkCollectionStories(ctx context.Context, storyIDs []string) error {
+	if len(storyIDs) == 0 {
+		return nil
+	}
+	cursor, err := r.coll.Find(r.ctx(ctx), bson.M{"_id": bson.M{"$in": storyIDs}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return fmt.Errorf("check collection stories: %w", err)
+	}
+	var docs []storyDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return fmt.Errorf("check collection stories: %w", err)
+	}
+	found := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		found[doc.ID] = true
+	}
+	for _, id := range storyIDs {
+		if !found[id] {
+			return fmt.Errorf("%w: %s", ErrStoryNotFound, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// collectionSelectColumns 為查詢 collections 時的欄位順序，需與 scanCollection 一致；最後一欄為依閱讀順序排列的 story ID
+const collectionSelectColumns = `id, slug, title, intro, cover_image, created_at, updated_at,
+	COALESCE((SELECT jsonb_agg(cs.story_id ORDER BY cs.position) FROM collection_stories cs WHERE cs.collection_id = collections.id), '[]'::jsonb)`
+
+func (r *PostgresStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	return r.getCollection(ctx, "id", id)
+}
+
+func (r *PostgresStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	return r.getCollection(ctx, "slug", slug)
+}
+
+// getCollection 依單一欄位查詢一個合集
+func (r *PostgresStoryRepository) getCollection(ctx context.Context, column, value string) (*Collection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	collection, err := scanCollection(r.q.QueryRowContext(ctx, `SELECT `+collectionSelectColumns+` FROM collections WHERE `+column+` = $1`, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get collection by %s: %w", column, err)
+	}
+	return collection, nil
+}
+
+func (r *PostgresStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	return r.queryCollections(ctx, "list collections",
+		`SELECT `+collectionSelectColumns+` FROM collections ORDER BY updated_at DESC, id DESC LIMIT $1 OFFSET $2`, collectionLimit(limit), max(offset, 0))
+}
+
+func (r *PostgresStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	return r.queryCollections(ctx, "get story collections",
+		`SELECT `+collectionSelectColumns+` FROM collections WHERE id IN (SELECT collection_id FROM collection_stories WHERE story_id = $1) ORDER BY title, id`, storyID)
+}
+
+// queryCollections 執行回傳多個合集的查詢
+func (r *PostgresStoryRepository) queryCollections(ctx context.Context, op, query string, args ...interface{}) ([]Collection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	collections := []Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan collection: %w", err)
+		}
+		collections = append(collections, *collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return collections, nil
+}
+
+func (r *PostgresStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := prepareCollection(collection, time.Now().UTC()); err != nil {
+		return err
+	}
+	return r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		_, err := tx.q.ExecContext(ctx, `INSERT INTO collections (id, slug, title, intro, cover_image, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			collection.ID, collection.Slug, collection.Title, collection.Intro, collection.CoverImage, collection.CreatedAt, collection.UpdatedAt)
+		if err := collectionWriteError("create collection", err); err != nil {
+			return err
+		}
+		return tx.setCollectionStories(ctx, collection.ID, collection.StoryIDs)
+	})
+}
+
+func (r *PostgresStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if collection.ID == "" {
+		return ErrCollectionNotFound
+	}
+	if err := prepareCollection(collection, time.Now().UTC()); err != nil {
+		return err
+	}
+	return r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		// created_at 不更新，回傳資料庫中的值
+		err := tx.q.QueryRowContext(ctx, `UPDATE collections SET slug = $2, title = $3, intro = $4, cover_image = $5, updated_at = $6 WHERE id = $1 RETURNING created_at`,
+			collection.ID, collection.Slug, collection.Title, collection.Intro, collection.CoverImage, collection.UpdatedAt).Scan(&collection.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCollectionNotFound
+		}
+		if err := collectionWriteError("update collection", err); err != nil {
+			return err
+		}
+		return tx.setCollectionStories(ctx, collection.ID, collection.StoryIDs)
+	})
+}
+
+func (r *PostgresStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.q.ExecContext(ctx, `DELETE FROM collections WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	if n == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// setCollectionStories 以 storyIDs 取代合集的 story，position 依 storyIDs 的順序
+func (r *PostgresStoryRepository) setCollectionStories(ctx context.Context, collectionID string, storyIDs []string) error {
+	if _, err := r.q.ExecContext(ctx, `DELETE FROM collection_stories WHERE collection_id = $1`, collectionID); err != nil {
+		return fmt.Errorf("clear collection stories: %w", err)
+	}
+	for i, storyID := range storyIDs {
+		_, err := r.q.ExecContext(ctx, `INSERT INTO collection_stories (collection_id, story_id, position) VALUES ($1, $2, $3)`,
+			collectionID, storyID, i)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("%w: %s", ErrStoryNotFound, storyID)
+		}
+		if err != nil {
+			return fmt.Errorf("set collection stories: %w", err)
+		}
+	}
+	return nil
+}
+
+// scanCollection 依 collectionSelectColumns 的順序讀取一個合集
+func scanCollection(row rowScanner) (*Collection, error) {
+	var collection Collection
+	var storyIDs []byte
+	if err := row.Scan(&collection.ID, &collection.Slug, &collection.Title, &collection.Intro, &collection.CoverImage,
+		&collection.CreatedAt, &collection.UpdatedAt, &storyIDs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(storyIDs, &collection.StoryIDs); err != nil {
+		return nil, fmt.Errorf("decode story ids: %w", err)
+	}
+	return &collection, nil
+}
+
+// collectionWriteError 將 slug 重複轉為 ErrCollectionSlugTaken；err 為 nil 時回傳 nil
+func collectionWriteError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return ErrCollectionSlugTaken
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,438 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrCommentNotFound is returned when no comment matches the lookup.
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrInvalidComment is returned (wrapped) for a comment with an empty or
+	// too long body, a missing user or a parent it cannot reply to.
+	ErrInvalidComment = errors.New("invalid comment")
+	// ErrCommentRateLimited is returned when a user posts more comments than
+	// the configured rate allows.
+	ErrCommentRateLimited = errors.New("too many comments, try again later")
+	// ErrCommentForbidden is returned when deleting another user's comment
+	// without the editor role.
+	ErrCommentForbidden = errors.New("comment belongs to another user")
+	// ErrCommentsUnsupported is returned by a nil CommentService, i.e. when
+	// comments are disabled.
+	ErrCommentsUnsupported = errors.New("comments are not enabled")
+)
+
+// Comment moderation statuses. Only approved comments are shown to
+// readers and counted.
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusHidden   = "hidden"
+)
+
+// 留言的長度上限 (字數)
+const (
+	maxCommentLength           = 5000
+	maxCommentAuthorNameLength = 100
+	maxCommentUserIDLength     = 200
+)
+
+// commentColumns 為查詢 comments 時的欄位順序，需與 scanComment 一致
+const commentColumns = `id, story_id, COALESCE(parent_id, ''), COALESCE(root_id, ''), user_id, author_name, body, status, created_at, updated_at`
+
+// commentCountCachePrefix 為留言數的 cache key 前綴；不在 story: 前綴下，留言寫入時只清除該 story 的留言數
+const commentCountCachePrefix = "comments:count"
+
+// Comment is a reader comment on a story. Replies carry the ID of the
+// comment they answer in ParentID; listings nest every reply of a thread
+// under its top-level comment, in Replies.
+type Comment struct {
+	ID         string    `json:"id"`
+	StoryID    string    `json:"storyId"`
+	ParentID   string    `json:"parentId,omitempty"`
+	UserID     string    `json:"userId,omitempty"` // 公開的 API 不回傳，只供審核使用
+	AuthorName string    `json:"authorName"`
+	Body       string    `json:"body"` // 純文字，輸出時需跳脫
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Replies    []Comment `json:"replies,omitempty"` // 只出現在 CommentService.List 的最上層留言中，依時間排序
+	rootID     string    // 討論串最上層的留言，最上層留言為空字串
+}
+
+// CommentPage is a page of top-level comments with their replies. Pass
+// NextCursor as after to fetch the next page.
+type CommentPage struct {
+	Comments    []Comment
+	NextCursor  string
+	HasNextPage bool
+}
+
+// CommentConfig configures a CommentService.
+type CommentConfig struct {
+	// AutoApprove publishes new comments right away; otherwise they wait
+	// in the pending status for an editor.
+	AutoApprove bool
+	// RateLimit is the number of comments a user may post per RateWindow;
+	// 0 disables the limit.
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// CommentService stores threaded reader comments on stories in the
+// comments table. New comments are pending until an editor approves them,
+// unless AutoApprove is set, and each user's posting rate is limited with
+// the shared RateLimiter. The number of approved comments of a story is
+// cached until a comment of the story changes. Moderation and deletions by
+// editors are recorded in the audit log.
+type CommentService struct {
+	db      *sql.DB
+	cache   *Cache
+	limiter *RateLimiter
+	audit   *AuditLog
+	cfg     CommentConfig
+}
+
+// NewCommentService returns a service storing comments in db (see
+// internal/data/migrations) and caching counts in cache. audit may be nil.
+func NewCommentService(db *sql.DB, cache *Cache, audit *AuditLog, cfg CommentConfig) *CommentService {
+	return &CommentService{db: db, cache: cache, limiter: NewRateLimiter(cache), audit: audit, cfg: cfg}
+}
+
+// Create adds comment to the story with storyID for the user in
+// comment.UserID, replying to comment.ParentID when set. The parent must
+// be an approved comment of the same story. ID, status and times are
+// assigned here.
+func (s *CommentService) Create(ctx context.Context, storyID string, comment Comment) (*Comment, error) {
+	if s == nil {
+		return nil, ErrCommentsUnsupported
+	}
+	comment.UserID = strings.TrimSpace(comment.UserID)
+	comment.AuthorName = strings.Join(strings.Fields(comment.AuthorName), " ")
+	comment.Body = strings.TrimSpace(comment.Body)
+	if err := validateComment(comment); err != nil {
+		return nil, err
+	}
+	if s.cfg.RateLimit > 0 {
+		result, err := s.limiter.Allow(ctx, "comment:"+comment.UserID, s.cfg.RateLimit, s.cfg.RateWindow)
+		if err == nil && !result.Allowed {
+			return nil, ErrCommentRateLimited
+		}
+	}
+
+	comment.StoryID = storyID
+	if comment.ParentID != "" {
+		parent, err := s.Comment(ctx, comment.ParentID)
+		if errors.Is(err, ErrCommentNotFound) {
+			return nil, fmt.Errorf("%w: parent %s not found", ErrInvalidComment, comment.ParentID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.StoryID != storyID || parent.Status != CommentStatusApproved {
+			return nil, fmt.Errorf("%w: cannot reply to comment %s", ErrInvalidComment, comment.ParentID)
+		}
+		comment.rootID = parent.rootID
+		if comment.rootID == "" {
+			comment.rootID = parent.ID
+		}
+	}
+	comment.ID = newUUID()
+	comment.Status = CommentStatusPending
+	if s.cfg.AutoApprove {
+		comment.Status = CommentStatusApproved
+	}
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+	comment.Replies = nil
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO comments (id, story_id, parent_id, root_id, user_id, author_name, body, status, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10)`,
+		comment.ID, comment.StoryID, comment.ParentID, comment.rootID, comment.UserID, comment.AuthorName, comment.Body,
+		comment.Status, comment.CreatedAt, comment.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create comment: %w", err)
+	}
+	if comment.Status == CommentStatusApproved {
+		s.invalidateCount(ctx, storyID)
+	}
+	return &comment, nil
+}
+
+// validateComment 檢查使用者、署名與內容
+func validateComment(comment Comment) error {
+	switch {
+	case comment.UserID == "":
+		return fmt.Errorf("%w: userId is required", ErrInvalidComment)
+	case len(comment.UserID) > maxCommentUserIDLength:
+		return fmt.Errorf("%w: userId is longer than %d bytes", ErrInvalidComment, maxCommentUserIDLength)
+	case comment.Body == "":
+		return fmt.Errorf("%w: body is required", ErrInvalidComment)
+	case !utf8.ValidString(comment.Body) || !utf8.ValidString(comment.AuthorName):
+		return fmt.Errorf("%w: invalid UTF-8", ErrInvalidComment)
+	case utf8.RuneCountInString(comment.Body) > maxCommentLength:
+		return fmt.Errorf("%w: body is longer than %d characters", ErrInvalidComment, maxCommentLength)
+	case utf8.RuneCountInString(comment.AuthorName) > maxCommentAuthorNameLength:
+		return fmt.Errorf("%w: authorName is longer than %d characters", ErrInvalidComment, maxCommentAuthorNameLength)
+	}
+	return nil
+}
+
+// Comment returns the comment with id in any status, or
+// ErrCommentNotFound.
+func (s *CommentService) Comment(ctx context.Context, id string) (*Comment, error) {
+	if s == nil {
+		return nil, ErrCommentsUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	comment, err := scanComment(s.db.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get comment: %w", err)
+	}
+	return comment, nil
+}
+
+// List returns a page of the approved top-level comments of the story
+// with storyID, newest first, each with its approved replies oldest first.
+// after is the NextCursor of the previous page.
+func (s *CommentService) List(ctx context.Context, storyID string, limit int, after string) (*CommentPage, error) {
+	if s == nil {
+		return nil, ErrCommentsUnsupported
+	}
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE story_id = $1 AND parent_id IS NULL AND status = $2`
+	args := []interface{}{storyID, CommentStatusApproved}
+	if after != "" {
+		createdAt, id, err := decodeCommentCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (created_at, id) < ($3, $4)`
+		args = append(args, createdAt, id)
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT %d`, limit+1)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	roots, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &CommentPage{Comments: roots}
+	if len(roots) > limit {
+		page.Comments, page.HasNextPage = roots[:limit], true
+		last := page.Comments[limit-1]
+		page.NextCursor = encodeCommentCursor(last.CreatedAt, last.ID)
+	}
+	if len(page.Comments) == 0 {
+		return page, nil
+	}
+
+	ids := make([]string, len(page.Comments))
+	index := make(map[string]int, len(page.Comments))
+	for i, root := range page.Comments {
+		ids[i], index[root.ID] = root.ID, i
+	}
+	replies, err := s.query(ctx, `SELECT `+commentColumns+` FROM comments WHERE root_id = ANY($1) AND status = $2 ORDER BY created_at, id`,
+		ids, CommentStatusApproved)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		root := &page.Comments[index[reply.rootID]]
+		root.Replies = append(root.Replies, reply)
+	}
+	return page, nil
+}
+
+// Moderation returns comments in status (any status when empty) across
+// all stories, newest first, for editors to review.
+func (s *CommentService) Moderation(ctx context.Context, status string, limit, offset int) ([]Comment, error) {
+	if s == nil {
+		return nil, ErrCommentsUnsupported
+	}
+	if status != "" && !validCommentStatus(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidComment, status)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.query(ctx, `SELECT `+commentColumns+` FROM comments WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`,
+		status, limit, offset)
+}
+
+// SetStatus moves the comment with id to status. Only editors may
+// moderate comments.
+func (s *CommentService) SetStatus(ctx context.Context, id, status string, role StoryRole) (*Comment, error) {
+	if s == nil {
+		return nil, ErrCommentsUnsupported
+	}
+	if err := requireEditor(role, "moderate comments"); err != nil {
+		return nil, err
+	}
+	if !validCommentStatus(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidComment, status)
+	}
+	before, err := s.Comment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	after, err := scanComment(s.db.QueryRowContext(ctx, `UPDATE comments SET status = $2, updated_at = now() WHERE id = $1 RETURNING `+commentColumns,
+		id, status))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update comment: %w", err)
+	}
+	s.invalidateCount(ctx, after.StoryID)
+	s.record(ctx, AuditActionTransition, id, before, after)
+	return after, nil
+}
+
+// Delete removes the comment with id and its replies. Users may delete
+// their own comments (userID); without a user, only editors (role) may
+// delete comments.
+func (s *CommentService) Delete(ctx context.Context, id, userID string, role StoryRole) error {
+	if s == nil {
+		return ErrCommentsUnsupported
+	}
+	if userID == "" {
+		if err := requireEditor(role, "delete comments"); err != nil {
+			return err
+		}
+	}
+	comment, err := s.Comment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(StoryRoleEditor) && comment.UserID != userID {
+		return ErrCommentForbidden
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete comment: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	s.invalidateCount(ctx, comment.StoryID)
+	if role.Includes(StoryRoleEditor) {
+		s.record(ctx, AuditActionDelete, id, comment, nil)
+	}
+	return nil
+}
+
+// Count returns the number of approved comments, replies included, on the
+// story with storyID. It is cached until a comment of the story changes.
+func (s *CommentService) Count(ctx context.Context, storyID string) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+	return NewTypedCache[int](s.cache).GetOrSet(ctx, s.countKey(storyID), 0, func(ctx context.Context) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		var n int
+		if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM comments WHERE story_id = $1 AND status = $2`,
+			storyID, CommentStatusApproved).Scan(&n); err != nil {
+			return 0, fmt.Errorf("count comments: %w", err)
+		}
+		return n, nil
+	})
+}
+
+// countKey 回傳 story 留言數的 cache key
+func (s *CommentService) countKey(storyID string) string {
+	return NewCacheKey(commentCountCachePrefix).Field("story", storyID).String()
+}
+
+// invalidateCount 清除 story 的留言數；失敗時只記錄日誌，留言數在 TTL 後更新
+func (s *CommentService) invalidateCount(ctx context.Context, storyID string) {
+	if err := NewTypedCache[int](s.cache).Delete(context.WithoutCancel(ctx), s.countKey(storyID)); err != nil {
+		slog.Warn("failed to invalidate comment count", "story", storyID, "error", err)
+	}
+}
+
+// query 執行查詢並讀取所有留言
+func (s *CommentService) query(ctx context.Context, query string, args ...interface{}) ([]Comment, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan comment: %w", err)
+		}
+		list = append(list, *comment)
+	}
+	return list, rows.Err()
+}
+
+// record 將編輯對留言的管理記錄到稽核紀錄；失敗時只記錄日誌
+func (s *CommentService) record(ctx context.Context, action, id string, before, after *Comment) {
+	var beforeValue, afterValue interface{}
+	if before != nil {
+		beforeValue = before
+	}
+	if after != nil {
+		afterValue = after
+	}
+	if err := s.audit.Record(context.WithoutCancel(ctx), action, AuditEntityComment, id, beforeValue, afterValue); err != nil {
+		slog.Warn("failed to record comment audit entry", "id", id, "action", action, "error", err)
+	}
+}
+
+func validCommentStatus(status string) bool {
+	return status == CommentStatusPending || status == CommentStatusApproved || status == CommentStatusHidden
+}
+
+// encodeCommentCursor 將最後一則留言的時間與 ID 編碼為 cursor
+func encodeCommentCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+// decodeCommentCursor 解析 encodeCommentCursor 產生的 cursor
+func decodeCommentCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	createdAt, err := time.Parse(time.RFC3339Nano, at)
+	if !ok || err != nil || id == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
+
+// scanComment 依 commentColumns 的順序讀取一則留言
+func scanComment(row rowScanner) (*Comment, error) {
+	var comment Comment
+	if err := row.Scan(&comment.ID, &comment.StoryID, &comment.ParentID, &comment.rootID, &comment.UserID, &comment.AuthorName,
+		&comment.Body, &comment.Status, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
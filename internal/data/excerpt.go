@@ -0,0 +1,266 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExcerptSentences and MaxExcerptLength bound the excerpt generated from a
+// story body: its first sentences, cut to at most MaxExcerptLength
+// characters.
+const (
+	ExcerptSentences = 2
+	MaxExcerptLength = 280
+)
+
+// ErrExcerptUnsupported is returned when the story store cannot update
+// excerpts on their own.
+var ErrExcerptUnsupported = errors.New("story store does not support excerpt updates")
+
+// ExcerptWriter is implemented by story repositories that can replace the
+// generated excerpt of a story without a full Update. Callers type-assert
+// a StoryRepository to it.
+type ExcerptWriter interface {
+	// SetExcerpt sets Story.Excerpt of the story with id, unless the story
+	// has a manual Summary meanwhile or does not exist. Story.UpdatedAt is
+	// left unchanged and no revision is recorded.
+	SetExcerpt(ctx context.Context, id, excerpt string) error
+}
+
+// Excerpt returns the excerpt of story: its Summary when the editor wrote
+// one, otherwise the first ExcerptSentences sentences of its body as plain
+// text (see ExtractExcerpt).
+func Excerpt(story *Story) string {
+	if summary := strings.TrimSpace(story.Summary); summary != "" {
+		return summary
+	}
+	return ExtractExcerpt(story.Body)
+}
+
+// ExtractExcerpt returns the first ExcerptSentences sentences of body, HTML
+// or Markdown, as plain text: markup, headings, captions and code are left
+// out and whitespace is collapsed. Text longer than MaxExcerptLength is
+// cut at a word boundary and ends with an ellipsis.
+func ExtractExcerpt(body string) string {
+	text := excerptText(body)
+	var (
+		sb        strings.Builder
+		sentences int
+	)
+	runes := []rune(text)
+	for i := 0; i < len(runes) && sentences < ExcerptSentences; i++ {
+		sb.WriteRune(runes[i])
+		if !isSentenceEnd(runes, i) {
+			continue
+		}
+		// 句尾的引號與括號屬於同一句
+		for i+1 < len(runes) && strings.ContainsRune(`"'”’」』）)`, runes[i+1]) {
+			i++
+			sb.WriteRune(runes[i])
+		}
+		sentences++
+	}
+	return truncateExcerpt(strings.TrimSpace(sb.String()), MaxExcerptLength)
+}
+
+// isSentenceEnd 判斷 runes[i] 是否為句尾：中日文的句號不需後接空白；英文的句號後需為空白或結尾，
+// 且下一個字不是小寫 (略過 e.g. 等縮寫)
+func isSentenceEnd(runes []rune, i int) bool {
+	switch runes[i] {
+	case '。', '！', '？', '…':
+		return true
+	case '.', '!', '?':
+		j := i + 1
+		for j < len(runes) && strings.ContainsRune(`"'”’)`, runes[j]) {
+			j++
+		}
+		if j == len(runes) {
+			return true
+		}
+		if !unicode.IsSpace(runes[j]) {
+			return false
+		}
+		for j < len(runes) && unicode.IsSpace(runes[j]) {
+			j++
+		}
+		return j == len(runes) || !unicode.IsLower(runes[j])
+	}
+	return false
+}
+
+// truncateExcerpt 將超過 limit 個字的文字在 limit 內最後一個空白處截斷並加上刪節號；沒有空白 (如中文) 時直接截斷
+func truncateExcerpt(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)[:limit-1]
+	cut := len(runes)
+	for i := cut - 1; i > limit/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// excerptSkippedElements 為摘要略過內容的元素：標題、圖說、程式碼與表格不適合作為摘要
+var excerptSkippedElements = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"figcaption": true, "pre": true, "table": true,
+}
+
+// markdownExcerptPatterns 移除 Markdown 語法，依序套用：圖片、連結保留文字、強調與行內程式碼符號
+var markdownExcerptPatterns = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`), ""},
+	{regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile("[*_`~]{1,3}"), ""},
+}
+
+// markdownBlockLine 為 Markdown 中不作為摘要的行：標題、分隔線與表格
+var markdownBlockLine = regexp.MustCompile(`^\s*(#{1,6}\s|([-*_]\s*){3,}$|\|)`)
+
+// markdownLinePrefix 為 Markdown 的引言與清單符號
+var markdownLinePrefix = regexp.MustCompile(`^\s*(>\s*)+|^\s*([-*+]|\d+[.)])\s+`)
+
+// excerptText 將 HTML 或 Markdown 的 body 轉為摘要用的純文字
+func excerptText(body string) string {
+	var sb strings.Builder
+	skipping := ""
+	for s := body; len(s) > 0; {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			i = len(s)
+		}
+		if skipping == "" {
+			sb.WriteString(s[:i])
+		}
+		if i == len(s) {
+			break
+		}
+		tag, n := readHTMLTag(s[i:])
+		s = s[i+n:]
+		switch {
+		case tag.kind == htmlTagText && skipping == "":
+			sb.WriteByte('<')
+		case tag.kind == htmlTagStart && rawTextElements[tag.name]:
+			s = skipRawText(s, tag.name)
+		case tag.kind == htmlTagStart && skipping == "" && excerptSkippedElements[tag.name]:
+			skipping = tag.name
+		case tag.kind == htmlTagEnd && tag.name == skipping:
+			skipping = ""
+		default:
+			// 標籤以換行取代，讓段落之間的文字不會連在一起
+			sb.WriteByte('\n')
+		}
+	}
+
+	var lines []string
+	fenced := false
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || markdownBlockLine.MatchString(line) {
+			continue
+		}
+		line = markdownLinePrefix.ReplaceAllString(line, "")
+		for _, p := range markdownExcerptPatterns {
+			line = p.pattern.ReplaceAllString(line, p.replace)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(strings.Join(lines, "\n"))), " ")
+}
+
+// Summarizer writes an abstract of a story, such as an LLM-backed
+// LLMSummarizer. It is only used for stories without a manual Summary.
+type Summarizer interface {
+	Summarize(ctx context.Context, story *Story) (string, error)
+}
+
+// summaryCachePrefix 為摘要的 cache key 前綴，依 body 的雜湊計算，不在 story: 前綴下，story 寫入時不需清除；
+// summaryCacheTTL 為摘要保留的時間，body 不變時不再呼叫 Summarizer
+const (
+	summaryCachePrefix = "summary"
+	summaryCacheTTL    = 30 * 24 * time.Hour
+)
+
+// SummaryGenerator replaces the extracted excerpt of published stories
+// without a manual Summary by one written by a Summarizer. As a
+// StoryEventListener it summarizes a story in the background when it is
+// published or updated and stores the abstract with ExcerptWriter, then
+// purges the story cache. Abstracts are cached by body, so updates that do
+// not change the body do not call the Summarizer again. Failures are
+// logged and the extracted excerpt is kept.
+type SummaryGenerator struct {
+	repo       StoryRepository
+	summarizer Summarizer
+	cache      *Cache
+}
+
+// NewSummaryGenerator returns a generator writing abstracts by summarizer
+// to repo, which must implement ExcerptWriter.
+func NewSummaryGenerator(repo StoryRepository, summarizer Summarizer, cache *Cache) *SummaryGenerator {
+	return &SummaryGenerator{repo: repo, summarizer: summarizer, cache: cache}
+}
+
+// HandleStoryEvent summarizes published and updated stories without a
+// manual Summary in the background.
+func (g *SummaryGenerator) HandleStoryEvent(ctx context.Context, event StoryEvent, story *Story) {
+	if event == StoryEventUnpublished || strings.TrimSpace(story.Summary) != "" || story.Body == "" {
+		return
+	}
+	current := *story
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := g.generate(ctx, &current); err != nil {
+			slog.Warn("failed to summarize story", "story", current.ID, "error", err)
+		}
+	}()
+}
+
+// generate 產生並寫入 story 的摘要
+func (g *SummaryGenerator) generate(ctx context.Context, story *Story) error {
+	writer, ok := g.repo.(ExcerptWriter)
+	if !ok {
+		return ErrExcerptUnsupported
+	}
+	sum := sha256.Sum256([]byte(story.Title + "\x00" + story.Body))
+	key := NewCacheKey(summaryCachePrefix).Field("body", hex.EncodeToString(sum[:])).String()
+	summary, err := NewTypedCache[string](g.cache).GetOrSet(ctx, key, summaryCacheTTL, func(ctx context.Context) (string, error) {
+		summary, err := g.summarizer.Summarize(ctx, story)
+		if err != nil {
+			return "", err
+		}
+		return truncateExcerpt(strings.Join(strings.Fields(summary), " "), 2*MaxExcerptLength), nil
+	})
+	if err != nil {
+		return err
+	}
+	if summary == "" || summary == story.Excerpt {
+		return nil
+	}
+	if err := writer.SetExcerpt(ctx, story.ID, summary); err != nil {
+		return err
+	}
+	if g.cache != nil {
+		_, _ = g.cache.DeleteByPrefix(ctx, storyCachePrefix)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnknownFeedFormat is returned (wrapped) for a feed format without a
+	// renderer.
+	ErrUnknownFeedFormat = errors.New("unknown feed format")
+	// ErrFeedsUnsupported is returned by a nil FeedService, i.e. when no
+	// site URL is configured.
+	ErrFeedsUnsupported = errors.New("feeds are not configured")
+)
+
+// FeedFormat is an output format of the story feeds.
+type FeedFormat string
+
+// Feed formats.
+const (
+	FeedFormatJSON FeedFormat = "json" // JSON Feed 1.1
+)
+
+// feedItemLimit 為每個 feed 的 story 數
+const feedItemLimit = 50
+
+// Site describes the public site that feeds, sitemaps and social metadata
+// link to. Story pages are at URL/story/{slug}, and their AMP pages at
+// URL/amp/{slug} when AMP is set.
+type Site struct {
+	Title       string
+	URL         string
+	Description string
+	Language    string // BCP 47，例如 zh-TW
+	Image       string // story 沒有圖片時的分享圖片
+	Twitter     string // 網站的 X (Twitter) 帳號，例如 @example
+	Logo        string // JSON-LD 中 publisher 的 logo
+	ArticleType string // JSON-LD 的類型，ArticleType* 之一，空字串為 NewsArticle
+	AMP         bool   // 是否提供 AMP 頁面
+}
+
+// StoryURL returns the public URL of the story page with slug.
+func (s Site) StoryURL(slug string) string {
+	return strings.TrimRight(s.URL, "/") + "/story/" + url.PathEscape(slug)
+}
+
+// AMPURL returns the public URL of the AMP page of the story with slug.
+func (s Site) AMPURL(slug string) string {
+	return strings.TrimRight(s.URL, "/") + "/amp/" + url.PathEscape(slug)
+}
+
+// FeedQuery selects the stories of a feed; zero values mean all published
+// stories. At most one of Section, Tag and Author should be set.
+type FeedQuery struct {
+	Section string
+	Tag     string
+	Author  string // author ID
+}
+
+// Feed is a feed before rendering, shared by every format.
+type Feed struct {
+	Title       string
+	HomePageURL string
+	FeedURL     string
+	Description string
+	Language    string
+	Updated     time.Time // 最新一篇的更新時間
+	Items       []FeedItem
+}
+
+// FeedItem is one story of a Feed. ContentHTML is empty for paid (metered
+// and member-only) stories, whose body is not published in feeds.
+type FeedItem struct {
+	ID          string
+	URL         string
+	Title       string
+	Summary     string
+	ContentHTML string
+	Image       string
+	Published   time.Time
+	Updated     time.Time
+	Authors     []string
+	Tags        []string
+}
+
+// feedRenderers 為各格式的輸出方式與 Content-Type
+var feedRenderers = map[FeedFormat]struct {
+	contentType string
+	render      func(feed *Feed) ([]byte, error)
+}{
+	FeedFormatJSON: {"application/feed+json; charset=utf-8", renderJSONFeed},
+}
+
+// FeedContentType returns the Content-Type of format, or "" when format
+// has no renderer.
+func FeedContentType(format FeedFormat) string {
+	return feedRenderers[format].contentType
+}
+
+// FeedService renders feeds of the newest published stories. Every format
+// is built from the same Feed, and the rendered output is cached per format
+// and query under the story cache prefix, so story writes purge it.
+type FeedService struct {
+	stories *StoryService
+	cache   *Cache
+	site    Site
+}
+
+// NewFeedService returns a service rendering feeds of stories that link to
+// site.
+func NewFeedService(stories *StoryService, cache *Cache, site Site) *FeedService {
+	return &FeedService{stories: stories, cache: cache, site: site}
+}
+
+// Render returns the feed selected by q in format; feedURL is the URL the
+// feed is served at.
+func (s *FeedService) Render(ctx context.Context, format FeedFormat, q FeedQuery, feedURL string) ([]byte, error) {
+	if s == nil {
+		return nil, ErrFeedsUnsupported
+	}
+	renderer, ok := feedRenderers[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFeedFormat, format)
+	}
+
+	key := NewCacheKey(storyCachePrefix + "feed").Fields(map[string]interface{}{"format": format, "query": q, "url": feedURL}).ShortHash().String()
+	return NewTypedCache[[]byte](s.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]byte, error) {
+		feed, err := s.build(ctx, q, feedURL)
+		if err != nil {
+			return nil, err
+		}
+		return renderer.render(feed)
+	})
+}
+
+// build 讀取 story 並組成與格式無關的 Feed
+func (s *FeedService) build(ctx context.Context, q FeedQuery, feedURL string) (*Feed, error) {
+	stories, err := s.stories.Stories(ctx, "", StoryListOptions{Section: q.Section, Tag: q.Tag, Author: q.Author, Limit: feedItemLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:       s.feedTitle(q),
+		HomePageURL: s.site.URL,
+		FeedURL:     feedURL,
+		Description: s.site.Description,
+		Language:    s.site.Language,
+		Items:       make([]FeedItem, 0, len(stories)),
+	}
+	for i := range stories {
+		story := &stories[i]
+		item := FeedItem{
+			ID:      story.ID,
+			URL:     s.site.StoryURL(story.Slug),
+			Title:   story.Title,
+			Summary: story.Excerpt,
+			Image:   story.CoverImage,
+			Updated: story.UpdatedAt,
+			Tags:    story.Tags,
+		}
+		if story.AccessTier() == StoryAccessFree {
+			html, err := s.stories.BodyHTML(ctx, story)
+			if err != nil {
+				return nil, err
+			}
+			item.ContentHTML = html
+		}
+		if story.PublishedAt != nil {
+			item.Published = *story.PublishedAt
+		}
+		authors, err := s.stories.Authors(ctx, story)
+		if err != nil {
+			return nil, err
+		}
+		for _, author := range authors {
+			item.Authors = append(item.Authors, author.Name)
+		}
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// feedTitle 依查詢範圍組成 feed 標題，例如「網站名稱 - section」
+func (s *FeedService) feedTitle(q FeedQuery) string {
+	for _, scope := range []string{q.Section, q.Tag} {
+		if scope != "" {
+			return s.site.Title + " - " + scope
+		}
+	}
+	return s.site.Title
+}
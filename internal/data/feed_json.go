@@ -0,0 +1,80 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// jsonFeedVersion 為 JSON Feed 的版本 URL
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// jsonFeed 為 JSON Feed 1.1 的頂層物件 (https://www.jsonfeed.org/version/1.1/)
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Language    string         `json:"language,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// renderJSONFeed 將 feed 輸出為 JSON Feed 1.1
+func renderJSONFeed(feed *Feed) ([]byte, error) {
+	out := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       feed.Title,
+		HomePageURL: feed.HomePageURL,
+		FeedURL:     feed.FeedURL,
+		Description: feed.Description,
+		Language:    feed.Language,
+		Items:       make([]jsonFeedItem, 0, len(feed.Items)),
+	}
+	for _, item := range feed.Items {
+		entry := jsonFeedItem{
+			ID:          item.ID,
+			URL:         item.URL,
+			Title:       item.Title,
+			Summary:     item.Summary,
+			ContentHTML: item.ContentHTML,
+			Image:       item.Image,
+			Tags:        item.Tags,
+		}
+		// 每個 item 都必須有 content_html 或 content_text；會員文章只提供摘要
+		if entry.ContentHTML == "" {
+			entry.ContentText = item.Summary
+			if entry.ContentText == "" {
+				entry.ContentText = item.Title
+			}
+		}
+		if !item.Published.IsZero() {
+			entry.DatePublished = item.Published.Format(time.RFC3339)
+		}
+		if !item.Updated.IsZero() {
+			entry.DateModified = item.Updated.Format(time.RFC3339)
+		}
+		for _, name := range item.Authors {
+			entry.Authors = append(entry.Authors, jsonFeedAuthor{Name: name})
+		}
+		out.Items = append(out.Items, entry)
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,15 @@
+package data
+
+import (
+	"image"
+	"io"
+
+	_ "golang.org/x/image/webp" // 註冊 WebP 的解碼，來源圖片也可為 WebP
+)
+
+// 註冊 WebP 的編碼 (lossless，不使用 quality)；AVIF 目前沒有可用的純 Go 編碼，不列入可輸出的格式
+func init() {
+	imageEncoders[ImageFormatWebP] = imageEncoder{contentType: "image/webp", encode: func(w io.Writer, img image.Image, _ int) error {
+		return encodeWebP(w, img)
+	}}
+}
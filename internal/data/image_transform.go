@@ -0,0 +1,447 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Image transform output formats. WebP is encoded losslessly; AVIF has no
+// encoder yet and is not available (see ImageFormatAvailable).
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatWebP = "webp"
+	ImageFormatAVIF = "avif"
+)
+
+// MaxImageDimension bounds the width and height of a transform, and
+// DefaultImageQuality is the quality of lossy formats when none is given.
+const (
+	MaxImageDimension   = 4096
+	DefaultImageQuality = 80
+)
+
+var (
+	// ErrInvalidImageTransform is returned (wrapped) by ParseImageTransform
+	// for invalid parameters.
+	ErrInvalidImageTransform = errors.New("invalid image transform")
+	// ErrImageSourceForbidden is returned for a source URL outside the
+	// proxy's allowed sources.
+	ErrImageSourceForbidden = errors.New("image source not allowed")
+	// ErrImageSourceNotFound is returned when the source answers 404.
+	ErrImageSourceNotFound = errors.New("image source not found")
+	// ErrUnsupportedImage is returned (wrapped) for a source that is not an
+	// image the proxy can decode, or is too large to transform.
+	ErrUnsupportedImage = errors.New("unsupported image")
+)
+
+// imageCachePrefix 為轉換結果的 cache key 前綴；imageObjectPrefix 為存放於 ObjectStorage 的 key 前綴
+const (
+	imageCachePrefix     = "image"
+	imageObjectPrefix    = "transforms/"
+	imageFetchTimeout    = 15 * time.Second
+	maxImageSourceSize   = 20 << 20
+	maxImageSourcePixels = 50_000_000 // 解碼前檢查，避免解壓縮炸彈
+)
+
+// imageEncoder 為輸出格式的 Content-Type 與編碼方式；quality 只用於失真壓縮的格式
+type imageEncoder struct {
+	contentType string
+	encode      func(w io.Writer, img image.Image, quality int) error
+}
+
+// imageEncoders 為可輸出的格式；WebP 由 image_codec.go 註冊
+var imageEncoders = map[string]imageEncoder{
+	ImageFormatJPEG: {contentType: "image/jpeg", encode: func(w io.Writer, img image.Image, quality int) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}},
+	ImageFormatPNG: {contentType: "image/png", encode: func(w io.Writer, img image.Image, quality int) error {
+		return (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(w, img)
+	}},
+}
+
+// ImageFormatAvailable reports whether this build can output format.
+func ImageFormatAvailable(format string) bool {
+	_, ok := imageEncoders[format]
+	return ok
+}
+
+// ImageTransform describes a transformed variant of a source image. Width
+// and Height bound the result; with only one of them the other follows the
+// aspect ratio. Crop fills exactly Width×Height, cutting the center of the
+// image to that aspect ratio. Images are never upscaled. An empty Format
+// keeps the format of the source (GIFs become PNGs).
+type ImageTransform struct {
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Crop    bool   `json:"crop,omitempty"`
+	Quality int    `json:"quality,omitempty"`
+	Format  string `json:"format,omitempty"`
+}
+
+// ParseImageTransform reads a transform from the query parameters w, h,
+// crop, q (1–100, default DefaultImageQuality) and format.
+func ParseImageTransform(query url.Values) (ImageTransform, error) {
+	t := ImageTransform{Quality: DefaultImageQuality, Format: query.Get("format")}
+	for _, p := range []struct {
+		name string
+		dest *int
+		max  int
+	}{{"w", &t.Width, MaxImageDimension}, {"h", &t.Height, MaxImageDimension}, {"q", &t.Quality, 100}} {
+		raw := query.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > p.max {
+			return ImageTransform{}, fmt.Errorf("%w: %s must be between 1 and %d", ErrInvalidImageTransform, p.name, p.max)
+		}
+		*p.dest = n
+	}
+	if raw := query.Get("crop"); raw != "" {
+		crop, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ImageTransform{}, fmt.Errorf("%w: crop must be a boolean", ErrInvalidImageTransform)
+		}
+		t.Crop = crop
+	}
+	if t.Crop && (t.Width == 0 || t.Height == 0) {
+		return ImageTransform{}, fmt.Errorf("%w: crop requires both w and h", ErrInvalidImageTransform)
+	}
+	if t.Format != "" && !ImageFormatAvailable(t.Format) {
+		return ImageTransform{}, fmt.Errorf("%w: unsupported format %q", ErrInvalidImageTransform, t.Format)
+	}
+	return t, nil
+}
+
+// Negotiate picks the output format from an Accept header when none was
+// requested explicitly: AVIF, then WebP, when the client accepts it and
+// this build can encode it. It reports whether the result depends on
+// accept, i.e. whether responses must vary on it.
+func (t *ImageTransform) Negotiate(accept string) bool {
+	if t.Format != "" {
+		return false
+	}
+	for _, format := range []string{ImageFormatAVIF, ImageFormatWebP} {
+		if ImageFormatAvailable(format) && acceptsMediaType(accept, "image/"+format) {
+			t.Format = format
+			break
+		}
+	}
+	return ImageFormatAvailable(ImageFormatAVIF) || ImageFormatAvailable(ImageFormatWebP)
+}
+
+// acceptsMediaType 判斷 Accept header 是否明確列出 mediaType (q=0 視為不接受)；不採用 image/* 以免選到用戶端未必支援的格式
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		name, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || name != mediaType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// TransformedImage is the encoded result of an ImageTransform.
+type TransformedImage struct {
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+// ImageProxy serves resized and re-encoded variants of images from allowed
+// sources, so stories can use responsive images without every size being
+// generated in advance. Results are cached in Redis for a TTL and, when an
+// ObjectStorage is given, stored there for good under transforms/.
+// Transforms run at most one per CPU at a time.
+type ImageProxy struct {
+	client  *http.Client
+	cache   *Cache
+	ttl     time.Duration
+	storage ObjectStorage
+	sources []string
+	sem     chan struct{}
+}
+
+// NewImageProxy returns a proxy for images whose URL starts with one of
+// sources (e.g. https://cdn.example.com/media/). cache holds results for
+// ttl; storage may be nil.
+func NewImageProxy(cache *Cache, ttl time.Duration, storage ObjectStorage, sources []string) *ImageProxy {
+	p := &ImageProxy{cache: cache, ttl: ttl, storage: storage, sem: make(chan struct{}, runtime.NumCPU())}
+	for _, source := range sources {
+		// 前綴一律以 / 結尾，避免 https://cdn.example.com 符合 https://cdn.example.com.evil.test
+		if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			p.sources = append(p.sources, strings.TrimSuffix(u.String(), "/")+"/")
+		}
+	}
+	p.client = &http.Client{
+		Timeout: imageFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 || !p.allowed(req.URL.String()) {
+				return ErrImageSourceForbidden
+			}
+			return nil
+		},
+	}
+	return p
+}
+
+// allowed 判斷來源網址是否在允許的前綴之下
+func (p *ImageProxy) allowed(source string) bool {
+	u, err := url.Parse(source)
+	if err != nil || u.User != nil || strings.Contains(u.Path, "..") {
+		return false
+	}
+	for _, prefix := range p.sources {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transform returns source transformed by t, from the cache, the object
+// storage or by fetching and transforming the source.
+func (p *ImageProxy) Transform(ctx context.Context, source string, t ImageTransform) (*TransformedImage, error) {
+	if !p.allowed(source) {
+		return nil, fmt.Errorf("%w: %s", ErrImageSourceForbidden, source)
+	}
+	key := NewCacheKey(imageCachePrefix).Field("url", source).Fields(t)
+	sum := sha256.Sum256([]byte(key.Canonical()))
+	objectKey := imageObjectPrefix + hex.EncodeToString(sum[:])
+
+	result, err := NewTypedCache[TransformedImage](p.cache).GetOrSet(ctx, key.String(), p.ttl, func(ctx context.Context) (TransformedImage, error) {
+		if p.storage != nil {
+			body, err := p.storage.Get(ctx, objectKey)
+			if err == nil {
+				return TransformedImage{ContentType: imageContentType(t.Format, body), Body: body}, nil
+			}
+			if !errors.Is(err, ErrObjectNotFound) {
+				slog.Warn("failed to read transformed image", "key", objectKey, "error", err)
+			}
+		}
+		transformed, err := p.transform(ctx, source, t)
+		if err != nil {
+			return TransformedImage{}, err
+		}
+		if p.storage != nil {
+			if err := p.storage.Put(ctx, objectKey, transformed.ContentType, transformed.Body); err != nil {
+				slog.Warn("failed to store transformed image", "key", objectKey, "error", err)
+			}
+		}
+		return *transformed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// imageContentType 回傳存放於 ObjectStorage 的結果的 Content-Type；未指定格式時由內容判斷
+func imageContentType(format string, body []byte) string {
+	if encoder, ok := imageEncoders[format]; ok {
+		return encoder.contentType
+	}
+	return http.DetectContentType(body)
+}
+
+// transform 下載並轉換來源圖片；同時進行的轉換數量以 sem 限制
+func (p *ImageProxy) transform(ctx context.Context, source string, t ImageTransform) (*TransformedImage, error) {
+	select {
+	case p.sem <- struct{}{}:
+		defer func() { <-p.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	content, err := p.fetch(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if config.Width*config.Height > maxImageSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d is too large", ErrUnsupportedImage, config.Width, config.Height)
+	}
+	src, sourceFormat, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	format := t.Format
+	if format == "" {
+		format = sourceFormat
+		if !ImageFormatAvailable(format) {
+			format = ImageFormatPNG
+		}
+	}
+	crop, width, height := t.plan(src.Bounds())
+	img := resizeImage(src, crop, width, height)
+	if format == ImageFormatJPEG {
+		img = flattenImage(img)
+	}
+	encoder := imageEncoders[format]
+	var buf bytes.Buffer
+	if err := encoder.encode(&buf, img, t.Quality); err != nil {
+		return nil, fmt.Errorf("encode %s: %w", format, err)
+	}
+	return &TransformedImage{ContentType: encoder.contentType, Body: buf.Bytes()}, nil
+}
+
+// fetch 下載來源圖片，大小以 maxImageSourceSize 為限
+func (p *ImageProxy) fetch(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrImageSourceForbidden) {
+			return nil, fmt.Errorf("%w: redirected from %s", ErrImageSourceForbidden, source)
+		}
+		return nil, fmt.Errorf("fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, fmt.Errorf("%w: %s", ErrImageSourceNotFound, source)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch image: %s answered %s", source, resp.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSourceSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch image: %w", err)
+	}
+	if len(content) > maxImageSourceSize {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrUnsupportedImage, maxImageSourceSize)
+	}
+	return content, nil
+}
+
+// plan 依轉換參數計算要使用的來源區域與輸出尺寸；不放大圖片
+func (t ImageTransform) plan(bounds image.Rectangle) (crop image.Rectangle, width, height int) {
+	sw, sh := bounds.Dx(), bounds.Dy()
+	crop = bounds
+	switch {
+	case t.Crop:
+		// 以目標比例取中間的區域
+		if sw*t.Height > sh*t.Width {
+			cw := max(1, sh*t.Width/t.Height)
+			x0 := bounds.Min.X + (sw-cw)/2
+			crop = image.Rect(x0, bounds.Min.Y, x0+cw, bounds.Max.Y)
+		} else {
+			ch := max(1, sw*t.Height/t.Width)
+			y0 := bounds.Min.Y + (sh-ch)/2
+			crop = image.Rect(bounds.Min.X, y0, bounds.Max.X, y0+ch)
+		}
+		if t.Width > crop.Dx() {
+			return crop, crop.Dx(), crop.Dy()
+		}
+		return crop, t.Width, t.Height
+	case t.Width > 0 || t.Height > 0:
+		scale := 1.0
+		if t.Width > 0 {
+			scale = math.Min(scale, float64(t.Width)/float64(sw))
+		}
+		if t.Height > 0 {
+			scale = math.Min(scale, float64(t.Height)/float64(sh))
+		}
+		return crop, max(1, int(math.Round(float64(sw)*scale))), max(1, int(math.Round(float64(sh)*scale)))
+	}
+	return crop, sw, sh
+}
+
+// resizeImage 以 box filter (面積平均) 將 src 的 crop 區域縮小為 width×height；
+// 先水平再垂直，以預乘 alpha 的 RGBA 計算，透明邊緣不會出現暗邊
+func resizeImage(src image.Image, crop image.Rectangle, width, height int) *image.RGBA {
+	in := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(in, in.Bounds(), src, crop.Min, draw.Src)
+	if in.Bounds().Dx() == width && in.Bounds().Dy() == height {
+		return in
+	}
+	sw, sh := crop.Dx(), crop.Dy()
+
+	tmp := image.NewRGBA(image.Rect(0, 0, width, sh))
+	for x := 0; x < width; x++ {
+		x0, x1 := boxSpan(x, sw, width)
+		for y := 0; y < sh; y++ {
+			var sum [4]uint32
+			for sx := x0; sx < x1; sx++ {
+				i := in.PixOffset(sx, y)
+				for c := 0; c < 4; c++ {
+					sum[c] += uint32(in.Pix[i+c])
+				}
+			}
+			setBoxAverage(tmp, x, y, sum, uint32(x1-x0))
+		}
+	}
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := boxSpan(y, sh, height)
+		for x := 0; x < width; x++ {
+			var sum [4]uint32
+			for sy := y0; sy < y1; sy++ {
+				i := tmp.PixOffset(x, sy)
+				for c := 0; c < 4; c++ {
+					sum[c] += uint32(tmp.Pix[i+c])
+				}
+			}
+			setBoxAverage(out, x, y, sum, uint32(y1-y0))
+		}
+	}
+	return out
+}
+
+// boxSpan 回傳輸出的第 i 個像素涵蓋的來源範圍 [start, end)
+func boxSpan(i, src, dst int) (start, end int) {
+	start, end = i*src/dst, (i+1)*src/dst
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// setBoxAverage 將 n 個像素的總和取平均 (四捨五入) 寫入 (x, y)
+func setBoxAverage(img *image.RGBA, x, y int, sum [4]uint32, n uint32) {
+	i := img.PixOffset(x, y)
+	for c := 0; c < 4; c++ {
+		img.Pix[i+c] = uint8((sum[c] + n/2) / n)
+	}
+}
+
+// flattenImage 將圖片疊在白色背景上；JPEG 沒有 alpha，透明處否則會變成黑色
+func flattenImage(img *image.RGBA) *image.RGBA {
+	if img.Opaque() {
+		return img
+	}
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
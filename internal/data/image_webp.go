@@ -0,0 +1,478 @@
+package data
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// WebP 輸出採 VP8L (lossless) 格式，以純 Go 實作，不需 cgo；
+// 依序套用 subtract-green 與 predictor 轉換，與前一個像素相同的連續像素以 backward reference 壓縮
+const (
+	webpMaxDimension    = 1 << 14
+	vp8lSignature       = 0x2f
+	vp8lPredictorBits   = 4 // predictor 區塊為 16×16
+	vp8lMinRun          = 3
+	vp8lMaxRun          = 4096
+	vp8lNumLengthCodes  = 24
+	vp8lNumDistCodes    = 40
+	vp8lMaxCodeLength   = 15
+	vp8lMaxCLCodeLength = 7
+	vp8lLeftDistCode    = 2 // distance code 2 對應左方 (前一個) 像素
+)
+
+// VP8L 轉換的種類
+const (
+	vp8lTransformPredictor     = 0
+	vp8lTransformSubtractGreen = 2
+)
+
+// vp8lCodeLengthOrder 為 code length code 各符號的長度寫入順序
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// vp8lPredictorModes 為每個區塊可選用的 predictor：L、T、Average2(L, T)、ClampAddSubtractFull
+var vp8lPredictorModes = []int{1, 2, 7, 12}
+
+// encodeWebP 將 img 編碼為 lossless WebP；lossless 不使用 quality
+func encodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > webpMaxDimension || height > webpMaxDimension {
+		return fmt.Errorf("%w: webp supports up to %dx%d, got %dx%d", ErrUnsupportedImage, webpMaxDimension, webpMaxDimension, width, height)
+	}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	pix := nrgba.Pix
+
+	var bw vp8lWriter
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if nrgba.Opaque() {
+		bw.writeBits(0, 1)
+	} else {
+		bw.writeBits(1, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	bw.writeBits(1, 1)
+	bw.writeBits(vp8lTransformSubtractGreen, 2)
+	for i := 0; i < len(pix); i += 4 {
+		pix[i] -= pix[i+1]
+		pix[i+2] -= pix[i+1]
+	}
+
+	modes, tilesPerRow := vp8lChoosePredictors(pix, width, height)
+	bw.writeBits(1, 1)
+	bw.writeBits(vp8lTransformPredictor, 2)
+	bw.writeBits(vp8lPredictorBits-2, 3)
+	bw.writeImage(modes, false)
+	residuals := vp8lResiduals(pix, modes, width, height, tilesPerRow)
+	bw.writeBits(0, 1) // 轉換結束
+
+	bw.writeImage(residuals, true)
+	data := bw.bytes()
+
+	header := make([]byte, 0, 20)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+len(data)+len(data)&1))
+	header = append(header, "WEBPVP8L"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+	if len(data)&1 == 1 {
+		data = append(data, 0)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// vp8lChoosePredictors 為每個區塊挑選殘差最小的 predictor，回傳 predictor 子影像 (mode 存於 green) 與其寬度
+func vp8lChoosePredictors(pix []byte, width, height int) ([]byte, int) {
+	tile := 1 << vp8lPredictorBits
+	tilesPerRow := (width + tile - 1) >> vp8lPredictorBits
+	tilesPerCol := (height + tile - 1) >> vp8lPredictorBits
+	modes := make([]byte, 4*tilesPerRow*tilesPerCol)
+	for ty := 0; ty < tilesPerCol; ty++ {
+		for tx := 0; tx < tilesPerRow; tx++ {
+			best, bestCost := vp8lPredictorModes[0], -1
+			for _, mode := range vp8lPredictorModes {
+				cost := 0
+				for y := max(ty*tile, 1); y < min((ty+1)*tile, height); y++ {
+					for x := max(tx*tile, 1); x < min((tx+1)*tile, width); x++ {
+						i := 4 * (y*width + x)
+						for c := 0; c < 4; c++ {
+							r := int(int8(pix[i+c] - vp8lPredict(mode, pix, i+c, 4*width)))
+							if r < 0 {
+								r = -r
+							}
+							cost += r
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			i := 4 * (ty*tilesPerRow + tx)
+			modes[i+1], modes[i+3] = byte(best), 0xff
+		}
+	}
+	return modes, tilesPerRow
+}
+
+// vp8lResiduals 回傳各像素與其 predictor 預測值的差；第一列與第一欄的 predictor 由格式固定
+func vp8lResiduals(pix, modes []byte, width, height, tilesPerRow int) []byte {
+	residuals := make([]byte, len(pix))
+	stride := 4 * width
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := int(modes[4*((y>>vp8lPredictorBits)*tilesPerRow+x>>vp8lPredictorBits)+1])
+			switch {
+			case x == 0 && y == 0:
+				mode = 0
+			case y == 0:
+				mode = 1
+			case x == 0:
+				mode = 2
+			}
+			i := 4 * (y*width + x)
+			for c := 0; c < 4; c++ {
+				residuals[i+c] = pix[i+c] - vp8lPredict(mode, pix, i+c, stride)
+			}
+		}
+	}
+	return residuals
+}
+
+// vp8lPredict 回傳 pix[i] 這個 channel 的預測值；mode 0 為不透明的黑色
+func vp8lPredict(mode int, pix []byte, i, stride int) byte {
+	switch mode {
+	case 0:
+		if i%4 == 3 {
+			return 0xff
+		}
+		return 0
+	case 1:
+		return pix[i-4]
+	case 2:
+		return pix[i-stride]
+	case 7:
+		return byte((int(pix[i-4]) + int(pix[i-stride])) / 2)
+	default: // 12: ClampAddSubtractFull
+		return byte(min(max(int(pix[i-4])+int(pix[i-stride])-int(pix[i-stride-4]), 0), 255))
+	}
+}
+
+// vp8lWriter 依 VP8L 的規定由低位元開始寫入 bit
+type vp8lWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *vp8lWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+// bytes 補齊最後一個 byte 後回傳寫入的內容
+func (w *vp8lWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
+
+// vp8lToken 為一個 literal 像素 (length 為 0) 或一段重複前一個像素的 backward reference
+type vp8lToken struct {
+	pixel  int
+	length int
+}
+
+// writeImage 寫入一張 entropy-coded 影像 (RGBA 排列)；main 為主影像時另需寫入 meta prefix 的旗標
+func (w *vp8lWriter) writeImage(pix []byte, main bool) {
+	w.writeBits(0, 1) // 不使用 color cache
+	if main {
+		w.writeBits(0, 1) // 整張影像共用一組 prefix code
+	}
+
+	var tokens []vp8lToken
+	n := len(pix) / 4
+	for i := 0; i < n; {
+		run := 0
+		if i > 0 {
+			prev := pix[4*(i-1) : 4*i]
+			for i+run < n && run < vp8lMaxRun && string(pix[4*(i+run):4*(i+run+1)]) == string(prev) {
+				run++
+			}
+		}
+		if run >= vp8lMinRun {
+			tokens = append(tokens, vp8lToken{length: run})
+			i += run
+			continue
+		}
+		tokens = append(tokens, vp8lToken{pixel: i})
+		i++
+	}
+
+	green := make([]int, 256+vp8lNumLengthCodes)
+	red, blue, alpha := make([]int, 256), make([]int, 256), make([]int, 256)
+	dist := make([]int, vp8lNumDistCodes)
+	distCode, _, _ := vp8lPrefix(vp8lLeftDistCode)
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, _, _ := vp8lPrefix(t.length)
+			green[256+code]++
+			dist[distCode]++
+			continue
+		}
+		p := pix[4*t.pixel:]
+		green[p[1]]++
+		red[p[0]]++
+		blue[p[2]]++
+		alpha[p[3]]++
+	}
+	greenCode := w.writePrefixCode(green)
+	redCode := w.writePrefixCode(red)
+	blueCode := w.writePrefixCode(blue)
+	alphaCode := w.writePrefixCode(alpha)
+	distPrefixCode := w.writePrefixCode(dist)
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, extraBits, extra := vp8lPrefix(t.length)
+			greenCode.write(w, 256+code)
+			w.writeBits(uint32(extra), uint(extraBits))
+			distPrefixCode.write(w, distCode)
+			continue
+		}
+		p := pix[4*t.pixel:]
+		greenCode.write(w, int(p[1]))
+		redCode.write(w, int(p[0]))
+		blueCode.write(w, int(p[2]))
+		alphaCode.write(w, int(p[3]))
+	}
+}
+
+// vp8lPrefix 將 length 或 distance 拆成 prefix code 與其後的額外 bit
+func vp8lPrefix(v int) (code, extraBits, extra int) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := bits.Len(uint(d)) - 1
+	return 2*h + (d>>(h-1))&1, h - 1, d & (1<<(h-1) - 1)
+}
+
+// vp8lPrefixCode 為各符號的 code 長度與 code；code 已反轉為由低位元開始寫入。長度為 0 的符號不寫入任何 bit
+type vp8lPrefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+func (c vp8lPrefixCode) write(w *vp8lWriter, symbol int) {
+	if n := c.lengths[symbol]; n > 0 {
+		w.writeBits(uint32(c.codes[symbol]), uint(n))
+	}
+}
+
+// writePrefixCode 依 histogram 建立 prefix code 並寫入其定義；至多兩個小於 256 的符號時使用 simple code
+func (w *vp8lWriter) writePrefixCode(histogram []int) vp8lPrefixCode {
+	var used []int
+	for symbol, n := range histogram {
+		if n > 0 {
+			used = append(used, symbol)
+		}
+	}
+	code := vp8lPrefixCode{lengths: make([]uint8, len(histogram)), codes: make([]uint16, len(histogram))}
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		w.writeBits(1, 1)
+		w.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(used[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(used[0]), 8)
+		}
+		// 兩個符號時先寫入的符號 code 為 0，與 canonical code 的順序一致
+		if len(used) == 2 {
+			w.writeBits(uint32(used[1]), 8)
+			code.lengths[used[0]], code.lengths[used[1]] = 1, 1
+			code.codes[used[1]] = 1
+		}
+		return code
+	}
+
+	lengths := vp8lCodeLengths(histogram, vp8lMaxCodeLength)
+	w.writeBits(0, 1)
+	w.writeCodeLengths(lengths)
+	// 只有一個符號時解碼端不讀取任何 bit
+	if len(used) > 1 {
+		code.lengths, code.codes = lengths, vp8lCanonicalCodes(lengths)
+	}
+	return code
+}
+
+// writeCodeLengths 以 code length code 寫入各符號的 code 長度；連續的 0 以 17、18 表示
+func (w *vp8lWriter) writeCodeLengths(lengths []uint8) {
+	type clToken struct{ symbol, extra int }
+	var tokens []clToken
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens = append(tokens, clToken{symbol: int(lengths[i])})
+			i++
+			continue
+		}
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			tokens = append(tokens, clToken{symbol: 18, extra: run - 11})
+		case run >= 3:
+			tokens = append(tokens, clToken{symbol: 17, extra: run - 3})
+		default:
+			run = 1
+			tokens = append(tokens, clToken{symbol: 0})
+		}
+		i += run
+	}
+
+	histogram := make([]int, len(vp8lCodeLengthOrder))
+	for _, t := range tokens {
+		histogram[t.symbol]++
+	}
+	clLengths := vp8lCodeLengths(histogram, vp8lMaxCLCodeLength)
+	n := 4
+	for i, symbol := range vp8lCodeLengthOrder {
+		if clLengths[symbol] != 0 && i+1 > n {
+			n = i + 1
+		}
+	}
+	w.writeBits(uint32(n-4), 4)
+	for _, symbol := range vp8lCodeLengthOrder[:n] {
+		w.writeBits(uint32(clLengths[symbol]), 3)
+	}
+	w.writeBits(0, 1) // 寫入全部符號的長度
+
+	// 只有一種符號時解碼端不讀取任何 bit
+	clCode := vp8lPrefixCode{lengths: make([]uint8, len(clLengths))}
+	used := 0
+	for _, l := range clLengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used > 1 {
+		clCode.lengths, clCode.codes = clLengths, vp8lCanonicalCodes(clLengths)
+	}
+	for _, t := range tokens {
+		clCode.write(w, t.symbol)
+		switch t.symbol {
+		case 17:
+			w.writeBits(uint32(t.extra), 3)
+		case 18:
+			w.writeBits(uint32(t.extra), 7)
+		}
+	}
+}
+
+// vp8lCodeLengths 依 histogram 建立 Huffman code 的長度，超過 maxLength 時壓平頻率後重建
+func vp8lCodeLengths(histogram []int, maxLength int) []uint8 {
+	type node struct{ weight, left, right int }
+	freq := append([]int(nil), histogram...)
+	for {
+		lengths := make([]uint8, len(freq))
+		var nodes []node
+		for symbol, n := range freq {
+			if n > 0 {
+				nodes = append(nodes, node{weight: n, left: -1, right: symbol})
+			}
+		}
+		switch len(nodes) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[nodes[0].right] = 1
+			return lengths
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].weight < nodes[j].weight || nodes[i].weight == nodes[j].weight && nodes[i].right < nodes[j].right
+		})
+
+		// 兩個佇列：葉節點依權重排序，新的內部節點權重遞增，依序取出最小的兩個合併
+		leaves := len(nodes)
+		next, internal := 0, leaves
+		pop := func() int {
+			if next < leaves && (internal >= len(nodes) || nodes[next].weight <= nodes[internal].weight) {
+				next++
+				return next - 1
+			}
+			internal++
+			return internal - 1
+		}
+		for len(nodes) < 2*leaves-1 {
+			a, b := pop(), pop()
+			nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, left: a, right: b})
+		}
+
+		depths := make([]int, len(nodes))
+		maxDepth := 0
+		for i := len(nodes) - 1; i >= leaves; i-- {
+			depths[nodes[i].left] = depths[i] + 1
+			depths[nodes[i].right] = depths[i] + 1
+		}
+		for i := 0; i < leaves; i++ {
+			lengths[nodes[i].right] = uint8(depths[i])
+			maxDepth = max(maxDepth, depths[i])
+		}
+		if maxDepth <= maxLength {
+			return lengths
+		}
+		for i, n := range freq {
+			if n > 0 {
+				freq[i] = n/2 + 1
+			}
+		}
+	}
+}
+
+// vp8lCanonicalCodes 回傳 lengths 對應的 canonical Huffman code，並反轉為由低位元開始寫入
+func vp8lCanonicalCodes(lengths []uint8) []uint16 {
+	var count [vp8lMaxCodeLength + 1]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [vp8lMaxCodeLength + 1]int
+	code := 0
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint16, len(lengths))
+	for symbol, l := range lengths {
+		if l > 0 {
+			codes[symbol] = bits.Reverse16(uint16(next[l])) >> (16 - l)
+			next[l]++
+		}
+	}
+	return codes
+}
@@ -0,0 +1,384 @@
+package data
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // 註冊 RS256 / ES256 等使用的 hash
+	_ "crypto/sha512" // 註冊 RS384 / RS512 等使用的 hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrInvalidToken is returned (wrapped) by JWTVerifier.Verify for a token
+// that is malformed, not signed by a key of the JWKS, expired or issued for
+// another issuer or audience.
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWT 驗證的預設值：允許的時鐘誤差、JWKS 的快取時間，以及重新取得 JWKS 的最短間隔
+const (
+	defaultJWTClockSkew  = time.Minute
+	defaultJWKSCacheTTL  = time.Hour
+	jwksMinRefreshPeriod = time.Minute
+	jwksFetchTimeout     = 10 * time.Second
+	jwksMaxSize          = 1 << 20
+	defaultJWTRolesClaim = "roles"
+	jwtMaxTokenLength    = 16 << 10
+)
+
+// JWTConfig configures a JWTVerifier.
+type JWTConfig struct {
+	// JWKSURL is where the identity provider publishes its signing keys.
+	JWKSURL string
+	// Issuer must equal the iss claim when set.
+	Issuer string
+	// Audience lists accepted aud values; when set the token must be issued
+	// for one of them.
+	Audience []string
+	// ClockSkew is the tolerance applied to exp, nbf and iat; zero uses one
+	// minute.
+	ClockSkew time.Duration
+	// CacheTTL is how long fetched keys are used before the JWKS is fetched
+	// again; zero uses one hour.
+	CacheTTL time.Duration
+	// RolesClaim names the claim holding the user's roles, an array or a
+	// space-separated string. Empty uses "roles".
+	RolesClaim string
+}
+
+// JWTVerifier verifies JWTs signed by an identity provider with the RSA
+// (RS256/384/512, PS256/384/512) or ECDSA (ES256/384/512) keys published
+// in its JWKS. Keys are cached for CacheTTL; a token signed with an
+// unknown kid triggers a refetch at most once a minute, so key rotation is
+// picked up without letting bogus tokens hammer the provider. When a
+// refetch fails the cached keys keep being used.
+type JWTVerifier struct {
+	cfg    JWTConfig
+	client *http.Client
+	group  singleflight.Group
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey // kid → key；沒有 kid 的 key 以空字串保存
+	fetchedAt   time.Time                   // 最近一次成功取得 JWKS 的時間
+	attemptedAt time.Time                   // 最近一次嘗試取得 JWKS 的時間，不論成功與否
+}
+
+// NewJWTVerifier returns a verifier for tokens signed with the keys at
+// cfg.JWKSURL. The JWKS is fetched on first use.
+func NewJWTVerifier(cfg JWTConfig) *JWTVerifier {
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = defaultJWTClockSkew
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultJWKSCacheTTL
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = defaultJWTRolesClaim
+	}
+	return &JWTVerifier{cfg: cfg, client: &http.Client{Timeout: jwksFetchTimeout}}
+}
+
+// Verify checks the signature and the exp, nbf, iat, iss and aud claims of
+// token and returns its principal.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	if len(token) > jwtMaxTokenLength {
+		return nil, fmt.Errorf("%w: too long", ErrInvalidToken)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return v.principal(claims), nil
+}
+
+// checkClaims 檢查有效期間 (允許 ClockSkew 的誤差)、iss 與 aud
+func (v *JWTVerifier) checkClaims(claims map[string]interface{}, now time.Time) error {
+	skew := v.cfg.ClockSkew
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if !now.Before(time.Unix(int64(exp), 0).Add(skew)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(skew).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: not yet valid", ErrInvalidToken)
+	}
+	if iat, ok := claims["iat"].(float64); ok && now.Add(skew).Before(time.Unix(int64(iat), 0)) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+	if v.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, iss)
+		}
+	}
+	if len(v.cfg.Audience) > 0 {
+		matched := false
+		for _, aud := range claimStrings(claims["aud"]) {
+			if slices.Contains(v.cfg.Audience, aud) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+		}
+	}
+	return nil
+}
+
+// principal 由 claims 組成 Principal
+func (v *JWTVerifier) principal(claims map[string]interface{}) *Principal {
+	p := &Principal{Claims: claims, Roles: claimStrings(claims[v.cfg.RolesClaim])}
+	p.Subject, _ = claims["sub"].(string)
+	p.Issuer, _ = claims["iss"].(string)
+	p.Email, _ = claims["email"].(string)
+	p.Name, _ = claims["name"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		p.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = claimStrings(claims["scp"])
+	}
+	return p
+}
+
+// claimStrings 將字串陣列或以空白分隔的字串形式的 claim 轉為 []string
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// key 回傳 kid 對應的公鑰；JWKS 過期或找不到 kid 時重新取得，但每分鐘最多一次，
+// 避免偽造的 token 造成大量請求；取得失敗時沿用過期的 key
+func (v *JWTVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := time.Since(v.fetchedAt) < v.cfg.CacheTTL
+	throttled := time.Since(v.attemptedAt) < jwksMinRefreshPeriod
+	v.mu.RUnlock()
+	if (ok && fresh) || throttled {
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+		}
+		return key, nil
+	}
+	if err := v.refresh(ctx); err != nil {
+		slog.Warn("failed to fetch JWKS", "url", v.cfg.JWKSURL, "error", err)
+	}
+	v.mu.RLock()
+	key, ok = v.keys[kid]
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// refresh 取得 JWKS 並取代快取的 key；同時進行的呼叫共用一次請求
+func (v *JWTVerifier) refresh(ctx context.Context) error {
+	_, err, _ := v.group.Do("jwks", func() (interface{}, error) {
+		keys, err := v.fetch(context.WithoutCancel(ctx))
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.attemptedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		v.keys, v.fetchedAt = keys, v.attemptedAt
+		return nil, nil
+	})
+	return err
+}
+
+// jwk 為 JWKS 中 RSA 與 EC 公鑰使用的欄位
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch 取得並解析 JWKS；無法解析或非簽章用途的 key 略過
+func (v *JWTVerifier) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: status %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("skipped JWKS key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("fetch jwks: no usable keys")
+	}
+	return keys, nil
+}
+
+// publicKey 將 JWK 轉為 *rsa.PublicKey 或 *ecdsa.PublicKey
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point not on curve %s", k.Crv)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// jwtHashes 為各簽章演算法使用的 hash
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// verifyJWTSignature 以 key 驗證 signed 的簽章；演算法需與 key 的種類一致，不接受 none 與 HMAC
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	hash, ok := jwtHashes[alg]
+	if !ok {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+	var valid bool
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(key, hash, digest, sig) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(key, hash, digest, sig, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] == "ES" && len(sig) == 2*size {
+			r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(key, digest, r, s)
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// ErrInvalidLocale is returned (wrapped) for a story locale or locale
+// filter that is not a BCP 47 language tag.
+var ErrInvalidLocale = errors.New("invalid locale")
+
+// StoryTranslation is a published story in the translation group of
+// another story, as listed in hreflang links.
+type StoryTranslation struct {
+	Locale string `json:"locale"`
+	Slug   string `json:"slug"`
+	Title  string `json:"title"`
+}
+
+// NormalizeLocale returns locale, a BCP 47 language tag, in its canonical
+// form (zh-tw → zh-TW, en_US → en-US). The empty string stays empty.
+func NormalizeLocale(locale string) (string, error) {
+	if locale == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, locale)
+	}
+	return tag.String(), nil
+}
+
+// normalizeStoryLocale 在寫入前正規化 story 的語言
+func normalizeStoryLocale(story *Story) error {
+	locale, err := NormalizeLocale(story.Locale)
+	if err != nil {
+		return err
+	}
+	story.Locale = locale
+	return nil
+}
+
+// Locale returns the locale of story: Story.Locale, or the site language
+// for stories stored without one.
+func (s *StoryService) Locale(story *Story) string {
+	if story.Locale == "" {
+		return s.site.Language
+	}
+	return story.Locale
+}
+
+// LocaleFilter returns the filter matching stories in locale, including
+// stories without a locale when it is the site language.
+func (s *StoryService) LocaleFilter(locale string) (*StringFilter, error) {
+	locale, err := NormalizeLocale(locale)
+	if err != nil || locale == "" {
+		return nil, err
+	}
+	if locale == s.site.Language {
+		return &StringFilter{In: []string{locale, ""}}, nil
+	}
+	return &StringFilter{Equals: &locale}, nil
+}
+
+// maxStoryTranslations 為列出的翻譯數量上限
+const maxStoryTranslations = 50
+
+// Translations returns the published stories in the translation group of
+// story, story itself included, one per locale, for hreflang links. A
+// story outside a group yields nil. When a group has several stories in
+// one locale, the most recently published one is listed.
+func (s *StoryService) Translations(ctx context.Context, story *Story) ([]StoryTranslation, error) {
+	if story.TranslationGroup == "" {
+		return nil, nil
+	}
+	stories, err := s.list(ctx, "", StoryListOptions{TranslationGroup: story.TranslationGroup, Limit: maxStoryTranslations})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var translations []StoryTranslation
+	for _, translation := range stories {
+		locale := s.Locale(&translation)
+		if seen[locale] {
+			continue
+		}
+		seen[locale] = true
+		translations = append(translations, StoryTranslation{Locale: locale, Slug: translation.Slug, Title: translation.Title})
+	}
+	return translations, nil
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// markdownExtenders 為各擴充語法對應的 goldmark extension
+var markdownExtenders = map[string]goldmark.Extender{
+	MarkdownExtensionTable:         extension.Table,
+	MarkdownExtensionFootnote:      extension.Footnote,
+	MarkdownExtensionStrikethrough: extension.Strikethrough,
+	MarkdownExtensionLinkify:       extension.Linkify,
+	MarkdownExtensionTaskList:      extension.TaskList,
+}
+
+// newMarkdownConverter 以 goldmark 轉換 Markdown，再以 bluemonday 的 UGC policy 過濾；
+// 內文中的 HTML 會保留到過濾階段，擴充語法產生的屬性 (註腳的錨點、task list 的 checkbox) 另外放行
+func newMarkdownConverter(extensions []string) (func([]byte) ([]byte, error), error) {
+	extenders := make([]goldmark.Extender, 0, len(extensions))
+	for _, ext := range extensions {
+		extenders = append(extenders, markdownExtenders[ext])
+	}
+	md := goldmark.New(goldmark.WithExtensions(extenders...), goldmark.WithRendererOptions(html.WithUnsafe()))
+
+	policy := bluemonday.UGCPolicy()
+	if slices.Contains(extensions, MarkdownExtensionFootnote) {
+		policy.AllowAttrs("id").Matching(regexp.MustCompile(`^fn(ref)?:[0-9]+$`)).OnElements("sup", "li")
+		policy.AllowAttrs("class").Matching(regexp.MustCompile(`^(footnote-ref|footnote-backref|footnotes)$`)).OnElements("a", "div")
+	}
+	if slices.Contains(extensions, MarkdownExtensionTaskList) {
+		policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+		policy.AllowAttrs("checked", "disabled").OnElements("input")
+	}
+
+	return func(source []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := md.Convert(source, &buf); err != nil {
+			return nil, err
+		}
+		return policy.SanitizeBytes(buf.Bytes()), nil
+	}, nil
+}
@@ -0,0 +1,316 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // 註冊 image.DecodeConfig 使用的格式
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxMediaSize is the largest upload MediaService accepts when no
+// limit is configured.
+const DefaultMaxMediaSize = 10 << 20
+
+var (
+	// ErrMediaNotFound is returned when no media matches the lookup.
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrInvalidMedia is returned (wrapped) for an upload that is empty, not
+	// an image of a supported type or cannot be decoded.
+	ErrInvalidMedia = errors.New("invalid media")
+	// ErrMediaTooLarge is returned for an upload over the size limit.
+	ErrMediaTooLarge = errors.New("media too large")
+	// ErrMediaUnsupported is returned by a nil MediaService, i.e. when no
+	// media storage is configured.
+	ErrMediaUnsupported = errors.New("media uploads are not configured")
+)
+
+// mediaTypes 為可上傳的格式與其副檔名；SVG 可含 script，不接受
+var mediaTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// mediaColumns 為查詢 media 時的欄位順序，需與 scanMedia 一致
+const mediaColumns = `id, hash, storage_key, content_type, size, width, height, filename, created_at`
+
+// maxMediaFilenameLength 為記錄的原始檔名長度上限 (字數)
+const maxMediaFilenameLength = 255
+
+// Media is an uploaded image. Image blocks reference it by MediaID (see
+// ContentBlock) and show it at URL. Uploads are deduplicated by the SHA-256
+// Hash of their content, so uploading the same file twice yields the same
+// Media.
+type Media struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"` // WebP 無法以標準函式庫讀取尺寸，為 0
+	Height      int       `json:"height,omitempty"`
+	Hash        string    `json:"hash"`
+	Filename    string    `json:"filename"` // 上傳時的檔名，只供辨識
+	CreatedAt   time.Time `json:"createdAt"`
+	key         string    // 在 ObjectStorage 中的 key
+}
+
+// MediaService validates uploads and stores them in an ObjectStorage, with
+// their metadata in the media table. Uploads and deletions are recorded in
+// the audit log.
+type MediaService struct {
+	db      *sql.DB
+	storage ObjectStorage
+	maxSize int64
+	audit   *AuditLog
+}
+
+// NewMediaService returns a service storing files in storage and metadata
+// in db (see internal/data/migrations). Uploads over maxSize bytes are
+// rejected; 0 means DefaultMaxMediaSize. audit may be nil.
+func NewMediaService(db *sql.DB, storage ObjectStorage, maxSize int64, audit *AuditLog) *MediaService {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMediaSize
+	}
+	return &MediaService{db: db, storage: storage, maxSize: maxSize, audit: audit}
+}
+
+// MaxSize returns the largest accepted upload in bytes.
+func (s *MediaService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload validates the image read from body, stores it and returns its
+// Media. The content type is detected from the content, not taken from
+// filename or the request. created is false when the same content was
+// uploaded before; its existing Media is returned and nothing is stored.
+func (s *MediaService) Upload(ctx context.Context, filename string, body io.Reader) (media *Media, created bool, err error) {
+	if s == nil {
+		return nil, false, ErrMediaUnsupported
+	}
+	content, err := io.ReadAll(io.LimitReader(body, s.maxSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("read upload: %w", err)
+	}
+	if int64(len(content)) > s.maxSize {
+		return nil, false, fmt.Errorf("%w: over %d bytes", ErrMediaTooLarge, s.maxSize)
+	}
+	if len(content) == 0 {
+		return nil, false, fmt.Errorf("%w: empty file", ErrInvalidMedia)
+	}
+	contentType := http.DetectContentType(content)
+	ext, ok := mediaTypes[contentType]
+	if !ok {
+		return nil, false, fmt.Errorf("%w: unsupported type %s", ErrInvalidMedia, contentType)
+	}
+	sum := sha256.Sum256(content)
+	media = &Media{
+		ID:          newUUID(),
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Hash:        hex.EncodeToString(sum[:]),
+		Filename:    mediaFilename(filename),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if contentType != "image/webp" {
+		config, _, err := image.DecodeConfig(bytes.NewReader(content))
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+		}
+		media.Width, media.Height = config.Width, config.Height
+	}
+	// 以雜湊為 key，內容相同的檔案只存一份
+	media.key = media.Hash[:2] + "/" + media.Hash + ext
+
+	if existing, err := s.byHash(ctx, media.Hash); !errors.Is(err, ErrMediaNotFound) {
+		return existing, false, err
+	}
+	if err := s.storage.Put(ctx, media.key, contentType, content); err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	// 同時上傳相同內容時只有一筆寫入成功，其餘回傳先寫入的 media
+	res, err := s.db.ExecContext(ctx, `INSERT INTO media (`+mediaColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (hash) DO NOTHING`,
+		media.ID, media.Hash, media.key, media.ContentType, media.Size, media.Width, media.Height, media.Filename, media.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("create media: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		existing, err := s.byHash(ctx, media.Hash)
+		return existing, false, err
+	}
+	media.URL = s.storage.URL(media.key)
+	s.record(ctx, AuditActionCreate, media.ID, nil, media)
+	return media, true, nil
+}
+
+// mediaFilename 只保留檔名部分，並限制長度與移除無效的 UTF-8
+func mediaFilename(name string) string {
+	name = strings.ToValidUTF8(filepath.Base(strings.ReplaceAll(name, `\`, "/")), "")
+	if name == "." || name == "/" {
+		return ""
+	}
+	for utf8.RuneCountInString(name) > maxMediaFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// Media returns the media with id, or ErrMediaNotFound.
+func (s *MediaService) Media(ctx context.Context, id string) (*Media, error) {
+	if s == nil {
+		return nil, ErrMediaUnsupported
+	}
+	return s.get(ctx, `id = $1`, id)
+}
+
+// MediaByIDs returns the media with each id at the same index, or nil when
+// there is none, in a single query.
+func (s *MediaService) MediaByIDs(ctx context.Context, ids []string) ([]*Media, error) {
+	if s == nil {
+		return nil, ErrMediaUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+mediaColumns+` FROM media WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	defer rows.Close()
+
+	byID := map[string]*Media{}
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan media: %w", err)
+		}
+		media.URL = s.storage.URL(media.key)
+		byID[media.ID] = media
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	list := make([]*Media, len(ids))
+	for i, id := range ids {
+		list[i] = byID[id]
+	}
+	return list, nil
+}
+
+// byHash 回傳內容雜湊為 hash 的 media
+func (s *MediaService) byHash(ctx context.Context, hash string) (*Media, error) {
+	return s.get(ctx, `hash = $1`, hash)
+}
+
+// get 回傳符合條件的一筆 media
+func (s *MediaService) get(ctx context.Context, cond string, arg interface{}) (*Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	media, err := scanMedia(s.db.QueryRowContext(ctx, `SELECT `+mediaColumns+` FROM media WHERE `+cond, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+	media.URL = s.storage.URL(media.key)
+	return media, nil
+}
+
+// List returns uploaded media, newest first.
+func (s *MediaService) List(ctx context.Context, limit, offset int) ([]Media, error) {
+	if s == nil {
+		return nil, ErrMediaUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+mediaColumns+` FROM media ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list media: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Media{}
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan media: %w", err)
+		}
+		media.URL = s.storage.URL(media.key)
+		list = append(list, *media)
+	}
+	return list, rows.Err()
+}
+
+// Delete removes the media with id and its file, or returns
+// ErrMediaNotFound. Only editors may delete media. Stories still showing it
+// keep its URL, which no longer resolves.
+func (s *MediaService) Delete(ctx context.Context, id string, role StoryRole) error {
+	if s == nil {
+		return ErrMediaUnsupported
+	}
+	if err := requireEditor(role, "delete media"); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	media, err := scanMedia(s.db.QueryRowContext(ctx, `DELETE FROM media WHERE id = $1 RETURNING `+mediaColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMediaNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("delete media: %w", err)
+	}
+	media.URL = s.storage.URL(media.key)
+	if err := s.storage.Delete(ctx, media.key); err != nil {
+		// 資料已刪除，檔案留在儲存空間中只記錄日誌
+		slog.Warn("failed to delete media object", "id", id, "key", media.key, "error", err)
+	}
+	s.record(ctx, AuditActionDelete, id, media, nil)
+	return nil
+}
+
+// record 將 media 的寫入記錄到稽核紀錄；失敗時只記錄日誌
+func (s *MediaService) record(ctx context.Context, action, id string, before, after *Media) {
+	var beforeValue, afterValue interface{}
+	if before != nil {
+		beforeValue = before
+	}
+	if after != nil {
+		afterValue = after
+	}
+	if err := s.audit.Record(context.WithoutCancel(ctx), action, AuditEntityMedia, id, beforeValue, afterValue); err != nil {
+		slog.Warn("failed to record media audit entry", "id", id, "action", action, "error", err)
+	}
+}
+
+// scanMedia 依 mediaColumns 的順序讀取一筆 media
+func scanMedia(row rowScanner) (*Media, error) {
+	var media Media
+	if err := row.Scan(&media.ID, &media.Hash, &media.key, &media.ContentType, &media.Size, &media.Width, &media.Height,
+		&media.Filename, &media.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
@@ -0,0 +1,334 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Media storage backends, selected with MEDIA_STORAGE.
+const (
+	MediaStorageLocal = "local"
+	MediaStorageS3    = "s3"
+	MediaStorageGCS   = "gcs"
+)
+
+// ErrObjectNotFound is returned by ObjectStorage.Get for a missing object.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStorage stores uploaded media files under keys such as
+// "ab/abcdef….jpg". Objects are written once and never modified, so they can
+// be cached forever.
+type ObjectStorage interface {
+	// Put stores body under key, replacing an object with the same key.
+	Put(ctx context.Context, key, contentType string, body []byte) error
+	// Get returns the content of the object with key, or ErrObjectNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object with key; a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the object with key.
+	URL(key string) string
+}
+
+// ObjectStorageConfig configures the backend returned by OpenObjectStorage.
+type ObjectStorageConfig struct {
+	Dir             string // local：存放檔案的目錄
+	PublicURL       string // 物件網址的前綴；local 必填，S3 / GCS 未設定時為 bucket 的網址
+	Endpoint        string // S3：API 位址，未設定時為 AWS 的區域位址；GCS 固定為 storage.googleapis.com
+	Region          string
+	Bucket          string
+	AccessKeyID     string // GCS 為 HMAC key
+	SecretAccessKey string
+}
+
+// OpenObjectStorage returns the ObjectStorage for backend: files in
+// cfg.Dir, an S3 (or S3-compatible, such as MinIO or R2) bucket, or a Google
+// Cloud Storage bucket through its S3-compatible XML API with HMAC keys.
+func OpenObjectStorage(backend string, cfg ObjectStorageConfig) (ObjectStorage, error) {
+	switch backend {
+	case MediaStorageLocal:
+		if cfg.Dir == "" || cfg.PublicURL == "" {
+			return nil, errors.New("MEDIA_LOCAL_DIR and MEDIA_PUBLIC_URL are required for the local media storage")
+		}
+		// 圖片 block 的網址須為絕對網址；檔案由本服務在該路徑下提供，不可為根路徑
+		u, err := url.Parse(cfg.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return nil, fmt.Errorf("MEDIA_PUBLIC_URL %q is not an absolute http(s) URL with a path", cfg.PublicURL)
+		}
+		return NewLocalObjectStorage(cfg.Dir, cfg.PublicURL), nil
+	case MediaStorageS3, MediaStorageGCS:
+		if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+			return nil, fmt.Errorf("MEDIA_BUCKET, MEDIA_ACCESS_KEY_ID and MEDIA_SECRET_ACCESS_KEY are required for the %s media storage", backend)
+		}
+		if backend == MediaStorageGCS {
+			cfg.Endpoint, cfg.Region = "https://storage.googleapis.com", "auto"
+		}
+		return NewS3ObjectStorage(cfg)
+	}
+	return nil, fmt.Errorf("unknown media storage %q", backend)
+}
+
+// LocalObjectStorage keeps objects as files in a directory, served by the
+// application itself under the path of its public URL (see main.go).
+type LocalObjectStorage struct {
+	dir       string
+	publicURL string
+}
+
+// NewLocalObjectStorage returns a storage writing to dir whose objects are
+// served at publicURL, e.g. https://api.example.com/media.
+func NewLocalObjectStorage(dir, publicURL string) *LocalObjectStorage {
+	return &LocalObjectStorage{dir: dir, publicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+// Dir returns the directory the objects are stored in.
+func (s *LocalObjectStorage) Dir() string {
+	return s.dir
+}
+
+// PublicURL returns the URL prefix the objects are served at.
+func (s *LocalObjectStorage) PublicURL() string {
+	return s.publicURL
+}
+
+// path 回傳 key 對應的檔案路徑；key 不得跳出目錄
+func (s *LocalObjectStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// Put writes the object to a temporary file first, so readers never see a
+// partial file.
+func (s *LocalObjectStorage) Put(ctx context.Context, key, contentType string, body []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("put object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalObjectStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+	return body, nil
+}
+
+func (s *LocalObjectStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalObjectStorage) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+// S3ObjectStorage stores objects in an S3 bucket with path-style requests
+// signed with AWS Signature Version 4, which S3-compatible services and the
+// Google Cloud Storage XML API also accept.
+type S3ObjectStorage struct {
+	client    *http.Client
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	publicURL string
+}
+
+// NewS3ObjectStorage returns a storage for cfg.Bucket. An empty
+// cfg.Endpoint means AWS S3 in cfg.Region (default us-east-1).
+func NewS3ObjectStorage(cfg ObjectStorageConfig) (*S3ObjectStorage, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid media storage endpoint %q", cfg.Endpoint)
+	}
+	s := &S3ObjectStorage{
+		client:    &http.Client{Timeout: 60 * time.Second},
+		endpoint:  strings.TrimSuffix(cfg.Endpoint, "/"),
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		publicURL: strings.TrimSuffix(cfg.PublicURL, "/"),
+	}
+	if s.publicURL == "" {
+		s.publicURL = s.endpoint + "/" + s.bucket
+	}
+	return s, nil
+}
+
+// Put uploads the object with a long-lived Cache-Control, as objects are
+// addressed by their content.
+func (s *S3ObjectStorage) Put(ctx context.Context, key, contentType string, body []byte) error {
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, err := s.do(ctx, http.MethodPut, key, headers, body, http.StatusOK)
+	return err
+}
+
+func (s *S3ObjectStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, http.Header{}, nil, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status == http.StatusNotFound {
+		return nil, ErrObjectNotFound
+	}
+	return resp.body, nil
+}
+
+func (s *S3ObjectStorage) Delete(ctx context.Context, key string) error {
+	// S3 刪除不存在的物件時同樣回應 204
+	_, err := s.do(ctx, http.MethodDelete, key, http.Header{}, nil, http.StatusNoContent, http.StatusNotFound)
+	return err
+}
+
+func (s *S3ObjectStorage) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+// s3Response 為 S3 的回應狀態碼與內容
+type s3Response struct {
+	status int
+	body   []byte
+}
+
+// s3MaxObjectSize 為讀取物件的大小上限
+const s3MaxObjectSize = 64 << 20
+
+// do 送出簽署後的請求，回應的狀態碼需為 ok 之一
+func (s *S3ObjectStorage) do(ctx context.Context, method, key string, headers http.Header, body []byte, ok ...int) (*s3Response, error) {
+	path := "/" + s3EscapePath(s.bucket+"/"+key)
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	s.sign(req, path, body, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s object: %w", strings.ToLower(method), err)
+	}
+	defer resp.Body.Close()
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			content, err := io.ReadAll(io.LimitReader(resp.Body, s3MaxObjectSize))
+			if err != nil {
+				return nil, fmt.Errorf("%s object: %w", strings.ToLower(method), err)
+			}
+			return &s3Response{status: status, body: content}, nil
+		}
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("%s object: %s: %s", strings.ToLower(method), resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// sign 以 AWS Signature Version 4 簽署請求；簽署 host、x-amz-content-sha256 與 x-amz-date
+func (s *S3ObjectStorage) sign(req *http.Request, path string, body []byte, now time.Time) {
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	hmacSHA256 := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// s3EscapePath 依 SigV4 的規則跳脫路徑：保留英數字、-._~ 與 /
+func s3EscapePath(path string) string {
+	var sb strings.Builder
+	for _, b := range []byte(path) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9', strings.IndexByte("-._~/", b) >= 0:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,21 @@
+-- stories：由 go-story 自行管理的文章
+CREATE TABLE IF NOT EXISTS stories (
+    id           TEXT PRIMARY KEY,
+    slug         TEXT NOT NULL UNIQUE,
+    title        TEXT NOT NULL,
+    subtitle     TEXT NOT NULL DEFAULT '',
+    summary      TEXT NOT NULL DEFAULT '',
+    body         TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL DEFAULT 'draft',
+    section      TEXT NOT NULL DEFAULT '',
+    tags         JSONB NOT NULL DEFAULT '[]',
+    cover_image  TEXT NOT NULL DEFAULT '',
+    is_member    BOOLEAN NOT NULL DEFAULT FALSE,
+    published_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS stories_status_published_at_idx ON stories (status, published_at DESC);
+CREATE INDEX IF NOT EXISTS stories_section_idx ON stories (section);
+CREATE INDEX IF NOT EXISTS stories_tags_idx ON stories USING GIN (tags);
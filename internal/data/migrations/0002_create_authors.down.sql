@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS story_authors;
+DROP TABLE IF EXISTS authors;
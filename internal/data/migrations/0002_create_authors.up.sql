@@ -0,0 +1,18 @@
+-- authors：story 的作者
+CREATE TABLE IF NOT EXISTS authors (
+    id         TEXT PRIMARY KEY,
+    slug       TEXT NOT NULL UNIQUE,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- story_authors：story 與作者的對應，position 為署名順序
+CREATE TABLE IF NOT EXISTS story_authors (
+    story_id  TEXT NOT NULL REFERENCES stories (id) ON DELETE CASCADE,
+    author_id TEXT NOT NULL REFERENCES authors (id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (story_id, author_id)
+);
+
+CREATE INDEX IF NOT EXISTS story_authors_author_id_idx ON story_authors (author_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS stories_updated_at_idx;
+DROP INDEX IF EXISTS stories_view_count_idx;
+ALTER TABLE stories DROP COLUMN IF EXISTS view_count;
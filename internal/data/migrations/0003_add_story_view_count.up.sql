@@ -0,0 +1,5 @@
+-- view_count：story 的瀏覽數，供依熱門程度排序
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS view_count BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS stories_view_count_idx ON stories (view_count DESC, id DESC);
+CREATE INDEX IF NOT EXISTS stories_updated_at_idx ON stories (updated_at DESC, id DESC);
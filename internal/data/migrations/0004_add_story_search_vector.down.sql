@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS stories_search_vector_idx;
+ALTER TABLE stories DROP COLUMN IF EXISTS search_vector;
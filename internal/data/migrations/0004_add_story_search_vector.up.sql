@@ -0,0 +1,8 @@
+-- search_vector：全文搜尋用的 tsvector，權重 A (title) > B (subtitle、summary) > C (body)
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', title), 'A') ||
+    setweight(to_tsvector('simple', subtitle || ' ' || summary), 'B') ||
+    setweight(to_tsvector('simple', body), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS stories_search_vector_idx ON stories USING GIN (search_vector);
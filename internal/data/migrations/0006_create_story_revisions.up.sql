@@ -0,0 +1,20 @@
+-- story_revisions：story 每次新增或修改後的完整內容，寫入後不再變更；number 依 story 自 1 起遞增
+CREATE TABLE IF NOT EXISTS story_revisions (
+    story_id   TEXT NOT NULL REFERENCES stories (id) ON DELETE CASCADE,
+    number     INTEGER NOT NULL,
+    snapshot   JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (story_id, number)
+);
+
+-- 既有的 story 以目前內容作為第一個版本，snapshot 的格式與 data.Story 的 JSON 相同
+INSERT INTO story_revisions (story_id, number, snapshot, created_at)
+SELECT s.id, 1, jsonb_build_object(
+        'id', s.id, 'slug', s.slug, 'title', s.title, 'subtitle', s.subtitle, 'summary', s.summary,
+        'body', s.body, 'status', s.status, 'section', s.section, 'tags', s.tags,
+        'authorIds', COALESCE((SELECT jsonb_agg(sa.author_id ORDER BY sa.position) FROM story_authors sa WHERE sa.story_id = s.id), '[]'::jsonb),
+        'coverImage', s.cover_image, 'isMember', s.is_member, 'publishedAt', s.published_at,
+        'createdAt', s.created_at, 'updatedAt', s.updated_at, 'viewCount', 0),
+    s.updated_at
+FROM stories s
+ON CONFLICT DO NOTHING;
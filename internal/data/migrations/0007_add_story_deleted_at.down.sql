@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS stories_deleted_at_idx;
+-- 還原前先永久刪除垃圾桶中的 story，避免降版後重新出現
+DELETE FROM stories WHERE deleted_at IS NOT NULL;
+ALTER TABLE stories DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+-- deleted_at：story 移至垃圾桶的時間，NULL 表示未刪除；垃圾桶中的 story 不出現在任何查詢中，可還原或永久刪除
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS stories_deleted_at_idx ON stories (deleted_at DESC, id DESC) WHERE deleted_at IS NOT NULL;
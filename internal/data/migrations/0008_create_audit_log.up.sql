@@ -0,0 +1,15 @@
+-- audit_log：所有寫入操作的稽核紀錄，只新增不修改；before / after 為寫入前後的內容 (新增時 before 為 NULL，刪除時 after 為 NULL)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id         BIGSERIAL PRIMARY KEY,
+    actor      TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    entity     TEXT NOT NULL,
+    entity_id  TEXT NOT NULL,
+    before     JSONB,
+    after      JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity, entity_id, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, id DESC);
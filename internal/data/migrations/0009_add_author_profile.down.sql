@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS authors_name_idx;
+ALTER TABLE authors DROP COLUMN IF EXISTS social_links;
+ALTER TABLE authors DROP COLUMN IF EXISTS avatar;
+ALTER TABLE authors DROP COLUMN IF EXISTS bio;
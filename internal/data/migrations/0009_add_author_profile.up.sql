@@ -0,0 +1,6 @@
+-- 作者頁面的個人資料：簡介、頭像與社群連結 ([{"network": "x", "url": "https://..."}])
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS avatar TEXT NOT NULL DEFAULT '';
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS social_links JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS authors_name_idx ON authors (name, id);
@@ -0,0 +1,22 @@
+-- taxonomy_terms：tag 與分類 (category) 的名稱與階層；story 以 stories.tags / stories.section 中的 slug 關聯
+-- parent 只用於分類，改名時隨 ON UPDATE CASCADE 更新
+CREATE TABLE IF NOT EXISTS taxonomy_terms (
+    kind       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    parent     TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (kind, slug),
+    FOREIGN KEY (kind, parent) REFERENCES taxonomy_terms (kind, slug) ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS taxonomy_terms_name_idx ON taxonomy_terms (kind, name, slug);
+
+-- 既有 story 使用的 tag 與 section 建為 term，名稱先與 slug 相同
+INSERT INTO taxonomy_terms (kind, slug, name)
+SELECT DISTINCT 'tag', t.tag, t.tag FROM stories s, jsonb_array_elements_text(s.tags) AS t(tag) WHERE t.tag <> ''
+ON CONFLICT DO NOTHING;
+INSERT INTO taxonomy_terms (kind, slug, name)
+SELECT DISTINCT 'category', s.section, s.section FROM stories s WHERE s.section <> ''
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,22 @@
+-- collections：依閱讀順序排列的 story 合集，例如多篇的專題報導
+CREATE TABLE IF NOT EXISTS collections (
+    id          TEXT PRIMARY KEY,
+    slug        TEXT NOT NULL UNIQUE,
+    title       TEXT NOT NULL,
+    intro       TEXT NOT NULL DEFAULT '',
+    cover_image TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS collections_updated_at_idx ON collections (updated_at DESC, id DESC);
+
+-- collection_stories：合集與 story 的對應，position 為閱讀順序；story 永久刪除時一併移除
+CREATE TABLE IF NOT EXISTS collection_stories (
+    collection_id TEXT NOT NULL REFERENCES collections (id) ON DELETE CASCADE,
+    story_id      TEXT NOT NULL REFERENCES stories (id) ON DELETE CASCADE,
+    position      INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (collection_id, story_id)
+);
+
+CREATE INDEX IF NOT EXISTS collection_stories_story_id_idx ON collection_stories (story_id);
@@ -0,0 +1,2 @@
+-- blocks：結構化的 story 內容 ([{"type": "paragraph", "text": "..."}])；以 block 撰寫時 body 為其轉換的 HTML
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS blocks JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,2 @@
+ALTER TABLE stories DROP COLUMN IF EXISTS reading_time;
+ALTER TABLE stories DROP COLUMN IF EXISTS word_count;
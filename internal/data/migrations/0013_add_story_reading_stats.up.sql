@@ -0,0 +1,3 @@
+-- word_count / reading_time：寫入時依 body 計算的字數與預估閱讀時間 (分鐘)；既有的 story 為 0，讀取時補算，下次修改時寫入
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS word_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS reading_time INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- excerpt：summary，沒有時為寫入時由 body 產生的摘要 (或 SummaryGenerator 產生的摘要)；既有的 story 為空字串，讀取時補算
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS excerpt TEXT NOT NULL DEFAULT '';
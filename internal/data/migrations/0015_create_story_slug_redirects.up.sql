@@ -0,0 +1,9 @@
+-- story_slug_redirects：story 改名前的 slug，GetBySlug 找不到 story 時依此找出改名後的 story；
+-- slug 被其他 story 使用時刪除，story 永久刪除時一併刪除
+CREATE TABLE IF NOT EXISTS story_slug_redirects (
+    slug       TEXT PRIMARY KEY,
+    story_id   TEXT NOT NULL REFERENCES stories (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS story_slug_redirects_story_id_idx ON story_slug_redirects (story_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS stories_translation_group_idx;
+DROP INDEX IF EXISTS stories_locale_published_at_idx;
+ALTER TABLE stories DROP COLUMN IF EXISTS translation_group;
+ALTER TABLE stories DROP COLUMN IF EXISTS locale;
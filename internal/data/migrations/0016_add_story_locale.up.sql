@@ -0,0 +1,6 @@
+-- locale：BCP 47 語言，空字串表示網站的語言 (SITE_LANGUAGE)；translation_group：同一篇文章各語言版本共用的識別字
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS translation_group TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS stories_locale_published_at_idx ON stories (locale, published_at DESC);
+CREATE INDEX IF NOT EXISTS stories_translation_group_idx ON stories (translation_group) WHERE translation_group <> '';
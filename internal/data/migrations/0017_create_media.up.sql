@@ -0,0 +1,14 @@
+-- media：上傳的圖片，檔案存放於 ObjectStorage 的 storage_key；hash 為內容的 SHA-256，用於去除重複
+CREATE TABLE IF NOT EXISTS media (
+    id           TEXT PRIMARY KEY,
+    hash         TEXT NOT NULL UNIQUE,
+    storage_key  TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size         BIGINT NOT NULL,
+    width        INTEGER NOT NULL DEFAULT 0,
+    height       INTEGER NOT NULL DEFAULT 0,
+    filename     TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS media_created_at_idx ON media (created_at DESC, id DESC);
@@ -0,0 +1,18 @@
+-- comments：story 的讀者留言；parent_id 為回覆的留言，root_id 為討論串最上層的留言 (最上層留言為 NULL)
+-- status 為 pending (待審)、approved (公開) 或 hidden (隱藏)；user_id 為前台登入系統的使用者 ID
+CREATE TABLE IF NOT EXISTS comments (
+    id          TEXT PRIMARY KEY,
+    story_id    TEXT NOT NULL,
+    parent_id   TEXT REFERENCES comments (id) ON DELETE CASCADE,
+    root_id     TEXT REFERENCES comments (id) ON DELETE CASCADE,
+    user_id     TEXT NOT NULL,
+    author_name TEXT NOT NULL DEFAULT '',
+    body        TEXT NOT NULL,
+    status      TEXT NOT NULL DEFAULT 'pending',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS comments_story_roots_idx ON comments (story_id, created_at DESC, id DESC) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS comments_root_id_idx ON comments (root_id, created_at, id);
+CREATE INDEX IF NOT EXISTS comments_status_idx ON comments (status, created_at DESC, id DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS story_reaction_counts;
+DROP TABLE IF EXISTS story_reactions;
@@ -0,0 +1,16 @@
+-- story_reactions：每位使用者對 story 的回應 (例如 like、clap)，同一種回應只計一次；user_id 為前台登入系統的使用者 ID
+CREATE TABLE IF NOT EXISTS story_reactions (
+    story_id   TEXT NOT NULL,
+    user_id    TEXT NOT NULL,
+    reaction   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (story_id, user_id, reaction)
+);
+
+-- story_reaction_counts：各 story 每種回應的總數，由 Redis 中累計的增減定期批次寫入
+CREATE TABLE IF NOT EXISTS story_reaction_counts (
+    story_id TEXT NOT NULL,
+    reaction TEXT NOT NULL,
+    count    BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (story_id, reaction)
+);
@@ -0,0 +1,9 @@
+-- bookmarks：讀者收藏的 story；user_id 為前台登入系統的使用者 ID
+CREATE TABLE IF NOT EXISTS bookmarks (
+    user_id    TEXT NOT NULL,
+    story_id   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, story_id)
+);
+
+CREATE INDEX IF NOT EXISTS bookmarks_user_created_at_idx ON bookmarks (user_id, created_at DESC, story_id DESC);
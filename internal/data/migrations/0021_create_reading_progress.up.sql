@@ -0,0 +1,12 @@
+-- reading_progress：讀者在各 story 的閱讀位置，供跨裝置繼續閱讀；position 為捲動比例 (0–1)，anchor 為最近的標題 id
+-- 最新的位置先寫入 Redis，此表為 Redis 無法使用或資料過期時的備援，可能落後最多一個寫入間隔
+CREATE TABLE IF NOT EXISTS reading_progress (
+    user_id    TEXT NOT NULL,
+    story_id   TEXT NOT NULL,
+    position   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    anchor     TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, story_id)
+);
+
+CREATE INDEX IF NOT EXISTS reading_progress_user_updated_at_idx ON reading_progress (user_id, updated_at DESC);
@@ -0,0 +1,8 @@
+-- push_devices：iOS App 註冊的 APNs device token，推播時逐一送出；APNs 回報失效的 token 會被刪除
+-- user_id 為前台登入系統的使用者 ID，未登入時為空字串
+CREATE TABLE IF NOT EXISTS push_devices (
+    token      TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
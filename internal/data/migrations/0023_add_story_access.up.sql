@@ -0,0 +1,3 @@
+-- access：閱讀權限，free (免費)、metered (計次，未訂閱的訪客每段期間可讀有限篇數) 或 members (限會員)；既有的會員文章為 members
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS access TEXT NOT NULL DEFAULT 'free';
+UPDATE stories SET access = 'members' WHERE is_member AND access = 'free';
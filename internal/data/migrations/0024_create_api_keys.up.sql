@@ -0,0 +1,12 @@
+-- api_keys：發給機器用戶端的 API key，只保存 key 的 SHA-256 hash 與開頭 (prefix) 供辨識
+-- scopes 為 key 的權限，rate_limit 為每個時間窗的請求上限 (0 表示使用預設值)，revoked_at 不為空時已撤銷
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    prefix     TEXT NOT NULL,
+    key_hash   TEXT NOT NULL UNIQUE,
+    scopes     JSONB NOT NULL DEFAULT '[]'::jsonb,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1 @@
+ALTER TABLE stories DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,5 @@
+-- stories.owner_id：建立 story 的使用者 (JWT 的 sub 或工作流程 API 的帳號)，作者只能修改自己的草稿
+-- 既有的 story 沒有擁有者，只有編輯可以修改
+ALTER TABLE stories ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS stories_owner_idx ON stories (owner_id) WHERE owner_id <> '';
@@ -0,0 +1,26 @@
+-- oidc_logins：進行中的管理介面登入 (OIDC authorization code + PKCE)，以 state 的 SHA-256 hash 查詢，使用一次即刪除
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state_hash    TEXT PRIMARY KEY,
+    nonce         TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    return_to     TEXT NOT NULL DEFAULT '',
+    expires_at    TIMESTAMPTZ NOT NULL
+);
+
+-- admin_sessions：管理介面的登入 session，只保存 session token 的 SHA-256 hash
+-- groups 與 role 為身分提供者的群組與對應的角色，refresh_at 為下次以 refresh_token 向身分提供者確認身分的時間
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id            TEXT PRIMARY KEY,
+    token_hash    TEXT NOT NULL UNIQUE,
+    subject       TEXT NOT NULL,
+    email         TEXT NOT NULL DEFAULT '',
+    name          TEXT NOT NULL DEFAULT '',
+    groups        JSONB NOT NULL DEFAULT '[]'::jsonb,
+    role          TEXT NOT NULL,
+    refresh_token TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    refresh_at    TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS admin_sessions_expires_idx ON admin_sessions (expires_at);
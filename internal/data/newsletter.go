@@ -0,0 +1,325 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidDigestPeriod is returned (wrapped) for a period other than
+	// daily and weekly.
+	ErrInvalidDigestPeriod = errors.New("invalid digest period")
+	// ErrEmptyDigest is returned by DigestService.Send when no story was
+	// published in the period, so there is nothing to send.
+	ErrEmptyDigest = errors.New("no stories for the digest")
+	// ErrDigestSenderUnset is returned by DigestService.Send when no
+	// DigestSender is configured.
+	ErrDigestSenderUnset = errors.New("no digest sender configured")
+)
+
+// DigestPeriod is how often a newsletter digest goes out, and how far back
+// it picks stories from.
+type DigestPeriod string
+
+// Digest periods.
+const (
+	DigestDaily  DigestPeriod = "daily"
+	DigestWeekly DigestPeriod = "weekly"
+)
+
+// window 回傳 period 涵蓋的時間長度；不支援的 period 回傳 0
+func (p DigestPeriod) window() time.Duration {
+	switch p {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// defaultDigestStoriesPerSection 為每個 section 預設列出的 story 數
+const defaultDigestStoriesPerSection = 3
+
+// Digest is a newsletter digest before rendering: the most read stories
+// published in [From, To), grouped by section.
+type Digest struct {
+	Period      DigestPeriod    `json:"period"`
+	Subject     string          `json:"subject"`
+	SiteTitle   string          `json:"siteTitle"`
+	SiteURL     string          `json:"siteUrl"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Sections    []DigestSection `json:"sections"`
+}
+
+// DigestSection is a section of a Digest. Name is empty for stories
+// without a section.
+type DigestSection struct {
+	Name    string        `json:"name"`
+	Stories []DigestStory `json:"stories"`
+}
+
+// DigestStory is a story in a DigestSection, most read first.
+type DigestStory struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Excerpt     string    `json:"excerpt"`
+	Image       string    `json:"image,omitempty"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// DigestEmail is what a DigestSender hands to an email provider: the digest
+// as data and rendered as an HTML email.
+type DigestEmail struct {
+	Subject string  `json:"subject"`
+	HTML    string  `json:"html"`
+	Digest  *Digest `json:"digest"`
+}
+
+// DigestSender hands a digest to an email provider, which sends it to the
+// subscribers.
+type DigestSender interface {
+	SendDigest(ctx context.Context, email *DigestEmail) error
+}
+
+// DigestConfig configures a DigestService.
+type DigestConfig struct {
+	// Sections lists the sections of the digest in order; empty includes
+	// every section, ordered by their most read story.
+	Sections []string
+	// StoriesPerSection is the number of stories per section; 0 uses 3.
+	StoriesPerSection int
+}
+
+// DigestService assembles daily and weekly newsletter digests of the most
+// read stories published in the period, by section, and renders them as
+// HTML email. Digests are cached per period and hour under the story cache
+// prefix, so a preview matches what is sent in the same hour and story
+// writes purge them.
+type DigestService struct {
+	stories *StoryService
+	cache   *Cache
+	site    Site
+	sender  DigestSender
+	cfg     DigestConfig
+}
+
+// NewDigestService returns a service building digests of stories that
+// link to site. sender may be nil, which makes Send fail with
+// ErrDigestSenderUnset.
+func NewDigestService(stories *StoryService, cache *Cache, site Site, sender DigestSender, cfg DigestConfig) *DigestService {
+	if cfg.StoriesPerSection <= 0 {
+		cfg.StoriesPerSection = defaultDigestStoriesPerSection
+	}
+	return &DigestService{stories: stories, cache: cache, site: site, sender: sender, cfg: cfg}
+}
+
+// Digest returns the digest of period ending at the current hour.
+func (s *DigestService) Digest(ctx context.Context, period DigestPeriod) (*Digest, error) {
+	window := period.window()
+	if window == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidDigestPeriod, period)
+	}
+	to := time.Now().UTC().Truncate(time.Hour)
+	key := NewCacheKey(storyCachePrefix + "digest").Fields(map[string]interface{}{"period": period, "to": to}).ShortHash().String()
+	return NewTypedCache[*Digest](s.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) (*Digest, error) {
+		return s.build(ctx, period, to.Add(-window), to)
+	})
+}
+
+// Email returns the digest of period rendered as an HTML email.
+func (s *DigestService) Email(ctx context.Context, period DigestPeriod) (*DigestEmail, error) {
+	digest, err := s.Digest(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := digestTemplate.Execute(&buf, digest); err != nil {
+		return nil, fmt.Errorf("render digest: %w", err)
+	}
+	return &DigestEmail{Subject: digest.Subject, HTML: buf.String(), Digest: digest}, nil
+}
+
+// Send renders the digest of period and hands it to the DigestSender. An
+// empty digest is not sent and returns ErrEmptyDigest.
+func (s *DigestService) Send(ctx context.Context, period DigestPeriod) (*DigestEmail, error) {
+	if s.sender == nil {
+		return nil, ErrDigestSenderUnset
+	}
+	email, err := s.Email(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	if len(email.Digest.Sections) == 0 {
+		return nil, ErrEmptyDigest
+	}
+	if err := s.sender.SendDigest(ctx, email); err != nil {
+		return nil, fmt.Errorf("send digest: %w", err)
+	}
+	return email, nil
+}
+
+// build 讀取期間內發布、依瀏覽數排序的 story，依 section 分組
+func (s *DigestService) build(ctx context.Context, period DigestPeriod, from, to time.Time) (*Digest, error) {
+	gte, lt := from.Format(time.RFC3339), to.Format(time.RFC3339)
+	stories, err := s.stories.Stories(ctx, "", StoryListOptions{
+		Where:   &StoryWhereInput{PublishedAt: &DateTimeRangeFilter{Gte: &gte, Lt: &lt}},
+		OrderBy: []OrderRule{{Field: StorySortPopularity, Direction: "desc"}},
+		Limit:   maxStoryLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &Digest{
+		Period:      period,
+		Subject:     s.subject(period, to),
+		SiteTitle:   s.site.Title,
+		SiteURL:     s.site.URL,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    []DigestSection{},
+	}
+	// 未指定 section 時依各 section 最熱門的 story 排序，也就是第一次出現的順序
+	order := s.cfg.Sections
+	bySection := map[string][]DigestStory{}
+	for i := range stories {
+		story := &stories[i]
+		if len(s.cfg.Sections) == 0 && bySection[story.Section] == nil {
+			order = append(order, story.Section)
+		}
+		if len(bySection[story.Section]) == s.cfg.StoriesPerSection {
+			continue
+		}
+		item := DigestStory{
+			ID:      story.ID,
+			Title:   story.Title,
+			URL:     s.site.StoryURL(story.Slug),
+			Excerpt: story.Excerpt,
+			Image:   story.CoverImage,
+		}
+		if story.PublishedAt != nil {
+			item.PublishedAt = *story.PublishedAt
+		}
+		bySection[story.Section] = append(bySection[story.Section], item)
+	}
+	for _, name := range order {
+		if items := bySection[name]; len(items) > 0 {
+			digest.Sections = append(digest.Sections, DigestSection{Name: name, Stories: items})
+		}
+	}
+	return digest, nil
+}
+
+// subject 組成郵件主旨，例如「網站名稱 daily digest, 2024-05-01」
+func (s *DigestService) subject(period DigestPeriod, to time.Time) string {
+	// 期間結束於 to，日期以最後一天表示
+	return s.site.Title + " " + string(period) + " digest, " + to.Add(-time.Second).Format("2006-01-02")
+}
+
+// digestTemplate 為 HTML 郵件：以 table 排版並使用 inline style，相容於常見的郵件軟體
+var digestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Subject}}</title>
+</head>
+<body style="margin:0;padding:0;background:#f4f4f4;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="background:#f4f4f4;">
+<tr><td align="center" style="padding:24px 12px;">
+<table role="presentation" width="600" cellpadding="0" cellspacing="0" style="max-width:600px;width:100%;background:#ffffff;font-family:Helvetica,Arial,sans-serif;color:#222222;">
+<tr><td style="padding:24px;border-bottom:1px solid #eeeeee;">
+<a href="{{.SiteURL}}" style="font-size:22px;font-weight:bold;color:#222222;text-decoration:none;">{{.SiteTitle}}</a>
+<div style="font-size:14px;color:#666666;padding-top:4px;">{{.Subject}}</div>
+</td></tr>
+{{- range .Sections}}
+{{- if .Name}}
+<tr><td style="padding:24px 24px 0;font-size:13px;font-weight:bold;letter-spacing:1px;text-transform:uppercase;color:#888888;">{{.Name}}</td></tr>
+{{- end}}
+{{- range .Stories}}
+<tr><td style="padding:16px 24px;">
+{{- if .Image}}
+<a href="{{.URL}}"><img src="{{.Image}}" alt="" width="552" style="display:block;width:100%;max-width:552px;height:auto;border:0;margin-bottom:12px;"></a>
+{{- end}}
+<a href="{{.URL}}" style="font-size:18px;font-weight:bold;color:#222222;text-decoration:none;">{{.Title}}</a>
+{{- if .Excerpt}}
+<p style="font-size:15px;line-height:1.5;color:#444444;margin:8px 0 0;">{{.Excerpt}}</p>
+{{- end}}
+</td></tr>
+{{- end}}
+{{- end}}
+<tr><td style="padding:24px;border-top:1px solid #eeeeee;font-size:12px;color:#999999;">
+<a href="{{.SiteURL}}" style="color:#999999;">{{.SiteTitle}}</a>
+</td></tr>
+</table>
+</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// DigestSignatureHeader and DigestTimestampHeader carry the signature of a
+// digest POSTed by HTTPDigestSender, computed like webhook signatures (see
+// SignWebhookPayload).
+const (
+	DigestTimestampHeader = WebhookTimestampHeader
+	DigestSignatureHeader = WebhookSignatureHeader
+)
+
+// HTTPDigestSender POSTs a DigestEmail as JSON to an endpoint, such as an
+// email provider's campaign API or a small adapter in front of it. With a
+// secret the request is signed like a webhook delivery.
+type HTTPDigestSender struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewHTTPDigestSender returns a sender POSTing to url, signing with secret
+// when not empty.
+func NewHTTPDigestSender(url, secret string) *HTTPDigestSender {
+	return &HTTPDigestSender{url: url, secret: secret, client: &http.Client{Timeout: webhookRequestTimeout}}
+}
+
+// SendDigest POSTs email and fails on a non-2xx response.
+func (s *HTTPDigestSender) SendDigest(ctx context.Context, email *DigestEmail) error {
+	body, err := json.Marshal(email)
+	if err != nil {
+		return fmt.Errorf("marshal digest: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-story-newsletter")
+	if s.secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(DigestTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(DigestSignatureHeader, SignWebhookPayload(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOEmbedUnsupported is returned by OEmbedResolver.Resolve for a URL of a
+// site without a configured oEmbed provider.
+var ErrOEmbedUnsupported = errors.New("no oembed provider for url")
+
+// OEmbed is the resolved oEmbed payload of an embed block: the provider's
+// embed HTML and the metadata apps need to lay it out themselves.
+type OEmbed struct {
+	Type            string    `json:"type"` // video、rich 或 photo
+	Provider        string    `json:"provider"`
+	Title           string    `json:"title,omitempty"`
+	AuthorName      string    `json:"authorName,omitempty"`
+	AuthorURL       string    `json:"authorUrl,omitempty"`
+	HTML            string    `json:"html"`
+	Width           int       `json:"width,omitempty"`
+	Height          int       `json:"height,omitempty"`
+	ThumbnailURL    string    `json:"thumbnailUrl,omitempty"`
+	ThumbnailWidth  int       `json:"thumbnailWidth,omitempty"`
+	ThumbnailHeight int       `json:"thumbnailHeight,omitempty"`
+	ResolvedAt      time.Time `json:"resolvedAt"`
+}
+
+// oEmbedProvider 為支援的 oEmbed provider；hosts 為內容頁面的 host (不含 www.)
+type oEmbedProvider struct {
+	name     string
+	hosts    []string
+	endpoint string
+}
+
+// oEmbedProviders 為支援的 provider；Instagram 需要 Meta 的 access token，未設定時不使用
+var oEmbedProviders = []oEmbedProvider{
+	{name: "youtube", hosts: []string{"youtube.com", "m.youtube.com", "youtu.be"}, endpoint: "https://www.youtube.com/oembed"},
+	{name: "x", hosts: []string{"x.com", "twitter.com", "mobile.twitter.com"}, endpoint: "https://publish.twitter.com/oembed"},
+	{name: "instagram", hosts: []string{"instagram.com"}, endpoint: "https://graph.facebook.com/v19.0/instagram_oembed"},
+}
+
+// oEmbedCachePrefix 為 payload 的 cache key 前綴，依內容頁面的網址計算，不在 story: 前綴下，story 寫入時不需清除；
+// oEmbedFailureTTL 為解析失敗時記錄的時間，期間內不再向 provider 請求
+const (
+	oEmbedCachePrefix   = "oembed"
+	oEmbedFailureTTL    = 10 * time.Minute
+	oEmbedTimeout       = 10 * time.Second
+	oEmbedMaxResponse   = 1 << 20
+	oEmbedMaxConcurrent = 4
+)
+
+// OEmbedResolver resolves embed blocks through the oEmbed endpoints of
+// YouTube, X (Twitter) and Instagram and caches the payloads for a long
+// TTL, so readers are served the embed HTML without each calling the
+// provider. As a StoryEventListener it resolves the embeds of a story when
+// it is published (and new embeds when a published story is updated);
+// reads only look in the cache and resolve misses in the background.
+// Failures are remembered for a few minutes and the block falls back to a
+// link.
+type OEmbedResolver struct {
+	client         *http.Client
+	cache          *Cache
+	ttl            time.Duration
+	instagramToken string
+	sem            chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]bool // 背景解析中的網址
+}
+
+// NewOEmbedResolver returns a resolver caching payloads in cache for ttl.
+// instagramToken is a Meta app access token for Instagram's oEmbed
+// endpoint; Instagram embeds are not resolved without it.
+func NewOEmbedResolver(cache *Cache, ttl time.Duration, instagramToken string) *OEmbedResolver {
+	return &OEmbedResolver{client: &http.Client{Timeout: oEmbedTimeout}, cache: cache, ttl: ttl, instagramToken: instagramToken,
+		sem: make(chan struct{}, oEmbedMaxConcurrent), pending: map[string]bool{}}
+}
+
+// provider 回傳網址對應的 provider
+func (r *OEmbedResolver) provider(pageURL string) (oEmbedProvider, bool) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return oEmbedProvider{}, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for _, p := range oEmbedProviders {
+		if p.name == "instagram" && r.instagramToken == "" {
+			continue
+		}
+		for _, h := range p.hosts {
+			if host == h {
+				return p, true
+			}
+		}
+	}
+	return oEmbedProvider{}, false
+}
+
+// cacheKey 回傳網址的 payload 在 cache 中的 key
+func (r *OEmbedResolver) cacheKey(pageURL string) string {
+	return NewCacheKey(oEmbedCachePrefix).Field("url", pageURL).String()
+}
+
+// Resolve fetches the payload of pageURL from its provider and caches it,
+// replacing any cached payload. Failures are cached briefly so Lookup does
+// not retry them on every read.
+func (r *OEmbedResolver) Resolve(ctx context.Context, pageURL string) (*OEmbed, error) {
+	provider, ok := r.provider(pageURL)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOEmbedUnsupported, pageURL)
+	}
+	embed, err := r.fetch(ctx, provider, pageURL)
+	cache := NewTypedCache[OEmbed](r.cache)
+	if err != nil {
+		// 以沒有 HTML 的 payload 記錄失敗
+		if setErr := cache.SetWithTTL(ctx, r.cacheKey(pageURL), OEmbed{Provider: provider.name}, oEmbedFailureTTL); setErr != nil {
+			slog.Warn("failed to cache oembed failure", "url", pageURL, "error", setErr)
+		}
+		return nil, err
+	}
+	if err := cache.SetWithTTL(ctx, r.cacheKey(pageURL), *embed, r.ttl); err != nil {
+		slog.Warn("failed to cache oembed", "url", pageURL, "error", err)
+	}
+	return embed, nil
+}
+
+// Lookup returns the cached payload of pageURL, or nil when it is not
+// resolved (yet). A miss for a supported URL is resolved in the
+// background, so a later read finds it. A nil resolver returns nil.
+func (r *OEmbedResolver) Lookup(ctx context.Context, pageURL string) *OEmbed {
+	if r == nil || !r.cache.Enabled() {
+		return nil
+	}
+	if _, ok := r.provider(pageURL); !ok {
+		return nil
+	}
+	embed, found, err := NewTypedCache[OEmbed](r.cache).Get(ctx, r.cacheKey(pageURL))
+	if err != nil && !errors.Is(err, ErrCachedNotFound) {
+		slog.Warn("failed to read oembed cache", "url", pageURL, "error", err)
+		return nil
+	}
+	if !found {
+		r.resolveInBackground(ctx, []string{pageURL})
+		return nil
+	}
+	if embed.HTML == "" {
+		return nil
+	}
+	return &embed
+}
+
+// Blocks returns blocks with the cached payloads of their embed blocks in
+// ContentBlock.Embed. blocks is not modified. A nil resolver returns
+// blocks unchanged.
+func (r *OEmbedResolver) Blocks(ctx context.Context, blocks []ContentBlock) []ContentBlock {
+	if r == nil {
+		return blocks
+	}
+	var resolved []ContentBlock
+	for i, block := range blocks {
+		if block.Type != BlockEmbed {
+			continue
+		}
+		embed := r.Lookup(ctx, block.URL)
+		if embed == nil {
+			continue
+		}
+		if resolved == nil {
+			resolved = append([]ContentBlock(nil), blocks...)
+		}
+		resolved[i].Embed = embed
+	}
+	if resolved == nil {
+		return blocks
+	}
+	return resolved
+}
+
+// HandleStoryEvent resolves the embeds of a story in the background: all of
+// them when it is published, so the payload is fresh, and only those not
+// cached yet when a published story is updated.
+func (r *OEmbedResolver) HandleStoryEvent(ctx context.Context, event StoryEvent, story *Story) {
+	if event == StoryEventUnpublished {
+		return
+	}
+	var urls []string
+	for _, block := range story.Blocks {
+		if block.Type == BlockEmbed {
+			if _, ok := r.provider(block.URL); ok {
+				urls = append(urls, block.URL)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+	if event == StoryEventUpdated {
+		r.resolveInBackground(ctx, urls)
+		return
+	}
+	go func() {
+		for _, pageURL := range urls {
+			r.resolveOne(context.WithoutCancel(ctx), pageURL)
+		}
+	}()
+}
+
+// resolveInBackground 在背景解析尚未在 cache 中、也不在解析中的網址
+func (r *OEmbedResolver) resolveInBackground(ctx context.Context, urls []string) {
+	ctx = context.WithoutCancel(ctx)
+	r.mu.Lock()
+	var queued []string
+	for _, pageURL := range urls {
+		if !r.pending[pageURL] {
+			r.pending[pageURL] = true
+			queued = append(queued, pageURL)
+		}
+	}
+	r.mu.Unlock()
+	if len(queued) == 0 {
+		return
+	}
+	go func() {
+		for _, pageURL := range queued {
+			if _, found, _ := NewTypedCache[OEmbed](r.cache).Get(ctx, r.cacheKey(pageURL)); !found {
+				r.resolveOne(ctx, pageURL)
+			}
+			r.mu.Lock()
+			delete(r.pending, pageURL)
+			r.mu.Unlock()
+		}
+	}()
+}
+
+// resolveOne 解析一個網址，同時向 provider 發出的請求不超過 oEmbedMaxConcurrent；失敗時只記錄日誌
+func (r *OEmbedResolver) resolveOne(ctx context.Context, pageURL string) {
+	r.sem <- struct{}{}
+	defer func() { <-r.sem }()
+	if _, err := r.Resolve(ctx, pageURL); err != nil {
+		slog.Warn("failed to resolve oembed", "url", pageURL, "error", err)
+	}
+}
+
+// oEmbedResponse 為 provider 回應中用到的欄位；寬高可能是數字、字串或 null
+type oEmbedResponse struct {
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name"`
+	AuthorURL       string `json:"author_url"`
+	HTML            string `json:"html"`
+	Width           any    `json:"width"`
+	Height          any    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailWidth  any    `json:"thumbnail_width"`
+	ThumbnailHeight any    `json:"thumbnail_height"`
+}
+
+// fetch 向 provider 請求 pageURL 的 payload
+func (r *OEmbedResolver) fetch(ctx context.Context, provider oEmbedProvider, pageURL string) (*OEmbed, error) {
+	query := url.Values{"url": {pageURL}, "format": {"json"}}
+	if provider.name == "instagram" {
+		query.Set("access_token", r.instagramToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "go-story-oembed")
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oembed %s: %w", provider.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oembed %s: %s", provider.name, resp.Status)
+	}
+	var body oEmbedResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oEmbedMaxResponse)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("oembed %s: decode response: %w", provider.name, err)
+	}
+	if body.HTML == "" {
+		return nil, fmt.Errorf("oembed %s: response has no html", provider.name)
+	}
+	return &OEmbed{
+		Type: body.Type, Provider: provider.name, Title: body.Title, AuthorName: body.AuthorName, AuthorURL: body.AuthorURL,
+		HTML: body.HTML, Width: oEmbedSize(body.Width), Height: oEmbedSize(body.Height), ThumbnailURL: body.ThumbnailURL,
+		ThumbnailWidth: oEmbedSize(body.ThumbnailWidth), ThumbnailHeight: oEmbedSize(body.ThumbnailHeight),
+		ResolvedAt: time.Now().UTC(),
+	}, nil
+}
+
+// oEmbedSize 將寬高轉為整數；無法轉換 (如 "100%" 或 null) 時為 0
+func oEmbedSize(v any) int {
+	switch v := v.(type) {
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,361 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrOIDCUnavailable is returned (wrapped) when the identity provider's
+	// discovery document or token endpoint cannot be reached or answers
+	// with a server error.
+	ErrOIDCUnavailable = errors.New("identity provider unavailable")
+	// ErrOIDCRejected is returned (wrapped) when the identity provider
+	// rejects a code or refresh token, or returns an invalid ID token.
+	ErrOIDCRejected = errors.New("rejected by identity provider")
+)
+
+// OIDC client 的預設值與限制
+const (
+	oidcFetchTimeout       = 10 * time.Second
+	oidcMaxResponseSize    = 1 << 20
+	defaultOIDCGroupsClaim = "groups"
+)
+
+// defaultOIDCScopes 為未設定 Scopes 時要求的 scope
+var defaultOIDCScopes = []string{"openid", "profile", "email"}
+
+// OIDCConfig configures an OIDCClient.
+type OIDCConfig struct {
+	// IssuerURL is the identity provider's issuer; its discovery document
+	// is read from IssuerURL + "/.well-known/openid-configuration".
+	IssuerURL string
+	// ClientID and ClientSecret identify the client to the provider.
+	// ClientSecret may be empty for a public client, which relies on PKCE
+	// alone.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered with the provider.
+	RedirectURL string
+	// Scopes are requested on login; empty uses "openid profile email".
+	// Add "offline_access" (or what the provider uses) to receive refresh
+	// tokens.
+	Scopes []string
+	// GroupsClaim names the ID token claim holding the user's groups.
+	// Empty uses "groups".
+	GroupsClaim string
+	// GroupRoles maps groups to story roles (see ParseStoryRole); a user
+	// gets the most privileged role among their groups. When empty, the
+	// group names are taken as role names.
+	GroupRoles map[string]string
+	// ClockSkew is the tolerance applied to the ID token's exp, nbf and
+	// iat; zero uses one minute.
+	ClockSkew time.Duration
+}
+
+// OIDCIdentity is a user authenticated by the identity provider.
+type OIDCIdentity struct {
+	// Principal holds the ID token's claims; its Roles are the user's
+	// groups. It is nil after a refresh that returned no ID token.
+	Principal *Principal
+	// Role is the most privileged story role mapped from the groups.
+	Role StoryRole
+	// RefreshToken is empty when the provider issued none.
+	RefreshToken string
+	// ExpiresAt is when the tokens expire and the identity should be
+	// refreshed to pick up changes to the user's groups.
+	ExpiresAt time.Time
+}
+
+// OIDCClient signs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE (S256). The provider's endpoints are
+// read from its discovery document on first use, and ID tokens are
+// verified against its JWKS with a JWTVerifier, which also checks the
+// issuer and that the token was issued for ClientID.
+type OIDCClient struct {
+	cfg    OIDCConfig
+	roles  map[string]StoryRole
+	client *http.Client
+	group  singleflight.Group
+
+	mu        sync.RWMutex
+	discovery *oidcDiscovery
+	verifier  *JWTVerifier
+}
+
+// oidcDiscovery 為 discovery document 中使用的欄位
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// NewOIDCClient returns a client for the provider at cfg.IssuerURL, or an
+// error when GroupRoles maps a group to an unknown role.
+func NewOIDCClient(cfg OIDCConfig) (*OIDCClient, error) {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultOIDCScopes
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = defaultOIDCGroupsClaim
+	}
+	roles := make(map[string]StoryRole, len(cfg.GroupRoles))
+	for group, name := range cfg.GroupRoles {
+		role, ok := ParseStoryRole(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown role %q for group %q", name, group)
+		}
+		roles[group] = role
+	}
+	return &OIDCClient{cfg: cfg, roles: roles, client: &http.Client{Timeout: oidcFetchTimeout}}, nil
+}
+
+// RedirectURL returns the callback URL registered with the provider.
+func (c *OIDCClient) RedirectURL() string {
+	return c.cfg.RedirectURL
+}
+
+// AuthCodeURL returns the provider's authorization URL for a login with
+// state and nonce, whose code can only be redeemed with codeVerifier.
+func (c *OIDCClient) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	disc, _, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(c.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// EndSessionURL returns the provider's logout URL, or "" when the provider
+// does not publish one.
+func (c *OIDCClient) EndSessionURL(ctx context.Context) string {
+	disc, _, err := c.discover(ctx)
+	if err != nil {
+		return ""
+	}
+	return disc.EndSessionEndpoint
+}
+
+// Exchange redeems the authorization code of a login started with nonce
+// and codeVerifier, and returns the signed-in user.
+func (c *OIDCClient) Exchange(ctx context.Context, code, nonce, codeVerifier string) (*OIDCIdentity, error) {
+	tokens, err := c.token(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id token", ErrOIDCRejected)
+	}
+	identity, err := c.identity(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+	if got, _ := identity.Principal.Claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCRejected)
+	}
+	return identity, nil
+}
+
+// Refresh redeems refreshToken for new tokens. The returned identity keeps
+// refreshToken when the provider does not rotate it, and has a nil
+// Principal and the zero Role when the provider returned no ID token.
+func (c *OIDCClient) Refresh(ctx context.Context, refreshToken string) (*OIDCIdentity, error) {
+	tokens, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tokens.RefreshToken == "" {
+		tokens.RefreshToken = refreshToken
+	}
+	if tokens.IDToken == "" {
+		return &OIDCIdentity{RefreshToken: tokens.RefreshToken, ExpiresAt: tokens.expiresAt()}, nil
+	}
+	return c.identity(ctx, tokens)
+}
+
+// oidcTokenResponse 為 token endpoint 的回應
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// expiresAt 回傳 access token 的到期時間；未提供 expires_in 時為零值
+func (t *oidcTokenResponse) expiresAt() time.Time {
+	if t.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+// identity 驗證 ID token 並依 groups 對應角色
+func (c *OIDCClient) identity(ctx context.Context, tokens *oidcTokenResponse) (*OIDCIdentity, error) {
+	_, verifier, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := verifier.Verify(ctx, tokens.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCRejected, err)
+	}
+	identity := &OIDCIdentity{Principal: principal, Role: c.role(principal.Roles), RefreshToken: tokens.RefreshToken, ExpiresAt: principal.ExpiresAt}
+	if exp := tokens.expiresAt(); !exp.IsZero() && exp.Before(identity.ExpiresAt) {
+		identity.ExpiresAt = exp
+	}
+	return identity, nil
+}
+
+// role 回傳 groups 對應的最高角色；未設定 GroupRoles 時直接以 group 名稱作為角色
+func (c *OIDCClient) role(groups []string) StoryRole {
+	if len(c.roles) == 0 {
+		return StoryRoleOf(groups)
+	}
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if role, ok := c.roles[group]; ok {
+			names = append(names, string(role))
+		}
+	}
+	return StoryRoleOf(names)
+}
+
+// token 呼叫 token endpoint；有 client secret 時以 HTTP Basic (client_secret_basic) 驗證
+func (c *OIDCClient) token(ctx context.Context, form url.Values) (*oidcTokenResponse, error) {
+	disc, _, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, oidcFetchTimeout)
+	defer cancel()
+
+	if c.cfg.ClientSecret == "" {
+		form.Set("client_id", c.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: token endpoint status %d", ErrOIDCUnavailable, resp.StatusCode)
+	}
+	var tokens oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%w: decode token response: %w", ErrOIDCRejected, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrOIDCRejected, tokens.Error, tokens.ErrorDescription)
+	}
+	return &tokens, nil
+}
+
+// discover 回傳 discovery document 與驗證 ID token 的 verifier；成功取得後不再重新讀取，
+// 失敗時下次呼叫再試，同時進行的呼叫共用一次請求
+func (c *OIDCClient) discover(ctx context.Context) (*oidcDiscovery, *JWTVerifier, error) {
+	c.mu.RLock()
+	disc, verifier := c.discovery, c.verifier
+	c.mu.RUnlock()
+	if disc != nil {
+		return disc, verifier, nil
+	}
+	_, err, _ := c.group.Do("discovery", func() (interface{}, error) {
+		disc, err := c.fetchDiscovery(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		verifier := NewJWTVerifier(JWTConfig{
+			JWKSURL:    disc.JWKSURI,
+			Issuer:     disc.Issuer,
+			Audience:   []string{c.cfg.ClientID},
+			ClockSkew:  c.cfg.ClockSkew,
+			RolesClaim: c.cfg.GroupsClaim,
+		})
+		c.mu.Lock()
+		c.discovery, c.verifier = disc, verifier
+		c.mu.Unlock()
+		return nil, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.discovery, c.verifier, nil
+}
+
+// fetchDiscovery 讀取並檢查 discovery document；issuer 需與設定一致 (忽略結尾的 /)
+func (c *OIDCClient) fetchDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, oidcFetchTimeout)
+	defer cancel()
+	issuer := strings.TrimSuffix(c.cfg.IssuerURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOIDCUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: discovery status %d", ErrOIDCUnavailable, resp.StatusCode)
+	}
+	var disc oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&disc); err != nil {
+		return nil, fmt.Errorf("%w: decode discovery: %w", ErrOIDCUnavailable, err)
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("%w: discovery issuer %q does not match %q", ErrOIDCUnavailable, disc.Issuer, c.cfg.IssuerURL)
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document lacks endpoints", ErrOIDCUnavailable)
+	}
+	return &disc, nil
+}
@@ -0,0 +1,298 @@
+package data
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Story access tiers, set in Story.Access.
+const (
+	// StoryAccessFree stories are readable by everyone.
+	StoryAccessFree = "free"
+	// StoryAccessMetered stories are readable by members, and by other
+	// visitors up to the meter limit per window.
+	StoryAccessMetered = "metered"
+	// StoryAccessMembers stories are readable by members only.
+	StoryAccessMembers = "members"
+)
+
+var (
+	// ErrInvalidStoryAccess is returned (wrapped) by Create and Update for a
+	// story whose Access is not one of the StoryAccess* tiers.
+	ErrInvalidStoryAccess = errors.New("invalid story access")
+	// ErrInvalidMembershipToken is returned (wrapped) by
+	// Paywall.Entitlement for a token that is malformed, not signed with
+	// the paywall secret or expired.
+	ErrInvalidMembershipToken = errors.New("invalid membership token")
+)
+
+// 計次的預設值：每位訪客每 30 天可免費閱讀 3 篇計次文章；預設以 membership claim 判斷會員
+const (
+	defaultMeterLimit      = 3
+	defaultMeterWindow     = 30 * 24 * time.Hour
+	defaultMembershipClaim = "membership"
+)
+
+// AccessTier returns the access tier of the story: Access, or for stories
+// stored before access tiers existed, StoryAccessMembers when IsMember is
+// set and StoryAccessFree otherwise.
+func (s Story) AccessTier() string {
+	switch {
+	case s.Access != "":
+		return s.Access
+	case s.IsMember:
+		return StoryAccessMembers
+	}
+	return StoryAccessFree
+}
+
+// normalizeStoryAccess 在寫入前補上 Access 並同步 IsMember；沒有 Access 的 story 依 IsMember 決定
+func normalizeStoryAccess(story *Story) error {
+	story.Access = story.AccessTier()
+	if !slices.Contains([]string{StoryAccessFree, StoryAccessMetered, StoryAccessMembers}, story.Access) {
+		return fmt.Errorf("%w: %q (must be %s, %s or %s)", ErrInvalidStoryAccess, story.Access, StoryAccessFree, StoryAccessMetered, StoryAccessMembers)
+	}
+	story.IsMember = story.Access == StoryAccessMembers
+	return nil
+}
+
+// Entitlement is what a reader is entitled to read: whether the membership
+// token sent with the request grants membership, and the opaque visitor ID
+// metered reads are counted against.
+type Entitlement struct {
+	Member  bool
+	Visitor string
+}
+
+// entitlementKey 為 context 中 Entitlement 的 key
+type entitlementKey struct{}
+
+// WithEntitlement returns a copy of ctx carrying the reader's entitlement,
+// for resolvers that check access on their own.
+func WithEntitlement(ctx context.Context, e Entitlement) context.Context {
+	return context.WithValue(ctx, entitlementKey{}, e)
+}
+
+// EntitlementFromContext returns the entitlement set on ctx with
+// WithEntitlement, or that of an anonymous reader.
+func EntitlementFromContext(ctx context.Context) Entitlement {
+	e, _ := ctx.Value(entitlementKey{}).(Entitlement)
+	return e
+}
+
+// StoryAccess is the outcome of Paywall.Check for one reader and story.
+type StoryAccess struct {
+	Tier    string `json:"tier"` // StoryAccess* 之一
+	Granted bool   `json:"granted"`
+	// Reason explains the outcome: "free", "member" or "metered" when
+	// granted, and "members_only", "meter_exhausted" or "visitor_required"
+	// (a metered story requested without a visitor ID) when not.
+	Reason string `json:"reason"`
+	// 計次文章的上限與剩餘篇數；會員或非計次文章時不出現
+	MeterLimit     int  `json:"meterLimit,omitempty"`
+	MeterRemaining *int `json:"meterRemaining,omitempty"`
+}
+
+// PaywallConfig configures a Paywall.
+type PaywallConfig struct {
+	// TokenSecret is the HMAC key membership tokens (HS256 JWTs issued by
+	// the membership system) are signed with. It may be empty when readers
+	// authenticate with JWTs verified by a JWTVerifier instead.
+	TokenSecret string
+	// MembershipClaim names the token claim holding the reader's
+	// membership. Empty uses "membership".
+	MembershipClaim string
+	// MeterLimit is the number of metered stories a visitor may read per
+	// MeterWindow without membership; zero uses 3 per 30 days.
+	MeterLimit  int
+	MeterWindow time.Duration
+}
+
+// Paywall gates paid stories. Free stories are readable by everyone;
+// members-only stories only by readers whose membership token grants
+// membership; metered stories also by visitors who have read fewer than
+// the meter limit of distinct metered stories in the current window, which
+// starts with a visitor's first metered read. Meters are kept in Redis
+// under the hashed visitor ID; when Redis is unavailable metered stories
+// are readable, so the paywall never takes stories down.
+//
+// Stories a reader may not read keep their metadata and excerpt, which
+// serves as the teaser, but lose body, blocks, bodyHtml and
+// tableOfContents. Listings never count against the meter: paid stories in
+// them are always withheld, and readers open the story to read it. A nil
+// Paywall grants every story.
+type Paywall struct {
+	cache  *Cache
+	secret []byte
+	claim  string
+	limit  int
+	window time.Duration
+}
+
+// NewPaywall returns a paywall keeping meters in cache.
+func NewPaywall(cache *Cache, cfg PaywallConfig) *Paywall {
+	p := &Paywall{cache: cache, secret: []byte(cfg.TokenSecret), claim: cfg.MembershipClaim, limit: cfg.MeterLimit, window: cfg.MeterWindow}
+	if p.claim == "" {
+		p.claim = defaultMembershipClaim
+	}
+	if p.limit <= 0 {
+		p.limit = defaultMeterLimit
+	}
+	if p.window <= 0 {
+		p.window = defaultMeterWindow
+	}
+	return p
+}
+
+// Entitlement returns the entitlement of a reader sending the membership
+// token (empty for anonymous readers) and visitor ID. The token is an HS256
+// JWT signed with the token secret; it grants membership when its
+// membership claim is true or a tier name other than "free" or "none", and
+// it has not expired. Without a token secret the token is ignored.
+func (p *Paywall) Entitlement(token, visitor string) (Entitlement, error) {
+	e := Entitlement{Visitor: visitor}
+	if p == nil || token == "" || len(p.secret) == 0 {
+		return e, nil
+	}
+	claims, err := p.verifyToken(token, time.Now())
+	if err != nil {
+		return e, err
+	}
+	e.Member = p.member(claims)
+	return e, nil
+}
+
+// PrincipalEntitlement returns the entitlement of an authenticated reader
+// (see JWTVerifier), whose membership claim is read from the verified token.
+func (p *Paywall) PrincipalEntitlement(principal *Principal, visitor string) Entitlement {
+	e := Entitlement{Visitor: visitor}
+	if p != nil && principal != nil {
+		e.Member = p.member(principal.Claims)
+	}
+	return e
+}
+
+// member 回傳 claims 中的 membership claim 是否表示會員
+func (p *Paywall) member(claims map[string]interface{}) bool {
+	switch membership := claims[p.claim].(type) {
+	case bool:
+		return membership
+	case string:
+		return membership != "" && !strings.EqualFold(membership, "free") && !strings.EqualFold(membership, "none")
+	}
+	return false
+}
+
+// verifyToken 驗證 HS256 JWT 的簽章與有效期間，回傳 claims
+func (p *Paywall) verifyToken(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidMembershipToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported header", ErrInvalidMembershipToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidMembershipToken)
+	}
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidMembershipToken)
+	}
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidMembershipToken)
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidMembershipToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return nil, fmt.Errorf("%w: not yet valid", ErrInvalidMembershipToken)
+	}
+	return claims, nil
+}
+
+// decodeJWTPart 解碼 JWT 中 base64url 編碼的 JSON
+func decodeJWTPart(part string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Check decides whether a reader with entitlement e may read story, counting
+// a metered story against the visitor's meter when granted. Reading the same
+// story again within the window is not counted twice.
+func (p *Paywall) Check(ctx context.Context, story *Story, e Entitlement) *StoryAccess {
+	tier := story.AccessTier()
+	access := &StoryAccess{Tier: tier}
+	switch {
+	case p == nil || tier == StoryAccessFree:
+		access.Granted, access.Reason = true, "free"
+	case e.Member:
+		access.Granted, access.Reason = true, "member"
+	case tier == StoryAccessMembers:
+		access.Reason = "members_only"
+	case e.Visitor == "":
+		access.Reason = "visitor_required"
+		access.MeterLimit = p.limit
+	default:
+		granted, used, err := p.cache.MeterRead(ctx, e.Visitor, story.ID, p.limit, p.window)
+		if err != nil {
+			// 無法計次時開放閱讀
+			slog.Warn("failed to meter story read", "story", story.ID, "error", err)
+			granted, used = true, 0
+		}
+		remaining := max(p.limit-used, 0)
+		access.Granted, access.MeterLimit, access.MeterRemaining = granted, p.limit, &remaining
+		access.Reason = "metered"
+		if !granted {
+			access.Reason = "meter_exhausted"
+		}
+	}
+	return access
+}
+
+// Gate checks story for a reader with entitlement e, records the outcome in
+// story.Paywall and withholds the body when access is denied. story must be
+// a copy, not a value shared through the cache.
+func (p *Paywall) Gate(ctx context.Context, story *Story, e Entitlement) {
+	if p == nil {
+		return
+	}
+	story.Paywall = p.Check(ctx, story, e)
+	p.Withhold(story)
+}
+
+// Withheld reports whether the body of story is withheld: the story is paid
+// and was not granted by Gate, as with every paid story in a listing.
+func (p *Paywall) Withheld(story *Story) bool {
+	if p == nil || story.AccessTier() == StoryAccessFree {
+		return false
+	}
+	return story.Paywall == nil || !story.Paywall.Granted
+}
+
+// Withhold clears the body of story when it is withheld. story must be a
+// copy, not a value shared through the cache.
+func (p *Paywall) Withhold(story *Story) {
+	if !p.Withheld(story) {
+		return
+	}
+	story.Body, story.Blocks, story.BodyHTML, story.TableOfContents = "", nil, "", nil
+}
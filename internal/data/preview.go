@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPreviewsUnsupported is returned when story previews are not
+	// configured.
+	ErrPreviewsUnsupported = errors.New("story previews are not configured")
+	// ErrInvalidPreviewToken is returned for a preview token that is
+	// malformed or not signed with the preview secret.
+	ErrInvalidPreviewToken = errors.New("invalid preview token")
+	// ErrPreviewTokenExpired is returned for a correctly signed preview
+	// token past its expiry.
+	ErrPreviewTokenExpired = errors.New("preview token expired")
+)
+
+// PreviewToken is a signed, expiring grant to read one story in any status.
+// URL is where the token can be opened.
+type PreviewToken struct {
+	Token     string    `json:"token"`
+	StoryID   string    `json:"storyId"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// previewURLPlaceholder 為預覽網址範本中代入 token 的位置
+const previewURLPlaceholder = "{token}"
+
+// PreviewService issues and checks preview tokens, letting editors share
+// an unpublished story with people outside the newsroom. A token is
+// "<payload>.<signature>", both base64url: the payload holds the story ID
+// and expiry, the signature is an HMAC-SHA256 of it with the secret, so
+// tokens need no storage and stop working when the secret changes. Stories
+// are read from the repository without the cache, so drafts never land in
+// cache entries shared with public readers.
+type PreviewService struct {
+	repo        StoryRepository
+	secret      []byte
+	ttl         time.Duration
+	urlTemplate string
+}
+
+// NewPreviewService returns a service signing tokens with secret that are
+// valid for ttl. repo should not be a CachedStoryRepository. urlTemplate
+// is the preview URL with "{token}" in place of the token; when empty, the
+// REST preview endpoint is used.
+func NewPreviewService(repo StoryRepository, secret string, ttl time.Duration, urlTemplate string) *PreviewService {
+	if urlTemplate == "" {
+		urlTemplate = "/api/v1/preview/" + previewURLPlaceholder
+	}
+	return &PreviewService{repo: repo, secret: []byte(secret), ttl: ttl, urlTemplate: urlTemplate}
+}
+
+// NewToken returns a preview token for the story with id, or
+// ErrStoryNotFound.
+func (p *PreviewService) NewToken(ctx context.Context, id string) (*PreviewToken, error) {
+	if p == nil {
+		return nil, ErrPreviewsUnsupported
+	}
+	if _, err := p.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(p.ttl).UTC().Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(id + "." + strconv.FormatInt(expiresAt.Unix(), 10)))
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(p.sign(payload))
+	return &PreviewToken{
+		Token:     token,
+		StoryID:   id,
+		URL:       strings.ReplaceAll(p.urlTemplate, previewURLPlaceholder, token),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Story checks token and returns the story it grants, in any status, with
+// the token's expiry.
+func (p *PreviewService) Story(ctx context.Context, token string) (*Story, time.Time, error) {
+	if p == nil {
+		return nil, time.Time{}, ErrPreviewsUnsupported
+	}
+	id, expiresAt, err := p.verify(token, time.Now())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	story, err := p.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return story, expiresAt, nil
+}
+
+// verify 檢查簽章與期限，回傳 token 中的 story ID 與到期時間
+func (p *PreviewService) verify(token string, now time.Time) (string, time.Time, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", time.Time{}, ErrInvalidPreviewToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, p.sign(payload)) {
+		return "", time.Time{}, ErrInvalidPreviewToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidPreviewToken
+	}
+	// story ID 不含 "."，以最後一個 "." 分隔
+	i := strings.LastIndexByte(string(raw), '.')
+	if i < 0 {
+		return "", time.Time{}, ErrInvalidPreviewToken
+	}
+	unix, err := strconv.ParseInt(string(raw[i+1:]), 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidPreviewToken
+	}
+	expiresAt := time.Unix(unix, 0).UTC()
+	if !now.Before(expiresAt) {
+		return "", time.Time{}, fmt.Errorf("%w at %s", ErrPreviewTokenExpired, expiresAt.Format(time.RFC3339))
+	}
+	return string(raw[:i]), expiresAt, nil
+}
+
+// sign 計算 payload 的 HMAC-SHA256；加上用途前綴，避免與其他以同一 secret 簽署的資料混用
+func (p *PreviewService) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte("story-preview."))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,275 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidPushDevice is returned (wrapped) for a device token that is
+	// empty, too long or not hexadecimal.
+	ErrInvalidPushDevice = errors.New("invalid push device")
+	// ErrPushDevicesUnsupported is returned by a nil PushDeviceService, i.e.
+	// when APNs push notifications are disabled.
+	ErrPushDevicesUnsupported = errors.New("push devices are not enabled")
+)
+
+// 推播設定：同一篇 story 不重複推播的期間、每個 provider 的重試次數與間隔
+const (
+	pushDedupeTTL     = 30 * 24 * time.Hour
+	pushMaxAttempts   = 3
+	pushBaseBackoff   = 5 * time.Second
+	pushTimeout       = 2 * time.Minute
+	pushDefaultTag    = "breaking"
+	pushBodyMaxLength = 180
+)
+
+// PushNotification is the payload pushed to readers when a flagged story is
+// published.
+type PushNotification struct {
+	StoryID string `json:"storyId"`
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	URL     string `json:"url,omitempty"`
+	Image   string `json:"image,omitempty"`
+	Section string `json:"section,omitempty"`
+	// 同一篇 story 的通知共用的識別字，裝置上新的通知取代舊的
+	CollapseKey string    `json:"collapseKey"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// PushProvider delivers a PushNotification to readers' devices, such as
+// FCM, APNs or an in-house push gateway reached by webhook.
+type PushProvider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	// Push delivers n. It is retried on error, so it should not partially
+	// succeed without reporting success.
+	Push(ctx context.Context, n *PushNotification) error
+}
+
+// PushConfig configures a PushDispatcher.
+type PushConfig struct {
+	// Tags flag the stories to push: a story is pushed when published
+	// with any of them. Empty uses "breaking".
+	Tags []string
+}
+
+// PushDispatcher pushes a notification to every PushProvider when a story
+// tagged with one of the configured tags is published. As a
+// StoryEventListener it dispatches in the background, retrying each
+// provider up to 3 times. A story is pushed at most once: the story ID is
+// marked in Redis for 30 days before dispatching, so unpublishing and
+// republishing a story to correct it does not notify readers again, and
+// updates of published stories never do. When Redis is unavailable only
+// the story.published event itself deduplicates.
+type PushDispatcher struct {
+	providers []PushProvider
+	cache     *Cache
+	site      Site
+	tags      []string
+}
+
+// NewPushDispatcher returns a dispatcher pushing flagged stories, linked to
+// site when it has a URL, to providers.
+func NewPushDispatcher(cache *Cache, site Site, cfg PushConfig, providers ...PushProvider) *PushDispatcher {
+	tags := cfg.Tags
+	if len(tags) == 0 {
+		tags = []string{pushDefaultTag}
+	}
+	return &PushDispatcher{providers: providers, cache: cache, site: site, tags: tags}
+}
+
+// HandleStoryEvent dispatches the notification of a flagged story being
+// published in the background.
+func (d *PushDispatcher) HandleStoryEvent(ctx context.Context, event StoryEvent, story *Story) {
+	if event != StoryEventPublished || len(d.providers) == 0 || !d.flagged(story) {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	first, err := d.cache.MarkSeen(ctx, "push:"+story.ID, pushDedupeTTL)
+	if err != nil {
+		// 無法確認時仍然推播：重複的通知比漏掉快訊好
+		slog.Warn("failed to check pushed stories", "story", story.ID, "error", err)
+	} else if !first {
+		slog.Info("skipped push of republished story", "story", story.ID)
+		return
+	}
+	n := d.notification(story)
+	go d.Dispatch(ctx, n)
+}
+
+// Dispatch sends n to every provider concurrently and waits for them.
+// Failures are logged after the last attempt.
+func (d *PushDispatcher) Dispatch(ctx context.Context, n *PushNotification) {
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, provider := range d.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := pushWithRetry(ctx, provider, n); err != nil {
+				slog.Error("failed to push story", "provider", provider.Name(), "story", n.StoryID, "error", err)
+				return
+			}
+			slog.Info("pushed story", "provider", provider.Name(), "story", n.StoryID)
+		}()
+	}
+	wg.Wait()
+}
+
+// flagged 回傳 story 是否帶有推播的 tag
+func (d *PushDispatcher) flagged(story *Story) bool {
+	for _, tag := range story.Tags {
+		if slices.Contains(d.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// notification 由 story 組成通知；內文為摘要，過長時截斷
+func (d *PushDispatcher) notification(story *Story) *PushNotification {
+	n := &PushNotification{
+		StoryID:     story.ID,
+		Slug:        story.Slug,
+		Title:       story.Title,
+		Body:        truncateExcerpt(story.Excerpt, pushBodyMaxLength),
+		Image:       story.CoverImage,
+		Section:     story.Section,
+		CollapseKey: "story-" + story.ID,
+	}
+	if d.site.URL != "" {
+		n.URL = d.site.StoryURL(story.Slug)
+	}
+	if story.PublishedAt != nil {
+		n.PublishedAt = *story.PublishedAt
+	}
+	return n
+}
+
+// pushWithRetry 呼叫 provider，失敗時等待後重試，間隔每次加倍
+func pushWithRetry(ctx context.Context, provider PushProvider, n *PushNotification) error {
+	backoff := pushBaseBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = provider.Push(ctx, n); err == nil || attempt == pushMaxAttempts {
+			return err
+		}
+		slog.Warn("push failed, retrying", "provider", provider.Name(), "story", n.StoryID, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// PushDevice is an iOS device registered for APNs push notifications.
+type PushDevice struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"userId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PushDeviceService stores the APNs device tokens the iOS app registers in
+// the push_devices table. APNs, unlike FCM topics, has no broadcast, so
+// APNsPushProvider sends to every registered token and removes those APNs
+// reports as no longer valid.
+type PushDeviceService struct {
+	db *sql.DB
+}
+
+// NewPushDeviceService returns a service storing device tokens in db (see
+// internal/data/migrations).
+func NewPushDeviceService(db *sql.DB) *PushDeviceService {
+	return &PushDeviceService{db: db}
+}
+
+// Register stores token, for userID when not empty. Registering a token
+// again refreshes it and its user.
+func (s *PushDeviceService) Register(ctx context.Context, token, userID string) error {
+	if s == nil {
+		return ErrPushDevicesUnsupported
+	}
+	token, err := normalizePushToken(token)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO push_devices (token, user_id) VALUES ($1, $2)
+		ON CONFLICT (token) DO UPDATE SET user_id = EXCLUDED.user_id, updated_at = now()`, token, userID); err != nil {
+		return fmt.Errorf("register push device: %w", err)
+	}
+	return nil
+}
+
+// Unregister removes token, if registered.
+func (s *PushDeviceService) Unregister(ctx context.Context, token string) error {
+	if s == nil {
+		return ErrPushDevicesUnsupported
+	}
+	token, err := normalizePushToken(token)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM push_devices WHERE token = $1`, token); err != nil {
+		return fmt.Errorf("unregister push device: %w", err)
+	}
+	return nil
+}
+
+// Tokens returns up to limit registered tokens ordered after after, which
+// is the last token of the previous page ("" for the first page).
+func (s *PushDeviceService) Tokens(ctx context.Context, after string, limit int) ([]string, error) {
+	if s == nil {
+		return nil, ErrPushDevicesUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT token FROM push_devices WHERE token > $1 ORDER BY token LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list push devices: %w", err)
+	}
+	defer rows.Close()
+	tokens := []string{}
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, fmt.Errorf("scan push device: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list push devices: %w", err)
+	}
+	return tokens, nil
+}
+
+// normalizePushToken 檢查 APNs device token 為 hex 字串 (目前為 64 字元，Apple 保留加長的可能)，轉為小寫
+func normalizePushToken(token string) (string, error) {
+	token = strings.ToLower(strings.TrimSpace(token))
+	if token == "" || len(token) > 200 {
+		return "", fmt.Errorf("%w: token must be 1-200 hex characters", ErrInvalidPushDevice)
+	}
+	for _, r := range token {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return "", fmt.Errorf("%w: token must be 1-200 hex characters", ErrInvalidPushDevice)
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,484 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Push provider names, as reported by PushProvider.Name.
+const (
+	PushProviderFCM     = "fcm"
+	PushProviderAPNs    = "apns"
+	PushProviderWebhook = "webhook"
+)
+
+// 各 provider 的設定：請求逾時、FCM 的 OAuth scope、APNs 的主機、token 更新間隔、同時送出的數量與每次讀取的 device 數
+const (
+	pushRequestTimeout  = 10 * time.Second
+	fcmScope            = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmEndpoint         = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	apnsHost            = "https://api.push.apple.com"
+	apnsSandboxHost     = "https://api.sandbox.push.apple.com"
+	apnsTokenRefresh    = 50 * time.Minute // Apple 要求 20 到 60 分鐘之間更新
+	apnsConcurrency     = 20
+	apnsDevicePageSize  = 500
+	apnsCollapseIDLimit = 64
+)
+
+// DefaultFCMTopic is the FCM topic notifications go to when none is
+// configured; the apps subscribe to it.
+const DefaultFCMTopic = "stories"
+
+// signJWT 以 signer 簽署 header 與 claims，回傳 compact 格式的 JWT
+func signJWT(header, claims map[string]interface{}, sign func(digest []byte) ([]byte, error)) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := sign(digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign jwt: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parsePKCS8PEM 讀取 PEM 格式的 PKCS #8 私鑰
+func parsePKCS8PEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+// fcmServiceAccount 為 Google service account 金鑰檔中使用的欄位
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMPushProvider sends notifications to an FCM topic through the HTTP v1
+// API, which reaches both the Android and the iOS app. It authenticates
+// with a Google service account, exchanging a signed JWT for an access
+// token that is reused until shortly before it expires.
+type FCMPushProvider struct {
+	account  fcmServiceAccount
+	key      *rsa.PrivateKey
+	topic    string
+	client   *http.Client
+	mu       sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+// NewFCMPushProvider returns a provider sending to topic (DefaultFCMTopic
+// when empty) with the service account key in credentialsFile.
+func NewFCMPushProvider(credentialsFile, topic string) (*FCMPushProvider, error) {
+	raw, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read fcm credentials: %w", err)
+	}
+	var account fcmServiceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("parse fcm credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("fcm credentials need project_id, client_email and private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	parsed, err := parsePKCS8PEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse fcm private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("fcm private key is not an RSA key")
+	}
+	if topic == "" {
+		topic = DefaultFCMTopic
+	}
+	return &FCMPushProvider{account: account, key: key, topic: topic, client: &http.Client{Timeout: pushRequestTimeout}}, nil
+}
+
+// Name returns PushProviderFCM.
+func (p *FCMPushProvider) Name() string { return PushProviderFCM }
+
+// Push sends n to the topic.
+func (p *FCMPushProvider) Push(ctx context.Context, n *PushNotification) error {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{"storyId": n.StoryID, "slug": n.Slug}
+	if n.URL != "" {
+		data["url"] = n.URL
+	}
+	notification := map[string]string{"title": n.Title, "body": n.Body}
+	if n.Image != "" {
+		notification["image"] = n.Image
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": map[string]interface{}{
+		"topic":        p.topic,
+		"notification": notification,
+		"data":         data,
+		"android":      map[string]interface{}{"collapse_key": n.CollapseKey, "priority": "high"},
+		"apns": map[string]interface{}{
+			"headers": map[string]string{"apns-collapse-id": apnsCollapseID(n.CollapseKey)},
+			"payload": map[string]interface{}{"aps": map[string]interface{}{"sound": "default", "mutable-content": 1}},
+		},
+	}})
+	if err != nil {
+		return fmt.Errorf("marshal fcm message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmEndpoint, url.PathEscape(p.account.ProjectID)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode == http.StatusUnauthorized {
+		p.resetToken()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("fcm: unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// accessToken 回傳尚未過期的 access token，到期前一分鐘重新取得
+func (p *FCMPushProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.tokenExp.Add(-time.Minute)) {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]interface{}{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{"iss": p.account.ClientEmail, "scope": fcmScope, "aud": p.account.TokenURI, "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()},
+		func(digest []byte) ([]byte, error) {
+			return rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest)
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fcm token: unexpected status %d", resp.StatusCode)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil || result.AccessToken == "" {
+		return "", fmt.Errorf("fcm token: invalid response: %v", err)
+	}
+	p.token, p.tokenExp = result.AccessToken, now.Add(time.Duration(result.ExpiresIn)*time.Second)
+	return p.token, nil
+}
+
+// resetToken 捨棄 access token，下次重新取得
+func (p *FCMPushProvider) resetToken() {
+	p.mu.Lock()
+	p.token = ""
+	p.mu.Unlock()
+}
+
+// APNsConfig configures an APNsPushProvider with token-based (.p8 key)
+// authentication.
+type APNsConfig struct {
+	KeyFile string // App Store Connect 下載的 .p8 金鑰
+	KeyID   string
+	TeamID  string
+	Topic   string // App 的 bundle ID
+	Sandbox bool   // 送到開發環境，供 debug build 使用
+}
+
+// APNsPushProvider sends notifications directly through APNs to every
+// device token in a PushDeviceService, for iOS apps not using FCM. Tokens
+// APNs rejects as unregistered or invalid are removed.
+type APNsPushProvider struct {
+	devices  *PushDeviceService
+	key      *ecdsa.PrivateKey
+	cfg      APNsConfig
+	host     string
+	client   *http.Client
+	mu       sync.Mutex
+	token    string
+	tokenIat time.Time
+}
+
+// NewAPNsPushProvider returns a provider sending to the tokens in devices.
+func NewAPNsPushProvider(devices *PushDeviceService, cfg APNsConfig) (*APNsPushProvider, error) {
+	if cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+		return nil, errors.New("apns needs a key ID, team ID and topic")
+	}
+	raw, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read apns key: %w", err)
+	}
+	parsed, err := parsePKCS8PEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns key is not an ECDSA key")
+	}
+	host := apnsHost
+	if cfg.Sandbox {
+		host = apnsSandboxHost
+	}
+	// APNs 只接受 HTTP/2；預設的 Transport 在 TLS 連線時會協商 HTTP/2
+	return &APNsPushProvider{devices: devices, key: key, cfg: cfg, host: host, client: &http.Client{Timeout: pushRequestTimeout}}, nil
+}
+
+// Name returns PushProviderAPNs.
+func (p *APNsPushProvider) Name() string { return PushProviderAPNs }
+
+// Push sends n to every registered device. Failures of single devices are
+// logged; it fails only when no device received n, so a retry does not
+// notify devices twice.
+func (p *APNsPushProvider) Push(ctx context.Context, n *PushNotification) error {
+	aps := map[string]interface{}{
+		"alert":           map[string]string{"title": n.Title, "body": n.Body},
+		"sound":           "default",
+		"mutable-content": 1,
+	}
+	payload := map[string]interface{}{"aps": aps, "storyId": n.StoryID, "slug": n.Slug}
+	if n.URL != "" {
+		payload["url"] = n.URL
+	}
+	if n.Image != "" {
+		payload["image"] = n.Image
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal apns payload: %w", err)
+	}
+
+	var (
+		mu           sync.Mutex
+		sent, failed int
+		firstErr     error
+	)
+	after := ""
+	for {
+		tokens, err := p.devices.Tokens(ctx, after, apnsDevicePageSize)
+		if err != nil {
+			return err
+		}
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(apnsConcurrency)
+		for _, token := range tokens {
+			g.Go(func() error {
+				err := p.send(gctx, token, n.CollapseKey, body)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+					return nil
+				}
+				sent++
+				return nil
+			})
+		}
+		_ = g.Wait()
+		if len(tokens) < apnsDevicePageSize {
+			break
+		}
+		after = tokens[len(tokens)-1]
+	}
+	if failed > 0 {
+		if sent == 0 {
+			return firstErr
+		}
+		slog.Warn("apns push failed for some devices", "story", n.StoryID, "sent", sent, "failed", failed, "error", firstErr)
+	}
+	return nil
+}
+
+// send 送出一則通知；APNs 回報 token 已失效時刪除該 token，不視為失敗
+func (p *APNsPushProvider) send(ctx context.Context, token, collapseKey string, body []byte) error {
+	auth, err := p.authToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+auth)
+	req.Header.Set("apns-topic", p.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-collapse-id", apnsCollapseID(collapseKey))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 4<<10)).Decode(&result)
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		if err := p.devices.Unregister(context.WithoutCancel(ctx), token); err != nil {
+			slog.Warn("failed to remove invalid push device", "error", err)
+		}
+		return nil
+	}
+	if result.Reason == "ExpiredProviderToken" || result.Reason == "InvalidProviderToken" {
+		p.resetToken()
+	}
+	return fmt.Errorf("apns: unexpected status %d: %s", resp.StatusCode, result.Reason)
+}
+
+// authToken 回傳 provider token；Apple 限制更新頻率，同一個 token 使用 50 分鐘
+func (p *APNsPushProvider) authToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Since(p.tokenIat) < apnsTokenRefresh {
+		return p.token, nil
+	}
+	now := time.Now()
+	token, err := signJWT(
+		map[string]interface{}{"alg": "ES256", "kid": p.cfg.KeyID},
+		map[string]interface{}{"iss": p.cfg.TeamID, "iat": now.Unix()},
+		func(digest []byte) ([]byte, error) {
+			// JWS 的 ES256 簽章為固定長度的 r || s，而非 ASN.1
+			r, s, err := ecdsa.Sign(rand.Reader, p.key, digest)
+			if err != nil {
+				return nil, err
+			}
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+			return sig, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	p.token, p.tokenIat = token, now
+	return token, nil
+}
+
+// resetToken 捨棄 provider token，下次重新簽署
+func (p *APNsPushProvider) resetToken() {
+	p.mu.Lock()
+	p.token = ""
+	p.mu.Unlock()
+}
+
+// apnsCollapseID 將 collapse key 截斷為 APNs 允許的 64 bytes
+func apnsCollapseID(key string) string {
+	if len(key) > apnsCollapseIDLimit {
+		return key[:apnsCollapseIDLimit]
+	}
+	return key
+}
+
+// WebhookPushProvider POSTs the PushNotification as JSON to an in-house
+// push gateway. With a secret the request is signed like a webhook
+// delivery (see SignWebhookPayload).
+type WebhookPushProvider struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookPushProvider returns a provider POSTing to url, signing with
+// secret when not empty.
+func NewWebhookPushProvider(url, secret string) *WebhookPushProvider {
+	return &WebhookPushProvider{url: url, secret: secret, client: &http.Client{Timeout: pushRequestTimeout}}
+}
+
+// Name returns PushProviderWebhook.
+func (p *WebhookPushProvider) Name() string { return PushProviderWebhook }
+
+// Push POSTs n and fails on a non-2xx response.
+func (p *WebhookPushProvider) Push(ctx context.Context, n *PushNotification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshal push notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-story-push")
+	if p.secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(p.secret, timestamp, body))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitResult describes the outcome of RateLimiter.Allow.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // 目前時間窗內還可使用的次數
+	RetryAfter time.Duration // 被拒絕時建議等待的時間
+	Reset      time.Duration // 距離目前時間窗結束的時間；無法計數時為零
+}
+
+// rateLimitBackend is implemented by backends that can count requests in
+// fixed windows atomically.
+type rateLimitBackend interface {
+	// CountRequest adds one request to curKey unless the sliding-window count
+	// (prevKey weighted by the part of the previous window still covered,
+	// plus curKey) already reached limit. It returns whether the request was
+	// counted and the resulting count.
+	CountRequest(ctx context.Context, curKey, prevKey string, limit int, window, elapsed time.Duration) (bool, int, error)
+}
+
+// RateLimiter throttles requests per key using a sliding window counter kept
+// in the cache's Redis connection, so limits are shared by every instance.
+type RateLimiter struct {
+	cache *Cache
+}
+
+// NewRateLimiter creates a limiter on top of cache.
+func NewRateLimiter(cache *Cache) *RateLimiter {
+	return &RateLimiter{cache: cache}
+}
+
+// Allow counts one request for key and reports whether it stays within limit
+// requests per window. The window slides: the previous window's count is
+// weighted by how much of it still overlaps. When Redis is unavailable (or
+// the cache is disabled) every request is allowed, so throttling never takes
+// the API down.
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	allowAll := RateLimitResult{Allowed: true, Limit: limit, Remaining: limit}
+	if l == nil || l.cache == nil || limit <= 0 || window <= 0 {
+		return allowAll, nil
+	}
+	c := l.cache
+	backend := c.active()
+	rb, ok := backend.(rateLimitBackend)
+	if !ok {
+		return allowAll, nil
+	}
+
+	now := time.Now()
+	index := now.UnixMilli() / window.Milliseconds()
+	elapsed := time.Duration(now.UnixMilli()-index*window.Milliseconds()) * time.Millisecond
+
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	start := time.Now()
+	allowed, count, err := rb.CountRequest(ctx, l.windowKey(key, index), l.windowKey(key, index-1), limit, window, elapsed)
+	c.observe(CacheOpRateLimit, start)
+	if err != nil {
+		c.metrics.Error(CacheOpRateLimit)
+		c.logger.Error("rate limit check failed", "key", key, "error", err)
+		c.handleBackendError(backend, err)
+		return allowAll, nil
+	}
+	c.handleBackendSuccess(backend)
+
+	result := RateLimitResult{Allowed: allowed, Limit: limit, Remaining: limit - count, Reset: window - elapsed}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	if !allowed {
+		result.RetryAfter = window - elapsed
+		c.logger.Debug("rate limited", "key", key, "limit", limit, "window", window)
+	}
+	return result, nil
+}
+
+// windowKey 回傳某個固定時間窗的計數 key；以 hash tag 讓同一個 key 的前後時間窗落在同一個 cluster slot
+func (l *RateLimiter) windowKey(key string, index int64) string {
+	return l.cache.fullKey("ratelimit:{" + key + "}:" + strconv.FormatInt(index, 10))
+}
+
+// rateLimitScript 以前一個時間窗的加權計數加上目前時間窗的計數判斷是否超過上限，
+// 未超過時才計入這次請求
+var rateLimitScript = redis.NewScript(`
+local cur = tonumber(redis.call("GET", KEYS[1]) or "0")
+local prev = tonumber(redis.call("GET", KEYS[2]) or "0")
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local count = math.floor(prev * (window - elapsed) / window) + cur
+if count >= limit then
+	return {0, count}
+end
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], window * 2)
+return {1, count + 1}
+`)
+
+func (b *redisBackend) CountRequest(ctx context.Context, curKey, prevKey string, limit int, window, elapsed time.Duration) (bool, int, error) {
+	res, err := rateLimitScript.Run(ctx, b.client, []string{curKey, prevKey}, limit, window.Milliseconds(), elapsed.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, int(res[1]), nil
+}
+
+func (b *tieredBackend) CountRequest(ctx context.Context, curKey, prevKey string, limit int, window, elapsed time.Duration) (bool, int, error) {
+	if rb, ok := b.remote.(rateLimitBackend); ok {
+		return rb.CountRequest(ctx, curKey, prevKey, limit, window, elapsed)
+	}
+	return true, 0, nil
+}
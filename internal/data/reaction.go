@@ -0,0 +1,339 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidReaction is returned (wrapped) for a reaction outside the
+	// configured set or a missing user.
+	ErrInvalidReaction = errors.New("invalid reaction")
+	// ErrReactionsUnsupported is returned by a nil ReactionService, i.e.
+	// when reactions are disabled.
+	ErrReactionsUnsupported = errors.New("reactions are not enabled")
+)
+
+// DefaultReactions is the reaction set used when none is configured.
+var DefaultReactions = []string{"like", "clap"}
+
+// reactionNamePattern 為回應名稱的格式；名稱會用在 Redis hash 的 field 中，不可包含分隔字元
+var reactionNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// reactionCounterName 為 Redis 中待寫入回應數的計數名稱；reactionFlushLock 與 reactionFlushLockTTL 為寫入時的鎖
+const (
+	reactionCounterName  = "story-reactions"
+	reactionFlushLock    = "reaction-flush"
+	reactionFlushLockTTL = 5 * time.Minute
+)
+
+// reactionTotalsCachePrefix 為已寫入資料庫的回應數的 cache key 前綴；寫入新的一批後清除該 story 的 key
+const reactionTotalsCachePrefix = "reactions:totals"
+
+// ReactionToggle is the result of ReactionService.Toggle: whether the user
+// now has the reaction, and the totals of the story after the toggle.
+type ReactionToggle struct {
+	StoryID  string           `json:"storyId"`
+	Reaction string           `json:"reaction"`
+	Active   bool             `json:"active"`
+	Totals   map[string]int64 `json:"totals"`
+}
+
+// ReactionService stores per-story reactions such as likes and claps. Each
+// user has a reaction at most once, kept in the story_reactions table; the
+// totals are counted in Redis like ViewCounter counts views and added to
+// the story_reaction_counts table in one batch by Flush, so a popular story
+// does not contend on a single row. While Redis is unavailable changes are
+// counted in memory and flushed by this instance.
+type ReactionService struct {
+	db        *sql.DB
+	cache     *Cache
+	reactions []string
+
+	mu    sync.Mutex
+	local map[string]int64 // Redis 無法使用時暫存的增減，key 見 reactionField
+}
+
+// NewReactionService returns a service storing reactions in db (see
+// internal/data/migrations) for the reaction names in reactions, or
+// DefaultReactions when empty. Names are lower-case letters, digits, "-"
+// and "_".
+func NewReactionService(db *sql.DB, cache *Cache, reactions []string) (*ReactionService, error) {
+	if len(reactions) == 0 {
+		reactions = DefaultReactions
+	}
+	seen := make(map[string]bool, len(reactions))
+	for _, reaction := range reactions {
+		if !reactionNamePattern.MatchString(reaction) {
+			return nil, fmt.Errorf("%w: %q is not a valid reaction name", ErrInvalidReaction, reaction)
+		}
+		if seen[reaction] {
+			return nil, fmt.Errorf("%w: duplicate reaction %q", ErrInvalidReaction, reaction)
+		}
+		seen[reaction] = true
+	}
+	return &ReactionService{db: db, cache: cache, reactions: reactions, local: map[string]int64{}}, nil
+}
+
+// Reactions returns the configured reaction names.
+func (s *ReactionService) Reactions() []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s.reactions...)
+}
+
+// Toggle adds reaction to the story with storyID for userID, or removes it
+// when the user already has it.
+func (s *ReactionService) Toggle(ctx context.Context, storyID, userID, reaction string) (*ReactionToggle, error) {
+	if s == nil {
+		return nil, ErrReactionsUnsupported
+	}
+	userID = strings.TrimSpace(userID)
+	switch {
+	case userID == "":
+		return nil, fmt.Errorf("%w: userId is required", ErrInvalidReaction)
+	case len(userID) > maxCommentUserIDLength:
+		return nil, fmt.Errorf("%w: userId is longer than %d bytes", ErrInvalidReaction, maxCommentUserIDLength)
+	case !s.valid(reaction):
+		return nil, fmt.Errorf("%w: unknown reaction %q, expected one of %s", ErrInvalidReaction, reaction, strings.Join(s.reactions, ", "))
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	// 先嘗試移除，沒有可移除的才新增；計數只依實際異動的列增減，重複或同時送出的請求不會重複計算
+	res, err := s.db.ExecContext(opCtx, `DELETE FROM story_reactions WHERE story_id = $1 AND user_id = $2 AND reaction = $3`,
+		storyID, userID, reaction)
+	if err != nil {
+		return nil, fmt.Errorf("remove reaction: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("remove reaction: %w", err)
+	}
+	active := false
+	if removed > 0 {
+		s.add(ctx, storyID, reaction, -removed)
+	} else {
+		res, err := s.db.ExecContext(opCtx, `INSERT INTO story_reactions (story_id, user_id, reaction) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, storyID, userID, reaction)
+		if err != nil {
+			return nil, fmt.Errorf("add reaction: %w", err)
+		}
+		added, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("add reaction: %w", err)
+		}
+		s.add(ctx, storyID, reaction, added)
+		active = true
+	}
+
+	totals, err := s.Totals(ctx, storyID)
+	if err != nil {
+		return nil, err
+	}
+	return &ReactionToggle{StoryID: storyID, Reaction: reaction, Active: active, Totals: totals}, nil
+}
+
+// Totals returns the number of each configured reaction on the story with
+// storyID, including changes not flushed yet. On a nil ReactionService it
+// returns nil.
+func (s *ReactionService) Totals(ctx context.Context, storyID string) (map[string]int64, error) {
+	if s == nil {
+		return nil, nil
+	}
+	stored, err := NewTypedCache[map[string]int64](s.cache).GetOrSet(ctx, s.totalsKey(storyID), 0, func(ctx context.Context) (map[string]int64, error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		rows, err := s.db.QueryContext(ctx, `SELECT reaction, count FROM story_reaction_counts WHERE story_id = $1`, storyID)
+		if err != nil {
+			return nil, fmt.Errorf("count reactions: %w", err)
+		}
+		defer rows.Close()
+		counts := map[string]int64{}
+		for rows.Next() {
+			var reaction string
+			var n int64
+			if err := rows.Scan(&reaction, &n); err != nil {
+				return nil, fmt.Errorf("scan reaction count: %w", err)
+			}
+			counts[reaction] = n
+		}
+		return counts, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]int64, len(s.reactions))
+	for _, reaction := range s.reactions {
+		field := reactionField(storyID, reaction)
+		pending, err := s.cache.PendingCount(ctx, reactionCounterName, field)
+		if err != nil {
+			slog.Debug("read pending reaction count failed", "id", storyID, "reaction", reaction, "error", err)
+		}
+		s.mu.Lock()
+		total := stored[reaction] + pending + s.local[field]
+		s.mu.Unlock()
+		// 尚未寫入的移除可能先於對應的新增被計入，總數不會小於 0
+		totals[reaction] = max(total, 0)
+	}
+	return totals, nil
+}
+
+// Flush adds the counted changes to the story_reaction_counts table and
+// returns how many story reactions were updated. With Redis the batch is
+// held under a lock so only one instance writes it; a failed batch is
+// retried by the next Flush.
+func (s *ReactionService) Flush(ctx context.Context) (int, error) {
+	if s == nil {
+		return 0, ErrReactionsUnsupported
+	}
+
+	// process 中暫存的增減
+	s.mu.Lock()
+	local := s.local
+	s.local = map[string]int64{}
+	s.mu.Unlock()
+	flushed := 0
+	if len(local) > 0 {
+		if err := s.write(ctx, local); err != nil {
+			s.restore(local)
+			return 0, err
+		}
+		flushed += len(local)
+	}
+
+	lock, err := s.cache.Lock(ctx, reactionFlushLock, reactionFlushLockTTL)
+	if errors.Is(err, ErrLockNotAcquired) {
+		return flushed, nil
+	}
+	if err != nil {
+		return flushed, err
+	}
+	defer func() { _ = lock.Unlock(context.WithoutCancel(ctx)) }()
+
+	counts, commit, err := s.cache.DrainCounts(ctx, reactionCounterName)
+	if err != nil {
+		return flushed, err
+	}
+	if len(counts) == 0 {
+		return flushed, nil
+	}
+	if err := s.write(ctx, counts); err != nil {
+		return flushed, err
+	}
+	if err := commit(ctx); err != nil {
+		// 已寫入但未能移除這一批，下次會重複寫入，記錄下來方便追查
+		slog.Error("failed to commit flushed reaction counts", "fields", len(counts), "error", err)
+		return flushed + len(counts), err
+	}
+	return flushed + len(counts), nil
+}
+
+// Run calls Flush every interval until ctx is done, and once more before
+// returning.
+func (s *ReactionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			s.flush(ctx)
+		}
+	}
+}
+
+// flush 執行 Flush 並記錄結果
+func (s *ReactionService) flush(ctx context.Context) {
+	n, err := s.Flush(ctx)
+	if err != nil {
+		slog.Warn("failed to flush reaction counts", "error", err)
+		return
+	}
+	if n > 0 {
+		slog.Debug("flushed reaction counts", "fields", n)
+	}
+}
+
+// write 將一批增減累加到 story_reaction_counts，並清除相關 story 已快取的總數
+func (s *ReactionService) write(ctx context.Context, counts map[string]int64) error {
+	countsJSON, err := json.Marshal(counts)
+	if err != nil {
+		return fmt.Errorf("marshal reaction counts: %w", err)
+	}
+	opCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err = s.db.ExecContext(opCtx, `INSERT INTO story_reaction_counts (story_id, reaction, count)
+		SELECT split_part(v.field, '|', 1), split_part(v.field, '|', 2), GREATEST(v.n::bigint, 0) FROM jsonb_each_text($1::jsonb) AS v(field, n)
+		ON CONFLICT (story_id, reaction) DO UPDATE SET count = GREATEST(story_reaction_counts.count + EXCLUDED.count, 0)`,
+		string(countsJSON))
+	if err != nil {
+		return fmt.Errorf("add reaction counts: %w", err)
+	}
+
+	stories := map[string]bool{}
+	for field := range counts {
+		storyID, _, _ := strings.Cut(field, "|")
+		stories[storyID] = true
+	}
+	for storyID := range stories {
+		if err := NewTypedCache[map[string]int64](s.cache).Delete(context.WithoutCancel(ctx), s.totalsKey(storyID)); err != nil {
+			slog.Warn("failed to invalidate reaction totals", "story", storyID, "error", err)
+		}
+	}
+	return nil
+}
+
+// add 累計一筆增減；Redis 無法使用時暫存在 process 中
+func (s *ReactionService) add(ctx context.Context, storyID, reaction string, n int64) {
+	if n == 0 {
+		return
+	}
+	field := reactionField(storyID, reaction)
+	if s.cache.AddCount(ctx, reactionCounterName, field, n) {
+		return
+	}
+	s.mu.Lock()
+	s.local[field] += n
+	s.mu.Unlock()
+}
+
+// restore 將寫入失敗的增減加回 process 中暫存的計數
+func (s *ReactionService) restore(counts map[string]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for field, n := range counts {
+		s.local[field] += n
+	}
+}
+
+func (s *ReactionService) valid(reaction string) bool {
+	for _, r := range s.reactions {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+// totalsKey 回傳 story 已寫入資料庫的回應數的 cache key
+func (s *ReactionService) totalsKey(storyID string) string {
+	return NewCacheKey(reactionTotalsCachePrefix).Field("story", storyID).String()
+}
+
+// reactionField 回傳計數中 story 的一種回應的 field
+func reactionField(storyID, reaction string) string {
+	return storyID + "|" + reaction
+}
@@ -0,0 +1,182 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrReadingProgressNotFound is returned when a reader has no reading
+	// position for a story.
+	ErrReadingProgressNotFound = errors.New("reading progress not found")
+	// ErrInvalidReadingProgress is returned (wrapped) for a position outside
+	// 0–1, a too long anchor or a missing user.
+	ErrInvalidReadingProgress = errors.New("invalid reading progress")
+	// ErrReadingProgressUnsupported is returned by a nil
+	// ReadingProgressService, i.e. when reading progress is disabled.
+	ErrReadingProgressUnsupported = errors.New("reading progress is not enabled")
+)
+
+// readingProgressCachePrefix 為閱讀位置的 cache key 前綴；readingProgressTTL 為 Redis 中保存的時間，
+// readingProgressPersistInterval 為同一篇 story 寫入資料庫的最短間隔
+const (
+	readingProgressCachePrefix     = "progress"
+	readingProgressTTL             = 30 * 24 * time.Hour
+	readingProgressPersistInterval = 30 * time.Second
+	maxReadingProgressAnchorLength = 200
+)
+
+// ReadingProgress is how far a reader got in a story: Position is the
+// fraction of the story scrolled, from 0 to 1, and Anchor optionally the ID
+// of the nearest heading (see TOCEntry) for apps that restore by heading.
+type ReadingProgress struct {
+	StoryID   string    `json:"storyId"`
+	Position  float64   `json:"position"`
+	Anchor    string    `json:"anchor,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// readingProgressEntry 為 cache 中的閱讀位置與上次寫入資料庫的時間
+type readingProgressEntry struct {
+	Progress    ReadingProgress `json:"progress"`
+	PersistedAt time.Time       `json:"persistedAt"`
+}
+
+// ReadingProgressService syncs readers' reading positions across devices.
+// The latest position is kept in Redis, as apps report it while the reader
+// scrolls; it is written to the reading_progress table at most every 30
+// seconds per story, and on every save while Redis holds no copy. Reads
+// fall back to the table, which may lag Redis by that interval.
+type ReadingProgressService struct {
+	db    *sql.DB
+	cache *Cache
+}
+
+// NewReadingProgressService returns a service keeping positions in cache
+// and db (see internal/data/migrations).
+func NewReadingProgressService(db *sql.DB, cache *Cache) *ReadingProgressService {
+	return &ReadingProgressService{db: db, cache: cache}
+}
+
+// Save records the reading position of userID in the story with storyID.
+// UpdatedAt is assigned here.
+func (s *ReadingProgressService) Save(ctx context.Context, userID, storyID string, progress ReadingProgress) (*ReadingProgress, error) {
+	if s == nil {
+		return nil, ErrReadingProgressUnsupported
+	}
+	progress.Anchor = strings.TrimSpace(progress.Anchor)
+	switch {
+	case strings.TrimSpace(userID) == "":
+		return nil, fmt.Errorf("%w: user is required", ErrInvalidReadingProgress)
+	case len(userID) > maxCommentUserIDLength:
+		return nil, fmt.Errorf("%w: user is longer than %d bytes", ErrInvalidReadingProgress, maxCommentUserIDLength)
+	case math.IsNaN(progress.Position) || progress.Position < 0 || progress.Position > 1:
+		return nil, fmt.Errorf("%w: position must be between 0 and 1", ErrInvalidReadingProgress)
+	case utf8.RuneCountInString(progress.Anchor) > maxReadingProgressAnchorLength:
+		return nil, fmt.Errorf("%w: anchor is longer than %d characters", ErrInvalidReadingProgress, maxReadingProgressAnchorLength)
+	}
+	progress.StoryID = storyID
+	progress.UpdatedAt = time.Now().UTC()
+
+	// 上次寫入資料庫不久時只更新 Redis；cache 中沒有資料 (Redis 無法使用或已過期) 時一律寫入資料庫
+	entry := readingProgressEntry{Progress: progress}
+	var cached readingProgressEntry
+	found, err := s.cache.Get(ctx, s.key(userID, storyID), &cached)
+	if err != nil {
+		slog.Debug("read cached reading progress failed", "story", storyID, "error", err)
+	}
+	if found && err == nil && progress.UpdatedAt.Sub(cached.PersistedAt) < readingProgressPersistInterval {
+		entry.PersistedAt = cached.PersistedAt
+	} else {
+		if err := s.persist(ctx, userID, progress); err != nil {
+			return nil, err
+		}
+		entry.PersistedAt = progress.UpdatedAt
+	}
+	if err := s.cache.SetWithTTL(ctx, s.key(userID, storyID), entry, readingProgressTTL); err != nil {
+		slog.Warn("failed to cache reading progress", "story", storyID, "error", err)
+	}
+	return &progress, nil
+}
+
+// Progress returns the latest reading position of userID in the story
+// with storyID, or ErrReadingProgressNotFound.
+func (s *ReadingProgressService) Progress(ctx context.Context, userID, storyID string) (*ReadingProgress, error) {
+	if s == nil {
+		return nil, ErrReadingProgressUnsupported
+	}
+	var cached readingProgressEntry
+	if found, err := s.cache.Get(ctx, s.key(userID, storyID), &cached); err == nil && found {
+		return &cached.Progress, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	progress := ReadingProgress{StoryID: storyID}
+	err := s.db.QueryRowContext(ctx, `SELECT position, anchor, updated_at FROM reading_progress WHERE user_id = $1 AND story_id = $2`,
+		userID, storyID).Scan(&progress.Position, &progress.Anchor, &progress.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReadingProgressNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reading progress: %w", err)
+	}
+	return &progress, nil
+}
+
+// InProgress returns the stories userID started but did not finish
+// (position below 1), most recently read first, for a "continue reading"
+// list. Positions newer than the table are taken from Redis.
+func (s *ReadingProgressService) InProgress(ctx context.Context, userID string, limit, offset int) ([]ReadingProgress, error) {
+	if s == nil {
+		return nil, ErrReadingProgressUnsupported
+	}
+	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := s.db.QueryContext(opCtx, `SELECT story_id, position, anchor, updated_at FROM reading_progress
+		WHERE user_id = $1 AND position < 1 ORDER BY updated_at DESC, story_id LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list reading progress: %w", err)
+	}
+	defer rows.Close()
+	list := []ReadingProgress{}
+	for rows.Next() {
+		var progress ReadingProgress
+		if err := rows.Scan(&progress.StoryID, &progress.Position, &progress.Anchor, &progress.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan reading progress: %w", err)
+		}
+		var cached readingProgressEntry
+		if found, err := s.cache.Get(ctx, s.key(userID, progress.StoryID), &cached); err == nil && found &&
+			cached.Progress.UpdatedAt.After(progress.UpdatedAt) {
+			progress = cached.Progress
+		}
+		list = append(list, progress)
+	}
+	return list, rows.Err()
+}
+
+// persist 將閱讀位置寫入資料庫；其他裝置已寫入較新的位置時不覆寫
+func (s *ReadingProgressService) persist(ctx context.Context, userID string, progress ReadingProgress) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO reading_progress (user_id, story_id, position, anchor, updated_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, story_id) DO UPDATE SET position = EXCLUDED.position, anchor = EXCLUDED.anchor, updated_at = EXCLUDED.updated_at
+		WHERE reading_progress.updated_at <= EXCLUDED.updated_at`,
+		userID, progress.StoryID, progress.Position, progress.Anchor, progress.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save reading progress: %w", err)
+	}
+	return nil
+}
+
+// key 回傳讀者在 story 的閱讀位置的 cache key
+func (s *ReadingProgressService) key(userID, storyID string) string {
+	return NewCacheKey(readingProgressCachePrefix).Field("user", userID).Field("story", storyID).String()
+}
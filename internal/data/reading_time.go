@@ -0,0 +1,96 @@
+package data
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Reading speeds used for Story.ReadingTime: words per minute for
+// space-separated scripts and characters per minute for Chinese and
+// Japanese, which are counted per character.
+const (
+	WordsPerMinute    = 230
+	CJKCharsPerMinute = 400
+)
+
+// TextStats is the word count and estimated reading time of a text.
+type TextStats struct {
+	Words       int // 空白分隔的詞，加上中日文的字數
+	CJKChars    int // 中日文的字數 (已包含在 Words 中)
+	ReadingTime int // 分鐘，有內容時至少 1
+}
+
+// CountText counts the words of text, HTML or Markdown: tags and entity
+// escapes are skipped, runs of letters or digits separated by spaces or
+// punctuation count as one word each, and every Han, Hiragana or Katakana
+// character counts as a word on its own (Korean is written with spaces and
+// counted by word). ReadingTime combines WordsPerMinute for words and
+// CJKCharsPerMinute for CJK characters, rounded up to whole minutes.
+func CountText(text string) TextStats {
+	var stats TextStats
+	inWord := false
+	for _, r := range html.UnescapeString(stripHTMLTags(text)) {
+		switch {
+		case isCJKChar(r):
+			stats.CJKChars++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if !inWord {
+				stats.Words++
+			}
+			inWord = true
+		case r == '\'' || r == '’' || r == '-':
+			// 縮寫與連字號不拆開詞 (don't、well-known)
+		default:
+			inWord = false
+		}
+	}
+	latin := stats.Words
+	stats.Words += stats.CJKChars
+	if stats.Words > 0 {
+		minutes := float64(latin)/WordsPerMinute + float64(stats.CJKChars)/CJKCharsPerMinute
+		stats.ReadingTime = max(1, int(math.Ceil(minutes)))
+	}
+	return stats
+}
+
+// isCJKChar 回傳字元是否為逐字計算的中日文 (漢字、平假名、片假名)
+func isCJKChar(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// stripHTMLTags 移除 HTML 標籤與 script / style 的內容，標籤以空白取代，避免相鄰段落的字連在一起
+func stripHTMLTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	var sb strings.Builder
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			sb.WriteString(s)
+			break
+		}
+		sb.WriteString(s[:i])
+		tag, n := readHTMLTag(s[i:])
+		s = s[i+n:]
+		switch {
+		case tag.kind == htmlTagText:
+			sb.WriteByte('<')
+		case tag.kind == htmlTagStart && rawTextElements[tag.name]:
+			s = skipRawText(s, tag.name)
+		default:
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
+
+// setReadingStats 依 story 的 body 計算字數與閱讀時間
+func setReadingStats(story *Story) {
+	stats := CountText(story.Body)
+	story.WordCount = stats.Words
+	story.ReadingTime = stats.ReadingTime
+}
@@ -131,6 +131,12 @@ type Topic struct {
 	Metadata                     map[string]any `json:"-"`
 }
 
+// CacheSensitive reports whether the story is member-only content, whose
+// cached copy is encrypted when cache encryption is configured.
+func (p Post) CacheSensitive() bool {
+	return p.IsMember
+}
+
 type Post struct {
 	ID                     string           `json:"id"`
 	Slug                   string           `json:"slug"`
@@ -409,20 +415,28 @@ func (r *Repo) QueryPosts(ctx context.Context, where *PostWhereInput, orders []O
 	defer cancel()
 
 	where = ensurePostPublished(where)
-
-	// 嘗試從 cache 讀取
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("posts", map[string]interface{}{
-			"where":  where,
-			"orders": orders,
-			"take":   take,
-			"skip":   skip,
-		})
-		var cachedPosts []Post
-		if found, _ := r.cache.Get(ctx, cacheKey, &cachedPosts); found {
-			return cachedPosts, nil
-		}
+	cacheKey := NewCacheKey("posts").
+		Field("where", where).
+		Field("orders", orders).
+		Field("take", take).
+		Field("skip", skip).
+		ShortHash().
+		String()
+
+	// 從 cache 讀取；過期資料會先回傳並在背景更新，miss 時同一個 key 只打一次 DB
+	var posts []Post
+	err := r.cache.GetOrRefresh(ctx, cacheKey, &posts, func(ctx context.Context) (interface{}, error) {
+		return r.loadPosts(ctx, where, orders, take, skip)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return posts, nil
+}
+
+func (r *Repo) loadPosts(ctx context.Context, where *PostWhereInput, orders []OrderRule, take, skip int) ([]Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	sb := strings.Builder{}
 	sb.WriteString(`SELECT id, slug, title, subtitle, state, style, "isMember", "isAdult", "publishedDate", "updatedAt", COALESCE("heroCaption",'') as heroCaption, COALESCE("extend_byline",'') as extend_byline, "heroImage", "heroVideo", brief, content, COALESCE(redirect,'') as redirect, COALESCE(og_title,'') as og_title, COALESCE(og_description,'') as og_description, "hiddenAdvertised", "isAdvertised", "isFeatured", topics, "og_image", "relatedsOne", "relatedsTwo", "manualOrderOfRelateds" FROM "Post" p`)
@@ -602,17 +616,6 @@ func (r *Repo) QueryPosts(ctx context.Context, where *PostWhereInput, orders []O
 		return nil, err
 	}
 
-	// 寫入 cache
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("posts", map[string]interface{}{
-			"where":  where,
-			"orders": orders,
-			"take":   take,
-			"skip":   skip,
-		})
-		_ = r.cache.Set(ctx, cacheKey, posts)
-	}
-
 	return posts, nil
 }
 
@@ -706,15 +709,27 @@ func (r *Repo) QueryPostByUnique(ctx context.Context, where *PostWhereUniqueInpu
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// 嘗試從 cache 讀取
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("post:unique", where)
-		var cachedPost *Post
-		if found, _ := r.cache.Get(ctx, cacheKey, &cachedPost); found {
-			return cachedPost, nil
-		}
+	cacheKey := NewCacheKey("post:unique").Fields(where, "id", "slug").ShortHash().String()
+
+	// 記錄熱門度供啟動時預熱使用，不阻塞請求
+	if r.cache != nil && r.cache.trackPopularity && where.Slug != nil {
+		go r.cache.RecordPopularity(context.WithoutCancel(ctx), popularPostsList, *where.Slug)
 	}
 
+	// 從 cache 讀取；miss 時同一個 key 只打一次 DB，查無資料時寫入 not found 標記
+	post, err := NewTypedCache[*Post](r.cache).GetOrSet(ctx, cacheKey, 0, func(ctx context.Context) (*Post, error) {
+		return r.loadPostByUnique(ctx, where)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (r *Repo) loadPostByUnique(ctx context.Context, where *PostWhereUniqueInput) (*Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
 	sb := strings.Builder{}
 	sb.WriteString(`SELECT id, slug, title, subtitle, state, style, "isMember", "isAdult", "publishedDate", "updatedAt", COALESCE("heroCaption",'') as heroCaption, COALESCE("extend_byline",'') as extend_byline, "heroImage", "heroVideo", brief, content, COALESCE(redirect,'') as redirect, COALESCE(og_title,'') as og_title, COALESCE(og_description,'') as og_description, "hiddenAdvertised", "isAdvertised", "isFeatured", topics, "og_image", "relatedsOne", "relatedsTwo", "manualOrderOfRelateds" FROM "Post" p WHERE `)
 	args := []interface{}{}
@@ -810,12 +825,6 @@ func (r *Repo) QueryPostByUnique(ctx context.Context, where *PostWhereUniqueInpu
 	}
 	p = posts[0]
 
-	// 寫入 cache
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("post:unique", where)
-		_ = r.cache.Set(ctx, cacheKey, &p)
-	}
-
 	return &p, nil
 }
 
@@ -824,20 +833,28 @@ func (r *Repo) QueryExternals(ctx context.Context, where *ExternalWhereInput, or
 	defer cancel()
 
 	where = ensureExternalPublished(where)
-
-	// 嘗試從 cache 讀取
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("externals", map[string]interface{}{
-			"where":  where,
-			"orders": orders,
-			"take":   take,
-			"skip":   skip,
-		})
-		var cachedExternals []External
-		if found, _ := r.cache.Get(ctx, cacheKey, &cachedExternals); found {
-			return cachedExternals, nil
-		}
+	cacheKey := NewCacheKey("externals").
+		Field("where", where).
+		Field("orders", orders).
+		Field("take", take).
+		Field("skip", skip).
+		ShortHash().
+		String()
+
+	// 從 cache 讀取；過期資料會先回傳並在背景更新，miss 時同一個 key 只打一次 DB
+	var externals []External
+	err := r.cache.GetOrRefresh(ctx, cacheKey, &externals, func(ctx context.Context) (interface{}, error) {
+		return r.loadExternals(ctx, where, orders, take, skip)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return externals, nil
+}
+
+func (r *Repo) loadExternals(ctx context.Context, where *ExternalWhereInput, orders []OrderRule, take, skip int) ([]External, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	sb := strings.Builder{}
 	sb.WriteString(`SELECT e.id, e.slug, e.title, e.state, e."publishedDate", e."publishedDateString", e."extend_byline", e.thumb, e."thumbCaption", e.brief, e.content, e.source, e.partner, e."createdAt", e."updatedAt" FROM "External" e`)
@@ -979,17 +996,6 @@ func (r *Repo) QueryExternals(ctx context.Context, where *ExternalWhereInput, or
 		}
 	}
 
-	// 寫入 cache
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("externals", map[string]interface{}{
-			"where":  where,
-			"orders": orders,
-			"take":   take,
-			"skip":   skip,
-		})
-		_ = r.cache.Set(ctx, cacheKey, result)
-	}
-
 	return result, nil
 }
 
@@ -1037,19 +1043,28 @@ func (r *Repo) QueryTopics(ctx context.Context, where *TopicWhereInput, orders [
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// 嘗試從 cache 讀取
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("topics", map[string]interface{}{
-			"where":  where,
-			"orders": orders,
-			"take":   take,
-			"skip":   skip,
-		})
-		var cachedTopics []Topic
-		if found, _ := r.cache.Get(ctx, cacheKey, &cachedTopics); found {
-			return cachedTopics, nil
-		}
+	cacheKey := NewCacheKey("topics").
+		Field("where", where).
+		Field("orders", orders).
+		Field("take", take).
+		Field("skip", skip).
+		ShortHash().
+		String()
+
+	// 從 cache 讀取；過期資料會先回傳並在背景更新，miss 時同一個 key 只打一次 DB
+	var topics []Topic
+	err := r.cache.GetOrRefresh(ctx, cacheKey, &topics, func(ctx context.Context) (interface{}, error) {
+		return r.loadTopics(ctx, where, orders, take, skip)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return topics, nil
+}
+
+func (r *Repo) loadTopics(ctx context.Context, where *TopicWhereInput, orders []OrderRule, take, skip int) ([]Topic, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	sb := strings.Builder{}
 	sb.WriteString(`SELECT id, name, slug, "sortOrder", state, brief, "heroImage", "heroUrl", "leading", "og_title", "og_description", "og_image", "isFeatured", "title_style", type, style, javascript, dfp, "mobile_dfp", "createdAt", "updatedAt" FROM "Topic" t`)
@@ -1218,17 +1233,6 @@ func (r *Repo) QueryTopics(ctx context.Context, where *TopicWhereInput, orders [
 		return nil, err
 	}
 
-	// 寫入 cache
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("topics", map[string]interface{}{
-			"where":  where,
-			"orders": orders,
-			"take":   take,
-			"skip":   skip,
-		})
-		_ = r.cache.Set(ctx, cacheKey, topics)
-	}
-
 	return topics, nil
 }
 
@@ -1236,14 +1240,21 @@ func (r *Repo) QueryTopicsCount(ctx context.Context, where *TopicWhereInput) (in
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	// 嘗試從 cache 讀取
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("topicsCount", where)
-		var cachedCount int
-		if found, _ := r.cache.Get(ctx, cacheKey, &cachedCount); found {
-			return cachedCount, nil
-		}
+	cacheKey := NewCacheKey("topicsCount").Fields(where).ShortHash().String()
+
+	// 從 cache 讀取；miss 時同一個 key 只打一次 DB
+	count, err := NewTypedCache[int](r.cache).GetOrSet(ctx, cacheKey, 0, func(ctx context.Context) (int, error) {
+		return r.loadTopicsCount(ctx, where)
+	})
+	if err != nil {
+		return 0, err
 	}
+	return count, nil
+}
+
+func (r *Repo) loadTopicsCount(ctx context.Context, where *TopicWhereInput) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
 	sb := strings.Builder{}
 	sb.WriteString(`SELECT COUNT(*) FROM "Topic" t`)
@@ -1286,12 +1297,6 @@ func (r *Repo) QueryTopicsCount(ctx context.Context, where *TopicWhereInput) (in
 		return 0, err
 	}
 
-	// 寫入 cache
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("topicsCount", where)
-		_ = r.cache.Set(ctx, cacheKey, count)
-	}
-
 	return count, nil
 }
 
@@ -1302,14 +1307,21 @@ func (r *Repo) QueryTopicByUnique(ctx context.Context, where *TopicWhereUniqueIn
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// 嘗試從 cache 讀取
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("topic:unique", where)
-		var cachedTopic *Topic
-		if found, _ := r.cache.Get(ctx, cacheKey, &cachedTopic); found {
-			return cachedTopic, nil
-		}
+	cacheKey := NewCacheKey("topic:unique").Fields(where, "id", "name", "slug").ShortHash().String()
+
+	// 從 cache 讀取；miss 時同一個 key 只打一次 DB，查無資料時寫入 not found 標記
+	topic, err := NewTypedCache[*Topic](r.cache).GetOrSet(ctx, cacheKey, 0, func(ctx context.Context) (*Topic, error) {
+		return r.loadTopicByUnique(ctx, where)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return topic, nil
+}
+
+func (r *Repo) loadTopicByUnique(ctx context.Context, where *TopicWhereUniqueInput) (*Topic, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	sb := strings.Builder{}
 	sb.WriteString(`SELECT id, name, slug, "sortOrder", state, brief, "heroImage", "heroUrl", "leading", "og_title", "og_description", "og_image", "isFeatured", "title_style", type, style, javascript, dfp, "mobile_dfp", "createdAt", "updatedAt" FROM "Topic" t WHERE `)
@@ -1435,12 +1447,6 @@ func (r *Repo) QueryTopicByUnique(ctx context.Context, where *TopicWhereUniqueIn
 	}
 	t = topics[0]
 
-	// 寫入 cache
-	if r.cache != nil && r.cache.Enabled() {
-		cacheKey := GenerateCacheKey("topic:unique", where)
-		_ = r.cache.Set(ctx, cacheKey, &t)
-	}
-
 	return &t, nil
 }
 
@@ -0,0 +1,407 @@
+package data
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultHTMLAllowlist is the allowlist used when none is configured (see
+// NewHTMLSanitizer for the syntax): text formatting, headings, lists,
+// quotes, links, images, figures and tables, with the attributes the
+// rendered content blocks use.
+const DefaultHTMLAllowlist = "p,br,hr,h1,h2,h3,h4,h5,h6,strong,b,em,i,u,s,del,ins,sub,sup,small,mark,span,div," +
+	"abbr[title],cite,q[cite],blockquote[cite],code,pre,ul,ol[start],li,dl,dt,dd,time[datetime]," +
+	"a[href|title|rel|target],img[src|alt|title|width|height|loading],figure[class|data-url],figcaption," +
+	"table,caption,thead,tbody,tfoot,tr,th[colspan|rowspan|scope],td[colspan|rowspan]"
+
+// DefaultExternalLinkRel is the rel added to links to other sites when none
+// is configured.
+var DefaultExternalLinkRel = []string{"noopener", "nofollow"}
+
+// rawTextElements 的內容不是 HTML，過濾時連同內容一併移除，且不可加入 allowlist
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true, "iframe": true,
+	"noembed": true, "noframes": true, "noscript": true, "plaintext": true,
+}
+
+// unsafeElements 為不可加入 allowlist 的元素：可執行內容或載入外部文件、表單
+var unsafeElements = map[string]bool{
+	"object": true, "embed": true, "applet": true, "frame": true, "frameset": true, "base": true, "link": true,
+	"meta": true, "form": true, "input": true, "button": true, "select": true, "option": true, "svg": true, "math": true,
+	"template": true,
+}
+
+// voidElements 沒有結束標籤
+var voidElements = map[string]bool{
+	"area": true, "br": true, "col": true, "hr": true, "img": true, "source": true, "track": true, "wbr": true,
+}
+
+// urlAttributes 的值為網址，只保留相對網址與 http、https、mailto、tel
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true, "poster": true}
+
+// safeURLSchemes 為網址屬性允許的 scheme
+var safeURLSchemes = map[string]bool{"http": true, "https": true, "mailto": true, "tel": true}
+
+// HTMLSanitizer cleans story HTML against an allowlist of elements and
+// their attributes. Elements outside the allowlist are removed but their
+// text is kept, except script, style and the other raw text elements,
+// which are removed with their content; comments and attributes outside
+// the allowlist (including every on* handler) are dropped. URLs must be
+// relative or use http, https, mailto or tel; protocol-relative URLs
+// (//host/path) are rewritten to https. Links to other hosts than the
+// site get the configured rel values. The output is re-serialized, so
+// text and attribute values are always escaped and unclosed elements are
+// closed.
+type HTMLSanitizer struct {
+	allowed  map[string]map[string]bool
+	siteHost string
+	rel      []string
+}
+
+// NewHTMLSanitizer returns a sanitizer for allowlist (DefaultHTMLAllowlist
+// when empty): comma-separated element names, each optionally followed by
+// its allowed attributes in brackets, e.g. "p,br,a[href|title]". Links
+// whose host differs from siteURL's (every absolute link when siteURL is
+// empty) get externalRel, or DefaultExternalLinkRel when nil. Raw text
+// elements such as script and elements that load or run other content
+// (object, embed, form, svg, ...) cannot be allowed, nor can on* or style
+// attributes.
+func NewHTMLSanitizer(allowlist, siteURL string, externalRel []string) (*HTMLSanitizer, error) {
+	if allowlist == "" {
+		allowlist = DefaultHTMLAllowlist
+	}
+	if externalRel == nil {
+		externalRel = DefaultExternalLinkRel
+	}
+	allowed := map[string]map[string]bool{}
+	for _, item := range strings.Split(allowlist, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		name, attrs := item, ""
+		if i := strings.IndexByte(item, '['); i >= 0 {
+			if !strings.HasSuffix(item, "]") {
+				return nil, fmt.Errorf("html allowlist: malformed entry %q", item)
+			}
+			name, attrs = item[:i], item[i+1:len(item)-1]
+		}
+		if !validHTMLName(name) {
+			return nil, fmt.Errorf("html allowlist: invalid element %q", name)
+		}
+		if rawTextElements[name] || unsafeElements[name] {
+			return nil, fmt.Errorf("html allowlist: element %q cannot be allowed", name)
+		}
+		if allowed[name] == nil {
+			allowed[name] = map[string]bool{}
+		}
+		for _, attr := range strings.Split(attrs, "|") {
+			if attr = strings.TrimSpace(attr); attr == "" {
+				continue
+			}
+			if !validHTMLName(attr) {
+				return nil, fmt.Errorf("html allowlist: invalid attribute %q of %s", attr, name)
+			}
+			if strings.HasPrefix(attr, "on") || attr == "style" || attr == "srcdoc" || attr == "formaction" {
+				return nil, fmt.Errorf("html allowlist: attribute %q cannot be allowed", attr)
+			}
+			allowed[name][attr] = true
+		}
+	}
+
+	var siteHost string
+	if siteURL != "" {
+		u, err := url.Parse(siteURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse site url: %w", err)
+		}
+		siteHost = strings.ToLower(u.Hostname())
+	}
+	return &HTMLSanitizer{allowed: allowed, siteHost: siteHost, rel: externalRel}, nil
+}
+
+// Sanitize returns s cleaned against the allowlist. A nil sanitizer
+// returns s unchanged.
+func (z *HTMLSanitizer) Sanitize(s string) string {
+	if z == nil || s == "" {
+		return s
+	}
+	var (
+		sb   strings.Builder
+		open []string
+	)
+	sb.Grow(len(s))
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			writeHTMLText(&sb, s)
+			break
+		}
+		writeHTMLText(&sb, s[:i])
+		tag, n := readHTMLTag(s[i:])
+		s = s[i+n:]
+
+		switch tag.kind {
+		case htmlTagText:
+			sb.WriteString("&lt;")
+		case htmlTagStart:
+			if rawTextElements[tag.name] {
+				s = skipRawText(s, tag.name)
+				continue
+			}
+			attrs, ok := z.allowed[tag.name]
+			if !ok {
+				continue
+			}
+			sb.WriteString("<" + tag.name)
+			z.writeAttributes(&sb, tag, attrs)
+			sb.WriteByte('>')
+			if !voidElements[tag.name] {
+				open = append(open, tag.name)
+			}
+		case htmlTagEnd:
+			// 只關閉已開啟的元素，中間未關閉的元素一併關閉；其他結束標籤略過
+			i := slices.Index(open, tag.name)
+			for j := len(open) - 1; i >= 0 && j >= i; j-- {
+				sb.WriteString("</" + open[j] + ">")
+			}
+			if i >= 0 {
+				open = open[:i]
+			}
+		}
+	}
+	for j := len(open) - 1; j >= 0; j-- {
+		sb.WriteString("</" + open[j] + ">")
+	}
+	return sb.String()
+}
+
+// writeAttributes 寫入 allowlist 中的屬性；網址不安全的屬性略過，外部連結加上 rel
+func (z *HTMLSanitizer) writeAttributes(sb *strings.Builder, tag htmlTag, allowed map[string]bool) {
+	var (
+		rel      []string
+		external bool
+		seen     = map[string]bool{}
+	)
+	for _, attr := range tag.attrs {
+		if !allowed[attr.name] || seen[attr.name] {
+			continue
+		}
+		seen[attr.name] = true
+		value := attr.value
+		if urlAttributes[attr.name] {
+			var ok bool
+			if value, ok = cleanHTMLURL(value); !ok {
+				continue
+			}
+			if tag.name == "a" && attr.name == "href" {
+				external = z.external(value)
+			}
+		}
+		if tag.name == "a" && attr.name == "rel" {
+			rel = strings.Fields(strings.ToLower(value))
+			continue
+		}
+		sb.WriteString(" " + attr.name + `="` + html.EscapeString(value) + `"`)
+	}
+	if external {
+		for _, value := range z.rel {
+			if !slices.Contains(rel, value) {
+				rel = append(rel, value)
+			}
+		}
+	}
+	if len(rel) > 0 {
+		sb.WriteString(` rel="` + html.EscapeString(strings.Join(rel, " ")) + `"`)
+	}
+}
+
+// external 回傳網址是否連到其他網站；相對網址與 mailto、tel 不是外部連結
+func (z *HTMLSanitizer) external(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return z.siteHost == "" || strings.ToLower(u.Hostname()) != z.siteHost
+}
+
+// cleanHTMLURL 移除網址中的換行與 tab (瀏覽器會忽略，可用來隱藏 scheme)，將 protocol-relative 網址改為 https，
+// scheme 不在 safeURLSchemes 中時回傳 false
+func cleanHTMLURL(raw string) (string, bool) {
+	raw = strings.TrimFunc(raw, func(r rune) bool { return r <= ' ' })
+	raw = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(raw)
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" && !safeURLSchemes[u.Scheme] {
+		return "", false
+	}
+	return raw, true
+}
+
+// validHTMLName 確認名稱只包含小寫英文字母、數字與 -
+func validHTMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeHTMLText 寫入跳脫後的文字；先還原 entity，避免重複跳脫
+func writeHTMLText(sb *strings.Builder, text string) {
+	if text != "" {
+		sb.WriteString(html.EscapeString(html.UnescapeString(text)))
+	}
+}
+
+// readHTMLTag 的結果種類：開始標籤、結束標籤、略過的內容 (註解、doctype 等)，或不是標籤的 '<'
+const (
+	htmlTagSkip = iota
+	htmlTagStart
+	htmlTagEnd
+	htmlTagText
+)
+
+// htmlTag 為 readHTMLTag 讀到的標籤；名稱與屬性名稱已轉為小寫，屬性值已還原 entity
+type htmlTag struct {
+	kind  int
+	name  string
+	attrs []htmlAttribute
+}
+
+type htmlAttribute struct {
+	name  string
+	value string
+}
+
+// readHTMLTag 讀取 s 開頭 (必為 '<') 的標籤並回傳讀取的長度；沒有結尾的標籤視為略過到 s 的結尾
+func readHTMLTag(s string) (htmlTag, int) {
+	switch {
+	case strings.HasPrefix(s, "<!--"):
+		if end := strings.Index(s[4:], "-->"); end >= 0 {
+			return htmlTag{kind: htmlTagSkip}, 4 + end + 3
+		}
+		return htmlTag{kind: htmlTagSkip}, len(s)
+	case len(s) > 1 && (s[1] == '!' || s[1] == '?'):
+		return skipHTMLTag(s)
+	case len(s) > 2 && s[1] == '/' && isASCIILetter(s[2]):
+		name, n := readHTMLName(s, 2)
+		if end := strings.IndexByte(s[n:], '>'); end >= 0 {
+			return htmlTag{kind: htmlTagEnd, name: name}, n + end + 1
+		}
+		return htmlTag{kind: htmlTagSkip}, len(s)
+	case len(s) > 1 && s[1] == '/':
+		return skipHTMLTag(s)
+	case len(s) > 1 && isASCIILetter(s[1]):
+		return readHTMLStartTag(s)
+	}
+	return htmlTag{kind: htmlTagText}, 1
+}
+
+// readHTMLStartTag 讀取開始標籤的名稱與屬性
+func readHTMLStartTag(s string) (htmlTag, int) {
+	name, i := readHTMLName(s, 1)
+	tag := htmlTag{kind: htmlTagStart, name: name}
+	for {
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			return htmlTag{kind: htmlTagSkip}, len(s)
+		}
+		if s[i] == '>' {
+			return tag, i + 1
+		}
+		start := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' && (s[i] != '=' || i == start) {
+			i++
+		}
+		attr := htmlAttribute{name: strings.ToLower(s[start:i])}
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return htmlTag{kind: htmlTagSkip}, len(s)
+				}
+				attr.value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.value = s[start:i]
+			}
+			attr.value = html.UnescapeString(attr.value)
+		}
+		tag.attrs = append(tag.attrs, attr)
+	}
+}
+
+// readHTMLName 由 s[i] 起讀取標籤名稱，回傳小寫的名稱與名稱之後的位置
+func readHTMLName(s string, i int) (string, int) {
+	start := i
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	return strings.ToLower(s[start:i]), i
+}
+
+// skipHTMLTag 略過到下一個 '>'
+func skipHTMLTag(s string) (htmlTag, int) {
+	if end := strings.IndexByte(s, '>'); end >= 0 {
+		return htmlTag{kind: htmlTagSkip}, end + 1
+	}
+	return htmlTag{kind: htmlTagSkip}, len(s)
+}
+
+// skipRawText 略過 raw text 元素的內容與結束標籤，回傳其後的內容；沒有結束標籤時略過到結尾
+func skipRawText(s, name string) string {
+	if name == "plaintext" {
+		return ""
+	}
+	for i := 0; ; {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return ""
+		}
+		i += j + 2
+		if len(s)-i < len(name) || !strings.EqualFold(s[i:i+len(name)], name) {
+			continue
+		}
+		after := i + len(name)
+		if after == len(s) || isHTMLSpace(s[after]) || s[after] == '/' || s[after] == '>' {
+			if end := strings.IndexByte(s[after:], '>'); end >= 0 {
+				return s[after+end+1:]
+			}
+			return ""
+		}
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
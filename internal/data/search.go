@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Search backends, selected with SEARCH_BACKEND.
+const (
+	SearchBackendPostgres      = "postgres"
+	SearchBackendElasticsearch = "elasticsearch"
+)
+
+var (
+	// ErrEmptySearchQuery is returned when the search query has no terms.
+	ErrEmptySearchQuery = errors.New("search query is empty")
+	// ErrSearchUnsupported is returned by a nil SearchService, i.e. when no
+	// search backend is configured.
+	ErrSearchUnsupported = errors.New("search is not configured")
+)
+
+// highlightPreTag 與 highlightPostTag 包住 snippet 中命中的字詞
+const (
+	highlightPreTag  = "<mark>"
+	highlightPostTag = "</mark>"
+)
+
+// SearchQuery is a full-text search over stories. Query follows web search
+// syntax: terms must all match, "quoted text" matches a phrase and -term
+// excludes a term. Title matches rank above subtitle and summary matches,
+// which rank above body matches.
+type SearchQuery struct {
+	Query  string
+	Where  *StoryWhereInput
+	Limit  int // 0 表示使用預設值 (defaultStoryLimit)
+	Offset int
+}
+
+// SearchHit is one story matching a SearchQuery.
+type SearchHit struct {
+	Story Story   `json:"story"`
+	Score float64 `json:"score"`
+	// Highlights maps a field name (title, body) to snippets of it with the
+	// matched terms wrapped in <mark></mark>. Fields without a match are
+	// left out.
+	Highlights map[string][]string `json:"highlights"`
+}
+
+// SearchResult is one page of search hits, best match first.
+type SearchResult struct {
+	Hits  []SearchHit `json:"hits"`
+	Total int         `json:"total"` // 所有符合的筆數，不受 Limit / Offset 影響
+}
+
+// SearchBackend runs full-text searches. Implementations apply Where as in
+// StoryRepository.List and order hits by relevance.
+type SearchBackend interface {
+	Search(ctx context.Context, q SearchQuery) (*SearchResult, error)
+}
+
+// OpenSearchBackend returns the SearchBackend for backend: Postgres full-text
+// search on db, or an Elasticsearch / OpenSearch index at esURL.
+func OpenSearchBackend(backend string, db *sql.DB, esURL, esIndex string) (SearchBackend, error) {
+	switch backend {
+	case SearchBackendPostgres:
+		return NewPostgresSearchBackend(db), nil
+	case SearchBackendElasticsearch:
+		if esURL == "" {
+			return nil, errors.New("ELASTICSEARCH_URL is required for the elasticsearch search backend")
+		}
+		return NewElasticsearchSearchBackend(esURL, esIndex), nil
+	}
+	return nil, fmt.Errorf("unknown search backend %q", backend)
+}
+
+// SearchService searches published stories through a SearchBackend and
+// caches results per normalized query, under the story cache prefix so
+// story writes through CachedStoryRepository also purge them.
+type SearchService struct {
+	backend SearchBackend
+	cache   *Cache
+}
+
+// NewSearchService returns a service searching backend. A nil or disabled
+// cache disables result caching.
+func NewSearchService(backend SearchBackend, cache *Cache) *SearchService {
+	return &SearchService{backend: backend, cache: cache}
+}
+
+// Search runs q over published stories; a status filter in q.Where is
+// replaced. It returns ErrEmptySearchQuery when q.Query has no terms.
+func (s *SearchService) Search(ctx context.Context, q SearchQuery) (*SearchResult, error) {
+	if s == nil {
+		return nil, ErrSearchUnsupported
+	}
+	q.Query = normalizeSearchQuery(q.Query)
+	if q.Query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	published := StoryStatusPublished
+	where := StoryWhereInput{}
+	if q.Where != nil {
+		where = *q.Where
+	}
+	where.Status = &StringFilter{Equals: &published}
+	q.Where = &where
+	if err := (StoryListOptions{Where: q.Where}).validate(); err != nil {
+		return nil, err
+	}
+	q.Limit = StoryListOptions{Limit: q.Limit}.limit()
+	if q.Limit > maxStoryLimit {
+		q.Limit = maxStoryLimit
+	}
+
+	key := NewCacheKey(storyCachePrefix + "fulltext").Fields(q).ShortHash().String()
+	return NewTypedCache[*SearchResult](s.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) (*SearchResult, error) {
+		return s.backend.Search(ctx, q)
+	})
+}
+
+// normalizeSearchQuery 統一大小寫與空白，讓相同的查詢共用同一筆 cache
+func normalizeSearchQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
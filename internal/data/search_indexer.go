@@ -0,0 +1,356 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// indexBatchSize 為重建與同步時每次讀取與寫入 index 的筆數
+const indexBatchSize = 100
+
+// indexSyncLag 為同步時 checkpoint 落後現在的時間；較晚更新的 story 仍會寫入，但 checkpoint
+// 不會越過它們，避免尚未 commit、updated_at 較早的 transaction 被略過
+const indexSyncLag = 30 * time.Second
+
+// indexSyncLockName 與 indexSyncLockTTL 為同步時使用的鎖，讓多個 instance 只有一個在同步
+const (
+	indexSyncLockName = "search-sync"
+	indexSyncLockTTL  = 10 * time.Minute
+)
+
+// esStoryMapping 為 story index 的 mapping；篩選用的欄位為 keyword
+var esStoryMapping = map[string]interface{}{
+	"properties": map[string]interface{}{
+		"id":          map[string]interface{}{"type": "keyword"},
+		"slug":        map[string]interface{}{"type": "keyword"},
+		"title":       map[string]interface{}{"type": "text"},
+		"subtitle":    map[string]interface{}{"type": "text"},
+		"summary":     map[string]interface{}{"type": "text"},
+		"excerpt":     map[string]interface{}{"type": "text", "index": false},
+		"body":        map[string]interface{}{"type": "text"},
+		"blocks":      map[string]interface{}{"type": "object", "enabled": false}, // body 已包含 block 的文字
+		"status":      map[string]interface{}{"type": "keyword"},
+		"section":     map[string]interface{}{"type": "keyword"},
+		"locale":      map[string]interface{}{"type": "keyword"},
+		"tags":        map[string]interface{}{"type": "keyword"},
+		"authorIds":   map[string]interface{}{"type": "keyword"},
+		"coverImage":  map[string]interface{}{"type": "keyword", "index": false},
+		"isMember":    map[string]interface{}{"type": "boolean"},
+		"publishedAt": map[string]interface{}{"type": "date"},
+		"createdAt":   map[string]interface{}{"type": "date"},
+		"updatedAt":   map[string]interface{}{"type": "date"},
+		"wordCount":   map[string]interface{}{"type": "integer"},
+		"readingTime": map[string]interface{}{"type": "integer"},
+		"viewCount":   map[string]interface{}{"type": "long"},
+	},
+}
+
+// indexSyncOptions 為同步與重建時讀取 story 的順序：依更新時間由舊到新，包含未發布的 story
+var indexSyncOptions = StoryListOptions{
+	OrderBy: []OrderRule{{Field: StorySortUpdatedAt, Direction: "asc"}},
+	Limit:   indexBatchSize,
+}
+
+// StoryIndexer keeps an Elasticsearch / OpenSearch index in sync with a
+// StoryRepository. The configured index name is an alias pointing at a
+// concrete index (<alias>-<timestamp>), so Reindex can build a fresh index
+// and swap it in atomically. The incremental sync checkpoint is stored in
+// the <alias>-sync index.
+type StoryIndexer struct {
+	es    *esClient
+	alias string
+}
+
+// NewStoryIndexer creates an indexer for the alias at url.
+func NewStoryIndexer(url, alias string) *StoryIndexer {
+	return &StoryIndexer{es: newESClient(url), alias: alias}
+}
+
+// EnsureIndex creates a concrete index behind the alias when neither exists.
+func (x *StoryIndexer) EnsureIndex(ctx context.Context) error {
+	err := x.es.do(ctx, http.MethodHead, "/"+url.PathEscape(x.alias), nil, nil)
+	if !isESNotFound(err) {
+		return err
+	}
+	return x.createIndex(ctx, x.newIndexName(), true)
+}
+
+// Index writes story to the index, replacing any previous version.
+func (x *StoryIndexer) Index(ctx context.Context, story *Story) error {
+	if err := x.es.do(ctx, http.MethodPut, "/"+url.PathEscape(x.alias)+"/_doc/"+url.PathEscape(story.ID), story, nil); err != nil {
+		return fmt.Errorf("index story %s: %w", story.ID, err)
+	}
+	return nil
+}
+
+// Delete removes the story with id from the index. Missing documents are
+// not an error.
+func (x *StoryIndexer) Delete(ctx context.Context, id string) error {
+	err := x.es.do(ctx, http.MethodDelete, "/"+url.PathEscape(x.alias)+"/_doc/"+url.PathEscape(id), nil, nil)
+	if err != nil && !isESNotFound(err) {
+		return fmt.Errorf("delete story %s from index: %w", id, err)
+	}
+	return nil
+}
+
+// Reindex builds a new index from every story in repo, points the alias at
+// it and drops the previous index. It returns the number of stories
+// indexed. Writes made while it runs are picked up by the next Sync; stories
+// deleted meanwhile may remain until the following reindex.
+func (x *StoryIndexer) Reindex(ctx context.Context, repo StoryRepository) (int, error) {
+	old, err := x.aliasIndices(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(old) == 0 {
+		// alias 不存在時，同名的 index 會讓之後無法建立 alias
+		err := x.es.do(ctx, http.MethodHead, "/"+url.PathEscape(x.alias), nil, nil)
+		if err == nil {
+			return 0, fmt.Errorf("index %q is not an alias; delete it before reindexing", x.alias)
+		}
+		if !isESNotFound(err) {
+			return 0, err
+		}
+	}
+
+	index := x.newIndexName()
+	if err := x.createIndex(ctx, index, false); err != nil {
+		return 0, err
+	}
+	count, checkpoint, err := x.copyStories(ctx, repo, index, "")
+	if err != nil {
+		_ = x.es.do(context.WithoutCancel(ctx), http.MethodDelete, "/"+url.PathEscape(index), nil, nil)
+		return count, err
+	}
+	if err := x.es.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_refresh", nil, nil); err != nil {
+		return count, err
+	}
+
+	actions := []interface{}{}
+	for _, name := range old {
+		actions = append(actions, map[string]interface{}{"remove": map[string]interface{}{"index": name, "alias": x.alias}})
+	}
+	actions = append(actions, map[string]interface{}{"add": map[string]interface{}{"index": index, "alias": x.alias}})
+	if err := x.es.do(ctx, http.MethodPost, "/_aliases", map[string]interface{}{"actions": actions}, nil); err != nil {
+		return count, fmt.Errorf("swap alias: %w", err)
+	}
+	for _, name := range old {
+		if err := x.es.do(ctx, http.MethodDelete, "/"+url.PathEscape(name), nil, nil); err != nil {
+			slog.Warn("failed to delete old search index", "index", name, "error", err)
+		}
+	}
+	if checkpoint != "" {
+		if err := x.saveCheckpoint(ctx, checkpoint); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// Sync indexes every story updated since the last checkpoint and advances
+// the checkpoint. It returns the number of stories indexed.
+func (x *StoryIndexer) Sync(ctx context.Context, repo StoryRepository) (int, error) {
+	if err := x.EnsureIndex(ctx); err != nil {
+		return 0, err
+	}
+	after, err := x.loadCheckpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count, checkpoint, err := x.copyStories(ctx, repo, x.alias, after)
+	if checkpoint != "" && checkpoint != after {
+		if saveErr := x.saveCheckpoint(ctx, checkpoint); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return count, err
+}
+
+// RunSync runs Sync immediately and then every interval until ctx is done.
+// With an enabled cache only one instance syncs at a time.
+func (x *StoryIndexer) RunSync(ctx context.Context, repo StoryRepository, cache *Cache, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		x.syncOnce(ctx, repo, cache)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncOnce 取得鎖後同步一次，錯誤只記錄在日誌
+func (x *StoryIndexer) syncOnce(ctx context.Context, repo StoryRepository, cache *Cache) {
+	if cache != nil && cache.Enabled() {
+		lock, err := cache.Lock(ctx, indexSyncLockName, indexSyncLockTTL)
+		if err != nil {
+			slog.Debug("search sync skipped", "reason", err)
+			return
+		}
+		defer func() { _ = lock.Unlock(context.WithoutCancel(ctx)) }()
+	}
+	count, err := x.Sync(ctx, repo)
+	if err != nil {
+		slog.Warn("search sync failed", "indexed", count, "error", err)
+		return
+	}
+	if count > 0 {
+		slog.Info("search index synced", "indexed", count)
+	}
+}
+
+// copyStories 從 after 之後依更新時間分批讀取 story 並寫入 index，回傳寫入筆數與新的 checkpoint
+// (最後一篇早於 indexSyncLag 的 story 的 cursor；沒有前進時為空字串)
+func (x *StoryIndexer) copyStories(ctx context.Context, repo StoryRepository, index, after string) (int, string, error) {
+	safe := time.Now().Add(-indexSyncLag)
+	count, checkpoint := 0, ""
+	opts := indexSyncOptions
+	opts.After = after
+	for {
+		stories, err := repo.List(ctx, opts)
+		if err != nil {
+			return count, checkpoint, err
+		}
+		if len(stories) == 0 {
+			return count, checkpoint, nil
+		}
+		if err := x.bulk(ctx, index, stories); err != nil {
+			return count, checkpoint, err
+		}
+		count += len(stories)
+		for _, story := range stories {
+			if !story.UpdatedAt.Before(safe) {
+				break
+			}
+			checkpoint = StoryCursor(story, opts)
+		}
+		if len(stories) < opts.Limit {
+			return count, checkpoint, nil
+		}
+		opts.After = StoryCursor(stories[len(stories)-1], opts)
+	}
+}
+
+// esBulkResponse 為 bulk API 回應中用到的部分
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID    string          `json:"_id"`
+		Error json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk 以 bulk API 寫入多篇 story；任一筆失敗即回傳錯誤
+func (x *StoryIndexer) bulk(ctx context.Context, index string, stories []Story) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range stories {
+		if err := enc.Encode(map[string]interface{}{"index": map[string]interface{}{"_id": stories[i].ID}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(&stories[i]); err != nil {
+			return err
+		}
+	}
+	var resp esBulkResponse
+	if err := x.es.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_bulk", buf.Bytes(), &resp); err != nil {
+		return fmt.Errorf("bulk index stories: %w", err)
+	}
+	if resp.Errors {
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if len(result.Error) > 0 {
+					return fmt.Errorf("bulk index story %s: %s", result.ID, result.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// aliasIndices 回傳 alias 目前指向的 index；alias 不存在時回傳空清單
+func (x *StoryIndexer) aliasIndices(ctx context.Context) ([]string, error) {
+	var resp map[string]json.RawMessage
+	err := x.es.do(ctx, http.MethodGet, "/_alias/"+url.PathEscape(x.alias), nil, &resp)
+	if isESNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(resp))
+	for name := range resp {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// createIndex 以 esStoryMapping 建立 index；withAlias 為 true 時同時加上 alias
+func (x *StoryIndexer) createIndex(ctx context.Context, index string, withAlias bool) error {
+	body := map[string]interface{}{"mappings": esStoryMapping}
+	if withAlias {
+		body["aliases"] = map[string]interface{}{x.alias: map[string]interface{}{}}
+	}
+	if err := x.es.do(ctx, http.MethodPut, "/"+url.PathEscape(index), body, nil); err != nil {
+		return fmt.Errorf("create index %s: %w", index, err)
+	}
+	return nil
+}
+
+// newIndexName 產生 alias 背後新的 index 名稱
+func (x *StoryIndexer) newIndexName() string {
+	return fmt.Sprintf("%s-%d", x.alias, time.Now().UnixNano())
+}
+
+// checkpointPath 為同步 checkpoint 文件的路徑
+func (x *StoryIndexer) checkpointPath() string {
+	return "/" + url.PathEscape(x.alias+"-sync") + "/_doc/checkpoint"
+}
+
+// indexCheckpoint 為同步 checkpoint 文件的內容
+type indexCheckpoint struct {
+	Cursor    string    `json:"cursor"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// loadCheckpoint 讀取 checkpoint；尚未同步過時回傳空字串 (從頭開始)
+func (x *StoryIndexer) loadCheckpoint(ctx context.Context) (string, error) {
+	var resp struct {
+		Source indexCheckpoint `json:"_source"`
+	}
+	err := x.es.do(ctx, http.MethodGet, x.checkpointPath(), nil, &resp)
+	if isESNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("load search checkpoint: %w", err)
+	}
+	// 排序方式改變後舊的 cursor 無法使用，改為從頭同步
+	keys, _ := indexSyncOptions.sortKeys()
+	if _, err := decodeStoryCursor(resp.Source.Cursor, keys); err != nil {
+		return "", nil
+	}
+	return resp.Source.Cursor, nil
+}
+
+func (x *StoryIndexer) saveCheckpoint(ctx context.Context, cursor string) error {
+	if err := x.es.do(ctx, http.MethodPut, x.checkpointPath(), indexCheckpoint{Cursor: cursor, UpdatedAt: time.Now().UTC()}, nil); err != nil {
+		return fmt.Errorf("save search checkpoint: %w", err)
+	}
+	return nil
+}
+
+// isESNotFound 回報 err 是否為 404 回應
+func isESNotFound(err error) bool {
+	var esErr *esError
+	return errors.As(err, &esErr) && esErr.Status == http.StatusNotFound
+}
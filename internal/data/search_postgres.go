@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// postgresSearchConfig 為建立 search_vector 與解析查詢時的 text search 設定；
+// simple 不做詞幹處理，中英文混合的內容也能以原字詞比對
+const postgresSearchConfig = "simple"
+
+// postgresSearchWeights 為 ts_rank 中 D (未使用)、C (body)、B (subtitle、summary)、A (title) 的權重
+const postgresSearchWeights = "{0.1, 0.2, 0.4, 1.0}"
+
+// ts_headline 的設定：title 整段回傳，body 取最多兩段片段，片段之間以 postgresFragmentDelimiter (預設值) 分隔
+const (
+	postgresTitleHeadline     = "HighlightAll=true, StartSel=" + highlightPreTag + ", StopSel=" + highlightPostTag
+	postgresBodyHeadline      = "MaxFragments=2, MinWords=10, MaxWords=30, StartSel=" + highlightPreTag + ", StopSel=" + highlightPostTag
+	postgresFragmentDelimiter = " ... "
+)
+
+// PostgresSearchBackend is a SearchBackend on the search_vector column of
+// the stories table (see migration 0004), searched with
+// websearch_to_tsquery and ranked with ts_rank.
+type PostgresSearchBackend struct {
+	db *sql.DB
+}
+
+// NewPostgresSearchBackend creates a backend on db.
+func NewPostgresSearchBackend(db *sql.DB) *PostgresSearchBackend {
+	return &PostgresSearchBackend{db: db}
+}
+
+func (b *PostgresSearchBackend) Search(ctx context.Context, q SearchQuery) (*SearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	args := []interface{}{q.Query}
+	bind := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := []string{`search_vector @@ q.query`, `deleted_at IS NULL`}
+	if q.Where != nil {
+		whereConds, err := storyWhereSQL(q.Where, bind)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, whereConds...)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + storySelectColumns)
+	sb.WriteString(`, ts_rank('` + postgresSearchWeights + `', search_vector, q.query) AS rank`)
+	sb.WriteString(`, ts_headline('` + postgresSearchConfig + `', title, q.query, '` + postgresTitleHeadline + `')`)
+	sb.WriteString(`, ts_headline('` + postgresSearchConfig + `', body, q.query, '` + postgresBodyHeadline + `')`)
+	sb.WriteString(`, count(*) OVER ()`)
+	sb.WriteString(` FROM stories, websearch_to_tsquery('` + postgresSearchConfig + `', $1) AS q(query)`)
+	sb.WriteString(` WHERE ` + strings.Join(conds, " AND "))
+	sb.WriteString(` ORDER BY rank DESC, id DESC`)
+	sb.WriteString(fmt.Sprintf(` LIMIT %s OFFSET %s`, bind(q.Limit), bind(q.Offset)))
+
+	rows, err := b.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("search stories: %w", err)
+	}
+	defer rows.Close()
+
+	result := &SearchResult{Hits: []SearchHit{}}
+	for rows.Next() {
+		var (
+			hit         SearchHit
+			title, body string
+		)
+		story, err := scanStory(searchRankScanner{rows: rows, score: &hit.Score, title: &title, body: &body, total: &result.Total})
+		if err != nil {
+			return nil, fmt.Errorf("scan story: %w", err)
+		}
+		hit.Story = *story
+		hit.Highlights = map[string][]string{}
+		if strings.Contains(title, highlightPreTag) {
+			hit.Highlights["title"] = []string{title}
+		}
+		if strings.Contains(body, highlightPreTag) {
+			hit.Highlights["body"] = strings.Split(body, postgresFragmentDelimiter)
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search stories: %w", err)
+	}
+	return result, nil
+}
+
+// searchRankScanner 讓 scanStory 讀取 story 欄位後，再讀取排名、highlight 與總筆數
+type searchRankScanner struct {
+	rows  *sql.Rows
+	score *float64
+	title *string
+	body  *string
+	total *int
+}
+
+func (s searchRankScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, s.score, s.title, s.body, s.total)...)
+}
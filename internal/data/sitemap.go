@@ -0,0 +1,244 @@
+package data
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSitemapNotFound is returned by SitemapService.File for a file that was
+// not generated.
+var ErrSitemapNotFound = errors.New("sitemap not found")
+
+// SitemapIndexFile is the name of the sitemap index.
+const SitemapIndexFile = "sitemap.xml"
+
+// sitemapMaxURLs 為每個 sitemap 檔案的 URL 上限 (sitemaps.org 規範為 50,000)
+const sitemapMaxURLs = 50000
+
+// sitemapPageSize 為產生 sitemap 時每次讀取的 story 數 (List 的上限)
+const sitemapPageSize = maxStoryLimit
+
+// sitemap 產生時的鎖與 cache 設定；檔案在 cache 中保留到下一次產生之後
+const (
+	sitemapLockName  = "sitemap"
+	sitemapLockTTL   = 10 * time.Minute
+	sitemapKeyPrefix = "sitemap:"
+)
+
+// sitemapXMLNS 為 sitemap 的 XML namespace
+const sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// sitemapGenerateOptions 依發布時間由舊到新讀取已發布的 story，產生期間新發布的 story 不會影響既有的分頁
+var sitemapGenerateOptions = StoryListOptions{
+	Status:  StoryStatusPublished,
+	OrderBy: []OrderRule{{Field: StorySortPublishedAt, Direction: "asc"}},
+	Limit:   sitemapPageSize,
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// SitemapService generates the XML sitemaps of published stories: an index
+// (sitemap.xml) pointing to stories-N.xml files of up to 50,000 URLs each,
+// with lastmod taken from the stories' update times, and to a Google News
+// sitemap (news.xml) of the stories published in the last 48 hours, with
+// the site's name and language and the story tags as keywords. Generate
+// runs under a cache lock so only one instance regenerates at a time; the
+// files are stored in the cache for every instance to serve, and kept in
+// memory as a fallback. As a StoryEventListener it regenerates as soon as a
+// story is published or unpublished instead of waiting for the interval.
+type SitemapService struct {
+	repo    StoryRepository
+	cache   *Cache
+	site    Site
+	ttl     time.Duration
+	refresh chan struct{} // 有 story 發布或下架，需要提早重新產生
+
+	mu    sync.RWMutex
+	files map[string][]byte // 這個 instance 最近一次產生的檔案
+}
+
+// NewSitemapService returns a service generating sitemaps of the stories in
+// repo that link to site. Generated files are cached for ttl, which should
+// be longer than the regeneration interval.
+func NewSitemapService(repo StoryRepository, cache *Cache, site Site, ttl time.Duration) *SitemapService {
+	return &SitemapService{repo: repo, cache: cache, site: site, ttl: ttl, refresh: make(chan struct{}, 1), files: map[string][]byte{}}
+}
+
+// HandleStoryEvent asks Run to regenerate the sitemaps when a story is
+// published or unpublished. Updates wait for the next interval.
+func (s *SitemapService) HandleStoryEvent(ctx context.Context, event StoryEvent, story *Story) {
+	if event == StoryEventUpdated {
+		return
+	}
+	select {
+	case s.refresh <- struct{}{}:
+	default: // 已有待處理的重新產生
+	}
+}
+
+// File returns the generated sitemap file with name, e.g. sitemap.xml or
+// stories-1.xml.
+func (s *SitemapService) File(ctx context.Context, name string) ([]byte, error) {
+	body, found, err := NewTypedCache[[]byte](s.cache).Get(ctx, sitemapKeyPrefix+name)
+	if err == nil && found {
+		return body, nil
+	}
+	s.mu.RLock()
+	body, ok := s.files[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrSitemapNotFound
+	}
+	return body, nil
+}
+
+// Generate regenerates every sitemap file. It returns ErrLockNotAcquired
+// when another instance is generating.
+func (s *SitemapService) Generate(ctx context.Context) error {
+	lock, err := s.cache.Lock(ctx, sitemapLockName, sitemapLockTTL)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock(context.WithoutCancel(ctx)) }()
+
+	files, err := s.generate(ctx)
+	if err != nil {
+		return err
+	}
+	typed := NewTypedCache[[]byte](s.cache)
+	for name, body := range files {
+		if err := typed.SetWithTTL(ctx, sitemapKeyPrefix+name, body, s.ttl); err != nil {
+			slog.Warn("failed to cache sitemap", "file", name, "error", err)
+		}
+	}
+	s.mu.Lock()
+	s.files = files
+	s.mu.Unlock()
+	return nil
+}
+
+// Run calls Generate immediately, then every interval and after story
+// events, until ctx is done.
+func (s *SitemapService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Generate(ctx); errors.Is(err, ErrLockNotAcquired) {
+			slog.Debug("sitemap generation skipped", "reason", err)
+		} else if err != nil {
+			slog.Warn("failed to generate sitemap", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refresh:
+		}
+	}
+}
+
+// generate 讀取所有已發布的 story，每 sitemapMaxURLs 筆輸出一個檔案，再加上 Google News sitemap，最後輸出 index
+func (s *SitemapService) generate(ctx context.Context) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	chunk := sitemapURLSet{XMLNS: sitemapXMLNS}
+	var chunkModified time.Time
+
+	flush := func() error {
+		if len(chunk.URLs) == 0 {
+			return nil
+		}
+		name := fmt.Sprintf("stories-%d.xml", len(index.Sitemaps)+1)
+		body, err := marshalSitemap(chunk)
+		if err != nil {
+			return err
+		}
+		files[name] = body
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: s.fileURL(name), LastMod: sitemapTime(chunkModified)})
+		chunk.URLs, chunkModified = nil, time.Time{}
+		return nil
+	}
+
+	opts := sitemapGenerateOptions
+	for {
+		stories, err := s.repo.List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("list stories: %w", err)
+		}
+		for _, story := range stories {
+			chunk.URLs = append(chunk.URLs, sitemapURL{Loc: s.site.StoryURL(story.Slug), LastMod: sitemapTime(story.UpdatedAt)})
+			if story.UpdatedAt.After(chunkModified) {
+				chunkModified = story.UpdatedAt
+			}
+			if len(chunk.URLs) == sitemapMaxURLs {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(stories) < opts.Limit {
+			break
+		}
+		opts.After = StoryCursor(stories[len(stories)-1], opts)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	news, newsModified, err := s.generateNews(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	files[SitemapNewsFile] = news
+	index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: s.fileURL(SitemapNewsFile), LastMod: sitemapTime(newsModified)})
+
+	body, err := marshalSitemap(index)
+	if err != nil {
+		return nil, err
+	}
+	files[SitemapIndexFile] = body
+	return files, nil
+}
+
+// fileURL 回傳 sitemap 檔案的公開網址
+func (s *SitemapService) fileURL(name string) string {
+	return strings.TrimRight(s.site.URL, "/") + "/sitemaps/" + name
+}
+
+// marshalSitemap 輸出含 XML 宣告的 sitemap
+func marshalSitemap(v interface{}) ([]byte, error) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// sitemapTime 以 W3C Datetime 格式輸出時間；零值輸出空字串
+func sitemapTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
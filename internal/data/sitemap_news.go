@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SitemapNewsFile is the name of the Google News sitemap.
+const SitemapNewsFile = "news.xml"
+
+// Google News sitemap 只收錄最近 48 小時內發布的文章，每個檔案最多 1,000 篇
+const (
+	sitemapNewsWindow  = 48 * time.Hour
+	sitemapNewsMaxURLs = 1000
+)
+
+// sitemapNewsXMLNS 為 Google News sitemap 的 XML namespace
+const sitemapNewsXMLNS = "http://www.google.com/schemas/sitemap-news/0.9"
+
+type sitemapNewsURLSet struct {
+	XMLName   xml.Name         `xml:"urlset"`
+	XMLNS     string           `xml:"xmlns,attr"`
+	NewsXMLNS string           `xml:"xmlns:news,attr"`
+	URLs      []sitemapNewsURL `xml:"url"`
+}
+
+type sitemapNewsURL struct {
+	Loc  string      `xml:"loc"`
+	News sitemapNews `xml:"news:news"`
+}
+
+type sitemapNews struct {
+	Publication     sitemapNewsPublication `xml:"news:publication"`
+	PublicationDate string                 `xml:"news:publication_date"`
+	Title           string                 `xml:"news:title"`
+	Keywords        string                 `xml:"news:keywords,omitempty"`
+}
+
+type sitemapNewsPublication struct {
+	Name     string `xml:"news:name"`
+	Language string `xml:"news:language"`
+}
+
+// generateNews 產生最近 48 小時內發布的 story 的 Google News sitemap，並回傳其中最新的更新時間
+func (s *SitemapService) generateNews(ctx context.Context, now time.Time) ([]byte, time.Time, error) {
+	since := now.Add(-sitemapNewsWindow).UTC().Format(time.RFC3339)
+	opts := StoryListOptions{
+		Status: StoryStatusPublished,
+		Where:  &StoryWhereInput{PublishedAt: &DateTimeRangeFilter{Gte: &since}},
+		Limit:  sitemapPageSize,
+	}
+	urlSet := sitemapNewsURLSet{XMLNS: sitemapXMLNS, NewsXMLNS: sitemapNewsXMLNS}
+	// Google News 的語言代碼為小寫的 ISO 639，例如 zh-tw
+	publication := sitemapNewsPublication{Name: s.site.Title, Language: strings.ToLower(s.site.Language)}
+	var modified time.Time
+
+	for len(urlSet.URLs) < sitemapNewsMaxURLs {
+		stories, err := s.repo.List(ctx, opts)
+		if err != nil {
+			return nil, modified, fmt.Errorf("list news stories: %w", err)
+		}
+		for _, story := range stories {
+			if story.PublishedAt == nil || len(urlSet.URLs) == sitemapNewsMaxURLs {
+				continue
+			}
+			// 有設定語言的 story 以其語言列出
+			storyPublication := publication
+			if story.Locale != "" {
+				storyPublication.Language = strings.ToLower(story.Locale)
+			}
+			urlSet.URLs = append(urlSet.URLs, sitemapNewsURL{
+				Loc: s.site.StoryURL(story.Slug),
+				News: sitemapNews{
+					Publication:     storyPublication,
+					PublicationDate: sitemapTime(*story.PublishedAt),
+					Title:           story.Title,
+					Keywords:        strings.Join(story.Tags, ", "),
+				},
+			})
+			if story.UpdatedAt.After(modified) {
+				modified = story.UpdatedAt
+			}
+		}
+		if len(stories) < opts.Limit {
+			break
+		}
+		opts.After = StoryCursor(stories[len(stories)-1], opts)
+	}
+
+	body, err := marshalSitemap(urlSet)
+	return body, modified, err
+}
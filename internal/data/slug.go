@@ -0,0 +1,87 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxSlugLength is the length, in characters, of slugs generated from a
+// title (see Slugify).
+const MaxSlugLength = 80
+
+// slugTransliterations 為無法以去除重音符號轉換的拉丁字母，以及希臘與西里爾字母的轉寫
+var slugTransliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i", 'ŋ': "ng",
+	// 希臘字母
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l",
+	'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f",
+	'χ': "ch", 'ψ': "ps", 'ω': "o",
+	// 西里爾字母
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+}
+
+// Slugify returns a URL slug for title: lower case, accents removed
+// (é → e), Greek and Cyrillic transliterated and other characters but
+// letters and digits turned into single dashes. Chinese, Japanese and other
+// scripts without a transliteration are kept as they are. The slug is cut
+// to MaxSlugLength characters at a dash when possible; an empty result
+// becomes "story".
+func Slugify(title string) string {
+	var (
+		sb   strings.Builder
+		dash bool
+		base rune // 前一個字元；只移除拉丁、希臘與西里爾字母的重音符號，日文的濁音等符號保留
+	)
+	for _, r := range norm.NFKD.String(strings.ToLower(title)) {
+		if unicode.Is(unicode.Mn, r) && base < 0x0530 {
+			continue
+		}
+		base = r
+		s, ok := slugTransliterations[r]
+		if !ok {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) {
+				dash = true
+				continue
+			}
+			s = string(r)
+		}
+		if s == "" {
+			continue
+		}
+		if dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+		}
+		dash = false
+		sb.WriteString(s)
+	}
+	slug := norm.NFC.String(sb.String())
+	if runes := []rune(slug); len(runes) > MaxSlugLength {
+		slug = string(runes[:MaxSlugLength])
+		if i := strings.LastIndexByte(slug, '-'); i > len(slug)/2 {
+			slug = slug[:i]
+		}
+	}
+	if slug = strings.Trim(slug, "-"); slug == "" {
+		return "story"
+	}
+	return slug
+}
+
+// freeSlug 回傳 base，已被使用時依序加上 -2、-3…；taken 為 base 與 base-* 中已使用的 slug
+func freeSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug
+}
@@ -0,0 +1,232 @@
+package data
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SocialMeta is the social sharing metadata of a story page: the Open
+// Graph and Twitter Card values, computed once here so the frontend and
+// edge renderers emit the same tags. MetaTags lists them ready to render
+// as <meta property|name="…" content="…">.
+type SocialMeta struct {
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	Image            string     `json:"image,omitempty"` // 見 StoryService.SocialMeta 的替代順序
+	ImageAlt         string     `json:"imageAlt,omitempty"`
+	CanonicalURL     string     `json:"canonicalUrl,omitempty"` // 未設定 SITE_URL 時為空
+	AMPURL           string     `json:"ampUrl,omitempty"`       // <link rel="amphtml"> 的網址，未啟用 AMP 時為空
+	SiteName         string     `json:"siteName"`
+	Type             string     `json:"type"`
+	Locale           string     `json:"locale"` // og:locale 的格式，例如 zh_TW
+	AlternateLocales []string   `json:"alternateLocales,omitempty"`
+	PublishedTime    *time.Time `json:"publishedTime,omitempty"`
+	ModifiedTime     time.Time  `json:"modifiedTime"`
+	Section          string     `json:"section,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	Authors          []string   `json:"authors,omitempty"` // 作者名稱，依署名順序
+	TwitterCard      string     `json:"twitterCard"`
+	TwitterSite      string     `json:"twitterSite,omitempty"`
+	TwitterCreator   string     `json:"twitterCreator,omitempty"` // 第一位作者的 X (Twitter) 帳號
+	MetaTags         []MetaTag  `json:"metaTags"`
+}
+
+// MetaTag is one <meta> tag of SocialMeta. Open Graph tags use Property,
+// Twitter Card tags use Name.
+type MetaTag struct {
+	Property string `json:"property,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Content  string `json:"content"`
+}
+
+// maxSocialDescriptionLength 為分享說明的長度上限 (字數)，超過時截斷並加上「…」
+const maxSocialDescriptionLength = 200
+
+// htmlImageSrc 找出 HTML 中第一張圖片的網址
+var htmlImageSrc = regexp.MustCompile(`(?i)<img\b[^>]*?\ssrc\s*=\s*["']([^"']+)["']`)
+
+// SocialMeta returns the social metadata of story. og:image falls back
+// from the cover image to the first image (or gallery) block, the first
+// image in the body and finally the site image (SITE_IMAGE); relative URLs
+// are resolved against the site URL. The description is the summary,
+// otherwise the excerpt, the subtitle or the site description.
+func (s *StoryService) SocialMeta(ctx context.Context, story *Story) (*SocialMeta, error) {
+	meta := &SocialMeta{
+		Title:         story.Title,
+		Description:   socialDescription(story, s.site.Description),
+		SiteName:      s.site.Title,
+		Type:          "article",
+		Locale:        ogLocale(s.Locale(story)),
+		PublishedTime: story.PublishedAt,
+		ModifiedTime:  story.UpdatedAt,
+		Section:       story.Section,
+		Tags:          story.Tags,
+		TwitterSite:   s.site.Twitter,
+	}
+	if s.site.URL != "" {
+		meta.CanonicalURL = s.site.StoryURL(story.Slug)
+		if s.site.AMP {
+			meta.AMPURL = s.site.AMPURL(story.Slug)
+		}
+	}
+	meta.Image, meta.ImageAlt = s.socialImage(story)
+
+	translations, err := s.Translations(ctx, story)
+	if err != nil {
+		return nil, err
+	}
+	for _, translation := range translations {
+		if locale := ogLocale(translation.Locale); locale != meta.Locale {
+			meta.AlternateLocales = append(meta.AlternateLocales, locale)
+		}
+	}
+	authors, err := s.Authors(ctx, story)
+	if err != nil {
+		return nil, err
+	}
+	for i, author := range authors {
+		meta.Authors = append(meta.Authors, author.Name)
+		if i == 0 {
+			meta.TwitterCreator = twitterHandle(author.SocialLinks)
+		}
+	}
+
+	meta.TwitterCard = "summary"
+	if meta.Image != "" {
+		meta.TwitterCard = "summary_large_image"
+	}
+	meta.MetaTags = meta.tags()
+	return meta, nil
+}
+
+// socialImage 依序取封面、第一個圖片或相簿 block、body 中的第一張圖片與網站的預設圖片
+func (s *StoryService) socialImage(story *Story) (image, alt string) {
+	image = story.CoverImage
+	if image == "" {
+		for _, block := range story.Blocks {
+			if block.Type == BlockImage {
+				image, alt = block.URL, block.Alt
+				break
+			}
+			if block.Type == BlockGallery && len(block.Images) > 0 {
+				image, alt = block.Images[0].URL, block.Images[0].Alt
+				break
+			}
+		}
+	}
+	if image == "" {
+		if m := htmlImageSrc.FindStringSubmatch(story.Body); m != nil {
+			image = m[1]
+		}
+	}
+	if image == "" {
+		image = s.site.Image
+	}
+	return s.absoluteURL(image), alt
+}
+
+// absoluteURL 以網站網址解析相對網址；無法解析或沒有網站網址時維持原值
+func (s *StoryService) absoluteURL(raw string) string {
+	if raw == "" || s.site.URL == "" {
+		return raw
+	}
+	base, err := url.Parse(s.site.URL)
+	if err != nil {
+		return raw
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// socialDescription 依序取 summary、excerpt、subtitle 與網站說明，並限制長度
+func socialDescription(story *Story, fallback string) string {
+	description := fallback
+	for _, candidate := range []string{story.Summary, story.Excerpt, story.Subtitle} {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			description = candidate
+			break
+		}
+	}
+	description = strings.Join(strings.Fields(description), " ")
+	if utf8.RuneCountInString(description) <= maxSocialDescriptionLength {
+		return description
+	}
+	runes := []rune(description)
+	return strings.TrimSpace(string(runes[:maxSocialDescriptionLength-1])) + "…"
+}
+
+// ogLocale 將 BCP 47 語言 (zh-TW) 轉為 og:locale 的格式 (zh_TW)
+func ogLocale(locale string) string {
+	return strings.ReplaceAll(locale, "-", "_")
+}
+
+// twitterHandle 由作者的 X / Twitter 連結取出帳號 (@handle)
+func twitterHandle(links []AuthorLink) string {
+	for _, link := range links {
+		if network := strings.ToLower(link.Network); network != "x" && network != "twitter" {
+			continue
+		}
+		u, err := url.Parse(link.URL)
+		if err != nil {
+			continue
+		}
+		if handle, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); handle != "" {
+			return "@" + strings.TrimPrefix(handle, "@")
+		}
+	}
+	return ""
+}
+
+// tags 產生 Open Graph 與 Twitter Card 的 meta tag；空值不輸出
+func (m *SocialMeta) tags() []MetaTag {
+	tags := []MetaTag{}
+	property := func(name, content string) {
+		if content != "" {
+			tags = append(tags, MetaTag{Property: name, Content: content})
+		}
+	}
+	name := func(name, content string) {
+		if content != "" {
+			tags = append(tags, MetaTag{Name: name, Content: content})
+		}
+	}
+	property("og:type", m.Type)
+	property("og:title", m.Title)
+	property("og:description", m.Description)
+	property("og:url", m.CanonicalURL)
+	property("og:site_name", m.SiteName)
+	property("og:locale", m.Locale)
+	for _, locale := range m.AlternateLocales {
+		property("og:locale:alternate", locale)
+	}
+	property("og:image", m.Image)
+	property("og:image:alt", m.ImageAlt)
+	if m.PublishedTime != nil {
+		property("article:published_time", m.PublishedTime.UTC().Format(time.RFC3339))
+	}
+	if !m.ModifiedTime.IsZero() {
+		property("article:modified_time", m.ModifiedTime.UTC().Format(time.RFC3339))
+	}
+	property("article:section", m.Section)
+	for _, tag := range m.Tags {
+		property("article:tag", tag)
+	}
+	for _, author := range m.Authors {
+		property("article:author", author)
+	}
+	name("twitter:card", m.TwitterCard)
+	name("twitter:title", m.Title)
+	name("twitter:description", m.Description)
+	name("twitter:image", m.Image)
+	name("twitter:image:alt", m.ImageAlt)
+	name("twitter:site", m.TwitterSite)
+	name("twitter:creator", m.TwitterCreator)
+	return tags
+}
@@ -0,0 +1,264 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Story statuses, in workflow order (see CheckStoryTransition). A scheduled
+// story has a PublishedAt in the future and is published at that time by
+// StoryScheduler. Only published stories are visible through StoryService.
+const (
+	StoryStatusDraft     = "draft"
+	StoryStatusInReview  = "in_review"
+	StoryStatusScheduled = "scheduled"
+	StoryStatusPublished = "published"
+	StoryStatusArchived  = "archived"
+)
+
+var (
+	// ErrStoryNotFound is returned when no story matches the lookup.
+	ErrStoryNotFound = errors.New("story not found")
+	// ErrStorySlugTaken is returned by Create and Update when another story
+	// already uses the slug.
+	ErrStorySlugTaken = errors.New("story slug already taken")
+)
+
+// Story is a story owned and stored by this service (as opposed to Post,
+// which is read from the upstream CMS database). Its body is either Body,
+// in the configured body format, or structured Blocks; a story with blocks
+// stores their HTML rendering in Body.
+type Story struct {
+	ID          string         `json:"id"`
+	Slug        string         `json:"slug"`
+	Title       string         `json:"title"`
+	Subtitle    string         `json:"subtitle"`
+	Summary     string         `json:"summary"`
+	Excerpt     string         `json:"excerpt"` // Summary，沒有時為由 body 產生的摘要 (見 Excerpt 與 SummaryGenerator)，寫入時由 prepareStory 產生
+	Body        string         `json:"body"`    // 有 Blocks 時為其轉換的 HTML，寫入時由 prepareStory 產生
+	Blocks      []ContentBlock `json:"blocks,omitempty"`
+	Status      string         `json:"status"`
+	Section     string         `json:"section"`
+	Locale      string         `json:"locale"` // BCP 47，寫入時正規化；空字串表示網站的語言 (SITE_LANGUAGE)
+	Tags        []string       `json:"tags"`
+	AuthorIDs   []string       `json:"authorIds"` // 依署名順序
+	CoverImage  string         `json:"coverImage"`
+	IsMember    bool           `json:"isMember"` // 與 Access 為 members 一致，寫入時由 normalizeStoryAccess 同步
+	Access      string         `json:"access"`   // StoryAccess* 之一；空字串依 IsMember 決定 (見 AccessTier)
+	OwnerID     string         `json:"ownerId"`  // 建立 story 的使用者 (見 StoryWorkflow.CreateStory)；Update 不會覆寫
+	PublishedAt *time.Time     `json:"publishedAt"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	WordCount   int            `json:"wordCount"`           // 由 prepareStory 依 body 計算 (見 CountText)
+	ReadingTime int            `json:"readingTime"`         // 預估閱讀時間 (分鐘)，與 WordCount 一同計算
+	ViewCount   int64          `json:"viewCount"`           // 只由儲存層累計，Create / Update 不會寫入
+	DeletedAt   *time.Time     `json:"deletedAt,omitempty"` // 移至垃圾桶的時間，只出現在 StoryTrash.ListTrash 的結果中
+	BodyHTML    string         `json:"bodyHtml,omitempty"`  // 由 StoryService.BodyHTML 轉換的 body，只出現在單篇 story 的回應中，不會寫入儲存層
+	// 同一篇文章各語言版本共用的識別字，慣例上為原文 story 的 ID；空字串表示沒有翻譯
+	TranslationGroup string `json:"translationGroup"`
+	// 由 StoryService.TableOfContents 產生，與 BodyHTML 相同只出現在單篇 story 的回應中
+	TableOfContents []TOCEntry `json:"tableOfContents,omitempty"`
+	// 由 StoryService.Translations 列出的各語言版本 (hreflang)，與 BodyHTML 相同只出現在單篇 story 的回應中
+	Translations []StoryTranslation `json:"translations,omitempty"`
+	// 由 StoryService.SocialMeta 產生的分享資訊，與 BodyHTML 相同只出現在單篇 story 的回應中
+	Meta *SocialMeta `json:"meta,omitempty"`
+	// 由 StoryService.StructuredData 產生的 JSON-LD，與 BodyHTML 相同只出現在單篇 story 的回應中
+	StructuredData *StoryJSONLD `json:"structuredData,omitempty"`
+	// 由 CommentService.Count 計算的公開留言數，只出現在單篇 story 的回應中；未啟用留言時為 nil
+	CommentCount *int `json:"commentCount,omitempty"`
+	// 由 ReactionService.Totals 計算的各種回應數，只出現在單篇 story 的回應中；未啟用回應時為 nil
+	Reactions map[string]int64 `json:"reactions,omitempty"`
+	// 請求帶有使用者時由 BookmarkService.Bookmarked 判斷是否已收藏，只出現在該使用者的單篇 story 回應中，不可寫入共用的 cache
+	IsBookmarked *bool `json:"isBookmarked,omitempty"`
+	// 由 Paywall.Gate 判斷的讀者閱讀權限，只出現在單篇 story 的回應中，不可寫入共用的 cache
+	Paywall *StoryAccess `json:"paywall,omitempty"`
+}
+
+// CacheSensitive reports whether the story is paid (metered or member-only)
+// content, whose cached copy is encrypted when cache encryption is
+// configured.
+func (s Story) CacheSensitive() bool {
+	return s.AccessTier() != StoryAccessFree
+}
+
+// StoryListOptions filters and pages List and Search results. Zero values
+// mean no filter; Where adds structured filters on top of the plain ones.
+// Without OrderBy results are ordered by publish time, newest first
+// (unpublished stories last), then by creation time; ID always breaks ties so
+// the order is stable. After is an opaque cursor from StoryCursor: only
+// stories ordered after it are returned, which unlike Offset does not skip or
+// repeat stories published while a client pages through.
+type StoryListOptions struct {
+	Status  string
+	Section string
+	Tag     string
+	Author  string // author ID
+	Locale  string // 比對 Story.Locale；StoryService 另外將沒有語言的 story 視為網站的語言
+	Where   *StoryWhereInput
+	OrderBy []OrderRule // Field 為 StorySort* 之一
+	After   string
+	Limit   int // 0 表示使用預設值 (defaultStoryLimit)
+	Offset  int
+	// 只列出此翻譯群組的 story
+	TranslationGroup string
+}
+
+// defaultStoryLimit 與 maxStoryLimit 為 List / Search 每頁筆數的預設值與上限；
+// 儲存層允許多取一筆，供 StoryService 判斷是否有下一頁
+const (
+	defaultStoryLimit = 20
+	maxStoryLimit     = 100
+)
+
+// limit 回傳套用預設值與上限後的筆數
+func (o StoryListOptions) limit() int {
+	switch {
+	case o.Limit <= 0:
+		return defaultStoryLimit
+	case o.Limit > maxStoryLimit+1:
+		return maxStoryLimit + 1
+	}
+	return o.Limit
+}
+
+// ErrInvalidCursor is returned when StoryListOptions.After is not a cursor
+// produced by StoryCursor for the same OrderBy.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// validate 檢查 Where、OrderBy 與 After 是否可用，讓錯誤在查詢 cache 與儲存層之前回報
+func (o StoryListOptions) validate() error {
+	keys, err := o.sortKeys()
+	if err != nil {
+		return err
+	}
+	if o.Where != nil && o.Where.PublishedAt != nil {
+		if _, _, err := o.Where.PublishedAt.bounds(); err != nil {
+			return err
+		}
+	}
+	if o.After != "" {
+		if _, err := decodeStoryCursor(o.After, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Story storage backends, selected with STORY_STORE.
+const (
+	StoryStorePostgres = "postgres"
+	StoryStoreMongo    = "mongo"
+)
+
+// OpenStoryRepository returns the StoryRepository for store: Postgres on db,
+// or MongoDB at mongoURI. The returned function releases the repository's
+// own connections.
+func OpenStoryRepository(ctx context.Context, store string, db *sql.DB, mongoURI, mongoDatabase string) (StoryRepository, func() error, error) {
+	switch store {
+	case "", StoryStorePostgres:
+		return NewPostgresStoryRepository(db), func() error { return nil }, nil
+	case StoryStoreMongo:
+		if mongoURI == "" {
+			return nil, nil, errors.New("MONGO_URL is required for the mongo story store")
+		}
+		return openMongoStoryRepository(ctx, mongoURI, mongoDatabase)
+	}
+	return nil, nil, fmt.Errorf("unknown story store %q", store)
+}
+
+// StoryRepository stores stories.
+type StoryRepository interface {
+	// GetByID returns the story with id, or ErrStoryNotFound.
+	GetByID(ctx context.Context, id string) (*Story, error)
+	// GetBySlug returns the story with slug, or the story that had slug
+	// before it was renamed, or ErrStoryNotFound.
+	GetBySlug(ctx context.Context, slug string) (*Story, error)
+	// List returns stories matching opts.
+	List(ctx context.Context, opts StoryListOptions) ([]Story, error)
+	// Search returns stories matching opts whose title, summary or body
+	// contain query.
+	Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error)
+	// Create stores a new story, filling in ID (when empty) and timestamps.
+	// An empty Slug is generated from the title (see Slugify), with a
+	// -2, -3 … suffix when it is taken.
+	Create(ctx context.Context, story *Story) error
+	// Update replaces the story with story.ID and refreshes UpdatedAt. An
+	// empty Slug keeps the current one; when the slug changes, the old one
+	// redirects to the story (see GetBySlug).
+	Update(ctx context.Context, story *Story) error
+	// Delete removes the story with id, or returns ErrStoryNotFound.
+	// Stores implementing StoryTrash move it to the trash instead.
+	Delete(ctx context.Context, id string) error
+	// WithTx runs fn with a repository whose operations all belong to one
+	// transaction, committed when fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(repo StoryRepository) error) error
+}
+
+// newUUID 產生隨機的 UUID v4，作為 story、webhook 等資料的 ID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// prepareStory 在寫入前補上 ID、狀態與時間欄位
+func prepareStory(story *Story, now time.Time) {
+	if story.ID == "" {
+		story.ID = newUUID()
+	}
+	if story.Status == "" {
+		story.Status = StoryStatusDraft
+	}
+	if story.Tags == nil {
+		story.Tags = []string{}
+	}
+	if story.AuthorIDs == nil {
+		story.AuthorIDs = []string{}
+	}
+	// 以 block 撰寫的 story 另存轉換後的 HTML 於 body，供搜尋與只讀取 body 的用戶端使用；
+	// embed 的 oEmbed payload 只在讀取時補上，不寫入儲存層
+	for i := range story.Blocks {
+		story.Blocks[i].Embed = nil
+	}
+	if len(story.Blocks) > 0 {
+		story.Body = RenderBlocks(story.Blocks)
+	}
+	setReadingStats(story)
+	story.Excerpt = Excerpt(story)
+	if story.Status == StoryStatusPublished && story.PublishedAt == nil {
+		publishedAt := now
+		story.PublishedAt = &publishedAt
+	}
+	// 發布時間在未來的 story 改為排程，時間到時由 StoryScheduler 發布；沒有發布時間的排程視為草稿
+	if story.Status == StoryStatusPublished && story.PublishedAt.After(now) {
+		story.Status = StoryStatusScheduled
+	}
+	if story.Status == StoryStatusScheduled && story.PublishedAt == nil {
+		story.Status = StoryStatusDraft
+	}
+	if story.CreatedAt.IsZero() {
+		story.CreatedAt = now
+	}
+	story.UpdatedAt = now
+}
+
+// fillComputedFields 為新增欄位前寫入、尚未計算的 story 補上字數、閱讀時間與摘要；下次修改時才會寫入儲存層
+func fillComputedFields(story *Story) {
+	if story.Body == "" && story.Summary == "" {
+		return
+	}
+	if story.WordCount == 0 && story.Body != "" {
+		setReadingStats(story)
+	}
+	if story.Excerpt == "" {
+		story.Excerpt = Excerpt(story)
+	}
+}
@@ -0,0 +1,528 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// AuditStoryRepository wraps a StoryRepository and records every write in
+// an AuditLog: creates, updates (as AuditActionTransition when the status
+// changes), deletes, and the StoryTrash restores and purges. The story is
+// read before each update and delete for the before snapshot; entries of
+// writes inside WithTx are recorded after the transaction commits. Like
+// the other decorators it logs recording failures instead of failing the
+// write, which has already been committed.
+type AuditStoryRepository struct {
+	repo  StoryRepository
+	audit *AuditLog
+}
+
+// NewAuditStoryRepository wraps repo so its writes are recorded in audit.
+func NewAuditStoryRepository(repo StoryRepository, audit *AuditLog) *AuditStoryRepository {
+	return &AuditStoryRepository{repo: repo, audit: audit}
+}
+
+func (r *AuditStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+func (r *AuditStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	return r.repo.GetBySlug(ctx, slug)
+}
+
+func (r *AuditStoryRepository) GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	return GetStories(ctx, r.repo, keys)
+}
+
+func (r *AuditStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	return r.repo.List(ctx, opts)
+}
+
+func (r *AuditStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	return r.repo.Search(ctx, query, opts)
+}
+
+func (r *AuditStoryRepository) Create(ctx context.Context, story *Story) error {
+	tx := &auditTx{StoryRepository: r.repo}
+	if err := tx.Create(ctx, story); err != nil {
+		return err
+	}
+	r.record(ctx, tx.pending)
+	return nil
+}
+
+func (r *AuditStoryRepository) Update(ctx context.Context, story *Story) error {
+	tx := &auditTx{StoryRepository: r.repo}
+	if err := tx.Update(ctx, story); err != nil {
+		return err
+	}
+	r.record(ctx, tx.pending)
+	return nil
+}
+
+func (r *AuditStoryRepository) Delete(ctx context.Context, id string) error {
+	tx := &auditTx{StoryRepository: r.repo}
+	if err := tx.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.record(ctx, tx.pending)
+	return nil
+}
+
+func (r *AuditStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	tx := &auditTx{}
+	err := r.repo.WithTx(ctx, func(repo StoryRepository) error {
+		tx.StoryRepository = repo
+		tx.pending = nil // transaction 重試時只保留最後一次的紀錄
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+	r.record(ctx, tx.pending)
+	return nil
+}
+
+// AddViewCounts 不記錄：瀏覽數不屬於內容的變更
+func (r *AuditStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	vw, ok := r.repo.(ViewCountWriter)
+	if !ok {
+		return ErrViewCountsUnsupported
+	}
+	return vw.AddViewCounts(ctx, counts)
+}
+
+// SetExcerpt 不記錄：摘要由系統產生，不屬於編輯的變更
+func (r *AuditStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	ew, ok := r.repo.(ExcerptWriter)
+	if !ok {
+		return ErrExcerptUnsupported
+	}
+	return ew.SetExcerpt(ctx, id, excerpt)
+}
+
+func (r *AuditStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.ListRevisions(ctx, storyID)
+}
+
+func (r *AuditStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.GetRevision(ctx, storyID, number)
+}
+
+func (r *AuditStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st.ListTrash(ctx, limit, offset)
+}
+
+func (r *AuditStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	story, err := st.RestoreStory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	after := revisionSnapshot(story)
+	r.record(ctx, []pendingAuditEntry{{action: AuditActionRestore, id: id, after: &after}})
+	return story, nil
+}
+
+func (r *AuditStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return ErrTrashUnsupported
+	}
+	if err := st.PurgeStory(ctx, id); err != nil {
+		return err
+	}
+	r.record(ctx, []pendingAuditEntry{{action: AuditActionPurge, id: id}})
+	return nil
+}
+
+// PurgeTrash 記錄一筆 entity ID 為空的紀錄，after 為刪除條件與筆數
+func (r *AuditStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return 0, ErrTrashUnsupported
+	}
+	n, err := st.PurgeTrash(ctx, before)
+	if err != nil {
+		return n, err
+	}
+	if n > 0 {
+		r.record(ctx, []pendingAuditEntry{{action: AuditActionPurge, after: map[string]interface{}{"before": before, "purged": n}}})
+	}
+	return n, nil
+}
+
+func (r *AuditStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorByID(ctx, id)
+}
+
+func (r *AuditStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorBySlug(ctx, slug)
+}
+
+func (r *AuditStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorsByIDs(ctx, ids)
+}
+
+func (r *AuditStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.ListAuthors(ctx, limit, offset)
+}
+
+func (r *AuditStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	if err := aw.CreateAuthor(ctx, author); err != nil {
+		return err
+	}
+	r.recordAuthor(ctx, AuditActionCreate, author.ID, nil, author)
+	return nil
+}
+
+func (r *AuditStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	before, err := r.authorBefore(ctx, author.ID)
+	if err != nil {
+		return err
+	}
+	if err := aw.UpdateAuthor(ctx, author); err != nil {
+		return err
+	}
+	r.recordAuthor(ctx, AuditActionUpdate, author.ID, before, author)
+	return nil
+}
+
+func (r *AuditStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	before, err := r.authorBefore(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := aw.DeleteAuthor(ctx, id); err != nil {
+		return err
+	}
+	r.recordAuthor(ctx, AuditActionDelete, id, before, nil)
+	return nil
+}
+
+// authorBefore 讀取寫入前的作者；不存在時回傳 nil，交由寫入本身回傳 ErrAuthorNotFound
+func (r *AuditStoryRepository) authorBefore(ctx context.Context, id string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok || id == "" {
+		return nil, nil
+	}
+	author, err := ar.GetAuthorByID(ctx, id)
+	if errors.Is(err, ErrAuthorNotFound) {
+		return nil, nil
+	}
+	return author, err
+}
+
+// recordAuthor 寫入作者的稽核紀錄；失敗時只記錄日誌
+func (r *AuditStoryRepository) recordAuthor(ctx context.Context, action, id string, before, after *Author) {
+	if err := r.audit.Record(context.WithoutCancel(ctx), action, AuditEntityAuthor, id, before, after); err != nil {
+		slog.Warn("failed to record author audit entry", "id", id, "action", action, "error", err)
+	}
+}
+
+func (r *AuditStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.ListTerms(ctx, kind, limit, offset)
+}
+
+func (r *AuditStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.GetTerm(ctx, kind, slug)
+}
+
+func (r *AuditStoryRepository) GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return LoadTerms(ctx, tr, kind, slugs)
+}
+
+func (r *AuditStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.CreateTerm(ctx, term); err != nil {
+		return err
+	}
+	r.recordTerm(ctx, AuditActionCreate, term.Kind, term.Slug, nil, term)
+	return nil
+}
+
+func (r *AuditStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	before, _ := tr.GetTerm(ctx, term.Kind, slug)
+	relinked, err := tr.UpdateTerm(ctx, slug, term)
+	if err != nil {
+		return nil, err
+	}
+	r.recordTerm(ctx, AuditActionUpdate, term.Kind, slug, before, term)
+	return relinked, nil
+}
+
+func (r *AuditStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	before, _ := tr.GetTerm(ctx, kind, from)
+	relinked, err := tr.MergeTerms(ctx, kind, from, into)
+	if err != nil {
+		return nil, err
+	}
+	r.recordTerm(ctx, AuditActionMerge, kind, from, before, map[string]interface{}{"into": into, "stories": relinked})
+	return relinked, nil
+}
+
+func (r *AuditStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	before, _ := tr.GetTerm(ctx, kind, slug)
+	if err := tr.DeleteTerm(ctx, kind, slug); err != nil {
+		return err
+	}
+	r.recordTerm(ctx, AuditActionDelete, kind, slug, before, nil)
+	return nil
+}
+
+// recordTerm 寫入 tag / 分類的稽核紀錄，entity 為 term 的 kind、entity ID 為寫入前的 slug；失敗時只記錄日誌
+func (r *AuditStoryRepository) recordTerm(ctx context.Context, action, kind, slug string, before *Term, after interface{}) {
+	if err := r.audit.Record(context.WithoutCancel(ctx), action, kind, slug, before, after); err != nil {
+		slog.Warn("failed to record term audit entry", "kind", kind, "slug", slug, "action", action, "error", err)
+	}
+}
+
+func (r *AuditStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionByID(ctx, id)
+}
+
+func (r *AuditStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionBySlug(ctx, slug)
+}
+
+func (r *AuditStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.ListCollections(ctx, limit, offset)
+}
+
+func (r *AuditStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.CollectionsByStory(ctx, storyID)
+}
+
+func (r *AuditStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	if err := cs.CreateCollection(ctx, collection); err != nil {
+		return err
+	}
+	r.recordCollection(ctx, AuditActionCreate, collection.ID, nil, collection)
+	return nil
+}
+
+func (r *AuditStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	before, err := r.collectionBefore(ctx, cs, collection.ID)
+	if err != nil {
+		return err
+	}
+	if err := cs.UpdateCollection(ctx, collection); err != nil {
+		return err
+	}
+	r.recordCollection(ctx, AuditActionUpdate, collection.ID, before, collection)
+	return nil
+}
+
+func (r *AuditStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	before, err := r.collectionBefore(ctx, cs, id)
+	if err != nil {
+		return err
+	}
+	if err := cs.DeleteCollection(ctx, id); err != nil {
+		return err
+	}
+	r.recordCollection(ctx, AuditActionDelete, id, before, nil)
+	return nil
+}
+
+// collectionBefore 讀取寫入前的合集；不存在時回傳 nil，交由寫入本身回傳 ErrCollectionNotFound
+func (r *AuditStoryRepository) collectionBefore(ctx context.Context, cs CollectionStore, id string) (*Collection, error) {
+	if id == "" {
+		return nil, nil
+	}
+	collection, err := cs.GetCollectionByID(ctx, id)
+	if errors.Is(err, ErrCollectionNotFound) {
+		return nil, nil
+	}
+	return collection, err
+}
+
+// recordCollection 寫入合集的稽核紀錄；失敗時只記錄日誌
+func (r *AuditStoryRepository) recordCollection(ctx context.Context, action, id string, before, after *Collection) {
+	if err := r.audit.Record(context.WithoutCancel(ctx), action, AuditEntityCollection, id, before, after); err != nil {
+		slog.Warn("failed to record collection audit entry", "id", id, "action", action, "error", err)
+	}
+}
+
+// record 寫入稽核紀錄；寫入已完成，不受請求取消影響，失敗時只記錄日誌
+func (r *AuditStoryRepository) record(ctx context.Context, entries []pendingAuditEntry) {
+	ctx = context.WithoutCancel(ctx)
+	for _, e := range entries {
+		if err := r.audit.Record(ctx, e.action, AuditEntityStory, e.id, e.before, e.after); err != nil {
+			slog.Warn("failed to record story audit entry", "id", e.id, "action", e.action, "error", err)
+		}
+	}
+}
+
+// pendingAuditEntry 為寫入後待記錄的稽核紀錄；before / after 為 nil 時記錄為 null
+type pendingAuditEntry struct {
+	action string
+	id     string
+	before interface{}
+	after  interface{}
+}
+
+// auditTx 為寫入時使用的 repository，讀取寫入前的 story 並記錄稽核紀錄，待 commit 後再寫入
+type auditTx struct {
+	StoryRepository
+	pending []pendingAuditEntry
+}
+
+func (t *auditTx) Create(ctx context.Context, story *Story) error {
+	if err := t.StoryRepository.Create(ctx, story); err != nil {
+		return err
+	}
+	after := revisionSnapshot(story)
+	t.pending = append(t.pending, pendingAuditEntry{action: AuditActionCreate, id: story.ID, after: &after})
+	return nil
+}
+
+func (t *auditTx) Update(ctx context.Context, story *Story) error {
+	before, err := t.before(ctx, story.ID)
+	if err != nil {
+		return err
+	}
+	if err := t.StoryRepository.Update(ctx, story); err != nil {
+		return err
+	}
+	action := AuditActionUpdate
+	if before != nil && before.Status != story.Status {
+		action = AuditActionTransition
+	}
+	after := revisionSnapshot(story)
+	t.pending = append(t.pending, pendingAuditEntry{action: action, id: story.ID, before: before, after: &after})
+	return nil
+}
+
+func (t *auditTx) Delete(ctx context.Context, id string) error {
+	before, err := t.before(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := t.StoryRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	t.pending = append(t.pending, pendingAuditEntry{action: AuditActionDelete, id: id, before: before})
+	return nil
+}
+
+// WithTx 已在 transaction 中，直接執行 fn
+func (t *auditTx) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	return fn(t)
+}
+
+// before 讀取寫入前的 story 作為紀錄內容 (不含瀏覽數)；不存在時回傳 nil，交由寫入本身回傳 ErrStoryNotFound
+func (t *auditTx) before(ctx context.Context, id string) (*Story, error) {
+	if id == "" {
+		return nil, nil
+	}
+	story, err := t.StoryRepository.GetByID(ctx, id)
+	if errors.Is(err, ErrStoryNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := revisionSnapshot(story)
+	return &snapshot, nil
+}
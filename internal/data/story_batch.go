@@ -0,0 +1,44 @@
+package data
+
+import (
+	"context"
+	"errors"
+)
+
+// StoryKey identifies a story by ID or, when ID is empty, by slug.
+type StoryKey struct {
+	ID   string `json:"id,omitempty"`
+	Slug string `json:"slug,omitempty"`
+}
+
+// StoryBatchReader is implemented by story repositories that can look up
+// many stories at once. Callers use GetStories, which falls back to
+// GetByID and GetBySlug for repositories without it.
+type StoryBatchReader interface {
+	// GetMany returns the story of each key at the same index, or nil when
+	// there is none. Slugs match like GetBySlug, including slugs a story
+	// had before it was renamed.
+	GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error)
+}
+
+// GetStories looks up keys with repo's GetMany when it implements
+// StoryBatchReader, and one key at a time otherwise. Stories not found are
+// nil.
+func GetStories(ctx context.Context, repo StoryRepository, keys []StoryKey) ([]*Story, error) {
+	if br, ok := repo.(StoryBatchReader); ok {
+		return br.GetMany(ctx, keys)
+	}
+	stories := make([]*Story, len(keys))
+	for i, key := range keys {
+		var err error
+		if key.ID != "" {
+			stories[i], err = repo.GetByID(ctx, key.ID)
+		} else {
+			stories[i], err = repo.GetBySlug(ctx, key.Slug)
+		}
+		if err != nil && !errors.Is(err, ErrStoryNotFound) {
+			return nil, err
+		}
+	}
+	return stories, nil
+}
@@ -0,0 +1,574 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// storyCachePrefix 為 story 相關 cache key 的共同前綴，寫入後整批清除
+const storyCachePrefix = "story:"
+
+// CachedStoryRepository wraps a StoryRepository with the cache. Lookups,
+// listings and author reads go through GetOrSet (missing stories are cached
+// as not-found markers); every write purges all story entries with
+// DeleteByPrefix. Operations inside WithTx bypass the cache so uncommitted
+// data is never cached.
+type CachedStoryRepository struct {
+	repo  StoryRepository
+	cache *Cache
+}
+
+// NewCachedStoryRepository wraps repo with cache. A nil or disabled cache
+// passes every call through to repo.
+func NewCachedStoryRepository(repo StoryRepository, cache *Cache) *CachedStoryRepository {
+	return &CachedStoryRepository{repo: repo, cache: cache}
+}
+
+func (r *CachedStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.getStory(ctx, storyKeyCacheKey(StoryKey{ID: id}), func(ctx context.Context) (*Story, error) {
+		return r.repo.GetByID(ctx, id)
+	})
+}
+
+func (r *CachedStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	return r.getStory(ctx, storyKeyCacheKey(StoryKey{Slug: slug}), func(ctx context.Context) (*Story, error) {
+		return r.repo.GetBySlug(ctx, slug)
+	})
+}
+
+// getStory 讀取單篇 story；查無資料時快取 not found 標記並回傳 ErrStoryNotFound
+func (r *CachedStoryRepository) getStory(ctx context.Context, key string, load func(ctx context.Context) (*Story, error)) (*Story, error) {
+	story, err := NewTypedCache[*Story](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) (*Story, error) {
+		story, err := load(ctx)
+		if errors.Is(err, ErrStoryNotFound) {
+			return nil, nil
+		}
+		return story, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if story == nil {
+		return nil, ErrStoryNotFound
+	}
+	return story, nil
+}
+
+// storyKeyCacheKey 回傳 key 在 GetByID / GetBySlug 使用的 cache key，批次讀取與單篇讀取共用
+func storyKeyCacheKey(key StoryKey) string {
+	if key.ID != "" {
+		return NewCacheKey(storyCachePrefix+"id").Field("id", key.ID).ShortHash().String()
+	}
+	return NewCacheKey(storyCachePrefix+"slug").Field("slug", key.Slug).ShortHash().String()
+}
+
+// GetMany 以 GetMulti 一次讀取單篇 story 的 cache，未命中的 key 再一次交給底層的儲存層，
+// 結果 (含查無資料的標記) 寫回 cache
+func (r *CachedStoryRepository) GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	if r.cache == nil || !r.cache.Enabled() {
+		return GetStories(ctx, r.repo, keys)
+	}
+	cacheKeys := make([]string, len(keys))
+	stories := make([]*Story, len(keys))
+	dests := make([]interface{}, len(keys))
+	for i, key := range keys {
+		cacheKeys[i] = storyKeyCacheKey(key)
+		dests[i] = &stories[i]
+	}
+	found, err := r.cache.GetMulti(ctx, cacheKeys, dests)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []StoryKey
+	var missingAt []int
+	for i, hit := range found {
+		if !hit {
+			missing = append(missing, keys[i])
+			missingAt = append(missingAt, i)
+		}
+	}
+	if len(missing) == 0 {
+		return stories, nil
+	}
+	loaded, err := GetStories(ctx, r.repo, missing)
+	if err != nil {
+		return nil, err
+	}
+	items := map[string]interface{}{}
+	for j, story := range loaded {
+		i := missingAt[j]
+		stories[i] = story
+		if story == nil {
+			_ = r.cache.SetNotFound(ctx, cacheKeys[i])
+			continue
+		}
+		items[cacheKeys[i]] = story
+	}
+	_ = r.cache.SetMulti(ctx, items)
+	return stories, nil
+}
+
+func (r *CachedStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	key := NewCacheKey(storyCachePrefix + "list").Fields(opts).ShortHash().String()
+	return NewTypedCache[[]Story](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Story, error) {
+		return r.repo.List(ctx, opts)
+	})
+}
+
+func (r *CachedStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	key := NewCacheKey(storyCachePrefix+"search").Field("query", query).Fields(opts).ShortHash().String()
+	return NewTypedCache[[]Story](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Story, error) {
+		return r.repo.Search(ctx, query, opts)
+	})
+}
+
+func (r *CachedStoryRepository) Create(ctx context.Context, story *Story) error {
+	if err := r.repo.Create(ctx, story); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) Update(ctx context.Context, story *Story) error {
+	if err := r.repo.Update(ctx, story); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) Delete(ctx context.Context, id string) error {
+	if err := r.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	if err := r.repo.WithTx(ctx, fn); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+// AddViewCounts 不清除 cache：瀏覽數頻繁變動，快取中的 ViewCount 隨 TTL 更新
+func (r *CachedStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	vw, ok := r.repo.(ViewCountWriter)
+	if !ok {
+		return ErrViewCountsUnsupported
+	}
+	return vw.AddViewCounts(ctx, counts)
+}
+
+func (r *CachedStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	ew, ok := r.repo.(ExcerptWriter)
+	if !ok {
+		return ErrExcerptUnsupported
+	}
+	if err := ew.SetExcerpt(ctx, id, excerpt); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+// ListRevisions 與 GetRevision 只供編輯使用，不快取
+func (r *CachedStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.ListRevisions(ctx, storyID)
+}
+
+func (r *CachedStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.GetRevision(ctx, storyID, number)
+}
+
+// ListTrash 只供編輯使用，不快取
+func (r *CachedStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st.ListTrash(ctx, limit, offset)
+}
+
+func (r *CachedStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	story, err := st.RestoreStory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.purge(ctx)
+	return story, nil
+}
+
+// PurgeStory 與 PurgeTrash 不清除 cache：垃圾桶中的 story 在移入時已自 cache 清除
+func (r *CachedStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return ErrTrashUnsupported
+	}
+	return st.PurgeStory(ctx, id)
+}
+
+func (r *CachedStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return 0, ErrTrashUnsupported
+	}
+	return st.PurgeTrash(ctx, before)
+}
+
+func (r *CachedStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	key := NewCacheKey(storyCachePrefix+"author:id").Field("id", id).ShortHash().String()
+	return r.getAuthor(ctx, key, func(ctx context.Context, ar AuthorReader) (*Author, error) {
+		return ar.GetAuthorByID(ctx, id)
+	})
+}
+
+func (r *CachedStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	key := NewCacheKey(storyCachePrefix+"author:slug").Field("slug", slug).ShortHash().String()
+	return r.getAuthor(ctx, key, func(ctx context.Context, ar AuthorReader) (*Author, error) {
+		return ar.GetAuthorBySlug(ctx, slug)
+	})
+}
+
+// getAuthor 讀取單一作者；查無資料時快取 not found 標記並回傳 ErrAuthorNotFound
+func (r *CachedStoryRepository) getAuthor(ctx context.Context, key string, load func(ctx context.Context, ar AuthorReader) (*Author, error)) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	author, err := NewTypedCache[*Author](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) (*Author, error) {
+		author, err := load(ctx, ar)
+		if errors.Is(err, ErrAuthorNotFound) {
+			return nil, nil
+		}
+		return author, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if author == nil {
+		return nil, ErrAuthorNotFound
+	}
+	return author, nil
+}
+
+func (r *CachedStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	if len(ids) == 0 {
+		return []Author{}, nil
+	}
+	key := NewCacheKey(storyCachePrefix+"authors").Field("ids", ids).ShortHash().String()
+	return NewTypedCache[[]Author](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Author, error) {
+		return ar.GetAuthorsByIDs(ctx, ids)
+	})
+}
+
+func (r *CachedStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	key := NewCacheKey(storyCachePrefix+"authors:list").Field("limit", limit).Field("offset", offset).ShortHash().String()
+	return NewTypedCache[[]Author](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Author, error) {
+		return ar.ListAuthors(ctx, limit, offset)
+	})
+}
+
+// 作者的 cache 與 story 共用 storyCachePrefix，作者寫入後整批清除，story 的署名、作者頁與作者列表一併更新
+func (r *CachedStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	if err := aw.CreateAuthor(ctx, author); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	if err := aw.UpdateAuthor(ctx, author); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	if err := aw.DeleteAuthor(ctx, id); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+// ListTerms 與 GetTerm 的 story 數隨 story 寫入改變，與 story 共用 storyCachePrefix 一併清除
+func (r *CachedStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	key := NewCacheKey(storyCachePrefix+"terms").Field("kind", kind).Field("limit", limit).Field("offset", offset).ShortHash().String()
+	return NewTypedCache[[]Term](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Term, error) {
+		return tr.ListTerms(ctx, kind, limit, offset)
+	})
+}
+
+// GetTerm 查無資料時快取 not found 標記並回傳 ErrTermNotFound
+func (r *CachedStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	term, err := NewTypedCache[*Term](r.cache).GetOrSet(ctx, termCacheKey(kind, slug), 0, func(ctx context.Context) (*Term, error) {
+		term, err := tr.GetTerm(ctx, kind, slug)
+		if errors.Is(err, ErrTermNotFound) {
+			return nil, nil
+		}
+		return term, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, ErrTermNotFound
+	}
+	return term, nil
+}
+
+// termCacheKey 為 GetTerm 與 GetTerms 共用的單一 term cache key
+func termCacheKey(kind, slug string) string {
+	return NewCacheKey(storyCachePrefix+"term").Field("kind", kind).Field("slug", slug).ShortHash().String()
+}
+
+// GetTerms 與 GetMany 相同，以 GetMulti 一次讀取 GetTerm 的 cache，未命中的 slug 再一次交給底層的儲存層
+func (r *CachedStoryRepository) GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	if r.cache == nil || !r.cache.Enabled() {
+		return LoadTerms(ctx, tr, kind, slugs)
+	}
+	cacheKeys := make([]string, len(slugs))
+	terms := make([]*Term, len(slugs))
+	dests := make([]interface{}, len(slugs))
+	for i, slug := range slugs {
+		cacheKeys[i] = termCacheKey(kind, slug)
+		dests[i] = &terms[i]
+	}
+	found, err := r.cache.GetMulti(ctx, cacheKeys, dests)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	var missingAt []int
+	for i, hit := range found {
+		if !hit {
+			missing = append(missing, slugs[i])
+			missingAt = append(missingAt, i)
+		}
+	}
+	if len(missing) == 0 {
+		return terms, nil
+	}
+	loaded, err := LoadTerms(ctx, tr, kind, missing)
+	if err != nil {
+		return nil, err
+	}
+	items := map[string]interface{}{}
+	for j, term := range loaded {
+		i := missingAt[j]
+		terms[i] = term
+		if term == nil {
+			_ = r.cache.SetNotFound(ctx, cacheKeys[i])
+			continue
+		}
+		items[cacheKeys[i]] = term
+	}
+	_ = r.cache.SetMulti(ctx, items)
+	return terms, nil
+}
+
+func (r *CachedStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.CreateTerm(ctx, term); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	relinked, err := tr.UpdateTerm(ctx, slug, term)
+	if err != nil {
+		return nil, err
+	}
+	r.purge(ctx)
+	return relinked, nil
+}
+
+func (r *CachedStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	relinked, err := tr.MergeTerms(ctx, kind, from, into)
+	if err != nil {
+		return nil, err
+	}
+	r.purge(ctx)
+	return relinked, nil
+}
+
+func (r *CachedStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.DeleteTerm(ctx, kind, slug); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	key := NewCacheKey(storyCachePrefix+"collection:id").Field("id", id).ShortHash().String()
+	return r.getCollection(ctx, key, func(ctx context.Context, cs CollectionStore) (*Collection, error) {
+		return cs.GetCollectionByID(ctx, id)
+	})
+}
+
+func (r *CachedStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	key := NewCacheKey(storyCachePrefix+"collection:slug").Field("slug", slug).ShortHash().String()
+	return r.getCollection(ctx, key, func(ctx context.Context, cs CollectionStore) (*Collection, error) {
+		return cs.GetCollectionBySlug(ctx, slug)
+	})
+}
+
+// getCollection 讀取單一合集；查無資料時快取 not found 標記並回傳 ErrCollectionNotFound
+func (r *CachedStoryRepository) getCollection(ctx context.Context, key string, load func(ctx context.Context, cs CollectionStore) (*Collection, error)) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	collection, err := NewTypedCache[*Collection](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) (*Collection, error) {
+		collection, err := load(ctx, cs)
+		if errors.Is(err, ErrCollectionNotFound) {
+			return nil, nil
+		}
+		return collection, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, nil
+}
+
+func (r *CachedStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	key := NewCacheKey(storyCachePrefix+"collections").Field("limit", limit).Field("offset", offset).ShortHash().String()
+	return NewTypedCache[[]Collection](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Collection, error) {
+		return cs.ListCollections(ctx, limit, offset)
+	})
+}
+
+func (r *CachedStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	key := NewCacheKey(storyCachePrefix+"collections:story").Field("id", storyID).ShortHash().String()
+	return NewTypedCache[[]Collection](r.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]Collection, error) {
+		return cs.CollectionsByStory(ctx, storyID)
+	})
+}
+
+// 合集的 cache 與 story 共用 storyCachePrefix，合集寫入後整批清除，story 的系列導覽一併更新
+func (r *CachedStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	if err := cs.CreateCollection(ctx, collection); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	if err := cs.UpdateCollection(ctx, collection); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+func (r *CachedStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	if err := cs.DeleteCollection(ctx, id); err != nil {
+		return err
+	}
+	r.purge(ctx)
+	return nil
+}
+
+// purge 清除所有 story 相關的 cache；失敗時由 DeleteByPrefix 記錄，不影響寫入結果
+func (r *CachedStoryRepository) purge(ctx context.Context) {
+	if r.cache == nil {
+		return
+	}
+	_, _ = r.cache.DeleteByPrefix(ctx, storyCachePrefix)
+}
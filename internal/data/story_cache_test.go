@@ -0,0 +1,230 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-story/internal/data"
+	"go-story/internal/data/cachetest"
+)
+
+// countingStoryRepository 為記憶體中的 StoryRepository，記錄每個讀取方法被呼叫的次數，
+// 用來確認 CachedStoryRepository 的讀取是否由 cache 回應
+type countingStoryRepository struct {
+	mu      sync.Mutex
+	stories map[string]data.Story
+	calls   map[string]int
+}
+
+func newCountingStoryRepository(stories ...data.Story) *countingStoryRepository {
+	repo := &countingStoryRepository{stories: map[string]data.Story{}, calls: map[string]int{}}
+	for _, story := range stories {
+		repo.stories[story.ID] = story
+	}
+	return repo
+}
+
+// count 回傳 method 被呼叫的次數
+func (r *countingStoryRepository) count(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[method]
+}
+
+// called 記錄一次 method 的呼叫
+func (r *countingStoryRepository) called(method string) {
+	r.mu.Lock()
+	r.calls[method]++
+	r.mu.Unlock()
+}
+
+func (r *countingStoryRepository) GetByID(_ context.Context, id string) (*data.Story, error) {
+	r.called("GetByID")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	story, ok := r.stories[id]
+	if !ok {
+		return nil, data.ErrStoryNotFound
+	}
+	return &story, nil
+}
+
+func (r *countingStoryRepository) GetBySlug(_ context.Context, slug string) (*data.Story, error) {
+	r.called("GetBySlug")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, story := range r.stories {
+		if story.Slug == slug {
+			return &story, nil
+		}
+	}
+	return nil, data.ErrStoryNotFound
+}
+
+func (r *countingStoryRepository) List(context.Context, data.StoryListOptions) ([]data.Story, error) {
+	r.called("List")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := []data.Story{}
+	for _, story := range r.stories {
+		list = append(list, story)
+	}
+	return list, nil
+}
+
+func (r *countingStoryRepository) Search(ctx context.Context, _ string, opts data.StoryListOptions) ([]data.Story, error) {
+	return r.List(ctx, opts)
+}
+
+func (r *countingStoryRepository) Create(_ context.Context, story *data.Story) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stories[story.ID] = *story
+	return nil
+}
+
+func (r *countingStoryRepository) Update(_ context.Context, story *data.Story) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.stories[story.ID]; !ok {
+		return data.ErrStoryNotFound
+	}
+	r.stories[story.ID] = *story
+	return nil
+}
+
+func (r *countingStoryRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.stories[id]; !ok {
+		return data.ErrStoryNotFound
+	}
+	delete(r.stories, id)
+	return nil
+}
+
+func (r *countingStoryRepository) WithTx(_ context.Context, fn func(repo data.StoryRepository) error) error {
+	return fn(r)
+}
+
+func TestCachedStoryRepositoryServesRepeatedReadsFromCache(t *testing.T) {
+	h, _ := cachetest.New(t)
+	repo := newCountingStoryRepository(data.Story{ID: "s1", Slug: "first", Title: "First"})
+	cached := data.NewCachedStoryRepository(repo, h.Cache)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		story, err := cached.GetByID(ctx, "s1")
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if story.Title != "First" {
+			t.Errorf("GetByID title = %q", story.Title)
+		}
+	}
+	if n := repo.count("GetByID"); n != 1 {
+		t.Errorf("repository GetByID called %d times, want 1", n)
+	}
+}
+
+func TestCachedStoryRepositoryCachesNotFound(t *testing.T) {
+	h, _ := cachetest.New(t, data.WithNegativeTTL(time.Minute))
+	repo := newCountingStoryRepository()
+	cached := data.NewCachedStoryRepository(repo, h.Cache)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cached.GetBySlug(ctx, "missing"); !errors.Is(err, data.ErrStoryNotFound) {
+			t.Fatalf("GetBySlug err = %v, want ErrStoryNotFound", err)
+		}
+	}
+	if n := repo.count("GetBySlug"); n != 1 {
+		t.Errorf("repository GetBySlug called %d times, want 1", n)
+	}
+}
+
+func TestCachedStoryRepositoryPurgesOnWrite(t *testing.T) {
+	h, _ := cachetest.New(t)
+	repo := newCountingStoryRepository(data.Story{ID: "s1", Slug: "first", Title: "Before"})
+	cached := data.NewCachedStoryRepository(repo, h.Cache)
+	ctx := context.Background()
+
+	if _, err := cached.GetByID(ctx, "s1"); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if _, err := cached.List(ctx, data.StoryListOptions{}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if err := cached.Update(ctx, &data.Story{ID: "s1", Slug: "first", Title: "After"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	story, err := cached.GetByID(ctx, "s1")
+	if err != nil {
+		t.Fatalf("GetByID after Update: %v", err)
+	}
+	if story.Title != "After" {
+		t.Errorf("GetByID after Update title = %q, want the updated story", story.Title)
+	}
+	if _, err := cached.List(ctx, data.StoryListOptions{}); err != nil {
+		t.Fatalf("List after Update: %v", err)
+	}
+	if n := repo.count("GetByID"); n != 2 {
+		t.Errorf("repository GetByID called %d times, want 2", n)
+	}
+	if n := repo.count("List"); n != 2 {
+		t.Errorf("repository List called %d times, want 2", n)
+	}
+}
+
+func TestCachedStoryRepositoryGetManyLoadsOnlyMisses(t *testing.T) {
+	h, _ := cachetest.New(t, data.WithNegativeTTL(time.Minute))
+	repo := newCountingStoryRepository(
+		data.Story{ID: "s1", Slug: "first", Title: "First"},
+		data.Story{ID: "s2", Slug: "second", Title: "Second"},
+	)
+	cached := data.NewCachedStoryRepository(repo, h.Cache)
+	ctx := context.Background()
+
+	if _, err := cached.GetByID(ctx, "s1"); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	keys := []data.StoryKey{{ID: "s1"}, {Slug: "second"}, {ID: "missing"}}
+	stories, err := cached.GetMany(ctx, keys)
+	if err != nil {
+		t.Fatalf("GetMany: %v", err)
+	}
+	if len(stories) != 3 || stories[0] == nil || stories[0].ID != "s1" || stories[1] == nil || stories[1].ID != "s2" || stories[2] != nil {
+		t.Fatalf("GetMany = %v, want s1, s2 and nil", stories)
+	}
+	if n := repo.count("GetByID"); n != 2 {
+		t.Errorf("repository GetByID called %d times, want 2 (s1 once, missing once)", n)
+	}
+
+	// 第二次全部由 cache 回應，含查無資料的標記
+	if _, err := cached.GetMany(ctx, keys); err != nil {
+		t.Fatalf("second GetMany: %v", err)
+	}
+	if n := repo.count("GetByID") + repo.count("GetBySlug"); n != 3 {
+		t.Errorf("repository lookups = %d after the second GetMany, want 3", n)
+	}
+}
+
+func TestCachedStoryRepositoryWithoutCache(t *testing.T) {
+	cache := data.NewCacheWithBackend(cachetest.NoopCache{}, 60, "test")
+	t.Cleanup(func() { _ = cache.Close() })
+	repo := newCountingStoryRepository(data.Story{ID: "s1", Slug: "first"})
+	cached := data.NewCachedStoryRepository(repo, cache)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cached.GetByID(context.Background(), "s1"); err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+	}
+	if n := repo.count("GetByID"); n != 2 {
+		t.Errorf("repository GetByID called %d times, want 2", n)
+	}
+}
@@ -0,0 +1,486 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StoryEvent is a change in the published state of a story.
+type StoryEvent string
+
+// Story events.
+const (
+	StoryEventPublished   StoryEvent = "story.published"   // story 變為已發布
+	StoryEventUpdated     StoryEvent = "story.updated"     // 已發布的 story 被修改
+	StoryEventUnpublished StoryEvent = "story.unpublished" // 已發布的 story 改為未發布或被刪除
+)
+
+// StoryEvents lists every StoryEvent.
+var StoryEvents = []StoryEvent{StoryEventPublished, StoryEventUpdated, StoryEventUnpublished}
+
+// StoryEventListener receives the events of an EventStoryRepository.
+type StoryEventListener interface {
+	// HandleStoryEvent is called after the write is committed, with the
+	// story as written (as it was before deletion for deletes). It should
+	// not block: it runs on the writer's request.
+	HandleStoryEvent(ctx context.Context, event StoryEvent, story *Story)
+}
+
+// EventStoryRepository wraps a StoryRepository and reports writes that
+// change what readers see as StoryEvents: a story becoming published, a
+// published story being updated, and a published story being unpublished
+// or deleted. Writes to unpublished stories have no event. The story is
+// read before each update and delete to compare states; events of writes
+// inside WithTx are reported after the transaction commits.
+type EventStoryRepository struct {
+	repo      StoryRepository
+	listeners []StoryEventListener
+}
+
+// NewEventStoryRepository wraps repo so its writes are reported to
+// listeners.
+func NewEventStoryRepository(repo StoryRepository, listeners ...StoryEventListener) *EventStoryRepository {
+	return &EventStoryRepository{repo: repo, listeners: listeners}
+}
+
+func (r *EventStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+func (r *EventStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	return r.repo.GetBySlug(ctx, slug)
+}
+
+func (r *EventStoryRepository) GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	return GetStories(ctx, r.repo, keys)
+}
+
+func (r *EventStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	return r.repo.List(ctx, opts)
+}
+
+func (r *EventStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	return r.repo.Search(ctx, query, opts)
+}
+
+func (r *EventStoryRepository) Create(ctx context.Context, story *Story) error {
+	tx := &eventTx{StoryRepository: r.repo}
+	if err := tx.Create(ctx, story); err != nil {
+		return err
+	}
+	r.emit(ctx, tx.pending)
+	return nil
+}
+
+func (r *EventStoryRepository) Update(ctx context.Context, story *Story) error {
+	tx := &eventTx{StoryRepository: r.repo}
+	if err := tx.Update(ctx, story); err != nil {
+		return err
+	}
+	r.emit(ctx, tx.pending)
+	return nil
+}
+
+func (r *EventStoryRepository) Delete(ctx context.Context, id string) error {
+	tx := &eventTx{StoryRepository: r.repo}
+	if err := tx.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.emit(ctx, tx.pending)
+	return nil
+}
+
+func (r *EventStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	tx := &eventTx{}
+	err := r.repo.WithTx(ctx, func(repo StoryRepository) error {
+		tx.StoryRepository = repo
+		tx.pending = nil // transaction 重試時只保留最後一次的事件
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+	r.emit(ctx, tx.pending)
+	return nil
+}
+
+// AddViewCounts 不產生事件：瀏覽數不屬於內容的變更
+func (r *EventStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	vw, ok := r.repo.(ViewCountWriter)
+	if !ok {
+		return ErrViewCountsUnsupported
+	}
+	return vw.AddViewCounts(ctx, counts)
+}
+
+// SetExcerpt 不產生事件：摘要由 SummaryGenerator 依事件產生，寫入不應再觸發事件
+func (r *EventStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	ew, ok := r.repo.(ExcerptWriter)
+	if !ok {
+		return ErrExcerptUnsupported
+	}
+	return ew.SetExcerpt(ctx, id, excerpt)
+}
+
+func (r *EventStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.ListRevisions(ctx, storyID)
+}
+
+func (r *EventStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.GetRevision(ctx, storyID, number)
+}
+
+func (r *EventStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st.ListTrash(ctx, limit, offset)
+}
+
+// RestoreStory 還原已發布的 story 時送出 StoryEventPublished
+func (r *EventStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	story, err := st.RestoreStory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tx := &eventTx{StoryRepository: r.repo}
+	tx.record(nil, story)
+	r.emit(ctx, tx.pending)
+	return story, nil
+}
+
+// PurgeStory 與 PurgeTrash 不產生事件：story 移至垃圾桶時已送出 StoryEventUnpublished
+func (r *EventStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return ErrTrashUnsupported
+	}
+	return st.PurgeStory(ctx, id)
+}
+
+func (r *EventStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return 0, ErrTrashUnsupported
+	}
+	return st.PurgeTrash(ctx, before)
+}
+
+func (r *EventStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorByID(ctx, id)
+}
+
+func (r *EventStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorBySlug(ctx, slug)
+}
+
+func (r *EventStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorsByIDs(ctx, ids)
+}
+
+func (r *EventStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.ListAuthors(ctx, limit, offset)
+}
+
+// CreateAuthor、UpdateAuthor 與 DeleteAuthor 不產生事件：事件只反映 story 的發布狀態
+func (r *EventStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.CreateAuthor(ctx, author)
+}
+
+func (r *EventStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.UpdateAuthor(ctx, author)
+}
+
+func (r *EventStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.DeleteAuthor(ctx, id)
+}
+
+func (r *EventStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.ListTerms(ctx, kind, limit, offset)
+}
+
+func (r *EventStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.GetTerm(ctx, kind, slug)
+}
+
+func (r *EventStoryRepository) GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return LoadTerms(ctx, tr, kind, slugs)
+}
+
+// 改名與合併會改寫 story 的 tag / 分類，已發布的 story 送出 StoryEventUpdated
+func (r *EventStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.CreateTerm(ctx, term); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *EventStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	relinked, err := tr.UpdateTerm(ctx, slug, term)
+	if err != nil {
+		return nil, err
+	}
+	r.emitRelinked(ctx, relinked)
+	return relinked, nil
+}
+
+func (r *EventStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	relinked, err := tr.MergeTerms(ctx, kind, from, into)
+	if err != nil {
+		return nil, err
+	}
+	r.emitRelinked(ctx, relinked)
+	return relinked, nil
+}
+
+func (r *EventStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.DeleteTerm(ctx, kind, slug); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *EventStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionByID(ctx, id)
+}
+
+func (r *EventStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionBySlug(ctx, slug)
+}
+
+func (r *EventStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.ListCollections(ctx, limit, offset)
+}
+
+func (r *EventStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.CollectionsByStory(ctx, storyID)
+}
+
+// 合集的寫入不改變 story 本身，不送出事件
+func (r *EventStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.CreateCollection(ctx, collection)
+}
+
+func (r *EventStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.UpdateCollection(ctx, collection)
+}
+
+func (r *EventStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.DeleteCollection(ctx, id)
+}
+
+// emitRelinked 對 tag / 分類被改寫的已發布 story 送出 StoryEventUpdated
+func (r *EventStoryRepository) emitRelinked(ctx context.Context, ids []string) {
+	tx := &eventTx{StoryRepository: r.repo}
+	for _, id := range ids {
+		story, err := r.repo.GetByID(ctx, id)
+		if err != nil {
+			continue // 垃圾桶中的 story 沒有事件
+		}
+		tx.record(story, story)
+	}
+	r.emit(ctx, tx.pending)
+}
+
+// emit 依序將事件交給每個 listener；寫入已完成，不受請求取消影響
+func (r *EventStoryRepository) emit(ctx context.Context, events []pendingStoryEvent) {
+	ctx = context.WithoutCancel(ctx)
+	for _, e := range events {
+		for _, l := range r.listeners {
+			l.HandleStoryEvent(ctx, e.event, e.story)
+		}
+	}
+}
+
+// pendingStoryEvent 為寫入後待送出的事件與當時的 story
+type pendingStoryEvent struct {
+	event StoryEvent
+	story *Story
+}
+
+// storyEventFor 依寫入前後的 story 判斷事件；nil 表示 story 不存在，沒有事件時回傳 false
+func storyEventFor(before, after *Story) (StoryEvent, bool) {
+	wasPublished := before != nil && before.Status == StoryStatusPublished
+	switch {
+	case after != nil && after.Status == StoryStatusPublished && wasPublished:
+		return StoryEventUpdated, true
+	case after != nil && after.Status == StoryStatusPublished:
+		return StoryEventPublished, true
+	case wasPublished:
+		return StoryEventUnpublished, true
+	}
+	return "", false
+}
+
+// eventTx 為寫入時使用的 repository，比對寫入前後的 story 並記錄事件，待 commit 後再送出
+type eventTx struct {
+	StoryRepository
+	pending []pendingStoryEvent
+}
+
+func (t *eventTx) Create(ctx context.Context, story *Story) error {
+	if err := t.StoryRepository.Create(ctx, story); err != nil {
+		return err
+	}
+	t.record(nil, story)
+	return nil
+}
+
+func (t *eventTx) Update(ctx context.Context, story *Story) error {
+	before, err := t.before(ctx, story.ID)
+	if err != nil {
+		return err
+	}
+	if err := t.StoryRepository.Update(ctx, story); err != nil {
+		return err
+	}
+	t.record(before, story)
+	return nil
+}
+
+func (t *eventTx) Delete(ctx context.Context, id string) error {
+	before, err := t.before(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := t.StoryRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	t.record(before, nil)
+	return nil
+}
+
+// WithTx 已在 transaction 中，直接執行 fn
+func (t *eventTx) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	return fn(t)
+}
+
+// before 讀取寫入前的 story；不存在時回傳 nil，交由寫入本身回傳 ErrStoryNotFound
+func (t *eventTx) before(ctx context.Context, id string) (*Story, error) {
+	if id == "" {
+		return nil, nil
+	}
+	story, err := t.StoryRepository.GetByID(ctx, id)
+	if errors.Is(err, ErrStoryNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return story, nil
+}
+
+// record 記錄 before 到 after 的事件；事件中的 story 為寫入後的副本，刪除時為刪除前的內容
+func (t *eventTx) record(before, after *Story) {
+	event, ok := storyEventFor(before, after)
+	if !ok {
+		return
+	}
+	snapshot := before
+	if after != nil {
+		copied := *after
+		if before != nil {
+			copied.ViewCount = before.ViewCount // Update 不會回填瀏覽數
+		}
+		snapshot = &copied
+	}
+	t.pending = append(t.pending, pendingStoryEvent{event: event, story: snapshot})
+}
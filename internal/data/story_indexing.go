@@ -0,0 +1,409 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// IndexingStoryRepository wraps a StoryRepository and pushes every
+// successful create, update and delete to a StoryIndexer. Indexing failures
+// are logged rather than returned: the write already succeeded and the next
+// StoryIndexer.Sync picks the story up. Stories are read back before
+// indexing so stored-only fields are current; writes inside WithTx are
+// indexed after the transaction commits.
+type IndexingStoryRepository struct {
+	repo    StoryRepository
+	indexer *StoryIndexer
+}
+
+// NewIndexingStoryRepository wraps repo so its writes are indexed by indexer.
+func NewIndexingStoryRepository(repo StoryRepository, indexer *StoryIndexer) *IndexingStoryRepository {
+	return &IndexingStoryRepository{repo: repo, indexer: indexer}
+}
+
+func (r *IndexingStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+func (r *IndexingStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	return r.repo.GetBySlug(ctx, slug)
+}
+
+func (r *IndexingStoryRepository) GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	return GetStories(ctx, r.repo, keys)
+}
+
+func (r *IndexingStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	return r.repo.List(ctx, opts)
+}
+
+func (r *IndexingStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	return r.repo.Search(ctx, query, opts)
+}
+
+func (r *IndexingStoryRepository) Create(ctx context.Context, story *Story) error {
+	if err := r.repo.Create(ctx, story); err != nil {
+		return err
+	}
+	r.index(ctx, story.ID)
+	return nil
+}
+
+func (r *IndexingStoryRepository) Update(ctx context.Context, story *Story) error {
+	if err := r.repo.Update(ctx, story); err != nil {
+		return err
+	}
+	r.index(ctx, story.ID)
+	return nil
+}
+
+func (r *IndexingStoryRepository) Delete(ctx context.Context, id string) error {
+	if err := r.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.delete(ctx, id)
+	return nil
+}
+
+func (r *IndexingStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	tx := &indexingTx{}
+	err := r.repo.WithTx(ctx, func(repo StoryRepository) error {
+		tx.StoryRepository = repo
+		tx.changed = nil // transaction 重試時只保留最後一次的變更
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range tx.changed {
+		r.index(ctx, id)
+	}
+	return nil
+}
+
+// AddViewCounts 不更新 index：瀏覽數不影響搜尋結果
+func (r *IndexingStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	vw, ok := r.repo.(ViewCountWriter)
+	if !ok {
+		return ErrViewCountsUnsupported
+	}
+	return vw.AddViewCounts(ctx, counts)
+}
+
+// SetExcerpt 更新 index 中的 story，摘要會出現在搜尋結果中
+func (r *IndexingStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	ew, ok := r.repo.(ExcerptWriter)
+	if !ok {
+		return ErrExcerptUnsupported
+	}
+	if err := ew.SetExcerpt(ctx, id, excerpt); err != nil {
+		return err
+	}
+	r.index(ctx, id)
+	return nil
+}
+
+func (r *IndexingStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.ListRevisions(ctx, storyID)
+}
+
+func (r *IndexingStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.GetRevision(ctx, storyID, number)
+}
+
+func (r *IndexingStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st.ListTrash(ctx, limit, offset)
+}
+
+func (r *IndexingStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	story, err := st.RestoreStory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.index(ctx, id)
+	return story, nil
+}
+
+// PurgeStory 與 PurgeTrash 不更新 index：story 移至垃圾桶時已自 index 移除
+func (r *IndexingStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return ErrTrashUnsupported
+	}
+	return st.PurgeStory(ctx, id)
+}
+
+func (r *IndexingStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return 0, ErrTrashUnsupported
+	}
+	return st.PurgeTrash(ctx, before)
+}
+
+func (r *IndexingStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorByID(ctx, id)
+}
+
+func (r *IndexingStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorBySlug(ctx, slug)
+}
+
+func (r *IndexingStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorsByIDs(ctx, ids)
+}
+
+func (r *IndexingStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.ListAuthors(ctx, limit, offset)
+}
+
+// CreateAuthor、UpdateAuthor 與 DeleteAuthor 不更新 index：index 中只有 author ID
+func (r *IndexingStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.CreateAuthor(ctx, author)
+}
+
+func (r *IndexingStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.UpdateAuthor(ctx, author)
+}
+
+func (r *IndexingStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.DeleteAuthor(ctx, id)
+}
+
+func (r *IndexingStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.ListTerms(ctx, kind, limit, offset)
+}
+
+func (r *IndexingStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.GetTerm(ctx, kind, slug)
+}
+
+func (r *IndexingStoryRepository) GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return LoadTerms(ctx, tr, kind, slugs)
+}
+
+// 改名與合併會改寫 story 的 tag / 分類，寫入後重新 index 這些 story
+func (r *IndexingStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.CreateTerm(ctx, term); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *IndexingStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	relinked, err := tr.UpdateTerm(ctx, slug, term)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range relinked {
+		r.index(ctx, id)
+	}
+	return relinked, nil
+}
+
+func (r *IndexingStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	relinked, err := tr.MergeTerms(ctx, kind, from, into)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range relinked {
+		r.index(ctx, id)
+	}
+	return relinked, nil
+}
+
+func (r *IndexingStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	if err := tr.DeleteTerm(ctx, kind, slug); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *IndexingStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionByID(ctx, id)
+}
+
+func (r *IndexingStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionBySlug(ctx, slug)
+}
+
+func (r *IndexingStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.ListCollections(ctx, limit, offset)
+}
+
+func (r *IndexingStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.CollectionsByStory(ctx, storyID)
+}
+
+// CreateCollection、UpdateCollection 與 DeleteCollection 不更新 index：index 中沒有合集
+func (r *IndexingStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.CreateCollection(ctx, collection)
+}
+
+func (r *IndexingStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.UpdateCollection(ctx, collection)
+}
+
+func (r *IndexingStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.DeleteCollection(ctx, id)
+}
+
+// index 重新讀取 story 並寫入 index，story 已不存在時改為刪除；失敗時只記錄日誌，由之後的同步補上
+func (r *IndexingStoryRepository) index(ctx context.Context, id string) {
+	ctx = context.WithoutCancel(ctx)
+	story, err := r.repo.GetByID(ctx, id)
+	if errors.Is(err, ErrStoryNotFound) {
+		r.delete(ctx, id)
+		return
+	}
+	if err == nil {
+		err = r.indexer.Index(ctx, story)
+	}
+	if err != nil {
+		slog.Warn("failed to index story", "id", id, "error", err)
+	}
+}
+
+// delete 從 index 移除 story；失敗時只記錄日誌
+func (r *IndexingStoryRepository) delete(ctx context.Context, id string) {
+	if err := r.indexer.Delete(context.WithoutCancel(ctx), id); err != nil {
+		slog.Warn("failed to delete story from index", "id", id, "error", err)
+	}
+}
+
+// indexingTx 為 transaction 中的 repository，記錄寫入的 story ID，待 commit 後再更新 index
+type indexingTx struct {
+	StoryRepository
+	changed []string
+}
+
+func (t *indexingTx) Create(ctx context.Context, story *Story) error {
+	if err := t.StoryRepository.Create(ctx, story); err != nil {
+		return err
+	}
+	t.changed = append(t.changed, story.ID)
+	return nil
+}
+
+func (t *indexingTx) Update(ctx context.Context, story *Story) error {
+	if err := t.StoryRepository.Update(ctx, story); err != nil {
+		return err
+	}
+	t.changed = append(t.changed, story.ID)
+	return nil
+}
+
+func (t *indexingTx) Delete(ctx context.Context, id string) error {
+	if err := t.StoryRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	t.changed = append(t.changed, id)
+	return nil
+}
+
+// WithTx 已在 transaction 中，直接執行 fn
+func (t *indexingTx) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	return fn(t)
+}
@@ -0,0 +1,1059 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// storyCollection、authorCollection、revisionCollection、termCollection、collectionCollection 與 redirectCollection 為存放 story、作者、story 版本、tag / 分類、合集與舊 slug 轉址的 collection 名稱
+const (
+	storyCollection      = "stories"
+	authorCollection     = "authors"
+	revisionCollection   = "story_revisions"
+	termCollection       = "taxonomy_terms"
+	collectionCollection = "collections"
+	redirectCollection   = "story_slug_redirects"
+)
+
+// storyDocument 為 story 在 MongoDB 中的格式
+type storyDocument struct {
+	ID          string          `bson:"_id"`
+	Slug        string          `bson:"slug"`
+	Title       string          `bson:"title"`
+	Subtitle    string          `bson:"subtitle"`
+	Summary     string          `bson:"summary"`
+	Body        string          `bson:"body"`
+	Blocks      []blockDocument `bson:"blocks,omitempty"`
+	Status      string          `bson:"status"`
+	Section     string          `bson:"section"`
+	Tags        []string        `bson:"tags"`
+	AuthorIDs   []string        `bson:"authorIds"`
+	CoverImage  string          `bson:"coverImage"`
+	IsMember    bool            `bson:"isMember"`
+	PublishedAt *time.Time      `bson:"publishedAt"`
+	CreatedAt   time.Time       `bson:"createdAt"`
+	UpdatedAt   time.Time       `bson:"updatedAt"`
+	WordCount   int             `bson:"wordCount"`
+	ReadingTime int             `bson:"readingTime"`
+	Excerpt     string          `bson:"excerpt"`
+	Locale      string          `bson:"locale"`
+	Translation string          `bson:"translationGroup"`
+	Access      string          `bson:"access"`    // 加入此欄位之前的 document 沒有，讀取時依 isMember 決定
+	OwnerID     string          `bson:"ownerId"`   // Update 不會覆寫
+	ViewCount   int64           `bson:"viewCount"` // Update 不會覆寫
+	DeletedAt   *time.Time      `bson:"deletedAt"` // 移至垃圾桶的時間，null 表示未刪除
+}
+
+// blockDocument 為 ContentBlock 在 MongoDB 中的格式
+type blockDocument struct {
+	Type    string                 `bson:"type"`
+	Text    string                 `bson:"text,omitempty"`
+	Level   int                    `bson:"level,omitempty"`
+	URL     string                 `bson:"url,omitempty"`
+	Alt     string                 `bson:"alt,omitempty"`
+	Caption string                 `bson:"caption,omitempty"`
+	Cite    string                 `bson:"cite,omitempty"`
+	Images  []galleryImageDocument `bson:"images,omitempty"`
+	MediaID string                 `bson:"mediaId,omitempty"`
+}
+
+// galleryImageDocument 為 GalleryImage 在 MongoDB 中的格式
+type galleryImageDocument struct {
+	URL     string `bson:"url"`
+	Alt     string `bson:"alt,omitempty"`
+	Caption string `bson:"caption,omitempty"`
+	MediaID string `bson:"mediaId,omitempty"`
+}
+
+// revisionDocument 為 story 版本在 MongoDB 中的格式
+type revisionDocument struct {
+	StoryID   string        `bson:"storyId"`
+	Number    int           `bson:"number"`
+	Story     storyDocument `bson:"story"`
+	CreatedAt time.Time     `bson:"createdAt"`
+}
+
+// MongoStoryRepository is a StoryRepository on a MongoDB collection, for
+// deployments whose CMS already lives in MongoDB. Delete moves stories to
+// the trash (the deletedAt field); see StoryTrash. It is only built with
+// the mongo build tag.
+type MongoStoryRepository struct {
+	client      *mongo.Client
+	coll        *mongo.Collection
+	authors     *mongo.Collection
+	revisions   *mongo.Collection
+	terms       *mongo.Collection
+	collections *mongo.Collection
+	redirects   *mongo.Collection // story 改名前的 slug (_id) 與 storyId
+	session     mongo.Session     // 進行中的 transaction，nil 表示不在 transaction 中
+}
+
+// NewMongoStoryRepository connects to uri and uses the stories collection of
+// database, creating its indexes when missing. Transactions (WithTx) require
+// a replica set.
+func NewMongoStoryRepository(ctx context.Context, uri, database string) (*MongoStoryRepository, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("connect mongo: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("ping mongo: %w", err)
+	}
+
+	repo := &MongoStoryRepository{
+		client:      client,
+		coll:        client.Database(database).Collection(storyCollection),
+		authors:     client.Database(database).Collection(authorCollection),
+		revisions:   client.Database(database).Collection(revisionCollection),
+		terms:       client.Database(database).Collection(termCollection),
+		collections: client.Database(database).Collection(collectionCollection),
+		redirects:   client.Database(database).Collection(redirectCollection),
+	}
+	_, err = repo.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "publishedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "section", Value: 1}}},
+		{Keys: bson.D{{Key: "locale", Value: 1}, {Key: "publishedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "translationGroup", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "authorIds", Value: 1}}},
+		{Keys: bson.D{{Key: "viewCount", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "deletedAt", Value: -1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("create story indexes: %w", err)
+	}
+	_, err = repo.authors.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("create author indexes: %w", err)
+	}
+	_, err = repo.revisions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "storyId", Value: 1}, {Key: "number", Value: -1}}, Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("create revision indexes: %w", err)
+	}
+	_, err = repo.terms.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "name", Value: 1}, {Key: "slug", Value: 1}}},
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "parent", Value: 1}}},
+	})
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("create term indexes: %w", err)
+	}
+	_, err = repo.collections.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "storyIds", Value: 1}}},
+	})
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("create collection indexes: %w", err)
+	}
+	_, err = repo.redirects.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "storyId", Value: 1}}})
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("create slug redirect indexes: %w", err)
+	}
+	return repo, nil
+}
+
+// Close disconnects from MongoDB.
+func (r *MongoStoryRepository) Close(ctx context.Context) error {
+	return r.client.Disconnect(ctx)
+}
+
+// ctx 在 transaction 中時將 session 綁到 ctx 上
+func (r *MongoStoryRepository) ctx(ctx context.Context) context.Context {
+	if r.session == nil {
+		return ctx
+	}
+	return mongo.NewSessionContext(ctx, r.session)
+}
+
+// 垃圾桶中的 story 不出現在查詢中；deletedAt 為 null 或不存在 (新增此欄位前的 document) 表示未刪除
+func (r *MongoStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.getOne(ctx, bson.M{"_id": id, "deletedAt": nil})
+}
+
+// GetBySlug 找不到 story 時依舊 slug 的轉址找出改名後的 story
+func (r *MongoStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	story, err := r.getOne(ctx, bson.M{"slug": slug, "deletedAt": nil})
+	if !errors.Is(err, ErrStoryNotFound) {
+		return story, err
+	}
+	var redirect slugRedirectDocument
+	err = r.redirects.FindOne(r.ctx(ctx), bson.M{"_id": slug}).Decode(&redirect)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get slug redirect: %w", err)
+	}
+	return r.getOne(ctx, bson.M{"_id": redirect.StoryID, "deletedAt": nil})
+}
+
+// slugRedirectDocument 為 story 改名前的 slug
+type slugRedirectDocument struct {
+	Slug      string    `bson:"_id"`
+	StoryID   string    `bson:"storyId"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// freeSlug 回傳以 base 為基礎、未被 story 或轉址使用的 slug
+func (r *MongoStoryRepository) freeSlug(ctx context.Context, base string) (string, error) {
+	pattern := bson.M{"$regex": "^" + regexp.QuoteMeta(base) + "(-.*)?$"}
+	var taken []string
+	for _, q := range []struct {
+		coll  *mongo.Collection
+		field string
+	}{{r.coll, "slug"}, {r.redirects, "_id"}} {
+		cursor, err := q.coll.Find(r.ctx(ctx), bson.M{q.field: pattern}, options.Find().SetProjection(bson.M{q.field: 1}))
+		if err != nil {
+			return "", fmt.Errorf("find free slug: %w", err)
+		}
+		var docs []bson.M
+		if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+			return "", fmt.Errorf("find free slug: %w", err)
+		}
+		for _, doc := range docs {
+			if slug, ok := doc[q.field].(string); ok {
+				taken = append(taken, slug)
+			}
+		}
+	}
+	return freeSlug(base, taken), nil
+}
+
+// moveSlugRedirect 在 slug 改變時將舊 slug 轉址到 story，並移除指向新 slug 的轉址 (該 slug 已屬於這篇 story)
+func (r *MongoStoryRepository) moveSlugRedirect(ctx context.Context, oldSlug string, story *Story) error {
+	if oldSlug == story.Slug {
+		return nil
+	}
+	if _, err := r.redirects.DeleteOne(r.ctx(ctx), bson.M{"_id": story.Slug}); err != nil {
+		return fmt.Errorf("delete slug redirect: %w", err)
+	}
+	if oldSlug == "" {
+		return nil
+	}
+	_, err := r.redirects.ReplaceOne(r.ctx(ctx), bson.M{"_id": oldSlug},
+		slugRedirectDocument{Slug: oldSlug, StoryID: story.ID, CreatedAt: story.UpdatedAt}, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("add slug redirect: %w", err)
+	}
+	return nil
+}
+
+// getOne 依條件查詢一篇 story
+func (r *MongoStoryRepository) getOne(ctx context.Context, filter bson.M) (*Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc storyDocument
+	err := r.coll.FindOne(r.ctx(ctx), filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get story: %w", err)
+	}
+	return doc.story(), nil
+}
+
+func (r *MongoStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	return r.list(ctx, "", opts)
+}
+
+func (r *MongoStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	return r.list(ctx, query, opts)
+}
+
+// list 組出 List / Search 的查詢；query 不為空時以不分大小寫的 regex 比對標題、摘要與內文
+func (r *MongoStoryRepository) list(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"deletedAt": nil}
+	if opts.Status != "" {
+		filter["status"] = opts.Status
+	}
+	if opts.Section != "" {
+		filter["section"] = opts.Section
+	}
+	if opts.Locale != "" {
+		filter["locale"] = opts.Locale
+	}
+	if opts.TranslationGroup != "" {
+		filter["translationGroup"] = opts.TranslationGroup
+	}
+	if opts.Tag != "" {
+		filter["tags"] = opts.Tag
+	}
+	if opts.Author != "" {
+		filter["authorIds"] = opts.Author
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(query), "$options": "i"}
+		filter["$or"] = bson.A{bson.M{"title": pattern}, bson.M{"summary": pattern}, bson.M{"body": pattern}}
+	}
+	if opts.Where != nil {
+		conds, err := storyWhereBSON(opts.Where)
+		if err != nil {
+			return nil, err
+		}
+		if len(conds) > 0 {
+			filter = bson.M{"$and": append(bson.A{filter}, conds...)}
+		}
+	}
+
+	keys, err := opts.sortKeys()
+	if err != nil {
+		return nil, err
+	}
+	if opts.After != "" {
+		values, err := decodeStoryCursor(opts.After, keys)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"$and": bson.A{filter, storyKeysetBSON(keys, values)}}
+	}
+
+	// MongoDB 排序時 null 最小，降冪排序下未發布的 story 會排在最後，與 Postgres 一致
+	sort := bson.D{}
+	for _, key := range keys {
+		direction := 1
+		if key.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: storySortField(key.Field), Value: direction})
+	}
+	findOpts := options.Find().
+		SetSort(sort).
+		SetLimit(int64(opts.limit())).
+		SetSkip(int64(opts.Offset))
+	cursor, err := r.coll.Find(r.ctx(ctx), filter, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("list stories: %w", err)
+	}
+	var docs []storyDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("list stories: %w", err)
+	}
+
+	stories := make([]Story, 0, len(docs))
+	for _, doc := range docs {
+		stories = append(stories, *doc.story())
+	}
+	return stories, nil
+}
+
+func (r *MongoStoryRepository) Create(ctx context.Context, story *Story) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	prepareStory(story, time.Now().UTC())
+	if err := checkNewStoryStatus(story); err != nil {
+		return err
+	}
+	if err := ValidateBlocks(story.Blocks); err != nil {
+		return err
+	}
+	if err := normalizeStoryLocale(story); err != nil {
+		return err
+	}
+	if err := normalizeStoryAccess(story); err != nil {
+		return err
+	}
+	if story.Slug == "" {
+		slug, err := r.freeSlug(ctx, Slugify(story.Title))
+		if err != nil {
+			return err
+		}
+		story.Slug = slug
+	}
+	_, err := r.coll.InsertOne(r.ctx(ctx), newStoryDocument(story))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrStorySlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("create story: %w", err)
+	}
+	if err := r.moveSlugRedirect(ctx, "", story); err != nil {
+		return err
+	}
+	return r.addRevision(ctx, story)
+}
+
+func (r *MongoStoryRepository) Update(ctx context.Context, story *Story) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if story.ID == "" {
+		return ErrStoryNotFound
+	}
+	prepareStory(story, time.Now().UTC())
+	if err := ValidateBlocks(story.Blocks); err != nil {
+		return err
+	}
+	if err := normalizeStoryLocale(story); err != nil {
+		return err
+	}
+	if err := normalizeStoryAccess(story); err != nil {
+		return err
+	}
+	// 先讀取目前的狀態檢查轉換，更新時以該狀態為條件，避免同時寫入的狀態互相覆蓋
+	var current storyDocument
+	err := r.coll.FindOne(r.ctx(ctx), bson.M{"_id": story.ID, "deletedAt": nil}, options.FindOne().SetProjection(bson.M{"status": 1, "slug": 1})).Decode(&current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrStoryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("get story status: %w", err)
+	}
+	if err := CheckStoryTransition(current.Status, story.Status); err != nil {
+		return err
+	}
+	// 沒有指定 slug 時沿用目前的 slug，不因標題修改而改變網址
+	if story.Slug == "" {
+		story.Slug = current.Slug
+	}
+	doc := newStoryDocument(story)
+	// createdAt 與 ownerId 不更新，回傳資料庫中的值
+	update := bson.M{"$set": bson.M{
+		"slug": doc.Slug, "title": doc.Title, "subtitle": doc.Subtitle, "summary": doc.Summary,
+		"body": doc.Body, "blocks": doc.Blocks, "status": doc.Status, "section": doc.Section, "tags": doc.Tags,
+		"authorIds": doc.AuthorIDs, "coverImage": doc.CoverImage, "isMember": doc.IsMember, "publishedAt": doc.PublishedAt,
+		"updatedAt": doc.UpdatedAt, "wordCount": doc.WordCount, "readingTime": doc.ReadingTime,
+		"excerpt": doc.Excerpt, "locale": doc.Locale, "translationGroup": doc.Translation, "access": doc.Access,
+	}}
+	var stored storyDocument
+	err = r.coll.FindOneAndUpdate(r.ctx(ctx), bson.M{"_id": story.ID, "status": current.Status, "deletedAt": nil}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: status of story %s changed concurrently", ErrInvalidStoryTransition, story.ID)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrStorySlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("update story: %w", err)
+	}
+	story.CreatedAt, story.OwnerID = stored.CreatedAt, stored.OwnerID
+	if err := r.moveSlugRedirect(ctx, current.Slug, story); err != nil {
+		return err
+	}
+	return r.addRevision(ctx, story)
+}
+
+// addRevision 記錄 story 寫入後的版本；不在 transaction 中時與寫入本身不是原子操作，
+// 同時寫入造成版本號重複時由 unique index 擋下並回傳錯誤
+func (r *MongoStoryRepository) addRevision(ctx context.Context, story *Story) error {
+	number := 1
+	var latest revisionDocument
+	err := r.revisions.FindOne(r.ctx(ctx), bson.M{"storyId": story.ID},
+		options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}}).SetProjection(bson.M{"number": 1})).Decode(&latest)
+	switch {
+	case err == nil:
+		number = latest.Number + 1
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return fmt.Errorf("get latest story revision: %w", err)
+	}
+	snapshot := revisionSnapshot(story)
+	_, err = r.revisions.InsertOne(r.ctx(ctx), revisionDocument{
+		StoryID: story.ID, Number: number, Story: newStoryDocument(&snapshot), CreatedAt: story.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("add story revision: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// 列表不回傳 body 與 blocks，需要時以 GetRevision 讀取單一版本
+	cur, err := r.revisions.Find(r.ctx(ctx), bson.M{"storyId": storyID},
+		options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetProjection(bson.M{"story.body": 0, "story.blocks": 0}))
+	if err != nil {
+		return nil, fmt.Errorf("list story revisions: %w", err)
+	}
+	var docs []revisionDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decode story revisions: %w", err)
+	}
+	revisions := make([]StoryRevision, 0, len(docs))
+	for _, doc := range docs {
+		revisions = append(revisions, doc.revision())
+	}
+	return revisions, nil
+}
+
+func (r *MongoStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc revisionDocument
+	err := r.revisions.FindOne(r.ctx(ctx), bson.M{"storyId": storyID, "number": number}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get story revision: %w", err)
+	}
+	revision := doc.revision()
+	return &revision, nil
+}
+
+func (r *MongoStoryRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.coll.UpdateOne(r.ctx(ctx), bson.M{"_id": id, "deletedAt": nil}, bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}})
+	if err != nil {
+		return fmt.Errorf("delete story: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrStoryNotFound
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "deletedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+	cursor, err := r.coll.Find(r.ctx(ctx), bson.M{"deletedAt": bson.M{"$ne": nil}}, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("list trashed stories: %w", err)
+	}
+	var docs []storyDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("list trashed stories: %w", err)
+	}
+	stories := make([]Story, 0, len(docs))
+	for _, doc := range docs {
+		stories = append(stories, *doc.story())
+	}
+	return stories, nil
+}
+
+func (r *MongoStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc storyDocument
+	err := r.coll.FindOneAndUpdate(r.ctx(ctx), bson.M{"_id": id, "deletedAt": bson.M{"$ne": nil}}, bson.M{"$set": bson.M{"deletedAt": nil}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore story: %w", err)
+	}
+	return doc.story(), nil
+}
+
+// PurgeStory 永久刪除垃圾桶中的 story 與其版本紀錄；不在 transaction 中時兩者不是原子操作，
+// 殘留的版本紀錄不會再被讀取
+func (r *MongoStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.coll.DeleteOne(r.ctx(ctx), bson.M{"_id": id, "deletedAt": bson.M{"$ne": nil}})
+	if err != nil {
+		return fmt.Errorf("purge story: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrStoryNotFound
+	}
+	if _, err := r.revisions.DeleteMany(r.ctx(ctx), bson.M{"storyId": id}); err != nil {
+		return fmt.Errorf("purge story revisions: %w", err)
+	}
+	if _, err := r.redirects.DeleteMany(r.ctx(ctx), bson.M{"storyId": id}); err != nil {
+		return fmt.Errorf("purge story slug redirects: %w", err)
+	}
+	if _, err := r.collections.UpdateMany(r.ctx(ctx), bson.M{"storyIds": id}, bson.M{"$pull": bson.M{"storyIds": id}}); err != nil {
+		return fmt.Errorf("remove story from collections: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// 逐篇刪除，讓版本紀錄隨 story 一併刪除
+	cursor, err := r.coll.Find(r.ctx(ctx), bson.M{"deletedAt": bson.M{"$ne": nil, "$lt": before}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+	var docs []storyDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+	purged := 0
+	for _, doc := range docs {
+		err := r.PurgeStory(ctx, doc.ID)
+		if errors.Is(err, ErrStoryNotFound) {
+			continue // 已被還原或刪除
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (r *MongoStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// 只累加 viewCount，不更新 updatedAt
+	models := make([]mongo.WriteModel, 0, len(counts))
+	for id, n := range counts {
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(bson.M{"$inc": bson.M{"viewCount": n}}))
+	}
+	if _, err := r.coll.BulkWrite(r.ctx(ctx), models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("add view counts: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	// 不更新 updatedAt，也不記錄版本；editor 已填寫 summary 時不覆寫
+	_, err := r.coll.UpdateOne(r.ctx(ctx), bson.M{"_id": id, "summary": ""}, bson.M{"$set": bson.M{"excerpt": excerpt}})
+	if err != nil {
+		return fmt.Errorf("set excerpt: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	if r.session != nil {
+		// 已在 transaction 中，直接沿用
+		return fn(r)
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(&MongoStoryRepository{client: r.client, coll: r.coll, authors: r.authors, revisions: r.revisions, terms: r.terms, collections: r.collections, redirects: r.redirects, session: session})
+	})
+	return err
+}
+
+func (r *MongoStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	return r.getAuthor(ctx, bson.M{"_id": id})
+}
+
+func (r *MongoStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	return r.getAuthor(ctx, bson.M{"slug": slug})
+}
+
+// getAuthor 依條件查詢一位作者
+func (r *MongoStoryRepository) getAuthor(ctx context.Context, filter bson.M) (*Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc authorDocument
+	err := r.authors.FindOne(r.ctx(ctx), filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrAuthorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get author: %w", err)
+	}
+	author := doc.author()
+	return &author, nil
+}
+
+func (r *MongoStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	if len(ids) == 0 {
+		return []Author{}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.authors.Find(r.ctx(ctx), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, fmt.Errorf("get authors: %w", err)
+	}
+	var docs []authorDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("get authors: %w", err)
+	}
+	authors := make([]Author, 0, len(docs))
+	for _, doc := range docs {
+		authors = append(authors, doc.author())
+	}
+	return orderAuthors(ids, authors), nil
+}
+
+func (r *MongoStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(authorLimit(limit))).SetSkip(int64(max(offset, 0)))
+	cursor, err := r.authors.Find(r.ctx(ctx), bson.M{}, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("list authors: %w", err)
+	}
+	var docs []authorDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("list authors: %w", err)
+	}
+	authors := make([]Author, 0, len(docs))
+	for _, doc := range docs {
+		authors = append(authors, doc.author())
+	}
+	return authors, nil
+}
+
+func (r *MongoStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := prepareAuthor(author, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.authors.InsertOne(r.ctx(ctx), newAuthorDocument(author))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAuthorSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("create author: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if author.ID == "" {
+		return ErrAuthorNotFound
+	}
+	if err := prepareAuthor(author, time.Now().UTC()); err != nil {
+		return err
+	}
+	doc := newAuthorDocument(author)
+	// createdAt 不更新，回傳資料庫中的值
+	update := bson.M{"$set": bson.M{
+		"slug": doc.Slug, "name": doc.Name, "bio": doc.Bio, "avatar": doc.Avatar, "socialLinks": doc.SocialLinks,
+		"updatedAt": doc.UpdatedAt,
+	}}
+	var stored authorDocument
+	err := r.authors.FindOneAndUpdate(r.ctx(ctx), bson.M{"_id": author.ID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAuthorNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAuthorSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("update author: %w", err)
+	}
+	author.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+// DeleteAuthor 只刪除沒有任何 story (含垃圾桶) 署名的作者；檢查與刪除不是原子操作
+func (r *MongoStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := r.coll.CountDocuments(r.ctx(ctx), bson.M{"authorIds": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("count author stories: %w", err)
+	}
+	if n > 0 {
+		if _, err := r.getAuthor(ctx, bson.M{"_id": id}); err != nil {
+			return err
+		}
+		return ErrAuthorHasStories
+	}
+	res, err := r.authors.DeleteOne(r.ctx(ctx), bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("delete author: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrAuthorNotFound
+	}
+	return nil
+}
+
+// authorDocument 為作者在 MongoDB 中的格式
+type authorDocument struct {
+	ID          string               `bson:"_id"`
+	Slug        string               `bson:"slug"`
+	Name        string               `bson:"name"`
+	Bio         string               `bson:"bio"`
+	Avatar      string               `bson:"avatar"`
+	SocialLinks []authorLinkDocument `bson:"socialLinks"`
+	CreatedAt   time.Time            `bson:"createdAt"`
+	UpdatedAt   time.Time            `bson:"updatedAt"`
+}
+
+// authorLinkDocument 為 AuthorLink 在 MongoDB 中的格式
+type authorLinkDocument struct {
+	Network string `bson:"network"`
+	URL     string `bson:"url"`
+}
+
+// newAuthorDocument 將 Author 轉為 MongoDB document
+func newAuthorDocument(a *Author) authorDocument {
+	links := make([]authorLinkDocument, len(a.SocialLinks))
+	for i, link := range a.SocialLinks {
+		links[i] = authorLinkDocument{Network: link.Network, URL: link.URL}
+	}
+	return authorDocument{
+		ID: a.ID, Slug: a.Slug, Name: a.Name, Bio: a.Bio, Avatar: a.Avatar, SocialLinks: links,
+		CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt,
+	}
+}
+
+// author 將 document 轉回 Author；新增個人資料欄位前的 document 沒有 socialLinks
+func (d authorDocument) author() Author {
+	links := make([]AuthorLink, len(d.SocialLinks))
+	for i, link := range d.SocialLinks {
+		links[i] = AuthorLink{Network: link.Network, URL: link.URL}
+	}
+	return Author{
+		ID: d.ID, Slug: d.Slug, Name: d.Name, Bio: d.Bio, Avatar: d.Avatar, SocialLinks: links,
+		CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// newStoryDocument 將 Story 轉為 MongoDB document
+func newStoryDocument(s *Story) storyDocument {
+	return storyDocument{
+		ID: s.ID, Slug: s.Slug, Title: s.Title, Subtitle: s.Subtitle, Summary: s.Summary, Body: s.Body, Blocks: newBlockDocuments(s.Blocks),
+		Status: s.Status, Section: s.Section, Tags: s.Tags, AuthorIDs: s.AuthorIDs, CoverImage: s.CoverImage, IsMember: s.IsMember,
+		PublishedAt: s.PublishedAt, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt, WordCount: s.WordCount, ReadingTime: s.ReadingTime,
+		Excerpt: s.Excerpt, Locale: s.Locale, Translation: s.TranslationGroup, Access: s.Access, OwnerID: s.OwnerID,
+	}
+}
+
+// story 將 document 轉回 Story
+func (d storyDocument) story() *Story {
+	tags := d.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	authorIDs := d.AuthorIDs
+	if authorIDs == nil {
+		authorIDs = []string{}
+	}
+	story := &Story{
+		ID: d.ID, Slug: d.Slug, Title: d.Title, Subtitle: d.Subtitle, Summary: d.Summary, Body: d.Body, Blocks: d.blocks(),
+		Status: d.Status, Section: d.Section, Tags: tags, AuthorIDs: authorIDs, CoverImage: d.CoverImage, IsMember: d.IsMember,
+		PublishedAt: d.PublishedAt, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt, WordCount: d.WordCount, ReadingTime: d.ReadingTime,
+		Excerpt: d.Excerpt, Locale: d.Locale, TranslationGroup: d.Translation, Access: d.Access, OwnerID: d.OwnerID, ViewCount: d.ViewCount, DeletedAt: d.DeletedAt,
+	}
+	fillComputedFields(story)
+	return story
+}
+
+// newBlockDocuments 將 ContentBlock 轉為 MongoDB document；沒有 block 時不寫入該欄位
+func newBlockDocuments(blocks []ContentBlock) []blockDocument {
+	if len(blocks) == 0 {
+		return nil
+	}
+	docs := make([]blockDocument, len(blocks))
+	for i, b := range blocks {
+		images := make([]galleryImageDocument, len(b.Images))
+		for j, image := range b.Images {
+			images[j] = galleryImageDocument{URL: image.URL, Alt: image.Alt, Caption: image.Caption, MediaID: image.MediaID}
+		}
+		docs[i] = blockDocument{Type: b.Type, Text: b.Text, Level: b.Level, URL: b.URL, Alt: b.Alt, Caption: b.Caption, Cite: b.Cite, Images: images,
+			MediaID: b.MediaID}
+	}
+	return docs
+}
+
+// blocks 將 document 的 block 轉回 ContentBlock；新增 block 前的 document 沒有 blocks
+func (d storyDocument) blocks() []ContentBlock {
+	if len(d.Blocks) == 0 {
+		return nil
+	}
+	blocks := make([]ContentBlock, len(d.Blocks))
+	for i, b := range d.Blocks {
+		var images []GalleryImage
+		for _, image := range b.Images {
+			images = append(images, GalleryImage{URL: image.URL, Alt: image.Alt, Caption: image.Caption, MediaID: image.MediaID})
+		}
+		blocks[i] = ContentBlock{Type: b.Type, Text: b.Text, Level: b.Level, URL: b.URL, Alt: b.Alt, Caption: b.Caption, Cite: b.Cite, Images: images,
+			MediaID: b.MediaID}
+	}
+	return blocks
+}
+
+// revision 將 document 轉回 StoryRevision
+func (d revisionDocument) revision() StoryRevision {
+	return StoryRevision{StoryID: d.StoryID, Number: d.Number, Story: *d.Story.story(), CreatedAt: d.CreatedAt}
+}
+
+// openMongoStoryRepository 供 OpenStoryRepository 使用
+func openMongoStoryRepository(ctx context.Context, uri, database string) (StoryRepository, func() error, error) {
+	repo, err := NewMongoStoryRepository(ctx, uri, database)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, func() error { return repo.Close(context.Background()) }, nil
+}
+
+// storySortField 回傳排序欄位對應的 document 欄位
+func storySortField(field string) string {
+	switch field {
+	case StorySortPublishedAt:
+		return "publishedAt"
+	case StorySortUpdatedAt:
+		return "updatedAt"
+	case StorySortPopularity:
+		return "viewCount"
+	case "createdAt":
+		return "createdAt"
+	}
+	return "_id"
+}
+
+// storyKeysetBSON 組出「排在 cursor 之後」的條件；未發布的 publishedAt (null) 視為最小值
+func storyKeysetBSON(keys []storySortKey, values []interface{}) bson.M {
+	// 將 *time.Time 還原為 null 或時間，方便比較
+	for i, value := range values {
+		if t, ok := value.(*time.Time); ok {
+			if t == nil {
+				values[i] = nil
+			} else {
+				values[i] = *t
+			}
+		}
+	}
+	ors := bson.A{}
+	for i, key := range keys {
+		ands := bson.A{}
+		for j := 0; j < i; j++ {
+			ands = append(ands, bson.M{storySortField(keys[j].Field): values[j]})
+		}
+		field := storySortField(key.Field)
+		switch {
+		case values[i] == nil && key.Desc:
+			// 沒有比 null 更小的值
+			continue
+		case values[i] == nil:
+			ands = append(ands, bson.M{field: bson.M{"$ne": nil}})
+		case key.Desc && key.Field == StorySortPublishedAt:
+			ands = append(ands, bson.M{"$or": bson.A{bson.M{field: bson.M{"$lt": values[i]}}, bson.M{field: nil}}})
+		case key.Desc:
+			ands = append(ands, bson.M{field: bson.M{"$lt": values[i]}})
+		default:
+			ands = append(ands, bson.M{field: bson.M{"$gt": values[i]}})
+		}
+		ors = append(ors, bson.M{"$and": ands})
+	}
+	return bson.M{"$or": ors}
+}
+
+// storyStringFilterBSON 將 StringFilter 轉為 field 上的條件；tags 與 authorIds 為陣列，equals 即為含有該值
+func storyStringFilterBSON(field string, f *StringFilter) bson.A {
+	if f == nil {
+		return nil
+	}
+	conds := bson.A{}
+	if f.Equals != nil {
+		conds = append(conds, bson.M{field: *f.Equals})
+	}
+	if len(f.In) > 0 {
+		conds = append(conds, bson.M{field: bson.M{"$in": f.In}})
+	}
+	if not := storyStringFilterBSON(field, f.Not); len(not) > 0 {
+		conds = append(conds, bson.M{"$nor": bson.A{bson.M{"$and": not}}})
+	}
+	return conds
+}
+
+// storyLocaleFilterBSON 同 storyStringFilterBSON；空字串一併比對加入 locale 欄位之前、沒有此欄位的 document
+func storyLocaleFilterBSON(f *StringFilter) bson.A {
+	if f == nil {
+		return nil
+	}
+	values := func(locales ...string) bson.A {
+		a := bson.A{}
+		for _, locale := range locales {
+			a = append(a, locale)
+			if locale == "" {
+				a = append(a, nil)
+			}
+		}
+		return a
+	}
+	conds := bson.A{}
+	if f.Equals != nil {
+		conds = append(conds, bson.M{"locale": bson.M{"$in": values(*f.Equals)}})
+	}
+	if len(f.In) > 0 {
+		conds = append(conds, bson.M{"locale": bson.M{"$in": values(f.In...)}})
+	}
+	if not := storyLocaleFilterBSON(f.Not); len(not) > 0 {
+		conds = append(conds, bson.M{"$nor": bson.A{bson.M{"$and": not}}})
+	}
+	return conds
+}
+
+// storyWhereBSON 將 StoryWhereInput 轉為條件
+func storyWhereBSON(where *StoryWhereInput) (bson.A, error) {
+	conds := bson.A{}
+	conds = append(conds, storyStringFilterBSON("section", where.Section)...)
+	conds = append(conds, storyStringFilterBSON("tags", where.Tag)...)
+	conds = append(conds, storyStringFilterBSON("authorIds", where.Author)...)
+	conds = append(conds, storyStringFilterBSON("status", where.Status)...)
+	conds = append(conds, storyLocaleFilterBSON(where.Locale)...)
+	if where.PublishedAt != nil {
+		gte, lt, err := where.PublishedAt.bounds()
+		if err != nil {
+			return nil, err
+		}
+		if gte != nil {
+			conds = append(conds, bson.M{"publishedAt": bson.M{"$gte": *gte}})
+		}
+		if lt != nil {
+			conds = append(conds, bson.M{"publishedAt": bson.M{"$lt": *lt}})
+		}
+	}
+	return conds, nil
+}
@@ -0,0 +1,967 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres 的錯誤代碼：unique constraint 與 foreign key 違反
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// storyColumns 為寫入 stories 時的欄位順序；view_count 只由資料庫累計，不在其中
+const storyColumns = `id, slug, title, subtitle, summary, body, status, section, tags, cover_image, is_member, published_at, created_at, updated_at, blocks, word_count, reading_time, excerpt, locale, translation_group, access, owner_id`
+
+// storySelectColumns 為查詢 stories 時的欄位順序，需與 scanStory 一致；最後一欄為依署名順序排列的 author ID
+const storySelectColumns = storyColumns + `, view_count, deleted_at, COALESCE((SELECT jsonb_agg(sa.author_id ORDER BY sa.position) FROM story_authors sa WHERE sa.story_id = stories.id), '[]'::jsonb)`
+
+// authorColumns 為查詢 authors 時的欄位順序，需與 scanAuthor 一致
+const authorColumns = `id, slug, name, bio, avatar, social_links, created_at, updated_at`
+
+// sqlExecutor 為 *sql.DB 與 *sql.Tx 共同的方法
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// PostgresStoryRepository is a StoryRepository on the stories table (see
+// internal/data/migrations). Delete moves stories to the trash (the
+// deleted_at column); see StoryTrash.
+type PostgresStoryRepository struct {
+	db *sql.DB
+	q  sqlExecutor // db 或進行中的 transaction
+}
+
+// NewPostgresStoryRepository creates a repository on db.
+func NewPostgresStoryRepository(db *sql.DB) *PostgresStoryRepository {
+	return &PostgresStoryRepository{db: db, q: db}
+}
+
+func (r *PostgresStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.getOne(ctx, "id", id)
+}
+
+// GetBySlug 找不到 story 時依舊 slug 的轉址找出改名後的 story
+func (r *PostgresStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	story, err := r.getOne(ctx, "slug", slug)
+	if !errors.Is(err, ErrStoryNotFound) {
+		return story, err
+	}
+	var id string
+	err = r.q.QueryRowContext(ctx, `SELECT story_id FROM story_slug_redirects WHERE slug = $1`, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get slug redirect: %w", err)
+	}
+	return r.getOne(ctx, "id", id)
+}
+
+// getOne 依單一欄位查詢一篇不在垃圾桶中的 story
+func (r *PostgresStoryRepository) getOne(ctx context.Context, column, value string) (*Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := r.q.QueryRowContext(ctx, `SELECT `+storySelectColumns+` FROM stories WHERE `+column+` = $1 AND deleted_at IS NULL`, value)
+	story, err := scanStory(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get story by %s: %w", column, err)
+	}
+	return story, nil
+}
+
+// GetMany 以一次查詢讀取 keys 的 story；第一欄為比對到的舊 slug，供以舊 slug 查詢的 key 對應
+func (r *PostgresStoryRepository) GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ids, slugs := []string{}, []string{}
+	for _, key := range keys {
+		if key.ID != "" {
+			ids = append(ids, key.ID)
+		} else {
+			slugs = append(slugs, key.Slug)
+		}
+	}
+	rows, err := r.q.QueryContext(ctx, `SELECT COALESCE((SELECT jsonb_agg(rd.slug) FROM story_slug_redirects rd WHERE rd.story_id = stories.id AND rd.slug = ANY($2)), '[]'::jsonb), `+
+		storySelectColumns+` FROM stories
+		WHERE deleted_at IS NULL AND (id = ANY($1) OR slug = ANY($2) OR id IN (SELECT story_id FROM story_slug_redirects WHERE slug = ANY($2)))`,
+		ids, slugs)
+	if err != nil {
+		return nil, fmt.Errorf("get stories: %w", err)
+	}
+	defer rows.Close()
+
+	byID, bySlug, byOldSlug := map[string]*Story{}, map[string]*Story{}, map[string]*Story{}
+	for rows.Next() {
+		var oldSlugs []byte
+		story, err := scanStory(prefixedRow{rows, []interface{}{&oldSlugs}})
+		if err != nil {
+			return nil, fmt.Errorf("scan story: %w", err)
+		}
+		var redirects []string
+		if err := json.Unmarshal(oldSlugs, &redirects); err != nil {
+			return nil, fmt.Errorf("decode slug redirects: %w", err)
+		}
+		byID[story.ID], bySlug[story.Slug] = story, story
+		for _, slug := range redirects {
+			byOldSlug[slug] = story
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get stories: %w", err)
+	}
+
+	// 與 GetBySlug 相同，目前的 slug 優先於舊 slug
+	stories := make([]*Story, len(keys))
+	for i, key := range keys {
+		switch {
+		case key.ID != "":
+			stories[i] = byID[key.ID]
+		case bySlug[key.Slug] != nil:
+			stories[i] = bySlug[key.Slug]
+		default:
+			stories[i] = byOldSlug[key.Slug]
+		}
+	}
+	return stories, nil
+}
+
+func (r *PostgresStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	return r.list(ctx, "", opts)
+}
+
+func (r *PostgresStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	return r.list(ctx, query, opts)
+}
+
+// list 組出 List / Search 的查詢；query 不為空時比對標題、摘要與內文
+func (r *PostgresStoryRepository) list(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + storySelectColumns + ` FROM stories`)
+
+	conds := []string{`deleted_at IS NULL`}
+	args := []interface{}{}
+	argIdx := 1
+	addCond := func(format string, value interface{}) {
+		conds = append(conds, fmt.Sprintf(format, argIdx))
+		args = append(args, value)
+		argIdx++
+	}
+	bind := func(value interface{}) string {
+		args = append(args, value)
+		argIdx++
+		return fmt.Sprintf("$%d", argIdx-1)
+	}
+
+	if opts.Status != "" {
+		addCond(`status = $%d`, opts.Status)
+	}
+	if opts.Section != "" {
+		addCond(`section = $%d`, opts.Section)
+	}
+	if opts.Locale != "" {
+		addCond(`locale = $%d`, opts.Locale)
+	}
+	if opts.TranslationGroup != "" {
+		addCond(`translation_group = $%d`, opts.TranslationGroup)
+	}
+	if opts.Tag != "" {
+		tag, _ := json.Marshal([]string{opts.Tag})
+		addCond(`tags @> $%d::jsonb`, string(tag))
+	}
+	if opts.Author != "" {
+		addCond(`EXISTS (SELECT 1 FROM story_authors sa WHERE sa.story_id = stories.id AND sa.author_id = $%d)`, opts.Author)
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		addCond(`(title ILIKE $%[1]d OR summary ILIKE $%[1]d OR body ILIKE $%[1]d)`, "%"+escapeLike(query)+"%")
+	}
+	if opts.Where != nil {
+		whereConds, err := storyWhereSQL(opts.Where, bind)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, whereConds...)
+	}
+
+	keys, err := opts.sortKeys()
+	if err != nil {
+		return nil, err
+	}
+	if opts.After != "" {
+		values, err := decodeStoryCursor(opts.After, keys)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, storyKeysetSQL(keys, values, bind))
+	}
+
+	sb.WriteString(" WHERE ")
+	sb.WriteString(strings.Join(conds, " AND "))
+	orders := make([]string, len(keys))
+	for i, key := range keys {
+		orders[i] = storySortColumn(key.Field) + " ASC"
+		if key.Desc {
+			orders[i] = storySortColumn(key.Field) + " DESC"
+		}
+	}
+	sb.WriteString(" ORDER BY " + strings.Join(orders, ", "))
+	sb.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1))
+	args = append(args, opts.limit(), opts.Offset)
+
+	rows, err := r.q.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list stories: %w", err)
+	}
+	defer rows.Close()
+
+	stories := []Story{}
+	for rows.Next() {
+		story, err := scanStory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan story: %w", err)
+		}
+		stories = append(stories, *story)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list stories: %w", err)
+	}
+	return stories, nil
+}
+
+func (r *PostgresStoryRepository) Create(ctx context.Context, story *Story) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	prepareStory(story, time.Now().UTC())
+	if err := checkNewStoryStatus(story); err != nil {
+		return err
+	}
+	if err := ValidateBlocks(story.Blocks); err != nil {
+		return err
+	}
+	if err := normalizeStoryLocale(story); err != nil {
+		return err
+	}
+	if err := normalizeStoryAccess(story); err != nil {
+		return err
+	}
+	tags, blocks, err := marshalStoryJSON(story)
+	if err != nil {
+		return err
+	}
+	return r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		if story.Slug == "" {
+			slug, err := tx.freeSlug(ctx, Slugify(story.Title))
+			if err != nil {
+				return err
+			}
+			story.Slug = slug
+		}
+		_, err := tx.q.ExecContext(ctx, `INSERT INTO stories (`+storyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)`,
+			story.ID, story.Slug, story.Title, story.Subtitle, story.Summary, story.Body, story.Status, story.Section,
+			tags, story.CoverImage, story.IsMember, story.PublishedAt, story.CreatedAt, story.UpdatedAt, blocks, story.WordCount, story.ReadingTime, story.Excerpt,
+			story.Locale, story.TranslationGroup, story.Access, story.OwnerID)
+		if err != nil {
+			return storyWriteError("create story", err)
+		}
+		if err := tx.moveSlugRedirect(ctx, "", story); err != nil {
+			return err
+		}
+		if err := tx.setStoryAuthors(ctx, story.ID, story.AuthorIDs); err != nil {
+			return err
+		}
+		return tx.addRevision(ctx, story)
+	})
+}
+
+func (r *PostgresStoryRepository) Update(ctx context.Context, story *Story) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if story.ID == "" {
+		return ErrStoryNotFound
+	}
+	prepareStory(story, time.Now().UTC())
+	if err := ValidateBlocks(story.Blocks); err != nil {
+		return err
+	}
+	if err := normalizeStoryLocale(story); err != nil {
+		return err
+	}
+	if err := normalizeStoryAccess(story); err != nil {
+		return err
+	}
+	tags, blocks, err := marshalStoryJSON(story)
+	if err != nil {
+		return err
+	}
+	return r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		// 鎖定這一列後檢查狀態轉換，避免同時寫入的狀態互相覆蓋
+		var current, currentSlug string
+		err := tx.q.QueryRowContext(ctx, `SELECT status, slug FROM stories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, story.ID).Scan(&current, &currentSlug)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStoryNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock story: %w", err)
+		}
+		if err := CheckStoryTransition(current, story.Status); err != nil {
+			return err
+		}
+		// 沒有指定 slug 時沿用目前的 slug，不因標題修改而改變網址
+		if story.Slug == "" {
+			story.Slug = currentSlug
+		}
+
+		// created_at 與 owner_id 不更新，回傳資料庫中的值
+		err = tx.q.QueryRowContext(ctx, `UPDATE stories SET slug = $2, title = $3, subtitle = $4, summary = $5, body = $6, status = $7, section = $8, tags = $9, cover_image = $10, is_member = $11, published_at = $12, updated_at = $13, blocks = $14, word_count = $15, reading_time = $16, excerpt = $17, locale = $18, translation_group = $19, access = $20 WHERE id = $1 RETURNING created_at, owner_id`,
+			story.ID, story.Slug, story.Title, story.Subtitle, story.Summary, story.Body, story.Status, story.Section,
+			tags, story.CoverImage, story.IsMember, story.PublishedAt, story.UpdatedAt, blocks, story.WordCount, story.ReadingTime, story.Excerpt,
+			story.Locale, story.TranslationGroup, story.Access).Scan(&story.CreatedAt, &story.OwnerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStoryNotFound
+		}
+		if err != nil {
+			return storyWriteError("update story", err)
+		}
+		if err := tx.moveSlugRedirect(ctx, currentSlug, story); err != nil {
+			return err
+		}
+		if err := tx.setStoryAuthors(ctx, story.ID, story.AuthorIDs); err != nil {
+			return err
+		}
+		return tx.addRevision(ctx, story)
+	})
+}
+
+// freeSlug 回傳以 base 為基礎、未被 story 或轉址使用的 slug
+func (r *PostgresStoryRepository) freeSlug(ctx context.Context, base string) (string, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT slug FROM stories WHERE slug = $1 OR slug LIKE $2
+		UNION SELECT slug FROM story_slug_redirects WHERE slug = $1 OR slug LIKE $2`, base, escapeLike(base)+"-%")
+	if err != nil {
+		return "", fmt.Errorf("find free slug: %w", err)
+	}
+	defer rows.Close()
+	var taken []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", fmt.Errorf("find free slug: %w", err)
+		}
+		taken = append(taken, slug)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("find free slug: %w", err)
+	}
+	return freeSlug(base, taken), nil
+}
+
+// moveSlugRedirect 在 slug 改變時將舊 slug 轉址到 story，並移除指向新 slug 的轉址 (該 slug 已屬於這篇 story)
+func (r *PostgresStoryRepository) moveSlugRedirect(ctx context.Context, oldSlug string, story *Story) error {
+	if oldSlug == story.Slug {
+		return nil
+	}
+	if _, err := r.q.ExecContext(ctx, `DELETE FROM story_slug_redirects WHERE slug = $1`, story.Slug); err != nil {
+		return fmt.Errorf("delete slug redirect: %w", err)
+	}
+	if oldSlug == "" {
+		return nil
+	}
+	_, err := r.q.ExecContext(ctx, `INSERT INTO story_slug_redirects (slug, story_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (slug) DO UPDATE SET story_id = EXCLUDED.story_id, created_at = EXCLUDED.created_at`, oldSlug, story.ID, story.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("add slug redirect: %w", err)
+	}
+	return nil
+}
+
+// marshalStoryJSON 將 story 的 tags 與 blocks 轉為 JSONB 欄位的值；沒有 block 時寫入空陣列
+func marshalStoryJSON(story *Story) (tags, blocks string, err error) {
+	b, err := json.Marshal(story.Tags)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal tags: %w", err)
+	}
+	tags = string(b)
+	if len(story.Blocks) == 0 {
+		return tags, "[]", nil
+	}
+	if b, err = json.Marshal(story.Blocks); err != nil {
+		return "", "", fmt.Errorf("marshal blocks: %w", err)
+	}
+	return tags, string(b), nil
+}
+
+// addRevision 在同一個 transaction 中記錄 story 寫入後的版本；Update 已鎖定該列，版本號不會重複
+func (r *PostgresStoryRepository) addRevision(ctx context.Context, story *Story) error {
+	snapshot, err := json.Marshal(revisionSnapshot(story))
+	if err != nil {
+		return fmt.Errorf("marshal revision: %w", err)
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO story_revisions (story_id, number, snapshot, created_at)
+		SELECT $1, COALESCE(MAX(number), 0) + 1, $2, $3 FROM story_revisions WHERE story_id = $1`,
+		story.ID, string(snapshot), story.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("add story revision: %w", err)
+	}
+	return nil
+}
+
+// setStoryAuthors 以 authorIDs 取代 story 的作者，position 依 authorIDs 的順序
+func (r *PostgresStoryRepository) setStoryAuthors(ctx context.Context, storyID string, authorIDs []string) error {
+	if _, err := r.q.ExecContext(ctx, `DELETE FROM story_authors WHERE story_id = $1`, storyID); err != nil {
+		return fmt.Errorf("clear story authors: %w", err)
+	}
+	for i, authorID := range authorIDs {
+		_, err := r.q.ExecContext(ctx, `INSERT INTO story_authors (story_id, author_id, position) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			storyID, authorID, i)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("%w: %s", ErrAuthorNotFound, authorID)
+		}
+		if err != nil {
+			return fmt.Errorf("set story authors: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete 將 story 移至垃圾桶，updated_at 不變
+func (r *PostgresStoryRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.q.ExecContext(ctx, `UPDATE stories SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`, id, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("delete story: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrStoryNotFound
+	}
+	return nil
+}
+
+func (r *PostgresStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := r.q.QueryContext(ctx, `SELECT `+storySelectColumns+` FROM stories WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC LIMIT $1 OFFSET $2`,
+		limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list trashed stories: %w", err)
+	}
+	defer rows.Close()
+
+	stories := []Story{}
+	for rows.Next() {
+		story, err := scanStory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan story: %w", err)
+		}
+		stories = append(stories, *story)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list trashed stories: %w", err)
+	}
+	return stories, nil
+}
+
+func (r *PostgresStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := r.q.QueryRowContext(ctx, `UPDATE stories SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING `+storySelectColumns, id)
+	story, err := scanStory(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore story: %w", err)
+	}
+	return story, nil
+}
+
+// PurgeStory 永久刪除垃圾桶中的 story；版本紀錄與作者關聯由 ON DELETE CASCADE 一併刪除
+func (r *PostgresStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.q.ExecContext(ctx, `DELETE FROM stories WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge story: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrStoryNotFound
+	}
+	return nil
+}
+
+func (r *PostgresStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	res, err := r.q.ExecContext(ctx, `DELETE FROM stories WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+	return int(n), nil
+}
+
+func (r *PostgresStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	countsJSON, err := json.Marshal(counts)
+	if err != nil {
+		return fmt.Errorf("marshal view counts: %w", err)
+	}
+	// 只累加 view_count，不更新 updated_at
+	_, err = r.q.ExecContext(ctx, `UPDATE stories SET view_count = stories.view_count + v.n::bigint
+		FROM jsonb_each_text($1::jsonb) AS v(id, n) WHERE stories.id = v.id`, string(countsJSON))
+	if err != nil {
+		return fmt.Errorf("add view counts: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	// 不更新 updated_at，也不記錄版本；editor 已填寫 summary 時不覆寫
+	if _, err := r.q.ExecContext(ctx, `UPDATE stories SET excerpt = $2 WHERE id = $1 AND summary = ''`, id, excerpt); err != nil {
+		return fmt.Errorf("set excerpt: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	return r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		return fn(tx)
+	})
+}
+
+// inTx 在 transaction 中執行 fn；已在 transaction 中時直接沿用
+func (r *PostgresStoryRepository) inTx(ctx context.Context, fn func(tx *PostgresStoryRepository) error) error {
+	if _, ok := r.q.(*sql.Tx); ok {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	if err := fn(&PostgresStoryRepository{db: r.db, q: tx}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// 列表不回傳 body 與 blocks，需要時以 GetRevision 讀取單一版本
+	rows, err := r.q.QueryContext(ctx, `SELECT story_id, number, snapshot - 'body' - 'blocks', created_at FROM story_revisions WHERE story_id = $1 ORDER BY number DESC`, storyID)
+	if err != nil {
+		return nil, fmt.Errorf("list story revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []StoryRevision{}
+	for rows.Next() {
+		revision, err := scanStoryRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *PostgresStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	revision, err := scanStoryRevision(r.q.QueryRowContext(ctx, `SELECT story_id, number, snapshot, created_at FROM story_revisions WHERE story_id = $1 AND number = $2`, storyID, number))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, err
+}
+
+func (r *PostgresStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	return r.getAuthor(ctx, "id", id)
+}
+
+func (r *PostgresStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	return r.getAuthor(ctx, "slug", slug)
+}
+
+// getAuthor 依單一欄位查詢一位作者
+func (r *PostgresStoryRepository) getAuthor(ctx context.Context, column, value string) (*Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := r.q.QueryRowContext(ctx, `SELECT `+authorColumns+` FROM authors WHERE `+column+` = $1`, value)
+	author, err := scanAuthor(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAuthorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get author by %s: %w", column, err)
+	}
+	return author, nil
+}
+
+func (r *PostgresStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	if len(ids) == 0 {
+		return []Author{}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("marshal author ids: %w", err)
+	}
+	rows, err := r.q.QueryContext(ctx, `SELECT `+authorColumns+` FROM authors WHERE id IN (SELECT jsonb_array_elements_text($1::jsonb))`, string(idsJSON))
+	if err != nil {
+		return nil, fmt.Errorf("get authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := []Author{}
+	for rows.Next() {
+		author, err := scanAuthor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan author: %w", err)
+		}
+		authors = append(authors, *author)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get authors: %w", err)
+	}
+	return orderAuthors(ids, authors), nil
+}
+
+func (r *PostgresStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.q.QueryContext(ctx, `SELECT `+authorColumns+` FROM authors ORDER BY name, id LIMIT $1 OFFSET $2`, authorLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("list authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := []Author{}
+	for rows.Next() {
+		author, err := scanAuthor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan author: %w", err)
+		}
+		authors = append(authors, *author)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list authors: %w", err)
+	}
+	return authors, nil
+}
+
+func (r *PostgresStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := prepareAuthor(author, time.Now().UTC()); err != nil {
+		return err
+	}
+	links, err := json.Marshal(author.SocialLinks)
+	if err != nil {
+		return fmt.Errorf("marshal social links: %w", err)
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO authors (`+authorColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		author.ID, author.Slug, author.Name, author.Bio, author.Avatar, string(links), author.CreatedAt, author.UpdatedAt)
+	return authorWriteError("create author", err)
+}
+
+func (r *PostgresStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if author.ID == "" {
+		return ErrAuthorNotFound
+	}
+	if err := prepareAuthor(author, time.Now().UTC()); err != nil {
+		return err
+	}
+	links, err := json.Marshal(author.SocialLinks)
+	if err != nil {
+		return fmt.Errorf("marshal social links: %w", err)
+	}
+	// created_at 不更新，回傳資料庫中的值
+	err = r.q.QueryRowContext(ctx, `UPDATE authors SET slug = $2, name = $3, bio = $4, avatar = $5, social_links = $6, updated_at = $7 WHERE id = $1 RETURNING created_at`,
+		author.ID, author.Slug, author.Name, author.Bio, author.Avatar, string(links), author.UpdatedAt).Scan(&author.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAuthorNotFound
+	}
+	return authorWriteError("update author", err)
+}
+
+// DeleteAuthor 只刪除沒有任何 story (含垃圾桶) 署名的作者，避免 story 的署名在不知情下消失
+func (r *PostgresStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.q.ExecContext(ctx, `DELETE FROM authors WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM story_authors WHERE author_id = $1)`, id)
+	if err != nil {
+		return fmt.Errorf("delete author: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if _, err := r.getAuthor(ctx, "id", id); err != nil {
+		return err
+	}
+	return ErrAuthorHasStories
+}
+
+// rowScanner 為 *sql.Row 與 *sql.Rows 共同的 Scan
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// prefixedRow 將查詢最前面的額外欄位讀入 extra，其餘欄位交給原本的 scan 函式
+type prefixedRow struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (p prefixedRow) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(slices.Clip(p.extra), dest...)...)
+}
+
+// scanStory 依 storySelectColumns 的順序讀取一筆 story
+func scanStory(row rowScanner) (*Story, error) {
+	var (
+		story       Story
+		tags        []byte
+		blocks      []byte
+		authorIDs   []byte
+		publishedAt sql.NullTime
+		deletedAt   sql.NullTime
+	)
+	if err := row.Scan(&story.ID, &story.Slug, &story.Title, &story.Subtitle, &story.Summary, &story.Body,
+		&story.Status, &story.Section, &tags, &story.CoverImage, &story.IsMember, &publishedAt,
+		&story.CreatedAt, &story.UpdatedAt, &blocks, &story.WordCount, &story.ReadingTime, &story.Excerpt,
+		&story.Locale, &story.TranslationGroup, &story.Access, &story.OwnerID, &story.ViewCount, &deletedAt, &authorIDs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tags, &story.Tags); err != nil {
+		return nil, fmt.Errorf("decode tags: %w", err)
+	}
+	if err := json.Unmarshal(blocks, &story.Blocks); err != nil {
+		return nil, fmt.Errorf("decode blocks: %w", err)
+	}
+	if err := json.Unmarshal(authorIDs, &story.AuthorIDs); err != nil {
+		return nil, fmt.Errorf("decode author ids: %w", err)
+	}
+	if publishedAt.Valid {
+		story.PublishedAt = &publishedAt.Time
+	}
+	if deletedAt.Valid {
+		story.DeletedAt = &deletedAt.Time
+	}
+	fillComputedFields(&story)
+	return &story, nil
+}
+
+// scanStoryRevision 讀取一筆版本紀錄，欄位依序為 story_id、number、snapshot、created_at
+func scanStoryRevision(row rowScanner) (*StoryRevision, error) {
+	var (
+		revision StoryRevision
+		snapshot []byte
+	)
+	if err := row.Scan(&revision.StoryID, &revision.Number, &snapshot, &revision.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan story revision: %w", err)
+	}
+	if err := json.Unmarshal(snapshot, &revision.Story); err != nil {
+		return nil, fmt.Errorf("decode story revision: %w", err)
+	}
+	return &revision, nil
+}
+
+// scanAuthor 依 authorColumns 的順序讀取一位作者
+func scanAuthor(row rowScanner) (*Author, error) {
+	var author Author
+	var links []byte
+	if err := row.Scan(&author.ID, &author.Slug, &author.Name, &author.Bio, &author.Avatar, &links, &author.CreatedAt, &author.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(links, &author.SocialLinks); err != nil {
+		return nil, fmt.Errorf("decode social links: %w", err)
+	}
+	return &author, nil
+}
+
+// storyWriteError 將 slug 重複轉為 ErrStorySlugTaken
+func storyWriteError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return ErrStorySlugTaken
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// authorWriteError 將 slug 重複轉為 ErrAuthorSlugTaken；err 為 nil 時回傳 nil
+func authorWriteError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return ErrAuthorSlugTaken
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// escapeLike 跳脫 LIKE pattern 中的特殊字元
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// storySortColumn 回傳排序欄位對應的 SQL；未發布的 published_at 視為最早，降冪時排在最後
+func storySortColumn(field string) string {
+	switch field {
+	case StorySortPublishedAt:
+		return `COALESCE(published_at, '-infinity'::timestamptz)`
+	case StorySortUpdatedAt:
+		return `updated_at`
+	case StorySortPopularity:
+		return `view_count`
+	case "createdAt":
+		return `created_at`
+	}
+	return `id`
+}
+
+// storyKeysetSQL 組出「排在 cursor 之後」的條件：前面的排序欄位相等且目前欄位依方向較小 (或較大)
+func storyKeysetSQL(keys []storySortKey, values []interface{}, bind func(interface{}) string) string {
+	placeholders := make([]string, len(keys))
+	for i, key := range keys {
+		placeholders[i] = bind(values[i])
+		if key.Field == StorySortPublishedAt {
+			placeholders[i] = `COALESCE(` + placeholders[i] + `::timestamptz, '-infinity'::timestamptz)`
+		}
+	}
+
+	ors := make([]string, len(keys))
+	for i, key := range keys {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, storySortColumn(keys[j].Field)+` = `+placeholders[j])
+		}
+		op := ` > `
+		if key.Desc {
+			op = ` < `
+		}
+		ands = append(ands, storySortColumn(key.Field)+op+placeholders[i])
+		ors[i] = `(` + strings.Join(ands, ` AND `) + `)`
+	}
+	return `(` + strings.Join(ors, ` OR `) + `)`
+}
+
+// storyStringMatch 為 StringFilter 在某個欄位上的比對方式；%s 為 bind 產生的 placeholder，
+// in 的參數為 JSON 字串陣列
+type storyStringMatch struct {
+	equals string
+	in     string
+}
+
+var (
+	storySectionMatch = storyStringMatch{`section = %s`, `section IN (SELECT jsonb_array_elements_text(%s::jsonb))`}
+	storyStatusMatch  = storyStringMatch{`status = %s`, `status IN (SELECT jsonb_array_elements_text(%s::jsonb))`}
+	storyLocaleMatch  = storyStringMatch{`locale = %s`, `locale IN (SELECT jsonb_array_elements_text(%s::jsonb))`}
+	storyTagMatch     = storyStringMatch{`tags ? %s`, `tags ?| ARRAY(SELECT jsonb_array_elements_text(%s::jsonb))`}
+	storyAuthorMatch  = storyStringMatch{
+		`EXISTS (SELECT 1 FROM story_authors sa WHERE sa.story_id = stories.id AND sa.author_id = %s)`,
+		`EXISTS (SELECT 1 FROM story_authors sa WHERE sa.story_id = stories.id AND sa.author_id IN (SELECT jsonb_array_elements_text(%s::jsonb)))`,
+	}
+)
+
+// storyStringFilterSQL 將 StringFilter 轉為條件；沒有任何條件時回傳空字串
+func storyStringFilterSQL(f *StringFilter, match storyStringMatch, bind func(interface{}) string) string {
+	if f == nil {
+		return ""
+	}
+	conds := []string{}
+	if f.Equals != nil {
+		conds = append(conds, fmt.Sprintf(match.equals, bind(*f.Equals)))
+	}
+	if len(f.In) > 0 {
+		in, _ := json.Marshal(f.In)
+		conds = append(conds, fmt.Sprintf(match.in, bind(string(in))))
+	}
+	if not := storyStringFilterSQL(f.Not, match, bind); not != "" {
+		conds = append(conds, `NOT (`+not+`)`)
+	}
+	return strings.Join(conds, ` AND `)
+}
+
+// storyWhereSQL 將 StoryWhereInput 轉為條件
+func storyWhereSQL(where *StoryWhereInput, bind func(interface{}) string) ([]string, error) {
+	conds := []string{}
+	for _, cond := range []string{
+		storyStringFilterSQL(where.Section, storySectionMatch, bind),
+		storyStringFilterSQL(where.Tag, storyTagMatch, bind),
+		storyStringFilterSQL(where.Author, storyAuthorMatch, bind),
+		storyStringFilterSQL(where.Status, storyStatusMatch, bind),
+		storyStringFilterSQL(where.Locale, storyLocaleMatch, bind),
+	} {
+		if cond != "" {
+			conds = append(conds, cond)
+		}
+	}
+	if where.PublishedAt != nil {
+		gte, lt, err := where.PublishedAt.bounds()
+		if err != nil {
+			return nil, err
+		}
+		if gte != nil {
+			conds = append(conds, `published_at >= `+bind(*gte))
+		}
+		if lt != nil {
+			conds = append(conds, `published_at < `+bind(*lt))
+		}
+	}
+	return conds, nil
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidStoryQuery is returned (wrapped) when StoryListOptions.Where or
+// OrderBy cannot be applied, e.g. an unknown sort field or a malformed date.
+var ErrInvalidStoryQuery = errors.New("invalid story query")
+
+// StoryWhereInput is the structured filter of a story listing. All set
+// filters must match. Section plays the role of the category.
+type StoryWhereInput struct {
+	Section     *StringFilter        `mapstructure:"section"`
+	Tag         *StringFilter        `mapstructure:"tag"`    // equals: 含有此 tag；in: 含有任一 tag
+	Author      *StringFilter        `mapstructure:"author"` // author ID，比對方式同 tag
+	Status      *StringFilter        `mapstructure:"status"`
+	Locale      *StringFilter        `mapstructure:"locale"` // 空字串比對沒有語言的 story
+	PublishedAt *DateTimeRangeFilter `mapstructure:"publishedAt"`
+}
+
+// DateTimeRangeFilter matches times in [Gte, Lt). Bounds are RFC 3339
+// strings; unpublished stories never match a publishedAt range.
+type DateTimeRangeFilter struct {
+	Gte *string `mapstructure:"gte"`
+	Lt  *string `mapstructure:"lt"`
+}
+
+// DecodeStoryWhere decodes a GraphQL / JSON filter object into a
+// StoryWhereInput.
+func DecodeStoryWhere(input interface{}) (*StoryWhereInput, error) {
+	if input == nil {
+		return nil, nil
+	}
+	var where StoryWhereInput
+	if err := decodeInto(input, &where); err != nil {
+		return nil, fmt.Errorf("%w: story where: %v", ErrInvalidStoryQuery, err)
+	}
+	return &where, nil
+}
+
+// bounds 解析時間範圍的上下限
+func (f *DateTimeRangeFilter) bounds() (gte, lt *time.Time, err error) {
+	parse := func(name string, s *string) (*time.Time, error) {
+		if s == nil {
+			return nil, nil
+		}
+		t, err := time.Parse(time.RFC3339, *s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidStoryQuery, name, err)
+		}
+		return &t, nil
+	}
+	if gte, err = parse("gte", f.Gte); err != nil {
+		return nil, nil, err
+	}
+	lt, err = parse("lt", f.Lt)
+	return gte, lt, err
+}
+
+// Story sort fields for StoryListOptions.OrderBy; directions are "asc" and
+// "desc".
+const (
+	StorySortPublishedAt = "publishedAt"
+	StorySortUpdatedAt   = "updatedAt"
+	StorySortPopularity  = "popularity"
+)
+
+// storySortKey 為一個排序欄位；依序比較所有 key 即為列表的順序
+type storySortKey struct {
+	Field string
+	Desc  bool
+}
+
+// defaultStorySort 為未指定 OrderBy 時的順序：最新發布的在前，未發布的在最後
+var defaultStorySort = []storySortKey{{StorySortPublishedAt, true}, {"createdAt", true}, {"id", true}}
+
+// sortKeys 回傳 opts 的排序欄位，最後一定以 id 排序讓順序穩定
+func (o StoryListOptions) sortKeys() ([]storySortKey, error) {
+	if len(o.OrderBy) == 0 {
+		return defaultStorySort, nil
+	}
+	keys := make([]storySortKey, 0, len(o.OrderBy)+1)
+	seen := map[string]bool{}
+	for _, rule := range o.OrderBy {
+		switch rule.Field {
+		case StorySortPublishedAt, StorySortUpdatedAt, StorySortPopularity:
+		default:
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidStoryQuery, rule.Field)
+		}
+		if seen[rule.Field] {
+			continue
+		}
+		seen[rule.Field] = true
+		switch strings.ToLower(rule.Direction) {
+		case "asc":
+			keys = append(keys, storySortKey{rule.Field, false})
+		case "", "desc":
+			keys = append(keys, storySortKey{rule.Field, true})
+		default:
+			return nil, fmt.Errorf("%w: unknown sort direction %q", ErrInvalidStoryQuery, rule.Direction)
+		}
+	}
+	return append(keys, storySortKey{"id", true}), nil
+}
+
+// sortSignature 為排序欄位的字串表示，記錄在 cursor 中以確認 cursor 與目前的排序相符
+func sortSignature(keys []storySortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// sortValue 回傳 story 在排序欄位上的值；未發布的 publishedAt 為 nil
+func sortValue(story Story, field string) interface{} {
+	switch field {
+	case StorySortPublishedAt:
+		if story.PublishedAt == nil {
+			return nil
+		}
+		return *story.PublishedAt
+	case StorySortUpdatedAt:
+		return story.UpdatedAt
+	case StorySortPopularity:
+		return story.ViewCount
+	case "createdAt":
+		return story.CreatedAt
+	}
+	return story.ID
+}
+
+// storyCursor 為 cursor 編碼的內容：排序方式與最後一篇在各排序欄位上的值
+type storyCursor struct {
+	Order  string            `json:"o"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// StoryCursor returns the opaque cursor pointing right after story in the
+// order of opts, for StoryListOptions.After. It returns "" when opts has an
+// invalid OrderBy.
+func StoryCursor(story Story, opts StoryListOptions) string {
+	keys, err := opts.sortKeys()
+	if err != nil {
+		return ""
+	}
+	c := storyCursor{Order: sortSignature(keys), Values: make([]json.RawMessage, len(keys))}
+	for i, key := range keys {
+		c.Values[i], _ = json.Marshal(sortValue(story, key.Field))
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeStoryCursor 解析 cursor 並依 keys 的型別還原各欄位的值 (time.Time、*time.Time、int64 或 string)
+func decodeStoryCursor(cursor string, keys []storySortKey) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c storyCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Order != sortSignature(keys) || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		var err error
+		switch key.Field {
+		case StorySortPublishedAt:
+			var t *time.Time
+			err = json.Unmarshal(c.Values[i], &t)
+			values[i] = t
+		case StorySortUpdatedAt, "createdAt":
+			var t time.Time
+			err = json.Unmarshal(c.Values[i], &t)
+			values[i] = t
+		case StorySortPopularity:
+			var n int64
+			err = json.Unmarshal(c.Values[i], &n)
+			values[i] = n
+		default:
+			var s string
+			err = json.Unmarshal(c.Values[i], &s)
+			values[i] = s
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// 相關文章的評分權重：tag 重疊 (Jaccard 係數) 為主，同 section、發布時間接近與共讀次數為輔
+const (
+	relatedTagWeight     = 1.0
+	relatedSectionWeight = 0.3
+	relatedRecencyWeight = 0.4
+	relatedCoReadWeight  = 0.8
+)
+
+// relatedRecencyHalfLife 為發布時間差距的半衰期：相差一個半衰期的 story，時間分數減半
+const relatedRecencyHalfLife = 7 * 24 * time.Hour
+
+// relatedCandidateLimit 為每種來源 (相同 tag、相同 section、共讀) 最多取用的候選數
+const relatedCandidateLimit = 50
+
+// defaultRelatedLimit 與 maxRelatedLimit 為 Related 筆數的預設值與上限
+const (
+	defaultRelatedLimit = 5
+	maxRelatedLimit     = 20
+)
+
+// RelatedStory is a story recommended next to another one.
+type RelatedStory struct {
+	Story Story   `json:"story"`
+	Score float64 `json:"score"`
+}
+
+// RelatedService recommends published stories related to a story. Candidates
+// share a tag or the section with it, or were read by the same visitors;
+// they are scored by tag overlap, same section, how close their publish
+// times are and how often they were read together. Results are cached per
+// story under the story cache prefix, so story writes purge them.
+type RelatedService struct {
+	stories *StoryService
+	cache   *Cache
+}
+
+// NewRelatedService returns a service recommending among stories. Co-read
+// signals are kept in cache; without Redis only content signals are used.
+func NewRelatedService(stories *StoryService, cache *Cache) *RelatedService {
+	return &RelatedService{stories: stories, cache: cache}
+}
+
+// Related returns up to limit (default 5, at most 20) published stories
+// related to the story with id, or with slug when id is empty, best first.
+func (s *RelatedService) Related(ctx context.Context, id, slug string, limit int) ([]RelatedStory, error) {
+	story, err := s.stories.Story(ctx, id, slug)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case limit <= 0:
+		limit = defaultRelatedLimit
+	case limit > maxRelatedLimit:
+		limit = maxRelatedLimit
+	}
+
+	key := NewCacheKey(storyCachePrefix + "related").Fields(map[string]interface{}{"id": story.ID, "limit": limit}).ShortHash().String()
+	return NewTypedCache[[]RelatedStory](s.cache).GetOrSet(ctx, key, 0, func(ctx context.Context) ([]RelatedStory, error) {
+		return s.compute(ctx, story, limit)
+	})
+}
+
+// RecordRead notes that visitor (an opaque, stable ID from the client) read
+// the published story with slug, so it counts as co-read with the other
+// stories the visitor read recently.
+func (s *RelatedService) RecordRead(ctx context.Context, visitor, slug string) error {
+	story, err := s.stories.Story(ctx, "", slug)
+	if err != nil {
+		return err
+	}
+	return s.cache.RecordCoRead(ctx, visitor, story.ID)
+}
+
+// compute 收集候選 story 並依評分排序
+func (s *RelatedService) compute(ctx context.Context, story *Story, limit int) ([]RelatedStory, error) {
+	candidates := map[string]Story{}
+	add := func(stories []Story) {
+		for _, candidate := range stories {
+			if candidate.ID != story.ID {
+				candidates[candidate.ID] = candidate
+			}
+		}
+	}
+
+	if len(story.Tags) > 0 {
+		stories, err := s.stories.Stories(ctx, "", StoryListOptions{
+			Where: &StoryWhereInput{Tag: &StringFilter{In: story.Tags}},
+			Limit: relatedCandidateLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		add(stories)
+	}
+	if story.Section != "" {
+		stories, err := s.stories.Stories(ctx, "", StoryListOptions{Section: story.Section, Limit: relatedCandidateLimit})
+		if err != nil {
+			return nil, err
+		}
+		add(stories)
+	}
+
+	// 共讀分數依排名遞減：最常一起閱讀的為 1
+	coReads := map[string]float64{}
+	ids, err := s.cache.TopCoReads(ctx, story.ID, relatedCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		coReads[id] = 1 - float64(i)/float64(len(ids))
+		if _, ok := candidates[id]; ok || id == story.ID {
+			continue
+		}
+		coRead, err := s.stories.Story(ctx, id, "")
+		if errors.Is(err, ErrStoryNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		candidates[id] = *coRead
+	}
+
+	related := make([]RelatedStory, 0, len(candidates))
+	for _, candidate := range candidates {
+		related = append(related, RelatedStory{Story: candidate, Score: relatedScore(story, &candidate, coReads[candidate.ID])})
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].Story.ID > related[j].Story.ID
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+// relatedScore 計算 candidate 與 story 的相關分數
+func relatedScore(story, candidate *Story, coRead float64) float64 {
+	score := relatedTagWeight*tagOverlap(story.Tags, candidate.Tags) + relatedCoReadWeight*coRead
+	if story.Section != "" && candidate.Section == story.Section {
+		score += relatedSectionWeight
+	}
+	if story.PublishedAt != nil && candidate.PublishedAt != nil {
+		gap := story.PublishedAt.Sub(*candidate.PublishedAt).Abs()
+		score += relatedRecencyWeight * math.Pow(0.5, float64(gap)/float64(relatedRecencyHalfLife))
+	}
+	return score
+}
+
+// tagOverlap 回傳兩組 tag 的 Jaccard 係數 (交集 / 聯集)
+func tagOverlap(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, tag := range a {
+		set[tag] = true
+	}
+	shared, union := 0, len(set)
+	seen := map[string]bool{}
+	for _, tag := range b {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		if set[tag] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,218 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// 共用的 StoryRepository 整合測試：同一組測試在每個儲存層上執行，確認行為一致。
+// 需要可寫入的資料庫，未設定對應的環境變數時略過：
+//
+//	TEST_DATABASE_URL  Postgres 連線字串，測試前執行 migration
+//	TEST_MONGO_URL     MongoDB 連線字串，使用測試專用的 database，結束時刪除；transaction 需要 replica set
+
+// storyRepositories 回傳已設定的儲存層，依名稱執行 sub-test
+func storyRepositories(t *testing.T) map[string]func(t *testing.T) StoryRepository {
+	t.Helper()
+	repos := map[string]func(t *testing.T) StoryRepository{
+		StoryStorePostgres: func(t *testing.T) StoryRepository {
+			dsn := os.Getenv("TEST_DATABASE_URL")
+			if dsn == "" {
+				t.Skip("TEST_DATABASE_URL is not set")
+			}
+			db, err := NewDB(dsn)
+			if err != nil {
+				t.Fatalf("open postgres: %v", err)
+			}
+			t.Cleanup(func() { _ = db.Close() })
+			migrator, err := NewMigrator(db)
+			if err != nil {
+				t.Fatalf("load migrations: %v", err)
+			}
+			if _, err := migrator.Up(context.Background()); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			return NewPostgresStoryRepository(db)
+		},
+		StoryStoreMongo: func(t *testing.T) StoryRepository {
+			uri := os.Getenv("TEST_MONGO_URL")
+			if uri == "" {
+				t.Skip("TEST_MONGO_URL is not set")
+			}
+			repo, err := NewMongoStoryRepository(context.Background(), uri, "go-story-test-"+newUUID()[:8])
+			if err != nil {
+				t.Fatalf("open mongo: %v", err)
+			}
+			t.Cleanup(func() {
+				_ = repo.coll.Database().Drop(context.Background())
+				_ = repo.Close(context.Background())
+			})
+			return repo
+		},
+	}
+	return repos
+}
+
+// runStoryRepositoryTest 在每個儲存層上執行 test
+func runStoryRepositoryTest(t *testing.T, test func(t *testing.T, repo StoryRepository)) {
+	for name, open := range storyRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+// uniqueSlug 回傳本次測試專用的 slug，Postgres 的資料不會清除，重複執行時不會衝突
+func uniqueSlug(base string) string {
+	return base + "-" + newUUID()[:8]
+}
+
+func TestStoryRepositoryCreateAndGet(t *testing.T) {
+	runStoryRepositoryTest(t, func(t *testing.T, repo StoryRepository) {
+		ctx := context.Background()
+		story := &Story{Slug: uniqueSlug("create"), Title: "Create", Body: "<p>hello world</p>", Tags: []string{"go"}}
+		if err := repo.Create(ctx, story); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if story.ID == "" || story.CreatedAt.IsZero() || story.Status != StoryStatusDraft {
+			t.Fatalf("Create did not fill in defaults: %+v", story)
+		}
+
+		byID, err := repo.GetByID(ctx, story.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if byID.Slug != story.Slug || byID.Title != story.Title || byID.Body != story.Body {
+			t.Errorf("GetByID = %+v, want %+v", byID, story)
+		}
+		if len(byID.Tags) != 1 || byID.Tags[0] != "go" {
+			t.Errorf("GetByID tags = %v, want [go]", byID.Tags)
+		}
+
+		bySlug, err := repo.GetBySlug(ctx, story.Slug)
+		if err != nil {
+			t.Fatalf("GetBySlug: %v", err)
+		}
+		if bySlug.ID != story.ID {
+			t.Errorf("GetBySlug ID = %q, want %q", bySlug.ID, story.ID)
+		}
+
+		if _, err := repo.GetByID(ctx, newUUID()); !errors.Is(err, ErrStoryNotFound) {
+			t.Errorf("GetByID of an unknown id: err = %v, want ErrStoryNotFound", err)
+		}
+	})
+}
+
+func TestStoryRepositoryGeneratesUniqueSlugs(t *testing.T) {
+	runStoryRepositoryTest(t, func(t *testing.T, repo StoryRepository) {
+		ctx := context.Background()
+		title := uniqueSlug("Same Title")
+		first, second := &Story{Title: title}, &Story{Title: title}
+		if err := repo.Create(ctx, first); err != nil {
+			t.Fatalf("Create first: %v", err)
+		}
+		if err := repo.Create(ctx, second); err != nil {
+			t.Fatalf("Create second: %v", err)
+		}
+		if first.Slug == "" || second.Slug != first.Slug+"-2" {
+			t.Errorf("slugs = %q, %q; want a generated slug and the same with -2", first.Slug, second.Slug)
+		}
+	})
+}
+
+func TestStoryRepositoryUpdateRedirectsOldSlug(t *testing.T) {
+	runStoryRepositoryTest(t, func(t *testing.T, repo StoryRepository) {
+		ctx := context.Background()
+		story := &Story{Slug: uniqueSlug("before"), Title: "Before"}
+		if err := repo.Create(ctx, story); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		oldSlug := story.Slug
+		story.Slug, story.Title = uniqueSlug("after"), "After"
+		if err := repo.Update(ctx, story); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		got, err := repo.GetBySlug(ctx, oldSlug)
+		if err != nil {
+			t.Fatalf("GetBySlug of the old slug: %v", err)
+		}
+		if got.ID != story.ID || got.Slug != story.Slug || got.Title != "After" {
+			t.Errorf("GetBySlug(%q) = %+v, want the renamed story", oldSlug, got)
+		}
+	})
+}
+
+func TestStoryRepositoryListByStatus(t *testing.T) {
+	runStoryRepositoryTest(t, func(t *testing.T, repo StoryRepository) {
+		ctx := context.Background()
+		section := uniqueSlug("section")
+		publishedAt := time.Now().Add(-time.Hour).UTC()
+		published := &Story{Slug: uniqueSlug("published"), Title: "Published", Section: section, Status: StoryStatusPublished, PublishedAt: &publishedAt}
+		draft := &Story{Slug: uniqueSlug("draft"), Title: "Draft", Section: section}
+		for _, story := range []*Story{published, draft} {
+			if err := repo.Create(ctx, story); err != nil {
+				t.Fatalf("Create %s: %v", story.Slug, err)
+			}
+		}
+
+		list, err := repo.List(ctx, StoryListOptions{Status: StoryStatusPublished, Section: section})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(list) != 1 || list[0].ID != published.ID {
+			t.Errorf("List published = %v, want only %s", storyIDs(list), published.ID)
+		}
+	})
+}
+
+func TestStoryRepositoryDelete(t *testing.T) {
+	runStoryRepositoryTest(t, func(t *testing.T, repo StoryRepository) {
+		ctx := context.Background()
+		story := &Story{Slug: uniqueSlug("delete"), Title: "Delete"}
+		if err := repo.Create(ctx, story); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repo.Delete(ctx, story.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, story.ID); !errors.Is(err, ErrStoryNotFound) {
+			t.Errorf("GetByID after Delete: err = %v, want ErrStoryNotFound", err)
+		}
+		if err := repo.Delete(ctx, story.ID); !errors.Is(err, ErrStoryNotFound) {
+			t.Errorf("second Delete: err = %v, want ErrStoryNotFound", err)
+		}
+	})
+}
+
+func TestStoryRepositoryWithTxRollsBack(t *testing.T) {
+	runStoryRepositoryTest(t, func(t *testing.T, repo StoryRepository) {
+		ctx := context.Background()
+		story := &Story{Slug: uniqueSlug("rollback"), Title: "Rollback"}
+		errAbort := errors.New("abort")
+		err := repo.WithTx(ctx, func(tx StoryRepository) error {
+			if err := tx.Create(ctx, story); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithTx: err = %v, want the error returned by fn", err)
+		}
+		if _, err := repo.GetBySlug(ctx, story.Slug); !errors.Is(err, ErrStoryNotFound) {
+			t.Errorf("GetBySlug after rollback: err = %v, want ErrStoryNotFound", err)
+		}
+	})
+}
+
+// storyIDs 回傳 stories 的 ID，供錯誤訊息使用
+func storyIDs(stories []Story) []string {
+	ids := make([]string, len(stories))
+	for i, story := range stories {
+		ids[i] = story.ID
+	}
+	return ids
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRevisionNotFound is returned when no revision matches the lookup.
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrRevisionsUnsupported is returned when the story store does not keep
+	// revisions.
+	ErrRevisionsUnsupported = errors.New("story store does not support revisions")
+)
+
+// StoryRevision is the content of a story after one create or update.
+// Revisions are numbered from 1 per story and never change once written.
+type StoryRevision struct {
+	StoryID   string    `json:"storyId"`
+	Number    int       `json:"number"`
+	Story     Story     `json:"story"` // ViewCount 不記錄
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RevisionReader is implemented by story repositories that record a
+// StoryRevision on every Create and Update. Callers type-assert a
+// StoryRepository to it.
+type RevisionReader interface {
+	// ListRevisions returns the revisions of the story with id, newest
+	// first, with Story.Body and Story.Blocks left empty.
+	ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error)
+	// GetRevision returns revision number of the story, or
+	// ErrRevisionNotFound.
+	GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error)
+}
+
+// revisionSnapshot 回傳寫入版本紀錄的 story 副本；瀏覽數不屬於內容
+func revisionSnapshot(story *Story) Story {
+	snapshot := *story
+	snapshot.ViewCount = 0
+	snapshot.DeletedAt = nil
+	return snapshot
+}
+
+// StoryDiff is the difference between two revisions of a story: the
+// metadata fields that changed and the body blocks inserted or deleted.
+type StoryDiff struct {
+	StoryID string             `json:"storyId"`
+	From    int                `json:"from"`
+	To      int                `json:"to"`
+	Fields  []StoryFieldChange `json:"fields"`
+	Body    []StoryBlockChange `json:"body"`
+}
+
+// StoryFieldChange is a metadata field whose value differs between two
+// revisions. Field is the JSON name of the Story field.
+type StoryFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Body block operations of a StoryBlockChange.
+const (
+	StoryBlockInsert = "insert"
+	StoryBlockDelete = "delete"
+)
+
+// StoryBlockChange is a body block (a non-empty line of the body) deleted
+// from the older revision or inserted in the newer one. Index is the
+// block's position in the revision it belongs to, from 0.
+type StoryBlockChange struct {
+	Op    string `json:"op"`
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+// storyDiffFields 為比對的 metadata 欄位與其 JSON 名稱；body 另外依段落比對
+var storyDiffFields = []struct {
+	name  string
+	value func(s *Story) interface{}
+}{
+	{"slug", func(s *Story) interface{} { return s.Slug }},
+	{"title", func(s *Story) interface{} { return s.Title }},
+	{"subtitle", func(s *Story) interface{} { return s.Subtitle }},
+	{"summary", func(s *Story) interface{} { return s.Summary }},
+	{"status", func(s *Story) interface{} { return s.Status }},
+	{"section", func(s *Story) interface{} { return s.Section }},
+	{"locale", func(s *Story) interface{} { return s.Locale }},
+	{"translationGroup", func(s *Story) interface{} { return s.TranslationGroup }},
+	{"tags", func(s *Story) interface{} { return s.Tags }},
+	{"authorIds", func(s *Story) interface{} { return s.AuthorIDs }},
+	{"coverImage", func(s *Story) interface{} { return s.CoverImage }},
+	{"isMember", func(s *Story) interface{} { return s.IsMember }},
+	{"access", func(s *Story) interface{} { return s.AccessTier() }},
+	{"publishedAt", func(s *Story) interface{} {
+		if s.PublishedAt == nil {
+			return nil
+		}
+		return s.PublishedAt.UTC() // 資料庫讀回的時區可能不同
+	}},
+}
+
+// storyDiffMaxCells 為段落比對表的上限；超過時整段視為刪除後再新增
+const storyDiffMaxCells = 4 << 20
+
+// DiffStoryRevisions returns the changes from revision from to revision to.
+func DiffStoryRevisions(from, to *StoryRevision) *StoryDiff {
+	diff := &StoryDiff{StoryID: to.StoryID, From: from.Number, To: to.Number, Fields: []StoryFieldChange{}}
+	for _, field := range storyDiffFields {
+		a, b := field.value(&from.Story), field.value(&to.Story)
+		if !reflect.DeepEqual(a, b) {
+			diff.Fields = append(diff.Fields, StoryFieldChange{Field: field.name, From: a, To: b})
+		}
+	}
+	diff.Body = diffStoryBlocks(storyBodyBlocks(from.Story.Body), storyBodyBlocks(to.Story.Body))
+	return diff
+}
+
+// storyBodyBlocks 將 body 依行切成段落，略過空行
+func storyBodyBlocks(body string) []string {
+	var blocks []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			blocks = append(blocks, line)
+		}
+	}
+	return blocks
+}
+
+// diffStoryBlocks 以最長共同子序列比對兩組段落，回傳刪除與新增的段落
+func diffStoryBlocks(a, b []string) []StoryBlockChange {
+	changes := []StoryBlockChange{}
+	if len(a)*len(b) > storyDiffMaxCells {
+		for i, text := range a {
+			changes = append(changes, StoryBlockChange{Op: StoryBlockDelete, Index: i, Text: text})
+		}
+		for j, text := range b {
+			changes = append(changes, StoryBlockChange{Op: StoryBlockInsert, Index: j, Text: text})
+		}
+		return changes
+	}
+
+	// lcs[i][j] 為 a[i:] 與 b[j:] 的最長共同子序列長度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, StoryBlockChange{Op: StoryBlockDelete, Index: i, Text: a[i]})
+			i++
+		default:
+			changes = append(changes, StoryBlockChange{Op: StoryBlockInsert, Index: j, Text: b[j]})
+			j++
+		}
+	}
+	return changes
+}
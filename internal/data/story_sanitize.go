@@ -0,0 +1,323 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// SanitizingStoryRepository wraps a StoryRepository and cleans the body of
+// every created and updated story with an HTMLSanitizer before it is
+// stored, so the store, the search index and every later reader only see
+// allowed HTML. Stories with blocks are left as they are: their body is
+// rendered from the blocks by the store. It is only used for HTML bodies;
+// Markdown bodies are sanitized when rendered.
+type SanitizingStoryRepository struct {
+	repo      StoryRepository
+	sanitizer *HTMLSanitizer
+}
+
+// NewSanitizingStoryRepository wraps repo so story bodies are cleaned with
+// sanitizer before they are written.
+func NewSanitizingStoryRepository(repo StoryRepository, sanitizer *HTMLSanitizer) *SanitizingStoryRepository {
+	return &SanitizingStoryRepository{repo: repo, sanitizer: sanitizer}
+}
+
+func (r *SanitizingStoryRepository) GetByID(ctx context.Context, id string) (*Story, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) GetBySlug(ctx context.Context, slug string) (*Story, error) {
+	return r.repo.GetBySlug(ctx, slug)
+}
+
+func (r *SanitizingStoryRepository) GetMany(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	return GetStories(ctx, r.repo, keys)
+}
+
+func (r *SanitizingStoryRepository) List(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	return r.repo.List(ctx, opts)
+}
+
+func (r *SanitizingStoryRepository) Search(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	return r.repo.Search(ctx, query, opts)
+}
+
+func (r *SanitizingStoryRepository) Create(ctx context.Context, story *Story) error {
+	r.sanitize(story)
+	return r.repo.Create(ctx, story)
+}
+
+func (r *SanitizingStoryRepository) Update(ctx context.Context, story *Story) error {
+	r.sanitize(story)
+	return r.repo.Update(ctx, story)
+}
+
+func (r *SanitizingStoryRepository) Delete(ctx context.Context, id string) error {
+	return r.repo.Delete(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	return r.repo.WithTx(ctx, func(repo StoryRepository) error {
+		return fn(&sanitizingTx{StoryRepository: repo, sanitize: r.sanitize})
+	})
+}
+
+func (r *SanitizingStoryRepository) AddViewCounts(ctx context.Context, counts map[string]int64) error {
+	vw, ok := r.repo.(ViewCountWriter)
+	if !ok {
+		return ErrViewCountsUnsupported
+	}
+	return vw.AddViewCounts(ctx, counts)
+}
+
+func (r *SanitizingStoryRepository) SetExcerpt(ctx context.Context, id, excerpt string) error {
+	ew, ok := r.repo.(ExcerptWriter)
+	if !ok {
+		return ErrExcerptUnsupported
+	}
+	return ew.SetExcerpt(ctx, id, excerpt)
+}
+
+func (r *SanitizingStoryRepository) ListRevisions(ctx context.Context, storyID string) ([]StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.ListRevisions(ctx, storyID)
+}
+
+func (r *SanitizingStoryRepository) GetRevision(ctx context.Context, storyID string, number int) (*StoryRevision, error) {
+	rr, ok := r.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr.GetRevision(ctx, storyID, number)
+}
+
+func (r *SanitizingStoryRepository) ListTrash(ctx context.Context, limit, offset int) ([]Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st.ListTrash(ctx, limit, offset)
+}
+
+func (r *SanitizingStoryRepository) RestoreStory(ctx context.Context, id string) (*Story, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st.RestoreStory(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) PurgeStory(ctx context.Context, id string) error {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return ErrTrashUnsupported
+	}
+	return st.PurgeStory(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	st, ok := r.repo.(StoryTrash)
+	if !ok {
+		return 0, ErrTrashUnsupported
+	}
+	return st.PurgeTrash(ctx, before)
+}
+
+func (r *SanitizingStoryRepository) GetAuthorByID(ctx context.Context, id string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorByID(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorBySlug(ctx, slug)
+}
+
+func (r *SanitizingStoryRepository) GetAuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorsByIDs(ctx, ids)
+}
+
+func (r *SanitizingStoryRepository) ListAuthors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ar, ok := r.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.ListAuthors(ctx, limit, offset)
+}
+
+func (r *SanitizingStoryRepository) CreateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.CreateAuthor(ctx, author)
+}
+
+func (r *SanitizingStoryRepository) UpdateAuthor(ctx context.Context, author *Author) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.UpdateAuthor(ctx, author)
+}
+
+func (r *SanitizingStoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	aw, ok := r.repo.(AuthorWriter)
+	if !ok {
+		return ErrAuthorsUnsupported
+	}
+	return aw.DeleteAuthor(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.ListTerms(ctx, kind, limit, offset)
+}
+
+func (r *SanitizingStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.GetTerm(ctx, kind, slug)
+}
+
+func (r *SanitizingStoryRepository) GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return LoadTerms(ctx, tr, kind, slugs)
+}
+
+func (r *SanitizingStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	return tr.CreateTerm(ctx, term)
+}
+
+func (r *SanitizingStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.UpdateTerm(ctx, slug, term)
+}
+
+func (r *SanitizingStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr.MergeTerms(ctx, kind, from, into)
+}
+
+func (r *SanitizingStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	tr, ok := r.repo.(Taxonomy)
+	if !ok {
+		return ErrTaxonomyUnsupported
+	}
+	return tr.DeleteTerm(ctx, kind, slug)
+}
+
+func (r *SanitizingStoryRepository) GetCollectionByID(ctx context.Context, id string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionByID(ctx, id)
+}
+
+func (r *SanitizingStoryRepository) GetCollectionBySlug(ctx context.Context, slug string) (*Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.GetCollectionBySlug(ctx, slug)
+}
+
+func (r *SanitizingStoryRepository) ListCollections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.ListCollections(ctx, limit, offset)
+}
+
+func (r *SanitizingStoryRepository) CollectionsByStory(ctx context.Context, storyID string) ([]Collection, error) {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs.CollectionsByStory(ctx, storyID)
+}
+
+func (r *SanitizingStoryRepository) CreateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.CreateCollection(ctx, collection)
+}
+
+func (r *SanitizingStoryRepository) UpdateCollection(ctx context.Context, collection *Collection) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.UpdateCollection(ctx, collection)
+}
+
+func (r *SanitizingStoryRepository) DeleteCollection(ctx context.Context, id string) error {
+	cs, ok := r.repo.(CollectionStore)
+	if !ok {
+		return ErrCollectionsUnsupported
+	}
+	return cs.DeleteCollection(ctx, id)
+}
+
+// sanitize 過濾沒有 block 的 story 的 body
+func (r *SanitizingStoryRepository) sanitize(story *Story) {
+	if len(story.Blocks) == 0 {
+		story.Body = r.sanitizer.Sanitize(story.Body)
+	}
+}
+
+// sanitizingTx 為 transaction 中的 repository，寫入前同樣過濾 body
+type sanitizingTx struct {
+	StoryRepository
+	sanitize func(story *Story)
+}
+
+func (t *sanitizingTx) Create(ctx context.Context, story *Story) error {
+	t.sanitize(story)
+	return t.StoryRepository.Create(ctx, story)
+}
+
+func (t *sanitizingTx) Update(ctx context.Context, story *Story) error {
+	t.sanitize(story)
+	return t.StoryRepository.Update(ctx, story)
+}
+
+// WithTx 已在 transaction 中，直接執行 fn
+func (t *sanitizingTx) WithTx(ctx context.Context, fn func(repo StoryRepository) error) error {
+	return fn(t)
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// 排程發布時的鎖
+const (
+	storyScheduleLock    = "story-schedule"
+	storyScheduleLockTTL = time.Minute
+)
+
+// storyScheduleActor 為排程發布在稽核紀錄中的 actor
+const storyScheduleActor = "system:scheduler"
+
+// StoryScheduler publishes scheduled stories once their PublishedAt has
+// passed. It runs under a cache lock so only one instance publishes at a
+// time. Writes go through the repository it is given, so its decorators
+// index the story and report StoryEventPublished to webhooks and sitemaps;
+// the story cache (and with it the feeds) is purged after each batch.
+type StoryScheduler struct {
+	repo  StoryRepository
+	cache *Cache
+}
+
+// NewStoryScheduler returns a scheduler publishing the stories in repo.
+// repo should not be a CachedStoryRepository: the scheduler lists stories
+// with a moving time filter, which would only fill the cache.
+func NewStoryScheduler(repo StoryRepository, cache *Cache) *StoryScheduler {
+	return &StoryScheduler{repo: repo, cache: cache}
+}
+
+// PublishDue publishes every scheduled story whose PublishedAt is before
+// now and returns how many were published. It returns
+// ErrLockNotAcquired when another instance is publishing.
+func (s *StoryScheduler) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	lock, err := s.cache.Lock(ctx, storyScheduleLock, storyScheduleLockTTL)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = lock.Unlock(context.WithoutCancel(ctx)) }()
+
+	ctx = WithAuditActor(ctx, storyScheduleActor)
+	due := now.UTC().Format(time.RFC3339)
+	opts := StoryListOptions{
+		Status:  StoryStatusScheduled,
+		Where:   &StoryWhereInput{PublishedAt: &DateTimeRangeFilter{Lt: &due}},
+		OrderBy: []OrderRule{{Field: StorySortPublishedAt, Direction: "asc"}},
+		Limit:   maxStoryLimit,
+	}
+	published := 0
+	defer func() {
+		if published > 0 && s.cache != nil {
+			_, _ = s.cache.DeleteByPrefix(context.WithoutCancel(ctx), storyCachePrefix)
+		}
+	}()
+	for {
+		stories, err := s.repo.List(ctx, opts)
+		if err != nil {
+			return published, fmt.Errorf("list scheduled stories: %w", err)
+		}
+		for _, story := range stories {
+			ok, err := s.publish(ctx, story.ID, now)
+			if err != nil {
+				// 單篇失敗不影響其他 story，下次排程時重試
+				slog.Warn("failed to publish scheduled story", "id", story.ID, "error", err)
+				continue
+			}
+			if ok {
+				published++
+			}
+		}
+		if len(stories) < opts.Limit {
+			return published, nil
+		}
+		opts.After = StoryCursor(stories[len(stories)-1], opts)
+	}
+}
+
+// Run calls PublishDue every interval until ctx is done.
+func (s *StoryScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := s.PublishDue(ctx, time.Now())
+		switch {
+		case errors.Is(err, ErrLockNotAcquired):
+			slog.Debug("scheduled publishing skipped", "reason", err)
+		case err != nil:
+			slog.Warn("failed to publish scheduled stories", "error", err)
+		case n > 0:
+			slog.Info("published scheduled stories", "stories", n)
+		}
+	}
+}
+
+// publish 在 transaction 中重新讀取 story，仍為到期的排程時改為已發布；story 已被修改或刪除時略過
+func (s *StoryScheduler) publish(ctx context.Context, id string, now time.Time) (bool, error) {
+	published := false
+	err := s.repo.WithTx(ctx, func(repo StoryRepository) error {
+		published = false
+		story, err := repo.GetByID(ctx, id)
+		if errors.Is(err, ErrStoryNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if story.Status != StoryStatusScheduled || story.PublishedAt == nil || story.PublishedAt.After(now) {
+			return nil
+		}
+		story.Status = StoryStatusPublished
+		if err := repo.Update(ctx, story); err != nil {
+			return err
+		}
+		published = true
+		return nil
+	})
+	return published, err
+}
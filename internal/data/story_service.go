@@ -0,0 +1,426 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// StoryService is the public story read API shared by GraphQL, REST and
+// gRPC. It only exposes published stories: drafts are reported as
+// ErrStoryNotFound and listings are always filtered by status.
+type StoryService struct {
+	repo   StoryRepository
+	body   *BodyRenderer
+	embeds *OEmbedResolver
+	site   Site // site.Language 為網站的語言，沒有語言的 story 屬於此語言
+}
+
+// NewStoryService returns a service reading from repo (usually a
+// CachedStoryRepository) that renders bodies with body and fills in embed
+// blocks from embeds. A nil body serves bodies as stored; a nil embeds
+// leaves embeds unresolved. Stories without a locale are in the language
+// of site, whose URL and defaults also feed SocialMeta.
+func NewStoryService(repo StoryRepository, body *BodyRenderer, embeds *OEmbedResolver, site Site) *StoryService {
+	if normalized, err := NormalizeLocale(site.Language); err == nil {
+		site.Language = normalized
+	}
+	return &StoryService{repo: repo, body: body, embeds: embeds, site: site}
+}
+
+// Blocks returns the blocks of story with the resolved oEmbed payloads of
+// its embed blocks (see OEmbedResolver.Blocks).
+func (s *StoryService) Blocks(ctx context.Context, story *Story) []ContentBlock {
+	return s.embeds.Blocks(ctx, story.Blocks)
+}
+
+// BodyHTML returns the body of story rendered to HTML: its blocks (see
+// BodyRenderer.RenderBlocks), with resolved embeds, when it has any,
+// otherwise Body (see BodyRenderer). Headings carry the anchors of
+// TableOfContents.
+func (s *StoryService) BodyHTML(ctx context.Context, story *Story) (string, error) {
+	body, _, err := s.render(ctx, story)
+	return body, err
+}
+
+// TableOfContents returns the nested headings of the body of story, whose
+// IDs are anchors in BodyHTML (see AnchorHeadings).
+func (s *StoryService) TableOfContents(ctx context.Context, story *Story) ([]TOCEntry, error) {
+	_, toc, err := s.render(ctx, story)
+	return toc, err
+}
+
+// render 轉換 story 的 body，並為標題加上 anchor
+func (s *StoryService) render(ctx context.Context, story *Story) (string, []TOCEntry, error) {
+	var (
+		body string
+		err  error
+	)
+	if len(story.Blocks) > 0 {
+		body = s.body.RenderBlocks(s.Blocks(ctx, story))
+	} else if body, err = s.body.Render(ctx, story.Body); err != nil {
+		return "", nil, err
+	}
+	body, toc := AnchorHeadings(body)
+	return body, toc, nil
+}
+
+// Story returns the published story with id, or with slug when id is empty.
+// A slug the story had before it was renamed also finds it; compare
+// Story.Slug to tell the two apart.
+func (s *StoryService) Story(ctx context.Context, id, slug string) (*Story, error) {
+	var (
+		story *Story
+		err   error
+	)
+	switch {
+	case id != "":
+		story, err = s.repo.GetByID(ctx, id)
+	case slug != "":
+		story, err = s.repo.GetBySlug(ctx, slug)
+	default:
+		return nil, errors.New("story id or slug is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if story.Status != StoryStatusPublished {
+		return nil, ErrStoryNotFound
+	}
+	return story, nil
+}
+
+// StoriesByKey returns the published story of each key at the same index,
+// or nil when there is none. Keys are looked up together: one cache
+// round trip and one store query for the misses (see StoryBatchReader).
+// Slugs match like Story, old slugs included.
+func (s *StoryService) StoriesByKey(ctx context.Context, keys []StoryKey) ([]*Story, error) {
+	stories, err := GetStories(ctx, s.repo, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, story := range stories {
+		if story != nil && story.Status != StoryStatusPublished {
+			stories[i] = nil
+		}
+	}
+	return stories, nil
+}
+
+// StoryPage is one page of a cursor-paginated listing.
+type StoryPage struct {
+	Stories []Story `json:"stories"`
+	// Cursors holds the cursor of each story in Stories, in the page's order.
+	Cursors []string `json:"cursors"`
+	// EndCursor points after the last story; pass it as
+	// StoryListOptions.After to fetch the next page. Empty when Stories is.
+	EndCursor   string `json:"endCursor"`
+	HasNextPage bool   `json:"hasNextPage"`
+}
+
+// Stories lists published stories matching opts; a non-empty query searches
+// title, summary and body. opts.Status is ignored; a status filter in
+// opts.Where still applies but can only narrow the published stories.
+func (s *StoryService) Stories(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	if opts.Limit > maxStoryLimit {
+		opts.Limit = maxStoryLimit
+	}
+	return s.list(ctx, query, opts)
+}
+
+// StoriesPage is Stories with cursor pagination: it reads one story past
+// the page to report HasNextPage and returns the cursor of the last story.
+func (s *StoryService) StoriesPage(ctx context.Context, query string, opts StoryListOptions) (*StoryPage, error) {
+	size := opts.limit()
+	if size > maxStoryLimit {
+		size = maxStoryLimit
+	}
+	opts.Limit = size + 1
+	stories, err := s.list(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &StoryPage{Stories: stories}
+	if len(stories) > size {
+		page.Stories, page.HasNextPage = stories[:size], true
+	}
+	page.Cursors = make([]string, len(page.Stories))
+	for i, story := range page.Stories {
+		page.Cursors[i] = StoryCursor(story, opts)
+	}
+	if len(page.Cursors) > 0 {
+		page.EndCursor = page.Cursors[len(page.Cursors)-1]
+	}
+	return page, nil
+}
+
+// list 只列出已發布的 story；query 不為空時改用 Search
+func (s *StoryService) list(ctx context.Context, query string, opts StoryListOptions) ([]Story, error) {
+	opts.Status = StoryStatusPublished
+	// 網站語言的列表一併包含沒有語言的 story，改以 Where 比對
+	if opts.Locale != "" {
+		filter, err := s.LocaleFilter(opts.Locale)
+		if err != nil {
+			return nil, err
+		}
+		where := StoryWhereInput{}
+		if opts.Where != nil {
+			where = *opts.Where
+		}
+		if where.Locale == nil {
+			where.Locale, opts.Locale = filter, ""
+			opts.Where = &where
+		}
+	}
+	// 先驗證條件與 cursor，避免無效的查詢佔用 cache 或打到儲存層
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if query != "" {
+		return s.repo.Search(ctx, query, opts)
+	}
+	return s.repo.List(ctx, opts)
+}
+
+// Author returns the author with id, or with slug when id is empty.
+func (s *StoryService) Author(ctx context.Context, id, slug string) (*Author, error) {
+	ar, err := s.authors()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case id != "":
+		return ar.GetAuthorByID(ctx, id)
+	case slug != "":
+		return ar.GetAuthorBySlug(ctx, slug)
+	}
+	return nil, errors.New("author id or slug is required")
+}
+
+// Authors returns the authors of story in byline order. Stores without
+// authors yield an empty list.
+func (s *StoryService) Authors(ctx context.Context, story *Story) ([]Author, error) {
+	return s.AuthorsByIDs(ctx, story.AuthorIDs)
+}
+
+// AuthorsByIDs returns the authors with ids in the order of ids, skipping
+// ids that do not exist, in one lookup. Stores without authors yield an
+// empty list.
+func (s *StoryService) AuthorsByIDs(ctx context.Context, ids []string) ([]Author, error) {
+	ar, err := s.authors()
+	if err != nil || len(ids) == 0 {
+		return []Author{}, nil
+	}
+	return ar.GetAuthorsByIDs(ctx, ids)
+}
+
+// AuthorPage is one page of the author listing.
+type AuthorPage struct {
+	Authors     []Author `json:"authors"`
+	HasNextPage bool     `json:"hasNextPage"`
+}
+
+// AuthorsPage lists authors by name, reading one author past the page to
+// report HasNextPage.
+func (s *StoryService) AuthorsPage(ctx context.Context, limit, offset int) (*AuthorPage, error) {
+	ar, err := s.authors()
+	if err != nil {
+		return nil, err
+	}
+	size := min(authorLimit(limit), maxAuthorLimit)
+	authors, err := ar.ListAuthors(ctx, size+1, offset)
+	if err != nil {
+		return nil, err
+	}
+	page := &AuthorPage{Authors: authors}
+	if len(authors) > size {
+		page.Authors, page.HasNextPage = authors[:size], true
+	}
+	return page, nil
+}
+
+// TermPage is one page of a tag or category listing.
+type TermPage struct {
+	Terms       []Term `json:"terms"`
+	HasNextPage bool   `json:"hasNextPage"`
+}
+
+// TermsPage lists the tags or categories (see TermKindTag) by name with
+// their published story counts, reading one term past the page to report
+// HasNextPage.
+func (s *StoryService) TermsPage(ctx context.Context, kind string, limit, offset int) (*TermPage, error) {
+	tr, err := s.taxonomy(kind)
+	if err != nil {
+		return nil, err
+	}
+	size := min(termLimit(limit), maxTermLimit)
+	terms, err := tr.ListTerms(ctx, kind, size+1, offset)
+	if err != nil {
+		return nil, err
+	}
+	page := &TermPage{Terms: terms}
+	if len(terms) > size {
+		page.Terms, page.HasNextPage = terms[:size], true
+	}
+	return page, nil
+}
+
+// Term returns the tag or category with slug, or ErrTermNotFound.
+func (s *StoryService) Term(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, err := s.taxonomy(kind)
+	if err != nil {
+		return nil, err
+	}
+	return tr.GetTerm(ctx, kind, slug)
+}
+
+// TermsBySlug returns the tag or category with each slug at the same
+// index, or nil when there is none, in one lookup (see TermBatchReader).
+func (s *StoryService) TermsBySlug(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	tr, err := s.taxonomy(kind)
+	if err != nil {
+		return nil, err
+	}
+	return LoadTerms(ctx, tr, kind, slugs)
+}
+
+// Collection returns the collection with id, or with slug when id is empty,
+// with StoryIDs narrowed to its published stories.
+func (s *StoryService) Collection(ctx context.Context, id, slug string) (*Collection, error) {
+	cs, err := s.collections()
+	if err != nil {
+		return nil, err
+	}
+	var collection *Collection
+	switch {
+	case id != "":
+		collection, err = cs.GetCollectionByID(ctx, id)
+	case slug != "":
+		collection, err = cs.GetCollectionBySlug(ctx, slug)
+	default:
+		return nil, errors.New("collection id or slug is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.publishedOnly(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// CollectionPage is one page of the collection listing.
+type CollectionPage struct {
+	Collections []Collection `json:"collections"`
+	HasNextPage bool         `json:"hasNextPage"`
+}
+
+// CollectionsPage lists collections, most recently updated first, reading
+// one collection past the page to report HasNextPage.
+func (s *StoryService) CollectionsPage(ctx context.Context, limit, offset int) (*CollectionPage, error) {
+	cs, err := s.collections()
+	if err != nil {
+		return nil, err
+	}
+	size := min(collectionLimit(limit), maxCollectionLimit)
+	collections, err := cs.ListCollections(ctx, size+1, offset)
+	if err != nil {
+		return nil, err
+	}
+	page := &CollectionPage{Collections: collections}
+	if len(collections) > size {
+		page.Collections, page.HasNextPage = collections[:size], true
+	}
+	for i := range page.Collections {
+		if err := s.publishedOnly(ctx, &page.Collections[i]); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// CollectionStories returns the published stories of collection in reading
+// order, skipping drafts and trashed stories.
+func (s *StoryService) CollectionStories(ctx context.Context, collection *Collection) ([]Story, error) {
+	stories := make([]Story, 0, len(collection.StoryIDs))
+	for _, id := range collection.StoryIDs {
+		story, err := s.Story(ctx, id, "")
+		if errors.Is(err, ErrStoryNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stories = append(stories, *story)
+	}
+	return stories, nil
+}
+
+// publishedOnly 將 collection.StoryIDs 縮減為已發布的 story，避免公開 API 透露草稿
+func (s *StoryService) publishedOnly(ctx context.Context, collection *Collection) error {
+	stories, err := s.CollectionStories(ctx, collection)
+	if err != nil {
+		return err
+	}
+	collection.StoryIDs = make([]string, len(stories))
+	for i, story := range stories {
+		collection.StoryIDs[i] = story.ID
+	}
+	return nil
+}
+
+// Series returns where story stands in each collection containing it
+// ("part N of M" with the previous and next parts), counting only published
+// stories. Stores without collections yield an empty list.
+func (s *StoryService) Series(ctx context.Context, story *Story) ([]StorySeries, error) {
+	cs, err := s.collections()
+	if err != nil {
+		return []StorySeries{}, nil
+	}
+	collections, err := cs.CollectionsByStory(ctx, story.ID)
+	if err != nil {
+		return nil, err
+	}
+	series := make([]StorySeries, 0, len(collections))
+	for _, collection := range collections {
+		stories, err := s.CollectionStories(ctx, &collection)
+		if err != nil {
+			return nil, err
+		}
+		if part := newStorySeries(collection, stories, story.ID); part != nil {
+			series = append(series, *part)
+		}
+	}
+	return series, nil
+}
+
+// collections 回傳儲存層的 CollectionStore；不支援時回傳 ErrCollectionsUnsupported
+func (s *StoryService) collections() (CollectionStore, error) {
+	cs, ok := s.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs, nil
+}
+
+// taxonomy 檢查 kind 並回傳儲存層的 Taxonomy；不支援時回傳 ErrTaxonomyUnsupported
+func (s *StoryService) taxonomy(kind string) (Taxonomy, error) {
+	if !ValidTermKind(kind) {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidTerm, kind)
+	}
+	tr, ok := s.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr, nil
+}
+
+// authors 回傳儲存層的 AuthorReader；不支援作者時回傳 ErrAuthorsUnsupported
+func (s *StoryService) authors() (AuthorReader, error) {
+	ar, ok := s.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar, nil
+}
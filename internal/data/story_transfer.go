@@ -0,0 +1,327 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// StoryExportOptions filters ExportStories. Zero values export every story
+// outside the trash, in any status.
+type StoryExportOptions struct {
+	Section string
+	// PublishedFrom and PublishedTo select stories published in
+	// [PublishedFrom, PublishedTo); unpublished stories never match a range.
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+}
+
+// StoryImportOptions controls ImportStories.
+type StoryImportOptions struct {
+	// DryRun validates every line and reports what would be created or
+	// updated without writing.
+	DryRun bool
+	// BatchSize is the number of stories written per transaction (default
+	// 100). The story cache is purged and the search index updated once per
+	// batch.
+	BatchSize int
+}
+
+// StoryImportReport summarises an import. Lines counts the non-empty lines
+// read; every line is either created, updated or listed in Errors.
+type StoryImportReport struct {
+	Lines   int                `json:"lines"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Failed  int                `json:"failed"`
+	DryRun  bool               `json:"dryRun"`
+	Errors  []StoryImportError `json:"errors"`
+}
+
+// StoryImportError reports why the story on Line (1-based) was not
+// imported.
+type StoryImportError struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Slug  string `json:"slug,omitempty"`
+	Error string `json:"error"`
+}
+
+// defaultImportBatchSize 為每個 transaction 寫入的 story 數預設值；maxImportLineSize 為單行 NDJSON 的上限
+const (
+	defaultImportBatchSize = 100
+	maxImportLineSize      = 16 << 20
+)
+
+// exportOptions 為匯出時讀取 story 的順序：依更新時間由舊到新，包含未發布的 story
+var exportOptions = StoryListOptions{
+	OrderBy: []OrderRule{{Field: StorySortUpdatedAt, Direction: "asc"}},
+	Limit:   maxStoryLimit,
+}
+
+// ExportStories writes the stories of repo matching opts to w as NDJSON
+// (one Story per line, oldest update first) and returns how many were
+// written. The output is the input format of ImportStories.
+func ExportStories(ctx context.Context, repo StoryRepository, w io.Writer, opts StoryExportOptions) (int, error) {
+	list := exportOptions
+	list.Section = opts.Section
+	if opts.PublishedFrom != nil || opts.PublishedTo != nil {
+		published := &DateTimeRangeFilter{}
+		if opts.PublishedFrom != nil {
+			from := opts.PublishedFrom.Format(time.RFC3339Nano)
+			published.Gte = &from
+		}
+		if opts.PublishedTo != nil {
+			to := opts.PublishedTo.Format(time.RFC3339Nano)
+			published.Lt = &to
+		}
+		list.Where = &StoryWhereInput{PublishedAt: published}
+	}
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for {
+		stories, err := repo.List(ctx, list)
+		if err != nil {
+			return count, fmt.Errorf("export stories: %w", err)
+		}
+		for _, story := range stories {
+			if err := enc.Encode(story); err != nil {
+				return count, fmt.Errorf("write story %s: %w", story.ID, err)
+			}
+			count++
+		}
+		if len(stories) < list.Limit {
+			return count, nil
+		}
+		list.After = StoryCursor(stories[len(stories)-1], list)
+	}
+}
+
+// ImportStories reads NDJSON stories from r (see ExportStories) and upserts
+// them into repo: a story whose ID exists is updated, one without an ID but
+// with the slug of an existing story updates that story, and any other is
+// created (keeping its ID when given). New stories keep their status and
+// publish time, bypassing the workflow, so imports can carry over a CMS's
+// published archive; updates must follow CheckStoryTransition. View counts
+// are ignored.
+//
+// Invalid lines (bad JSON, missing slug or title, unknown status or
+// authors, blocks not matching the block schema, a slug used by another story or repeated in the input, a status
+// change the workflow forbids) are reported and skipped. Valid stories are
+// written in batches of opts.BatchSize, one transaction each; when a write
+// fails the whole batch is rolled back and reported, and the import
+// continues with the next batch. The returned error is only set when
+// reading r fails or ctx is done.
+func ImportStories(ctx context.Context, repo StoryRepository, r io.Reader, opts StoryImportOptions) (*StoryImportReport, error) {
+	imp := newStoryImport(repo, opts)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return imp.report, err
+		}
+		var story Story
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&story); err != nil {
+			imp.report.Lines++
+			imp.fail(line, &story, fmt.Errorf("invalid JSON: %w", err))
+			continue
+		}
+		imp.add(ctx, line, story)
+	}
+	if err := scanner.Err(); err != nil {
+		return imp.report, fmt.Errorf("read line %d: %w", line+1, err)
+	}
+	imp.flush(ctx)
+	return imp.report, nil
+}
+
+// storyImport 為一次匯入的狀態；seen 記錄已讀到的 ID 與 slug 所在的行號，用於找出輸入中重複的 story，
+// pendingAuthors 為同一次匯入中將建立的作者 (dry run 時尚未寫入)，檢查作者時視為已存在
+type storyImport struct {
+	repo           StoryRepository
+	opts           StoryImportOptions
+	report         *StoryImportReport
+	seen           map[string]int
+	pendingAuthors map[string]bool
+	batch          []importedStory
+}
+
+// newStoryImport 建立一次匯入，套用 BatchSize 的預設值
+func newStoryImport(repo StoryRepository, opts StoryImportOptions) *storyImport {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultImportBatchSize
+	}
+	return &storyImport{repo: repo, opts: opts, seen: map[string]int{}, pendingAuthors: map[string]bool{},
+		report: &StoryImportReport{DryRun: opts.DryRun, Errors: []StoryImportError{}}}
+}
+
+// importedStory 為通過檢查、待寫入的 story；existing 為要更新的既有 story，nil 表示新增
+type importedStory struct {
+	line     int
+	story    Story
+	existing *Story
+}
+
+// add 檢查一篇 story，通過時加入 batch 並在 batch 滿時寫入，否則記錄錯誤
+func (imp *storyImport) add(ctx context.Context, line int, story Story) {
+	imp.report.Lines++
+	existing, err := imp.check(ctx, &story)
+	if err != nil {
+		imp.fail(line, &story, err)
+		return
+	}
+	if story.ID != "" {
+		imp.seen["id:"+story.ID] = line
+	}
+	imp.seen["slug:"+story.Slug] = line
+	imp.batch = append(imp.batch, importedStory{line: line, story: story, existing: existing})
+	if len(imp.batch) >= imp.opts.BatchSize {
+		imp.flush(ctx)
+	}
+}
+
+// check 檢查 story 的欄位並找出要更新的既有 story；既有 story 不存在時回傳 nil
+func (imp *storyImport) check(ctx context.Context, story *Story) (*Story, error) {
+	switch {
+	case story.Slug == "" || story.Title == "":
+		return nil, errors.New("slug and title are required")
+	case story.Status != "" && !ValidStoryStatus(story.Status):
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStoryStatus, story.Status)
+	}
+	if err := ValidateBlocks(story.Blocks); err != nil {
+		return nil, err
+	}
+	if first, ok := imp.seen["id:"+story.ID]; ok && story.ID != "" {
+		return nil, fmt.Errorf("id already imported on line %d", first)
+	}
+	if first, ok := imp.seen["slug:"+story.Slug]; ok {
+		return nil, fmt.Errorf("slug already imported on line %d", first)
+	}
+	if err := imp.checkAuthors(ctx, story.AuthorIDs); err != nil {
+		return nil, err
+	}
+
+	var existing *Story
+	if story.ID != "" {
+		found, err := imp.repo.GetByID(ctx, story.ID)
+		if err != nil && !errors.Is(err, ErrStoryNotFound) {
+			return nil, err
+		}
+		existing = found
+	}
+	bySlug, err := imp.repo.GetBySlug(ctx, story.Slug)
+	if err != nil && !errors.Is(err, ErrStoryNotFound) {
+		return nil, err
+	}
+	switch {
+	case bySlug == nil || bySlug.Slug != story.Slug:
+		// 舊 slug 的轉址不算佔用，匯入後轉址由 repository 移除
+	case existing == nil && story.ID == "":
+		existing = bySlug
+	case bySlug.ID != story.ID:
+		return nil, fmt.Errorf("%w by story %s", ErrStorySlugTaken, bySlug.ID)
+	}
+	if existing != nil {
+		to := story.Status
+		if to == "" {
+			to = StoryStatusDraft
+		}
+		if err := CheckStoryTransition(existing.Status, to); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// checkAuthors 確認署名的作者都存在；儲存層不支援作者時略過，由寫入本身檢查
+func (imp *storyImport) checkAuthors(ctx context.Context, ids []string) error {
+	ar, ok := imp.repo.(AuthorReader)
+	if !ok || len(ids) == 0 {
+		return nil
+	}
+	authors, err := ar.GetAuthorsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]bool, len(authors))
+	for _, author := range authors {
+		found[author.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] && !imp.pendingAuthors[id] {
+			return fmt.Errorf("%w: %s", ErrAuthorNotFound, id)
+		}
+	}
+	return nil
+}
+
+// flush 在一個 transaction 中寫入 batch；失敗時整批記錄為錯誤
+func (imp *storyImport) flush(ctx context.Context) {
+	batch := imp.batch
+	imp.batch = nil
+	if len(batch) == 0 {
+		return
+	}
+	if imp.opts.DryRun {
+		imp.count(batch)
+		return
+	}
+	err := imp.repo.WithTx(ctx, func(tx StoryRepository) error {
+		for i := range batch {
+			item := &batch[i]
+			story := item.story
+			var err error
+			if item.existing != nil {
+				story.ID = item.existing.ID
+				if story.CreatedAt.IsZero() {
+					story.CreatedAt = item.existing.CreatedAt
+				}
+				err = tx.Update(ctx, &story)
+			} else {
+				err = tx.Create(ctx, &story)
+			}
+			if err != nil {
+				return fmt.Errorf("line %d: %w", item.line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range batch {
+			imp.fail(batch[i].line, &batch[i].story, fmt.Errorf("batch rolled back: %w", err))
+		}
+		return
+	}
+	imp.count(batch)
+}
+
+// count 將寫入成功的 batch 計入新增或更新
+func (imp *storyImport) count(batch []importedStory) {
+	for _, item := range batch {
+		if item.existing != nil {
+			imp.report.Updated++
+		} else {
+			imp.report.Created++
+		}
+	}
+}
+
+// fail 記錄一行的錯誤
+func (imp *storyImport) fail(line int, story *Story, err error) {
+	imp.report.Failed++
+	imp.report.Errors = append(imp.report.Errors, StoryImportError{Line: line, ID: story.ID, Slug: story.Slug, Error: err.Error()})
+}
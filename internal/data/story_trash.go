@@ -0,0 +1,31 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTrashUnsupported is returned when the story store deletes stories
+// permanently instead of moving them to the trash.
+var ErrTrashUnsupported = errors.New("story store does not support trash")
+
+// StoryTrash is implemented by story repositories whose Delete moves a story
+// to the trash (soft delete) rather than removing it. Trashed stories are
+// left out of GetByID, GetBySlug, List, Search and Update as if they did not
+// exist, but keep their slug and revisions until purged. Callers type-assert
+// a StoryRepository to it.
+type StoryTrash interface {
+	// ListTrash returns trashed stories, most recently deleted first, with
+	// DeletedAt set.
+	ListTrash(ctx context.Context, limit, offset int) ([]Story, error)
+	// RestoreStory moves the story with id out of the trash and returns it,
+	// or ErrStoryNotFound when it is not in the trash.
+	RestoreStory(ctx context.Context, id string) (*Story, error)
+	// PurgeStory permanently deletes the trashed story with id and its
+	// revisions, or returns ErrStoryNotFound when it is not in the trash.
+	PurgeStory(ctx context.Context, id string) error
+	// PurgeTrash permanently deletes every story trashed before before and
+	// returns how many were deleted.
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
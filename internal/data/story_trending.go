@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTrendingWindow is returned (wrapped) for a window other than
+// 1h, 24h and 7d.
+var ErrInvalidTrendingWindow = errors.New("invalid trending window")
+
+// trendingStoriesName 為 story 熱門排行在 Redis 中的名稱
+const trendingStoriesName = "stories"
+
+// trendingCacheTTL 為排行結果的快取時間；排行變化快，只短暫快取以減少 ZUNION 的次數
+const trendingCacheTTL = time.Minute
+
+// TrendingStory is a story in the trending or most-read ranking.
+type TrendingStory struct {
+	Story Story   `json:"story"`
+	Score float64 `json:"score"`
+}
+
+// TrendingService ranks published stories by the views reported with
+// RecordView, counted per visitor at most once per dedupe window. Views are
+// kept in Redis sorted sets bucketed by time; without Redis the rankings are
+// empty.
+type TrendingService struct {
+	stories *StoryService
+	cache   *Cache
+	dedupe  time.Duration
+}
+
+// NewTrendingService returns a service ranking stories. A view of the same
+// story by the same visitor is counted once per dedupe; 0 disables dedupe.
+func NewTrendingService(stories *StoryService, cache *Cache, dedupe time.Duration) *TrendingService {
+	return &TrendingService{stories: stories, cache: cache, dedupe: dedupe}
+}
+
+// RecordView counts a view of the published story with slug by visitor (an
+// opaque, stable ID from the client, stored only hashed) and reports whether
+// it was counted or dropped as a repeat view.
+func (s *TrendingService) RecordView(ctx context.Context, visitor, slug string) (bool, error) {
+	story, err := s.stories.Story(ctx, "", slug)
+	if err != nil {
+		return false, err
+	}
+	if visitor != "" && s.dedupe > 0 {
+		first, err := s.cache.MarkSeen(ctx, "view:"+visitorHash(visitor)+":"+story.ID, s.dedupe)
+		if err != nil {
+			return false, err
+		}
+		if !first {
+			return false, nil
+		}
+	}
+	return true, s.cache.RecordTrending(ctx, trendingStoriesName, story.ID)
+}
+
+// Trending returns up to limit published stories with the most views in
+// window, recent views weighing more.
+func (s *TrendingService) Trending(ctx context.Context, window TrendingWindow, limit int) ([]TrendingStory, error) {
+	return s.rank(ctx, window, limit, true)
+}
+
+// MostRead returns up to limit published stories with the most views in
+// window.
+func (s *TrendingService) MostRead(ctx context.Context, window TrendingWindow, limit int) ([]TrendingStory, error) {
+	return s.rank(ctx, window, limit, false)
+}
+
+// rank 讀取排行並載入 story；排行中已刪除或下架的 story 直接略過
+func (s *TrendingService) rank(ctx context.Context, window TrendingWindow, limit int, decay bool) ([]TrendingStory, error) {
+	if !window.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTrendingWindow, window)
+	}
+	limit = StoryListOptions{Limit: limit}.limit()
+	if limit > maxStoryLimit {
+		limit = maxStoryLimit
+	}
+
+	key := NewCacheKey(storyCachePrefix + "trending").Fields(map[string]interface{}{"window": window, "limit": limit, "decay": decay}).ShortHash().String()
+	return NewTypedCache[[]TrendingStory](s.cache).GetOrSet(ctx, key, trendingCacheTTL, func(ctx context.Context) ([]TrendingStory, error) {
+		// 多取一些，補上被略過的 story
+		members, err := s.cache.TopTrending(ctx, trendingStoriesName, window, limit*2, decay)
+		if err != nil {
+			return nil, err
+		}
+		ranked := make([]TrendingStory, 0, limit)
+		for _, member := range members {
+			if len(ranked) == limit {
+				break
+			}
+			story, err := s.stories.Story(ctx, member.Member, "")
+			if errors.Is(err, ErrStoryNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			ranked = append(ranked, TrendingStory{Story: *story, Score: member.Score})
+		}
+		return ranked, nil
+	})
+}
@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrViewCountsUnsupported is returned when the story store cannot persist
+// view counts.
+var ErrViewCountsUnsupported = errors.New("story store does not support view counts")
+
+// ViewCountWriter is implemented by story repositories that store view
+// counts. Callers type-assert a StoryRepository to it.
+type ViewCountWriter interface {
+	// AddViewCounts adds counts[id] to the view count of each story,
+	// skipping ids that do not exist. Story.UpdatedAt is left unchanged.
+	AddViewCounts(ctx context.Context, counts map[string]int64) error
+}
+
+// viewCounterName 為 Redis 中待寫入瀏覽數的計數名稱；viewFlushLock 與 viewFlushLockTTL 為寫入時的鎖
+const (
+	viewCounterName  = "story-views"
+	viewFlushLock    = "view-flush"
+	viewFlushLockTTL = 5 * time.Minute
+)
+
+// ViewCounter counts story views in Redis and periodically adds the totals
+// to Story.ViewCount in the story store in one batch, so page views do not
+// write to the primary store. While Redis is unavailable views are counted
+// in memory and flushed by this instance. Counts not flushed yet are added
+// by Total; counts in memory are lost if the process exits.
+type ViewCounter struct {
+	repo  StoryRepository
+	cache *Cache
+
+	mu    sync.Mutex
+	local map[string]int64 // Redis 無法使用時暫存的瀏覽數
+}
+
+// NewViewCounter returns a counter persisting to repo, which must implement
+// ViewCountWriter for Flush to succeed.
+func NewViewCounter(repo StoryRepository, cache *Cache) *ViewCounter {
+	return &ViewCounter{repo: repo, cache: cache, local: map[string]int64{}}
+}
+
+// Add counts one view of the story with id. It does nothing on a nil
+// ViewCounter.
+func (v *ViewCounter) Add(ctx context.Context, id string) {
+	if v == nil {
+		return
+	}
+	if v.cache.AddCount(ctx, viewCounterName, id, 1) {
+		return
+	}
+	v.mu.Lock()
+	v.local[id]++
+	v.mu.Unlock()
+}
+
+// Total returns the view count of story including views not flushed yet.
+// On a nil ViewCounter it returns story.ViewCount.
+func (v *ViewCounter) Total(ctx context.Context, story *Story) int64 {
+	total := story.ViewCount
+	if v == nil {
+		return total
+	}
+	pending, err := v.cache.PendingCount(ctx, viewCounterName, story.ID)
+	if err != nil {
+		slog.Debug("read pending view count failed", "id", story.ID, "error", err)
+	}
+	v.mu.Lock()
+	total += pending + v.local[story.ID]
+	v.mu.Unlock()
+	return total
+}
+
+// Flush adds the counted views to the story store and returns how many
+// stories were updated. With Redis the batch is held under a lock so only
+// one instance writes it; a failed batch is retried by the next Flush.
+func (v *ViewCounter) Flush(ctx context.Context) (int, error) {
+	writer, ok := v.repo.(ViewCountWriter)
+	if !ok {
+		return 0, ErrViewCountsUnsupported
+	}
+
+	// process 中暫存的瀏覽數
+	v.mu.Lock()
+	local := v.local
+	v.local = map[string]int64{}
+	v.mu.Unlock()
+	flushed := 0
+	if len(local) > 0 {
+		if err := writer.AddViewCounts(ctx, local); err != nil {
+			v.restore(local)
+			return 0, err
+		}
+		flushed += len(local)
+	}
+
+	lock, err := v.cache.Lock(ctx, viewFlushLock, viewFlushLockTTL)
+	if errors.Is(err, ErrLockNotAcquired) {
+		return flushed, nil
+	}
+	if err != nil {
+		return flushed, err
+	}
+	defer func() { _ = lock.Unlock(context.WithoutCancel(ctx)) }()
+
+	counts, commit, err := v.cache.DrainCounts(ctx, viewCounterName)
+	if err != nil {
+		return flushed, err
+	}
+	if len(counts) == 0 {
+		return flushed, nil
+	}
+	if err := writer.AddViewCounts(ctx, counts); err != nil {
+		return flushed, err
+	}
+	if err := commit(ctx); err != nil {
+		// 已寫入但未能移除這一批，下次會重複寫入，記錄下來方便追查
+		slog.Error("failed to commit flushed view counts", "stories", len(counts), "error", err)
+		return flushed + len(counts), err
+	}
+	return flushed + len(counts), nil
+}
+
+// Run calls Flush every interval until ctx is done, and once more before
+// returning.
+func (v *ViewCounter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			v.flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			v.flush(ctx)
+		}
+	}
+}
+
+// flush 執行 Flush 並記錄結果
+func (v *ViewCounter) flush(ctx context.Context) {
+	n, err := v.Flush(ctx)
+	if err != nil {
+		slog.Warn("failed to flush view counts", "error", err)
+		return
+	}
+	if n > 0 {
+		slog.Debug("flushed view counts", "stories", n)
+	}
+}
+
+// restore 將寫入失敗的瀏覽數加回 process 中暫存的計數
+func (v *ViewCounter) restore(counts map[string]int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for id, n := range counts {
+		v.local[id] += n
+	}
+}
@@ -0,0 +1,719 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrInvalidStoryStatus is returned (wrapped) for a status that is not
+	// one of the StoryStatus* constants.
+	ErrInvalidStoryStatus = errors.New("invalid story status")
+	// ErrInvalidStoryTransition is returned (wrapped) by Update and
+	// StoryWorkflow.Transition when the workflow does not allow moving a
+	// story from its current status to the new one.
+	ErrInvalidStoryTransition = errors.New("invalid story status transition")
+	// ErrStoryTransitionForbidden is returned (wrapped) by
+	// StoryWorkflow.Transition when the role may not make the transition,
+	// and by the other StoryWorkflow writes the role is not permitted to
+	// make, such as an author editing another user's story.
+	ErrStoryTransitionForbidden = errors.New("story status transition not permitted")
+)
+
+// StoryRole is the editorial role of a StoryWorkflow caller.
+type StoryRole string
+
+// Story roles, from least to most privileged; each role may do everything
+// the roles below it may. Readers may not use the workflow. Authors create
+// drafts, edit their own drafts, and submit them for review or withdraw
+// them; editors edit every story and make every transition; admins may
+// additionally import stories and empty the trash.
+const (
+	StoryRoleReader StoryRole = "reader"
+	StoryRoleAuthor StoryRole = "author"
+	StoryRoleEditor StoryRole = "editor"
+	StoryRoleAdmin  StoryRole = "admin"
+)
+
+// storyRoleRanks 為角色的權限高低；不在其中的角色沒有任何權限
+var storyRoleRanks = map[StoryRole]int{
+	StoryRoleReader: 1,
+	StoryRoleAuthor: 2,
+	StoryRoleEditor: 3,
+	StoryRoleAdmin:  4,
+}
+
+// ParseStoryRole returns the role named name, accepting "writer" (the
+// former name of the author role) for StoryRoleAuthor, and whether it is
+// one.
+func ParseStoryRole(name string) (StoryRole, bool) {
+	if name == "writer" {
+		return StoryRoleAuthor, true
+	}
+	role := StoryRole(name)
+	_, ok := storyRoleRanks[role]
+	return role, ok
+}
+
+// StoryRoleOf returns the most privileged role among roles (such as a
+// Principal's Roles), or StoryRoleReader when none is a story role.
+func StoryRoleOf(roles []string) StoryRole {
+	best := StoryRoleReader
+	for _, name := range roles {
+		if role, ok := ParseStoryRole(name); ok && role.Includes(best) {
+			best = role
+		}
+	}
+	return best
+}
+
+// Includes reports whether role r may do everything role other may.
+func (r StoryRole) Includes(other StoryRole) bool {
+	rank, ok := storyRoleRanks[r]
+	return ok && rank >= storyRoleRanks[other]
+}
+
+// storyTransitions 為工作流程允許的狀態轉換與可執行的最低角色；相同狀態之間的寫入 (修改內容) 一律允許
+var storyTransitions = map[string]map[string]StoryRole{
+	StoryStatusDraft: {
+		StoryStatusInReview: StoryRoleAuthor,
+	},
+	StoryStatusInReview: {
+		StoryStatusDraft:     StoryRoleAuthor, // 退回或撤回
+		StoryStatusScheduled: StoryRoleEditor,
+		StoryStatusPublished: StoryRoleEditor,
+	},
+	StoryStatusScheduled: {
+		StoryStatusDraft:     StoryRoleEditor, // 取消排程
+		StoryStatusPublished: StoryRoleEditor, // 提前發布，或由 StoryScheduler 到期發布
+	},
+	StoryStatusPublished: {
+		StoryStatusArchived: StoryRoleEditor,
+	},
+	StoryStatusArchived: {
+		StoryStatusDraft: StoryRoleEditor, // 重新編輯
+	},
+}
+
+// ValidStoryStatus reports whether status is one of the StoryStatus*
+// constants.
+func ValidStoryStatus(status string) bool {
+	_, ok := storyTransitions[status]
+	return ok
+}
+
+// CheckStoryTransition returns nil when the workflow allows a story to move
+// from status from to status to: draft → in_review → scheduled → published
+// → archived, with in_review → published, in_review → draft (rejected),
+// scheduled → draft (unscheduled) and archived → draft (reopened). Writes
+// that keep the status are always allowed. Every store's Update enforces it.
+func CheckStoryTransition(from, to string) error {
+	if !ValidStoryStatus(to) {
+		return fmt.Errorf("%w: %q", ErrInvalidStoryStatus, to)
+	}
+	if from == to {
+		return nil
+	}
+	if _, ok := storyTransitions[from][to]; !ok {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidStoryTransition, from, to)
+	}
+	return nil
+}
+
+// CanTransition reports whether role may move a story from status from to
+// status to. Authors may only do so for their own stories (see
+// StoryWorkflow.Transition).
+func (r StoryRole) CanTransition(from, to string) bool {
+	if !r.Includes(StoryRoleAuthor) {
+		return false
+	}
+	min, ok := storyTransitions[from][to]
+	return from == to || (ok && r.Includes(min))
+}
+
+// Transitions returns the statuses role may move a story in status from to.
+func (r StoryRole) Transitions(from string) []string {
+	targets := []string{}
+	for _, to := range []string{StoryStatusDraft, StoryStatusInReview, StoryStatusScheduled, StoryStatusPublished, StoryStatusArchived} {
+		if to != from && r.CanTransition(from, to) {
+			targets = append(targets, to)
+		}
+	}
+	return targets
+}
+
+// checkNewStoryStatus 檢查新增的 story 的狀態；新增時可為任何狀態，供匯入既有內容
+func checkNewStoryStatus(story *Story) error {
+	if !ValidStoryStatus(story.Status) {
+		return fmt.Errorf("%w: %q", ErrInvalidStoryStatus, story.Status)
+	}
+	return nil
+}
+
+// StoryWorkflow is the editorial API over stories in every status, used by
+// the workflow admin endpoints. Unlike StoryService it returns unpublished
+// stories, so it must never back a public endpoint.
+type StoryWorkflow struct {
+	repo StoryRepository
+}
+
+// NewStoryWorkflow returns a workflow writing to repo (usually a
+// CachedStoryRepository, so transitions purge the story cache).
+func NewStoryWorkflow(repo StoryRepository) *StoryWorkflow {
+	return &StoryWorkflow{repo: repo}
+}
+
+// Story returns the story with id in any status, or ErrStoryNotFound.
+func (w *StoryWorkflow) Story(ctx context.Context, id string) (*Story, error) {
+	return w.repo.GetByID(ctx, id)
+}
+
+// Stories lists stories in any status; opts.Status selects one.
+func (w *StoryWorkflow) Stories(ctx context.Context, opts StoryListOptions) ([]Story, error) {
+	if opts.Status != "" && !ValidStoryStatus(opts.Status) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStoryStatus, opts.Status)
+	}
+	if opts.Limit > maxStoryLimit {
+		opts.Limit = maxStoryLimit
+	}
+	return w.repo.List(ctx, opts)
+}
+
+// CreateStory stores a new story on behalf of role, owned by the caller
+// (the subject of the principal on ctx, see WithPrincipal). Authors may
+// only create drafts; editors may create stories in any status.
+func (w *StoryWorkflow) CreateStory(ctx context.Context, story *Story, role StoryRole) error {
+	if err := requireRole(role, StoryRoleAuthor, "create stories"); err != nil {
+		return err
+	}
+	if story.Status == "" {
+		story.Status = StoryStatusDraft
+	}
+	if story.Status != StoryStatusDraft && !role.Includes(StoryRoleEditor) {
+		return fmt.Errorf("%w: %s cannot create %s stories", ErrStoryTransitionForbidden, role, story.Status)
+	}
+	story.OwnerID = storyCaller(ctx)
+	return w.repo.Create(ctx, story)
+}
+
+// UpdateStory replaces the content of the story with story.ID on behalf of
+// role and returns the updated story. Authors may only edit their own
+// drafts; editors may edit every story. The status, publish time and owner
+// are left as they are, since they belong to the workflow (see
+// Transition).
+func (w *StoryWorkflow) UpdateStory(ctx context.Context, story *Story, role StoryRole) (*Story, error) {
+	if err := requireRole(role, StoryRoleAuthor, "edit stories"); err != nil {
+		return nil, err
+	}
+	var updated *Story
+	err := w.repo.WithTx(ctx, func(repo StoryRepository) error {
+		current, err := repo.GetByID(ctx, story.ID)
+		if err != nil {
+			return err
+		}
+		if err := requireOwner(ctx, current, role, "edit stories"); err != nil {
+			return err
+		}
+		if current.Status != StoryStatusDraft && !role.Includes(StoryRoleEditor) {
+			return fmt.Errorf("%w: %s cannot edit %s stories", ErrStoryTransitionForbidden, role, current.Status)
+		}
+		restoreStoryContent(current, story)
+		current.Locale = story.Locale
+		current.TranslationGroup = story.TranslationGroup
+		if err := repo.Update(ctx, current); err != nil {
+			return err
+		}
+		updated = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Revisions returns the revisions of the story with id, newest first,
+// without bodies.
+func (w *StoryWorkflow) Revisions(ctx context.Context, id string) ([]StoryRevision, error) {
+	rr, err := w.revisions()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return rr.ListRevisions(ctx, id)
+}
+
+// Revision returns revision number of the story with id.
+func (w *StoryWorkflow) Revision(ctx context.Context, id string, number int) (*StoryRevision, error) {
+	rr, err := w.revisions()
+	if err != nil {
+		return nil, err
+	}
+	return rr.GetRevision(ctx, id, number)
+}
+
+// Diff returns the changes of the story with id from revision from to
+// revision to.
+func (w *StoryWorkflow) Diff(ctx context.Context, id string, from, to int) (*StoryDiff, error) {
+	older, err := w.Revision(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := w.Revision(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffStoryRevisions(older, newer), nil
+}
+
+// Rollback restores the content of the story with id to revision number on
+// behalf of role and returns the updated story. Only editors may roll back.
+// The status and publish time are left as they are, since they belong to
+// the workflow; the rollback is written as an ordinary update, so it
+// becomes the newest revision and the repository's decorators purge the
+// caches and feeds and reindex the story.
+func (w *StoryWorkflow) Rollback(ctx context.Context, id string, number int, role StoryRole) (*Story, error) {
+	if err := requireEditor(role, "roll back stories"); err != nil {
+		return nil, err
+	}
+	// 版本寫入後不會變更，可在 transaction 外讀取
+	revision, err := w.Revision(ctx, id, number)
+	if err != nil {
+		return nil, err
+	}
+	var updated *Story
+	err = w.repo.WithTx(ctx, func(repo StoryRepository) error {
+		story, err := repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		restoreStoryContent(story, &revision.Story)
+		if err := repo.Update(ctx, story); err != nil {
+			return err
+		}
+		updated = story
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// restoreStoryContent 將 snapshot 的內容欄位寫回 story；狀態、發布時間與瀏覽數不變
+func restoreStoryContent(story, snapshot *Story) {
+	story.Slug = snapshot.Slug
+	story.Title = snapshot.Title
+	story.Subtitle = snapshot.Subtitle
+	story.Summary = snapshot.Summary
+	story.Body = snapshot.Body
+	story.Blocks = slices.Clone(snapshot.Blocks)
+	story.Section = snapshot.Section
+	story.Tags = slices.Clone(snapshot.Tags)
+	story.AuthorIDs = slices.Clone(snapshot.AuthorIDs)
+	story.CoverImage = snapshot.CoverImage
+	story.IsMember = snapshot.IsMember
+	story.Access = snapshot.Access
+}
+
+// Delete moves the story with id to the trash on behalf of role. Only
+// editors may delete. Stores without a trash delete the story permanently.
+func (w *StoryWorkflow) Delete(ctx context.Context, id string, role StoryRole) error {
+	if err := requireEditor(role, "delete stories"); err != nil {
+		return err
+	}
+	return w.repo.Delete(ctx, id)
+}
+
+// Trash lists trashed stories, most recently deleted first.
+func (w *StoryWorkflow) Trash(ctx context.Context, limit, offset int) ([]Story, error) {
+	st, err := w.trash()
+	if err != nil {
+		return nil, err
+	}
+	return st.ListTrash(ctx, min(limit, maxStoryLimit), offset)
+}
+
+// Restore moves the story with id out of the trash on behalf of role and
+// returns it with its status unchanged. Only editors may restore.
+func (w *StoryWorkflow) Restore(ctx context.Context, id string, role StoryRole) (*Story, error) {
+	if err := requireEditor(role, "restore stories"); err != nil {
+		return nil, err
+	}
+	st, err := w.trash()
+	if err != nil {
+		return nil, err
+	}
+	return st.RestoreStory(ctx, id)
+}
+
+// Purge permanently deletes the trashed story with id on behalf of role.
+// Only editors may purge.
+func (w *StoryWorkflow) Purge(ctx context.Context, id string, role StoryRole) error {
+	if err := requireEditor(role, "purge stories"); err != nil {
+		return err
+	}
+	st, err := w.trash()
+	if err != nil {
+		return err
+	}
+	return st.PurgeStory(ctx, id)
+}
+
+// PurgeTrash permanently deletes every story trashed before before on
+// behalf of role and returns how many were deleted. Only admins may empty
+// the trash.
+func (w *StoryWorkflow) PurgeTrash(ctx context.Context, before time.Time, role StoryRole) (int, error) {
+	if err := requireRole(role, StoryRoleAdmin, "empty the trash"); err != nil {
+		return 0, err
+	}
+	st, err := w.trash()
+	if err != nil {
+		return 0, err
+	}
+	return st.PurgeTrash(ctx, before)
+}
+
+// Export writes the stories matching opts to out as NDJSON on behalf of
+// role (see ExportStories) and returns how many were written. Only editors
+// may export.
+func (w *StoryWorkflow) Export(ctx context.Context, out io.Writer, opts StoryExportOptions, role StoryRole) (int, error) {
+	if err := requireEditor(role, "export stories"); err != nil {
+		return 0, err
+	}
+	return ExportStories(ctx, w.repo, out, opts)
+}
+
+// Import upserts the NDJSON stories read from in on behalf of role (see
+// ImportStories). Only admins may import, since an import overwrites
+// stories wholesale.
+func (w *StoryWorkflow) Import(ctx context.Context, in io.Reader, opts StoryImportOptions, role StoryRole) (*StoryImportReport, error) {
+	if err := requireRole(role, StoryRoleAdmin, "import stories"); err != nil {
+		return nil, err
+	}
+	return ImportStories(ctx, w.repo, in, opts)
+}
+
+// Authors lists authors by name.
+func (w *StoryWorkflow) Authors(ctx context.Context, limit, offset int) ([]Author, error) {
+	ar, ok := w.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.ListAuthors(ctx, min(limit, maxAuthorLimit), offset)
+}
+
+// Author returns the author with id, or ErrAuthorNotFound.
+func (w *StoryWorkflow) Author(ctx context.Context, id string) (*Author, error) {
+	ar, ok := w.repo.(AuthorReader)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return ar.GetAuthorByID(ctx, id)
+}
+
+// CreateAuthor stores a new author on behalf of role. Authors and editors
+// may create authors.
+func (w *StoryWorkflow) CreateAuthor(ctx context.Context, author *Author, role StoryRole) error {
+	if err := requireRole(role, StoryRoleAuthor, "create authors"); err != nil {
+		return err
+	}
+	aw, err := w.authorWriter()
+	if err != nil {
+		return err
+	}
+	return aw.CreateAuthor(ctx, author)
+}
+
+// UpdateAuthor replaces the author with author.ID on behalf of role.
+// Authors and editors may update authors; the bylines of the author's
+// stories change with it.
+func (w *StoryWorkflow) UpdateAuthor(ctx context.Context, author *Author, role StoryRole) error {
+	if err := requireRole(role, StoryRoleAuthor, "edit authors"); err != nil {
+		return err
+	}
+	aw, err := w.authorWriter()
+	if err != nil {
+		return err
+	}
+	return aw.UpdateAuthor(ctx, author)
+}
+
+// DeleteAuthor removes the author with id on behalf of role. Only editors
+// may delete authors, and only authors no story credits.
+func (w *StoryWorkflow) DeleteAuthor(ctx context.Context, id string, role StoryRole) error {
+	if err := requireEditor(role, "delete authors"); err != nil {
+		return err
+	}
+	aw, err := w.authorWriter()
+	if err != nil {
+		return err
+	}
+	return aw.DeleteAuthor(ctx, id)
+}
+
+// Terms lists the tags or categories by name with their published story
+// counts.
+func (w *StoryWorkflow) Terms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	tr, err := w.taxonomy()
+	if err != nil {
+		return nil, err
+	}
+	return tr.ListTerms(ctx, kind, min(limit, maxTermLimit), offset)
+}
+
+// Term returns the tag or category with slug, or ErrTermNotFound.
+func (w *StoryWorkflow) Term(ctx context.Context, kind, slug string) (*Term, error) {
+	tr, err := w.taxonomy()
+	if err != nil {
+		return nil, err
+	}
+	return tr.GetTerm(ctx, kind, slug)
+}
+
+// CreateTerm stores a new tag or category on behalf of role. Authors and
+// editors may create terms.
+func (w *StoryWorkflow) CreateTerm(ctx context.Context, term *Term, role StoryRole) error {
+	if err := requireRole(role, StoryRoleAuthor, "create tags and categories"); err != nil {
+		return err
+	}
+	tr, err := w.taxonomy()
+	if err != nil {
+		return err
+	}
+	return tr.CreateTerm(ctx, term)
+}
+
+// UpdateTerm replaces the term of term.Kind with slug on behalf of role,
+// renaming it and re-linking its stories when term.Slug differs. Only
+// editors may update terms.
+func (w *StoryWorkflow) UpdateTerm(ctx context.Context, slug string, term *Term, role StoryRole) error {
+	if err := requireEditor(role, "edit tags and categories"); err != nil {
+		return err
+	}
+	tr, err := w.taxonomy()
+	if err != nil {
+		return err
+	}
+	_, err = tr.UpdateTerm(ctx, slug, term)
+	return err
+}
+
+// MergeTerms moves the stories of the term from into the term into and
+// deletes from on behalf of role, returning how many stories moved. Only
+// editors may merge terms.
+func (w *StoryWorkflow) MergeTerms(ctx context.Context, kind, from, into string, role StoryRole) (int, error) {
+	if err := requireEditor(role, "merge tags and categories"); err != nil {
+		return 0, err
+	}
+	tr, err := w.taxonomy()
+	if err != nil {
+		return 0, err
+	}
+	relinked, err := tr.MergeTerms(ctx, kind, from, into)
+	return len(relinked), err
+}
+
+// DeleteTerm deletes an unused tag or category on behalf of role. Only
+// editors may delete terms.
+func (w *StoryWorkflow) DeleteTerm(ctx context.Context, kind, slug string, role StoryRole) error {
+	if err := requireEditor(role, "delete tags and categories"); err != nil {
+		return err
+	}
+	tr, err := w.taxonomy()
+	if err != nil {
+		return err
+	}
+	return tr.DeleteTerm(ctx, kind, slug)
+}
+
+// Collections lists collections, most recently updated first.
+func (w *StoryWorkflow) Collections(ctx context.Context, limit, offset int) ([]Collection, error) {
+	cs, err := w.collections()
+	if err != nil {
+		return nil, err
+	}
+	return cs.ListCollections(ctx, min(limit, maxCollectionLimit), offset)
+}
+
+// Collection returns the collection with id, or ErrCollectionNotFound.
+func (w *StoryWorkflow) Collection(ctx context.Context, id string) (*Collection, error) {
+	cs, err := w.collections()
+	if err != nil {
+		return nil, err
+	}
+	return cs.GetCollectionByID(ctx, id)
+}
+
+// CreateCollection stores a new collection on behalf of role. Authors and
+// editors may create collections.
+func (w *StoryWorkflow) CreateCollection(ctx context.Context, collection *Collection, role StoryRole) error {
+	if err := requireRole(role, StoryRoleAuthor, "create collections"); err != nil {
+		return err
+	}
+	cs, err := w.collections()
+	if err != nil {
+		return err
+	}
+	return cs.CreateCollection(ctx, collection)
+}
+
+// UpdateCollection replaces the collection with collection.ID, including
+// the order of its stories, on behalf of role. Authors and editors may
+// update collections.
+func (w *StoryWorkflow) UpdateCollection(ctx context.Context, collection *Collection, role StoryRole) error {
+	if err := requireRole(role, StoryRoleAuthor, "edit collections"); err != nil {
+		return err
+	}
+	cs, err := w.collections()
+	if err != nil {
+		return err
+	}
+	return cs.UpdateCollection(ctx, collection)
+}
+
+// DeleteCollection removes the collection with id on behalf of role; its
+// stories are kept. Only editors may delete collections.
+func (w *StoryWorkflow) DeleteCollection(ctx context.Context, id string, role StoryRole) error {
+	if err := requireEditor(role, "delete collections"); err != nil {
+		return err
+	}
+	cs, err := w.collections()
+	if err != nil {
+		return err
+	}
+	return cs.DeleteCollection(ctx, id)
+}
+
+// collections 回傳 repo 的合集介面
+func (w *StoryWorkflow) collections() (CollectionStore, error) {
+	cs, ok := w.repo.(CollectionStore)
+	if !ok {
+		return nil, ErrCollectionsUnsupported
+	}
+	return cs, nil
+}
+
+// taxonomy 回傳 repo 的 tag / 分類介面
+func (w *StoryWorkflow) taxonomy() (Taxonomy, error) {
+	tr, ok := w.repo.(Taxonomy)
+	if !ok {
+		return nil, ErrTaxonomyUnsupported
+	}
+	return tr, nil
+}
+
+// authorWriter 回傳 repo 的作者寫入介面
+func (w *StoryWorkflow) authorWriter() (AuthorWriter, error) {
+	aw, ok := w.repo.(AuthorWriter)
+	if !ok {
+		return nil, ErrAuthorsUnsupported
+	}
+	return aw, nil
+}
+
+// trash 回傳 repo 的垃圾桶介面
+func (w *StoryWorkflow) trash() (StoryTrash, error) {
+	st, ok := w.repo.(StoryTrash)
+	if !ok {
+		return nil, ErrTrashUnsupported
+	}
+	return st, nil
+}
+
+// requireEditor 只允許編輯以上的角色執行 action
+func requireEditor(role StoryRole, action string) error {
+	return requireRole(role, StoryRoleEditor, action)
+}
+
+// requireRole 只允許 min 以上的角色執行 action
+func requireRole(role, min StoryRole, action string) error {
+	if !role.Includes(min) {
+		return fmt.Errorf("%w: %s cannot %s", ErrStoryTransitionForbidden, role, action)
+	}
+	return nil
+}
+
+// requireOwner 編輯以上的角色可處理所有 story；作者只能處理自己建立的 story
+func requireOwner(ctx context.Context, story *Story, role StoryRole, action string) error {
+	if role.Includes(StoryRoleEditor) {
+		return nil
+	}
+	if err := requireRole(role, StoryRoleAuthor, action); err != nil {
+		return err
+	}
+	if caller := storyCaller(ctx); caller == "" || story.OwnerID != caller {
+		return fmt.Errorf("%w: %s cannot %s of other users", ErrStoryTransitionForbidden, role, action)
+	}
+	return nil
+}
+
+// storyCaller 回傳 ctx 中 principal 的 subject，作為 story 的擁有者；沒有 principal 時為空字串
+func storyCaller(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+// revisions 回傳 repo 的版本紀錄介面
+func (w *StoryWorkflow) revisions() (RevisionReader, error) {
+	rr, ok := w.repo.(RevisionReader)
+	if !ok {
+		return nil, ErrRevisionsUnsupported
+	}
+	return rr, nil
+}
+
+// Transition moves the story with id to status to on behalf of role and
+// returns the updated story. Authors may only move their own stories.
+// Scheduling requires publishAt in the future; publishing directly
+// publishes now, and moving back to draft clears the publish time.
+func (w *StoryWorkflow) Transition(ctx context.Context, id, to string, publishAt *time.Time, role StoryRole) (*Story, error) {
+	var updated *Story
+	err := w.repo.WithTx(ctx, func(repo StoryRepository) error {
+		story, err := repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := CheckStoryTransition(story.Status, to); err != nil {
+			return err
+		}
+		if !role.CanTransition(story.Status, to) {
+			return fmt.Errorf("%w: %s cannot move %s → %s", ErrStoryTransitionForbidden, role, story.Status, to)
+		}
+		if err := requireOwner(ctx, story, role, "move stories"); err != nil {
+			return err
+		}
+
+		switch to {
+		case StoryStatusScheduled:
+			if publishAt == nil || !publishAt.After(time.Now()) {
+				return fmt.Errorf("%w: scheduling requires a future publishAt", ErrInvalidStoryTransition)
+			}
+			scheduled := publishAt.UTC()
+			story.PublishedAt = &scheduled
+		case StoryStatusPublished:
+			// 由 prepareStory 填入目前時間
+			story.PublishedAt = nil
+		case StoryStatusDraft:
+			story.PublishedAt = nil
+		}
+		story.Status = to
+		if err := repo.Update(ctx, story); err != nil {
+			return err
+		}
+		updated = story
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema.org article types for StoryJSONLD, selected with SITE_ARTICLE_TYPE.
+const (
+	ArticleTypeNews    = "NewsArticle"
+	ArticleTypeArticle = "Article"
+	ArticleTypeBlog    = "BlogPosting"
+)
+
+// maxJSONLDHeadlineLength 為 headline 的長度上限 (字數)，超過時 Google 不採用
+const maxJSONLDHeadlineLength = 110
+
+// StoryJSONLD is the schema.org article markup of a story page, to be
+// embedded as <script type="application/ld+json">. encoding/json escapes
+// <, > and &, so the marshalled value is safe to embed as is.
+type StoryJSONLD struct {
+	Context             string          `json:"@context"`
+	Type                string          `json:"@type"`
+	Headline            string          `json:"headline"`
+	Description         string          `json:"description,omitempty"`
+	Image               []string        `json:"image,omitempty"`
+	DatePublished       *time.Time      `json:"datePublished,omitempty"`
+	DateModified        time.Time       `json:"dateModified"`
+	Author              []JSONLDPerson  `json:"author,omitempty"`
+	Publisher           JSONLDPublisher `json:"publisher"`
+	MainEntityOfPage    *JSONLDThing    `json:"mainEntityOfPage,omitempty"`
+	URL                 string          `json:"url,omitempty"`
+	InLanguage          string          `json:"inLanguage,omitempty"`
+	ArticleSection      string          `json:"articleSection,omitempty"`
+	Keywords            string          `json:"keywords,omitempty"`
+	WordCount           int             `json:"wordCount,omitempty"`
+	IsAccessibleForFree bool            `json:"isAccessibleForFree"`
+}
+
+// JSONLDPerson is a schema.org Person, the author of a StoryJSONLD.
+type JSONLDPerson struct {
+	Type   string   `json:"@type"`
+	Name   string   `json:"name"`
+	Image  string   `json:"image,omitempty"`
+	SameAs []string `json:"sameAs,omitempty"` // 作者在其他網站的個人頁
+}
+
+// JSONLDPublisher is the schema.org Organization publishing the site.
+type JSONLDPublisher struct {
+	Type string       `json:"@type"`
+	Name string       `json:"name"`
+	URL  string       `json:"url,omitempty"`
+	Logo *JSONLDThing `json:"logo,omitempty"`
+}
+
+// JSONLDThing is a schema.org node referenced by URL, such as the
+// ImageObject of a logo or the WebPage of an article.
+type JSONLDThing struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// StructuredData returns the JSON-LD of story: a schema.org article of the
+// site's article type (SITE_ARTICLE_TYPE) published by the site (SITE_NAME,
+// SITE_LOGO). The image and description follow SocialMeta, so both agree.
+func (s *StoryService) StructuredData(ctx context.Context, story *Story) (*StoryJSONLD, error) {
+	articleType := s.site.ArticleType
+	if articleType == "" {
+		articleType = ArticleTypeNews
+	}
+	ld := &StoryJSONLD{
+		Context:             "https://schema.org",
+		Type:                articleType,
+		Headline:            jsonLDHeadline(story.Title),
+		Description:         socialDescription(story, ""),
+		DatePublished:       story.PublishedAt,
+		DateModified:        story.UpdatedAt,
+		Publisher:           JSONLDPublisher{Type: "Organization", Name: s.site.Title, URL: s.site.URL},
+		InLanguage:          s.Locale(story),
+		ArticleSection:      story.Section,
+		Keywords:            strings.Join(story.Tags, ", "),
+		WordCount:           story.WordCount,
+		IsAccessibleForFree: story.AccessTier() == StoryAccessFree,
+	}
+	if image, _ := s.socialImage(story); image != "" {
+		ld.Image = []string{image}
+	}
+	if s.site.Logo != "" {
+		ld.Publisher.Logo = &JSONLDThing{Type: "ImageObject", URL: s.absoluteURL(s.site.Logo)}
+	}
+	if s.site.URL != "" {
+		ld.URL = s.site.StoryURL(story.Slug)
+		ld.MainEntityOfPage = &JSONLDThing{Type: "WebPage", ID: ld.URL}
+	}
+
+	authors, err := s.Authors(ctx, story)
+	if err != nil {
+		return nil, err
+	}
+	for _, author := range authors {
+		person := JSONLDPerson{Type: "Person", Name: author.Name, Image: s.absoluteURL(author.Avatar)}
+		for _, link := range author.SocialLinks {
+			person.SameAs = append(person.SameAs, link.URL)
+		}
+		ld.Author = append(ld.Author, person)
+	}
+	return ld, nil
+}
+
+// jsonLDHeadline 將標題截斷至 maxJSONLDHeadlineLength 字
+func jsonLDHeadline(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if utf8.RuneCountInString(title) <= maxJSONLDHeadlineLength {
+		return title
+	}
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:maxJSONLDHeadlineLength-1])) + "…"
+}
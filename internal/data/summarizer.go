@@ -0,0 +1,104 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSummarizerModel is the model LLMSummarizer asks for when none is
+// configured.
+const DefaultSummarizerModel = "gpt-4o-mini"
+
+// llmSummaryMaxInput 為送給模型的內文長度上限 (字數)；llmSummaryMaxResponse 為回應的大小上限
+const (
+	llmSummaryMaxInput    = 12000
+	llmSummaryMaxResponse = 1 << 20
+)
+
+// llmSummaryPrompt 為摘要的指示；要求以文章的語言回覆，只回傳摘要本身
+const llmSummaryPrompt = "You write abstracts for a news site. Summarize the article below in two or three plain-text sentences, " +
+	"in the language the article is written in. Reply with the abstract only, without a heading, quotes or markup."
+
+// LLMSummarizer is a Summarizer backed by a chat completions API compatible
+// with OpenAI's (POST {"model", "messages"}, reading
+// choices[0].message.content), which most hosted and self-hosted LLM
+// servers provide. The story title and plain-text body are sent; long
+// bodies are cut to their beginning.
+type LLMSummarizer struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	model    string
+}
+
+// NewLLMSummarizer returns a summarizer posting to endpoint, the full chat
+// completions URL (e.g. https://api.openai.com/v1/chat/completions), with
+// apiKey as bearer token when set. An empty model means
+// DefaultSummarizerModel.
+func NewLLMSummarizer(endpoint, apiKey, model string) *LLMSummarizer {
+	if model == "" {
+		model = DefaultSummarizerModel
+	}
+	return &LLMSummarizer{client: &http.Client{Timeout: 60 * time.Second}, endpoint: endpoint, apiKey: apiKey, model: model}
+}
+
+// llmChatMessage 為 chat completions API 的訊息
+type llmChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Summarize asks the model for an abstract of story.
+func (s *LLMSummarizer) Summarize(ctx context.Context, story *Story) (string, error) {
+	text := []rune(excerptText(story.Body))
+	if len(text) > llmSummaryMaxInput {
+		text = text[:llmSummaryMaxInput]
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"model": s.model,
+		"messages": []llmChatMessage{
+			{Role: "system", Content: llmSummaryPrompt},
+			{Role: "user", Content: story.Title + "\n\n" + string(text)},
+		},
+		"temperature": 0.3,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("summarize: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("summarize: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var body struct {
+		Choices []struct {
+			Message llmChatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, llmSummaryMaxResponse)).Decode(&body); err != nil {
+		return "", fmt.Errorf("summarize: decode response: %w", err)
+	}
+	if len(body.Choices) == 0 || strings.TrimSpace(body.Choices[0].Message.Content) == "" {
+		return "", errors.New("summarize: empty response")
+	}
+	return strings.TrimSpace(body.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Taxonomy kinds. Stories reference tags by slug in Story.Tags and their
+// category by slug in Story.Section.
+const (
+	TermKindTag      = "tag"
+	TermKindCategory = "category"
+)
+
+var (
+	// ErrTermNotFound is returned when no tag or category matches the
+	// lookup.
+	ErrTermNotFound = errors.New("term not found")
+	// ErrTermSlugTaken is returned by CreateTerm and UpdateTerm when another
+	// term of the same kind already uses the slug.
+	ErrTermSlugTaken = errors.New("term slug already taken")
+	// ErrTermInUse is returned by DeleteTerm while stories use the term or,
+	// for categories, while it has subcategories. Merge it instead.
+	ErrTermInUse = errors.New("term is still in use")
+	// ErrInvalidTerm is returned (wrapped) for a term with an unknown kind,
+	// a missing slug or name, or an invalid parent.
+	ErrInvalidTerm = errors.New("invalid term")
+	// ErrTaxonomyUnsupported is returned when the story store does not
+	// store tags and categories.
+	ErrTaxonomyUnsupported = errors.New("story store does not support taxonomy")
+)
+
+// Term is a tag or a category. Categories form a hierarchy through Parent
+// (the parent category's slug, empty for top-level categories); tags are
+// flat. StoryCount is the number of published stories using the term,
+// filled in by listings and lookups.
+type Term struct {
+	Kind       string    `json:"kind"`
+	Slug       string    `json:"slug"`
+	Name       string    `json:"name"`
+	Parent     string    `json:"parent,omitempty"`
+	StoryCount int       `json:"storyCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Taxonomy is implemented by story repositories that also store tags and
+// categories. Callers type-assert a StoryRepository to it. Renames and
+// merges rewrite Story.Tags or Story.Section of every story using the term,
+// trashed ones included, and return the IDs of those stories; they do not
+// add story revisions.
+type Taxonomy interface {
+	// ListTerms returns the terms of kind ordered by name, then slug.
+	ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error)
+	// GetTerm returns the term of kind with slug, or ErrTermNotFound.
+	GetTerm(ctx context.Context, kind, slug string) (*Term, error)
+	// CreateTerm stores a new term, filling in timestamps.
+	CreateTerm(ctx context.Context, term *Term) error
+	// UpdateTerm replaces the term of term.Kind with slug. When term.Slug
+	// differs from slug the term is renamed and its stories re-linked.
+	UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error)
+	// MergeTerms moves every story from the term from into the term into
+	// (subcategories of a merged category move with it) and deletes from.
+	MergeTerms(ctx context.Context, kind, from, into string) ([]string, error)
+	// DeleteTerm deletes an unused term, or returns ErrTermInUse.
+	DeleteTerm(ctx context.Context, kind, slug string) error
+}
+
+// TermBatchReader is implemented by taxonomies that can look up many terms
+// of a kind at once. Callers use LoadTerms, which falls back to GetTerm for
+// taxonomies without it.
+type TermBatchReader interface {
+	// GetTerms returns the term of kind with each slug at the same index,
+	// or nil when there is none.
+	GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error)
+}
+
+// LoadTerms looks up slugs with tr's GetTerms when it implements
+// TermBatchReader, and one slug at a time otherwise. Terms not found are
+// nil.
+func LoadTerms(ctx context.Context, tr Taxonomy, kind string, slugs []string) ([]*Term, error) {
+	if br, ok := tr.(TermBatchReader); ok {
+		return br.GetTerms(ctx, kind, slugs)
+	}
+	terms := make([]*Term, len(slugs))
+	for i, slug := range slugs {
+		var err error
+		if terms[i], err = tr.GetTerm(ctx, kind, slug); err != nil && !errors.Is(err, ErrTermNotFound) {
+			return nil, err
+		}
+	}
+	return terms, nil
+}
+
+// defaultTermLimit 與 maxTermLimit 為 ListTerms 每頁筆數的預設值與上限
+const (
+	defaultTermLimit = 50
+	maxTermLimit     = 500
+)
+
+// termLimit 回傳套用預設值與上限後的筆數；儲存層允許多取一筆，供 StoryService 判斷是否有下一頁
+func termLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultTermLimit
+	case limit > maxTermLimit+1:
+		return maxTermLimit + 1
+	}
+	return limit
+}
+
+// ValidTermKind reports whether kind is TermKindTag or TermKindCategory.
+func ValidTermKind(kind string) bool {
+	return kind == TermKindTag || kind == TermKindCategory
+}
+
+// prepareTerm 在寫入前檢查欄位並補上時間欄位；parent 是否存在與是否形成循環由儲存層檢查
+func prepareTerm(term *Term, now time.Time) error {
+	switch {
+	case !ValidTermKind(term.Kind):
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidTerm, term.Kind)
+	case term.Slug == "" || term.Name == "":
+		return fmt.Errorf("%w: slug and name are required", ErrInvalidTerm)
+	case term.Parent != "" && term.Kind != TermKindCategory:
+		return fmt.Errorf("%w: only categories have a parent", ErrInvalidTerm)
+	case term.Parent == term.Slug && term.Parent != "":
+		return fmt.Errorf("%w: a category cannot be its own parent", ErrInvalidTerm)
+	}
+	if term.CreatedAt.IsZero() {
+		term.CreatedAt = now
+	}
+	term.UpdatedAt = now
+	term.StoryCount = 0
+	return nil
+}
+
+// checkTermParent 沿 parent 往上檢查，確認 parent 存在且 slug 不是自己的祖先；getParent 回傳某分類的 parent
+func checkTermParent(slug, parent string, getParent func(slug string) (string, error)) error {
+	for seen := 0; parent != ""; seen++ {
+		if parent == slug {
+			return fmt.Errorf("%w: parent would create a cycle", ErrInvalidTerm)
+		}
+		if seen > 100 {
+			return fmt.Errorf("%w: category hierarchy too deep", ErrInvalidTerm)
+		}
+		next, err := getParent(parent)
+		if errors.Is(err, ErrTermNotFound) {
+			return fmt.Errorf("%w: parent %q does not exist", ErrInvalidTerm, parent)
+		}
+		if err != nil {
+			return err
+		}
+		parent = next
+	}
+	return nil
+}
+
+// replaceTag 將 tags 中的 from 換成 to，保留順序並移除因此重複的 tag
+func replaceTag(tags []string, from, to string) []string {
+	replaced := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag == from {
+			tag = to
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			replaced = append(replaced, tag)
+		}
+	}
+	return replaced
+}
@@ -0,0 +1,260 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// termDocument 為 tag / 分類在 MongoDB 中的格式
+type termDocument struct {
+	Kind      string    `bson:"kind"`
+	Slug      string    `bson:"slug"`
+	Name      string    `bson:"name"`
+	Parent    string    `bson:"parent"`
+	CreatedAt time.Time `bson:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// term 將 document 轉回 Term，story 數另外計算
+func (d termDocument) term() Term {
+	return Term{Kind: d.Kind, Slug: d.Slug, Name: d.Name, Parent: d.Parent, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// termStoryFilter 回傳使用該 term 的 story 條件 (含垃圾桶中的 story)
+func termStoryFilter(kind, slug string) bson.M {
+	if kind == TermKindTag {
+		return bson.M{"tags": slug}
+	}
+	return bson.M{"section": slug}
+}
+
+func (r *MongoStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "slug", Value: 1}}).
+		SetLimit(int64(termLimit(limit))).SetSkip(int64(max(offset, 0)))
+	cursor, err := r.terms.Find(r.ctx(ctx), bson.M{"kind": kind}, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("list terms: %w", err)
+	}
+	var docs []termDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("list terms: %w", err)
+	}
+	terms := make([]Term, 0, len(docs))
+	for _, doc := range docs {
+		term := doc.term()
+		if err := r.countTermStories(ctx, &term); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+func (r *MongoStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var doc termDocument
+	err := r.terms.FindOne(r.ctx(ctx), bson.M{"kind": kind, "slug": slug}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTermNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get term: %w", err)
+	}
+	term := doc.term()
+	if err := r.countTermStories(ctx, &term); err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+func (r *MongoStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := prepareTerm(term, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := checkTermParent(term.Slug, term.Parent, r.termParent(ctx)); err != nil {
+		return err
+	}
+	_, err := r.terms.InsertOne(r.ctx(ctx), termDocument{
+		Kind: term.Kind, Slug: term.Slug, Name: term.Name, Parent: term.Parent, CreatedAt: term.CreatedAt, UpdatedAt: term.UpdatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrTermSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("create term: %w", err)
+	}
+	return nil
+}
+
+// UpdateTerm 不在 transaction 中時，改名、子分類與 story 的改寫不是原子操作
+func (r *MongoStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if err := prepareTerm(term, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if term.Parent == slug {
+		return nil, fmt.Errorf("%w: a category cannot be its own parent", ErrInvalidTerm)
+	}
+	if err := checkTermParent(slug, term.Parent, r.termParent(ctx)); err != nil {
+		return nil, err
+	}
+	var stored termDocument
+	err := r.terms.FindOneAndUpdate(r.ctx(ctx), bson.M{"kind": term.Kind, "slug": slug},
+		bson.M{"$set": bson.M{"slug": term.Slug, "name": term.Name, "parent": term.Parent, "updatedAt": term.UpdatedAt}}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTermNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrTermSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update term: %w", err)
+	}
+	term.CreatedAt = stored.CreatedAt
+
+	relinked := []string{}
+	if term.Slug != slug {
+		if _, err := r.terms.UpdateMany(r.ctx(ctx), bson.M{"kind": term.Kind, "parent": slug}, bson.M{"$set": bson.M{"parent": term.Slug}}); err != nil {
+			return nil, fmt.Errorf("rename parent of subcategories: %w", err)
+		}
+		if relinked, err = r.relinkStories(ctx, term.Kind, slug, term.Slug); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.countTermStories(ctx, term); err != nil {
+		return nil, err
+	}
+	return relinked, nil
+}
+
+// MergeTerms 不在 transaction 中時，各步驟不是原子操作；中途失敗時可再次合併
+func (r *MongoStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if from == into {
+		return nil, fmt.Errorf("%w: cannot merge a term into itself", ErrInvalidTerm)
+	}
+	n, err := r.terms.CountDocuments(r.ctx(ctx), bson.M{"kind": kind, "slug": bson.M{"$in": []string{from, into}}})
+	if err != nil {
+		return nil, fmt.Errorf("get terms: %w", err)
+	}
+	if n < 2 {
+		return nil, ErrTermNotFound
+	}
+	if kind == TermKindCategory {
+		if err := checkTermParent(from, into, r.termParent(ctx)); err != nil {
+			return nil, err
+		}
+		if _, err := r.terms.UpdateMany(r.ctx(ctx), bson.M{"kind": kind, "parent": from},
+			bson.M{"$set": bson.M{"parent": into, "updatedAt": time.Now().UTC()}}); err != nil {
+			return nil, fmt.Errorf("move subcategories: %w", err)
+		}
+	}
+	relinked, err := r.relinkStories(ctx, kind, from, into)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.terms.DeleteOne(r.ctx(ctx), bson.M{"kind": kind, "slug": from}); err != nil {
+		return nil, fmt.Errorf("delete merged term: %w", err)
+	}
+	return relinked, nil
+}
+
+// DeleteTerm 的檢查與刪除不是原子操作
+func (r *MongoStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.GetTerm(ctx, kind, slug); err != nil {
+		return err
+	}
+	stories, err := r.coll.CountDocuments(r.ctx(ctx), termStoryFilter(kind, slug), options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("count term stories: %w", err)
+	}
+	children, err := r.terms.CountDocuments(r.ctx(ctx), bson.M{"kind": kind, "parent": slug}, options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("count subcategories: %w", err)
+	}
+	if stories > 0 || children > 0 {
+		return ErrTermInUse
+	}
+	res, err := r.terms.DeleteOne(r.ctx(ctx), bson.M{"kind": kind, "slug": slug})
+	if err != nil {
+		return fmt.Errorf("delete term: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrTermNotFound
+	}
+	return nil
+}
+
+// relinkStories 將使用 from 的 story 改為使用 to，回傳被改寫的 story ID；tag 逐篇改寫以保留順序並移除重複
+func (r *MongoStoryRepository) relinkStories(ctx context.Context, kind, from, to string) ([]string, error) {
+	cursor, err := r.coll.Find(r.ctx(ctx), termStoryFilter(kind, from), options.Find().SetProjection(bson.M{"_id": 1, "tags": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("find term stories: %w", err)
+	}
+	var docs []storyDocument
+	if err := cursor.All(r.ctx(ctx), &docs); err != nil {
+		return nil, fmt.Errorf("find term stories: %w", err)
+	}
+	now := time.Now().UTC()
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		set := bson.M{"section": to, "updatedAt": now}
+		if kind == TermKindTag {
+			set = bson.M{"tags": replaceTag(doc.Tags, from, to), "updatedAt": now}
+		}
+		if _, err := r.coll.UpdateOne(r.ctx(ctx), bson.M{"_id": doc.ID}, bson.M{"$set": set}); err != nil {
+			return nil, fmt.Errorf("relink story %s: %w", doc.ID, err)
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, nil
+}
+
+// termParent 回傳查詢分類 parent 的函式，供 checkTermParent 使用
+func (r *MongoStoryRepository) termParent(ctx context.Context) func(slug string) (string, error) {
+	return func(slug string) (string, error) {
+		var doc termDocument
+		err := r.terms.FindOne(r.ctx(ctx), bson.M{"kind": TermKindCategory, "slug": slug}).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", ErrTermNotFound
+		}
+		if err != nil {
+			return "", fmt.Errorf("get parent category: %w", err)
+		}
+		return doc.Parent, nil
+	}
+}
+
+// countTermStories 計算 term 的已發布 story 數
+func (r *MongoStoryRepository) countTermStories(ctx context.Context, term *Term) error {
+	filter := termStoryFilter(term.Kind, term.Slug)
+	filter["status"] = StoryStatusPublished
+	filter["deletedAt"] = nil
+	n, err := r.coll.CountDocuments(r.ctx(ctx), filter)
+	if err != nil {
+		return fmt.Errorf("count term stories: %w", err)
+	}
+	term.StoryCount = int(n)
+	return nil
+}
@@ -0,0 +1,283 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// termSelect 查詢 taxonomy_terms 並計算已發布 story 數，欄位順序需與 scanTerm 一致
+const termSelect = `SELECT t.kind, t.slug, t.name, COALESCE(t.parent, ''), t.created_at, t.updated_at,
+	(SELECT count(*) FROM stories s WHERE s.status = 'published' AND s.deleted_at IS NULL
+		AND CASE t.kind WHEN 'tag' THEN s.tags @> jsonb_build_array(t.slug) ELSE s.section = t.slug END)
+	FROM taxonomy_terms t`
+
+func (r *PostgresStoryRepository) ListTerms(ctx context.Context, kind string, limit, offset int) ([]Term, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := r.q.QueryContext(ctx, termSelect+` WHERE t.kind = $1 ORDER BY t.name, t.slug LIMIT $2 OFFSET $3`, kind, termLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("list terms: %w", err)
+	}
+	defer rows.Close()
+
+	terms := []Term{}
+	for rows.Next() {
+		term, err := scanTerm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan term: %w", err)
+		}
+		terms = append(terms, *term)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list terms: %w", err)
+	}
+	return terms, nil
+}
+
+func (r *PostgresStoryRepository) GetTerm(ctx context.Context, kind, slug string) (*Term, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	term, err := scanTerm(r.q.QueryRowContext(ctx, termSelect+` WHERE t.kind = $1 AND t.slug = $2`, kind, slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTermNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get term: %w", err)
+	}
+	return term, nil
+}
+
+func (r *PostgresStoryRepository) GetTerms(ctx context.Context, kind string, slugs []string) ([]*Term, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := r.q.QueryContext(ctx, termSelect+` WHERE t.kind = $1 AND t.slug = ANY($2)`, kind, slugs)
+	if err != nil {
+		return nil, fmt.Errorf("get terms: %w", err)
+	}
+	defer rows.Close()
+
+	bySlug := map[string]*Term{}
+	for rows.Next() {
+		term, err := scanTerm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan term: %w", err)
+		}
+		bySlug[term.Slug] = term
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get terms: %w", err)
+	}
+	terms := make([]*Term, len(slugs))
+	for i, slug := range slugs {
+		terms[i] = bySlug[slug]
+	}
+	return terms, nil
+}
+
+func (r *PostgresStoryRepository) CreateTerm(ctx context.Context, term *Term) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := prepareTerm(term, time.Now().UTC()); err != nil {
+		return err
+	}
+	// 新的分類不可能是其他分類的祖先，只需確認 parent 存在
+	if err := checkTermParent(term.Slug, term.Parent, r.termParent(ctx)); err != nil {
+		return err
+	}
+	_, err := r.q.ExecContext(ctx, `INSERT INTO taxonomy_terms (kind, slug, name, parent, created_at, updated_at) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`,
+		term.Kind, term.Slug, term.Name, term.Parent, term.CreatedAt, term.UpdatedAt)
+	return termWriteError("create term", err)
+}
+
+func (r *PostgresStoryRepository) UpdateTerm(ctx context.Context, slug string, term *Term) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := prepareTerm(term, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	var relinked []string
+	err := r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		err := tx.q.QueryRowContext(ctx, `SELECT created_at FROM taxonomy_terms WHERE kind = $1 AND slug = $2 FOR UPDATE`, term.Kind, slug).Scan(&term.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTermNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock term: %w", err)
+		}
+		if term.Parent == slug {
+			return fmt.Errorf("%w: a category cannot be its own parent", ErrInvalidTerm)
+		}
+		if err := checkTermParent(slug, term.Parent, tx.termParent(ctx)); err != nil {
+			return err
+		}
+		// 子分類的 parent 由 foreign key 的 ON UPDATE CASCADE 一併改名
+		_, err = tx.q.ExecContext(ctx, `UPDATE taxonomy_terms SET slug = $3, name = $4, parent = NULLIF($5, ''), updated_at = $6 WHERE kind = $1 AND slug = $2`,
+			term.Kind, slug, term.Slug, term.Name, term.Parent, term.UpdatedAt)
+		if err := termWriteError("update term", err); err != nil {
+			return err
+		}
+		if term.Slug != slug {
+			if relinked, err = tx.relinkStories(ctx, term.Kind, slug, term.Slug); err != nil {
+				return err
+			}
+		}
+		return tx.countTermStories(ctx, term)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return relinked, nil
+}
+
+func (r *PostgresStoryRepository) MergeTerms(ctx context.Context, kind, from, into string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if from == into {
+		return nil, fmt.Errorf("%w: cannot merge a term into itself", ErrInvalidTerm)
+	}
+	var relinked []string
+	err := r.inTx(ctx, func(tx *PostgresStoryRepository) error {
+		var n int
+		err := tx.q.QueryRowContext(ctx, `SELECT count(*) FROM (SELECT 1 FROM taxonomy_terms WHERE kind = $1 AND slug IN ($2, $3) FOR UPDATE) locked`, kind, from, into).Scan(&n)
+		if err != nil {
+			return fmt.Errorf("lock terms: %w", err)
+		}
+		if n < 2 {
+			return ErrTermNotFound
+		}
+		if kind == TermKindCategory {
+			// into 不可為 from 的子孫，否則 from 的子分類移到 into 之下會形成循環
+			if err := checkTermParent(from, into, tx.termParent(ctx)); err != nil {
+				return err
+			}
+			if _, err := tx.q.ExecContext(ctx, `UPDATE taxonomy_terms SET parent = $3, updated_at = $4 WHERE kind = $1 AND parent = $2`,
+				kind, from, into, time.Now().UTC()); err != nil {
+				return fmt.Errorf("move subcategories: %w", err)
+			}
+		}
+		if relinked, err = tx.relinkStories(ctx, kind, from, into); err != nil {
+			return err
+		}
+		if _, err := tx.q.ExecContext(ctx, `DELETE FROM taxonomy_terms WHERE kind = $1 AND slug = $2`, kind, from); err != nil {
+			return fmt.Errorf("delete merged term: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return relinked, nil
+}
+
+func (r *PostgresStoryRepository) DeleteTerm(ctx context.Context, kind, slug string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// 垃圾桶中的 story 也算使用中，還原後才不會出現不存在的 tag / 分類
+	res, err := r.q.ExecContext(ctx, `DELETE FROM taxonomy_terms t WHERE t.kind = $1 AND t.slug = $2
+		AND NOT EXISTS (SELECT 1 FROM stories s WHERE CASE $1 WHEN 'tag' THEN s.tags @> jsonb_build_array($2::text) ELSE s.section = $2 END)
+		AND NOT EXISTS (SELECT 1 FROM taxonomy_terms c WHERE c.kind = $1 AND c.parent = $2)`, kind, slug)
+	if err != nil {
+		return fmt.Errorf("delete term: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if _, err := r.GetTerm(ctx, kind, slug); err != nil {
+		return err
+	}
+	return ErrTermInUse
+}
+
+// relinkStories 將使用 from 的 story 改為使用 to，回傳被改寫的 story ID；tag 保留原本的順序並移除重複
+func (r *PostgresStoryRepository) relinkStories(ctx context.Context, kind, from, to string) ([]string, error) {
+	query := `UPDATE stories SET section = $2, updated_at = $3 WHERE section = $1 RETURNING id`
+	if kind == TermKindTag {
+		query = `UPDATE stories SET updated_at = $3, tags = (
+			SELECT COALESCE(jsonb_agg(tag ORDER BY pos), '[]'::jsonb) FROM (
+				SELECT CASE WHEN e.tag = $1::text THEN $2::text ELSE e.tag END AS tag, min(e.pos) AS pos
+				FROM jsonb_array_elements_text(stories.tags) WITH ORDINALITY AS e(tag, pos) GROUP BY 1
+			) relinked)
+			WHERE tags @> jsonb_build_array($1::text) RETURNING id`
+	}
+	rows, err := r.q.QueryContext(ctx, query, from, to, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("relink stories: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("relink stories: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("relink stories: %w", err)
+	}
+	return ids, nil
+}
+
+// termParent 回傳查詢分類 parent 的函式，供 checkTermParent 使用
+func (r *PostgresStoryRepository) termParent(ctx context.Context) func(slug string) (string, error) {
+	return func(slug string) (string, error) {
+		var parent string
+		err := r.q.QueryRowContext(ctx, `SELECT COALESCE(parent, '') FROM taxonomy_terms WHERE kind = $1 AND slug = $2`, TermKindCategory, slug).Scan(&parent)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrTermNotFound
+		}
+		if err != nil {
+			return "", fmt.Errorf("get parent category: %w", err)
+		}
+		return parent, nil
+	}
+}
+
+// countTermStories 重新讀取 term 的已發布 story 數
+func (r *PostgresStoryRepository) countTermStories(ctx context.Context, term *Term) error {
+	stored, err := r.GetTerm(ctx, term.Kind, term.Slug)
+	if err != nil {
+		return err
+	}
+	term.StoryCount = stored.StoryCount
+	return nil
+}
+
+// scanTerm 依 termSelect 的順序讀取一個 term
+func scanTerm(row rowScanner) (*Term, error) {
+	var term Term
+	if err := row.Scan(&term.Kind, &term.Slug, &term.Name, &term.Parent, &term.CreatedAt, &term.UpdatedAt, &term.StoryCount); err != nil {
+		return nil, err
+	}
+	return &term, nil
+}
+
+// termWriteError 將 slug 重複轉為 ErrTermSlugTaken、parent 不存在轉為 ErrInvalidTerm；err 為 nil 時回傳 nil
+func termWriteError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return ErrTermSlugTaken
+		case pgForeignKeyViolation:
+			return fmt.Errorf("%w: parent does not exist", ErrInvalidTerm)
+		}
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TOCEntry is a heading of a story body in its table of contents. ID is
+// the anchor of the heading in the rendered body (bodyHtml), so apps can
+// jump to #ID; Children are the headings nested under it.
+type TOCEntry struct {
+	ID       string     `json:"id"`
+	Text     string     `json:"text"`
+	Level    int        `json:"level"`
+	Children []TOCEntry `json:"children,omitempty"`
+}
+
+// maxTOCAnchorLength 為由標題產生的 anchor 長度上限 (字數)
+const maxTOCAnchorLength = 64
+
+// AnchorHeadings gives the h2–h6 headings of body, a rendered and
+// sanitized story body, an id attribute and returns the body with the
+// anchors together with the table of contents. Headings keep an id they
+// already have; other anchors are derived from the heading text (lower
+// case, runs of other characters as "-"; Chinese and Japanese are kept),
+// with -2, -3 … appended to repeated texts, so they stay the same across
+// renders unless the headings change. A heading is nested under the
+// closest preceding heading of a higher level.
+func AnchorHeadings(body string) (string, []TOCEntry) {
+	if !strings.Contains(body, "<h") {
+		return body, nil
+	}
+	var (
+		sb    strings.Builder
+		head  strings.Builder // 目前標題開始標籤 "<hN" 之後的內容，標題結束時加上 id 寫入 sb
+		text  strings.Builder
+		flat  []TOCEntry
+		used  = map[string]bool{}
+		level int // 目前所在標題的層級，0 表示不在標題中
+		ownID string
+	)
+	for s := body; len(s) > 0; {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			i = len(s)
+		}
+		out := &sb
+		if level > 0 {
+			out = &head
+			text.WriteString(s[:i])
+		}
+		out.WriteString(s[:i])
+		if i == len(s) {
+			break
+		}
+		tag, n := readHTMLTag(s[i:])
+		raw := s[i : i+n]
+		s = s[i+n:]
+		if l := headingLevel(tag.name); l > 0 && level == 0 && tag.kind == htmlTagStart {
+			level, ownID = l, ""
+			for _, attr := range tag.attrs {
+				if attr.name == "id" {
+					ownID = attr.value
+				}
+			}
+			head.Reset()
+			head.WriteString(raw[3:]) // "<hN" 之後的屬性
+			text.Reset()
+			continue
+		}
+		out.WriteString(raw)
+		if level == 0 || tag.kind != htmlTagEnd || headingLevel(tag.name) != level {
+			continue
+		}
+		// 標題結束：產生 anchor 並加入開始標籤
+		label := strings.Join(strings.Fields(html.UnescapeString(stripHTMLTags(text.String()))), " ")
+		sb.WriteString("<h" + strconv.Itoa(level))
+		id := ownID
+		if id != "" {
+			used[id] = true
+		} else {
+			id = tocAnchor(label, used)
+			sb.WriteString(` id="` + html.EscapeString(id) + `"`)
+		}
+		sb.WriteString(head.String())
+		if label != "" {
+			flat = append(flat, TOCEntry{ID: id, Text: label, Level: level})
+		}
+		level = 0
+	}
+	if level > 0 {
+		// 沒有結束標籤的標題原樣輸出
+		sb.WriteString("<h" + strconv.Itoa(level) + head.String())
+	}
+	return sb.String(), nestTOC(flat)
+}
+
+// headingLevel 回傳 h2–h6 的層級；其他元素 (含 h1，標題為頁面的 h1) 回傳 0
+func headingLevel(name string) int {
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '2' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 0
+}
+
+// tocAnchor 由標題文字產生 anchor，重複時加上序號；沒有可用的字元時為 section
+func tocAnchor(text string, used map[string]bool) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if sb.Len() >= maxTOCAnchorLength*4 {
+			break
+		}
+	}
+	base := []rune(sb.String())
+	if len(base) > maxTOCAnchorLength {
+		base = base[:maxTOCAnchorLength]
+	}
+	anchor := strings.Trim(string(base), "-")
+	if anchor == "" {
+		anchor = "section"
+	}
+	id := anchor
+	for n := 2; used[id]; n++ {
+		id = anchor + "-" + strconv.Itoa(n)
+	}
+	used[id] = true
+	return id
+}
+
+// nestTOC 依層級將標題排成樹狀：每個標題放在前一個層級較高的標題之下
+func nestTOC(flat []TOCEntry) []TOCEntry {
+	var build func(i, parentLevel int) ([]TOCEntry, int)
+	build = func(i, parentLevel int) ([]TOCEntry, int) {
+		var entries []TOCEntry
+		for i < len(flat) && flat[i].Level > parentLevel {
+			entry := flat[i]
+			entry.Children, i = build(i+1, entry.Level)
+			entries = append(entries, entry)
+		}
+		return entries, i
+	}
+	entries, _ := build(0, 0)
+	return entries
+}
@@ -0,0 +1,374 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WordPressSite is the content of a WordPress site read by ReadWordPressWXR
+// or WordPressClient.Fetch, in the form ImportWordPress maps into stories.
+// Slugs are URL-decoded.
+type WordPressSite struct {
+	Authors    []WordPressAuthor
+	Categories []WordPressTerm
+	Tags       []WordPressTerm
+	Posts      []WordPressPost
+}
+
+// WordPressAuthor is a WordPress user. Slug is the user's login in a WXR
+// export and the user's slug in the REST API.
+type WordPressAuthor struct {
+	ID     int
+	Slug   string
+	Name   string
+	Bio    string
+	Avatar string
+}
+
+// WordPressTerm is a WordPress category or tag. Parent is the slug of the
+// parent category.
+type WordPressTerm struct {
+	ID     int
+	Slug   string
+	Name   string
+	Parent string
+}
+
+// WordPressPost is a WordPress post. Content is HTML, Status the WordPress
+// post status ("publish", "future", "draft", "pending", "private", ...),
+// Date the publish time in UTC (zero when WordPress has none), Author the
+// author's WordPressAuthor.Slug and Categories and Tags are term slugs.
+// FeaturedImage is the URL of the featured image.
+type WordPressPost struct {
+	ID            int
+	Slug          string
+	Title         string
+	Content       string
+	Excerpt       string
+	Status        string
+	Date          time.Time
+	Author        string
+	Categories    []string
+	Tags          []string
+	FeaturedImage string
+}
+
+// wxrNamespacePrefix 為 WXR 各版本 (1.0–1.2) wp: namespace 的共同前綴
+const wxrNamespacePrefix = "http://wordpress.org/export/"
+
+// wxrDocument 為 WXR 匯出檔中用到的部分；wp: 元素只比對名稱，namespace 隨 WXR 版本而不同
+type wxrDocument struct {
+	Channel struct {
+		Authors []struct {
+			ID          int    `xml:"author_id"`
+			Login       string `xml:"author_login"`
+			DisplayName string `xml:"author_display_name"`
+		} `xml:"author"`
+		Categories []struct {
+			XMLName  xml.Name
+			ID       int    `xml:"term_id"`
+			Nicename string `xml:"category_nicename"`
+			Parent   string `xml:"category_parent"`
+			Name     string `xml:"cat_name"`
+		} `xml:"category"`
+		Tags []struct {
+			ID   int    `xml:"term_id"`
+			Slug string `xml:"tag_slug"`
+			Name string `xml:"tag_name"`
+		} `xml:"tag"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// wxrItem 為 WXR 中的一個 item (文章、頁面、附件等)；content:encoded 與 excerpt:encoded 同名，依 namespace 區分
+type wxrItem struct {
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator"`
+	PostID   int    `xml:"post_id"`
+	DateGMT  string `xml:"post_date_gmt"`
+	Name     string `xml:"post_name"`
+	Status   string `xml:"status"`
+	PostType string `xml:"post_type"`
+	Attached string `xml:"attachment_url"`
+	Encoded  []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:"encoded"`
+	Terms []struct {
+		Domain   string `xml:"domain,attr"`
+		Nicename string `xml:"nicename,attr"`
+	} `xml:"category"`
+	Meta []struct {
+		Key   string `xml:"meta_key"`
+		Value string `xml:"meta_value"`
+	} `xml:"postmeta"`
+}
+
+// ReadWordPressWXR reads a WordPress export file (WXR, Tools → Export in
+// the WordPress admin). Only posts are kept; pages, attachments and other
+// post types are dropped, and attachments only resolve featured images.
+func ReadWordPressWXR(r io.Reader) (*WordPressSite, error) {
+	var doc wxrDocument
+	dec := xml.NewDecoder(r)
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse WXR: %w", err)
+	}
+
+	// 作者與 term 的名稱在 WordPress 中以 HTML entity 儲存，放在 CDATA 中原樣匯出
+	site := &WordPressSite{}
+	for _, a := range doc.Channel.Authors {
+		site.Authors = append(site.Authors, WordPressAuthor{ID: a.ID, Slug: wordPressSlug(a.Login), Name: html.UnescapeString(a.DisplayName)})
+	}
+	for _, c := range doc.Channel.Categories {
+		// RSS 本身的 <category> 沒有 wp: namespace
+		if !strings.HasPrefix(c.XMLName.Space, wxrNamespacePrefix) {
+			continue
+		}
+		site.Categories = append(site.Categories, WordPressTerm{ID: c.ID, Slug: wordPressSlug(c.Nicename), Name: html.UnescapeString(c.Name), Parent: wordPressSlug(c.Parent)})
+	}
+	for _, t := range doc.Channel.Tags {
+		site.Tags = append(site.Tags, WordPressTerm{ID: t.ID, Slug: wordPressSlug(t.Slug), Name: html.UnescapeString(t.Name)})
+	}
+
+	// 精選圖片以 _thumbnail_id 指向附件
+	attachments := map[string]string{}
+	for _, item := range doc.Channel.Items {
+		if item.PostType == "attachment" {
+			attachments[strconv.Itoa(item.PostID)] = item.Attached
+		}
+	}
+	for _, item := range doc.Channel.Items {
+		if item.PostType != "post" {
+			continue
+		}
+		post := WordPressPost{ID: item.PostID, Slug: wordPressSlug(item.Name), Title: item.Title, Status: item.Status,
+			Author: wordPressSlug(item.Creator), Categories: []string{}, Tags: []string{}}
+		if t, err := time.Parse(time.DateTime, item.DateGMT); err == nil {
+			post.Date = t
+		}
+		for _, encoded := range item.Encoded {
+			if strings.Contains(encoded.XMLName.Space, "excerpt") {
+				post.Excerpt = encoded.Value
+			} else {
+				post.Content = encoded.Value
+			}
+		}
+		for _, term := range item.Terms {
+			switch term.Domain {
+			case "category":
+				post.Categories = append(post.Categories, wordPressSlug(term.Nicename))
+			case "post_tag":
+				post.Tags = append(post.Tags, wordPressSlug(term.Nicename))
+			}
+		}
+		for _, meta := range item.Meta {
+			if meta.Key == "_thumbnail_id" {
+				post.FeaturedImage = attachments[meta.Value]
+			}
+		}
+		site.Posts = append(site.Posts, post)
+	}
+	return site, nil
+}
+
+// WordPressClient reads a WordPress site through its REST API
+// (/wp-json/wp/v2). Without credentials only published posts and the
+// authors of published posts are visible; with a username and an
+// application password it also reads drafts, pending, scheduled and private
+// posts.
+type WordPressClient struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewWordPressClient returns a client for the WordPress site at siteURL
+// (e.g. "https://example.com"). username and password are optional.
+func NewWordPressClient(siteURL, username, password string) *WordPressClient {
+	return &WordPressClient{url: strings.TrimRight(siteURL, "/"), username: username, password: password,
+		client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// wordPressPageSize 為每次向 REST API 取得的筆數 (WordPress 的上限)
+const wordPressPageSize = 100
+
+// wpRendered 為 REST API 中 {"rendered": "..."} 形式的欄位
+type wpRendered struct {
+	Rendered string `json:"rendered"`
+}
+
+// wpUser、wpTerm、wpPost 為 REST API 回應中用到的欄位
+type wpUser struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Slug        string            `json:"slug"`
+	Description string            `json:"description"`
+	AvatarURLs  map[string]string `json:"avatar_urls"`
+}
+
+type wpTerm struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Parent int    `json:"parent"`
+}
+
+type wpPost struct {
+	ID         int        `json:"id"`
+	DateGMT    string     `json:"date_gmt"`
+	Slug       string     `json:"slug"`
+	Status     string     `json:"status"`
+	Title      wpRendered `json:"title"`
+	Content    wpRendered `json:"content"`
+	Excerpt    wpRendered `json:"excerpt"`
+	Author     int        `json:"author"`
+	Categories []int      `json:"categories"`
+	Tags       []int      `json:"tags"`
+	Embedded   struct {
+		FeaturedMedia []struct {
+			SourceURL string `json:"source_url"`
+		} `json:"wp:featuredmedia"`
+	} `json:"_embedded"`
+}
+
+// Fetch reads every author, category, tag and post of the site.
+func (c *WordPressClient) Fetch(ctx context.Context) (*WordPressSite, error) {
+	users, err := fetchWordPress[wpUser](ctx, c, "users", nil)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := fetchWordPress[wpTerm](ctx, c, "categories", nil)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := fetchWordPress[wpTerm](ctx, c, "tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"_embed": {"wp:featuredmedia"}}
+	if c.username != "" {
+		query.Set("status", "publish,future,draft,pending,private")
+	}
+	posts, err := fetchWordPress[wpPost](ctx, c, "posts", query)
+	if err != nil {
+		return nil, err
+	}
+
+	site := &WordPressSite{}
+	authorSlugs := map[int]string{}
+	for _, u := range users {
+		author := WordPressAuthor{ID: u.ID, Slug: wordPressSlug(u.Slug), Name: u.Name, Bio: u.Description}
+		// avatar_urls 以像素為 key，取最大的一張
+		size := 0
+		for key, avatar := range u.AvatarURLs {
+			if n, _ := strconv.Atoi(key); n > size {
+				size, author.Avatar = n, avatar
+			}
+		}
+		authorSlugs[u.ID] = author.Slug
+		site.Authors = append(site.Authors, author)
+	}
+	categorySlugs := map[int]string{}
+	for _, t := range categories {
+		categorySlugs[t.ID] = wordPressSlug(t.Slug)
+	}
+	for _, t := range categories {
+		site.Categories = append(site.Categories, WordPressTerm{ID: t.ID, Slug: categorySlugs[t.ID], Name: html.UnescapeString(t.Name), Parent: categorySlugs[t.Parent]})
+	}
+	tagSlugs := map[int]string{}
+	for _, t := range tags {
+		tagSlugs[t.ID] = wordPressSlug(t.Slug)
+		site.Tags = append(site.Tags, WordPressTerm{ID: t.ID, Slug: tagSlugs[t.ID], Name: html.UnescapeString(t.Name)})
+	}
+	for _, p := range posts {
+		post := WordPressPost{ID: p.ID, Slug: wordPressSlug(p.Slug), Title: html.UnescapeString(p.Title.Rendered),
+			Content: p.Content.Rendered, Excerpt: wordPressText(p.Excerpt.Rendered), Status: p.Status,
+			Author: authorSlugs[p.Author], Categories: []string{}, Tags: []string{}}
+		if t, err := time.Parse("2006-01-02T15:04:05", p.DateGMT); err == nil {
+			post.Date = t
+		}
+		for _, id := range p.Categories {
+			if slug, ok := categorySlugs[id]; ok {
+				post.Categories = append(post.Categories, slug)
+			}
+		}
+		for _, id := range p.Tags {
+			if slug, ok := tagSlugs[id]; ok {
+				post.Tags = append(post.Tags, slug)
+			}
+		}
+		if len(p.Embedded.FeaturedMedia) > 0 {
+			post.FeaturedImage = p.Embedded.FeaturedMedia[0].SourceURL
+		}
+		site.Posts = append(site.Posts, post)
+	}
+	return site, nil
+}
+
+// fetchWordPress 依 X-WP-TotalPages 逐頁取得 /wp-json/wp/v2/<resource> 的所有資料
+func fetchWordPress[T any](ctx context.Context, c *WordPressClient, resource string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("per_page", strconv.Itoa(wordPressPageSize))
+	all := []T{}
+	for page, pages := 1, 1; page <= pages; page++ {
+		query.Set("page", strconv.Itoa(page))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/wp-json/wp/v2/"+resource+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "go-story-import")
+		if c.username != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetch wordpress %s: %w", resource, err)
+		}
+		if resp.StatusCode/100 != 2 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			return nil, fmt.Errorf("fetch wordpress %s: %s: %s", resource, resp.Status, body)
+		}
+		var items []T
+		err = json.NewDecoder(resp.Body).Decode(&items)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode wordpress %s: %w", resource, err)
+		}
+		all = append(all, items...)
+		if n, err := strconv.Atoi(resp.Header.Get("X-WP-TotalPages")); err == nil {
+			pages = n
+		}
+	}
+	return all, nil
+}
+
+// wordPressSlug 將 WordPress 以百分比編碼儲存的 slug (例如中文) 還原
+func wordPressSlug(slug string) string {
+	if decoded, err := url.PathUnescape(slug); err == nil {
+		return decoded
+	}
+	return slug
+}
+
+// wpTagPattern 比對 HTML tag，用於將 REST API 回傳的摘要轉為純文字
+var wpTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// wordPressText 移除 HTML tag 並還原 entity
+func wordPressText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(wpTagPattern.ReplaceAllString(s, "")))
+}
@@ -0,0 +1,223 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Actions in a WordPressMapping.
+const (
+	WordPressActionCreated  = "created"
+	WordPressActionUpdated  = "updated"
+	WordPressActionExisting = "existing"
+	WordPressActionSkipped  = "skipped"
+	WordPressActionFailed   = "failed"
+)
+
+// wordPressStatuses 將 WordPress 的文章狀態對應到 story 狀態；其他狀態 (auto-draft、trash 等) 不匯入
+var wordPressStatuses = map[string]string{
+	"publish": StoryStatusPublished,
+	"future":  StoryStatusScheduled,
+	"pending": StoryStatusInReview,
+	"draft":   StoryStatusDraft,
+	"private": StoryStatusArchived,
+}
+
+// WordPressImportReport maps every WordPress author, category, tag and post
+// to what ImportWordPress did with it. Stories is the report of the story
+// writes; its line numbers are positions in WordPressSite.Posts.
+type WordPressImportReport struct {
+	DryRun     bool               `json:"dryRun"`
+	Authors    []WordPressMapping `json:"authors"`
+	Categories []WordPressMapping `json:"categories"`
+	Tags       []WordPressMapping `json:"tags"`
+	Posts      []WordPressMapping `json:"posts"`
+	Stories    *StoryImportReport `json:"stories"`
+}
+
+// WordPressMapping maps a WordPress object to the author (ID) or story (ID)
+// or term (Slug) it was imported as. Action is one of the WordPressAction*
+// constants; in a dry run it is what the import would do, and IDs of
+// authors and stories to be created are placeholders.
+type WordPressMapping struct {
+	WordPressID int    `json:"wordpressId"`
+	ID          string `json:"id,omitempty"`
+	Slug        string `json:"slug"`
+	Action      string `json:"action"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ImportWordPress imports site into repo. Authors are matched to existing
+// authors by slug and created otherwise; categories (with their parents)
+// and tags likewise. Posts become stories upserted by slug through the same
+// validation and batched writes as ImportStories: the status maps publish →
+// published, future → scheduled, pending → in_review, draft → draft and
+// private → archived (other posts are skipped), the first category becomes
+// the section, the featured image the cover image and the excerpt the
+// summary. Content is kept as HTML and images keep their WordPress URLs.
+//
+// With opts.DryRun nothing is written. Stores without authors or taxonomy
+// skip them and import stories without authors or terms.
+func ImportWordPress(ctx context.Context, repo StoryRepository, site *WordPressSite, opts StoryImportOptions) (*WordPressImportReport, error) {
+	imp := newStoryImport(repo, opts)
+	report := &WordPressImportReport{DryRun: opts.DryRun, Stories: imp.report}
+
+	authorIDs, err := imp.importWordPressAuthors(ctx, site.Authors, report)
+	if err != nil {
+		return report, err
+	}
+	if report.Categories, err = imp.importWordPressTerms(ctx, TermKindCategory, site.Categories); err != nil {
+		return report, err
+	}
+	if report.Tags, err = imp.importWordPressTerms(ctx, TermKindTag, site.Tags); err != nil {
+		return report, err
+	}
+
+	// 依 slug 找出既有的 story，新的 story 先指定 ID，寫入後的對應即為確定
+	report.Posts = make([]WordPressMapping, len(site.Posts))
+	lines := map[int]int{}
+	for i, post := range site.Posts {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		mapping := &report.Posts[i]
+		*mapping = WordPressMapping{WordPressID: post.ID, Slug: post.Slug}
+		if mapping.Slug == "" {
+			mapping.Slug = "wp-" + strconv.Itoa(post.ID)
+		}
+		status, ok := wordPressStatuses[post.Status]
+		if !ok {
+			mapping.Action, mapping.Error = WordPressActionSkipped, fmt.Sprintf("post status %q is not imported", post.Status)
+			continue
+		}
+		story := Story{Slug: mapping.Slug, Title: post.Title, Summary: post.Excerpt, Body: post.Content, Status: status,
+			Tags: post.Tags, AuthorIDs: []string{}, CoverImage: post.FeaturedImage}
+		if len(post.Categories) > 0 {
+			story.Section = post.Categories[0]
+		}
+		if id, ok := authorIDs[post.Author]; ok {
+			story.AuthorIDs = append(story.AuthorIDs, id)
+		}
+		if !post.Date.IsZero() {
+			date := post.Date
+			story.CreatedAt = date
+			if status != StoryStatusDraft && status != StoryStatusInReview {
+				story.PublishedAt = &date
+			}
+		}
+		existing, err := repo.GetBySlug(ctx, story.Slug)
+		switch {
+		case err == nil:
+			story.ID, mapping.Action = existing.ID, WordPressActionUpdated
+		case errors.Is(err, ErrStoryNotFound):
+			story.ID, mapping.Action = newUUID(), WordPressActionCreated
+		default:
+			return report, err
+		}
+		mapping.ID = story.ID
+		lines[i+1] = i
+		imp.add(ctx, i+1, story)
+	}
+	imp.flush(ctx)
+
+	for _, e := range imp.report.Errors {
+		if i, ok := lines[e.Line]; ok {
+			report.Posts[i].Action, report.Posts[i].Error = WordPressActionFailed, e.Error
+		}
+	}
+	return report, nil
+}
+
+// importWordPressAuthors 依 slug 對應或建立作者，回傳 WordPress 作者 slug 對應的作者 ID
+func (imp *storyImport) importWordPressAuthors(ctx context.Context, authors []WordPressAuthor, report *WordPressImportReport) (map[string]string, error) {
+	ids := map[string]string{}
+	report.Authors = make([]WordPressMapping, len(authors))
+	ar, readOK := imp.repo.(AuthorReader)
+	aw, writeOK := imp.repo.(AuthorWriter)
+	for i, wp := range authors {
+		mapping := &report.Authors[i]
+		*mapping = WordPressMapping{WordPressID: wp.ID, Slug: wp.Slug}
+		if !readOK || !writeOK {
+			mapping.Action, mapping.Error = WordPressActionSkipped, ErrAuthorsUnsupported.Error()
+			continue
+		}
+		existing, err := ar.GetAuthorBySlug(ctx, wp.Slug)
+		switch {
+		case err == nil:
+			mapping.ID, mapping.Action = existing.ID, WordPressActionExisting
+		case !errors.Is(err, ErrAuthorNotFound):
+			return nil, err
+		case imp.opts.DryRun:
+			mapping.ID, mapping.Action = newUUID(), WordPressActionCreated
+			imp.pendingAuthors[mapping.ID] = true
+		default:
+			author := &Author{Slug: wp.Slug, Name: wp.Name, Bio: wp.Bio, Avatar: wp.Avatar}
+			if author.Name == "" {
+				author.Name = wp.Slug
+			}
+			if err := aw.CreateAuthor(ctx, author); err != nil {
+				mapping.Action, mapping.Error = WordPressActionFailed, err.Error()
+				continue
+			}
+			mapping.ID, mapping.Action = author.ID, WordPressActionCreated
+		}
+		ids[wp.Slug] = mapping.ID
+	}
+	return ids, nil
+}
+
+// importWordPressTerms 依 slug 對應或建立 tag / 分類；分類先建立 parent
+func (imp *storyImport) importWordPressTerms(ctx context.Context, kind string, terms []WordPressTerm) ([]WordPressMapping, error) {
+	mappings := make([]WordPressMapping, len(terms))
+	tx, ok := imp.repo.(Taxonomy)
+	index := make(map[string]int, len(terms))
+	for i, wp := range terms {
+		mappings[i] = WordPressMapping{WordPressID: wp.ID, Slug: wp.Slug}
+		index[wp.Slug] = i
+	}
+
+	var importTerm func(i int, depth int) error
+	importTerm = func(i int, depth int) error {
+		wp, mapping := terms[i], &mappings[i]
+		if mapping.Action != "" {
+			return nil
+		}
+		if !ok {
+			mapping.Action, mapping.Error = WordPressActionSkipped, ErrTaxonomyUnsupported.Error()
+			return nil
+		}
+		if parent, found := index[wp.Parent]; found && wp.Parent != "" && depth < len(terms) {
+			if err := importTerm(parent, depth+1); err != nil {
+				return err
+			}
+		}
+		_, err := tx.GetTerm(ctx, kind, wp.Slug)
+		switch {
+		case err == nil:
+			mapping.Action = WordPressActionExisting
+		case !errors.Is(err, ErrTermNotFound):
+			return err
+		case imp.opts.DryRun:
+			mapping.Action = WordPressActionCreated
+		default:
+			term := &Term{Kind: kind, Slug: wp.Slug, Name: wp.Name, Parent: wp.Parent}
+			if term.Name == "" {
+				term.Name = wp.Slug
+			}
+			if err := tx.CreateTerm(ctx, term); err != nil {
+				mapping.Action, mapping.Error = WordPressActionFailed, err.Error()
+				return nil
+			}
+			mapping.Action = WordPressActionCreated
+		}
+		return nil
+	}
+	for i := range terms {
+		if err := importTerm(i, 0); err != nil {
+			return nil, err
+		}
+	}
+	return mappings, nil
+}
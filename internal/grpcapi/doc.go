@@ -0,0 +1,12 @@
+// Package grpcapi serves the story read API (proto/story/v1/story.proto)
+// over gRPC on top of data.StoryService, the same service layer used by the
+// GraphQL and REST handlers.
+//
+// The storypb package is generated from the proto and committed; after
+// changing the proto, regenerate it with protoc, protoc-gen-go and
+// protoc-gen-go-grpc:
+//
+//	go generate ./internal/grpcapi
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=module=go-story/internal/grpcapi --go-grpc_out=. --go-grpc_opt=module=go-story/internal/grpcapi story/v1/story.proto
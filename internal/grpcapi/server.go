@@ -0,0 +1,198 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-story/internal/data"
+	"go-story/internal/grpcapi/storypb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// streamPageSize 為 StreamStories 每次向儲存層讀取的筆數
+const streamPageSize = 100
+
+// Server implements storypb.StoryServiceServer.
+type Server struct {
+	storypb.UnimplementedStoryServiceServer
+	stories *data.StoryService
+}
+
+// Register registers the story service on s.
+func Register(s *grpc.Server, stories *data.StoryService) {
+	storypb.RegisterStoryServiceServer(s, &Server{stories: stories})
+}
+
+func (s *Server) GetStory(ctx context.Context, req *storypb.GetStoryRequest) (*storypb.Story, error) {
+	if req.GetId() == "" && req.GetSlug() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id or slug is required")
+	}
+	story, err := s.stories.Story(ctx, req.GetId(), req.GetSlug())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProtoStory(story), nil
+}
+
+func (s *Server) ListStories(ctx context.Context, req *storypb.ListStoriesRequest) (*storypb.ListStoriesResponse, error) {
+	if req.GetLimit() < 0 || req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.stories.StoriesPage(ctx, req.GetQuery(), opts)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &storypb.ListStoriesResponse{
+		Stories:     make([]*storypb.Story, 0, len(page.Stories)),
+		HasNextPage: page.HasNextPage,
+	}
+	if page.HasNextPage {
+		resp.NextCursor = page.EndCursor
+	}
+	for i := range page.Stories {
+		resp.Stories = append(resp.Stories, toProtoStory(&page.Stories[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) StreamStories(req *storypb.ListStoriesRequest, stream storypb.StoryService_StreamStoriesServer) error {
+	if req.GetOffset() < 0 {
+		return status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+	opts, err := listOptions(req)
+	if err != nil {
+		return err
+	}
+	opts.Limit = streamPageSize
+	for {
+		page, err := s.stories.StoriesPage(stream.Context(), req.GetQuery(), opts)
+		if err != nil {
+			return statusError(err)
+		}
+		for i := range page.Stories {
+			if err := stream.Send(toProtoStory(&page.Stories[i])); err != nil {
+				return err
+			}
+		}
+		if !page.HasNextPage {
+			return nil
+		}
+		// 之後的頁面以 cursor 接續，不受串流期間新發布的 story 影響
+		opts.After, opts.Offset = page.EndCursor, 0
+	}
+}
+
+func (s *Server) GetAuthor(ctx context.Context, req *storypb.GetAuthorRequest) (*storypb.Author, error) {
+	if req.GetId() == "" && req.GetSlug() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id or slug is required")
+	}
+	author, err := s.stories.Author(ctx, req.GetId(), req.GetSlug())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &storypb.Author{Id: author.ID, Slug: author.Slug, Name: author.Name}, nil
+}
+
+// storySortFields 為 StorySortField 對應的排序欄位
+var storySortFields = map[storypb.StorySortField]string{
+	storypb.StorySortField_STORY_SORT_FIELD_PUBLISHED_AT: data.StorySortPublishedAt,
+	storypb.StorySortField_STORY_SORT_FIELD_UPDATED_AT:   data.StorySortUpdatedAt,
+	storypb.StorySortField_STORY_SORT_FIELD_POPULARITY:   data.StorySortPopularity,
+}
+
+// listOptions 將 request 轉為 StoryListOptions
+func listOptions(req *storypb.ListStoriesRequest) (data.StoryListOptions, error) {
+	opts := data.StoryListOptions{
+		Section: req.GetSection(),
+		Tag:     req.GetTag(),
+		Author:  req.GetAuthorId(),
+		Locale:  req.GetLocale(),
+		After:   req.GetAfter(),
+		Limit:   int(req.GetLimit()),
+		Offset:  int(req.GetOffset()),
+	}
+	for _, order := range req.GetOrderBy() {
+		field, ok := storySortFields[order.GetField()]
+		if !ok {
+			return opts, status.Errorf(codes.InvalidArgument, "unknown sort field %v", order.GetField())
+		}
+		rule := data.OrderRule{Field: field, Direction: "asc"}
+		if order.GetDescending() {
+			rule.Direction = "desc"
+		}
+		opts.OrderBy = append(opts.OrderBy, rule)
+	}
+	if req.GetPublishedFrom() != nil || req.GetPublishedTo() != nil {
+		opts.Where = &data.StoryWhereInput{PublishedAt: &data.DateTimeRangeFilter{}}
+		if from := req.GetPublishedFrom(); from != nil {
+			gte := from.AsTime().Format(time.RFC3339Nano)
+			opts.Where.PublishedAt.Gte = &gte
+		}
+		if to := req.GetPublishedTo(); to != nil {
+			lt := to.AsTime().Format(time.RFC3339Nano)
+			opts.Where.PublishedAt.Lt = &lt
+		}
+	}
+	return opts, nil
+}
+
+// statusError 將儲存層的錯誤轉為 gRPC status
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, data.ErrInvalidCursor), errors.Is(err, data.ErrInvalidStoryQuery), errors.Is(err, data.ErrInvalidLocale):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, data.ErrStoryNotFound), errors.Is(err, data.ErrAuthorNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, data.ErrAuthorsUnsupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// toProtoStory 將 Story 轉為 protobuf message
+func toProtoStory(s *data.Story) *storypb.Story {
+	return &storypb.Story{
+		Id:               s.ID,
+		Slug:             s.Slug,
+		Title:            s.Title,
+		Subtitle:         s.Subtitle,
+		Summary:          s.Summary,
+		Excerpt:          s.Excerpt,
+		Body:             s.Body,
+		Section:          s.Section,
+		Tags:             s.Tags,
+		AuthorIds:        s.AuthorIDs,
+		CoverImage:       s.CoverImage,
+		IsMember:         s.IsMember,
+		PublishedAt:      timestamp(s.PublishedAt),
+		CreatedAt:        timestamppb.New(s.CreatedAt),
+		UpdatedAt:        timestamppb.New(s.UpdatedAt),
+		ViewCount:        s.ViewCount,
+		WordCount:        int32(s.WordCount),
+		ReadingTime:      int32(s.ReadingTime),
+		Locale:           s.Locale,
+		TranslationGroup: s.TranslationGroup,
+		Access:           s.AccessTier(),
+	}
+}
+
+// timestamp 將可為 nil 的時間轉為 protobuf Timestamp
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
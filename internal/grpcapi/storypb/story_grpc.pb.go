@@ -0,0 +1,254 @@
+// Story read API for internal consumers. Only published stories are
+// returned. Generate the Go code with `go generate ./internal/grpcapi`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: story/v1/story.proto
+
+package storypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	StoryService_GetStory_FullMethodName      = "/gostory.story.v1.StoryService/GetStory"
+	StoryService_ListStories_FullMethodName   = "/gostory.story.v1.StoryService/ListStories"
+	StoryService_StreamStories_FullMethodName = "/gostory.story.v1.StoryService/StreamStories"
+	StoryService_GetAuthor_FullMethodName     = "/gostory.story.v1.StoryService/GetAuthor"
+)
+
+// StoryServiceClient is the client API for StoryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StoryServiceClient interface {
+	// GetStory returns a published story by id or slug (NOT_FOUND otherwise).
+	GetStory(ctx context.Context, in *GetStoryRequest, opts ...grpc.CallOption) (*Story, error)
+	// ListStories returns one page of published stories, newest first.
+	ListStories(ctx context.Context, in *ListStoriesRequest, opts ...grpc.CallOption) (*ListStoriesResponse, error)
+	// StreamStories streams every published story matching the filters,
+	// newest first, starting after the after cursor (or at offset); limit is
+	// ignored.
+	StreamStories(ctx context.Context, in *ListStoriesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Story], error)
+	// GetAuthor returns an author by id or slug.
+	GetAuthor(ctx context.Context, in *GetAuthorRequest, opts ...grpc.CallOption) (*Author, error)
+}
+
+type storyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStoryServiceClient(cc grpc.ClientConnInterface) StoryServiceClient {
+	return &storyServiceClient{cc}
+}
+
+func (c *storyServiceClient) GetStory(ctx context.Context, in *GetStoryRequest, opts ...grpc.CallOption) (*Story, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Story)
+	err := c.cc.Invoke(ctx, StoryService_GetStory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storyServiceClient) ListStories(ctx context.Context, in *ListStoriesRequest, opts ...grpc.CallOption) (*ListStoriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListStoriesResponse)
+	err := c.cc.Invoke(ctx, StoryService_ListStories_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storyServiceClient) StreamStories(ctx context.Context, in *ListStoriesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Story], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StoryService_ServiceDesc.Streams[0], StoryService_StreamStories_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListStoriesRequest, Story]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StoryService_StreamStoriesClient = grpc.ServerStreamingClient[Story]
+
+func (c *storyServiceClient) GetAuthor(ctx context.Context, in *GetAuthorRequest, opts ...grpc.CallOption) (*Author, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Author)
+	err := c.cc.Invoke(ctx, StoryService_GetAuthor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StoryServiceServer is the server API for StoryService service.
+// All implementations must embed UnimplementedStoryServiceServer
+// for forward compatibility.
+type StoryServiceServer interface {
+	// GetStory returns a published story by id or slug (NOT_FOUND otherwise).
+	GetStory(context.Context, *GetStoryRequest) (*Story, error)
+	// ListStories returns one page of published stories, newest first.
+	ListStories(context.Context, *ListStoriesRequest) (*ListStoriesResponse, error)
+	// StreamStories streams every published story matching the filters,
+	// newest first, starting after the after cursor (or at offset); limit is
+	// ignored.
+	StreamStories(*ListStoriesRequest, grpc.ServerStreamingServer[Story]) error
+	// GetAuthor returns an author by id or slug.
+	GetAuthor(context.Context, *GetAuthorRequest) (*Author, error)
+	mustEmbedUnimplementedStoryServiceServer()
+}
+
+// UnimplementedStoryServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStoryServiceServer struct{}
+
+func (UnimplementedStoryServiceServer) GetStory(context.Context, *GetStoryRequest) (*Story, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStory not implemented")
+}
+func (UnimplementedStoryServiceServer) ListStories(context.Context, *ListStoriesRequest) (*ListStoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStories not implemented")
+}
+func (UnimplementedStoryServiceServer) StreamStories(*ListStoriesRequest, grpc.ServerStreamingServer[Story]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamStories not implemented")
+}
+func (UnimplementedStoryServiceServer) GetAuthor(context.Context, *GetAuthorRequest) (*Author, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuthor not implemented")
+}
+func (UnimplementedStoryServiceServer) mustEmbedUnimplementedStoryServiceServer() {}
+func (UnimplementedStoryServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeStoryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StoryServiceServer will
+// result in compilation errors.
+type UnsafeStoryServiceServer interface {
+	mustEmbedUnimplementedStoryServiceServer()
+}
+
+func RegisterStoryServiceServer(s grpc.ServiceRegistrar, srv StoryServiceServer) {
+	// If the following call pancis, it indicates UnimplementedStoryServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&StoryService_ServiceDesc, srv)
+}
+
+func _StoryService_GetStory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoryServiceServer).GetStory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StoryService_GetStory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoryServiceServer).GetStory(ctx, req.(*GetStoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoryService_ListStories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoryServiceServer).ListStories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StoryService_ListStories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoryServiceServer).ListStories(ctx, req.(*ListStoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoryService_StreamStories_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListStoriesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoryServiceServer).StreamStories(m, &grpc.GenericServerStream[ListStoriesRequest, Story]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StoryService_StreamStoriesServer = grpc.ServerStreamingServer[Story]
+
+func _StoryService_GetAuthor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAuthorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoryServiceServer).GetAuthor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StoryService_GetAuthor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoryServiceServer).GetAuthor(ctx, req.(*GetAuthorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// StoryService_ServiceDesc is the grpc.ServiceDesc for StoryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StoryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gostory.story.v1.StoryService",
+	HandlerType: (*StoryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStory",
+			Handler:    _StoryService_GetStory_Handler,
+		},
+		{
+			MethodName: "ListStories",
+			Handler:    _StoryService_ListStories_Handler,
+		},
+		{
+			MethodName: "GetAuthor",
+			Handler:    _StoryService_GetAuthor_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamStories",
+			Handler:       _StoryService_StreamStories_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "story/v1/story.proto",
+}
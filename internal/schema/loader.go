@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"context"
+	"sync"
+)
+
+// loaderKind 識別一個請求中的各個 batchLoader
+type loaderKind int
+
+const (
+	authorLoader loaderKind = iota
+	tagLoader
+	mediaLoader
+)
+
+// requestLoaders 為一個 GraphQL 請求的 batchLoader，由 WithLoaders 放在 context 中
+type requestLoaders struct {
+	mu      sync.Mutex
+	loaders map[loaderKind]interface{}
+}
+
+type loadersKey struct{}
+
+// WithLoaders returns a copy of ctx carrying the dataloaders of one GraphQL
+// request. Within it the authors of stories, the terms of their tags and
+// the media of their images are looked up in batches: the lookups of a
+// field across a list of stories become a single lookup, and each key is
+// looked up once per request. Without it every field is looked up on its
+// own.
+func WithLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &requestLoaders{loaders: map[loaderKind]interface{}{}})
+}
+
+// loaderFor 回傳請求中 kind 的 batchLoader，第一次使用時以 fetch 建立；
+// context 中沒有 WithLoaders 時每次建立新的，查詢不會合併
+func loaderFor[K comparable, V any](ctx context.Context, kind loaderKind, fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	rl, ok := ctx.Value(loadersKey{}).(*requestLoaders)
+	if !ok {
+		return newBatchLoader(fetch)
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if l, ok := rl.loaders[kind].(*batchLoader[K, V]); ok {
+		return l
+	}
+	l := newBatchLoader(fetch)
+	rl.loaders[kind] = l
+	return l
+}
+
+// batchLoader 合併同一個請求中的查詢：resolver 以 load 登記需要的 key 並回傳 thunk，
+// graphql-go 執行完同一層所有的 resolver 後才呼叫 thunk，第一個被呼叫的 thunk 一次查詢所有登記的 key；
+// 查詢過的 key 在請求中不再查詢
+type batchLoader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error) // 查詢 keys，查無資料的 key 不在結果中
+	mu      sync.Mutex
+	pending []K         // 已登記、尚未查詢的 key
+	queued  map[K]bool  // pending 中的 key
+	loaded  map[K]V     // 查到的值
+	done    map[K]bool  // 查詢過的 key，含查無資料與查詢失敗的
+	errs    map[K]error // 查詢失敗的 key
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:  fetch,
+		queued: map[K]bool{},
+		loaded: map[K]V{},
+		done:   map[K]bool{},
+		errs:   map[K]error{},
+	}
+}
+
+// load 登記 keys，回傳的 thunk 取得其中查到的值；resolver 回傳呼叫 thunk 的 func() (interface{}, error)，由 graphql-go 延後解析
+func (l *batchLoader[K, V]) load(ctx context.Context, keys []K) func() (map[K]V, error) {
+	l.mu.Lock()
+	for _, key := range keys {
+		if !l.done[key] && !l.queued[key] {
+			l.pending = append(l.pending, key)
+			l.queued[key] = true
+		}
+	}
+	l.mu.Unlock()
+	return func() (map[K]V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, key := range keys {
+			if !l.done[key] {
+				l.dispatch(ctx)
+				break
+			}
+		}
+		found := make(map[K]V, len(keys))
+		for _, key := range keys {
+			if err := l.errs[key]; err != nil {
+				return nil, err
+			}
+			if value, ok := l.loaded[key]; ok {
+				found[key] = value
+			}
+		}
+		return found, nil
+	}
+}
+
+// dispatch 一次查詢所有登記的 key；查詢失敗時記錄錯誤，使用這些 key 的欄位都回傳該錯誤
+func (l *batchLoader[K, V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending, l.queued = nil, map[K]bool{}
+	if len(keys) == 0 {
+		return
+	}
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		l.done[key] = true
+		if err != nil {
+			l.errs[key] = err
+		}
+	}
+	for key, value := range values {
+		l.loaded[key] = value
+	}
+}
@@ -10,8 +10,23 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
-// Build constructs the GraphQL schema using provided repo.
-func Build(repo *data.Repo) (graphql.Schema, error) {
+// Build constructs the GraphQL schema using provided repo. When stories is
+// not nil, the story, stories, author, tag and section queries are added;
+// when related is not nil as well, stories get a related field, and when
+// trending is not nil the trendingStories and mostReadStories queries are
+// added. Story.viewCount includes views counted by views but not yet
+// persisted; views may be nil. Story.commentCount counts the approved
+// comments of comments, and is 0 when comments is nil. Story.reactions
+// lists the reaction totals of reactions, and is empty when reactions is
+// nil. Image blocks and gallery images get a media field with the uploaded
+// image when media is not nil. The story query gates paid stories with
+// paywall for the entitlement in the request context; other stories
+// queries always withhold the body of paid stories. A nil paywall serves
+// every story in full.
+//
+// Story.authors, StoryTag.term and media are looked up in batches with the
+// dataloaders of WithLoaders when the request context carries them.
+func Build(repo *data.Repo, stories *data.StoryService, related *data.RelatedService, trending *data.TrendingService, views *data.ViewCounter, comments *data.CommentService, reactions *data.ReactionService, media *data.MediaService, paywall *data.Paywall) (graphql.Schema, error) {
 	jsonScalar := newJSONScalar()
 	dateTimeScalar := newDateTimeScalar()
 
@@ -1073,6 +1088,12 @@ func Build(repo *data.Repo) (graphql.Schema, error) {
 		},
 	})
 
+	if stories != nil {
+		for name, field := range storyQueryFields(stories, related, trending, views, comments, reactions, media, paywall, dateTimeScalar, stringFilterInput, orderDirectionEnum) {
+			rootQuery.AddFieldConfig(name, field)
+		}
+	}
+
 	return graphql.NewSchema(graphql.SchemaConfig{
 		Query: rootQuery,
 	})
@@ -0,0 +1,964 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-story/internal/data"
+
+	"github.com/graphql-go/graphql"
+)
+
+// storyGroup 為 tag 與 section 查詢的回傳值，其下的 stories 由 field resolver 另外查詢
+type storyGroup struct {
+	Name string `json:"name"`
+}
+
+// storyEdge 為 StoryConnection 的一筆 edge
+type storyEdge struct {
+	Cursor string
+	Node   data.Story
+}
+
+// storyQueryFields 建立 story 相關的 root query 欄位；只會回傳已發布的 story
+func storyQueryFields(stories *data.StoryService, related *data.RelatedService, trending *data.TrendingService, views *data.ViewCounter, comments *data.CommentService, reactions *data.ReactionService, media *data.MediaService, paywall *data.Paywall, dateTimeScalar *graphql.Scalar, stringFilterInput *graphql.InputObject, orderDirectionEnum *graphql.Enum) graphql.Fields {
+	// 所有 story 列表共用的篩選與排序參數
+	whereInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "StoryWhereInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"section": &graphql.InputObjectFieldConfig{Type: stringFilterInput},
+			"tag":     &graphql.InputObjectFieldConfig{Type: stringFilterInput},
+			"author":  &graphql.InputObjectFieldConfig{Type: stringFilterInput},
+			"status":  &graphql.InputObjectFieldConfig{Type: stringFilterInput},
+			"locale":  &graphql.InputObjectFieldConfig{Type: stringFilterInput},
+			"publishedAt": &graphql.InputObjectFieldConfig{Type: graphql.NewInputObject(graphql.InputObjectConfig{
+				Name: "DateTimeRangeFilter",
+				Fields: graphql.InputObjectConfigFieldMap{
+					"gte": &graphql.InputObjectFieldConfig{Type: dateTimeScalar},
+					"lt":  &graphql.InputObjectFieldConfig{Type: dateTimeScalar},
+				},
+			})},
+		},
+	})
+	orderByInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "StoryOrderByInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			data.StorySortPublishedAt: &graphql.InputObjectFieldConfig{Type: orderDirectionEnum},
+			data.StorySortUpdatedAt:   &graphql.InputObjectFieldConfig{Type: orderDirectionEnum},
+			data.StorySortPopularity:  &graphql.InputObjectFieldConfig{Type: orderDirectionEnum},
+		},
+	})
+	queryArgs := func(args, extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args["where"] = &graphql.ArgumentConfig{Type: whereInput}
+		args["orderBy"] = &graphql.ArgumentConfig{Type: graphql.NewList(orderByInput)}
+		for name, arg := range extra {
+			args[name] = arg
+		}
+		return args
+	}
+	// applyQuery 將 where 與 orderBy 參數加入 opts
+	applyQuery := func(args map[string]interface{}, opts *data.StoryListOptions) error {
+		where, err := data.DecodeStoryWhere(args["where"])
+		if err != nil {
+			return err
+		}
+		opts.Where = where
+		opts.OrderBy = parseOrderRules(args["orderBy"])
+		return nil
+	}
+
+	listArgs := func(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		return queryArgs(graphql.FieldConfigArgument{
+			"take": &graphql.ArgumentConfig{Type: graphql.Int},
+			"skip": &graphql.ArgumentConfig{Type: graphql.Int},
+		}, extra)
+	}
+	listStories := func(ctx context.Context, args map[string]interface{}, opts data.StoryListOptions) ([]data.Story, error) {
+		if err := applyQuery(args, &opts); err != nil {
+			return nil, err
+		}
+		opts.Limit, opts.Offset = parsePagination(args)
+		search, _ := args["search"].(string)
+		return stories.Stories(ctx, search, opts)
+	}
+
+	// 作者、tag 與圖片以請求中的 batchLoader 查詢 (見 WithLoaders)，列表中所有 story 的查詢合併為一次
+	authorsOf := func(ctx context.Context) *batchLoader[string, data.Author] {
+		return loaderFor(ctx, authorLoader, func(ctx context.Context, ids []string) (map[string]data.Author, error) {
+			authors, err := stories.AuthorsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[string]data.Author, len(authors))
+			for _, author := range authors {
+				byID[author.ID] = author
+			}
+			return byID, nil
+		})
+	}
+	tagsOf := func(ctx context.Context) *batchLoader[string, *data.Term] {
+		return loaderFor(ctx, tagLoader, func(ctx context.Context, slugs []string) (map[string]*data.Term, error) {
+			terms, err := stories.TermsBySlug(ctx, data.TermKindTag, slugs)
+			if errors.Is(err, data.ErrTaxonomyUnsupported) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			bySlug := make(map[string]*data.Term, len(terms))
+			for _, term := range terms {
+				if term != nil {
+					bySlug[term.Slug] = term
+				}
+			}
+			return bySlug, nil
+		})
+	}
+	mediaOf := func(ctx context.Context) *batchLoader[string, *data.Media] {
+		return loaderFor(ctx, mediaLoader, func(ctx context.Context, ids []string) (map[string]*data.Media, error) {
+			list, err := media.MediaByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[string]*data.Media, len(list))
+			for _, m := range list {
+				if m != nil {
+					byID[m.ID] = m
+				}
+			}
+			return byID, nil
+		})
+	}
+
+	var storyType *graphql.Object
+
+	// 以 cursor 分頁的列表 (Relay connection)
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryPageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryEdge",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"cursor": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						edge, _ := p.Source.(storyEdge)
+						return edge.Cursor, nil
+					},
+				},
+				"node": &graphql.Field{
+					Type: storyType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						edge, _ := p.Source.(storyEdge)
+						return edge.Node, nil
+					},
+				},
+			}
+		}),
+	})
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewList(edgeType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					page, _ := p.Source.(*data.StoryPage)
+					edges := make([]storyEdge, len(page.Stories))
+					for i, story := range page.Stories {
+						edges[i] = storyEdge{Cursor: page.Cursors[i], Node: story}
+					}
+					return edges, nil
+				},
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+	connectionArgs := func(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		return queryArgs(graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int},
+			"after": &graphql.ArgumentConfig{Type: graphql.String},
+		}, extra)
+	}
+	storiesPage := func(ctx context.Context, args map[string]interface{}, opts data.StoryListOptions) (*data.StoryPage, error) {
+		if err := applyQuery(args, &opts); err != nil {
+			return nil, err
+		}
+		opts.Limit = asInt(args["first"])
+		opts.After, _ = args["after"].(string)
+		search, _ := args["search"].(string)
+		return stories.StoriesPage(ctx, search, opts)
+	}
+
+	galleryImageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryGalleryImage",
+		Fields: graphql.Fields{
+			"url":     &graphql.Field{Type: graphql.String},
+			"alt":     &graphql.Field{Type: graphql.String},
+			"caption": &graphql.Field{Type: graphql.String},
+			"mediaId": &graphql.Field{Type: graphql.String},
+		},
+	})
+	// embedType 為 embed block 解析後的 oEmbed payload
+	embedType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryEmbed",
+		Fields: graphql.Fields{
+			"type":            &graphql.Field{Type: graphql.String},
+			"provider":        &graphql.Field{Type: graphql.String},
+			"title":           &graphql.Field{Type: graphql.String},
+			"authorName":      &graphql.Field{Type: graphql.String},
+			"authorUrl":       &graphql.Field{Type: graphql.String},
+			"html":            &graphql.Field{Type: graphql.String},
+			"width":           &graphql.Field{Type: graphql.Int},
+			"height":          &graphql.Field{Type: graphql.Int},
+			"thumbnailUrl":    &graphql.Field{Type: graphql.String},
+			"thumbnailWidth":  &graphql.Field{Type: graphql.Int},
+			"thumbnailHeight": &graphql.Field{Type: graphql.Int},
+			"resolvedAt":      &graphql.Field{Type: dateTimeScalar},
+		},
+	})
+	// reactionCountType 為 story 的一種回應與其總數
+	reactionCountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryReactionCount",
+		Fields: graphql.Fields{
+			"reaction": &graphql.Field{Type: graphql.String},
+			"count":    &graphql.Field{Type: graphql.Int},
+		},
+	})
+	// tocEntryType 為目錄中的標題，children 為其下一層的標題
+	var tocEntryType *graphql.Object
+	tocEntryType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryTOCEntry",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":       &graphql.Field{Type: graphql.String},
+				"text":     &graphql.Field{Type: graphql.String},
+				"level":    &graphql.Field{Type: graphql.Int},
+				"children": &graphql.Field{Type: graphql.NewList(tocEntryType)},
+			}
+		}),
+	})
+	// blockType 為 story 的結構化內容；各類型使用的欄位見 data.ContentBlock
+	// mediaType 為圖片 block 與 gallery 圖片上傳至媒體庫的檔案，見 data.Media
+	mediaType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryMedia",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.ID},
+			"url":         &graphql.Field{Type: graphql.String},
+			"contentType": &graphql.Field{Type: graphql.String},
+			"size":        &graphql.Field{Type: graphql.Int},
+			"width":       &graphql.Field{Type: graphql.Int},
+			"height":      &graphql.Field{Type: graphql.Int},
+		},
+	})
+	blockType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryBlock",
+		Fields: graphql.Fields{
+			"type":    &graphql.Field{Type: graphql.String},
+			"text":    &graphql.Field{Type: graphql.String},
+			"level":   &graphql.Field{Type: graphql.Int},
+			"url":     &graphql.Field{Type: graphql.String},
+			"alt":     &graphql.Field{Type: graphql.String},
+			"caption": &graphql.Field{Type: graphql.String},
+			"cite":    &graphql.Field{Type: graphql.String},
+			"images":  &graphql.Field{Type: graphql.NewList(galleryImageType)},
+			"embed":   &graphql.Field{Type: embedType},
+			"mediaId": &graphql.Field{Type: graphql.String},
+		},
+	})
+	if media != nil {
+		// 圖片的 media 以 batchLoader 查詢，同一層所有圖片的 media 一次讀取
+		mediaField := &graphql.Field{
+			Type: mediaType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id := mediaID(p.Source)
+				if id == "" {
+					return nil, nil
+				}
+				load := mediaOf(p.Context).load(p.Context, []string{id})
+				return func() (interface{}, error) {
+					found, err := load()
+					if err != nil || found[id] == nil {
+						return nil, err
+					}
+					return found[id], nil
+				}, nil
+			},
+		}
+		galleryImageType.AddFieldConfig("media", mediaField)
+		blockType.AddFieldConfig("media", mediaField)
+	}
+	authorLinkType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryAuthorLink",
+		Fields: graphql.Fields{
+			"network": &graphql.Field{Type: graphql.String},
+			"url":     &graphql.Field{Type: graphql.String},
+		},
+	})
+	authorType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryAuthor",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          &graphql.Field{Type: graphql.ID},
+				"slug":        &graphql.Field{Type: graphql.String},
+				"name":        &graphql.Field{Type: graphql.String},
+				"bio":         &graphql.Field{Type: graphql.String},
+				"avatar":      &graphql.Field{Type: graphql.String},
+				"socialLinks": &graphql.Field{Type: graphql.NewList(authorLinkType)},
+				"stories": &graphql.Field{
+					Type: graphql.NewList(storyType),
+					Args: listArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						author := normalizeAuthor(p.Source)
+						return listStories(p.Context, p.Args, data.StoryListOptions{Author: author.ID})
+					},
+				},
+				"storiesConnection": &graphql.Field{
+					Type: connectionType,
+					Args: connectionArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						author := normalizeAuthor(p.Source)
+						return storiesPage(p.Context, p.Args, data.StoryListOptions{Author: author.ID})
+					},
+				},
+			}
+		}),
+	})
+
+	collectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryCollection",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":         &graphql.Field{Type: graphql.ID},
+				"slug":       &graphql.Field{Type: graphql.String},
+				"title":      &graphql.Field{Type: graphql.String},
+				"intro":      &graphql.Field{Type: graphql.String},
+				"coverImage": &graphql.Field{Type: graphql.String},
+				"updatedAt":  &graphql.Field{Type: dateTimeScalar},
+				"stories": &graphql.Field{
+					Type: graphql.NewList(storyType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						collection := normalizeCollection(p.Source)
+						return stories.CollectionStories(p.Context, &collection)
+					},
+				},
+			}
+		}),
+	})
+	seriesStoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StorySeriesPart",
+		Fields: graphql.Fields{
+			"part":  &graphql.Field{Type: graphql.Int},
+			"id":    &graphql.Field{Type: graphql.ID},
+			"slug":  &graphql.Field{Type: graphql.String},
+			"title": &graphql.Field{Type: graphql.String},
+		},
+	})
+	storyAccessType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryAccess",
+		Fields: graphql.Fields{
+			"tier":           &graphql.Field{Type: graphql.String},
+			"granted":        &graphql.Field{Type: graphql.Boolean},
+			"reason":         &graphql.Field{Type: graphql.String},
+			"meterLimit":     &graphql.Field{Type: graphql.Int},
+			"meterRemaining": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	translationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryTranslation",
+		Fields: graphql.Fields{
+			"locale": &graphql.Field{Type: graphql.String},
+			"slug":   &graphql.Field{Type: graphql.String},
+			"title":  &graphql.Field{Type: graphql.String},
+		},
+	})
+	// metaTagType 與 socialMetaType 為 story 頁面的 Open Graph 與 Twitter Card 資訊，見 data.SocialMeta
+	metaTagType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryMetaTag",
+		Fields: graphql.Fields{
+			"property": &graphql.Field{Type: graphql.String},
+			"name":     &graphql.Field{Type: graphql.String},
+			"content":  &graphql.Field{Type: graphql.String},
+		},
+	})
+	socialMetaType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StorySocialMeta",
+		Fields: graphql.Fields{
+			"title":            &graphql.Field{Type: graphql.String},
+			"description":      &graphql.Field{Type: graphql.String},
+			"image":            &graphql.Field{Type: graphql.String},
+			"imageAlt":         &graphql.Field{Type: graphql.String},
+			"canonicalUrl":     &graphql.Field{Type: graphql.String},
+			"ampUrl":           &graphql.Field{Type: graphql.String},
+			"siteName":         &graphql.Field{Type: graphql.String},
+			"type":             &graphql.Field{Type: graphql.String},
+			"locale":           &graphql.Field{Type: graphql.String},
+			"alternateLocales": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"publishedTime":    &graphql.Field{Type: dateTimeScalar},
+			"modifiedTime":     &graphql.Field{Type: dateTimeScalar},
+			"section":          &graphql.Field{Type: graphql.String},
+			"tags":             &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"authors":          &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"twitterCard":      &graphql.Field{Type: graphql.String},
+			"twitterSite":      &graphql.Field{Type: graphql.String},
+			"twitterCreator":   &graphql.Field{Type: graphql.String},
+			"metaTags":         &graphql.Field{Type: graphql.NewList(metaTagType)},
+		},
+	})
+	seriesType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StorySeries",
+		Fields: graphql.Fields{
+			"collection": &graphql.Field{Type: collectionType},
+			"part":       &graphql.Field{Type: graphql.Int},
+			"total":      &graphql.Field{Type: graphql.Int},
+			"previous":   &graphql.Field{Type: seriesStoryType},
+			"next":       &graphql.Field{Type: seriesStoryType},
+			"parts":      &graphql.Field{Type: graphql.NewList(seriesStoryType)},
+		},
+	})
+
+	termType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryTerm",
+		Fields: graphql.Fields{
+			"kind":       &graphql.Field{Type: graphql.String},
+			"slug":       &graphql.Field{Type: graphql.String},
+			"name":       &graphql.Field{Type: graphql.String},
+			"parent":     &graphql.Field{Type: graphql.String},
+			"storyCount": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	// 依 kind 列出 tag 或分類；儲存層不支援時回傳空列表
+	termsField := func(kind string) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewList(termType),
+			Args: graphql.FieldConfigArgument{
+				"limit":  &graphql.ArgumentConfig{Type: graphql.Int},
+				"offset": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page, err := stories.TermsPage(p.Context, kind, asInt(p.Args["limit"]), asInt(p.Args["offset"]))
+				if errors.Is(err, data.ErrTaxonomyUnsupported) {
+					return []data.Term{}, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return page.Terms, nil
+			},
+		}
+	}
+
+	tagType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StoryTag",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name": &graphql.Field{Type: graphql.String},
+				// term 為 tag 在 taxonomy 中的名稱與 story 數；不在 taxonomy 中或儲存層不支援時為 null
+				"term": &graphql.Field{
+					Type: termType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						tag, _ := p.Source.(storyGroup)
+						load := tagsOf(p.Context).load(p.Context, []string{tag.Name})
+						return func() (interface{}, error) {
+							found, err := load()
+							if err != nil || found[tag.Name] == nil {
+								return nil, err
+							}
+							return found[tag.Name], nil
+						}, nil
+					},
+				},
+				"stories": &graphql.Field{
+					Type: graphql.NewList(storyType),
+					Args: listArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						tag, _ := p.Source.(storyGroup)
+						return listStories(p.Context, p.Args, data.StoryListOptions{Tag: tag.Name})
+					},
+				},
+				"storiesConnection": &graphql.Field{
+					Type: connectionType,
+					Args: connectionArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						tag, _ := p.Source.(storyGroup)
+						return storiesPage(p.Context, p.Args, data.StoryListOptions{Tag: tag.Name})
+					},
+				},
+			}
+		}),
+	})
+
+	sectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StorySection",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name": &graphql.Field{Type: graphql.String},
+				"stories": &graphql.Field{
+					Type: graphql.NewList(storyType),
+					Args: listArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						section, _ := p.Source.(storyGroup)
+						return listStories(p.Context, p.Args, data.StoryListOptions{Section: section.Name})
+					},
+				},
+				"storiesConnection": &graphql.Field{
+					Type: connectionType,
+					Args: connectionArgs(nil),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						section, _ := p.Source.(storyGroup)
+						return storiesPage(p.Context, p.Args, data.StoryListOptions{Section: section.Name})
+					},
+				},
+			}
+		}),
+	})
+
+	storyType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Story",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.ID},
+			"slug":     &graphql.Field{Type: graphql.String},
+			"title":    &graphql.Field{Type: graphql.String},
+			"subtitle": &graphql.Field{Type: graphql.String},
+			"summary":  &graphql.Field{Type: graphql.String},
+			"excerpt":  &graphql.Field{Type: graphql.String},
+			// 讀者無權閱讀的付費文章 (列表中的付費文章一律如此) 不提供內文，以 excerpt 作為預覽
+			"body": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					if paywall.Withheld(&current) {
+						return nil, nil
+					}
+					return current.Body, nil
+				},
+			},
+			"blocks": &graphql.Field{
+				Type: graphql.NewList(blockType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					if paywall.Withheld(&current) {
+						return nil, nil
+					}
+					return stories.Blocks(p.Context, &current), nil
+				},
+			},
+			"coverImage": &graphql.Field{Type: graphql.String},
+			"isMember":   &graphql.Field{Type: graphql.Boolean},
+			"access": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return current.AccessTier(), nil
+				},
+			},
+			"paywall": &graphql.Field{Type: storyAccessType},
+			"bodyHtml": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					if paywall.Withheld(&current) {
+						return nil, nil
+					}
+					return stories.BodyHTML(p.Context, &current)
+				},
+			},
+			"tableOfContents": &graphql.Field{
+				Type: graphql.NewList(tocEntryType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					if paywall.Withheld(&current) {
+						return nil, nil
+					}
+					return stories.TableOfContents(p.Context, &current)
+				},
+			},
+			"section": &graphql.Field{
+				Type: sectionType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					if current.Section == "" {
+						return nil, nil
+					}
+					return storyGroup{Name: current.Section}, nil
+				},
+			},
+			"tags": &graphql.Field{
+				Type: graphql.NewList(tagType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					tags := make([]storyGroup, 0, len(current.Tags))
+					for _, tag := range current.Tags {
+						tags = append(tags, storyGroup{Name: tag})
+					}
+					return tags, nil
+				},
+			},
+			"authors": &graphql.Field{
+				Type: graphql.NewList(authorType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					ids := current.AuthorIDs
+					load := authorsOf(p.Context).load(p.Context, ids)
+					return func() (interface{}, error) {
+						found, err := load()
+						if err != nil {
+							return nil, err
+						}
+						authors := make([]data.Author, 0, len(ids))
+						for _, id := range ids {
+							if author, ok := found[id]; ok {
+								authors = append(authors, author)
+							}
+						}
+						return authors, nil
+					}, nil
+				},
+			},
+			"series": &graphql.Field{
+				Type: graphql.NewList(seriesType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return stories.Series(p.Context, &current)
+				},
+			},
+			"locale": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return stories.Locale(&current), nil
+				},
+			},
+			"translationGroup": &graphql.Field{Type: graphql.String},
+			"translations": &graphql.Field{
+				Type: graphql.NewList(translationType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return stories.Translations(p.Context, &current)
+				},
+			},
+			"meta": &graphql.Field{
+				Type: socialMetaType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return stories.SocialMeta(p.Context, &current)
+				},
+			},
+			// structuredData 為 JSON-LD 的 JSON 字串，可直接放入 <script type="application/ld+json">
+			"structuredData": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					ld, err := stories.StructuredData(p.Context, &current)
+					if err != nil {
+						return nil, err
+					}
+					encoded, err := json.Marshal(ld)
+					return string(encoded), err
+				},
+			},
+			"publishedAt": &graphql.Field{Type: dateTimeScalar},
+			"updatedAt":   &graphql.Field{Type: dateTimeScalar},
+			"wordCount":   &graphql.Field{Type: graphql.Int},
+			"readingTime": &graphql.Field{Type: graphql.Int},
+			"viewCount": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return views.Total(p.Context, &current), nil
+				},
+			},
+			"commentCount": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					return comments.Count(p.Context, current.ID)
+				},
+			},
+			"reactions": &graphql.Field{
+				Type: graphql.NewList(reactionCountType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := normalizeStory(p.Source)
+					totals, err := reactions.Totals(p.Context, current.ID)
+					if err != nil {
+						return nil, err
+					}
+					counts := make([]map[string]interface{}, 0, len(totals))
+					for _, reaction := range reactions.Reactions() {
+						counts = append(counts, map[string]interface{}{"reaction": reaction, "count": totals[reaction]})
+					}
+					return counts, nil
+				},
+			},
+		},
+	})
+	if related != nil {
+		storyType.AddFieldConfig("related", &graphql.Field{
+			Type: graphql.NewList(storyType),
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				current := normalizeStory(p.Source)
+				limit, _ := p.Args["limit"].(int)
+				results, err := related.Related(p.Context, current.ID, "", limit)
+				if err != nil {
+					return nil, err
+				}
+				relatedStories := make([]data.Story, len(results))
+				for i, result := range results {
+					relatedStories[i] = result.Story
+				}
+				return relatedStories, nil
+			},
+		})
+	}
+
+	fields := graphql.Fields{
+		"story": &graphql.Field{
+			Type: storyType,
+			Args: graphql.FieldConfigArgument{
+				"id":   &graphql.ArgumentConfig{Type: graphql.ID},
+				"slug": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, _ := p.Args["id"].(string)
+				slug, _ := p.Args["slug"].(string)
+				story, err := stories.Story(p.Context, id, slug)
+				if errors.Is(err, data.ErrStoryNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				// 複製一份再判斷閱讀權限，避免改到 cache 中的值
+				current := *story
+				paywall.Gate(p.Context, &current, data.EntitlementFromContext(p.Context))
+				return &current, nil
+			},
+		},
+		"stories": &graphql.Field{
+			Type: graphql.NewList(storyType),
+			Args: listArgs(graphql.FieldConfigArgument{
+				"section": &graphql.ArgumentConfig{Type: graphql.String},
+				"tag":     &graphql.ArgumentConfig{Type: graphql.String},
+				"author":  &graphql.ArgumentConfig{Type: graphql.ID},
+				"locale":  &graphql.ArgumentConfig{Type: graphql.String},
+				"search":  &graphql.ArgumentConfig{Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				opts := data.StoryListOptions{}
+				opts.Section, _ = p.Args["section"].(string)
+				opts.Tag, _ = p.Args["tag"].(string)
+				opts.Author, _ = p.Args["author"].(string)
+				opts.Locale, _ = p.Args["locale"].(string)
+				return listStories(p.Context, p.Args, opts)
+			},
+		},
+		"storiesConnection": &graphql.Field{
+			Type: connectionType,
+			Args: connectionArgs(graphql.FieldConfigArgument{
+				"section": &graphql.ArgumentConfig{Type: graphql.String},
+				"tag":     &graphql.ArgumentConfig{Type: graphql.String},
+				"author":  &graphql.ArgumentConfig{Type: graphql.ID},
+				"locale":  &graphql.ArgumentConfig{Type: graphql.String},
+				"search":  &graphql.ArgumentConfig{Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				opts := data.StoryListOptions{}
+				opts.Section, _ = p.Args["section"].(string)
+				opts.Tag, _ = p.Args["tag"].(string)
+				opts.Author, _ = p.Args["author"].(string)
+				opts.Locale, _ = p.Args["locale"].(string)
+				return storiesPage(p.Context, p.Args, opts)
+			},
+		},
+		"author": &graphql.Field{
+			Type: authorType,
+			Args: graphql.FieldConfigArgument{
+				"id":   &graphql.ArgumentConfig{Type: graphql.ID},
+				"slug": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, _ := p.Args["id"].(string)
+				slug, _ := p.Args["slug"].(string)
+				author, err := stories.Author(p.Context, id, slug)
+				if errors.Is(err, data.ErrAuthorNotFound) {
+					return nil, nil
+				}
+				return author, err
+			},
+		},
+		"authors": &graphql.Field{
+			Type: graphql.NewList(authorType),
+			Args: graphql.FieldConfigArgument{
+				"limit":  &graphql.ArgumentConfig{Type: graphql.Int},
+				"offset": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page, err := stories.AuthorsPage(p.Context, asInt(p.Args["limit"]), asInt(p.Args["offset"]))
+				if errors.Is(err, data.ErrAuthorsUnsupported) {
+					return []data.Author{}, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return page.Authors, nil
+			},
+		},
+		"collection": &graphql.Field{
+			Type: collectionType,
+			Args: graphql.FieldConfigArgument{
+				"id":   &graphql.ArgumentConfig{Type: graphql.ID},
+				"slug": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, _ := p.Args["id"].(string)
+				slug, _ := p.Args["slug"].(string)
+				collection, err := stories.Collection(p.Context, id, slug)
+				if errors.Is(err, data.ErrCollectionNotFound) || errors.Is(err, data.ErrCollectionsUnsupported) {
+					return nil, nil
+				}
+				return collection, err
+			},
+		},
+		"collections": &graphql.Field{
+			Type: graphql.NewList(collectionType),
+			Args: graphql.FieldConfigArgument{
+				"limit":  &graphql.ArgumentConfig{Type: graphql.Int},
+				"offset": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page, err := stories.CollectionsPage(p.Context, asInt(p.Args["limit"]), asInt(p.Args["offset"]))
+				if errors.Is(err, data.ErrCollectionsUnsupported) {
+					return []data.Collection{}, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return page.Collections, nil
+			},
+		},
+		"tags":       termsField(data.TermKindTag),
+		"categories": termsField(data.TermKindCategory),
+		"tag": &graphql.Field{
+			Type: tagType,
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				name, _ := p.Args["name"].(string)
+				return storyGroup{Name: name}, nil
+			},
+		},
+		"section": &graphql.Field{
+			Type: sectionType,
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				name, _ := p.Args["name"].(string)
+				return storyGroup{Name: name}, nil
+			},
+		},
+	}
+	if trending != nil {
+		// 熱門排行：window 為 1h、24h 或 7d，預設 24h
+		rankingArgs := graphql.FieldConfigArgument{
+			"window": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: string(data.TrendingWindowDay)},
+			"limit":  &graphql.ArgumentConfig{Type: graphql.Int},
+		}
+		rankingField := func(rank func(ctx context.Context, window data.TrendingWindow, limit int) ([]data.TrendingStory, error)) *graphql.Field {
+			return &graphql.Field{
+				Type: graphql.NewList(storyType),
+				Args: rankingArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					window, _ := p.Args["window"].(string)
+					limit, _ := p.Args["limit"].(int)
+					ranked, err := rank(p.Context, data.TrendingWindow(window), limit)
+					if err != nil {
+						return nil, err
+					}
+					rankedStories := make([]data.Story, len(ranked))
+					for i, result := range ranked {
+						rankedStories[i] = result.Story
+					}
+					return rankedStories, nil
+				},
+			}
+		}
+		fields["trendingStories"] = rankingField(trending.Trending)
+		fields["mostReadStories"] = rankingField(trending.MostRead)
+	}
+	return fields
+}
+
+func normalizeStory(src interface{}) data.Story {
+	switch v := src.(type) {
+	case data.Story:
+		return v
+	case *data.Story:
+		if v == nil {
+			return data.Story{}
+		}
+		return *v
+	default:
+		return data.Story{}
+	}
+}
+
+// mediaID 回傳圖片 block 或 gallery 圖片的 media ID
+func mediaID(src interface{}) string {
+	switch v := src.(type) {
+	case data.ContentBlock:
+		return v.MediaID
+	case *data.ContentBlock:
+		if v != nil {
+			return v.MediaID
+		}
+	case data.GalleryImage:
+		return v.MediaID
+	case *data.GalleryImage:
+		if v != nil {
+			return v.MediaID
+		}
+	}
+	return ""
+}
+
+func normalizeAuthor(src interface{}) data.Author {
+	switch v := src.(type) {
+	case data.Author:
+		return v
+	case *data.Author:
+		if v == nil {
+			return data.Author{}
+		}
+		return *v
+	default:
+		return data.Author{}
+	}
+}
+
+func normalizeCollection(src interface{}) data.Collection {
+	switch v := src.(type) {
+	case data.Collection:
+		return v
+	case *data.Collection:
+		if v == nil {
+			return data.Collection{}
+		}
+		return *v
+	default:
+		return data.Collection{}
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-story/internal/data"
+)
+
+// ampCacheControl 為 AMP 頁面的快取標頭；AMP cache 會另外依此重新抓取
+const ampCacheControl = "public, max-age=300"
+
+// NewAMPHandler serves the AMP pages of published stories:
+//
+//	GET /amp/{slug}
+//
+// A former slug redirects to the current one. A story whose AMP rendering
+// fails validation redirects to its canonical page on site instead.
+func NewAMPHandler(amp *data.AMPService, site data.Site) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /amp/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		slug := r.PathValue("slug")
+		doc, err := amp.Render(r.Context(), slug)
+		switch {
+		case errors.Is(err, data.ErrStoryNotFound):
+			http.NotFound(w, r)
+			return
+		case errors.Is(err, data.ErrInvalidAMP):
+			slog.Warn("invalid amp page", "slug", slug, "error", err)
+			http.Redirect(w, r, site.StoryURL(slug), http.StatusFound)
+			return
+		case err != nil:
+			slog.Error("failed to render amp page", "slug", slug, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if doc.Slug != slug {
+			http.Redirect(w, r, site.AMPURL(doc.Slug), http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", ampCacheControl)
+		_, _ = w.Write([]byte(doc.HTML))
+	})
+	return mux
+}
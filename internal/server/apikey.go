@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-story/internal/data"
+)
+
+// API key scopes checked by APIKeys (see RateLimitGroupScopes).
+const (
+	ScopeStoriesRead = "stories:read"
+	ScopeImagesRead  = "images:read"
+)
+
+// RateLimitGroupScopes maps each route group to the scope an API key must
+// have been granted to call its routes.
+var RateLimitGroupScopes = map[string]string{
+	RateLimitGroupGraphQL:  ScopeStoriesRead,
+	RateLimitGroupREST:     ScopeStoriesRead,
+	RateLimitGroupFeeds:    ScopeStoriesRead,
+	RateLimitGroupAMP:      ScopeStoriesRead,
+	RateLimitGroupSitemaps: ScopeStoriesRead,
+	RateLimitGroupImages:   ScopeImagesRead,
+}
+
+// apiKeyRequest 為發行 API key 的 payload
+type apiKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	RateLimit int      `json:"rateLimit"`
+}
+
+// APIKeys authenticates the API key sent in the X-API-Key header with keys
+// and passes it to next in the request context (see data.APIKeyFromContext),
+// together with its principal (see data.PrincipalFromContext), so
+// RateLimit applies the key's own limit. Keys without scope are answered
+// with 403; an empty scope accepts every key. Requests without a key pass
+// through anonymously; an unknown or revoked key is answered with 401. A
+// nil keys returns next unchanged.
+func APIKeys(keys *data.APIKeyService, scope string, next http.Handler) http.Handler {
+	if keys == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.Header.Get(apiKeyHeader)
+		if raw == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, err := keys.Authenticate(r.Context(), raw)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if errors.Is(err, data.ErrUnknownAPIKey) {
+				w.WriteHeader(http.StatusUnauthorized)
+				writeJSON(w, APIError{Error: err.Error()})
+				return
+			}
+			slog.Warn("api key lookup failed", "error", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeJSON(w, APIError{Error: "api key lookup failed"})
+			return
+		}
+		principal := key.Principal()
+		if scope != "" && !principal.HasScope(scope) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			writeJSON(w, APIError{Error: "api key lacks the " + scope + " scope"})
+			return
+		}
+		ctx := data.WithPrincipal(data.WithAPIKey(r.Context(), key), principal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// APIKeyAdminHandler serves the API key admin API under /internal/api-keys:
+//
+//	GET    /internal/api-keys       list keys, revoked ones included
+//	POST   /internal/api-keys       issue a key, body {"name", "scopes", "rateLimit"}
+//	GET    /internal/api-keys/{id}  get a key
+//	DELETE /internal/api-keys/{id}  revoke a key
+//
+// The key itself is only in the response to POST, as "key"; it cannot be
+// retrieved later. Every request must carry "Authorization: Bearer
+// <token>". Writes are recorded in the audit log as made by "api-key-admin"
+// (see AuditActorHeader).
+func APIKeyAdminHandler(keys *data.APIKeyService, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /internal/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		list, err := keys.Keys(r.Context())
+		if err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": list})
+	})
+	mux.HandleFunc("POST /internal/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		var payload apiKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		key := &data.APIKey{Name: payload.Name, Scopes: payload.Scopes, RateLimit: payload.RateLimit}
+		if err := keys.Issue(r.Context(), key); err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(key)
+	})
+	mux.HandleFunc("GET /internal/api-keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		key, err := keys.Key(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		writeJSON(w, key)
+	})
+	mux.HandleFunc("DELETE /internal/api-keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		key, err := keys.Revoke(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeAPIKeyError(w, err)
+			return
+		}
+		writeJSON(w, key)
+	})
+
+	return requireBearerToken(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, withAuditActor(r, "api-key-admin"))
+	}))
+}
+
+// writeAPIKeyError 將 API key 的錯誤轉為對應的 HTTP 狀態碼
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrAPIKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, data.ErrInvalidAPIKey):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Warn("api key admin request failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
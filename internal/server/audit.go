@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// AuditActorHeader optionally names the person behind an admin token, e.g.
+// the editor's account. Writes are then recorded in the audit log as
+// "<token name>:<header>" instead of just the token's name.
+const AuditActorHeader = "X-Audit-Actor"
+
+// auditActorMaxLen 為 AuditActorHeader 的長度上限
+const auditActorMaxLen = 100
+
+// withAuditActor 將 name (與選填的 AuditActorHeader) 設為這次請求寫入的稽核紀錄 actor
+func withAuditActor(r *http.Request, name string) *http.Request {
+	actor := name
+	if who := strings.TrimSpace(r.Header.Get(AuditActorHeader)); who != "" {
+		if len(who) > auditActorMaxLen {
+			who = who[:auditActorMaxLen]
+		}
+		actor += ":" + who
+	}
+	return r.WithContext(data.WithAuditActor(r.Context(), actor))
+}
+
+// AuditHandler serves the audit log at GET /internal/audit, newest first:
+//
+//	?actor=&action=&entity=&entityId=  exact matches, e.g. entity=story&entityId=<id>
+//	?since=&until=                     RFC 3339 time range, since inclusive and until exclusive
+//	?limit=&before=                    page size (default 50, at most 500) and the nextCursor of the previous page
+//
+// It responds {"data": [...], "nextCursor": "..."}. Every request must carry
+// "Authorization: Bearer <token>".
+func AuditHandler(audit *data.AuditLog, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /internal/audit", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		q := data.AuditQuery{
+			Actor:    query.Get("actor"),
+			Action:   query.Get("action"),
+			Entity:   query.Get("entity"),
+			EntityID: query.Get("entityId"),
+			Limit:    50,
+		}
+		for name, dst := range map[string]**time.Time{"since": &q.Since, "until": &q.Until} {
+			if raw := query.Get(name); raw != "" {
+				t, err := time.Parse(time.RFC3339, raw)
+				if err != nil {
+					http.Error(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+					return
+				}
+				*dst = &t
+			}
+		}
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			q.Limit = n
+		}
+		if raw := query.Get("before"); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id < 1 {
+				http.Error(w, "invalid cursor", http.StatusBadRequest)
+				return
+			}
+			q.BeforeID = id
+		}
+
+		entries, err := audit.Entries(r.Context(), q)
+		if errors.Is(err, data.ErrInvalidAuditQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			slog.Warn("audit request failed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		nextCursor := ""
+		if len(entries) == q.Limit {
+			nextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+		}
+		writeJSON(w, map[string]any{"data": entries, "nextCursor": nextCursor})
+	})
+	return requireBearerToken(token, mux)
+}
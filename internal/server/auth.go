@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"go-story/internal/data"
+)
+
+// Authenticate verifies the JWT sent as Authorization: Bearer with verifier
+// and passes its principal to next in the request context (see
+// data.PrincipalFromContext), for authorization decisions downstream. The
+// principal's subject replaces any X-User-ID header sent by the client, and
+// requests without a token pass through anonymously with X-User-ID
+// removed, so per-reader endpoints cannot be called on behalf of someone
+// else. An invalid or expired token is answered with 401. A nil verifier
+// returns next unchanged, trusting X-User-ID from the frontend.
+func Authenticate(verifier *data.JWTVerifier, next http.Handler) http.Handler {
+	if verifier == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			// 匿名的請求不得以 X-User-ID 冒用其他讀者
+			if r.Header.Get(restUserHeader) != "" {
+				r = r.Clone(r.Context())
+				r.Header.Del(restUserHeader)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, err := verifier.Verify(r.Context(), token)
+		if err != nil {
+			writeUnauthorized(w, err)
+			return
+		}
+		r = r.Clone(data.WithPrincipal(r.Context(), principal))
+		r.Header.Set(restUserHeader, principal.Subject)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken 回傳 Authorization header 中的 Bearer token，沒有時回傳空字串
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// writeUnauthorized 以 401 回應無效或過期的 token，讓用戶端更新 token 而非以匿名身分繼續
+func writeUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	writeJSON(w, APIError{Error: err.Error()})
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// CacheStatsHandler serves a JSON snapshot of cache.Stats for operators.
+func CacheStatsHandler(cache *data.Cache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, cache.Stats(r.Context()))
+	})
+}
+
+// CacheAdminHandler serves the cache admin API under /internal/cache/:
+//
+//	GET    /internal/cache/keys?key=K     inspect a key (decoded value and TTL)
+//	DELETE /internal/cache/keys?key=K     delete a key
+//	POST   /internal/cache/purge?prefix=P delete every key starting with P
+//	PUT    /internal/cache/enabled        toggle caching, body {"enabled": bool}
+//
+// Every request must carry "Authorization: Bearer <token>".
+func CacheAdminHandler(cache *data.Cache, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/internal/cache/keys", func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			http.Error(w, "missing key parameter", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			info, err := cache.Inspect(r.Context(), key)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("inspect failed: %v", err), http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, info)
+		case http.MethodDelete:
+			_ = cache.Delete(r.Context(), key)
+			writeJSON(w, map[string]any{"deleted": key})
+		default:
+			http.Error(w, "only GET or DELETE", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/internal/cache/purge", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST", http.StatusMethodNotAllowed)
+			return
+		}
+		// 空 prefix 會刪除整個 namespace，必須明確指定
+		prefix := r.URL.Query().Get("prefix")
+		if prefix == "" {
+			http.Error(w, "missing prefix parameter", http.StatusBadRequest)
+			return
+		}
+		n, _ := cache.DeleteByPrefix(r.Context(), prefix)
+		writeJSON(w, map[string]any{"prefix": prefix, "deleted": n})
+	})
+	mux.HandleFunc("/internal/cache/enabled", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "only PUT", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Enabled == nil {
+			http.Error(w, "invalid payload, need {\"enabled\": true|false}", http.StatusBadRequest)
+			return
+		}
+		cache.SetEnabled(*payload.Enabled)
+		writeJSON(w, map[string]any{"enabled": cache.Enabled()})
+	})
+
+	return requireBearerToken(token, mux)
+}
+
+// requireBearerToken 只放行帶有正確 Bearer token 的請求
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// setCacheHeaders 依這次請求實際用到的 cache entry 設定 Cache-Control、Age 與 X-Cache，
+// 讓 CDN 的快取時間與 Redis 中的剩餘 TTL 一致；沒有查詢 cache 時不設定
+func setCacheHeaders(w http.ResponseWriter, trace *data.CacheTrace, hasErrors bool) {
+	if trace.Lookups() == 0 {
+		return
+	}
+	if hasErrors {
+		w.Header().Set("Cache-Control", "no-store")
+		return
+	}
+
+	// max-age 以原始寫入時間起算，搭配 Age 讓 CDN 算出的剩餘時間等於 cache 中最短的剩餘 TTL
+	now := time.Now()
+	age := trace.Age(now)
+	cacheControl := fmt.Sprintf("public, max-age=%d", int((trace.MaxAge(now) + age).Seconds()))
+	if window := trace.StaleWindow(); window > 0 {
+		cacheControl += fmt.Sprintf(", stale-while-revalidate=%d", int(window.Seconds()))
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+
+	switch {
+	case trace.Hit() && trace.Stale():
+		w.Header().Set("X-Cache", "STALE")
+	case trace.Hit():
+		w.Header().Set("X-Cache", "HIT")
+	default:
+		w.Header().Set("X-Cache", "MISS")
+	}
+	if age > 0 {
+		w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+	}
+}
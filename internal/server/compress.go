@@ -0,0 +1,307 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encodingWriter 為可重複使用的壓縮 writer
+type encodingWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// responseEncoder 為一種 Content-Encoding，writer 以 pool 重複使用
+type responseEncoder struct {
+	name string
+	pool sync.Pool
+}
+
+func newResponseEncoder(name string, create func() encodingWriter) *responseEncoder {
+	return &responseEncoder{name: name, pool: sync.Pool{New: func() any { return create() }}}
+}
+
+// get 取出寫入 w 的壓縮 writer，用畢以 put 放回
+func (e *responseEncoder) get(w io.Writer) encodingWriter {
+	zw := e.pool.Get().(encodingWriter)
+	zw.Reset(w)
+	return zw
+}
+
+func (e *responseEncoder) put(zw encodingWriter) {
+	zw.Reset(nil)
+	e.pool.Put(zw)
+}
+
+// responseEncoders 為可用的壓縮方式，Accept-Encoding 的 q 值相同時依此順序選擇
+var responseEncoders = []*responseEncoder{
+	newResponseEncoder("br", func() encodingWriter {
+		// 等級 5 在即時壓縮的速度與壓縮率間取得平衡
+		return brotli.NewWriterLevel(nil, 5)
+	}),
+	newResponseEncoder("zstd", func() encodingWriter {
+		// 每個回應以單一 goroutine 壓縮；window 取 1 MB，遠低於瀏覽器接受的 8 MB 上限
+		zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(1<<20))
+		return zw
+	}),
+	newResponseEncoder("gzip", func() encodingWriter {
+		return gzip.NewWriter(nil)
+	}),
+}
+
+// DefaultCompressionMinSize is the response size below which Compress sends
+// the body as is; smaller bodies gain little and cost a round of framing.
+const DefaultCompressionMinSize = 1024
+
+// Compress compresses the responses of next with the best encoding the
+// client accepts (Accept-Encoding, by q-value): br, zstd or gzip, in that
+// order when the q-values tie. Only textual content types (text/*, JSON,
+// XML, JavaScript, SVG and NDJSON) of at least minSize bytes are
+// compressed; images, responses that already carry a Content-Encoding,
+// 204, 206 and 304 responses pass through unchanged. Responses of a
+// compressible type get "Vary: Accept-Encoding" whether or not they were
+// compressed.
+//
+// A compressed response is a different representation of the resource, so
+// its strong ETag gets the encoding appended ("abc" becomes "abc-gzip"); the
+// suffix is removed from If-None-Match before the request reaches next, so
+// handlers keep answering conditional requests with 304 (see
+// writeConditional). A minSize of 0 or less uses DefaultCompressionMinSize.
+func Compress(minSize int, next http.Handler) http.Handler {
+	if minSize <= 0 {
+		minSize = DefaultCompressionMinSize
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoder := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		cw := &compressWriter{ResponseWriter: w, encoder: encoder, minSize: minSize}
+		if encoder != nil {
+			r, cw.etagMatched = stripETagEncoding(r, encoder.name)
+		}
+		next.ServeHTTP(cw, r)
+		cw.close()
+	})
+}
+
+// compressWriter 先暫存回應的開頭，確定內容類型與大小達到門檻後才決定是否壓縮
+type compressWriter struct {
+	http.ResponseWriter
+	encoder     *responseEncoder // 協商出的壓縮方式，nil 表示不壓縮
+	minSize     int
+	etagMatched bool // If-None-Match 帶有加上此編碼後綴的 ETag
+
+	status      int
+	wroteHeader bool   // handler 已決定狀態碼
+	started     bool   // 已送出 header
+	buf         []byte // 決定是否壓縮前暫存的內容
+	zw          encodingWriter
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code < http.StatusOK {
+		// 1xx 不影響最終的回應
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	switch code {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		_ = w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.zw != nil:
+		return w.zw.Write(p)
+	case w.started:
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if w.encoder == nil || len(w.buf) >= w.minSize {
+		if err := w.start(w.encoder != nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush 送出目前的內容；尚未決定是否壓縮時，不論大小都依內容類型決定
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.started {
+		_ = w.start(w.encoder != nil)
+	}
+	if w.zw != nil {
+		_ = w.zw.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap 讓 http.ResponseController 取得原本的 ResponseWriter
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start 送出 header 與暫存的內容；compress 為 true 且內容可壓縮時改以 w.encoder 壓縮之後的內容
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	h := w.Header()
+	if _, ok := h["Content-Type"]; !ok && len(w.buf) > 0 {
+		// 與 net/http 相同，未設定時依內容判斷
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	switch {
+	case w.status == http.StatusNotModified && w.etagMatched:
+		// 304 的 ETag 需與用戶端持有的壓縮後內容相同
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", encodedETag(etag, w.encoder.name))
+		}
+		addVary(h, "Accept-Encoding")
+	case h.Get("Content-Encoding") == "" && compressibleType(h.Get("Content-Type")):
+		addVary(h, "Accept-Encoding")
+		if compress {
+			h.Set("Content-Encoding", w.encoder.name)
+			h.Del("Content-Length")
+			if etag := h.Get("ETag"); etag != "" {
+				h.Set("ETag", encodedETag(etag, w.encoder.name))
+			}
+			w.zw = w.encoder.get(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.zw != nil {
+		_, err := w.zw.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close 在 handler 結束後送出未達門檻的內容，或結束壓縮串流
+func (w *compressWriter) close() {
+	if w.wroteHeader && !w.started {
+		_ = w.start(false)
+	}
+	if w.zw != nil {
+		_ = w.zw.Close()
+		w.encoder.put(w.zw)
+		w.zw = nil
+	}
+}
+
+// negotiateEncoding 依 Accept-Encoding 選擇 q 值最高的壓縮方式 (q=0 視為不接受)，沒有可用的方式時回傳 nil
+func negotiateEncoding(accept string) *responseEncoder {
+	if accept == "" {
+		return nil
+	}
+	weights := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(params, "="); ok && strings.TrimSpace(name) == "q" {
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = v
+		}
+		weights[coding] = q
+	}
+	var best *responseEncoder
+	var bestQ float64
+	for _, encoder := range responseEncoders {
+		q, ok := weights[encoder.name]
+		if !ok {
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoder, q
+		}
+	}
+	return best
+}
+
+// compressibleType 判斷 Content-Type 是否為值得壓縮的文字格式
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// encodedETag 在強 ETag 後加上編碼名稱；弱 ETag 不受壓縮影響，維持不變
+func encodedETag(etag, encoding string) string {
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// stripETagEncoding 移除 If-None-Match 中 encodedETag 加上的 encoding 後綴，讓 handler 以原本的 ETag 比對；
+// 回傳的 bool 表示是否有移除
+func stripETagEncoding(r *http.Request, encoding string) (*http.Request, bool) {
+	match := r.Header.Get("If-None-Match")
+	suffix := "-" + encoding + `"`
+	if !strings.Contains(match, suffix) {
+		return r, false
+	}
+	candidates := strings.Split(match, ",")
+	for i, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasSuffix(candidate, suffix) {
+			candidate = strings.TrimSuffix(candidate, suffix) + `"`
+		}
+		candidates[i] = candidate
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("If-None-Match", strings.Join(candidates, ", "))
+	return r, true
+}
+
+// addVary 在 Vary header 加上 field，已列出時不重複
+func addVary(h http.Header, field string) {
+	for _, value := range h.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// writeConditional 寫入 body，附上由內容 hash 計算的強 ETag，以及 modified 不為零值時的 Last-Modified；
+// 請求的 If-None-Match 含該 ETag，或沒有 If-None-Match 而 If-Modified-Since 不早於 modified 時，
+// 改回應 304 Not Modified 且不寫入 body。呼叫前需先設定 Content-Type
+func writeConditional(w http.ResponseWriter, r *http.Request, body []byte, modified time.Time) {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	h := w.Header()
+	h.Set("ETag", etag)
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, modified) {
+		// 304 不帶內容相關的 header
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write(body)
+}
+
+// writeConditionalJSON 以 writeJSON 相同的格式編碼 v，再以 writeConditional 寫入
+func writeConditionalJSON(w http.ResponseWriter, r *http.Request, v interface{}, modified time.Time) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeConditional(w, r, buf.Bytes(), modified)
+}
+
+// notModified 依 RFC 9110 判斷條件式請求：有 If-None-Match 時只比對 ETag (弱比對)，否則比對 If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !modified.IsZero() {
+		t, err := http.ParseTime(since)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// lastModified 回傳回應內容的最後修改時間：單篇 story 為其 updatedAt；列表無法由內容得知
+// 是否有 story 被移出，只以 ETag 判斷，回傳零值
+func lastModified(body interface{}) time.Time {
+	if story, ok := body.(data.Story); ok {
+		return story.UpdatedAt
+	}
+	return time.Time{}
+}
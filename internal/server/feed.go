@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// feedCacheControl 為 feed 回應的快取標頭；feed reader 通常數分鐘才抓一次
+const feedCacheControl = "public, max-age=300"
+
+// NewFeedHandler serves the story feeds of feeds, newest first:
+//
+//	GET /feeds/{format}                  all published stories
+//	GET /feeds/sections/{name}/{format}  stories in a section
+//	GET /feeds/tags/{name}/{format}      stories with a tag
+//	GET /feeds/authors/{id}/{format}     stories by an author
+//
+// format is the feed format, e.g. json for JSON Feed 1.1. siteURL is the
+// public base URL the feed URLs are built from. Feeds carry an ETag
+// computed from their content and are answered with 304 Not Modified for a
+// matching If-None-Match.
+func NewFeedHandler(feeds *data.FeedService, siteURL string) http.Handler {
+	serve := func(query func(r *http.Request) data.FeedQuery) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			format := data.FeedFormat(r.PathValue("format"))
+			feedURL := strings.TrimRight(siteURL, "/") + r.URL.Path
+			body, err := feeds.Render(r.Context(), format, query(r), feedURL)
+			switch {
+			case errors.Is(err, data.ErrUnknownFeedFormat):
+				http.NotFound(w, r)
+				return
+			case errors.Is(err, data.ErrFeedsUnsupported):
+				http.Error(w, err.Error(), http.StatusNotImplemented)
+				return
+			case err != nil:
+				slog.Error("failed to render feed", "path", r.URL.Path, "error", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", data.FeedContentType(format))
+			w.Header().Set("Cache-Control", feedCacheControl)
+			writeConditional(w, r, body, time.Time{})
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds/{format}", serve(func(r *http.Request) data.FeedQuery {
+		return data.FeedQuery{}
+	}))
+	mux.HandleFunc("GET /feeds/sections/{name}/{format}", serve(func(r *http.Request) data.FeedQuery {
+		return data.FeedQuery{Section: r.PathValue("name")}
+	}))
+	mux.HandleFunc("GET /feeds/tags/{name}/{format}", serve(func(r *http.Request) data.FeedQuery {
+		return data.FeedQuery{Tag: r.PathValue("name")}
+	}))
+	mux.HandleFunc("GET /feeds/authors/{id}/{format}", serve(func(r *http.Request) data.FeedQuery {
+		return data.FeedQuery{Author: r.PathValue("id")}
+	}))
+	return mux
+}
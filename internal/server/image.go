@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-story/internal/data"
+)
+
+// imageCacheControl 為轉換後圖片的快取標頭；來源圖片可能在同一網址下更新，不設為 immutable
+const imageCacheControl = "public, max-age=86400"
+
+// NewImageHandler serves transformed images:
+//
+//	GET /images?url=<source>&w=&h=&crop=&q=&format=
+//
+// url must start with one of the proxy's sources. Without format the
+// response is WebP when the Accept header allows it (see
+// data.ImageTransform.Negotiate), otherwise the source's format.
+func NewImageHandler(proxy *data.ImageProxy) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /images", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		source := query.Get("url")
+		if source == "" {
+			http.Error(w, "url is required", http.StatusBadRequest)
+			return
+		}
+		t, err := data.ParseImageTransform(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if t.Negotiate(r.Header.Get("Accept")) {
+			w.Header().Set("Vary", "Accept")
+		}
+		img, err := proxy.Transform(r.Context(), source, t)
+		switch {
+		case errors.Is(err, data.ErrImageSourceForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case errors.Is(err, data.ErrImageSourceNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, data.ErrUnsupportedImage):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			slog.Warn("image transform failed", "url", source, "error", err)
+			http.Error(w, "failed to transform image", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", img.ContentType)
+		w.Header().Set("Cache-Control", imageCacheControl)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		_, _ = w.Write(img.Body)
+	})
+	return mux
+}
@@ -0,0 +1,114 @@
+package server_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/image/webp"
+
+	"go-story/internal/data"
+	"go-story/internal/data/cachetest"
+	"go-story/internal/server"
+)
+
+// testSourceImage 回傳涵蓋漸層、雜訊、單色區塊與透明區域的圖片，寬高不是 predictor 區塊的倍數
+func testSourceImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 70, 45))
+	seed := uint32(1)
+	for y := 0; y < 45; y++ {
+		for x := 0; x < 70; x++ {
+			seed = seed*1664525 + 1013904223
+			switch {
+			case x < 20:
+				img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 12), G: uint8(y * 5), B: uint8(x + y), A: 0xff})
+			case x < 40:
+				img.SetNRGBA(x, y, color.NRGBA{R: uint8(seed >> 24), G: uint8(seed >> 16), B: uint8(seed >> 8), A: 0xff})
+			case y < 30:
+				img.SetNRGBA(x, y, color.NRGBA{R: 0x20, G: 0x80, B: 0xc0, A: 0xff})
+			}
+		}
+	}
+	return img
+}
+
+func TestImageHandlerNegotiatesWebP(t *testing.T) {
+	src := testSourceImage()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, src); err != nil {
+		t.Fatalf("encode source: %v", err)
+	}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(origin.Close)
+
+	h := cachetest.NewMemory(t)
+	proxy := data.NewImageProxy(h.Cache, time.Minute, nil, []string{origin.URL + "/media/"})
+	handler := server.NewImageHandler(proxy)
+
+	req := httptest.NewRequest(http.MethodGet, "/images?url="+url.QueryEscape(origin.URL+"/media/photo.png"), nil)
+	req.Header.Set("Accept", "image/avif,image/webp,image/*,*/*;q=0.8")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/webp" {
+		t.Fatalf("Content-Type = %q, want image/webp", ct)
+	}
+	if vary := rec.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Vary = %q, want Accept", vary)
+	}
+
+	got, err := webp.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("decode webp: %v", err)
+	}
+	if got.Bounds() != src.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), src.Bounds())
+	}
+	// WebP 以 lossless 輸出，每個像素都應與來源相同
+	for y := 0; y < src.Bounds().Dy(); y++ {
+		for x := 0; x < src.Bounds().Dx(); x++ {
+			want := src.NRGBAAt(x, y)
+			if c := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA); c != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, c, want)
+			}
+		}
+	}
+}
+
+func TestImageHandlerKeepsSourceFormatWithoutWebP(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testSourceImage()); err != nil {
+		t.Fatalf("encode source: %v", err)
+	}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	t.Cleanup(origin.Close)
+
+	h := cachetest.NewMemory(t)
+	handler := server.NewImageHandler(data.NewImageProxy(h.Cache, time.Minute, nil, []string{origin.URL + "/media/"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/images?url="+url.QueryEscape(origin.URL+"/media/photo.png"), nil)
+	req.Header.Set("Accept", "image/png,image/*")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", ct)
+	}
+}
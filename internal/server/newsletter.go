@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-story/internal/data"
+)
+
+// NewsletterHandler serves the newsletter digests; {period} is daily or
+// weekly:
+//
+//	GET  /internal/newsletter/{period}/preview?format=html|json  the digest as the HTML email (default) or the payload sent to the email provider
+//	POST /internal/newsletter/{period}/send                      hand the digest to the email provider, e.g. from a daily cron job
+//
+// Sending an empty digest answers 409, and sending without a provider
+// configured 501. Every request must carry "Authorization: Bearer <token>".
+func NewsletterHandler(digests *data.DigestService, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /internal/newsletter/{period}/preview", func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "html" && format != "json" {
+			http.Error(w, "format must be html or json", http.StatusBadRequest)
+			return
+		}
+		email, err := digests.Email(r.Context(), data.DigestPeriod(r.PathValue("period")))
+		if err != nil {
+			writeNewsletterError(w, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "private, no-store")
+		if format == "json" {
+			writeJSON(w, email)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(email.HTML))
+	})
+	mux.HandleFunc("POST /internal/newsletter/{period}/send", func(w http.ResponseWriter, r *http.Request) {
+		period := data.DigestPeriod(r.PathValue("period"))
+		email, err := digests.Send(r.Context(), period)
+		if err != nil {
+			writeNewsletterError(w, err)
+			return
+		}
+		stories := 0
+		for _, section := range email.Digest.Sections {
+			stories += len(section.Stories)
+		}
+		slog.Info("sent newsletter digest", "period", period, "stories", stories)
+		writeJSON(w, map[string]any{"period": period, "subject": email.Subject, "stories": stories})
+	})
+	return requireBearerToken(token, mux)
+}
+
+// writeNewsletterError 將 digest 的錯誤轉為對應的 HTTP status
+func writeNewsletterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrInvalidDigestPeriod):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, data.ErrEmptyDigest):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, data.ErrDigestSenderUnset):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		slog.Error("newsletter request failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
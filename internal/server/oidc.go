@@ -0,0 +1,148 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-story/internal/data"
+)
+
+// 管理介面登入使用的 cookie：session token，以及綁定登入流程與瀏覽器的 state
+const (
+	adminSessionCookie = "gs_admin_session"
+	oidcStateCookie    = "gs_oidc_state"
+)
+
+// defaultLoginReturnTo 為登入後未指定 returnTo 時導向的頁面
+const defaultLoginReturnTo = "/auth/session"
+
+// AdminLoginHandler serves the OIDC login flow of the admin surface under
+// /auth:
+//
+//	GET  /auth/login?returnTo=/path  redirect to the identity provider
+//	GET  /auth/callback              the redirect URL registered with the provider; issues the session and redirects to returnTo
+//	GET  /auth/session               the caller's session, or 401
+//	POST /auth/logout                end the session; answers with the provider's logout URL, if any
+//
+// The session is kept in an HttpOnly, SameSite=Lax cookie, so it is not
+// sent with cross-site writes, and is accepted by the workflow API in
+// place of a Bearer token (see WorkflowHandler). Users whose groups grant
+// no role above reader cannot sign in.
+func AdminLoginHandler(sessions *data.AdminSessionService) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /auth/login", func(w http.ResponseWriter, r *http.Request) {
+		state, authURL, err := sessions.BeginLogin(r.Context(), loginReturnTo(r.URL.Query().Get("returnTo")))
+		if err != nil {
+			writeLoginError(w, err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state,
+			Path:     "/auth/",
+			MaxAge:   600,
+			HttpOnly: true,
+			Secure:   sessions.SecureCookies(),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+	mux.HandleFunc("GET /auth/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		clearCookie(w, oidcStateCookie, "/auth/", sessions.SecureCookies())
+		if reason := query.Get("error"); reason != "" {
+			http.Error(w, "login failed: "+reason, http.StatusUnauthorized)
+			return
+		}
+		state := query.Get("state")
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+			http.Error(w, data.ErrInvalidLogin.Error(), http.StatusBadRequest)
+			return
+		}
+		session, returnTo, err := sessions.CompleteLogin(r.Context(), state, query.Get("code"))
+		if err != nil {
+			writeLoginError(w, err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     adminSessionCookie,
+			Value:    session.Token,
+			Path:     "/",
+			MaxAge:   int(sessions.TTL().Seconds()),
+			HttpOnly: true,
+			Secure:   sessions.SecureCookies(),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, loginReturnTo(returnTo), http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /auth/session", func(w http.ResponseWriter, r *http.Request) {
+		session, err := adminSession(r, sessions)
+		if err != nil {
+			writeLoginError(w, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, session)
+	})
+	mux.HandleFunc("POST /auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(adminSessionCookie); err == nil {
+			if err := sessions.End(r.Context(), cookie.Value); err != nil {
+				writeLoginError(w, err)
+				return
+			}
+		}
+		clearCookie(w, adminSessionCookie, "/", sessions.SecureCookies())
+		writeJSON(w, map[string]string{"logoutUrl": sessions.LogoutURL(r.Context())})
+	})
+	return mux
+}
+
+// adminSession 回傳請求的 session cookie 對應的 session；沒有 cookie 時回傳 data.ErrSessionNotFound
+func adminSession(r *http.Request, sessions *data.AdminSessionService) (*data.AdminSession, error) {
+	cookie, err := r.Cookie(adminSessionCookie)
+	if err != nil {
+		return nil, data.ErrSessionNotFound
+	}
+	return sessions.Session(r.Context(), cookie.Value)
+}
+
+// hasCookie 回傳請求是否帶有 name cookie
+func hasCookie(r *http.Request, name string) bool {
+	_, err := r.Cookie(name)
+	return err == nil
+}
+
+// loginReturnTo 只接受站內的路徑作為登入後導向的頁面，避免被用來導向其他網站
+func loginReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return defaultLoginReturnTo
+	}
+	return returnTo
+}
+
+// clearCookie 刪除 cookie
+func clearCookie(w http.ResponseWriter, name, path string, secure bool) {
+	http.SetCookie(w, &http.Cookie{Name: name, Path: path, MaxAge: -1, HttpOnly: true, Secure: secure, SameSite: http.SameSiteLaxMode})
+}
+
+// writeLoginError 將登入與 session 的錯誤轉為對應的 HTTP 狀態碼
+func writeLoginError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrSessionNotFound), errors.Is(err, data.ErrOIDCRejected):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, data.ErrInvalidLogin):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, data.ErrLoginForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, data.ErrOIDCUnavailable):
+		slog.Warn("identity provider unavailable", "error", err)
+		http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+	default:
+		slog.Warn("admin login request failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPISchema 為 OpenAPI 3 的 schema object (只用到需要的欄位)
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Minimum              *int                      `json:"minimum,omitempty"`
+	Maximum              *int                      `json:"maximum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+// OpenAPIDocument is the OpenAPI 3 description of the REST API, generated
+// from the route definitions so it cannot drift from the handlers.
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+// timeType 為 time.Time 的型別，在 schema 中以 date-time 字串表示
+var timeType = reflect.TypeOf(time.Time{})
+
+// newOpenAPIDocument 依 routes 產生 OpenAPI 文件；回應型別中的 struct 會放在 components 並以 $ref 引用
+func newOpenAPIDocument(routes []restRoute) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI:    "3.0.3",
+		Info:       openAPIInfo{Title: "go-story REST API", Version: restAPIVersion},
+		Paths:      map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{Schemas: map[string]*openAPISchema{}},
+	}
+	errorResponse := func(description string) openAPIResponse {
+		return openAPIResponse{
+			Description: description,
+			Content:     map[string]openAPIMediaType{"application/json": {Schema: doc.schemaFor(reflect.TypeOf(APIError{}))}},
+		}
+	}
+
+	for _, route := range routes {
+		op := &openAPIOperation{
+			OperationID: route.OperationID,
+			Summary:     route.Summary,
+			Responses: map[string]openAPIResponse{
+				"400": errorResponse("Invalid parameters"),
+				"404": errorResponse("Not found"),
+			},
+		}
+		if route.Request != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  map[string]openAPIMediaType{"application/json": {Schema: doc.schemaFor(route.Request)}},
+			}
+		}
+		if route.Response != nil {
+			status := http.StatusOK
+			if route.Status != 0 {
+				status = route.Status
+			}
+			op.Responses[strconv.Itoa(status)] = openAPIResponse{
+				Description: http.StatusText(status),
+				Content:     map[string]openAPIMediaType{"application/json": {Schema: doc.schemaFor(route.Response)}},
+			}
+		} else {
+			op.Responses["204"] = openAPIResponse{Description: "No Content"}
+		}
+		if route.Private {
+			// 目前只有預覽使用 Private，一併列出 token 無效與過期的回應
+			op.Responses["403"] = errorResponse("Invalid preview token")
+			op.Responses["410"] = errorResponse("Preview token expired")
+		}
+		if route.Redirects {
+			op.Responses["301"] = errorResponse("Moved to the URL in the Location header")
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+		for _, param := range route.Params {
+			if param.In == "header" && param.Required {
+				op.Responses["401"] = errorResponse("Missing signed-in reader")
+			}
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:        param.Name,
+				In:          param.In,
+				Description: param.Description,
+				Required:    param.Required || param.In == "path",
+				Schema:      &openAPISchema{Type: param.Type, Format: param.Format, Minimum: param.Minimum, Maximum: param.Maximum},
+			})
+		}
+
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	// 文件本身
+	doc.Paths["/api/"+restAPIVersion+"/openapi.json"] = map[string]*openAPIOperation{
+		strings.ToLower(http.MethodGet): {
+			OperationID: "getOpenAPIDocument",
+			Summary:     "This document.",
+			Responses: map[string]openAPIResponse{
+				"200": {Description: "OK", Content: map[string]openAPIMediaType{"application/json": {Schema: &openAPISchema{Type: "object"}}}},
+			},
+		},
+	}
+	return doc
+}
+
+// schemaFor 以 reflection 依 json tag 產生 t 的 schema
+func (d *OpenAPIDocument) schemaFor(t reflect.Type) *openAPISchema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema *openAPISchema
+	switch {
+	case t == timeType:
+		schema = &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// 先佔位，避免自我參照的型別無限遞迴
+			object := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+			d.Components.Schemas[name] = object
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				jsonName := jsonFieldName(field)
+				if jsonName == "" {
+					continue
+				}
+				object.Properties[jsonName] = d.schemaFor(field.Type)
+			}
+		}
+		schema = &openAPISchema{Ref: "#/components/schemas/" + name}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = &openAPISchema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		schema = &openAPISchema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Bool:
+		schema = &openAPISchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = &openAPISchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = &openAPISchema{Type: "number"}
+	case t.Kind() == reflect.String:
+		schema = &openAPISchema{Type: "string"}
+	default:
+		schema = &openAPISchema{}
+	}
+
+	// OpenAPI 3.0 的 $ref 不能帶其他欄位，指向 struct 的指標不標記 nullable
+	if nullable && schema.Ref == "" {
+		schema.Nullable = true
+	}
+	return schema
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+
+	"go-story/internal/data"
+)
+
+// visitorHeader 為計次文章辨識訪客的 header，與瀏覽數的 visitor 參數為同一個識別碼
+const visitorHeader = "X-Visitor-ID"
+
+// Entitlements reads the reader's membership token (Authorization: Bearer)
+// and visitor ID (X-Visitor-ID) for paywall, and passes their entitlement
+// to next in the request context (see data.EntitlementFromContext). Behind
+// Authenticate, membership is read from the authenticated principal
+// instead. An invalid or expired token is answered with 401, so clients
+// refresh it instead of silently reading as anonymous. A nil paywall
+// returns next unchanged.
+func Entitlements(paywall *data.Paywall, next http.Handler) http.Handler {
+	if paywall == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visitor := r.Header.Get(visitorHeader)
+		if principal := data.PrincipalFromContext(r.Context()); principal != nil {
+			e := paywall.PrincipalEntitlement(principal, visitor)
+			next.ServeHTTP(w, r.WithContext(data.WithEntitlement(r.Context(), e)))
+			return
+		}
+		e, err := paywall.Entitlement(bearerToken(r), visitor)
+		if err != nil {
+			writeUnauthorized(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(data.WithEntitlement(r.Context(), e)))
+	})
+}
+
+// entitled 回傳請求是否帶有會員資格或訪客識別碼，此時付費文章的回應依讀者而不同
+func entitled(r *http.Request) bool {
+	e := data.EntitlementFromContext(r.Context())
+	return e.Member || e.Visitor != ""
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// apiKeyHeader 為辨識 API 使用者的 header
+const apiKeyHeader = "X-API-Key"
+
+// Route groups rate limited by their own policy (see RateLimitConfig.Group).
+const (
+	RateLimitGroupGraphQL  = "graphql"
+	RateLimitGroupREST     = "rest"
+	RateLimitGroupFeeds    = "feeds"
+	RateLimitGroupImages   = "images"
+	RateLimitGroupAMP      = "amp"
+	RateLimitGroupSitemaps = "sitemaps"
+)
+
+// RateLimitGroups lists the route groups a policy can be set for.
+var RateLimitGroups = []string{RateLimitGroupGraphQL, RateLimitGroupREST, RateLimitGroupFeeds, RateLimitGroupImages, RateLimitGroupAMP, RateLimitGroupSitemaps}
+
+// RateLimitConfig holds the limits applied by RateLimit.
+type RateLimitConfig struct {
+	// Group names the route group the policy is for; requests are counted
+	// per group, so each group has its own budget. Routes with an empty
+	// Group share one count.
+	Group     string
+	PerIP     int           // 每個 IP 在 Window 內的請求上限，0 表示不限制
+	PerAPIKey int           // 每個驗證通過的 API key 在 Window 內的請求上限，0 表示改用 IP 限制
+	Window    time.Duration // 計算請求次數的時間窗
+	// TrustedProxies lists the load balancers and proxies in front of the
+	// server. Only requests from them have their client IP taken from
+	// X-Forwarded-For; otherwise the IP is that of the connection.
+	TrustedProxies []netip.Prefix
+}
+
+// RateLimit throttles next per API key when one is verified by APIKeys,
+// otherwise per client IP: verified keys are throttled by ID with their own
+// rate limit when set and PerAPIKey otherwise, and keys that were not
+// verified are ignored. Responses carry the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers of the
+// IETF RateLimit header fields draft (and the older X-RateLimit-Limit and
+// X-RateLimit-Remaining); throttled requests get a 429 JSON response with a
+// Retry-After header.
+func RateLimit(limiter *data.RateLimiter, cfg RateLimitConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, limit := "ip:"+clientIP(r, cfg.TrustedProxies), cfg.PerIP
+		apiKey := data.APIKeyFromContext(r.Context())
+		switch {
+		case apiKey != nil && apiKey.RateLimit > 0:
+			key, limit = "key:"+apiKey.ID, apiKey.RateLimit
+		case apiKey != nil && cfg.PerAPIKey > 0:
+			key, limit = "key:"+apiKey.ID, cfg.PerAPIKey
+		}
+		if limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if cfg.Group != "" {
+			key = cfg.Group + ":" + key
+		}
+
+		res, err := limiter.Allow(r.Context(), key, limit, cfg.Window)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reset := res.Reset
+		if reset <= 0 {
+			reset = cfg.Window
+		}
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		h.Set("RateLimit-Policy", strconv.Itoa(res.Limit)+";w="+strconv.Itoa(ceilSeconds(cfg.Window)))
+		h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			h.Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			writeJSON(w, APIError{Error: "too many requests"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds 將時間無條件進位為秒數，至少為 1
+func ceilSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
+
+// clientIP 回傳請求來源 IP；連線來自 trusted 中的 proxy 時，由右而左取 X-Forwarded-For 中第一個不是 trusted 的位址，
+// 左邊的位址由 client 自行填寫，不採信
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !trustedProxy(host, trusted) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !trustedProxy(hop, trusted) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+// trustedProxy 回報 ip 是否在 trusted 中
+func trustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
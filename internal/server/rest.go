@@ -0,0 +1,1375 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// restParam describes a path or query parameter of a REST route. It is used
+// both to validate requests and to generate the OpenAPI document.
+type restParam struct {
+	Name        string
+	In          string // "path"、"query" 或 "header"
+	Type        string // "string" 或 "integer"
+	Format      string // 例如 "date-time"；只用於 OpenAPI 文件
+	Description string
+	Required    bool
+	Minimum     *int
+	Maximum     *int
+}
+
+// restRoute is one operation of the REST API.
+type restRoute struct {
+	Method      string
+	Path        string // OpenAPI 格式的路徑，例如 /api/v1/stories/{slug}
+	OperationID string
+	Summary     string
+	Tag         string
+	Params      []restParam
+	Request     reflect.Type // JSON request body 的型別，由 Handle 以 decodeRESTBody 讀取；nil 表示沒有 body
+	Response    reflect.Type // 200 回應的型別，用於產生 schema；nil 表示成功時回傳 204 No Content
+	Status      int          // 有回應內容時成功的 status，預設為 200
+	Private     bool         // 回應含未發布內容，不得由共用的 cache 或 CDN 保存
+	PerUser     bool         // 帶有 restUserHeader 時回應含該使用者的資料，不得由共用的 cache 或 CDN 保存
+	Gated       bool         // 回應依讀者的會員資格與計次而不同 (見 Entitlements)，帶有時不得由共用的 cache 或 CDN 保存
+	Redirects   bool         // 以舊 slug 請求時回應 301 轉到目前的網址
+	Lookup      bool         // 以 POST body 指定查詢的讀取 (例如 batchGet)，與 GET 相同接受 fields 參數
+	Handle      func(r *http.Request, params restValues) (interface{}, error)
+}
+
+// restValues 為驗證後的參數
+type restValues struct {
+	strings map[string]string
+	ints    map[string]int
+	fields  map[string]bool // fields 參數選取的 story 欄位，nil 表示全部
+}
+
+func (v restValues) String(name string) string { return v.strings[name] }
+
+func (v restValues) Int(name string) int { return v.ints[name] }
+
+// Wants 回傳回應是否需要 story 的 field 欄位，未選取的欄位不必計算
+func (v restValues) Wants(field string) bool { return v.fields == nil || v.fields[field] }
+
+// restError 為帶有 HTTP status 的錯誤，例如參數驗證失敗；Location 用於 301 轉址
+type restError struct {
+	Status   int
+	Message  string
+	Location string
+}
+
+func (e *restError) Error() string { return e.Message }
+
+// APIError is the body of every non-2xx REST response.
+type APIError struct {
+	Error string `json:"error"`
+}
+
+// StoryList is the body of the REST story listing endpoints. Pass
+// NextCursor as the after parameter to fetch the next page.
+type StoryList struct {
+	Data        []data.Story `json:"data"`
+	Limit       int          `json:"limit"`
+	Offset      int          `json:"offset"`
+	NextCursor  string       `json:"nextCursor,omitempty"`
+	HasNextPage bool         `json:"hasNextPage"`
+}
+
+// SearchResults is the body of GET /api/v1/search. Each hit carries the
+// story, its relevance score and highlighted snippets.
+type SearchResults struct {
+	Data   []data.SearchHit `json:"data"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+// RelatedStoryList is the body of GET /api/v1/stories/{slug}/related,
+// best match first.
+type RelatedStoryList struct {
+	Data []data.RelatedStory `json:"data"`
+}
+
+// TrendingStoryList is the body of the trending and most-read endpoints,
+// highest score first.
+type TrendingStoryList struct {
+	Window data.TrendingWindow  `json:"window"`
+	Data   []data.TrendingStory `json:"data"`
+}
+
+// AuthorList is the body of GET /api/v1/authors.
+type AuthorList struct {
+	Data        []data.Author `json:"data"`
+	Limit       int           `json:"limit"`
+	Offset      int           `json:"offset"`
+	HasNextPage bool          `json:"hasNextPage"`
+}
+
+// CollectionList is the body of GET /api/v1/collections.
+type CollectionList struct {
+	Data        []data.Collection `json:"data"`
+	Limit       int               `json:"limit"`
+	Offset      int               `json:"offset"`
+	HasNextPage bool              `json:"hasNextPage"`
+}
+
+// CollectionDetail is the body of GET /api/v1/collections/{slug}: the
+// collection and its published stories in reading order.
+type CollectionDetail struct {
+	Collection data.Collection `json:"collection"`
+	Stories    []data.Story    `json:"stories"`
+}
+
+// StorySeriesList is the body of GET /api/v1/stories/{slug}/series: the
+// story's place in each collection containing it.
+type StorySeriesList struct {
+	Data []data.StorySeries `json:"data"`
+}
+
+// TermList is the body of GET /api/v1/tags and GET /api/v1/categories.
+type TermList struct {
+	Data        []data.Term `json:"data"`
+	Limit       int         `json:"limit"`
+	Offset      int         `json:"offset"`
+	HasNextPage bool        `json:"hasNextPage"`
+}
+
+// StoryPreview is the body of GET /api/v1/preview/{token}: the story in
+// any status and when the preview token stops working.
+type StoryPreview struct {
+	Story     data.Story `json:"story"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+}
+
+// CommentList is the body of GET /api/v1/stories/{slug}/comments:
+// top-level comments newest first, each with its replies. Total counts
+// every approved comment of the story, replies included. Pass NextCursor
+// as the after parameter to fetch the next page.
+type CommentList struct {
+	Data        []data.Comment `json:"data"`
+	Total       int            `json:"total"`
+	NextCursor  string         `json:"nextCursor,omitempty"`
+	HasNextPage bool           `json:"hasNextPage"`
+}
+
+// CommentRequest is the body of POST /api/v1/stories/{slug}/comments. The
+// comment is posted for the signed-in reader in X-User-ID.
+type CommentRequest struct {
+	AuthorName string `json:"authorName"`
+	Body       string `json:"body"`
+	ParentID   string `json:"parentId,omitempty"` // 回覆的留言
+}
+
+// ReactionRequest is the body of POST /api/v1/stories/{slug}/reactions.
+// Like CommentRequest, the reaction is toggled for the signed-in reader in
+// X-User-ID.
+type ReactionRequest struct {
+	Reaction string `json:"reaction"`
+}
+
+// StoryReactions is the body of GET /api/v1/stories/{slug}/reactions.
+type StoryReactions struct {
+	StoryID string           `json:"storyId"`
+	Totals  map[string]int64 `json:"totals"`
+}
+
+// BookmarkList is the body of GET /api/v1/bookmarks: the reader's saved
+// stories, most recently saved first. Stories no longer published are
+// left out, so a page may be shorter than limit. Pass NextCursor as the
+// after parameter to fetch the next page.
+type BookmarkList struct {
+	Data        []BookmarkedStory `json:"data"`
+	NextCursor  string            `json:"nextCursor,omitempty"`
+	HasNextPage bool              `json:"hasNextPage"`
+}
+
+// BookmarkedStory is a story in a BookmarkList.
+type BookmarkedStory struct {
+	Story        data.Story `json:"story"`
+	BookmarkedAt time.Time  `json:"bookmarkedAt"`
+}
+
+// ReadingProgressRequest is the body of PUT
+// /api/v1/stories/{slug}/progress.
+type ReadingProgressRequest struct {
+	Position float64 `json:"position"`         // 捲動比例，0–1
+	Anchor   string  `json:"anchor,omitempty"` // 最近的標題 id，見 tableOfContents
+}
+
+// ReadingProgressList is the body of GET /api/v1/progress: the stories the
+// reader started but did not finish, most recently read first. Stories no
+// longer published are left out, so a page may be shorter than limit.
+type ReadingProgressList struct {
+	Data   []StoryProgress `json:"data"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// StoryProgress is a story in a ReadingProgressList.
+type StoryProgress struct {
+	Story    data.Story           `json:"story"`
+	Progress data.ReadingProgress `json:"progress"`
+}
+
+// StoryBatchRequest is the body of POST /api/v1/stories:batchGet: up to
+// 100 stories, each by id or slug.
+type StoryBatchRequest struct {
+	Keys []data.StoryKey `json:"keys"`
+}
+
+// StoryBatchResult is the body of POST /api/v1/stories:batchGet: one item
+// per requested key, in request order.
+type StoryBatchResult struct {
+	Data []StoryBatchItem `json:"data"`
+}
+
+// StoryBatchItem is the result for one key of a StoryBatchRequest: the
+// story with status 200, or the status and error a GET of the story alone
+// would have answered with.
+type StoryBatchItem struct {
+	Key    data.StoryKey `json:"key"`
+	Status int           `json:"status"`
+	Story  *data.Story   `json:"story,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// StoryViews is the body of GET /api/v1/stories/{slug}/views.
+type StoryViews struct {
+	StoryID string `json:"storyId"`
+	Views   int64  `json:"views"`
+}
+
+// restAPIVersion 為 REST API 與 OpenAPI 文件的版本；restDefaultLimit 為列表未指定 limit 時的筆數；
+// restMaxBodySize 為 request body 的大小上限；restUserHeader 為前台驗證讀者登入後帶入的使用者 ID；
+// restMaxBatchKeys 為 batchGet 一次可查詢的 story 數
+const (
+	restAPIVersion   = "v1"
+	restDefaultLimit = 20
+	restMaxBodySize  = 64 << 10
+	restUserHeader   = "X-User-ID"
+	restMaxBatchKeys = 100
+)
+
+// NewRESTHandler serves the versioned REST API under /api/v1/ on top of
+// stories, search and related, plus its OpenAPI 3 document at GET
+// /api/v1/openapi.json. Only published stories are returned, except through
+// a preview token from previews. A nil search makes /api/v1/search answer
+// 501, and a nil previews does the same for /api/v1/preview/{token}; a nil
+// views reports persisted view counts only. A nil comments makes the
+// comment endpoints answer 501 and leaves out commentCount; a nil
+// reactions does the same for the reaction endpoints and reactions, and a
+// nil bookmarks for the bookmark endpoints and isBookmarked. A nil
+// progress makes the reading progress endpoints answer 501, and a nil
+// pushDevices the push device endpoints. Paid stories are gated by
+// paywall, whose entitlement is read by Entitlements; a nil paywall serves
+// every story in full.
+//
+// Readers are identified by the X-User-ID header, which the API trusts; it
+// must be set by a frontend that authenticates readers. Responses that
+// depend on it are sent with Cache-Control: private.
+//
+// GET responses carry a strong ETag computed from their content, and
+// stories also a Last-Modified from updatedAt; requests with a matching
+// If-None-Match, or an If-Modified-Since not older than the story, are
+// answered with 304 Not Modified.
+//
+// POST /api/v1/stories:batchGet looks up to 100 stories by id or slug at
+// once, for clients that would otherwise send a request per story. Each
+// key gets an item in request order, with its own status and error, so one
+// missing story does not fail the rest. The stories are read from the cache
+// in one round trip, and the misses from the store in one query.
+//
+// GET routes whose response contains stories, and batchGet, take a fields parameter, e.g.
+// fields=title,slug,coverImage, that trims every story to those fields (id
+// is always kept), so card lists need not download story bodies. The
+// ETag is computed from the trimmed response, so each selection is cached
+// and revalidated on its own.
+func NewRESTHandler(stories *data.StoryService, search *data.SearchService, related *data.RelatedService, trending *data.TrendingService, views *data.ViewCounter, previews *data.PreviewService, comments *data.CommentService, reactions *data.ReactionService, bookmarks *data.BookmarkService, progress *data.ReadingProgressService, pushDevices *data.PushDeviceService, paywall *data.Paywall) http.Handler {
+	routes := restRoutes(stories, search, related, trending, views, previews, comments, reactions, bookmarks, progress, pushDevices, paywall)
+	for i, route := range routes {
+		if (route.Method == http.MethodGet || route.Lookup) && containsStory(route.Response) {
+			routes[i].Params = append(slices.Clone(route.Params), storyFieldsParam)
+		}
+	}
+	doc := newOpenAPIDocument(routes)
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.HandleFunc(route.Method+" "+route.Path, func(w http.ResponseWriter, r *http.Request) {
+			if route.PerUser {
+				w.Header().Add("Vary", restUserHeader)
+			}
+			if route.Gated {
+				w.Header().Add("Vary", "Authorization, "+visitorHeader)
+			}
+			if route.Private || (route.PerUser && r.Header.Get(restUserHeader) != "") || (route.Gated && entitled(r)) {
+				w.Header().Set("Cache-Control", "private, no-store")
+			}
+			params, err := parseRESTParams(r, route.Params)
+			if err == nil {
+				params.fields, err = parseStoryFields(params.String("fields"))
+			}
+			if err == nil {
+				var body interface{}
+				if body, err = route.Handle(r, params); err == nil {
+					if route.Response == nil {
+						w.WriteHeader(http.StatusNoContent)
+						return
+					}
+					if route.Status != 0 {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(route.Status)
+						writeJSON(w, body)
+						return
+					}
+					if params.fields != nil {
+						writeSparseJSON(w, r, body, params.fields)
+						return
+					}
+					if route.Method == http.MethodGet {
+						writeConditionalJSON(w, r, body, lastModified(body))
+						return
+					}
+					writeJSON(w, body)
+					return
+				}
+			}
+			writeRESTError(w, err)
+		})
+	}
+	mux.HandleFunc("GET /api/"+restAPIVersion+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, doc)
+	})
+	return mux
+}
+
+// restRoutes 定義 REST API 的所有 operation
+func restRoutes(stories *data.StoryService, search *data.SearchService, related *data.RelatedService, trending *data.TrendingService, views *data.ViewCounter, previews *data.PreviewService, comments *data.CommentService, reactions *data.ReactionService, bookmarks *data.BookmarkService, progress *data.ReadingProgressService, pushDevices *data.PushDeviceService, paywall *data.Paywall) []restRoute {
+	storyListParams := []restParam{
+		{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+		{Name: "offset", In: "query", Type: "integer", Description: "Number of stories to skip. Prefer after for deep pages.", Minimum: intPtr(0)},
+		{Name: "after", In: "query", Type: "string", Description: "Cursor from nextCursor of the previous page, requested with the same sort."},
+		{Name: "sort", In: "query", Type: "string", Description: "Comma-separated sort fields out of publishedAt, updatedAt and popularity; prefix with - for descending. Default -publishedAt."},
+	}
+	// 讀者的使用者 ID，由驗證登入的前台帶入
+	userParam := restParam{Name: restUserHeader, In: "header", Type: "string", Description: "ID of the signed-in reader, set by a frontend that authenticates readers."}
+	requiredUserParam := userParam
+	requiredUserParam.Required = true
+	paywallParams := []restParam{
+		{Name: "Authorization", In: "header", Type: "string", Description: "Bearer membership token issued by the membership system; members may read every paid story."},
+		{Name: visitorHeader, In: "header", Type: "string", Description: "Opaque, stable ID of the visitor, the same as for view reports. Metered stories are counted against it; without it they are withheld from non-members."},
+	}
+	publishedParams := []restParam{
+		{Name: "publishedFrom", In: "query", Type: "string", Format: "date-time", Description: "Only stories published at or after this time."},
+		{Name: "publishedTo", In: "query", Type: "string", Format: "date-time", Description: "Only stories published before this time."},
+	}
+	storyListParams = append(storyListParams, publishedParams...)
+	withListParams := func(params ...restParam) []restParam {
+		return append(params, storyListParams...)
+	}
+	listStories := func(r *http.Request, params restValues, opts data.StoryListOptions) (interface{}, error) {
+		opts.Limit, opts.Offset, opts.After = params.Int("limit"), params.Int("offset"), params.String("after")
+		opts.OrderBy = parseRESTSort(params.String("sort"))
+		if published := publishedRange(params); published != nil {
+			opts.Where = &data.StoryWhereInput{PublishedAt: published}
+		}
+		if opts.Limit == 0 {
+			opts.Limit = restDefaultLimit
+		}
+		page, err := stories.StoriesPage(r.Context(), params.String("q"), opts)
+		if err != nil {
+			return nil, err
+		}
+		list := StoryList{Data: withholdStories(paywall, page.Stories), Limit: opts.Limit, Offset: opts.Offset, HasNextPage: page.HasNextPage}
+		if page.HasNextPage {
+			list.NextCursor = page.EndCursor
+		}
+		return list, nil
+	}
+	storyListType := reflect.TypeOf(StoryList{})
+	// 作者頁以 ID 或 slug 查詢：先以 ID 查詢，查無時改以 slug 查詢
+	authorByIDOrSlug := func(r *http.Request, idOrSlug string) (*data.Author, error) {
+		author, err := stories.Author(r.Context(), idOrSlug, "")
+		if errors.Is(err, data.ErrAuthorNotFound) {
+			return stories.Author(r.Context(), "", idOrSlug)
+		}
+		return author, err
+	}
+	termListParams := []restParam{
+		{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+		{Name: "offset", In: "query", Type: "integer", Description: "Number of terms to skip.", Minimum: intPtr(0)},
+	}
+	listTerms := func(r *http.Request, params restValues, kind string) (interface{}, error) {
+		limit, offset := params.Int("limit"), params.Int("offset")
+		if limit == 0 {
+			limit = restDefaultLimit
+		}
+		page, err := stories.TermsPage(r.Context(), kind, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		return TermList{Data: page.Terms, Limit: limit, Offset: offset, HasNextPage: page.HasNextPage}, nil
+	}
+	rankingParams := []restParam{
+		{Name: "window", In: "query", Type: "string", Description: "Time window: 1h, 24h or 7d (default 24h)."},
+		{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Number of stories (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+	}
+	rankStories := func(r *http.Request, params restValues, rank func(ctx context.Context, window data.TrendingWindow, limit int) ([]data.TrendingStory, error)) (interface{}, error) {
+		window := data.TrendingWindow(params.String("window"))
+		if window == "" {
+			window = data.TrendingWindowDay
+		}
+		ranked, err := rank(r.Context(), window, params.Int("limit"))
+		if err != nil {
+			return nil, err
+		}
+		ranked = slices.Clone(ranked)
+		for i := range ranked {
+			paywall.Withhold(&ranked[i].Story)
+		}
+		return TrendingStoryList{Window: window, Data: ranked}, nil
+	}
+
+	return []restRoute{
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories", OperationID: "listStories", Tag: "stories",
+			Summary: "List published stories, newest first.",
+			Params: withListParams(
+				restParam{Name: "section", In: "query", Type: "string", Description: "Only stories in this section."},
+				restParam{Name: "tag", In: "query", Type: "string", Description: "Only stories with this tag."},
+				restParam{Name: "author", In: "query", Type: "string", Description: "Only stories by this author ID."},
+				restParam{Name: "locale", In: "query", Type: "string", Description: "Only stories in this language (BCP 47, e.g. en-US)."},
+				restParam{Name: "q", In: "query", Type: "string", Description: "Search in title, summary and body."},
+			),
+			Response: storyListType,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return listStories(r, params, data.StoryListOptions{
+					Section: params.String("section"),
+					Tag:     params.String("tag"),
+					Author:  params.String("author"),
+					Locale:  params.String("locale"),
+				})
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/search", OperationID: "searchStories", Tag: "search",
+			Summary: "Full-text search over published stories, best match first.",
+			Params: append([]restParam{
+				{Name: "q", In: "query", Type: "string", Required: true, Description: `Search terms; all must match. "quoted text" matches a phrase and -term excludes a term.`},
+				{Name: "section", In: "query", Type: "string", Description: "Only stories in this section."},
+				{Name: "tag", In: "query", Type: "string", Description: "Only stories with this tag."},
+				{Name: "author", In: "query", Type: "string", Description: "Only stories by this author ID."},
+				{Name: "locale", In: "query", Type: "string", Description: "Only stories in this language (BCP 47, e.g. en-US)."},
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+				{Name: "offset", In: "query", Type: "integer", Description: "Number of hits to skip.", Minimum: intPtr(0)},
+			}, publishedParams...),
+			Response: reflect.TypeOf(SearchResults{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				locale, err := stories.LocaleFilter(params.String("locale"))
+				if err != nil {
+					return nil, err
+				}
+				q := data.SearchQuery{
+					Query: params.String("q"),
+					Where: &data.StoryWhereInput{
+						Section:     equalsFilter(params.String("section")),
+						Tag:         equalsFilter(params.String("tag")),
+						Author:      equalsFilter(params.String("author")),
+						Locale:      locale,
+						PublishedAt: publishedRange(params),
+					},
+					Limit:  params.Int("limit"),
+					Offset: params.Int("offset"),
+				}
+				if q.Limit == 0 {
+					q.Limit = restDefaultLimit
+				}
+				result, err := search.Search(r.Context(), q)
+				if err != nil {
+					return nil, err
+				}
+				hits := slices.Clone(result.Hits)
+				for i := range hits {
+					paywall.Withhold(&hits[i].Story)
+				}
+				return SearchResults{Data: hits, Total: result.Total, Limit: q.Limit, Offset: q.Offset}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/trending", OperationID: "listTrendingStories", Tag: "stories",
+			Summary:  "List published stories with the most views in a time window, recent views weighing more.",
+			Params:   rankingParams,
+			Response: reflect.TypeOf(TrendingStoryList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return rankStories(r, params, trending.Trending)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/most-read", OperationID: "listMostReadStories", Tag: "stories",
+			Summary:  "List published stories with the most views in a time window.",
+			Params:   rankingParams,
+			Response: reflect.TypeOf(TrendingStoryList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return rankStories(r, params, trending.MostRead)
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/stories:batchGet", OperationID: "batchGetStories", Tag: "stories",
+			Summary:  fmt.Sprintf("Get up to %d published stories by id or slug at once. data has an item per key in request order: status 200 with the story, or the status and error of that key alone. Paid stories come without body, blocks, bodyHtml and tableOfContents.", restMaxBatchKeys),
+			Request:  reflect.TypeOf(StoryBatchRequest{}),
+			Response: reflect.TypeOf(StoryBatchResult{}),
+			Lookup:   true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				var req StoryBatchRequest
+				if err := decodeRESTBody(r, &req); err != nil {
+					return nil, err
+				}
+				if len(req.Keys) == 0 {
+					return nil, &restError{Status: http.StatusBadRequest, Message: "keys is required"}
+				}
+				if len(req.Keys) > restMaxBatchKeys {
+					return nil, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("at most %d keys are allowed", restMaxBatchKeys)}
+				}
+				// 只查詢有效的 key；無效的 key 各自回應 400
+				items := make([]StoryBatchItem, len(req.Keys))
+				keys := []data.StoryKey{}
+				for i, key := range req.Keys {
+					items[i].Key = key
+					if (key.ID == "") == (key.Slug == "") {
+						items[i].Status = http.StatusBadRequest
+						items[i].Error = "key needs exactly one of id and slug"
+						continue
+					}
+					keys = append(keys, key)
+				}
+				found, err := stories.StoriesByKey(r.Context(), keys)
+				if err != nil {
+					return nil, err
+				}
+				for i := range items {
+					if items[i].Status != 0 {
+						continue
+					}
+					story := found[0]
+					found = found[1:]
+					if story == nil {
+						items[i].Status = http.StatusNotFound
+						items[i].Error = data.ErrStoryNotFound.Error()
+						continue
+					}
+					// 複製一份再隱藏付費內容，避免改到 cache 中的值
+					current := *story
+					paywall.Withhold(&current)
+					items[i].Status = http.StatusOK
+					items[i].Story = &current
+				}
+				return StoryBatchResult{Data: items}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}", OperationID: "getStory", Tag: "stories",
+			Summary:   "Get a published story by slug. Renamed slugs answer 301 with the current URL. With a signed-in reader, isBookmarked tells whether the reader saved the story. Paid stories the reader may not read come without body, blocks, bodyHtml and tableOfContents; paywall tells why.",
+			Params:    append([]restParam{{Name: "slug", In: "path", Type: "string", Required: true}, userParam}, paywallParams...),
+			Response:  reflect.TypeOf(data.Story{}),
+			Redirects: true,
+			PerUser:   true,
+			Gated:     true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				if story.Slug != params.String("slug") {
+					// 舊 slug 經由轉址找到 story
+					return nil, &restError{
+						Status:   http.StatusMovedPermanently,
+						Message:  "story moved to " + story.Slug,
+						Location: "/api/" + restAPIVersion + "/stories/" + url.PathEscape(story.Slug),
+					}
+				}
+				// 複製一份再加上尚未寫入的瀏覽數、embed 的 oEmbed payload 與轉換後的 body，避免改到 cache 中的值；
+				// 讀者無權閱讀的付費文章不提供內文。fields 未選取的欄位不計算
+				current := *story
+				paywall.Gate(r.Context(), &current, data.EntitlementFromContext(r.Context()))
+				if params.Wants("viewCount") {
+					current.ViewCount = views.Total(r.Context(), story)
+				}
+				if !paywall.Withheld(&current) {
+					if params.Wants("blocks") {
+						current.Blocks = stories.Blocks(r.Context(), story)
+					}
+					if params.Wants("bodyHtml") {
+						if current.BodyHTML, err = stories.BodyHTML(r.Context(), story); err != nil {
+							return nil, err
+						}
+					}
+					if params.Wants("tableOfContents") {
+						if current.TableOfContents, err = stories.TableOfContents(r.Context(), story); err != nil {
+							return nil, err
+						}
+					}
+				}
+				if params.Wants("translations") {
+					if current.Translations, err = stories.Translations(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if params.Wants("meta") {
+					if current.Meta, err = stories.SocialMeta(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if params.Wants("structuredData") {
+					if current.StructuredData, err = stories.StructuredData(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if comments != nil && params.Wants("commentCount") {
+					count, err := comments.Count(r.Context(), story.ID)
+					if err != nil {
+						return nil, err
+					}
+					current.CommentCount = &count
+				}
+				if params.Wants("reactions") {
+					if current.Reactions, err = reactions.Totals(r.Context(), story.ID); err != nil {
+						return nil, err
+					}
+				}
+				if user := params.String(restUserHeader); user != "" && bookmarks != nil && params.Wants("isBookmarked") {
+					bookmarked, err := bookmarks.Bookmarked(r.Context(), user, story.ID)
+					if err != nil {
+						return nil, err
+					}
+					current.IsBookmarked = &bookmarked
+				}
+				return current, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/meta", OperationID: "getStoryMeta", Tag: "stories",
+			Summary:  "Get the Open Graph and Twitter Card metadata of a published story, also included as meta in the story.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(data.SocialMeta{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return stories.SocialMeta(r.Context(), story)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/related", OperationID: "listRelatedStories", Tag: "stories",
+			Summary: "List published stories related to a story by tags, section, publish time and co-reads, best match first.",
+			Params: []restParam{
+				{Name: "slug", In: "path", Type: "string", Required: true},
+				{Name: "limit", In: "query", Type: "integer", Description: "Number of stories (default 5).", Minimum: intPtr(1), Maximum: intPtr(20)},
+			},
+			Response: reflect.TypeOf(RelatedStoryList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				results, err := related.Related(r.Context(), "", params.String("slug"), params.Int("limit"))
+				if err != nil {
+					return nil, err
+				}
+				results = slices.Clone(results)
+				for i := range results {
+					paywall.Withhold(&results[i].Story)
+				}
+				return RelatedStoryList{Data: results}, nil
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/stories/{slug}/views", OperationID: "reportStoryView", Tag: "stories",
+			Summary: "Report that a visitor viewed a story, for the view count, the trending rankings and co-read recommendations. Repeat views by the same visitor are counted once.",
+			Params: []restParam{
+				{Name: "slug", In: "path", Type: "string", Required: true},
+				{Name: "visitor", In: "query", Type: "string", Required: true, Description: "Opaque, stable ID of the visitor, e.g. from a first-party cookie. It is only stored hashed."},
+			},
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				visitor, slug := params.String("visitor"), params.String("slug")
+				counted, err := trending.RecordView(r.Context(), visitor, slug)
+				if err != nil || !counted {
+					return nil, err
+				}
+				story, err := stories.Story(r.Context(), "", slug)
+				if err != nil {
+					return nil, err
+				}
+				views.Add(r.Context(), story.ID)
+				return nil, related.RecordRead(r.Context(), visitor, slug)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/views", OperationID: "getStoryViews", Tag: "stories",
+			Summary:  "Get the view count of a story, including views not yet written to the database.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(StoryViews{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return StoryViews{StoryID: story.ID, Views: views.Total(r.Context(), story)}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/reactions", OperationID: "getStoryReactions", Tag: "reactions",
+			Summary:  "Get the number of each reaction on a published story, including reactions not yet written to the database.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(StoryReactions{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				if reactions == nil {
+					return nil, data.ErrReactionsUnsupported
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				totals, err := reactions.Totals(r.Context(), story.ID)
+				if err != nil {
+					return nil, err
+				}
+				return StoryReactions{StoryID: story.ID, Totals: totals}, nil
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/stories/{slug}/reactions", OperationID: "toggleStoryReaction", Tag: "reactions",
+			Summary:  "Toggle a reaction of a user on a published story: add it, or remove it when the user already has it. Each user counts once per reaction.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}, requiredUserParam},
+			Request:  reflect.TypeOf(ReactionRequest{}),
+			Response: reflect.TypeOf(data.ReactionToggle{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				if reactions == nil {
+					return nil, data.ErrReactionsUnsupported
+				}
+				var req ReactionRequest
+				if err := decodeRESTBody(r, &req); err != nil {
+					return nil, err
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return reactions.Toggle(r.Context(), story.ID, params.String(restUserHeader), req.Reaction)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/bookmarks", OperationID: "listBookmarks", Tag: "bookmarks",
+			Summary: "List the published stories the signed-in reader saved, most recently saved first.",
+			Params: []restParam{
+				requiredUserParam,
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+				{Name: "after", In: "query", Type: "string", Description: "Cursor from nextCursor of the previous page."},
+			},
+			Response: reflect.TypeOf(BookmarkList{}),
+			PerUser:  true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				limit := params.Int("limit")
+				if limit == 0 {
+					limit = restDefaultLimit
+				}
+				page, err := bookmarks.List(r.Context(), params.String(restUserHeader), limit, params.String("after"))
+				if err != nil {
+					return nil, err
+				}
+				list := BookmarkList{Data: []BookmarkedStory{}, NextCursor: page.NextCursor, HasNextPage: page.HasNextPage}
+				for _, bookmark := range page.Bookmarks {
+					story, err := stories.Story(r.Context(), bookmark.StoryID, "")
+					if errors.Is(err, data.ErrStoryNotFound) {
+						// 已下架或刪除的 story 不列出，收藏保留至讀者移除
+						continue
+					}
+					if err != nil {
+						return nil, err
+					}
+					bookmarked := BookmarkedStory{Story: *story, BookmarkedAt: bookmark.CreatedAt}
+					paywall.Withhold(&bookmarked.Story)
+					list.Data = append(list.Data, bookmarked)
+				}
+				return list, nil
+			},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/bookmarks/{slug}", OperationID: "addBookmark", Tag: "bookmarks",
+			Summary: "Save a published story for the signed-in reader. Saving it again does nothing.",
+			Params:  []restParam{{Name: "slug", In: "path", Type: "string", Required: true}, requiredUserParam},
+			PerUser: true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				if bookmarks == nil {
+					return nil, data.ErrBookmarksUnsupported
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return nil, bookmarks.Add(r.Context(), params.String(restUserHeader), story.ID)
+			},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/bookmarks/{slug}", OperationID: "removeBookmark", Tag: "bookmarks",
+			Summary: "Remove a story from the signed-in reader's bookmarks.",
+			Params:  []restParam{{Name: "slug", In: "path", Type: "string", Required: true}, requiredUserParam},
+			PerUser: true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				if bookmarks == nil {
+					return nil, data.ErrBookmarksUnsupported
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return nil, bookmarks.Remove(r.Context(), params.String(restUserHeader), story.ID)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/progress", OperationID: "listReadingProgress", Tag: "progress",
+			Summary: "List the published stories the signed-in reader started but did not finish, most recently read first, for continue reading.",
+			Params: []restParam{
+				requiredUserParam,
+				{Name: "limit", In: "query", Type: "integer", Description: "Page size (default 10).", Minimum: intPtr(1), Maximum: intPtr(50)},
+				{Name: "offset", In: "query", Type: "integer", Description: "Number of stories to skip.", Minimum: intPtr(0)},
+			},
+			Response: reflect.TypeOf(ReadingProgressList{}),
+			PerUser:  true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				limit := params.Int("limit")
+				if limit == 0 {
+					limit = 10
+				}
+				positions, err := progress.InProgress(r.Context(), params.String(restUserHeader), limit, params.Int("offset"))
+				if err != nil {
+					return nil, err
+				}
+				list := ReadingProgressList{Data: []StoryProgress{}, Limit: limit, Offset: params.Int("offset")}
+				for _, position := range positions {
+					story, err := stories.Story(r.Context(), position.StoryID, "")
+					if errors.Is(err, data.ErrStoryNotFound) {
+						continue
+					}
+					if err != nil {
+						return nil, err
+					}
+					item := StoryProgress{Story: *story, Progress: position}
+					paywall.Withhold(&item.Story)
+					list.Data = append(list.Data, item)
+				}
+				return list, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/progress", OperationID: "getReadingProgress", Tag: "progress",
+			Summary:  "Get where the signed-in reader left off in a published story, as saved from any device. 404 when the reader has not started it.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}, requiredUserParam},
+			Response: reflect.TypeOf(data.ReadingProgress{}),
+			PerUser:  true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				if progress == nil {
+					return nil, data.ErrReadingProgressUnsupported
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return progress.Progress(r.Context(), params.String(restUserHeader), story.ID)
+			},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/stories/{slug}/progress", OperationID: "saveReadingProgress", Tag: "progress",
+			Summary:  "Save where the signed-in reader is in a published story. Apps may call it as the reader scrolls; the latest position wins across devices.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}, requiredUserParam},
+			Request:  reflect.TypeOf(ReadingProgressRequest{}),
+			Response: reflect.TypeOf(data.ReadingProgress{}),
+			PerUser:  true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				if progress == nil {
+					return nil, data.ErrReadingProgressUnsupported
+				}
+				var req ReadingProgressRequest
+				if err := decodeRESTBody(r, &req); err != nil {
+					return nil, err
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				return progress.Save(r.Context(), params.String(restUserHeader), story.ID, data.ReadingProgress{Position: req.Position, Anchor: req.Anchor})
+			},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/push/devices/{token}", OperationID: "registerPushDevice", Tag: "push",
+			Summary: "Register an APNs device token of the iOS app for breaking news notifications. Apps should register on every launch; the token of a signed-in reader is linked to the reader.",
+			Params: []restParam{
+				{Name: "token", In: "path", Type: "string", Required: true, Description: "APNs device token, hex-encoded."},
+				userParam,
+			},
+			Private: true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return nil, pushDevices.Register(r.Context(), params.String("token"), params.String(restUserHeader))
+			},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/push/devices/{token}", OperationID: "unregisterPushDevice", Tag: "push",
+			Summary: "Unregister an APNs device token, e.g. when the reader turns notifications off.",
+			Params:  []restParam{{Name: "token", In: "path", Type: "string", Required: true, Description: "APNs device token, hex-encoded."}},
+			Private: true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return nil, pushDevices.Unregister(r.Context(), params.String("token"))
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/comments", OperationID: "listStoryComments", Tag: "comments",
+			Summary: "List the approved comments of a published story: top-level comments newest first, each with its replies oldest first.",
+			Params: []restParam{
+				{Name: "slug", In: "path", Type: "string", Required: true},
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Number of top-level comments (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+				{Name: "after", In: "query", Type: "string", Description: "Cursor from nextCursor of the previous page."},
+			},
+			Response: reflect.TypeOf(CommentList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				limit := params.Int("limit")
+				if limit == 0 {
+					limit = restDefaultLimit
+				}
+				page, err := comments.List(r.Context(), story.ID, limit, params.String("after"))
+				if err != nil {
+					return nil, err
+				}
+				total, err := comments.Count(r.Context(), story.ID)
+				if err != nil {
+					return nil, err
+				}
+				return CommentList{Data: publicComments(page.Comments), Total: total, NextCursor: page.NextCursor, HasNextPage: page.HasNextPage}, nil
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/stories/{slug}/comments", OperationID: "createStoryComment", Tag: "comments",
+			Summary:  "Comment on a published story as the signed-in reader, or reply to one of its approved comments. New comments wait for moderation unless comments are auto-approved; each reader may only post a few comments per minute (429).",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}, requiredUserParam},
+			Request:  reflect.TypeOf(CommentRequest{}),
+			Response: reflect.TypeOf(data.Comment{}),
+			Status:   http.StatusCreated,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				var req CommentRequest
+				if err := decodeRESTBody(r, &req); err != nil {
+					return nil, err
+				}
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				comment, err := comments.Create(r.Context(), story.ID, data.Comment{
+					UserID: params.String(restUserHeader), AuthorName: req.AuthorName, Body: req.Body, ParentID: req.ParentID,
+				})
+				if err != nil {
+					return nil, err
+				}
+				return publicComments([]data.Comment{*comment})[0], nil
+			},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/stories/{slug}/comments/{id}", OperationID: "deleteStoryComment", Tag: "comments",
+			Summary: "Delete a comment of the signed-in reader and its replies. Comments of other readers answer 403.",
+			Params: []restParam{
+				{Name: "slug", In: "path", Type: "string", Required: true},
+				{Name: "id", In: "path", Type: "string", Required: true},
+				requiredUserParam,
+			},
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				comment, err := comments.Comment(r.Context(), params.String("id"))
+				if err != nil {
+					return nil, err
+				}
+				if comment.StoryID != story.ID {
+					return nil, data.ErrCommentNotFound
+				}
+				return nil, comments.Delete(r.Context(), comment.ID, params.String(restUserHeader), "")
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/preview/{token}", OperationID: "previewStory", Tag: "stories",
+			Summary:  "Get a story in any status with a preview token shared by an editor. The response must not be cached by shared caches.",
+			Params:   []restParam{{Name: "token", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(StoryPreview{}),
+			Private:  true,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, expiresAt, err := previews.Story(r.Context(), params.String("token"))
+				if err != nil {
+					return nil, err
+				}
+				preview := StoryPreview{Story: *story, ExpiresAt: expiresAt}
+				if params.Wants("blocks") {
+					preview.Story.Blocks = stories.Blocks(r.Context(), story)
+				}
+				if params.Wants("bodyHtml") {
+					if preview.Story.BodyHTML, err = stories.BodyHTML(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if params.Wants("tableOfContents") {
+					if preview.Story.TableOfContents, err = stories.TableOfContents(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if params.Wants("translations") {
+					if preview.Story.Translations, err = stories.Translations(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if params.Wants("meta") {
+					if preview.Story.Meta, err = stories.SocialMeta(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				if params.Wants("structuredData") {
+					if preview.Story.StructuredData, err = stories.StructuredData(r.Context(), story); err != nil {
+						return nil, err
+					}
+				}
+				return preview, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/authors", OperationID: "listAuthors", Tag: "authors",
+			Summary: "List authors by name.",
+			Params: []restParam{
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+				{Name: "offset", In: "query", Type: "integer", Description: "Number of authors to skip.", Minimum: intPtr(0)},
+			},
+			Response: reflect.TypeOf(AuthorList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				limit, offset := params.Int("limit"), params.Int("offset")
+				if limit == 0 {
+					limit = restDefaultLimit
+				}
+				page, err := stories.AuthorsPage(r.Context(), limit, offset)
+				if err != nil {
+					return nil, err
+				}
+				return AuthorList{Data: page.Authors, Limit: limit, Offset: offset, HasNextPage: page.HasNextPage}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/authors/{id}", OperationID: "getAuthor", Tag: "authors",
+			Summary:  "Get an author profile by ID or slug.",
+			Params:   []restParam{{Name: "id", In: "path", Type: "string", Required: true, Description: "Author ID or slug."}},
+			Response: reflect.TypeOf(data.Author{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return authorByIDOrSlug(r, params.String("id"))
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/authors/{id}/stories", OperationID: "listAuthorStories", Tag: "authors",
+			Summary:  "List published stories by an author, newest first.",
+			Params:   withListParams(restParam{Name: "id", In: "path", Type: "string", Required: true, Description: "Author ID or slug."}),
+			Response: storyListType,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				author, err := authorByIDOrSlug(r, params.String("id"))
+				if err != nil {
+					return nil, err
+				}
+				return listStories(r, params, data.StoryListOptions{Author: author.ID})
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/stories/{slug}/series", OperationID: "listStorySeries", Tag: "collections",
+			Summary:  "Get a story's \"part N of M\" position, with the previous and next parts, in each collection containing it.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(StorySeriesList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				story, err := stories.Story(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				series, err := stories.Series(r.Context(), story)
+				if err != nil {
+					return nil, err
+				}
+				return StorySeriesList{Data: series}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/collections", OperationID: "listCollections", Tag: "collections",
+			Summary: "List story collections, most recently updated first.",
+			Params: []restParam{
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size (default %d).", restDefaultLimit), Minimum: intPtr(1), Maximum: intPtr(100)},
+				{Name: "offset", In: "query", Type: "integer", Description: "Number of collections to skip.", Minimum: intPtr(0)},
+			},
+			Response: reflect.TypeOf(CollectionList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				limit, offset := params.Int("limit"), params.Int("offset")
+				if limit == 0 {
+					limit = restDefaultLimit
+				}
+				page, err := stories.CollectionsPage(r.Context(), limit, offset)
+				if err != nil {
+					return nil, err
+				}
+				return CollectionList{Data: page.Collections, Limit: limit, Offset: offset, HasNextPage: page.HasNextPage}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/collections/{slug}", OperationID: "getCollection", Tag: "collections",
+			Summary:  "Get a collection and its published stories in reading order.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(CollectionDetail{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				collection, err := stories.Collection(r.Context(), "", params.String("slug"))
+				if err != nil {
+					return nil, err
+				}
+				parts, err := stories.CollectionStories(r.Context(), collection)
+				if err != nil {
+					return nil, err
+				}
+				return CollectionDetail{Collection: *collection, Stories: withholdStories(paywall, parts)}, nil
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/tags", OperationID: "listTags", Tag: "taxonomy",
+			Summary:  "List tags by name with their published story counts.",
+			Params:   termListParams,
+			Response: reflect.TypeOf(TermList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return listTerms(r, params, data.TermKindTag)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/tags/{name}", OperationID: "getTag", Tag: "taxonomy",
+			Summary:  "Get a tag and its published story count.",
+			Params:   []restParam{{Name: "name", In: "path", Type: "string", Required: true, Description: "Tag slug."}},
+			Response: reflect.TypeOf(data.Term{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return stories.Term(r.Context(), data.TermKindTag, params.String("name"))
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/categories", OperationID: "listCategories", Tag: "taxonomy",
+			Summary:  "List categories by name with their parent and published story counts. A story's section is its category slug.",
+			Params:   termListParams,
+			Response: reflect.TypeOf(TermList{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return listTerms(r, params, data.TermKindCategory)
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/categories/{slug}", OperationID: "getCategory", Tag: "taxonomy",
+			Summary:  "Get a category and its published story count.",
+			Params:   []restParam{{Name: "slug", In: "path", Type: "string", Required: true}},
+			Response: reflect.TypeOf(data.Term{}),
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return stories.Term(r.Context(), data.TermKindCategory, params.String("slug"))
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/sections/{name}/stories", OperationID: "listSectionStories", Tag: "stories",
+			Summary:  "List published stories in a section, newest first.",
+			Params:   withListParams(restParam{Name: "name", In: "path", Type: "string", Required: true}),
+			Response: storyListType,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return listStories(r, params, data.StoryListOptions{Section: params.String("name")})
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/locales/{locale}/stories", OperationID: "listLocaleStories", Tag: "stories",
+			Summary:  "List published stories in a language (BCP 47, e.g. en-US), newest first. Stories without a language are in the site language.",
+			Params:   withListParams(restParam{Name: "locale", In: "path", Type: "string", Required: true}),
+			Response: storyListType,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return listStories(r, params, data.StoryListOptions{Locale: params.String("locale")})
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/tags/{name}/stories", OperationID: "listTagStories", Tag: "stories",
+			Summary:  "List published stories with a tag, newest first.",
+			Params:   withListParams(restParam{Name: "name", In: "path", Type: "string", Required: true}),
+			Response: storyListType,
+			Handle: func(r *http.Request, params restValues) (interface{}, error) {
+				return listStories(r, params, data.StoryListOptions{Tag: params.String("name")})
+			},
+		},
+	}
+}
+
+// withholdStories 複製 stories 並移除付費文章的內文，避免改到 cache 中的值；列表中的付費文章一律不提供內文
+func withholdStories(paywall *data.Paywall, stories []data.Story) []data.Story {
+	stories = slices.Clone(stories)
+	for i := range stories {
+		paywall.Withhold(&stories[i])
+	}
+	return stories
+}
+
+// parseRESTParams 依 params 的定義讀取並驗證參數，未知的 query 參數會被拒絕
+func parseRESTParams(r *http.Request, params []restParam) (restValues, error) {
+	values := restValues{strings: map[string]string{}, ints: map[string]int{}}
+	query := r.URL.Query()
+	known := map[string]bool{}
+	for _, param := range params {
+		var raw string
+		switch param.In {
+		case "path":
+			raw = r.PathValue(param.Name)
+		case "header":
+			raw = r.Header.Get(param.Name)
+		default:
+			known[param.Name] = true
+			raw = query.Get(param.Name)
+		}
+		if raw == "" {
+			if param.Required && param.In == "header" {
+				// 目前只有 restUserHeader 是 header 參數，缺少時表示讀者未登入
+				return values, &restError{Status: http.StatusUnauthorized, Message: fmt.Sprintf("missing header %q", param.Name)}
+			}
+			if param.Required {
+				return values, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("missing parameter %q", param.Name)}
+			}
+			continue
+		}
+
+		switch param.Type {
+		case "integer":
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return values, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("parameter %q must be an integer", param.Name)}
+			}
+			if param.Minimum != nil && n < *param.Minimum {
+				return values, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("parameter %q must be at least %d", param.Name, *param.Minimum)}
+			}
+			if param.Maximum != nil && n > *param.Maximum {
+				return values, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("parameter %q must be at most %d", param.Name, *param.Maximum)}
+			}
+			values.ints[param.Name] = n
+		default:
+			values.strings[param.Name] = raw
+		}
+	}
+	for name := range query {
+		if !known[name] {
+			return values, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("unknown parameter %q", name)}
+		}
+	}
+	return values, nil
+}
+
+// decodeRESTBody 讀取 JSON request body；格式錯誤、未知欄位或超過 restMaxBodySize 時回傳 400
+func decodeRESTBody(r *http.Request, dest interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, restMaxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		return &restError{Status: http.StatusBadRequest, Message: "invalid request body: " + err.Error()}
+	}
+	return nil
+}
+
+// writeRESTError 將錯誤轉為對應的 HTTP status 與 JSON body
+func writeRESTError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "internal error"
+	var re *restError
+	switch {
+	case errors.As(err, &re):
+		status, message = re.Status, re.Message
+		if re.Location != "" {
+			w.Header().Set("Location", re.Location)
+		}
+	case errors.Is(err, data.ErrInvalidCursor), errors.Is(err, data.ErrInvalidStoryQuery), errors.Is(err, data.ErrEmptySearchQuery),
+		errors.Is(err, data.ErrInvalidTrendingWindow), errors.Is(err, data.ErrInvalidLocale), errors.Is(err, data.ErrInvalidComment),
+		errors.Is(err, data.ErrInvalidReaction), errors.Is(err, data.ErrInvalidBookmark),
+		errors.Is(err, data.ErrInvalidReadingProgress), errors.Is(err, data.ErrInvalidPushDevice):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, data.ErrStoryNotFound), errors.Is(err, data.ErrAuthorNotFound), errors.Is(err, data.ErrTermNotFound),
+		errors.Is(err, data.ErrCollectionNotFound), errors.Is(err, data.ErrCommentNotFound),
+		errors.Is(err, data.ErrReadingProgressNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, data.ErrInvalidPreviewToken), errors.Is(err, data.ErrCommentForbidden):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, data.ErrCommentRateLimited):
+		status, message = http.StatusTooManyRequests, err.Error()
+	case errors.Is(err, data.ErrPreviewTokenExpired):
+		status, message = http.StatusGone, err.Error()
+	case errors.Is(err, data.ErrAuthorsUnsupported), errors.Is(err, data.ErrSearchUnsupported), errors.Is(err, data.ErrPreviewsUnsupported),
+		errors.Is(err, data.ErrTaxonomyUnsupported), errors.Is(err, data.ErrCollectionsUnsupported), errors.Is(err, data.ErrCommentsUnsupported),
+		errors.Is(err, data.ErrReactionsUnsupported), errors.Is(err, data.ErrBookmarksUnsupported),
+		errors.Is(err, data.ErrReadingProgressUnsupported), errors.Is(err, data.ErrPushDevicesUnsupported):
+		status, message = http.StatusNotImplemented, err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, APIError{Error: message})
+}
+
+// equalsFilter 回傳等於 value 的 StringFilter；value 為空時回傳 nil
+func equalsFilter(value string) *data.StringFilter {
+	if value == "" {
+		return nil
+	}
+	return &data.StringFilter{Equals: &value}
+}
+
+// publishedRange 讀取 publishedFrom / publishedTo 參數；都未指定時回傳 nil
+func publishedRange(params restValues) *data.DateTimeRangeFilter {
+	from, to := params.String("publishedFrom"), params.String("publishedTo")
+	if from == "" && to == "" {
+		return nil
+	}
+	published := &data.DateTimeRangeFilter{}
+	if from != "" {
+		published.Gte = &from
+	}
+	if to != "" {
+		published.Lt = &to
+	}
+	return published
+}
+
+// parseRESTSort 將 sort 參數 (例如 "-updatedAt,popularity") 轉為排序規則；欄位由儲存層驗證
+func parseRESTSort(sort string) []data.OrderRule {
+	rules := []data.OrderRule{}
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		rule := data.OrderRule{Field: field, Direction: "asc"}
+		if strings.HasPrefix(field, "-") {
+			rule = data.OrderRule{Field: field[1:], Direction: "desc"}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// publicComments 回傳不含 userId 的留言副本 (含回覆)，避免公開讀者在登入系統中的 ID
+func publicComments(comments []data.Comment) []data.Comment {
+	public := make([]data.Comment, len(comments))
+	for i, comment := range comments {
+		comment.UserID = ""
+		comment.Replies = publicComments(comment.Replies)
+		public[i] = comment
+	}
+	return public
+}
+
+func intPtr(n int) *int {
+	return &n
+}
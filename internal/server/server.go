@@ -9,10 +9,13 @@ import (
 	"reflect"
 	"time"
 
+	"go-story/internal/data"
+	"go-story/internal/schema"
+
 	"github.com/graphql-go/graphql"
 )
 
-func NewGraphQLHandler(schema graphql.Schema) http.Handler {
+func NewGraphQLHandler(gqlSchema graphql.Schema) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -31,14 +34,23 @@ func NewGraphQLHandler(schema graphql.Schema) http.Handler {
 			return
 		}
 
+		// 每個請求各自的 dataloader，合併同一層欄位的作者、tag 與圖片查詢
+		ctx, trace := data.WithCacheTrace(r.Context())
+		ctx = schema.WithLoaders(ctx)
 		result := graphql.Do(graphql.Params{
-			Schema:         schema,
+			Schema:         gqlSchema,
 			RequestString:  payload.Query,
 			VariableValues: payload.Variables,
 			OperationName:  payload.OperationName,
-			Context:        r.Context(),
+			Context:        ctx,
 		})
 
+		setCacheHeaders(w, trace, result.HasErrors())
+		// story 的內文依讀者的會員資格與計次而不同
+		w.Header().Add("Vary", "Authorization, "+visitorHeader)
+		if entitled(r) {
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(result); err != nil {
 			http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
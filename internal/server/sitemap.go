@@ -0,0 +1,45 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-story/internal/data"
+)
+
+// sitemapCacheControl 為 sitemap 回應的快取標頭
+const sitemapCacheControl = "public, max-age=600"
+
+// NewSitemapHandler serves the generated sitemaps:
+//
+//	GET /sitemap.xml       the sitemap index
+//	GET /sitemaps/{file}   a sitemap listed in the index
+//
+// Files are answered with 404 until the first generation finishes.
+func NewSitemapHandler(sitemaps *data.SitemapService) http.Handler {
+	serve := func(w http.ResponseWriter, r *http.Request, name string) {
+		body, err := sitemaps.File(r.Context(), name)
+		if errors.Is(err, data.ErrSitemapNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			slog.Error("failed to read sitemap", "file", name, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Header().Set("Cache-Control", sitemapCacheControl)
+		_, _ = w.Write(body)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+data.SitemapIndexFile, func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, data.SitemapIndexFile)
+	})
+	mux.HandleFunc("GET /sitemaps/{file}", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, r.PathValue("file"))
+	})
+	return mux
+}
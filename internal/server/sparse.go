@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"go-story/internal/data"
+)
+
+// storyType 為 fields 參數作用的型別；回應中每個 data.Story 只保留選取的欄位
+var storyType = reflect.TypeOf(data.Story{})
+
+// storyFieldsParam 由 NewRESTHandler 加在回應含有 story 的 GET 與 Lookup route
+var storyFieldsParam = restParam{
+	Name: "fields", In: "query", Type: "string",
+	Description: "Comma-separated story fields to return, e.g. title,slug,coverImage; id is always included. Other fields of each story are left out, and so is their work: a story without bodyHtml is not rendered. Default all fields.",
+}
+
+// storyFieldNames 為 data.Story 的 JSON 欄位名稱
+var storyFieldNames = sync.OnceValue(func() []string {
+	names := []string{}
+	for i := 0; i < storyType.NumField(); i++ {
+		if name := jsonFieldName(storyType.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+})
+
+// parseStoryFields 讀取 fields 參數；未指定時回傳 nil，表示回傳所有欄位
+func parseStoryFields(raw string) (map[string]bool, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	fields := map[string]bool{"id": true}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(storyFieldNames(), name) {
+			return nil, &restError{Status: http.StatusBadRequest, Message: fmt.Sprintf("unknown field %q in parameter \"fields\"", name)}
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// writeSparseJSON 以 writeConditionalJSON 相同的格式寫入 v，其中每個 data.Story 只保留 fields 的欄位；
+// ETag 依刪減後的內容計算，不同的 fields 各有自己的 ETag。POST 的查詢 (Lookup route) 不是條件式請求，直接寫入
+func writeSparseJSON(w http.ResponseWriter, r *http.Request, v interface{}, fields map[string]bool) {
+	raw, err := json.Marshal(v)
+	if err == nil {
+		raw, err = sparseJSON(raw, reflect.TypeOf(v), fields)
+	}
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		_, _ = w.Write(append(raw, '\n'))
+		return
+	}
+	writeConditional(w, r, append(raw, '\n'), lastModified(v))
+}
+
+// sparseJSON 依 t 的結構走訪 t 編碼後的 raw，刪減其中 data.Story 物件的欄位；欄位順序維持不變
+func sparseJSON(raw []byte, t reflect.Type, fields map[string]bool) ([]byte, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if !containsStory(t) || bytes.Equal(raw, []byte("null")) {
+		return raw, nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			item, err := sparseJSON(item, t.Elem(), fields)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(item)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	case reflect.Struct:
+		members, err := jsonMembers(raw)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		n := 0
+		for _, member := range members {
+			value := member.value
+			if t == storyType {
+				if !fields[member.name] {
+					continue
+				}
+			} else if field, ok := jsonField(t, member.name); ok {
+				if value, err = sparseJSON(value, field.Type, fields); err != nil {
+					return nil, err
+				}
+			}
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(member.name)
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(value)
+			n++
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	}
+	return raw, nil
+}
+
+// jsonMember 為 JSON 物件的一個成員
+type jsonMember struct {
+	name  string
+	value json.RawMessage
+}
+
+// jsonMembers 依原本的順序讀取 JSON 物件的成員
+func jsonMembers(raw []byte) ([]jsonMember, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("sparse fields: expected an object, got %v", tok)
+	}
+	members := []jsonMember{}
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		member := jsonMember{name: tok.(string)}
+		if err := decoder.Decode(&member.value); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// jsonField 回傳 struct t 中 JSON 名稱為 name 的欄位
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); jsonFieldName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonFieldName 回傳欄位編碼為 JSON 時的名稱；未匯出或標為 "-" 的欄位回傳空字串
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// storyContainers 快取 containsStory 的結果
+var storyContainers sync.Map
+
+// containsStory 回傳 t 的值是否可能含有 data.Story
+func containsStory(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if found, ok := storyContainers.Load(t); ok {
+		return found.(bool)
+	}
+	found := containsStoryType(t, map[reflect.Type]bool{})
+	storyContainers.Store(t, found)
+	return found
+}
+
+func containsStoryType(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == storyType {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return containsStoryType(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); jsonFieldName(field) != "" && containsStoryType(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
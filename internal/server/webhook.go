@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go-story/internal/data"
+)
+
+// defaultWebhookDeliveryLimit 與 maxWebhookDeliveryLimit 為投遞紀錄每次回傳筆數的預設值與上限
+const (
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 500
+)
+
+// webhookRequest 為新增與修改 webhook 的 payload；未指定 active 時視為啟用
+type webhookRequest struct {
+	URL    string            `json:"url"`
+	Secret string            `json:"secret"`
+	Events []data.StoryEvent `json:"events"`
+	Active *bool             `json:"active"`
+}
+
+// subscription 轉為 WebhookSubscription
+func (p webhookRequest) subscription(id string) *data.WebhookSubscription {
+	sub := &data.WebhookSubscription{ID: id, URL: p.URL, Secret: p.Secret, Events: p.Events, Active: true}
+	if p.Active != nil {
+		sub.Active = *p.Active
+	}
+	return sub
+}
+
+// WebhookAdminHandler serves the webhook admin API under /internal/webhooks/:
+//
+//	GET    /internal/webhooks/subscriptions                  list subscriptions
+//	POST   /internal/webhooks/subscriptions                  create, body {"url", "events", "secret", "active"}
+//	GET    /internal/webhooks/subscriptions/{id}             get a subscription
+//	PUT    /internal/webhooks/subscriptions/{id}             replace a subscription (empty secret keeps it)
+//	DELETE /internal/webhooks/subscriptions/{id}             delete a subscription and its deliveries
+//	GET    /internal/webhooks/subscriptions/{id}/deliveries  newest deliveries, ?limit= (default 50)
+//
+// Every request must carry "Authorization: Bearer <token>". Writes are
+// recorded in the audit log as made by "webhook-admin" (see
+// AuditActorHeader).
+func WebhookAdminHandler(webhooks *data.WebhookService, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /internal/webhooks/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		subs, err := webhooks.Subscriptions(r.Context())
+		if err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": subs})
+	})
+	mux.HandleFunc("POST /internal/webhooks/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		sub := payload.subscription("")
+		if err := webhooks.CreateSubscription(r.Context(), sub); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(sub)
+	})
+	mux.HandleFunc("GET /internal/webhooks/subscriptions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		sub, err := webhooks.Subscription(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, sub)
+	})
+	mux.HandleFunc("PUT /internal/webhooks/subscriptions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		sub := payload.subscription(r.PathValue("id"))
+		if err := webhooks.UpdateSubscription(r.Context(), sub); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, sub)
+	})
+	mux.HandleFunc("DELETE /internal/webhooks/subscriptions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := webhooks.DeleteSubscription(r.Context(), r.PathValue("id")); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /internal/webhooks/subscriptions/{id}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultWebhookDeliveryLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxWebhookDeliveryLimit {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		deliveries, err := webhooks.Deliveries(r.Context(), r.PathValue("id"), limit)
+		if err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": deliveries})
+	})
+
+	return requireBearerToken(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, withAuditActor(r, "webhook-admin"))
+	}))
+}
+
+// writeWebhookError 將 webhook 的錯誤轉為對應的 HTTP 狀態碼
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrWebhookNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, data.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Warn("webhook admin request failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
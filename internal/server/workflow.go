@@ -0,0 +1,836 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-story/internal/data"
+)
+
+// workflowRoleKey 為 context 中呼叫者角色的 key
+type workflowRoleKey struct{}
+
+// workflowStory 為工作流程 API 回傳的 story 與呼叫者可執行的狀態轉換
+type workflowStory struct {
+	Story       *data.Story `json:"story"`
+	Transitions []string    `json:"transitions"`
+}
+
+// workflowTransitionRequest 為狀態轉換的 payload；publishAt 只用於 scheduled
+type workflowTransitionRequest struct {
+	To        string     `json:"to"`
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// WorkflowHandler serves the editorial workflow API under /internal/stories/:
+//
+//	GET  /internal/stories?status=&limit=&after=  stories in any status, newest first; translationGroup= lists the language versions of a story
+//	POST /internal/stories                        body: a Story without id, owned by the caller (authors create drafts only)
+//	GET  /internal/stories/{id}                   a story and the transitions the caller may make
+//	PUT  /internal/stories/{id}                   replace the content of the story, keeping its status (authors: own drafts only)
+//	POST /internal/stories/{id}/transitions       body {"to": "in_review", "publishAt": "<RFC 3339>"} (authors: own stories only)
+//	GET  /internal/stories/{id}/revisions         revisions, newest first, without bodies
+//	GET  /internal/stories/{id}/revisions/{n}     one revision
+//	GET  /internal/stories/{id}/revisions/diff?from=&to=  changes between two revisions
+//	POST /internal/stories/{id}/revisions/{n}/restore     roll the story back to revision n (editors only)
+//	DELETE /internal/stories/{id}                 move the story to the trash (editors only)
+//	GET  /internal/stories/trash?limit=&offset=   trashed stories, most recently deleted first
+//	POST /internal/stories/trash/{id}/restore     move a story out of the trash (editors only)
+//	DELETE /internal/stories/trash/{id}           permanently delete a trashed story (editors only)
+//	DELETE /internal/stories/trash?before=<RFC 3339>  permanently delete stories trashed before (admins only)
+//	GET  /internal/stories/export?section=&publishedFrom=&publishedTo=  stories in any status as NDJSON (editors only)
+//	POST /internal/stories/import?dryRun=&batch=  upsert NDJSON stories from the body; answers with a report (admins only)
+//	POST /internal/stories/{id}/preview           a signed, expiring preview URL for the story in any status
+//
+// and author management under /internal/authors/:
+//
+//	GET    /internal/authors?limit=&offset=  authors by name
+//	POST   /internal/authors                 body: an Author without id
+//	GET    /internal/authors/{id}            one author
+//	PUT    /internal/authors/{id}            replace the author
+//	DELETE /internal/authors/{id}            delete an author no story credits (editors only)
+//
+// collection (story series) management under /internal/collections/:
+//
+//	GET    /internal/collections?limit=&offset=  collections, most recently updated first
+//	POST   /internal/collections                 body: a Collection without id; storyIds in reading order
+//	GET    /internal/collections/{id}            one collection
+//	PUT    /internal/collections/{id}            replace the collection and its stories
+//	DELETE /internal/collections/{id}            delete the collection, keeping its stories (editors only)
+//
+// and tag and category management under /internal/taxonomy/, where {kind}
+// is "tags" or "categories":
+//
+//	GET    /internal/taxonomy/{kind}?limit=&offset=  terms by name with published story counts
+//	POST   /internal/taxonomy/{kind}                 body {"slug": "go", "name": "Go", "parent": ""}
+//	GET    /internal/taxonomy/{kind}/{slug}          one term
+//	PUT    /internal/taxonomy/{kind}/{slug}          replace the term; a new slug renames it and re-links its stories (editors only)
+//	POST   /internal/taxonomy/{kind}/{slug}/merge    body {"into": "<slug>"}; move its stories to into and delete it (editors only)
+//	DELETE /internal/taxonomy/{kind}/{slug}          delete a term no story uses (editors only)
+//
+// and media uploads under /internal/media/, for image blocks to reference:
+//
+//	GET    /internal/media?limit=&offset=  uploaded media, newest first
+//	POST   /internal/media                 multipart/form-data with the image in "file"; 201, or 200 with the existing media for a duplicate
+//	GET    /internal/media/{id}            one media
+//	DELETE /internal/media/{id}            delete the media and its file (editors only)
+//
+// and comment moderation under /internal/comments/:
+//
+//	GET    /internal/comments?status=&limit=&offset=  comments on every story, newest first; status pending lists the queue
+//	PUT    /internal/comments/{id}/status             body {"status": "approved"}; pending, approved or hidden (editors only)
+//	DELETE /internal/comments/{id}                    delete the comment and its replies (editors only)
+//
+// Every request must carry "Authorization: Bearer <token>" with one of
+// tokens, which maps each token to the caller's role, or with a JWT verified
+// by verifier, whose roles claim grants the most privileged of admin, editor
+// and author among its roles (see data.StoryRoleOf). Readers get 403.
+// Permissions are checked by the workflow services (see data.StoryRole);
+// authors own the stories they create, identified by the subject of the JWT
+// or session, and may only edit and move their own drafts. Tokens are shared
+// and carry no identity, so stories created with them have no owner and only
+// editors may change them. Writes are recorded in the audit log as made by
+// the role (see AuditActorHeader). Requests without an Authorization header
+// may instead carry the session cookie of a user signed in with sessions
+// (see AdminLoginHandler). A nil verifier accepts tokens only and a nil
+// sessions no session cookies. A nil previews makes the preview endpoint
+// answer 501, a nil media the media endpoints and a nil comments the comment
+// endpoints.
+func WorkflowHandler(workflow *data.StoryWorkflow, previews *data.PreviewService, media *data.MediaService, comments *data.CommentService, tokens map[string]data.StoryRole, verifier *data.JWTVerifier, sessions *data.AdminSessionService) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /internal/stories", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		opts := data.StoryListOptions{Status: query.Get("status"), TranslationGroup: query.Get("translationGroup"), After: query.Get("after"), Limit: 20}
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+			opts.Limit = n
+		}
+		stories, err := workflow.Stories(r.Context(), opts)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		nextCursor := ""
+		if len(stories) == opts.Limit {
+			nextCursor = data.StoryCursor(stories[len(stories)-1], opts)
+		}
+		writeJSON(w, map[string]any{"data": stories, "nextCursor": nextCursor})
+	})
+	mux.HandleFunc("POST /internal/stories", func(w http.ResponseWriter, r *http.Request) {
+		var story data.Story
+		if err := json.NewDecoder(r.Body).Decode(&story); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		story.ID = ""
+		role := workflowRole(r.Context())
+		if err := workflow.CreateStory(r.Context(), &story, role); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(workflowStory{Story: &story, Transitions: role.Transitions(story.Status)})
+	})
+	mux.HandleFunc("GET /internal/stories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		story, err := workflow.Story(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, workflowStory{Story: story, Transitions: workflowRole(r.Context()).Transitions(story.Status)})
+	})
+	mux.HandleFunc("PUT /internal/stories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var payload data.Story
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		payload.ID = r.PathValue("id")
+		role := workflowRole(r.Context())
+		story, err := workflow.UpdateStory(r.Context(), &payload, role)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, workflowStory{Story: story, Transitions: role.Transitions(story.Status)})
+	})
+	mux.HandleFunc("POST /internal/stories/{id}/transitions", func(w http.ResponseWriter, r *http.Request) {
+		var payload workflowTransitionRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.To == "" {
+			http.Error(w, "invalid payload, need {\"to\": \"<status>\"}", http.StatusBadRequest)
+			return
+		}
+		role := workflowRole(r.Context())
+		story, err := workflow.Transition(r.Context(), r.PathValue("id"), payload.To, payload.PublishAt, role)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, workflowStory{Story: story, Transitions: role.Transitions(story.Status)})
+	})
+
+	mux.HandleFunc("GET /internal/stories/{id}/revisions", func(w http.ResponseWriter, r *http.Request) {
+		revisions, err := workflow.Revisions(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": revisions})
+	})
+	mux.HandleFunc("GET /internal/stories/{id}/revisions/{number}", func(w http.ResponseWriter, r *http.Request) {
+		number, err := strconv.Atoi(r.PathValue("number"))
+		if err != nil || number < 1 {
+			http.Error(w, "revision number must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		revision, err := workflow.Revision(r.Context(), r.PathValue("id"), number)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, revision)
+	})
+	mux.HandleFunc("GET /internal/stories/{id}/revisions/diff", func(w http.ResponseWriter, r *http.Request) {
+		from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+		to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+		if errFrom != nil || errTo != nil || from < 1 || to < 1 {
+			http.Error(w, "from and to must be revision numbers", http.StatusBadRequest)
+			return
+		}
+		diff, err := workflow.Diff(r.Context(), r.PathValue("id"), from, to)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, diff)
+	})
+	mux.HandleFunc("POST /internal/stories/{id}/revisions/{number}/restore", func(w http.ResponseWriter, r *http.Request) {
+		number, err := strconv.Atoi(r.PathValue("number"))
+		if err != nil || number < 1 {
+			http.Error(w, "revision number must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		role := workflowRole(r.Context())
+		story, err := workflow.Rollback(r.Context(), r.PathValue("id"), number, role)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, workflowStory{Story: story, Transitions: role.Transitions(story.Status)})
+	})
+
+	mux.HandleFunc("DELETE /internal/stories/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := workflow.Delete(r.Context(), r.PathValue("id"), workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /internal/stories/trash", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, offset := 20, 0
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if raw := query.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		stories, err := workflow.Trash(r.Context(), limit, offset)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": stories})
+	})
+	mux.HandleFunc("POST /internal/stories/trash/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		role := workflowRole(r.Context())
+		story, err := workflow.Restore(r.Context(), r.PathValue("id"), role)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, workflowStory{Story: story, Transitions: role.Transitions(story.Status)})
+	})
+	mux.HandleFunc("DELETE /internal/stories/trash/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := workflow.Purge(r.Context(), r.PathValue("id"), workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /internal/stories/trash", func(w http.ResponseWriter, r *http.Request) {
+		before, err := time.Parse(time.RFC3339, r.URL.Query().Get("before"))
+		if err != nil {
+			http.Error(w, "before must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		n, err := workflow.PurgeTrash(r.Context(), before, workflowRole(r.Context()))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"purged": n})
+	})
+	mux.HandleFunc("GET /internal/stories/export", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		opts := data.StoryExportOptions{Section: query.Get("section")}
+		for _, param := range []struct {
+			name string
+			dst  **time.Time
+		}{{"publishedFrom", &opts.PublishedFrom}, {"publishedTo", &opts.PublishedTo}} {
+			raw := query.Get(param.name)
+			if raw == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				http.Error(w, param.name+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*param.dst = &t
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		n, err := workflow.Export(r.Context(), w, opts, workflowRole(r.Context()))
+		if err != nil {
+			// 已送出部分內容時無法改變狀態碼，只能記錄
+			if n > 0 {
+				slog.Warn("story export interrupted", "exported", n, "error", err)
+				return
+			}
+			writeWorkflowError(w, err)
+		}
+	})
+	mux.HandleFunc("POST /internal/stories/import", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		opts := data.StoryImportOptions{DryRun: query.Get("dryRun") == "true"}
+		if raw := query.Get("batch"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 1000 {
+				http.Error(w, "batch must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+			opts.BatchSize = n
+		}
+		report, err := workflow.Import(r.Context(), r.Body, opts, workflowRole(r.Context()))
+		if errors.Is(err, bufio.ErrTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, report)
+	})
+
+	mux.HandleFunc("POST /internal/stories/{id}/preview", func(w http.ResponseWriter, r *http.Request) {
+		token, err := previews.NewToken(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, token)
+	})
+
+	mux.HandleFunc("GET /internal/authors", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, offset := 20, 0
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if raw := query.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		authors, err := workflow.Authors(r.Context(), limit, offset)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": authors})
+	})
+	mux.HandleFunc("POST /internal/authors", func(w http.ResponseWriter, r *http.Request) {
+		var author data.Author
+		if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		author.ID = ""
+		if err := workflow.CreateAuthor(r.Context(), &author, workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(author)
+	})
+	mux.HandleFunc("GET /internal/authors/{id}", func(w http.ResponseWriter, r *http.Request) {
+		author, err := workflow.Author(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, author)
+	})
+	mux.HandleFunc("PUT /internal/authors/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var author data.Author
+		if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		author.ID = r.PathValue("id")
+		if err := workflow.UpdateAuthor(r.Context(), &author, workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, author)
+	})
+	mux.HandleFunc("DELETE /internal/authors/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := workflow.DeleteAuthor(r.Context(), r.PathValue("id"), workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /internal/collections", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, offset := 20, 0
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if raw := query.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		collections, err := workflow.Collections(r.Context(), limit, offset)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": collections})
+	})
+	mux.HandleFunc("POST /internal/collections", func(w http.ResponseWriter, r *http.Request) {
+		var collection data.Collection
+		if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		collection.ID = ""
+		if err := workflow.CreateCollection(r.Context(), &collection, workflowRole(r.Context())); err != nil {
+			writeCollectionError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(collection)
+	})
+	mux.HandleFunc("GET /internal/collections/{id}", func(w http.ResponseWriter, r *http.Request) {
+		collection, err := workflow.Collection(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, collection)
+	})
+	mux.HandleFunc("PUT /internal/collections/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var collection data.Collection
+		if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		collection.ID = r.PathValue("id")
+		if err := workflow.UpdateCollection(r.Context(), &collection, workflowRole(r.Context())); err != nil {
+			writeCollectionError(w, err)
+			return
+		}
+		writeJSON(w, collection)
+	})
+	mux.HandleFunc("DELETE /internal/collections/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := workflow.DeleteCollection(r.Context(), r.PathValue("id"), workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /internal/taxonomy/{kind}", func(w http.ResponseWriter, r *http.Request) {
+		kind, ok := workflowTermKind(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		limit, offset := 50, 0
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if raw := query.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		terms, err := workflow.Terms(r.Context(), kind, limit, offset)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": terms})
+	})
+	mux.HandleFunc("POST /internal/taxonomy/{kind}", func(w http.ResponseWriter, r *http.Request) {
+		kind, ok := workflowTermKind(w, r)
+		if !ok {
+			return
+		}
+		var term data.Term
+		if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		term.Kind = kind
+		if err := workflow.CreateTerm(r.Context(), &term, workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(term)
+	})
+	mux.HandleFunc("GET /internal/taxonomy/{kind}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		kind, ok := workflowTermKind(w, r)
+		if !ok {
+			return
+		}
+		term, err := workflow.Term(r.Context(), kind, r.PathValue("slug"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, term)
+	})
+	mux.HandleFunc("PUT /internal/taxonomy/{kind}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		kind, ok := workflowTermKind(w, r)
+		if !ok {
+			return
+		}
+		var term data.Term
+		if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		term.Kind = kind
+		if term.Slug == "" {
+			term.Slug = r.PathValue("slug")
+		}
+		if err := workflow.UpdateTerm(r.Context(), r.PathValue("slug"), &term, workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, term)
+	})
+	mux.HandleFunc("POST /internal/taxonomy/{kind}/{slug}/merge", func(w http.ResponseWriter, r *http.Request) {
+		kind, ok := workflowTermKind(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			Into string `json:"into"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Into == "" {
+			http.Error(w, `body must be {"into": "<slug>"}`, http.StatusBadRequest)
+			return
+		}
+		moved, err := workflow.MergeTerms(r.Context(), kind, r.PathValue("slug"), req.Into, workflowRole(r.Context()))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"into": req.Into, "relinkedStories": moved})
+	})
+	mux.HandleFunc("DELETE /internal/taxonomy/{kind}/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		kind, ok := workflowTermKind(w, r)
+		if !ok {
+			return
+		}
+		if err := workflow.DeleteTerm(r.Context(), kind, r.PathValue("slug"), workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /internal/media", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, offset := 50, 0
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if raw := query.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		list, err := media.List(r.Context(), limit, offset)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": list})
+	})
+	mux.HandleFunc("POST /internal/media", func(w http.ResponseWriter, r *http.Request) {
+		if media == nil {
+			writeWorkflowError(w, data.ErrMediaUnsupported)
+			return
+		}
+		// 預留 multipart 標頭的空間；檔案本身的大小由 MediaService 檢查
+		r.Body = http.MaxBytesReader(w, r.Body, media.MaxSize()+1<<20)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeWorkflowError(w, data.ErrMediaTooLarge)
+				return
+			}
+			http.Error(w, `body must be multipart/form-data with the image in "file"`, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		uploaded, created, err := media.Upload(r.Context(), header.Filename, file)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(uploaded)
+	})
+	mux.HandleFunc("GET /internal/media/{id}", func(w http.ResponseWriter, r *http.Request) {
+		uploaded, err := media.Media(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, uploaded)
+	})
+	mux.HandleFunc("DELETE /internal/media/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := media.Delete(r.Context(), r.PathValue("id"), workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /internal/comments", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, offset := 50, 0
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > 500 {
+				http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if raw := query.Get("offset"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		list, err := comments.Moderation(r.Context(), query.Get("status"), limit, offset)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"data": list})
+	})
+	mux.HandleFunc("PUT /internal/comments/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Status == "" {
+			http.Error(w, "invalid payload, need {\"status\": \"<status>\"}", http.StatusBadRequest)
+			return
+		}
+		comment, err := comments.SetStatus(r.Context(), r.PathValue("id"), req.Status, workflowRole(r.Context()))
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, comment)
+	})
+	mux.HandleFunc("DELETE /internal/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := comments.Delete(r.Context(), r.PathValue("id"), "", workflowRole(r.Context())); err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return requireRoleToken(tokens, verifier, sessions, mux)
+}
+
+// writeCollectionError 將合集寫入的錯誤轉為 HTTP 狀態碼；storyIds 中不存在的 story 屬於 payload 錯誤，回應 400 而非 404
+func writeCollectionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, data.ErrStoryNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeWorkflowError(w, err)
+}
+
+// workflowTermKind 將路徑中的 tags / categories 轉為 term 種類；無效時回應 404
+func workflowTermKind(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch r.PathValue("kind") {
+	case "tags":
+		return data.TermKindTag, true
+	case "categories":
+		return data.TermKindCategory, true
+	}
+	http.NotFound(w, r)
+	return "", false
+}
+
+// requireRoleToken 只放行帶有 tokens 中任一 Bearer token、verifier 驗證通過的 JWT 或 sessions 的
+// session cookie 的請求，並將對應的角色與 principal 放入 context；token 為共用的，不帶 principal，
+// AuditActorHeader 只記錄於稽核紀錄，不作為 story 的擁有者
+func requireRoleToken(tokens map[string]data.StoryRole, verifier *data.JWTVerifier, sessions *data.AdminSessionService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		var role data.StoryRole
+		for token, tokenRole := range tokens {
+			if ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				role = tokenRole
+			}
+		}
+		ctx := r.Context()
+		switch {
+		case role != "":
+			r = withAuditActor(r, string(role))
+		case ok && verifier != nil:
+			principal, err := verifier.Verify(ctx, strings.TrimSpace(got))
+			if err != nil {
+				writeUnauthorized(w, err)
+				return
+			}
+			role = data.StoryRoleOf(principal.Roles)
+			ctx = data.WithAuditActor(data.WithPrincipal(ctx, principal), string(role)+":"+principal.Subject)
+			r = r.WithContext(ctx)
+		case r.Header.Get("Authorization") == "" && sessions != nil && hasCookie(r, adminSessionCookie):
+			session, err := adminSession(r, sessions)
+			if err != nil {
+				writeLoginError(w, err)
+				return
+			}
+			role = session.Role
+			ctx = data.WithAuditActor(data.WithPrincipal(ctx, session.Principal()), string(role)+":"+session.Subject)
+			r = r.WithContext(ctx)
+		default:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !role.Includes(data.StoryRoleAuthor) {
+			http.Error(w, "forbidden: the workflow API requires the author, editor or admin role", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), workflowRoleKey{}, role)))
+	})
+}
+
+// workflowRole 回傳 requireRoleToken 放入 context 的角色
+func workflowRole(ctx context.Context) data.StoryRole {
+	role, _ := ctx.Value(workflowRoleKey{}).(data.StoryRole)
+	return role
+}
+
+// writeWorkflowError 將工作流程的錯誤轉為對應的 HTTP 狀態碼
+func writeWorkflowError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrStoryNotFound), errors.Is(err, data.ErrRevisionNotFound), errors.Is(err, data.ErrAuthorNotFound),
+		errors.Is(err, data.ErrTermNotFound), errors.Is(err, data.ErrCollectionNotFound), errors.Is(err, data.ErrMediaNotFound),
+		errors.Is(err, data.ErrCommentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, data.ErrInvalidStoryStatus), errors.Is(err, data.ErrInvalidCursor), errors.Is(err, data.ErrInvalidStoryQuery),
+		errors.Is(err, data.ErrInvalidAuthor), errors.Is(err, data.ErrInvalidTerm), errors.Is(err, data.ErrInvalidCollection),
+		errors.Is(err, data.ErrInvalidBlocks), errors.Is(err, data.ErrInvalidLocale), errors.Is(err, data.ErrInvalidMedia),
+		errors.Is(err, data.ErrInvalidComment), errors.Is(err, data.ErrInvalidStoryAccess):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, data.ErrMediaTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, data.ErrStoryTransitionForbidden), errors.Is(err, data.ErrCommentForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, data.ErrInvalidStoryTransition), errors.Is(err, data.ErrStorySlugTaken),
+		errors.Is(err, data.ErrAuthorSlugTaken), errors.Is(err, data.ErrAuthorHasStories),
+		errors.Is(err, data.ErrTermSlugTaken), errors.Is(err, data.ErrTermInUse), errors.Is(err, data.ErrCollectionSlugTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, data.ErrRevisionsUnsupported), errors.Is(err, data.ErrTrashUnsupported),
+		errors.Is(err, data.ErrPreviewsUnsupported), errors.Is(err, data.ErrAuthorsUnsupported),
+		errors.Is(err, data.ErrTaxonomyUnsupported), errors.Is(err, data.ErrCollectionsUnsupported),
+		errors.Is(err, data.ErrMediaUnsupported), errors.Is(err, data.ErrCommentsUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		slog.Warn("workflow request failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -1,13 +1,26 @@
 package main
 
 import (
+	"context"
+	"crypto/cipher"
+	"crypto/tls"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"go-story/internal/config"
 	"go-story/internal/data"
 	"go-story/internal/schema"
 	"go-story/internal/server"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -16,14 +29,62 @@ func main() {
 		log.Fatalf("config error: %v", err)
 	}
 
+	logger, err := newLogger(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	// 子命令：go-story cache dump|restore
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		if err := runCacheCommand(cfg, logger, os.Args[2:]); err != nil {
+			log.Fatalf("cache: %v", err)
+		}
+		return
+	}
+
 	db, err := data.NewDB(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to connect db: %v", err)
 	}
 	defer db.Close()
 
+	// 子命令：go-story migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(db, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	// 子命令：go-story search reindex|sync
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		if err := runSearchCommand(cfg, db, os.Args[2:]); err != nil {
+			log.Fatalf("search: %v", err)
+		}
+		return
+	}
+	// 子命令：go-story stories export|import
+	if len(os.Args) > 1 && os.Args[1] == "stories" {
+		if err := runStoriesCommand(cfg, logger, db, os.Args[2:]); err != nil {
+			log.Fatalf("stories: %v", err)
+		}
+		return
+	}
+	if cfg.MigrateOnStart {
+		if err := migrateOnStart(db); err != nil {
+			log.Fatalf("failed to migrate db: %v", err)
+		}
+	}
+
 	// 初始化 Redis cache
-	cache, err := data.NewCache(cfg.RedisURL, cfg.RedisEnabled, cfg.RedisTTL, cfg.GoEnv)
+	var cacheMetrics data.CacheMetrics
+	if cfg.MetricsEnabled {
+		cacheMetrics, err = data.NewPrometheusCacheMetrics(prometheus.DefaultRegisterer)
+		if err != nil {
+			log.Fatalf("failed to register metrics: %v", err)
+		}
+	}
+	cache, err := newCache(cfg, logger, cacheMetrics)
 	if err != nil {
 		log.Printf("warning: failed to initialize cache: %v", err)
 	}
@@ -40,18 +101,460 @@ func main() {
 	}
 
 	repo := data.NewRepo(db, cfg.StaticsHost, cache)
-	gqlSchema, err := schema.Build(repo)
+	// 預熱熱門文章，避免部署後 cache 全空造成 DB 尖峰
+	if len(cfg.CacheWarmSlugs) > 0 || cfg.CacheWarmTopN > 0 {
+		warmer := data.NewCacheWarmer(repo, cfg.CacheWarmSlugs, cfg.CacheWarmTopN)
+		go warmer.Run(context.Background(), time.Duration(cfg.CacheWarmInterval)*time.Second)
+	}
+
+	stories, closeStories, err := data.OpenStoryRepository(context.Background(), cfg.StoryStore, db, cfg.MongoURL, cfg.MongoDatabase)
+	if err != nil {
+		log.Fatalf("failed to open story store: %v", err)
+	}
+	defer closeStories()
+
+	// HTML body 在寫入前過濾，讀取時再過濾一次，涵蓋過濾前已存入的內容
+	sanitizer, err := data.NewHTMLSanitizer(cfg.HTMLAllowlist, cfg.SiteURL, cfg.ExternalLinkRel)
+	if err != nil {
+		log.Fatalf("failed to configure html sanitizer: %v", err)
+	}
+	if cfg.StoryBodyFormat == "" || cfg.StoryBodyFormat == data.BodyFormatHTML {
+		stories = data.NewSanitizingStoryRepository(stories, sanitizer)
+	}
+
+	// 使用 Elasticsearch 時，story 的寫入即時推送到 index，並定期從 checkpoint 增量同步
+	if cfg.SearchBackend == data.SearchBackendElasticsearch && cfg.ElasticsearchURL != "" {
+		indexer := data.NewStoryIndexer(cfg.ElasticsearchURL, cfg.ElasticsearchIndex)
+		if err := indexer.EnsureIndex(context.Background()); err != nil {
+			log.Printf("warning: failed to ensure search index: %v", err)
+		}
+		if cfg.ElasticsearchSyncInterval > 0 {
+			go indexer.RunSync(context.Background(), stories, cache, time.Duration(cfg.ElasticsearchSyncInterval)*time.Second)
+		}
+		stories = data.NewIndexingStoryRepository(stories, indexer)
+	}
+
+	// 所有寫入操作記錄在 Postgres 的稽核紀錄
+	audit := data.NewAuditLog(db)
+
+	// story 發布、修改與下架時通知訂閱的 webhook；投遞紀錄存在 Postgres，由背景定期送出
+	var storyListeners []data.StoryEventListener
+	var webhooks *data.WebhookService
+	if cfg.WebhookDeliveryInterval > 0 {
+		webhooks = data.NewWebhookService(db, audit)
+		go webhooks.Run(context.Background(), time.Duration(cfg.WebhookDeliveryInterval)*time.Second)
+		storyListeners = append(storyListeners, webhooks)
+	}
+	// 上傳的圖片存放於 MEDIA_STORAGE，資料存在 Postgres；local 時由本服務提供檔案
+	var media *data.MediaService
+	var objects data.ObjectStorage
+	if cfg.MediaStorage != "" {
+		objects, err = data.OpenObjectStorage(cfg.MediaStorage, data.ObjectStorageConfig{
+			Dir:             cfg.MediaLocalDir,
+			PublicURL:       cfg.MediaPublicURL,
+			Endpoint:        cfg.MediaS3Endpoint,
+			Region:          cfg.MediaS3Region,
+			Bucket:          cfg.MediaBucket,
+			AccessKeyID:     cfg.MediaAccessKeyID,
+			SecretAccessKey: cfg.MediaSecretAccessKey,
+		})
+		if err != nil {
+			log.Fatalf("failed to open media storage: %v", err)
+		}
+		media = data.NewMediaService(db, objects, cfg.MediaMaxSize, audit)
+		if local, ok := objects.(*data.LocalObjectStorage); ok {
+			publicURL, _ := url.Parse(local.PublicURL())
+			prefix := strings.TrimSuffix(publicURL.Path, "/") + "/"
+			http.Handle(prefix, http.StripPrefix(prefix, http.FileServer(http.Dir(local.Dir()))))
+		}
+	}
+	// sitemap 定期由其中一個 instance 產生並存入 cache，檔案保留到之後幾次產生；story 發布或下架時提早重新產生
+	site := data.Site{Title: cfg.SiteName, URL: cfg.SiteURL, Description: cfg.SiteDescription, Language: cfg.SiteLanguage,
+		Image: cfg.SiteImage, Twitter: cfg.SiteTwitter, Logo: cfg.SiteLogo, ArticleType: cfg.SiteArticleType,
+		AMP: cfg.AMPEnabled && cfg.SiteURL != ""}
+	var sitemaps *data.SitemapService
+	if cfg.SiteURL != "" && cfg.SitemapInterval > 0 {
+		interval := time.Duration(cfg.SitemapInterval) * time.Second
+		sitemaps = data.NewSitemapService(stories, cache, site, 3*interval)
+		go sitemaps.Run(context.Background(), interval)
+		storyListeners = append(storyListeners, sitemaps)
+	}
+	// embed block 在發布時以 oEmbed 解析並存入 cache，讀取時只查 cache；需要啟用 cache
+	var embeds *data.OEmbedResolver
+	if cfg.OEmbedCacheTTL > 0 && cache.Enabled() {
+		embeds = data.NewOEmbedResolver(cache, time.Duration(cfg.OEmbedCacheTTL)*time.Second, cfg.InstagramOEmbedToken)
+		storyListeners = append(storyListeners, embeds)
+	}
+	// 沒有 summary 的 story 發布或修改時由 LLM 產生摘要，取代由 body 擷取的前幾句
+	if cfg.SummarizerURL != "" {
+		summarizer := data.NewLLMSummarizer(cfg.SummarizerURL, cfg.SummarizerAPIKey, cfg.SummarizerModel)
+		storyListeners = append(storyListeners, data.NewSummaryGenerator(stories, summarizer, cache))
+	}
+	// 帶有 PUSH_TAGS 的 story 發布時推播通知；同一篇 story 只推播一次，以 Redis 記錄
+	var pushProviders []data.PushProvider
+	var pushDevices *data.PushDeviceService
+	if cfg.PushFCMCredentialsFile != "" {
+		fcm, err := data.NewFCMPushProvider(cfg.PushFCMCredentialsFile, cfg.PushFCMTopic)
+		if err != nil {
+			log.Fatalf("failed to configure fcm: %v", err)
+		}
+		pushProviders = append(pushProviders, fcm)
+	}
+	if cfg.PushAPNsKeyFile != "" {
+		pushDevices = data.NewPushDeviceService(db)
+		apns, err := data.NewAPNsPushProvider(pushDevices, data.APNsConfig{
+			KeyFile: cfg.PushAPNsKeyFile,
+			KeyID:   cfg.PushAPNsKeyID,
+			TeamID:  cfg.PushAPNsTeamID,
+			Topic:   cfg.PushAPNsTopic,
+			Sandbox: cfg.PushAPNsSandbox,
+		})
+		if err != nil {
+			log.Fatalf("failed to configure apns: %v", err)
+		}
+		pushProviders = append(pushProviders, apns)
+	}
+	if cfg.PushWebhookURL != "" {
+		pushProviders = append(pushProviders, data.NewWebhookPushProvider(cfg.PushWebhookURL, cfg.PushWebhookSecret))
+	}
+	if len(pushProviders) > 0 {
+		storyListeners = append(storyListeners, data.NewPushDispatcher(cache, site, data.PushConfig{Tags: cfg.PushTags}, pushProviders...))
+	}
+	if len(storyListeners) > 0 {
+		stories = data.NewEventStoryRepository(stories, storyListeners...)
+	}
+	stories = data.NewAuditStoryRepository(stories, audit)
+	// 發布時間到期的排程 story 由其中一個 instance 發布
+	if cfg.PublishScheduleInterval > 0 {
+		scheduler := data.NewStoryScheduler(stories, cache)
+		go scheduler.Run(context.Background(), time.Duration(cfg.PublishScheduleInterval)*time.Second)
+	}
+
+	// 瀏覽數先累計在 Redis，定期批次寫入 story store
+	var viewCounter *data.ViewCounter
+	if cfg.ViewFlushInterval > 0 {
+		viewCounter = data.NewViewCounter(stories, cache)
+		go viewCounter.Run(context.Background(), time.Duration(cfg.ViewFlushInterval)*time.Second)
+	}
+
+	// Markdown body 的轉換結果依原文內容另外快取，story 寫入時不需清除
+	bodyRenderer, err := data.NewBodyRenderer(cfg.StoryBodyFormat, cfg.MarkdownExtensions, sanitizer, cache)
+	if err != nil {
+		log.Fatalf("failed to configure story bodies: %v", err)
+	}
+	cachedStories := data.NewCachedStoryRepository(stories, cache)
+	storyService := data.NewStoryService(cachedStories, bodyRenderer, embeds, site)
+
+	// 未發布 story 的預覽直接讀取 story store，不經過 cache，草稿不會寫入公開讀取共用的 cache
+	var previews *data.PreviewService
+	if cfg.PreviewSecret != "" {
+		previews = data.NewPreviewService(stories, cfg.PreviewSecret, time.Duration(cfg.PreviewTokenTTL)*time.Second, cfg.PreviewURL)
+	}
+
+	// 全文搜尋；未設定 backend 時 /api/v1/search 回傳 501
+	var searchService *data.SearchService
+	if cfg.SearchBackend != "" {
+		backend, err := data.OpenSearchBackend(cfg.SearchBackend, db, cfg.ElasticsearchURL, cfg.ElasticsearchIndex)
+		if err != nil {
+			log.Fatalf("failed to open search backend: %v", err)
+		}
+		searchService = data.NewSearchService(backend, cache)
+	}
+
+	// 相關文章推薦；共讀紀錄保存在 Redis，cache 停用時只依內容評分
+	relatedService := data.NewRelatedService(storyService, cache)
+	// 熱門與最多人閱讀排行，依前端回報的瀏覽計算
+	trendingService := data.NewTrendingService(storyService, cache, time.Duration(cfg.ViewDedupeWindow)*time.Second)
+
+	// 讀者留言存放於 Postgres，每位使用者的發表頻率以 Redis 計數限制；公開留言數另外快取
+	var comments *data.CommentService
+	if cfg.CommentsEnabled {
+		comments = data.NewCommentService(db, cache, audit, data.CommentConfig{
+			AutoApprove: cfg.CommentAutoApprove,
+			RateLimit:   cfg.CommentRateLimit,
+			RateWindow:  time.Duration(cfg.CommentRateWindow) * time.Second,
+		})
+	}
+
+	// 讀者回應：每位使用者的回應存放於 Postgres，總數先累計在 Redis，定期批次寫入
+	var reactions *data.ReactionService
+	if cfg.ReactionsEnabled {
+		reactions, err = data.NewReactionService(db, cache, cfg.Reactions)
+		if err != nil {
+			log.Fatalf("failed to configure reactions: %v", err)
+		}
+		go reactions.Run(context.Background(), time.Duration(cfg.ReactionFlushInterval)*time.Second)
+	}
+
+	// 讀者收藏存放於 Postgres；屬於個別讀者的資料，不經過 cache
+	var bookmarks *data.BookmarkService
+	if cfg.BookmarksEnabled {
+		bookmarks = data.NewBookmarkService(db)
+	}
+	// 閱讀位置先寫入 Redis，定期寫入 Postgres 作為備援
+	var progress *data.ReadingProgressService
+	if cfg.ReadingProgressEnabled {
+		progress = data.NewReadingProgressService(db, cache)
+	}
+
+	// 以身分提供者的 JWKS 驗證 Bearer JWT
+	var verifier *data.JWTVerifier
+	if cfg.AuthJWKSURL != "" {
+		verifier = data.NewJWTVerifier(data.JWTConfig{
+			JWKSURL:    cfg.AuthJWKSURL,
+			Issuer:     cfg.AuthIssuer,
+			Audience:   cfg.AuthAudience,
+			ClockSkew:  time.Duration(cfg.AuthClockSkew) * time.Second,
+			CacheTTL:   time.Duration(cfg.AuthJWKSCacheTTL) * time.Second,
+			RolesClaim: cfg.AuthRolesClaim,
+		})
+	}
+
+	// 管理介面以 OIDC 登入，session 保存於 Postgres
+	var adminSessions *data.AdminSessionService
+	if cfg.OIDCIssuerURL != "" {
+		oidc, err := data.NewOIDCClient(data.OIDCConfig{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+			GroupRoles:   cfg.OIDCGroupRoles,
+		})
+		if err != nil {
+			log.Fatalf("failed to configure OIDC login: %v", err)
+		}
+		adminSessions = data.NewAdminSessionService(db, oidc, time.Duration(cfg.AdminSessionTTL)*time.Second)
+	}
+
+	// 付費牆：計次文章的閱讀次數記錄於 Redis
+	var paywall *data.Paywall
+	if cfg.PaywallEnabled || cfg.PaywallTokenSecret != "" {
+		paywall = data.NewPaywall(cache, data.PaywallConfig{
+			TokenSecret:     cfg.PaywallTokenSecret,
+			MembershipClaim: cfg.PaywallMembershipClaim,
+			MeterLimit:      cfg.PaywallMeterLimit,
+			MeterWindow:     time.Duration(cfg.PaywallMeterWindow) * time.Second,
+		})
+	}
+
+	gqlSchema, err := schema.Build(repo, storyService, relatedService, trendingService, viewCounter, comments, reactions, media, paywall)
 	if err != nil {
 		log.Fatalf("failed to build schema: %v", err)
 	}
 
-	http.Handle("/api/graphql", server.NewGraphQLHandler(gqlSchema))
+	// 機器用戶端的 API key 只保存 hash 於 Postgres，驗證結果快取於 Redis
+	var apiKeys *data.APIKeyService
+	if cfg.APIKeysEnabled {
+		apiKeys = data.NewAPIKeyService(db, cache, audit)
+	}
+
+	// 公開的路由共用同一組 rate limit 計數，RATE_LIMIT_POLICIES 列出的 route group 另外計算；
+	// API key 先驗證並檢查 route group 需要的 scope，再依各 key 的上限計算
+	for group := range cfg.RateLimitPolicies {
+		if !slices.Contains(server.RateLimitGroups, group) {
+			log.Fatalf("unknown rate limit group %q in RATE_LIMIT_POLICIES (want one of %s)", group, strings.Join(server.RateLimitGroups, ", "))
+		}
+	}
+	rateLimit := func(group string, h http.Handler) http.Handler {
+		return server.APIKeys(apiKeys, server.RateLimitGroupScopes[group], h)
+	}
+	if cfg.RateLimitPerIP > 0 || cfg.RateLimitPerAPIKey > 0 || len(cfg.RateLimitPolicies) > 0 || apiKeys != nil {
+		limiter := data.NewRateLimiter(cache)
+		rateLimit = func(group string, h http.Handler) http.Handler {
+			rateLimitCfg := server.RateLimitConfig{
+				PerIP:          cfg.RateLimitPerIP,
+				PerAPIKey:      cfg.RateLimitPerAPIKey,
+				Window:         time.Duration(cfg.RateLimitWindow) * time.Second,
+				TrustedProxies: cfg.TrustedProxies,
+			}
+			if policy, ok := cfg.RateLimitPolicies[group]; ok {
+				rateLimitCfg = server.RateLimitConfig{
+					Group:          group,
+					PerIP:          policy.PerIP,
+					PerAPIKey:      policy.PerAPIKey,
+					Window:         time.Duration(policy.Window) * time.Second,
+					TrustedProxies: cfg.TrustedProxies,
+				}
+			}
+			return server.APIKeys(apiKeys, server.RateLimitGroupScopes[group], server.RateLimit(limiter, rateLimitCfg, h))
+		}
+	}
+
+	if cfg.GRPCPort != "" {
+		if err := startGRPCServer(":"+cfg.GRPCPort, storyService); err != nil {
+			log.Fatalf("failed to start gRPC server: %v", err)
+		}
+	}
+
+	http.Handle("/api/graphql", rateLimit(server.RateLimitGroupGraphQL, server.Authenticate(verifier, server.Entitlements(paywall, server.NewGraphQLHandler(gqlSchema)))))
+	http.Handle("/api/v1/", rateLimit(server.RateLimitGroupREST, server.Authenticate(verifier, server.Entitlements(paywall, server.NewRESTHandler(storyService, searchService, relatedService, trendingService, viewCounter, previews, comments, reactions, bookmarks, progress, pushDevices, paywall)))))
 	http.HandleFunc("/probe", server.ProbeHandler)
+	if cfg.MetricsEnabled {
+		http.Handle("/metrics", promhttp.Handler())
+	}
+	if cfg.SiteURL != "" {
+		feeds := data.NewFeedService(storyService, cache, site)
+		http.Handle("/feeds/", rateLimit(server.RateLimitGroupFeeds, server.NewFeedHandler(feeds, cfg.SiteURL)))
+	}
+	// 圖片轉換的來源限於 IMAGE_PROXY_SOURCES 與上傳的圖片；結果另存於上傳圖片的儲存空間
+	imageSources := cfg.ImageProxySources
+	if objects != nil {
+		imageSources = append(imageSources, objects.URL(""))
+	}
+	if len(imageSources) > 0 {
+		images := data.NewImageProxy(cache, time.Duration(cfg.ImageCacheTTL)*time.Second, objects, imageSources)
+		http.Handle("/images", rateLimit(server.RateLimitGroupImages, server.NewImageHandler(images)))
+	}
+	// AMP 頁面另外快取於 story: 前綴下；沒有尺寸的圖片只量測圖片轉換允許的來源
+	if site.AMP {
+		amp := data.NewAMPService(storyService, cache, site, imageSources)
+		http.Handle("/amp/", rateLimit(server.RateLimitGroupAMP, server.NewAMPHandler(amp, site)))
+	}
+	if sitemaps != nil {
+		sitemapHandler := rateLimit(server.RateLimitGroupSitemaps, server.NewSitemapHandler(sitemaps))
+		http.Handle("/sitemap.xml", sitemapHandler)
+		http.Handle("/sitemaps/", sitemapHandler)
+	}
+	if cfg.CacheStatsEnabled {
+		http.Handle("/internal/cache/stats", server.CacheStatsHandler(cache))
+	}
+	if cfg.CacheAdminToken != "" {
+		http.Handle("/internal/cache/", server.CacheAdminHandler(cache, cfg.CacheAdminToken))
+	}
+	// 編輯工作流程 API 可讀取所有狀態的 story，依 token、JWT 的 roles 或登入 session 區分撰稿者、編輯與管理者
+	if adminSessions != nil {
+		http.Handle("/auth/", server.AdminLoginHandler(adminSessions))
+	}
+	if cfg.WorkflowWriterToken != "" || cfg.WorkflowEditorToken != "" || cfg.WorkflowAdminToken != "" || verifier != nil || adminSessions != nil {
+		tokens := map[string]data.StoryRole{}
+		if cfg.WorkflowWriterToken != "" {
+			tokens[cfg.WorkflowWriterToken] = data.StoryRoleAuthor
+		}
+		if cfg.WorkflowEditorToken != "" {
+			tokens[cfg.WorkflowEditorToken] = data.StoryRoleEditor
+		}
+		if cfg.WorkflowAdminToken != "" {
+			tokens[cfg.WorkflowAdminToken] = data.StoryRoleAdmin
+		}
+		workflowHandler := server.WorkflowHandler(data.NewStoryWorkflow(cachedStories), previews, media, comments, tokens, verifier, adminSessions)
+		http.Handle("/internal/stories", workflowHandler)
+		http.Handle("/internal/stories/", workflowHandler)
+		http.Handle("/internal/authors", workflowHandler)
+		http.Handle("/internal/authors/", workflowHandler)
+		http.Handle("/internal/collections", workflowHandler)
+		http.Handle("/internal/collections/", workflowHandler)
+		http.Handle("/internal/taxonomy/", workflowHandler)
+		http.Handle("/internal/media", workflowHandler)
+		http.Handle("/internal/media/", workflowHandler)
+		http.Handle("/internal/comments", workflowHandler)
+		http.Handle("/internal/comments/", workflowHandler)
+	}
+	// 電子報摘要；設定 NEWSLETTER_WEBHOOK_URL 時才能寄送，否則只能預覽
+	if cfg.NewsletterAdminToken != "" && cfg.SiteURL != "" {
+		var sender data.DigestSender
+		if cfg.NewsletterWebhookURL != "" {
+			sender = data.NewHTTPDigestSender(cfg.NewsletterWebhookURL, cfg.NewsletterWebhookSecret)
+		}
+		digests := data.NewDigestService(storyService, cache, site, sender, data.DigestConfig{
+			Sections:          cfg.NewsletterSections,
+			StoriesPerSection: cfg.NewsletterStoriesPerSection,
+		})
+		http.Handle("/internal/newsletter/", server.NewsletterHandler(digests, cfg.NewsletterAdminToken))
+	}
+	if webhooks != nil && cfg.WebhookAdminToken != "" {
+		http.Handle("/internal/webhooks/", server.WebhookAdminHandler(webhooks, cfg.WebhookAdminToken))
+	}
+	if apiKeys != nil && cfg.APIKeyAdminToken != "" {
+		apiKeyAdmin := server.APIKeyAdminHandler(apiKeys, cfg.APIKeyAdminToken)
+		http.Handle("/internal/api-keys", apiKeyAdmin)
+		http.Handle("/internal/api-keys/", apiKeyAdmin)
+	}
+	if cfg.AuditAdminToken != "" {
+		http.Handle("/internal/audit", server.AuditHandler(audit, cfg.AuditAdminToken))
+	}
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte("GraphQL endpoint is available at POST /api/graphql"))
 	})
 
+	// 文字格式的回應依 Accept-Encoding 壓縮，圖片與已壓縮的回應 (例如 /metrics) 不受影響
+	var handler http.Handler = http.DefaultServeMux
+	if cfg.CompressionEnabled {
+		handler = server.Compress(cfg.CompressionMinSize, handler)
+	}
+
 	addr := ":" + cfg.Port
 	log.Printf("GraphQL server listening on %s (POST /api/graphql)", addr)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	log.Fatal(http.ListenAndServe(addr, handler))
+}
+
+// newCache 依設定建立 cache；設定錯誤時直接結束程式，連線失敗則回傳錯誤與停用的 cache
+func newCache(cfg config.Config, logger *slog.Logger, cacheMetrics data.CacheMetrics) (*data.Cache, error) {
+	codec, err := data.CacheCodecByName(cfg.CacheCodec)
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	compression, err := data.ParseCacheCompression(cfg.CacheCompression)
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	var redisTLS *tls.Config
+	if cfg.RedisTLSEnabled {
+		redisTLS, err = data.NewRedisTLSConfig(cfg.RedisTLSCAFile, cfg.RedisTLSCertFile, cfg.RedisTLSKeyFile, cfg.RedisTLSInsecureSkipVerify)
+		if err != nil {
+			log.Fatalf("config error: %v", err)
+		}
+	}
+	data.SetDebugCacheKeys(cfg.CacheDebugKeys)
+	var cacheCipher cipher.AEAD
+	if cfg.CacheEncryptionKey != "" {
+		if cacheCipher, err = data.NewCacheCipher(cfg.CacheEncryptionKey); err != nil {
+			log.Fatalf("config error: %v", err)
+		}
+	}
+	return data.NewCache(cfg.RedisURL, cfg.RedisEnabled, cfg.RedisTTL, cfg.GoEnv,
+		data.WithRedisMode(cfg.RedisMode),
+		data.WithRedisSentinel(cfg.RedisSentinelMaster, cfg.RedisSentinelAddrs, cfg.RedisSentinelPassword),
+		data.WithRedisAuth(cfg.RedisUsername, cfg.RedisPassword),
+		data.WithRedisTLS(redisTLS),
+		data.WithRedisTimeouts(
+			time.Duration(cfg.RedisDialTimeoutMS)*time.Millisecond,
+			time.Duration(cfg.RedisReadTimeoutMS)*time.Millisecond,
+			time.Duration(cfg.RedisWriteTimeoutMS)*time.Millisecond,
+		),
+		data.WithOperationTimeout(time.Duration(cfg.CacheOpTimeoutMS)*time.Millisecond),
+		data.WithKeyNamespace(cfg.CacheNamespace, cfg.CacheKeyVersion),
+		data.WithFallbackLRU(cfg.CacheFallbackSize),
+		data.WithCircuitBreaker(cfg.CacheBreakerThreshold, time.Duration(cfg.CacheBreakerCooldown)*time.Second),
+		data.WithLocalTier(cfg.CacheLocalSize, time.Duration(cfg.CacheLocalTTL)*time.Second),
+		data.WithStaleWhileRevalidate(time.Duration(cfg.CacheStaleTTL)*time.Second),
+		data.WithHotKeyRefresh(cfg.CacheHotKeyThreshold, time.Duration(cfg.CacheHotKeyRefreshAhead)*time.Second),
+		data.WithNegativeTTL(time.Duration(cfg.CacheNegativeTTL)*time.Second),
+		data.WithTTLJitter(cfg.CacheTTLJitter),
+		data.WithCodec(codec),
+		data.WithCompression(compression, cfg.CacheCompressionThreshold),
+		data.WithMaxValueSize(cfg.CacheMaxValueSize),
+		data.WithEncryption(cacheCipher, cfg.CacheEncryptedPrefixes...),
+		data.WithPopularityTracking(cfg.CacheWarmTopN > 0),
+		data.WithMetrics(cacheMetrics),
+		data.WithLogger(logger),
+	)
+}
+
+// newLogger 依 LOG_LEVEL 與 LOG_FORMAT 建立 slog logger
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL value: %v", err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid LOG_FORMAT value: %q", format)
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go-story/internal/data"
+)
+
+const migrateCommandUsage = `usage:
+  go-story migrate up
+  go-story migrate down
+  go-story migrate status`
+
+// runMigrateCommand 執行 migrate 子命令：up 套用所有未執行的 migration，down 還原最後一個，
+// status 列出各 migration 的狀態
+func runMigrateCommand(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateCommandUsage)
+	}
+	migrator, err := data.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(os.Stderr, "applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(os.Stderr, "no pending migrations")
+		}
+		return nil
+
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Fprintln(os.Stderr, "no applied migrations")
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "reverted %d_%s\n", reverted.Version, reverted.Name)
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+	return errors.New(migrateCommandUsage)
+}
+
+// migrateOnStart 於啟動時套用未執行的 migration
+func migrateOnStart(db *sql.DB) error {
+	migrator, err := data.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		log.Printf("applied migration %d_%s", m.Version, m.Name)
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"go-story/internal/config"
+	"go-story/internal/data"
+)
+
+const searchCommandUsage = `usage:
+  go-story search reindex
+  go-story search sync`
+
+// runSearchCommand 執行 search 子命令：reindex 由 story 儲存層重建整個 Elasticsearch index 並切換 alias，
+// sync 從上次的 checkpoint 增量同步一次
+func runSearchCommand(cfg config.Config, db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New(searchCommandUsage)
+	}
+	if cfg.SearchBackend != data.SearchBackendElasticsearch || cfg.ElasticsearchURL == "" {
+		return errors.New("the search index is only used with SEARCH_BACKEND=elasticsearch and ELASTICSEARCH_URL")
+	}
+
+	ctx := context.Background()
+	stories, closeStories, err := data.OpenStoryRepository(ctx, cfg.StoryStore, db, cfg.MongoURL, cfg.MongoDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to open story store: %w", err)
+	}
+	defer closeStories()
+	indexer := data.NewStoryIndexer(cfg.ElasticsearchURL, cfg.ElasticsearchIndex)
+
+	switch args[0] {
+	case "reindex":
+		count, err := indexer.Reindex(ctx, stories)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "reindexed %d stories into %s\n", count, cfg.ElasticsearchIndex)
+		return nil
+
+	case "sync":
+		count, err := indexer.Sync(ctx, stories)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "synced %d stories\n", count)
+		return nil
+	}
+	return errors.New(searchCommandUsage)
+}
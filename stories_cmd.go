@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"go-story/internal/config"
+	"go-story/internal/data"
+)
+
+const storiesCommandUsage = `usage:
+  go-story stories export [-section <section>] [-from <date>] [-to <date>] [-out <file>]
+  go-story stories import [-dry-run] [-batch <size>] [-in <file>]
+  go-story stories import-wordpress (-wxr <file> | -url <site> [-user <name>]) [-dry-run] [-batch <size>]`
+
+// importActor 為 CLI 匯入寫入稽核紀錄時的 actor
+const importActor = data.AuditActorSystem + ":import"
+
+// runStoriesCommand 執行 stories 子命令：export 將 story 匯出成 NDJSON，import 將 NDJSON 以 upsert 寫回，
+// import-wordpress 由 WordPress 的 WXR 匯出檔或 REST API 匯入，用於 CMS 之間的搬移。
+// 匯入會更新 search index、清除 story cache 並寫入稽核紀錄，但不送出 webhook
+func runStoriesCommand(cfg config.Config, logger *slog.Logger, db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(storiesCommandUsage)
+	}
+
+	ctx := context.Background()
+	stories, closeStories, err := data.OpenStoryRepository(ctx, cfg.StoryStore, db, cfg.MongoURL, cfg.MongoDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to open story store: %w", err)
+	}
+	defer closeStories()
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("stories export", flag.ContinueOnError)
+		section := fs.String("section", "", "only export stories in this section")
+		from := fs.String("from", "", "only export stories published at or after this time (RFC 3339 or YYYY-MM-DD)")
+		to := fs.String("to", "", "only export stories published before this time (RFC 3339 or YYYY-MM-DD)")
+		out := fs.String("out", "", "output file (default stdout)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		opts := data.StoryExportOptions{Section: *section}
+		if opts.PublishedFrom, err = parseExportTime("from", *from); err != nil {
+			return err
+		}
+		if opts.PublishedTo, err = parseExportTime("to", *to); err != nil {
+			return err
+		}
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := data.ExportStories(ctx, stories, w, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %d stories\n", n)
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("stories import", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "validate and report without writing")
+		batch := fs.Int("batch", 100, "stories written per transaction")
+		in := fs.String("in", "", "NDJSON file (default stdin)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+		if *in != "" {
+			f, err := os.Open(*in)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		stories, closeCache, err := importStoryRepository(cfg, logger, db, stories)
+		if err != nil {
+			return err
+		}
+		defer closeCache()
+		report, err := data.ImportStories(data.WithAuditActor(ctx, importActor), stories, r, data.StoryImportOptions{DryRun: *dryRun, BatchSize: *batch})
+		if report != nil {
+			if encErr := writeImportReport(report); encErr != nil {
+				return encErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("%d of %d stories failed", report.Failed, report.Lines)
+		}
+		return nil
+
+	case "import-wordpress":
+		fs := flag.NewFlagSet("stories import-wordpress", flag.ContinueOnError)
+		wxr := fs.String("wxr", "", "WordPress export (WXR) file")
+		site := fs.String("url", "", "WordPress site URL, read through the REST API")
+		user := fs.String("user", "", "WordPress username for the REST API (reads unpublished posts)")
+		dryRun := fs.Bool("dry-run", false, "map and validate without writing")
+		batch := fs.Int("batch", 100, "stories written per transaction")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if (*wxr == "") == (*site == "") {
+			return errors.New("import-wordpress needs exactly one of -wxr and -url")
+		}
+		var wp *data.WordPressSite
+		if *wxr != "" {
+			f, err := os.Open(*wxr)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if wp, err = data.ReadWordPressWXR(f); err != nil {
+				return err
+			}
+		} else {
+			// application password 由環境變數讀取，避免出現在 process 列表中
+			client := data.NewWordPressClient(*site, *user, os.Getenv("WORDPRESS_APP_PASSWORD"))
+			if wp, err = client.Fetch(ctx); err != nil {
+				return err
+			}
+		}
+
+		stories, closeCache, err := importStoryRepository(cfg, logger, db, stories)
+		if err != nil {
+			return err
+		}
+		defer closeCache()
+		report, err := data.ImportWordPress(data.WithAuditActor(ctx, importActor), stories, wp, data.StoryImportOptions{DryRun: *dryRun, BatchSize: *batch})
+		if report != nil {
+			if encErr := writeImportReport(report); encErr != nil {
+				return encErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if report.Stories.Failed > 0 {
+			return fmt.Errorf("%d of %d posts failed", report.Stories.Failed, len(wp.Posts))
+		}
+		return nil
+	}
+	return errors.New(storiesCommandUsage)
+}
+
+// importStoryRepository 以與 server 相同的裝飾順序包裝 stories，HTML body 寫入前過濾，寫入後每批更新一次
+// search index 與 story cache，並寫入稽核紀錄；回傳的函式關閉 cache
+func importStoryRepository(cfg config.Config, logger *slog.Logger, db *sql.DB, stories data.StoryRepository) (data.StoryRepository, func(), error) {
+	if cfg.StoryBodyFormat == "" || cfg.StoryBodyFormat == data.BodyFormatHTML {
+		sanitizer, err := data.NewHTMLSanitizer(cfg.HTMLAllowlist, cfg.SiteURL, cfg.ExternalLinkRel)
+		if err != nil {
+			return nil, nil, fmt.Errorf("configure html sanitizer: %w", err)
+		}
+		stories = data.NewSanitizingStoryRepository(stories, sanitizer)
+	}
+	if cfg.SearchBackend == data.SearchBackendElasticsearch && cfg.ElasticsearchURL != "" {
+		stories = data.NewIndexingStoryRepository(stories, data.NewStoryIndexer(cfg.ElasticsearchURL, cfg.ElasticsearchIndex))
+	}
+	stories = data.NewAuditStoryRepository(stories, data.NewAuditLog(db))
+	cache, err := newCache(cfg, logger, nil)
+	if err != nil {
+		logger.Warn("story cache will not be purged", "error", err)
+	}
+	return data.NewCachedStoryRepository(stories, cache), func() { cache.Close() }, nil
+}
+
+// writeImportReport 將匯入報告以縮排的 JSON 輸出到 stdout
+func writeImportReport(report any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// parseExportTime 解析 -from/-to 的時間，接受 RFC 3339 或日期 (UTC 零時)；空字串回傳 nil
+func parseExportTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid -%s %q: use RFC 3339 or YYYY-MM-DD", name, value)
+}